@@ -2,141 +2,578 @@ package api
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/lib/pq"
 	db "github.com/micaelapucciariello/simplebank/db/sqlc"
 	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
 	"net/http"
 )
 
+var (
+	errAccountCurrencyExists = errors.New("account with this currency already exists")
+	errAccountNotEmpty       = errors.New("account balance must be zero before deleting it")
+)
+
 type (
 	createAccountReq struct {
-		Owner    string `json:"owner" binding:"required"`
-		Currency string `json:"currency" binding:"required,currency"`
+		Owner string `json:"owner" binding:"required"`
+		// Currency defaults to the configured one when it is missing
+		Currency string `json:"currency" binding:"omitempty,currency"`
+		// InitialBalance is deposited when the account is opened, recorded by its opening entry
+		InitialBalance utils.Money `json:"initial_balance" binding:"min=0"`
 	}
 
 	getAccountReq struct {
-		ID int64 `uri:"id" binding:"required,min=1"`
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
 	}
 
 	getAccountsListReq struct {
-		PageID   int32 `form:"page_id" binding:"required,min=1"`
-		PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+		Sort     string `form:"sort" binding:"omitempty,oneof=id -id balance -balance created_at -created_at"`
+		Currency string `form:"currency" binding:"omitempty,currency"`
+		// Label only lists the accounts tagged with it
+		Label string `form:"label"`
+	}
+
+	listAccountsAfterNumberReq struct {
+		// AfterNumber is the account number the page starts after, an empty one starts from the first page
+		AfterNumber string `form:"after_number" binding:"omitempty,len=16,numeric"`
+		Limit       int32  `form:"limit" binding:"required,min=5,max=10"`
+		Sort        string `form:"sort" binding:"omitempty,oneof=id -id balance -balance created_at -created_at"`
+		Currency    string `form:"currency" binding:"omitempty,currency"`
+		Label       string `form:"label"`
 	}
 
 	deleteAccountReq struct {
-		ID int64 `uri:"id" binding:"required,min=1"`
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	closeAccountUriReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	closeAccountReq struct {
+		// DestinationAccountNumber receives the remaining balance, it must be another live account in the same currency and organization.
+		// An owner holds a single live account per currency, so it is never one of the owner's own accounts
+		DestinationAccountNumber string `json:"destination_account_number" binding:"required,len=16,numeric"`
+	}
+
+	closeAccountResponse struct {
+		SweptAmount int64             `json:"swept_amount"`
+		Transfer    *transferResponse `json:"transfer"`
+		Destination accountResponse   `json:"destination"`
+		format      amountFormat
+	}
+
+	accountEntryResponse struct {
+		Account accountResponse `json:"account"`
+		Entry   entryResponse   `json:"entry"`
+	}
+
+	updateAccountBalanceUriReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	updateAccountBalanceReq struct {
+		Amount utils.Money `json:"amount" binding:"required"`
+		// Version is the account version the client read, the update is rejected with a conflict if the account changed since
+		Version int64 `json:"version" binding:"omitempty,min=1"`
+	}
+
+	accountEntryUriReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	accountEntryReq struct {
+		Amount utils.Money `json:"amount" binding:"required,gt=0"`
 	}
 )
 
 func (s *Server) createAccount(ctx *gin.Context) {
 	var req createAccountReq
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		// gin converts key-value error into a json
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+	if !bindJSON(ctx, &req) {
+		return
 	}
+	// normalized before hashing too, so a retry spelling the owner in another case matches the request
+	req.Owner = utils.NormalizeUsername(req.Owner)
 
 	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
 	if authPayload.UserName != req.Owner {
-		ctx.JSON(http.StatusUnauthorized, fmt.Errorf("owner doesn't belong to the authenticated user"))
+		err := fmt.Errorf("owner doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeNotAccountOwner, err))
 		return
 	}
+	// defaulted before hashing, so a retry naming the default currency matches the request that omitted it
+	if req.Currency == "" {
+		req.Currency = s.config.DefaultCurrency
+	}
 	arg := db.CreateAccountParams{
 		Owner:    authPayload.UserName,
-		Balance:  0,
+		Balance:  int64(req.InitialBalance),
 		Currency: req.Currency,
+		OrgID:    authPayload.OrgID,
 	}
 
-	account, err := s.store.CreateAccount(ctx, arg)
+	idempotencyKey := ctx.GetHeader(idempotencyKeyHeader)
+	if idempotencyKey == "" {
+		account, err := s.openAccount(ctx, arg)
+		if err != nil {
+			respondCreateAccountError(ctx, err)
+		} else {
+			ctx.JSON(http.StatusOK, newAccountResponse(account, requestAmountFormat(ctx)))
+		}
+		return
+	}
+
+	requestHash, err := hashRequest(req)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "foreign_key_violation", "unique_violation":
-				ctx.JSON(http.StatusForbidden, errResponse(err))
-			}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
+	}
 
+	// a retried request returns the account created first instead of failing on the currency constraint
+	result, err := s.store.IdempotentCreateAccountTx(ctx, db.IdempotentCreateAccountTxParams{
+		CreateAccountParams: arg,
+		IdempotencyKey:      idempotencyKey,
+		RequestHash:         requestHash,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyMismatch) {
+			ctx.JSON(http.StatusConflict, errorResponse(codeIdempotencyMismatch, err))
+			return
 		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
-	} else {
-		ctx.JSON(http.StatusOK, account)
+		respondCreateAccountError(ctx, err)
+		return
+	}
+
+	if result.Replayed {
+		ctx.Header(idempotencyReplayedHeader, "true")
+	}
+	ctx.JSON(http.StatusOK, newAccountResponse(result.Account, requestAmountFormat(ctx)))
+}
+
+// openAccount creates the account, an initial balance is recorded by an opening entry created in the same transaction
+func (s *Server) openAccount(ctx *gin.Context, arg db.CreateAccountParams) (db.Account, error) {
+	if arg.Balance == 0 {
+		return s.store.CreateAccount(ctx, arg)
+	}
+
+	var account db.Account
+	err := s.store.ExecTx(ctx, func(q db.Querier) error {
+		var err error
+		arg.AccountNumber, err = db.NewAccountNumber(ctx, q)
+		if err != nil {
+			return err
+		}
+
+		account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateEntry(ctx, db.CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    arg.Balance,
+		})
+		return err
+	})
+	if err != nil {
+		return db.Account{}, err
+	}
+	return account, nil
+}
+
+func respondCreateAccountError(ctx *gin.Context, err error) {
+	// an owner holds at most one account per currency
+	if status, _ := dbErrorToHTTP(err); status == http.StatusConflict {
+		ctx.JSON(status, errorResponse(codeAccountExists, errAccountCurrencyExists))
+		return
 	}
+	respondDBError(ctx, err, codeUserNotFound)
 }
 
 func (s *Server) getAccount(ctx *gin.Context) {
 	var req getAccountReq
-	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+	if !bindURI(ctx, &req) {
 		return
 	}
 
-	account, err := s.store.GetAccount(ctx, req.ID)
+	account, err := s.store.GetAccountByNumber(ctx, req.AccountNumber)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
 	}
 	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
 	if authPayload.UserName != account.Owner {
 		err = fmt.Errorf("owner doesn't belong to the authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeNotAccountOwner, err))
 		return
 	} else {
-		ctx.JSON(http.StatusOK, account)
+		ctx.JSON(http.StatusOK, newAccountResponse(account, requestAmountFormat(ctx)))
 	}
 }
 
-// getAccountsList executes a paginated query
+// getAccountsList executes a paginated query, requests carrying an after_number cursor are paginated by account number instead of offset
 func (s *Server) getAccountsList(ctx *gin.Context) {
+	if _, ok := ctx.GetQuery("after_number"); ok {
+		s.listAccountsAfterNumber(ctx)
+		return
+	}
+
 	var req getAccountsListReq
-	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+	if !bindQuery(ctx, &req) {
 		return
 	}
 
-	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
-	params := db.ListAccountsParams{
-		Owner:  authPayload.UserName,
-		Limit:  req.PageSize,
-		Offset: req.PageID,
+	page, ok := parsePagination(ctx)
+	if !ok {
+		return
+	}
+
+	// accounts are sorted by ascending id unless a sort key is given, a "-" prefix sorts them descending
+	if req.Sort == "" {
+		req.Sort = "id"
+	}
+
+	label, ok := bindAccountLabelFilter(ctx, req.Label)
+	if !ok {
+		return
 	}
 
-	account, err := s.store.ListAccounts(ctx, params)
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+
+	var accounts []db.Account
+	var total int64
+	var err error
+	if req.Currency != "" {
+		accounts, err = s.store.ListAccountsByCurrency(ctx, db.ListAccountsByCurrencyParams{
+			Owner:    authPayload.UserName,
+			Currency: req.Currency,
+			Label:    label,
+			SortBy:   req.Sort,
+			Limit:    page.PageSize,
+			Offset:   page.offset(),
+		})
+		if err == nil {
+			total, err = s.store.CountAccountsByCurrency(ctx, db.CountAccountsByCurrencyParams{
+				Owner:    authPayload.UserName,
+				Currency: req.Currency,
+				Label:    label,
+			})
+		}
+	} else {
+		accounts, err = s.store.ListAccounts(ctx, db.ListAccountsParams{
+			Owner:  authPayload.UserName,
+			Label:  label,
+			SortBy: req.Sort,
+			Limit:  page.PageSize,
+			Offset: page.offset(),
+		})
+		if err == nil {
+			total, err = s.store.CountAccounts(ctx, db.CountAccountsParams{
+				Owner: authPayload.UserName,
+				Label: label,
+			})
+		}
+	}
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		respondDBError(ctx, err, codeNotFound)
 	} else {
-		ctx.JSON(http.StatusOK, account)
+		rsp := newAccountResponses(accounts, requestAmountFormat(ctx))
+		ctx.JSON(http.StatusOK, newListResponse(rsp, page.PageID, page.PageSize, total))
 	}
 }
 
+// listAccountsAfterNumber returns the accounts whose number follows the cursor, pages never repeat an account while new ones are opened
+// The cursor is the account number and not the internal id, so the pages don't tell how many accounts the bank holds
+func (s *Server) listAccountsAfterNumber(ctx *gin.Context) {
+	var req listAccountsAfterNumberReq
+	if !bindQuery(ctx, &req) {
+		return
+	}
+
+	// the cursor only follows ascending account numbers, any other order would skip or repeat accounts across pages
+	if req.Sort != "" {
+		err := fmt.Errorf("sort [%v] can't be combined with after_number, cursor pages are sorted by account number", req.Sort)
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return
+	}
+
+	label, ok := bindAccountLabelFilter(ctx, req.Label)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+
+	// one extra account tells whether there is a next page without a count query
+	accounts, err := s.store.ListAccountsAfterNumber(ctx, db.ListAccountsAfterNumberParams{
+		Owner:       authPayload.UserName,
+		AfterNumber: req.AfterNumber,
+		Currency:    sql.NullString{String: req.Currency, Valid: req.Currency != ""},
+		Label:       label,
+		Limit:       req.Limit + 1,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	var nextAfterNumber *string
+	if len(accounts) > int(req.Limit) {
+		accounts = accounts[:req.Limit]
+		nextAfterNumber = &accounts[len(accounts)-1].AccountNumber
+	}
+
+	rsp := newAccountResponses(accounts, requestAmountFormat(ctx))
+	ctx.JSON(http.StatusOK, newCursorListResponse(rsp, req.Limit, nextAfterNumber))
+}
+
+// bindAccountLabelFilter normalizes the label an account list is filtered by, an empty label doesn't filter
+func bindAccountLabelFilter(ctx *gin.Context, raw string) (sql.NullString, bool) {
+	if raw == "" {
+		return sql.NullString{}, true
+	}
+	label, err := normalizeAccountLabel(raw)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return sql.NullString{}, false
+	}
+	return sql.NullString{String: label, Valid: true}, true
+}
+
 func (s *Server) deleteAccount(ctx *gin.Context) {
 	var req deleteAccountReq
-	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+	if !bindURI(ctx, &req) {
 		return
 	}
 
-	account, err := s.store.GetAccount(ctx, req.ID)
+	account, err := s.store.GetAccountByNumber(ctx, req.AccountNumber)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
-			return
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != account.Owner {
+		err = fmt.Errorf("owner doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	// an account holding money can't be removed, otherwise its funds would be lost. The delete itself checks the balance,
+	// the account read above may be stale and money may land on it in between.
+	// The account is only marked as deleted, so its entries and transfers keep pointing to it.
+	// Its schedules are cancelled along, they would only fail from now on
+	err = s.store.ExecTx(ctx, func(q db.Querier) error {
+		deleted, err := q.SoftDeleteAccount(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return fmt.Errorf("%w: account [%v] still holds money or funds on hold", errAccountNotEmpty, account.AccountNumber)
 		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		return q.CancelAccountScheduledTransfers(ctx, account.ID)
+	})
+	switch {
+	case errors.Is(err, errAccountNotEmpty):
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeAccountNotEmpty, err))
+	case err != nil:
+		respondDBError(ctx, err, codeAccountNotFound)
+	default:
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+// closeAccount sweeps the remaining balance into another account in the same currency and deletes the account, so closing never needs an empty account first
+func (s *Server) closeAccount(ctx *gin.Context) {
+	var uriReq closeAccountUriReq
+	if !bindURI(ctx, &uriReq) {
+		return
+	}
+
+	var req closeAccountReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	if req.DestinationAccountNumber == uriReq.AccountNumber {
+		err := errors.New("destination must be another account")
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, uriReq.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != account.Owner {
+		err = fmt.Errorf("owner doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	destination, err := s.store.GetAccountByNumber(ctx, req.DestinationAccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+
+	// the store checks the destination organization and currency again with both accounts locked
+	result, err := s.store.CloseAccountTx(ctx, db.CloseAccountTxParams{
+		AccountID:     account.ID,
+		DestinationID: destination.ID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrOrganizationMismatch):
+			ctx.JSON(http.StatusForbidden, errorResponse(codeOtherOrganization, err))
+		case errors.Is(err, db.ErrCurrencyMismatch):
+			ctx.JSON(http.StatusBadRequest, errorResponse(codeCurrencyMismatch, err))
+		case errors.Is(err, db.ErrAccountFrozen):
+			ctx.JSON(http.StatusForbidden, errorResponse(codeAccountFrozen, err))
+		case errors.Is(err, db.ErrAccountHasHolds):
+			ctx.JSON(http.StatusConflict, errorResponse(codeAccountNotEmpty, err))
+		case errors.Is(err, db.ErrInsufficientBalance):
+			ctx.JSON(http.StatusConflict, errorResponse(codeInsufficientBalance, err))
+		default:
+			respondDBError(ctx, err, codeAccountNotFound)
+		}
+		return
+	}
+
+	format := requestAmountFormat(ctx)
+	rsp := closeAccountResponse{
+		SweptAmount: result.SweptAmount,
+		Destination: newAccountResponse(result.Destination, format),
+		format:      format,
+	}
+	if result.Transfer != nil {
+		transfer := newTransferResponse(*result.Transfer, newAccountNumbers(account, result.Destination), format)
+		rsp.Transfer = &transfer
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// updateAccountBalance adds the requested amount (positive or negative) to the account balance
+func (s *Server) updateAccountBalance(ctx *gin.Context) {
+	var uriReq updateAccountBalanceUriReq
+	if !bindURI(ctx, &uriReq) {
+		return
+	}
+
+	var req updateAccountBalanceReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, uriReq.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
 	}
 	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
 	if authPayload.UserName != account.Owner {
 		err = fmt.Errorf("owner doesn't belong to the authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
 		return
 	}
 
-	err = s.store.DeleteAccount(ctx, req.ID)
+	account, err = s.store.AddAccountBalanceTx(ctx, db.AddAccountBalanceTxParams{
+		AccountID:       account.ID,
+		Amount:          int64(req.Amount),
+		ExpectedVersion: req.Version,
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		if errors.Is(err, db.ErrInsufficientBalance) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(codeInsufficientBalance, err))
+			return
+		}
+		if errors.Is(err, db.ErrVersionConflict) {
+			ctx.JSON(http.StatusConflict, errorResponse(codeVersionConflict, err))
+			return
+		}
+		if errors.Is(err, db.ErrAccountFrozen) {
+			ctx.JSON(http.StatusForbidden, errorResponse(codeAccountFrozen, err))
+			return
+		}
+		respondDBError(ctx, err, codeAccountNotFound)
 	} else {
-		ctx.JSON(http.StatusOK, gin.H{"account": req.ID})
+		ctx.JSON(http.StatusOK, newAccountResponse(account, requestAmountFormat(ctx)))
+	}
+}
+
+// deposit adds external funds to the account and records the entry
+func (s *Server) deposit(ctx *gin.Context) {
+	s.addAccountEntry(ctx, 1)
+}
+
+// withdraw takes funds out of the account and records the entry, the withdrawals that would overdraw it are rejected
+func (s *Server) withdraw(ctx *gin.Context) {
+	s.addAccountEntry(ctx, -1)
+}
+
+// addAccountEntry moves the requested amount in (sign 1) or out (sign -1) of the authenticated user account
+func (s *Server) addAccountEntry(ctx *gin.Context, sign int64) {
+	var uriReq accountEntryUriReq
+	if !bindURI(ctx, &uriReq) {
+		return
 	}
+
+	var req accountEntryReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, uriReq.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != account.Owner {
+		err = fmt.Errorf("owner doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	result, err := s.store.EntryTx(ctx, db.EntryTxParams{
+		AccountID: account.ID,
+		Amount:    sign * int64(req.Amount),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientBalance) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(codeInsufficientBalance, err))
+			return
+		}
+		if errors.Is(err, db.ErrAccountFrozen) {
+			ctx.JSON(http.StatusForbidden, errorResponse(codeAccountFrozen, err))
+			return
+		}
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+
+	format := requestAmountFormat(ctx)
+	ctx.JSON(http.StatusOK, accountEntryResponse{
+		Account: newAccountResponse(result.Account, format),
+		Entry:   entryResponse{Entry: result.Entry, format: format},
+	})
 }
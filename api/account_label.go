@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"net/http"
+	"strings"
+)
+
+const (
+	maxAccountLabels      = 10
+	maxAccountLabelLength = 32
+)
+
+type (
+	setAccountLabelsUriReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	setAccountLabelsReq struct {
+		// Labels replaces every label of the account, an empty list removes them
+		Labels []string `json:"labels" binding:"required"`
+	}
+)
+
+// setAccountLabels replaces the labels the owner tags the account with, e.g. "savings" or "rent"
+func (s *Server) setAccountLabels(ctx *gin.Context) {
+	var uriReq setAccountLabelsUriReq
+	if !bindURI(ctx, &uriReq) {
+		return
+	}
+
+	var req setAccountLabelsReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	labels, err := normalizeAccountLabels(req.Labels)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, uriReq.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != account.Owner {
+		err = fmt.Errorf("owner doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	account, err = s.store.SetAccountLabels(ctx, db.SetAccountLabelsParams{
+		ID:     account.ID,
+		Labels: labels,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+	} else {
+		ctx.JSON(http.StatusOK, newAccountResponse(account, requestAmountFormat(ctx)))
+	}
+}
+
+// normalizeAccountLabel lowercases and trims the label, so "Rent" and "rent " both match the same accounts
+func normalizeAccountLabel(label string) (string, error) {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label == "" || len(label) > maxAccountLabelLength {
+		return "", fmt.Errorf("a label has from 1 to %v characters, got %q", maxAccountLabelLength, label)
+	}
+	return label, nil
+}
+
+// normalizeAccountLabels normalizes every label and drops the repeated ones, keeping their order
+func normalizeAccountLabels(labels []string) ([]string, error) {
+	normalized := make([]string, 0, len(labels))
+	seen := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		label, err := normalizeAccountLabel(label)
+		if err != nil {
+			return nil, err
+		}
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+
+	if len(normalized) > maxAccountLabels {
+		return nil, fmt.Errorf("an account holds up to %v labels, got %v", maxAccountLabels, len(normalized))
+	}
+	return normalized, nil
+}
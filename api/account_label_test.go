@@ -0,0 +1,204 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestSetAccountLabelsAPI(t *testing.T) {
+	user, _ := randomUser()
+	otherUser, _ := randomUser()
+	account := randomAccount(user.Username)
+
+	labeled := account
+	labeled.Labels = []string{"savings", "rent"}
+	labeled.Version++
+
+	tooManyLabels := make([]string, maxAccountLabels+1)
+	for i := range tooManyLabels {
+		tooManyLabels[i] = fmt.Sprintf("label%d", i)
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		accountNumber string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path set labels",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			// the labels are stored lowercase and only once
+			body: gin.H{"labels": []string{"Savings ", "rent", "savings"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountLabels(gomock.Any(), gomock.Eq(db.SetAccountLabelsParams{
+					ID:     account.ID,
+					Labels: []string{"savings", "rent"},
+				})).Times(1).Return(labeled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseAccount(t, recorder.Body, labeled)
+			},
+		},
+		{
+			name: "empty list removes the labels",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"labels": []string{}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountLabels(gomock.Any(), gomock.Eq(db.SetAccountLabelsParams{
+					ID:     account.ID,
+					Labels: []string{},
+				})).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "too many labels",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"labels": tooManyLabels},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().SetAccountLabels(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "blank label",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"labels": []string{"rent", " "}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountLabels(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "missing labels",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountLabels(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "not the account owner",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, otherUser.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"labels": []string{"rent"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountLabels(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeNotAccountOwner)
+			},
+		},
+		{
+			name: "account not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"labels": []string{"rent"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().SetAccountLabels(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountNotFound)
+			},
+		},
+		{
+			name:          "no authorization",
+			setupAuth:     func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"labels": []string{"rent"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountLabels(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%s/labels", tc.accountNumber)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"net/http"
+)
+
+type (
+	setAccountLimitsUriReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	setAccountLimitsReq struct {
+		// the amounts the account can send per UTC day and per UTC month in minor units, 0 removes the limit
+		DailyLimit   *int64 `json:"daily_limit" binding:"required,min=0"`
+		MonthlyLimit *int64 `json:"monthly_limit" binding:"required,min=0"`
+	}
+)
+
+// setAccountLimits replaces the daily and monthly outgoing limits the owner puts on the account, the transfers
+// that would send more are rejected
+func (s *Server) setAccountLimits(ctx *gin.Context) {
+	var uriReq setAccountLimitsUriReq
+	if !bindURI(ctx, &uriReq) {
+		return
+	}
+
+	var req setAccountLimitsReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	daily, monthly := *req.DailyLimit, *req.MonthlyLimit
+	if daily > 0 && monthly > 0 && daily > monthly {
+		err := fmt.Errorf("daily limit %v can't exceed the monthly limit %v", daily, monthly)
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, uriReq.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != account.Owner {
+		err = fmt.Errorf("owner doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	account, err = s.store.SetAccountSpendingLimits(ctx, db.SetAccountSpendingLimitsParams{
+		ID:           account.ID,
+		DailyLimit:   daily,
+		MonthlyLimit: monthly,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountResponse(account, requestAmountFormat(ctx)))
+}
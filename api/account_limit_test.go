@@ -0,0 +1,193 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestSetAccountLimitsAPI(t *testing.T) {
+	user, _ := randomUser()
+	otherUser, _ := randomUser()
+	account := randomAccount(user.Username)
+
+	limited := account
+	limited.DailyLimit = 10000
+	limited.MonthlyLimit = 100000
+	limited.Version++
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path set limits",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"daily_limit": 10000, "monthly_limit": 100000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountSpendingLimits(gomock.Any(), gomock.Eq(db.SetAccountSpendingLimitsParams{
+					ID:           account.ID,
+					DailyLimit:   10000,
+					MonthlyLimit: 100000,
+				})).Times(1).Return(limited, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseAccount(t, recorder.Body, limited)
+			},
+		},
+		{
+			name: "zero removes the limits",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			// only a monthly limit is kept
+			body: gin.H{"daily_limit": 0, "monthly_limit": 500},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountSpendingLimits(gomock.Any(), gomock.Eq(db.SetAccountSpendingLimitsParams{
+					ID:           account.ID,
+					DailyLimit:   0,
+					MonthlyLimit: 500,
+				})).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "daily limit above the monthly limit",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"daily_limit": 1000, "monthly_limit": 500},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().SetAccountSpendingLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "negative limit",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"daily_limit": -1, "monthly_limit": 500},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountSpendingLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "missing monthly limit",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"daily_limit": 100},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountSpendingLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "not the account owner",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, otherUser.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"daily_limit": 100, "monthly_limit": 1000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountSpendingLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeNotAccountOwner)
+			},
+		},
+		{
+			name: "account not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"daily_limit": 100, "monthly_limit": 1000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().SetAccountSpendingLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountNotFound)
+			},
+		},
+		{
+			name:      "no authorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			body:      gin.H{"daily_limit": 100, "monthly_limit": 1000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountSpendingLimits(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%s/limits", account.AccountNumber)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"net/http"
+)
+
+type (
+	setLowBalanceThresholdUriReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	setLowBalanceThresholdReq struct {
+		// the balance in minor units a transfer has to take the account below to alert the owner, 0 disables the alert
+		LowBalanceThreshold *int64 `json:"low_balance_threshold" binding:"required,min=0"`
+	}
+)
+
+// setLowBalanceThreshold replaces the threshold the owner is alerted about when a transfer takes the balance below it
+func (s *Server) setLowBalanceThreshold(ctx *gin.Context) {
+	var uriReq setLowBalanceThresholdUriReq
+	if !bindURI(ctx, &uriReq) {
+		return
+	}
+
+	var req setLowBalanceThresholdReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, uriReq.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != account.Owner {
+		err = fmt.Errorf("owner doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	account, err = s.store.SetAccountLowBalanceThreshold(ctx, db.SetAccountLowBalanceThresholdParams{
+		ID:                  account.ID,
+		LowBalanceThreshold: *req.LowBalanceThreshold,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountResponse(account, requestAmountFormat(ctx)))
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestSetLowBalanceThresholdAPI(t *testing.T) {
+	user, _ := randomUser()
+	otherUser, _ := randomUser()
+	account := randomAccount(user.Username)
+
+	updated := account
+	updated.LowBalanceThreshold = 5000
+	updated.Version++
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path set threshold",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"low_balance_threshold": 5000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountLowBalanceThreshold(gomock.Any(), gomock.Eq(db.SetAccountLowBalanceThresholdParams{
+					ID:                  account.ID,
+					LowBalanceThreshold: 5000,
+				})).Times(1).Return(updated, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseAccount(t, recorder.Body, updated)
+			},
+		},
+		{
+			name: "zero disables the alert",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"low_balance_threshold": 0},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountLowBalanceThreshold(gomock.Any(), gomock.Eq(db.SetAccountLowBalanceThresholdParams{
+					ID: account.ID,
+				})).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "negative threshold",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"low_balance_threshold": -1},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().SetAccountLowBalanceThreshold(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "missing threshold",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountLowBalanceThreshold(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "not the account owner",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, otherUser.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"low_balance_threshold": 5000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountLowBalanceThreshold(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeNotAccountOwner)
+			},
+		},
+		{
+			name: "account not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"low_balance_threshold": 5000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().SetAccountLowBalanceThreshold(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountNotFound)
+			},
+		},
+		{
+			name:      "no authorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			body:      gin.H{"low_balance_threshold": 5000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountLowBalanceThreshold(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%s/low_balance_threshold", account.AccountNumber)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
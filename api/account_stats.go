@@ -0,0 +1,83 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"net/http"
+	"time"
+)
+
+type (
+	getAccountStatsUriReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	// getAccountStatsReq range includes from and excludes to, like the statement one
+	getAccountStatsReq struct {
+		From time.Time `form:"from" binding:"required" time_format:"2006-01-02T15:04:05Z07:00"`
+		To   time.Time `form:"to" binding:"required" time_format:"2006-01-02T15:04:05Z07:00"`
+	}
+
+	accountStatsResponse struct {
+		AccountNumber string    `json:"account_number"`
+		From          time.Time `json:"from"`
+		To            time.Time `json:"to"`
+		db.GetAccountStatsRow
+	}
+)
+
+// getAccountStats returns the money the account received and sent within the date range and how many transfers it took part in
+// The outflow is positive, the net change is the inflow minus the outflow
+func (s *Server) getAccountStats(ctx *gin.Context) {
+	var uri getAccountStatsUriReq
+	if !bindURI(ctx, &uri) {
+		return
+	}
+
+	var req getAccountStatsReq
+	if !bindQuery(ctx, &req) {
+		return
+	}
+
+	if !req.From.Before(req.To) {
+		err := errors.New("from must be before to")
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, uri.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != account.Owner {
+		err = errors.New("account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	// the created_at columns have no time zone and are stored in UTC
+	stats, err := s.store.GetAccountStats(ctx, db.GetAccountStatsParams{
+		AccountID: account.ID,
+		FromTime:  req.From.UTC(),
+		ToTime:    req.To.UTC(),
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accountStatsResponse{
+		AccountNumber:      account.AccountNumber,
+		From:               req.From,
+		To:                 req.To,
+		GetAccountStatsRow: stats,
+	})
+}
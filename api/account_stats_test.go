@@ -0,0 +1,181 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestGetAccountStatsAPI(t *testing.T) {
+	user, _ := randomUser()
+	otherUser, _ := randomUser()
+	account := randomAccount(user.Username)
+
+	from := time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Second)
+	to := time.Now().UTC().Truncate(time.Second)
+
+	arg := db.GetAccountStatsParams{
+		AccountID: account.ID,
+		FromTime:  from,
+		ToTime:    to,
+	}
+	stats := db.GetAccountStatsRow{
+		TotalInflow:   500,
+		TotalOutflow:  120,
+		NetChange:     380,
+		TransferCount: 4,
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path account stats",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(from, to, ""),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountStats(gomock.Any(), gomock.Eq(arg)).Times(1).Return(stats, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp accountStatsResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, account.AccountNumber, rsp.AccountNumber)
+				require.True(t, from.Equal(rsp.From))
+				require.True(t, to.Equal(rsp.To))
+				require.Equal(t, stats, rsp.GetAccountStatsRow)
+			},
+		},
+		{
+			name: "from after to",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(to, from, ""),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetAccountStats(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "missing range",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: url.Values{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountStats(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "account doesn't belong to the authenticated user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, otherUser.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(from, to, ""),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountStats(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeNotAccountOwner)
+			},
+		},
+		{
+			name: "account not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(from, to, ""),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().GetAccountStats(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(from, to, ""),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountStats(gomock.Any(), gomock.Any()).Times(1).Return(db.GetAccountStatsRow{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:      "no authorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			query:     statementQuery(from, to, ""),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountStats(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/accounts/%s/stats?%s", account.AccountNumber, tc.query.Encode())
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -2,15 +2,22 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
 	"github.com/micaelapucciariello/simplebank/token"
 	"github.com/stretchr/testify/require"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -26,19 +33,19 @@ func TestGetAccountAPI(t *testing.T) {
 	testCases := []struct {
 		name          string
 		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
-		accountID     int64
+		accountNumber string
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(*testing.T, *httptest.ResponseRecorder)
 	}{
 		{
 			name: "happy path get account",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
-			accountID: account.ID,
+			accountNumber: account.AccountNumber,
 			buildStubs: func(store *mockdb.MockStore) {
 				// build stubs
-				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
 					Times(1).
 					Return(account, nil)
 			},
@@ -51,29 +58,68 @@ func TestGetAccountAPI(t *testing.T) {
 		{
 			name: "account not found",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
-			accountID: account.ID,
+			accountNumber: account.AccountNumber,
 			buildStubs: func(store *mockdb.MockStore) {
 				// build stubs
-				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
 					Times(1).
 					Return(db.Account{}, sql.ErrNoRows)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				// check response
 				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountNotFound)
+			},
+		},
+		{
+			name: "token minted before organizations",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				// the token carries no organization, its user belongs to the default one
+				addOrgAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, 0, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name: "account of another organization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				// a valid token and account number aren't enough to cross tenants
+				addOrgAuthorization(t, request, tokenMaker, _authorizationTypeBearer, utils.RandomOwner(), utils.DepositorRole, db.DefaultOrgID+1, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeOtherOrganization)
 			},
 		},
 		{
 			name: "unauthorized user",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "unauthorized_user", time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "unauthorized_user", utils.DepositorRole, time.Minute)
 			},
-			accountID: account.ID,
+			accountNumber: account.AccountNumber,
 			buildStubs: func(store *mockdb.MockStore) {
 				// build stubs
-				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
 					Times(1).
 					Return(account, nil)
 			},
@@ -83,12 +129,12 @@ func TestGetAccountAPI(t *testing.T) {
 			},
 		},
 		{
-			name:      "no authorization",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
-			accountID: account.ID,
+			name:          "no authorization",
+			setupAuth:     func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			accountNumber: account.AccountNumber,
 			buildStubs: func(store *mockdb.MockStore) {
 				// build stubs
-				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -99,29 +145,47 @@ func TestGetAccountAPI(t *testing.T) {
 		{
 			name: "internal server error",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
-			accountID: account.ID,
+			accountNumber: account.AccountNumber,
 			buildStubs: func(store *mockdb.MockStore) {
 				// build stubs
-				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
 					Times(1).
-					Return(db.Account{}, sql.ErrConnDone)
+					Return(db.Account{}, sql.ErrTxDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				// check response
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name: "database connection lost",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(db.Account{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireErrorCode(t, recorder, codeUnavailable)
+			},
+		},
 		{
 			name: "invalid request",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
-			accountID: 0,
+			accountNumber: "0",
 			buildStubs: func(store *mockdb.MockStore) {
 				// build stubs
-				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -143,7 +207,7 @@ func TestGetAccountAPI(t *testing.T) {
 			recorder := httptest.NewRecorder()
 			server := newTestServer(t, store)
 
-			url := fmt.Sprintf("/accounts/%d", tc.accountID)
+			url := fmt.Sprintf("/accounts/%s", tc.accountNumber)
 
 			request, err := http.NewRequest(http.MethodGet, url, nil)
 			// check request
@@ -159,6 +223,10 @@ func TestGetAccountAPI(t *testing.T) {
 func TestCreateAccountAPI(t *testing.T) {
 	user, _ := randomUser()
 	account := randomAccount(user.Username)
+	unsupportedCurrencyAccount := account
+	unsupportedCurrencyAccount.Currency = "XYZ"
+	mixedCaseOwnerAccount := account
+	mixedCaseOwnerAccount.Owner = strings.ToUpper(account.Owner)
 
 	testCases := []struct {
 		name          string
@@ -170,7 +238,7 @@ func TestCreateAccountAPI(t *testing.T) {
 		{
 			name: "happy path create account",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			account: account,
 			buildStubs: func(store *mockdb.MockStore) {
@@ -179,6 +247,7 @@ func TestCreateAccountAPI(t *testing.T) {
 					Owner:    account.Owner,
 					Balance:  0,
 					Currency: account.Currency,
+					OrgID:    db.DefaultOrgID,
 				})).
 					Times(1).
 					Return(account, nil)
@@ -189,10 +258,32 @@ func TestCreateAccountAPI(t *testing.T) {
 				validateResponseAccount(t, recorder.Body, account)
 			},
 		},
+		{
+			name: "mixed case owner",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			account: mixedCaseOwnerAccount,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(db.CreateAccountParams{
+					Owner:    account.Owner,
+					Balance:  0,
+					Currency: account.Currency,
+					OrgID:    db.DefaultOrgID,
+				})).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
 		{
 			name: "internal server error",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			account: account,
 			buildStubs: func(store *mockdb.MockStore) {
@@ -201,19 +292,55 @@ func TestCreateAccountAPI(t *testing.T) {
 					Owner:    account.Owner,
 					Balance:  0,
 					Currency: account.Currency,
+					OrgID:    db.DefaultOrgID,
 				})).
 					Times(1).
-					Return(db.Account{}, sql.ErrConnDone)
+					Return(db.Account{}, sql.ErrTxDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				// check response
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name: "duplicate currency",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			account: account,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, &pq.Error{Code: "23505", Constraint: "owner_currency_key"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.JSONEq(t, `{"code": "account_already_exists", "message": "account with this currency already exists"}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "owner doesn't exist",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			account: account,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, &pq.Error{Code: "23503"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
 		{
 			name: "unauthorized user",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "unauthorized_user", time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "unauthorized_user", utils.DepositorRole, time.Minute)
 			},
 			account: account,
 			buildStubs: func(store *mockdb.MockStore) {
@@ -222,6 +349,7 @@ func TestCreateAccountAPI(t *testing.T) {
 					Owner:    account.Owner,
 					Balance:  0,
 					Currency: account.Currency,
+					OrgID:    db.DefaultOrgID,
 				})).
 					Times(0)
 			},
@@ -230,6 +358,97 @@ func TestCreateAccountAPI(t *testing.T) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
+		{
+			name: "unsupported currency",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			account: unsupportedCurrencyAccount,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				rsp := requireErrorCode(t, recorder, codeInvalidRequest)
+				require.Equal(t, []fieldError{{
+					Field:   "currency",
+					Rule:    "currency",
+					Message: "currency must be a supported currency",
+				}}, rsp.Details)
+			},
+		},
+		{
+			name: "idempotency key first request",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+				request.Header.Set(idempotencyKeyHeader, "key-1")
+			},
+			account: account,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					IdempotentCreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, params db.IdempotentCreateAccountTxParams) (db.IdempotentCreateAccountTxResult, error) {
+						require.Equal(t, account.Owner, params.Owner)
+						require.Equal(t, account.Currency, params.Currency)
+						require.Equal(t, "key-1", params.IdempotencyKey)
+						require.NotEmpty(t, params.RequestHash)
+						return db.IdempotentCreateAccountTxResult{Account: account}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, recorder.Header().Get(idempotencyReplayedHeader))
+				validateResponseAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name: "idempotency key replayed",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+				request.Header.Set(idempotencyKeyHeader, "key-1")
+			},
+			account: account,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().
+					IdempotentCreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotentCreateAccountTxResult{Account: account, Replayed: true}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "true", recorder.Header().Get(idempotencyReplayedHeader))
+				validateResponseAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name: "idempotency key reused with a different currency",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+				request.Header.Set(idempotencyKeyHeader, "key-1")
+			},
+			account: account,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().
+					IdempotentCreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotentCreateAccountTxResult{}, db.ErrIdempotencyKeyMismatch)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeIdempotencyMismatch)
+			},
+		},
 		{
 			name:      "no authorization",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
@@ -260,7 +479,7 @@ func TestCreateAccountAPI(t *testing.T) {
 
 			url := fmt.Sprintf("/accounts")
 
-			body := fmt.Sprintf(`{"owner": "%v", "currency": "%v"}`, account.Owner, account.Currency)
+			body := fmt.Sprintf(`{"owner": "%v", "currency": "%v"}`, tc.account.Owner, tc.account.Currency)
 			jsonBody := []byte(body)
 			bodyReader := bytes.NewReader(jsonBody)
 
@@ -275,96 +494,119 @@ func TestCreateAccountAPI(t *testing.T) {
 	}
 }
 
-func TestDeleteAccountAPI(t *testing.T) {
+func TestCreateAccountInitialBalanceAPI(t *testing.T) {
 	user, _ := randomUser()
 	account := randomAccount(user.Username)
+	initialBalance := utils.RandomInt(1, 1000)
+	opened := account
+	opened.Balance = initialBalance
+
+	arg := db.CreateAccountParams{
+		Owner:    account.Owner,
+		Balance:  initialBalance,
+		Currency: account.Currency,
+		OrgID:    db.DefaultOrgID,
+	}
+	// the queries the transaction runs go to the same mock store
+	execTx := func(store *mockdb.MockStore) *gomock.Call {
+		store.EXPECT().AccountNumberExists(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+		return store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).
+			DoAndReturn(func(ctx context.Context, fn func(db.Querier) error) error {
+				return fn(store)
+			})
+	}
 
 	testCases := []struct {
 		name          string
-		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
-		accountID     int64
+		body          gin.H
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(*testing.T, *httptest.ResponseRecorder)
 	}{
 		{
-			name: "happy path delete account",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
-			},
-			accountID: account.ID,
+			name: "opening entry",
+			body: gin.H{"owner": account.Owner, "currency": account.Currency, "initial_balance": initialBalance},
 			buildStubs: func(store *mockdb.MockStore) {
-				// build stubs
-				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
-					Times(1).
-					Return(account, nil)
-				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).
-					Times(1).
-					Return(nil)
+				execTx(store)
+				store.EXPECT().CreateAccount(gomock.Any(), EqCreateAccountParams(arg)).Times(1).Return(opened, nil)
+				store.EXPECT().CreateEntry(gomock.Any(), gomock.Eq(db.CreateEntryParams{
+					AccountID: opened.ID,
+					Amount:    initialBalance,
+				})).Times(1).Return(db.Entry{AccountID: opened.ID, Amount: initialBalance}, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				// check response
 				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseAccount(t, recorder.Body, opened)
 			},
 		},
 		{
-			name: "internal server error",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
+			name: "decimal initial balance",
+			body: gin.H{"owner": account.Owner, "currency": account.Currency, "initial_balance": utils.Money(initialBalance).String()},
+			buildStubs: func(store *mockdb.MockStore) {
+				execTx(store)
+				store.EXPECT().CreateAccount(gomock.Any(), EqCreateAccountParams(arg)).Times(1).Return(opened, nil)
+				store.EXPECT().CreateEntry(gomock.Any(), gomock.Eq(db.CreateEntryParams{
+					AccountID: opened.ID,
+					Amount:    initialBalance,
+				})).Times(1).Return(db.Entry{AccountID: opened.ID, Amount: initialBalance}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseAccount(t, recorder.Body, opened)
 			},
-			accountID: account.ID,
+		},
+		{
+			name: "entry insert fails",
+			body: gin.H{"owner": account.Owner, "currency": account.Currency, "initial_balance": initialBalance},
 			buildStubs: func(store *mockdb.MockStore) {
-				// build stubs
-				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
-					Times(1).
-					Return(account, nil)
-				store.EXPECT().DeleteAccount(gomock.Any(), account.ID).
-					Times(1).
-					Return(sql.ErrConnDone)
+				execTx(store)
+				store.EXPECT().CreateAccount(gomock.Any(), EqCreateAccountParams(arg)).Times(1).Return(opened, nil)
+				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(1).Return(db.Entry{}, sql.ErrTxDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				// check response
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				// the account rolled back with the entry, it isn't returned
+				require.NotContains(t, recorder.Body.String(), `"id"`)
 			},
 		},
 		{
-			name: "unauthorized user",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "invalid username", time.Minute)
-			},
-			accountID: account.ID,
+			name: "zero initial balance",
+			body: gin.H{"owner": account.Owner, "currency": account.Currency, "initial_balance": 0},
 			buildStubs: func(store *mockdb.MockStore) {
-				// build stubs
-				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
-					Times(1).
-					Return(account, nil)
-				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).
-					Times(0)
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(db.CreateAccountParams{
+					Owner:    account.Owner,
+					Currency: account.Currency,
+					OrgID:    db.DefaultOrgID,
+				})).Times(1).Return(account, nil)
+				store.EXPECT().CreateEntry(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				// check response
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusOK, recorder.Code)
 			},
 		},
 		{
-			name:      "no authorization",
-			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
-			accountID: account.ID,
+			name: "negative initial balance",
+			body: gin.H{"owner": account.Owner, "currency": account.Currency, "initial_balance": -1},
 			buildStubs: func(store *mockdb.MockStore) {
-				// build stubs
-				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
-					Times(0)
-				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
-					Times(0)
+				store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				// check response
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				rsp := requireErrorCode(t, recorder, codeInvalidRequest)
+				require.Equal(t, "initial_balance", rsp.Details[0].Field)
 			},
 		},
 	}
 
 	for i := range testCases {
 		tc := testCases[i]
+
 		t.Run(tc.name, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			defer ctrl.Finish()
@@ -374,36 +616,1593 @@ func TestDeleteAccountAPI(t *testing.T) {
 			recorder := httptest.NewRecorder()
 			server := newTestServer(t, store)
 
-			url := fmt.Sprintf("/accounts/%d", tc.accountID)
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
 
-			request, err := http.NewRequest(http.MethodDelete, url, nil)
-			// check request
+			request, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(data))
 			require.NoError(t, err)
+			addAuthorization(t, request, server.token, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 
-			tc.setupAuth(t, request, server.token)
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(t, recorder)
 		})
 	}
 }
 
-func randomAccount(owner string) db.Account {
-	account := db.Account{
-		Owner:    owner,
-		Balance:  utils.RandomBalance(),
-		Currency: utils.USD,
-		ID:       utils.RandomInt(1, 1000),
+func TestCreateAccountDefaultCurrencyAPI(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+	account.Currency = utils.EUR
+
+	testCases := []struct {
+		name       string
+		body       gin.H
+		buildStubs func(store *mockdb.MockStore)
+		code       int
+	}{
+		{
+			name: "missing currency uses the default",
+			body: gin.H{"owner": account.Owner},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(db.CreateAccountParams{
+					Owner:    account.Owner,
+					Currency: utils.EUR,
+					OrgID:    db.DefaultOrgID,
+				})).Times(1).Return(account, nil)
+			},
+			code: http.StatusOK,
+		},
+		{
+			name: "given currency wins over the default",
+			body: gin.H{"owner": account.Owner, "currency": utils.ARS},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(db.CreateAccountParams{
+					Owner:    account.Owner,
+					Currency: utils.ARS,
+					OrgID:    db.DefaultOrgID,
+				})).Times(1).Return(account, nil)
+			},
+			code: http.StatusOK,
+		},
+		{
+			name: "unsupported currency",
+			body: gin.H{"owner": account.Owner, "currency": "XYZ"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: http.StatusBadRequest,
+		},
 	}
 
-	return account
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+			server.config.DefaultCurrency = utils.EUR
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.token, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			// check response
+			require.Equal(t, tc.code, recorder.Code)
+		})
+	}
 }
 
-func validateResponseAccount(t *testing.T, body *bytes.Buffer, acc db.Account) {
-	data, err := ioutil.ReadAll(body)
-	require.NoError(t, err)
+func TestListAccountsAPI(t *testing.T) {
+	user, _ := randomUser()
 
-	var rspAccount db.Account
-	err = json.Unmarshal(data, &rspAccount)
-	require.NoError(t, err)
-	require.Equal(t, acc, rspAccount)
+	n := 5
+	accounts := make([]db.Account, n)
+	for i := 0; i < n; i++ {
+		accounts[i] = randomAccount(user.Username)
+	}
+	total := 3 * n
+
+	type query struct {
+		pageID   int
+		pageSize int
+		sort     string
+		currency string
+		label    string
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		query         query
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path list accounts",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsParams{
+					Owner:  user.Username,
+					SortBy: "id",
+					Limit:  int32(n),
+					Offset: 0,
+				}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts, nil)
+				store.EXPECT().CountAccounts(gomock.Any(), gomock.Eq(db.CountAccountsParams{Owner: user.Username})).Times(1).Return(int64(total), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp struct {
+					Data     []db.Account `json:"data"`
+					PageID   int32        `json:"page_id"`
+					PageSize int32        `json:"page_size"`
+					Total    int64        `json:"total"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, publicAccounts(accounts), rsp.Data)
+				require.Equal(t, int32(1), rsp.PageID)
+				require.Equal(t, int32(n), rsp.PageSize)
+				// the total counts every account of the owner, not only the ones in the page
+				require.Equal(t, int64(total), rsp.Total)
+			},
+		},
+		{
+			name: "sorted by descending balance",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 2, pageSize: n, sort: "-balance"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsParams{
+					Owner:  user.Username,
+					SortBy: "-balance",
+					Limit:  int32(n),
+					Offset: int32(n),
+				}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts, nil)
+				store.EXPECT().CountAccounts(gomock.Any(), gomock.Eq(db.CountAccountsParams{Owner: user.Username})).Times(1).Return(int64(total), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "filtered by currency",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n, currency: utils.EUR},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsByCurrencyParams{
+					Owner:    user.Username,
+					Currency: utils.EUR,
+					SortBy:   "id",
+					Limit:    int32(n),
+					Offset:   0,
+				}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountsByCurrency(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.Account{}, nil)
+				store.EXPECT().CountAccounts(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CountAccountsByCurrency(gomock.Any(), gomock.Eq(db.CountAccountsByCurrencyParams{
+					Owner:    user.Username,
+					Currency: utils.EUR,
+				})).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"data": [], "page_id": 1, "page_size": 5, "total": 0}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "filtered by currency sorted by descending balance",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n, currency: utils.EUR, sort: "-balance"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsByCurrencyParams{
+					Owner:    user.Username,
+					Currency: utils.EUR,
+					SortBy:   "-balance",
+					Limit:    int32(n),
+					Offset:   0,
+				}
+				store.EXPECT().ListAccountsByCurrency(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.Account{}, nil)
+				store.EXPECT().CountAccountsByCurrency(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "filtered by label",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n, label: " Savings"},
+			buildStubs: func(store *mockdb.MockStore) {
+				label := sql.NullString{String: "savings", Valid: true}
+				arg := db.ListAccountsParams{
+					Owner:  user.Username,
+					Label:  label,
+					SortBy: "id",
+					Limit:  int32(n),
+					Offset: 0,
+				}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts[:1], nil)
+				store.EXPECT().CountAccounts(gomock.Any(), gomock.Eq(db.CountAccountsParams{
+					Owner: user.Username,
+					Label: label,
+				})).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "filtered by currency and label",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n, currency: utils.EUR, label: "rent"},
+			buildStubs: func(store *mockdb.MockStore) {
+				label := sql.NullString{String: "rent", Valid: true}
+				arg := db.ListAccountsByCurrencyParams{
+					Owner:    user.Username,
+					Currency: utils.EUR,
+					Label:    label,
+					SortBy:   "id",
+					Limit:    int32(n),
+					Offset:   0,
+				}
+				store.EXPECT().ListAccountsByCurrency(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.Account{}, nil)
+				store.EXPECT().CountAccountsByCurrency(gomock.Any(), gomock.Eq(db.CountAccountsByCurrencyParams{
+					Owner:    user.Username,
+					Currency: utils.EUR,
+					Label:    label,
+				})).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "label too long",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n, label: strings.Repeat("a", maxAccountLabelLength+1)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "unsupported currency filter",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n, currency: "XYZ"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountsByCurrency(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "unknown sort key",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n, sort: "owner"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(1).Return([]db.Account{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "count internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(1).Return(accounts, nil)
+				store.EXPECT().CountAccounts(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:      "no authorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			query:     query{pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodGet, "/accounts", nil)
+			require.NoError(t, err)
+
+			q := request.URL.Query()
+			q.Add("page_id", fmt.Sprintf("%d", tc.query.pageID))
+			q.Add("page_size", fmt.Sprintf("%d", tc.query.pageSize))
+			if tc.query.sort != "" {
+				q.Add("sort", tc.query.sort)
+			}
+			if tc.query.currency != "" {
+				q.Add("currency", tc.query.currency)
+			}
+			if tc.query.label != "" {
+				q.Add("label", tc.query.label)
+			}
+			request.URL.RawQuery = q.Encode()
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAccountsAfterNumberAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	n := 5
+	accounts := make([]db.Account, n+1)
+	for i := range accounts {
+		accounts[i] = randomAccount(user.Username)
+		// the pages follow the ascending account numbers
+		accounts[i].AccountNumber = fmt.Sprintf("%016d", 10+i)
+	}
+
+	type cursorResponse struct {
+		Data            []db.Account `json:"data"`
+		Limit           int32        `json:"limit"`
+		NextAfterNumber *string      `json:"next_after_number"`
+	}
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "first page",
+			query: url.Values{"after_number": {""}, "limit": {fmt.Sprint(n)}},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsAfterNumberParams{
+					Owner:       user.Username,
+					AfterNumber: "",
+					Limit:       int32(n + 1),
+				}
+				store.EXPECT().ListAccountsAfterNumber(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts, nil)
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CountAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp cursorResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				// the extra account only signals a next page, it isn't returned
+				require.Equal(t, publicAccounts(accounts[:n]), rsp.Data)
+				require.Equal(t, int32(n), rsp.Limit)
+				require.NotNil(t, rsp.NextAfterNumber)
+				require.Equal(t, accounts[n-1].AccountNumber, *rsp.NextAfterNumber)
+			},
+		},
+		{
+			name:  "last page",
+			query: url.Values{"after_number": {accounts[n-1].AccountNumber}, "limit": {fmt.Sprint(n)}},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsAfterNumberParams{
+					Owner:       user.Username,
+					AfterNumber: accounts[n-1].AccountNumber,
+					Limit:       int32(n + 1),
+				}
+				store.EXPECT().ListAccountsAfterNumber(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts[n:], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp cursorResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, publicAccounts(accounts[n:]), rsp.Data)
+				require.Nil(t, rsp.NextAfterNumber)
+			},
+		},
+		{
+			name:  "past the last account",
+			query: url.Values{"after_number": {accounts[n].AccountNumber}, "limit": {fmt.Sprint(n)}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsAfterNumber(gomock.Any(), gomock.Any()).Times(1).Return([]db.Account{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, fmt.Sprintf(`{"data": [], "limit": %d, "next_after_number": null}`, n), recorder.Body.String())
+			},
+		},
+		{
+			name:  "filtered by currency and label",
+			query: url.Values{"after_number": {""}, "limit": {fmt.Sprint(n)}, "currency": {utils.EUR}, "label": {" Rent"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsAfterNumberParams{
+					Owner:    user.Username,
+					Currency: sql.NullString{String: utils.EUR, Valid: true},
+					Label:    sql.NullString{String: "rent", Valid: true},
+					Limit:    int32(n + 1),
+				}
+				store.EXPECT().ListAccountsAfterNumber(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.Account{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "sort can't be combined with the cursor",
+			query: url.Values{"after_number": {""}, "limit": {fmt.Sprint(n)}, "sort": {"-balance"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsAfterNumber(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name:  "sort by id can't be combined with the cursor either",
+			query: url.Values{"after_number": {""}, "limit": {fmt.Sprint(n)}, "sort": {"id"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsAfterNumber(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name:  "missing limit",
+			query: url.Values{"after_number": {""}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsAfterNumber(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "cursor isn't an account number",
+			query: url.Values{"after_number": {"10"}, "limit": {fmt.Sprint(n)}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsAfterNumber(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "internal server error",
+			query: url.Values{"after_number": {""}, "limit": {fmt.Sprint(n)}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsAfterNumber(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodGet, "/accounts?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.token, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDeleteAccountAPI(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+	account.Balance = 0
+	fundedAccount := randomAccount(user.Username)
+	fundedAccount.Balance = utils.RandomInt(1, 1000)
+
+	// the queries the transaction runs go to the same mock store
+	execTx := func(store *mockdb.MockStore) {
+		store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).
+			DoAndReturn(func(ctx context.Context, fn func(db.Querier) error) error {
+				return fn(store)
+			})
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		accountNumber string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path delete account",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+				execTx(store)
+				store.EXPECT().SoftDeleteAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().CancelAccountScheduledTransfers(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "account not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().SoftDeleteAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "non-zero balance",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: fundedAccount.AccountNumber,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(fundedAccount.AccountNumber)).
+					Times(1).
+					Return(fundedAccount, nil)
+				// the balance is checked by the delete, the account read may be stale
+				execTx(store)
+				store.EXPECT().SoftDeleteAccount(gomock.Any(), gomock.Eq(fundedAccount.ID)).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().CancelAccountScheduledTransfers(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountNotEmpty)
+			},
+		},
+		{
+			name: "internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+				execTx(store)
+				store.EXPECT().SoftDeleteAccount(gomock.Any(), account.ID).
+					Times(1).
+					Return(int64(0), sql.ErrTxDone)
+				store.EXPECT().CancelAccountScheduledTransfers(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "unauthorized user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "invalid username", utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().SoftDeleteAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:          "no authorization",
+			setupAuth:     func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			accountNumber: account.AccountNumber,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/accounts/%s", tc.accountNumber)
+
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			// check request
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCloseAccountAPI(t *testing.T) {
+	user, _ := randomUser()
+	otherUser, _ := randomUser()
+	account := randomAccount(user.Username)
+	destination := randomAccount(user.Username)
+	destination.Currency = account.Currency
+
+	swept := db.Transfer{
+		ID:            utils.RandomInt(1, 1000),
+		FromAccountID: account.ID,
+		ToAccountID:   destination.ID,
+		Amount:        account.Balance,
+	}
+	sweptDestination := destination
+	sweptDestination.Balance += account.Balance
+
+	arg := db.CloseAccountTxParams{
+		AccountID:     account.ID,
+		DestinationID: destination.ID,
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path close account",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"destination_account_number": destination.AccountNumber},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(destination.AccountNumber)).Times(1).Return(destination, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CloseAccountTxResult{SweptAmount: account.Balance, Transfer: &swept, Destination: sweptDestination}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp closeAccountResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, account.Balance, rsp.SweptAmount)
+				require.NotNil(t, rsp.Transfer)
+				require.Equal(t, account.Balance, rsp.Transfer.Amount)
+				require.Equal(t, sweptDestination.Balance, rsp.Destination.Balance)
+			},
+		},
+		{
+			name: "empty account has no transfer",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"destination_account_number": destination.AccountNumber},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(destination.AccountNumber)).Times(1).Return(destination, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CloseAccountTxResult{Destination: destination}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp closeAccountResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Zero(t, rsp.SweptAmount)
+				require.Nil(t, rsp.Transfer)
+			},
+		},
+		{
+			name: "currency mismatch",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"destination_account_number": destination.AccountNumber},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(destination.AccountNumber)).Times(1).Return(destination, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CloseAccountTxResult{}, db.ErrCurrencyMismatch)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeCurrencyMismatch)
+			},
+		},
+		{
+			name: "destination in another organization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"destination_account_number": destination.AccountNumber},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(destination.AccountNumber)).Times(1).Return(destination, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CloseAccountTxResult{}, db.ErrOrganizationMismatch)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeOtherOrganization)
+			},
+		},
+		{
+			name: "account with holds",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"destination_account_number": destination.AccountNumber},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(destination.AccountNumber)).Times(1).Return(destination, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.CloseAccountTxResult{}, db.ErrAccountHasHolds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountNotEmpty)
+			},
+		},
+		{
+			name: "account doesn't belong to the authenticated user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, otherUser.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"destination_account_number": destination.AccountNumber},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeNotAccountOwner)
+			},
+		},
+		{
+			name: "destination not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"destination_account_number": destination.AccountNumber},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(destination.AccountNumber)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountNotFound)
+			},
+		},
+		{
+			name: "destination is the account itself",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{"destination_account_number": account.AccountNumber},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name:      "no authorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			body:      gin.H{"destination_account_number": destination.AccountNumber},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CloseAccountTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			jsonBody, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%s/close", account.AccountNumber)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(jsonBody))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateAccountBalanceAPI(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+	amount := utils.RandomInt(1, 100)
+
+	updatedAccount := account
+	updatedAccount.Balance += amount
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		accountNumber string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path update account balance",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Eq(db.AddAccountBalanceTxParams{
+					AccountID: account.ID,
+					Amount:    amount,
+				})).
+					Times(1).
+					Return(updatedAccount, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseAccount(t, recorder.Body, updatedAccount)
+			},
+		},
+		{
+			name: "happy path expected version",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"amount": amount, "version": account.Version},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Eq(db.AddAccountBalanceTxParams{
+					AccountID:       account.ID,
+					Amount:          amount,
+					ExpectedVersion: account.Version,
+				})).
+					Times(1).
+					Return(updatedAccount, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseAccount(t, recorder.Body, updatedAccount)
+			},
+		},
+		{
+			name: "stale version",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"amount": amount, "version": account.Version},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, db.ErrVersionConflict)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeVersionConflict)
+			},
+		},
+		{
+			name: "frozen account",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+				// the freeze is checked by the store once the account is locked
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, db.ErrAccountFrozen)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountFrozen)
+			},
+		},
+		{
+			name: "invalid version",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"amount": amount, "version": -1},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "negative resulting balance",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"amount": -(account.Balance + 1)},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, db.ErrInsufficientBalance)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "account not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "unauthorized user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "unauthorized_user", utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "invalid amount",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"amount": 0},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:          "no authorization",
+			setupAuth:     func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/accounts/%s/balance", tc.accountNumber)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			// check request
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// TestUpdateAccountBalanceAPIRace sends two read-modify-write updates based on the same version, the store stub checks the version the way the store does
+func TestUpdateAccountBalanceAPIRace(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+	account.Version = 1
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	var mu sync.Mutex
+	current := account
+	store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(2).Return(account, nil)
+	store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(ctx context.Context, arg db.AddAccountBalanceTxParams) (db.Account, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if arg.ExpectedVersion != current.Version {
+				return db.Account{}, db.ErrVersionConflict
+			}
+			current.Balance += arg.Amount
+			current.Version++
+			return current, nil
+		})
+
+	server := newTestServer(t, store)
+	url := fmt.Sprintf("/accounts/%s/balance", account.AccountNumber)
+
+	n := 2
+	codes := make(chan int)
+	for i := 0; i < n; i++ {
+		go func() {
+			data, err := json.Marshal(gin.H{"amount": 10, "version": account.Version})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthorization(t, request, server.token, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			codes <- recorder.Code
+		}()
+	}
+
+	got := []int{<-codes, <-codes}
+	require.ElementsMatch(t, []int{http.StatusOK, http.StatusConflict}, got)
+	require.Equal(t, account.Balance+10, current.Balance)
+}
+
+type eqCreateAccountParamsMatcher struct {
+	arg db.CreateAccountParams
+}
+
+// Matches accepts any account number the handler picked, as long as it is one
+func (e eqCreateAccountParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateAccountParams)
+	if !ok || len(arg.AccountNumber) != utils.AccountNumberLength {
+		return false
+	}
+	e.arg.AccountNumber = arg.AccountNumber
+	return reflect.DeepEqual(e.arg, arg)
+}
+
+func EqCreateAccountParams(arg db.CreateAccountParams) gomock.Matcher {
+	return eqCreateAccountParamsMatcher{arg: arg}
+}
+
+func (e eqCreateAccountParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v with a new account number", e.arg)
+}
+
+func randomAccount(owner string) db.Account {
+	account := db.Account{
+		Owner:         owner,
+		Balance:       utils.RandomBalance(),
+		Currency:      utils.USD,
+		ID:            utils.RandomInt(1, 1000),
+		AccountNumber: utils.RandomAccountNumber(),
+		OrgID:         db.DefaultOrgID,
+	}
+
+	return account
+}
+
+func validateResponseAccount(t *testing.T, body *bytes.Buffer, acc db.Account) {
+	data, err := ioutil.ReadAll(body)
+	require.NoError(t, err)
+
+	// the internal id is never sent, the account is only known by its number
+	require.NotContains(t, string(data), `"id"`)
+
+	var rspAccount db.Account
+	err = json.Unmarshal(data, &rspAccount)
+	require.NoError(t, err)
+	require.Equal(t, publicAccount(acc), rspAccount)
+}
+
+// publicAccount is the account as a client reads it, without its internal id
+func publicAccount(account db.Account) db.Account {
+	account.ID = 0
+	return account
+}
+
+func publicAccounts(accounts []db.Account) []db.Account {
+	rsp := make([]db.Account, len(accounts))
+	for i, account := range accounts {
+		rsp[i] = publicAccount(account)
+	}
+	return rsp
+}
+
+func TestAccountEntryAPI(t *testing.T) {
+	user, _ := randomUser()
+	otherUser, _ := randomUser()
+	account := randomAccount(user.Username)
+	amount := utils.RandomInt(1, 100)
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		operation     string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path deposit",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			operation: "deposit",
+			body:      gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				updatedAccount := account
+				updatedAccount.Balance += amount
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().EntryTx(gomock.Any(), gomock.Eq(db.EntryTxParams{
+					AccountID: account.ID,
+					Amount:    amount,
+				})).
+					Times(1).
+					Return(db.EntryTxResult{
+						Account: updatedAccount,
+						Entry:   db.Entry{ID: utils.RandomInt(1, 1000), AccountID: account.ID, Amount: amount},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp db.EntryTxResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, account.Balance+amount, rsp.Account.Balance)
+				require.Equal(t, amount, rsp.Entry.Amount)
+			},
+		},
+		{
+			name: "happy path withdraw",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			operation: "withdraw",
+			body:      gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				// the withdrawn amount is recorded as a negative entry
+				store.EXPECT().EntryTx(gomock.Any(), gomock.Eq(db.EntryTxParams{
+					AccountID: account.ID,
+					Amount:    -amount,
+				})).
+					Times(1).
+					Return(db.EntryTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "withdraw overdraft",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			operation: "withdraw",
+			body:      gin.H{"amount": account.Balance + 1},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().EntryTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EntryTxResult{}, db.ErrInsufficientBalance)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "withdraw from a frozen account",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			operation: "withdraw",
+			body:      gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().EntryTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EntryTxResult{}, db.ErrAccountFrozen)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountFrozen)
+			},
+		},
+		{
+			name: "deposit into a frozen account",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			operation: "deposit",
+			body:      gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().EntryTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EntryTxResult{}, db.ErrAccountFrozen)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountFrozen)
+			},
+		},
+		{
+			name: "non positive amount",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			operation: "deposit",
+			body:      gin.H{"amount": -amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().EntryTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "account not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			operation: "deposit",
+			body:      gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().EntryTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "unauthorized user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, otherUser.Username, utils.DepositorRole, time.Minute)
+			},
+			operation: "withdraw",
+			body:      gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().EntryTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "no authorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			operation: "deposit",
+			body:      gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().EntryTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			operation: "deposit",
+			body:      gin.H{"amount": amount},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().EntryTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EntryTxResult{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/accounts/%s/%s", account.AccountNumber, tc.operation)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
 }
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"net/http"
+	"strings"
+)
+
+// likeEscaper escapes the LIKE wildcards, so a search for "a_b" doesn't match "axb"
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type (
+	listAllAccountsReq struct {
+		Owner string `form:"owner" binding:"omitempty,username"`
+	}
+
+	restoreAccountReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	searchUsersReq struct {
+		Query string `form:"q" binding:"required,min=2,max=100"`
+	}
+
+	freezeAccountReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	transferOwnershipUriReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	transferOwnershipReq struct {
+		NewOwner string `json:"new_owner" binding:"required,username"`
+	}
+
+	transferOwnershipResponse struct {
+		Account accountResponse            `json:"account"`
+		Change  accountOwnerChangeResponse `json:"change"`
+	}
+
+	// accountOwnerChangeResponse leaves out the internal id of the account, the response already carries the account
+	accountOwnerChangeResponse struct {
+		db.AccountOwnerChange
+		AccountID *int64 `json:"account_id,omitempty"`
+	}
+)
+
+// listAllAccounts lists the accounts of every owner, optionally filtered by one of them
+// It is meant for support staff, so it is only reachable by bankers
+func (s *Server) listAllAccounts(ctx *gin.Context) {
+	var req listAllAccountsReq
+	if !bindQuery(ctx, &req) {
+		return
+	}
+
+	page, ok := parsePagination(ctx)
+	if !ok {
+		return
+	}
+
+	// bankers only see the accounts of their own organization
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	owner := sql.NullString{String: utils.NormalizeUsername(req.Owner), Valid: req.Owner != ""}
+	accounts, err := s.store.ListAllAccounts(ctx, db.ListAllAccountsParams{
+		OrgID:  authPayload.OrgID,
+		Owner:  owner,
+		Limit:  page.PageSize,
+		Offset: page.offset(),
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	total, err := s.store.CountAllAccounts(ctx, db.CountAllAccountsParams{
+		OrgID: authPayload.OrgID,
+		Owner: owner,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	rsp := newAccountResponses(accounts, requestAmountFormat(ctx))
+	ctx.JSON(http.StatusOK, newListResponse(rsp, page.PageID, page.PageSize, total))
+}
+
+// restoreAccount brings back a soft-deleted account, it is only reachable by bankers
+// An account of another organization is reported as not found, like one that doesn't exist
+func (s *Server) restoreAccount(ctx *gin.Context) {
+	var req restoreAccountReq
+	if !bindURI(ctx, &req) {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	account, err := s.store.RestoreAccount(ctx, db.RestoreAccountParams{
+		AccountNumber: req.AccountNumber,
+		OrgID:         authPayload.OrgID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = fmt.Errorf("account [%v] doesn't exist or isn't deleted", req.AccountNumber)
+			ctx.JSON(http.StatusNotFound, errorResponse(codeAccountNotFound, err))
+			return
+		}
+		// a unique_violation means the owner opened a new account in the same currency since this one was deleted
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountResponse(account, requestAmountFormat(ctx)))
+}
+
+// freezeAccount puts a compliance hold on the account, it can't send nor receive transfers until it is unfrozen
+func (s *Server) freezeAccount(ctx *gin.Context) {
+	s.setAccountFrozen(ctx, true)
+}
+
+// unfreezeAccount lifts the compliance hold of the account
+func (s *Server) unfreezeAccount(ctx *gin.Context) {
+	s.setAccountFrozen(ctx, false)
+}
+
+func (s *Server) setAccountFrozen(ctx *gin.Context, frozen bool) {
+	var req freezeAccountReq
+	if !bindURI(ctx, &req) {
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, req.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	account, err = s.store.SetAccountFrozenTx(ctx, db.SetAccountFrozenTxParams{
+		// an account of another organization isn't found, like one that doesn't exist
+		SetAccountFrozenParams: db.SetAccountFrozenParams{
+			ID:       account.ID,
+			IsFrozen: frozen,
+			OrgID:    authPayload.OrgID,
+		},
+		Actor: db.Actor{Username: authPayload.UserName, ClientIP: ctx.ClientIP()},
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountResponse(account, requestAmountFormat(ctx)))
+}
+
+// transferAccountOwnership hands an account over to another existing user, the balance and the history stay with the account
+// It is only reachable by bankers, and the banker who made the change is recorded in the account owner history
+func (s *Server) transferAccountOwnership(ctx *gin.Context) {
+	var uriReq transferOwnershipUriReq
+	if !bindURI(ctx, &uriReq) {
+		return
+	}
+
+	var req transferOwnershipReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, uriReq.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	result, err := s.store.TransferAccountOwnershipTx(ctx, db.TransferAccountOwnershipTxParams{
+		AccountID: account.ID,
+		NewOwner:  utils.NormalizeUsername(req.NewOwner),
+		Actor:     db.Actor{Username: authPayload.UserName, ClientIP: ctx.ClientIP()},
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrNewOwnerNotFound):
+			ctx.JSON(http.StatusNotFound, errorResponse(codeUserNotFound, err))
+		case errors.Is(err, db.ErrOwnerHasCurrency):
+			ctx.JSON(http.StatusConflict, errorResponse(codeAccountExists, err))
+		case errors.Is(err, db.ErrOrganizationMismatch):
+			ctx.JSON(http.StatusForbidden, errorResponse(codeOtherOrganization, err))
+		default:
+			respondDBError(ctx, err, codeAccountNotFound)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transferOwnershipResponse{
+		Account: newAccountResponse(result.Account, requestAmountFormat(ctx)),
+		Change:  accountOwnerChangeResponse{AccountOwnerChange: result.Change},
+	})
+}
+
+// searchUsers finds the users whose username, full name or email contains the query, ignoring case
+// It is meant for support lookups, so it is only reachable by bankers
+func (s *Server) searchUsers(ctx *gin.Context) {
+	var req searchUsersReq
+	if !bindQuery(ctx, &req) {
+		return
+	}
+
+	page, ok := parsePagination(ctx)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	query := likeEscaper.Replace(req.Query)
+	users, err := s.store.SearchUsers(ctx, db.SearchUsersParams{
+		OrgID:  authPayload.OrgID,
+		Query:  query,
+		Limit:  page.PageSize,
+		Offset: page.offset(),
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	total, err := s.store.CountSearchUsers(ctx, db.CountSearchUsersParams{
+		OrgID: authPayload.OrgID,
+		Query: query,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	// users are converted one by one, so the hashed passwords never leave the server
+	rsp := make([]userResponse, len(users))
+	for i, user := range users {
+		rsp[i] = newUserResponse(user)
+	}
+
+	ctx.JSON(http.StatusOK, newListResponse(rsp, page.PageID, page.PageSize, total))
+}
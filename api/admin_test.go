@@ -0,0 +1,890 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestListAllAccountsAPI(t *testing.T) {
+	banker, _ := randomUser()
+	banker.Role = utils.BankerRole
+	depositor, _ := randomUser()
+
+	n := 5
+	accounts := make([]db.Account, n)
+	for i := range accounts {
+		owner, _ := randomUser()
+		accounts[i] = randomAccount(owner.Username)
+	}
+
+	type query struct {
+		pageID   int
+		pageSize int
+		owner    string
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		query         query
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path banker lists every account",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAllAccountsParams{
+					Owner:  sql.NullString{},
+					Limit:  int32(n),
+					Offset: 0,
+					OrgID:  db.DefaultOrgID,
+				}
+				store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts, nil)
+				store.EXPECT().CountAllAccounts(gomock.Any(), gomock.Eq(db.CountAllAccountsParams{OrgID: db.DefaultOrgID})).Times(1).Return(int64(2*n), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp struct {
+					Data     []db.Account `json:"data"`
+					PageID   int32        `json:"page_id"`
+					PageSize int32        `json:"page_size"`
+					Total    int64        `json:"total"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, publicAccounts(accounts), rsp.Data)
+				require.Equal(t, int64(2*n), rsp.Total)
+			},
+		},
+		{
+			name: "happy path banker filters by owner",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: query{pageID: 2, pageSize: n, owner: accounts[0].Owner},
+			buildStubs: func(store *mockdb.MockStore) {
+				owner := sql.NullString{String: accounts[0].Owner, Valid: true}
+				arg := db.ListAllAccountsParams{
+					Owner:  owner,
+					Limit:  int32(n),
+					Offset: int32(n),
+					OrgID:  db.DefaultOrgID,
+				}
+				store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts[:1], nil)
+				store.EXPECT().CountAllAccounts(gomock.Any(), gomock.Eq(db.CountAllAccountsParams{OrgID: db.DefaultOrgID, Owner: owner})).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp struct {
+					Data  []db.Account `json:"data"`
+					Total int64        `json:"total"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, publicAccounts(accounts[:1]), rsp.Data)
+				require.Equal(t, int64(1), rsp.Total)
+			},
+		},
+		{
+			name: "invalid owner",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n, owner: "not-an-owner!"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "depositor is forbidden",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, depositor.Username, depositor.Role, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n, owner: depositor.Username},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CountAllAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "unauthenticated",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			query:     query{pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "zero page size",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: 0},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Any()).Times(1).Return([]db.Account{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodGet, "/admin/accounts", nil)
+			require.NoError(t, err)
+
+			q := request.URL.Query()
+			q.Add("page_id", fmt.Sprintf("%d", tc.query.pageID))
+			q.Add("page_size", fmt.Sprintf("%d", tc.query.pageSize))
+			if tc.query.owner != "" {
+				q.Add("owner", tc.query.owner)
+			}
+			request.URL.RawQuery = q.Encode()
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRestoreAccountAPI(t *testing.T) {
+	banker, _ := randomUser()
+	banker.Role = utils.BankerRole
+	depositor, _ := randomUser()
+	account := randomAccount(depositor.Username)
+
+	testCases := []struct {
+		name          string
+		accountNumber string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:          "happy path banker restores account",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RestoreAccount(gomock.Any(), gomock.Eq(db.RestoreAccountParams{AccountNumber: account.AccountNumber, OrgID: db.DefaultOrgID})).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name:          "depositor is forbidden",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, depositor.Username, depositor.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RestoreAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:          "account not deleted",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RestoreAccount(gomock.Any(), gomock.Eq(db.RestoreAccountParams{AccountNumber: account.AccountNumber, OrgID: db.DefaultOrgID})).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountNotFound)
+			},
+		},
+		{
+			name:          "currency already taken by a new account",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RestoreAccount(gomock.Any(), gomock.Eq(db.RestoreAccountParams{AccountNumber: account.AccountNumber, OrgID: db.DefaultOrgID})).Times(1).Return(db.Account{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeConflict)
+			},
+		},
+		{
+			name:          "invalid account number",
+			accountNumber: "123",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RestoreAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:          "internal server error",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RestoreAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/admin/accounts/%s/restore", tc.accountNumber)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestFreezeAccountAPI(t *testing.T) {
+	banker, _ := randomUser()
+	banker.Role = utils.BankerRole
+	depositor, _ := randomUser()
+	account := randomAccount(depositor.Username)
+
+	frozenAccount := account
+	frozenAccount.IsFrozen = true
+
+	testCases := []struct {
+		name          string
+		operation     string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "happy path banker freezes account",
+			operation: "freeze",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.SetAccountFrozenTxParams{
+					SetAccountFrozenParams: db.SetAccountFrozenParams{ID: account.ID, IsFrozen: true, OrgID: db.DefaultOrgID},
+					Actor:                  db.Actor{Username: banker.Username},
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(frozenAccount, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseAccount(t, recorder.Body, frozenAccount)
+			},
+		},
+		{
+			name:      "happy path banker unfreezes account",
+			operation: "unfreeze",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.SetAccountFrozenTxParams{
+					SetAccountFrozenParams: db.SetAccountFrozenParams{ID: account.ID, IsFrozen: false, OrgID: db.DefaultOrgID},
+					Actor:                  db.Actor{Username: banker.Username},
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name:      "depositor is forbidden",
+			operation: "freeze",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, depositor.Username, depositor.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "account not found",
+			operation: "unfreeze",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "account number not found",
+			operation: "freeze",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountNotFound)
+			},
+		},
+		{
+			name:      "internal server error",
+			operation: "freeze",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/admin/accounts/%s/%s", account.AccountNumber, tc.operation)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestSearchUsersAPI(t *testing.T) {
+	banker, _ := randomUser()
+	banker.Role = utils.BankerRole
+	depositor, _ := randomUser()
+
+	user, _ := randomUser()
+	query := user.Username[1:4]
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path partial match",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: url.Values{"q": {query}, "page_id": {"1"}, "page_size": {"5"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.SearchUsersParams{
+					Query:  query,
+					Limit:  5,
+					Offset: 0,
+					OrgID:  db.DefaultOrgID,
+				}
+				store.EXPECT().SearchUsers(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.User{user}, nil)
+				store.EXPECT().CountSearchUsers(gomock.Any(), gomock.Eq(db.CountSearchUsersParams{OrgID: db.DefaultOrgID, Query: query})).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				// the hashed password must never reach the client
+				require.NotContains(t, recorder.Body.String(), "hashed_password")
+
+				var rsp struct {
+					Data  []userResponse `json:"data"`
+					Total int64          `json:"total"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.Data, 1)
+				require.Equal(t, user.Username, rsp.Data[0].UserName)
+				require.Equal(t, user.Email, rsp.Data[0].Email)
+				require.Equal(t, int64(1), rsp.Total)
+			},
+		},
+		{
+			name: "no match",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: url.Values{"q": {"nobody"}, "page_id": {"1"}, "page_size": {"5"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SearchUsers(gomock.Any(), gomock.Any()).Times(1).Return([]db.User{}, nil)
+				store.EXPECT().CountSearchUsers(gomock.Any(), gomock.Eq(db.CountSearchUsersParams{OrgID: db.DefaultOrgID, Query: "nobody"})).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"data": [], "page_id": 1, "page_size": 5, "total": 0}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "wildcards are matched literally",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: url.Values{"q": {`50%_off\`}, "page_id": {"1"}, "page_size": {"5"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.SearchUsersParams{
+					Query:  `50\%\_off\\`,
+					Limit:  5,
+					Offset: 0,
+					OrgID:  db.DefaultOrgID,
+				}
+				store.EXPECT().SearchUsers(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.User{}, nil)
+				store.EXPECT().CountSearchUsers(gomock.Any(), gomock.Eq(db.CountSearchUsersParams{OrgID: db.DefaultOrgID, Query: arg.Query})).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "query too short",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: url.Values{"q": {"a"}, "page_id": {"1"}, "page_size": {"5"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SearchUsers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "depositor is forbidden",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, depositor.Username, depositor.Role, time.Minute)
+			},
+			query: url.Values{"q": {query}, "page_id": {"1"}, "page_size": {"5"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SearchUsers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: url.Values{"q": {query}, "page_id": {"1"}, "page_size": {"5"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().SearchUsers(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrTxDone)
+				store.EXPECT().CountSearchUsers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodGet, "/admin/users/search?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestTransferAccountOwnershipAPI(t *testing.T) {
+	banker, _ := randomUser()
+	banker.Role = utils.BankerRole
+	depositor, _ := randomUser()
+	newOwner, _ := randomUser()
+	account := randomAccount(depositor.Username)
+
+	transferredAccount := account
+	transferredAccount.Owner = newOwner.Username
+	change := db.AccountOwnerChange{
+		ID:            utils.RandomInt(1, 1000),
+		AccountID:     account.ID,
+		PreviousOwner: depositor.Username,
+		NewOwner:      newOwner.Username,
+		ChangedBy:     banker.Username,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+
+	testCases := []struct {
+		name          string
+		accountNumber string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:          "happy path",
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"new_owner": newOwner.Username},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// the banker making the change is taken from the token
+				arg := db.TransferAccountOwnershipTxParams{
+					AccountID: account.ID,
+					NewOwner:  newOwner.Username,
+					Actor:     db.Actor{Username: banker.Username},
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().TransferAccountOwnershipTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.TransferAccountOwnershipTxResult{Account: transferredAccount, Change: change}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp transferOwnershipResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, publicAccount(transferredAccount), rsp.Account.Account)
+				publicChange := change
+				publicChange.AccountID = 0
+				require.Equal(t, publicChange, rsp.Change.AccountOwnerChange)
+				require.NotContains(t, recorder.Body.String(), `"account_id"`)
+			},
+		},
+		{
+			name:          "mixed case new owner",
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"new_owner": strings.ToUpper(newOwner.Username)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// the new owner is looked up by its stored, lowercase username
+				arg := db.TransferAccountOwnershipTxParams{
+					AccountID: account.ID,
+					NewOwner:  newOwner.Username,
+					Actor:     db.Actor{Username: banker.Username},
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().TransferAccountOwnershipTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.TransferAccountOwnershipTxResult{Account: transferredAccount, Change: change}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:          "new owner has an account in the currency",
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"new_owner": newOwner.Username},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().TransferAccountOwnershipTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferAccountOwnershipTxResult{}, fmt.Errorf("%w: %v already has a %v account", db.ErrOwnerHasCurrency, newOwner.Username, account.Currency))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountExists)
+			},
+		},
+		{
+			name:          "banker of another organization",
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"new_owner": newOwner.Username},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addOrgAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, db.DefaultOrgID+1, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().TransferAccountOwnershipTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeOtherOrganization)
+			},
+		},
+		{
+			name:          "new owner of another organization",
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"new_owner": newOwner.Username},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().TransferAccountOwnershipTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferAccountOwnershipTxResult{}, fmt.Errorf("%w: %v can't own account [%v]", db.ErrOrganizationMismatch, newOwner.Username, account.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeOtherOrganization)
+			},
+		},
+		{
+			name:          "new owner not found",
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"new_owner": newOwner.Username},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().TransferAccountOwnershipTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferAccountOwnershipTxResult{}, fmt.Errorf("%w: %v", db.ErrNewOwnerNotFound, newOwner.Username))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeUserNotFound)
+			},
+		},
+		{
+			name:          "account not found",
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"new_owner": newOwner.Username},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().TransferAccountOwnershipTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountNotFound)
+			},
+		},
+		{
+			name:          "depositor is forbidden",
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"new_owner": newOwner.Username},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, depositor.Username, depositor.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferAccountOwnershipTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:          "invalid new owner",
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"new_owner": "not a username"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferAccountOwnershipTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:          "invalid account id",
+			accountNumber: "0",
+			body:          gin.H{"new_owner": newOwner.Username},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferAccountOwnershipTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:          "internal server error",
+			accountNumber: account.AccountNumber,
+			body:          gin.H{"new_owner": newOwner.Username},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().TransferAccountOwnershipTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferAccountOwnershipTxResult{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%s/transfer_ownership", tc.accountNumber)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
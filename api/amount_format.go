@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"net/http"
+)
+
+const (
+	amountFormatQuery = "amount_format"
+	amountFormatKey   = "amount_format"
+)
+
+// amountFormat is how the amounts and balances of a response are written, the zero value keeps the integer minor units
+type amountFormat int
+
+const (
+	// amountFormatMinor writes 1234 for 12.34, like every response before the amount format was added
+	amountFormatMinor amountFormat = iota
+	// amountFormatDecimal writes the decimal string "12.34", see utils.Money.String
+	amountFormatDecimal
+)
+
+var amountFormats = map[string]amountFormat{
+	"minor":   amountFormatMinor,
+	"decimal": amountFormatDecimal,
+}
+
+// amountFormatMiddleware reads the amount_format query param of the request, a missing one keeps the minor units
+// and an unknown one is answered with a bad request before reaching the handler
+func amountFormatMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		format := amountFormatMinor
+		if value := ctx.Query(amountFormatQuery); value != "" {
+			var ok bool
+			format, ok = amountFormats[value]
+			if !ok {
+				err := fmt.Errorf("invalid %v %q: must be minor or decimal", amountFormatQuery, value)
+				ctx.AbortWithStatusJSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+				return
+			}
+		}
+
+		ctx.Set(amountFormatKey, format)
+		ctx.Next()
+	}
+}
+
+// requestAmountFormat returns the amount format the request asked for, the minor units when the middleware didn't run
+func requestAmountFormat(ctx *gin.Context) amountFormat {
+	format, _ := ctx.Get(amountFormatKey)
+	f, _ := format.(amountFormat)
+	return f
+}
+
+// formatAmount writes the amount as the json value of the format
+func (f amountFormat) formatAmount(amount int64) interface{} {
+	if f == amountFormatDecimal {
+		return utils.Money(amount).String()
+	}
+	return amount
+}
+
+// accountResponse is an account whose balances are written in the requested amount format
+type accountResponse struct {
+	db.Account
+	format amountFormat
+}
+
+func newAccountResponse(account db.Account, format amountFormat) accountResponse {
+	return accountResponse{Account: account, format: format}
+}
+
+func newAccountResponses(accounts []db.Account, format amountFormat) []accountResponse {
+	rsp := make([]accountResponse, len(accounts))
+	for i, account := range accounts {
+		rsp[i] = newAccountResponse(account, format)
+	}
+	return rsp
+}
+
+// MarshalJSON writes the account like db.Account, with the balances and limits replaced by their formatted value
+// The internal id is left out, clients only ever address the account by its number
+func (r accountResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		db.Account
+		// ID is never set, it only shadows the id of db.Account
+		ID                  *int64      `json:"id,omitempty"`
+		Balance             interface{} `json:"balance"`
+		HeldBalance         interface{} `json:"held_balance"`
+		DailyLimit          interface{} `json:"daily_limit"`
+		MonthlyLimit        interface{} `json:"monthly_limit"`
+		LowBalanceThreshold interface{} `json:"low_balance_threshold"`
+	}{
+		Account:             r.Account,
+		Balance:             r.format.formatAmount(r.Balance),
+		HeldBalance:         r.format.formatAmount(r.HeldBalance),
+		DailyLimit:          r.format.formatAmount(r.DailyLimit),
+		MonthlyLimit:        r.format.formatAmount(r.MonthlyLimit),
+		LowBalanceThreshold: r.format.formatAmount(r.LowBalanceThreshold),
+	})
+}
+
+// entryResponse is an entry whose amount is written in the requested amount format
+type entryResponse struct {
+	db.Entry
+	format amountFormat
+}
+
+func newEntryResponses(entries []db.Entry, format amountFormat) []entryResponse {
+	rsp := make([]entryResponse, len(entries))
+	for i, entry := range entries {
+		rsp[i] = entryResponse{Entry: entry, format: format}
+	}
+	return rsp
+}
+
+// MarshalJSON writes the entry like db.Entry, with the amount replaced by its formatted value and without the internal account id
+func (r entryResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		db.Entry
+		AccountID *int64      `json:"account_id,omitempty"`
+		Amount    interface{} `json:"amount"`
+	}{
+		Entry:  r.Entry,
+		Amount: r.format.formatAmount(r.Amount),
+	})
+}
+
+// MarshalJSON writes the preview with its amounts in the requested amount format
+func (r transferPreviewResponse) MarshalJSON() ([]byte, error) {
+	type plain transferPreviewResponse
+	return json.Marshal(struct {
+		plain
+		Amount   interface{} `json:"amount"`
+		ToAmount interface{} `json:"to_amount"`
+	}{
+		plain:    plain(r),
+		Amount:   r.format.formatAmount(r.Amount),
+		ToAmount: r.format.formatAmount(r.ToAmount),
+	})
+}
+
+// MarshalJSON writes the closing response with the swept amount in the requested amount format
+func (r closeAccountResponse) MarshalJSON() ([]byte, error) {
+	type plain closeAccountResponse
+	return json.Marshal(struct {
+		plain
+		SweptAmount interface{} `json:"swept_amount"`
+	}{
+		plain:       plain(r),
+		SweptAmount: r.format.formatAmount(r.SweptAmount),
+	})
+}
+
+// MarshalJSON writes the transfer with its amounts in the requested amount format
+func (r transferResponse) MarshalJSON() ([]byte, error) {
+	// plain has the fields of transferResponse but not its MarshalJSON, so marshaling it doesn't recurse
+	type plain transferResponse
+	return json.Marshal(struct {
+		plain
+		Amount   interface{} `json:"amount"`
+		ToAmount interface{} `json:"to_amount"`
+	}{
+		plain:    plain(r),
+		Amount:   r.format.formatAmount(r.Amount),
+		ToAmount: r.format.formatAmount(r.ToAmount),
+	})
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestTransferResponseAmountFormat(t *testing.T) {
+	transfer := db.Transfer{
+		UUID:          uuid.New(),
+		FromAccountID: 1,
+		ToAccountID:   2,
+		Amount:        1234,
+		ToAmount:      -50,
+	}
+	numbers := accountNumbers{1: utils.RandomAccountNumber(), 2: utils.RandomAccountNumber()}
+
+	data, err := json.Marshal(newTransferResponse(transfer, numbers, amountFormatMinor))
+	require.NoError(t, err)
+	var minor map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &minor))
+	require.Equal(t, float64(1234), minor["amount"])
+	require.Equal(t, float64(-50), minor["to_amount"])
+
+	data, err = json.Marshal(newTransferResponse(transfer, numbers, amountFormatDecimal))
+	require.NoError(t, err)
+	var decimal map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decimal))
+	require.Equal(t, "12.34", decimal["amount"])
+	require.Equal(t, "-0.50", decimal["to_amount"])
+
+	// only the amounts change
+	require.Equal(t, minor["id"], decimal["id"])
+	require.Equal(t, numbers[1], decimal["from_account_number"])
+	require.Equal(t, numbers[2], decimal["to_account_number"])
+	require.NotContains(t, decimal, "from_account_id")
+	require.Len(t, decimal, len(minor))
+}
+
+func TestAccountResponseAmountFormat(t *testing.T) {
+	account := randomAccount(utils.RandomOwner())
+	account.Balance = 100005
+	account.HeldBalance = 5
+	account.DailyLimit = 50000
+
+	data, err := json.Marshal(newAccountResponse(account, amountFormatMinor))
+	require.NoError(t, err)
+	// the minor units keep the json of db.Account, without its internal id
+	expected, err := json.Marshal(account)
+	require.NoError(t, err)
+	var expectedFields map[string]interface{}
+	require.NoError(t, json.Unmarshal(expected, &expectedFields))
+	delete(expectedFields, "id")
+	var minor map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &minor))
+	require.Equal(t, expectedFields, minor)
+
+	data, err = json.Marshal(newAccountResponse(account, amountFormatDecimal))
+	require.NoError(t, err)
+	var decimal map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decimal))
+	require.Equal(t, "1000.05", decimal["balance"])
+	require.Equal(t, "0.05", decimal["held_balance"])
+	require.Equal(t, "500.00", decimal["daily_limit"])
+	require.Equal(t, "0.00", decimal["monthly_limit"])
+	require.Equal(t, "0.00", decimal["low_balance_threshold"])
+	require.Equal(t, account.AccountNumber, decimal["account_number"])
+}
+
+func TestAmountFormatMiddleware(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+	account.Balance = 1234
+
+	testCases := []struct {
+		name          string
+		amountFormat  string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "default minor units",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp map[string]interface{}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, float64(1234), rsp["balance"])
+			},
+		},
+		{
+			name:         "minor units",
+			amountFormat: "minor",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp map[string]interface{}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, float64(1234), rsp["balance"])
+			},
+		},
+		{
+			name:         "decimal strings",
+			amountFormat: "decimal",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var rsp map[string]interface{}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, "12.34", rsp["balance"])
+			},
+		},
+		{
+			name:         "unknown format",
+			amountFormat: "cents",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/accounts/%s", account.AccountNumber)
+			if tc.amountFormat != "" {
+				url += "?" + amountFormatQuery + "=" + tc.amountFormat
+			}
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.token, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
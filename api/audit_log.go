@@ -0,0 +1,68 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"net/http"
+	"time"
+)
+
+// listAuditLogsReq range includes from and excludes to
+type listAuditLogsReq struct {
+	Username string    `form:"username" binding:"omitempty,username"`
+	From     time.Time `form:"from" binding:"required" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       time.Time `form:"to" binding:"required" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// listAuditLogs lists the audited actions within the date range, newest first, optionally filtered by the user who made them
+// It is meant for compliance reviews, so it is only reachable by bankers
+func (s *Server) listAuditLogs(ctx *gin.Context) {
+	var req listAuditLogsReq
+	if !bindQuery(ctx, &req) {
+		return
+	}
+
+	page, ok := parsePagination(ctx)
+	if !ok {
+		return
+	}
+
+	if !req.From.Before(req.To) {
+		err := errors.New("from must be before to")
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return
+	}
+
+	// only the actions of the users of the banker organization are listed
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	username := sql.NullString{String: utils.NormalizeUsername(req.Username), Valid: req.Username != ""}
+	logs, err := s.store.ListAuditLogs(ctx, db.ListAuditLogsParams{
+		OrgID:    authPayload.OrgID,
+		Username: username,
+		FromTime: req.From,
+		ToTime:   req.To,
+		Limit:    page.PageSize,
+		Offset:   page.offset(),
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	total, err := s.store.CountAuditLogs(ctx, db.CountAuditLogsParams{
+		OrgID:    authPayload.OrgID,
+		Username: username,
+		FromTime: req.From,
+		ToTime:   req.To,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newListResponse(logs, page.PageID, page.PageSize, total))
+}
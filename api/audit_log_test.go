@@ -0,0 +1,210 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestListAuditLogsAPI(t *testing.T) {
+	banker, _ := randomUser()
+	banker.Role = utils.BankerRole
+	depositor, _ := randomUser()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	log := db.AuditLog{
+		ID:         utils.RandomInt(1, 1000),
+		Username:   depositor.Username,
+		Action:     db.AuditActionChangePassword,
+		EntityType: db.AuditEntityUser,
+		EntityID:   depositor.Username,
+		ClientIp:   "10.0.0.1",
+		CreatedAt:  from.Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path filtered by user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: url.Values{
+				"username":  {depositor.Username},
+				"from":      {from.Format(time.RFC3339)},
+				"to":        {to.Format(time.RFC3339)},
+				"page_id":   {"1"},
+				"page_size": {"5"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				username := sql.NullString{String: depositor.Username, Valid: true}
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Eq(db.ListAuditLogsParams{
+					Username: username,
+					FromTime: from,
+					ToTime:   to,
+					Limit:    5,
+					Offset:   0,
+					OrgID:    db.DefaultOrgID,
+				})).Times(1).Return([]db.AuditLog{log}, nil)
+				store.EXPECT().CountAuditLogs(gomock.Any(), gomock.Eq(db.CountAuditLogsParams{
+					Username: username,
+					FromTime: from,
+					ToTime:   to,
+					OrgID:    db.DefaultOrgID,
+				})).Times(1).Return(int64(1), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp struct {
+					Data  []db.AuditLog `json:"data"`
+					Total int64         `json:"total"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, []db.AuditLog{log}, rsp.Data)
+				require.Equal(t, int64(1), rsp.Total)
+			},
+		},
+		{
+			name: "happy path every user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: url.Values{
+				"from":      {from.Format(time.RFC3339)},
+				"to":        {to.Format(time.RFC3339)},
+				"page_id":   {"2"},
+				"page_size": {"5"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Eq(db.ListAuditLogsParams{
+					FromTime: from,
+					ToTime:   to,
+					Limit:    5,
+					Offset:   5,
+					OrgID:    db.DefaultOrgID,
+				})).Times(1).Return([]db.AuditLog{}, nil)
+				store.EXPECT().CountAuditLogs(gomock.Any(), gomock.Any()).Times(1).Return(int64(5), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `{"data": [], "page_id": 2, "page_size": 5, "total": 5}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "invalid range",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: url.Values{
+				"from":      {to.Format(time.RFC3339)},
+				"to":        {from.Format(time.RFC3339)},
+				"page_id":   {"1"},
+				"page_size": {"5"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "missing range",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: url.Values{"page_id": {"1"}, "page_size": {"5"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "depositor is forbidden",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, depositor.Username, depositor.Role, time.Minute)
+			},
+			query: url.Values{
+				"username":  {depositor.Username},
+				"from":      {from.Format(time.RFC3339)},
+				"to":        {to.Format(time.RFC3339)},
+				"page_id":   {"1"},
+				"page_size": {"5"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: url.Values{
+				"from":      {from.Format(time.RFC3339)},
+				"to":        {to.Format(time.RFC3339)},
+				"page_id":   {"1"},
+				"page_size": {"5"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrTxDone)
+				store.EXPECT().CountAuditLogs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodGet, "/admin/audit_logs?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"net/http"
+	"time"
+)
+
+const (
+	dayFormat = "2006-01-02"
+	// maxBalanceHistoryDays bounds the range of a single request, a year of daily snapshots is enough for a chart
+	maxBalanceHistoryDays = 366
+)
+
+type (
+	getBalanceHistoryUriReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	// getBalanceHistoryReq range includes both from and to, the days are UTC days
+	getBalanceHistoryReq struct {
+		From time.Time `form:"from" binding:"required" time_format:"2006-01-02" time_utc:"1"`
+		To   time.Time `form:"to" binding:"required" time_format:"2006-01-02" time_utc:"1"`
+	}
+
+	// balanceSnapshotResponse leaves out the internal account id, the history is requested by account number
+	balanceSnapshotResponse struct {
+		db.BalanceSnapshot
+		AccountID *int64 `json:"account_id,omitempty"`
+	}
+)
+
+// getBalanceHistory returns the closing balance of the account for every snapshotted day within the date range
+func (s *Server) getBalanceHistory(ctx *gin.Context) {
+	var uri getBalanceHistoryUriReq
+	if !bindURI(ctx, &uri) {
+		return
+	}
+
+	var req getBalanceHistoryReq
+	if !bindQuery(ctx, &req) {
+		return
+	}
+
+	if req.To.Before(req.From) {
+		err := errors.New("from must not be after to")
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return
+	}
+	if req.To.Sub(req.From) >= maxBalanceHistoryDays*24*time.Hour {
+		err := fmt.Errorf("date range must not exceed %v days", maxBalanceHistoryDays)
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, uri.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != account.Owner {
+		err = errors.New("account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	snapshots, err := s.store.ListBalanceSnapshots(ctx, db.ListBalanceSnapshotsParams{
+		AccountID: account.ID,
+		FromDay:   req.From,
+		ToDay:     req.To,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	rsp := make([]balanceSnapshotResponse, len(snapshots))
+	for i, snapshot := range snapshots {
+		rsp[i] = balanceSnapshotResponse{BalanceSnapshot: snapshot}
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
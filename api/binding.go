@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// bindJSON binds the request body into req, a request that doesn't bind is answered with its validation errors
+// It returns whether the handler can go on
+func bindJSON(ctx *gin.Context, req interface{}) bool {
+	return bindRequest(ctx, ctx.ShouldBindJSON(req))
+}
+
+// bindURI binds the path parameters into req like bindJSON does the body
+func bindURI(ctx *gin.Context, req interface{}) bool {
+	return bindRequest(ctx, ctx.ShouldBindUri(req))
+}
+
+// bindQuery binds the query string into req like bindJSON does the body
+func bindQuery(ctx *gin.Context, req interface{}) bool {
+	return bindRequest(ctx, ctx.ShouldBindQuery(req))
+}
+
+func bindRequest(ctx *gin.Context, err error) bool {
+	if err != nil {
+		respondBindError(ctx, err)
+		return false
+	}
+	return true
+}
+
+// respondBindError writes the error response of a request that couldn't be read, a body cut at the size limit gets a 413
+func respondBindError(ctx *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(codeRequestTooLarge, errBodyTooLarge(maxBytesErr.Limit)))
+		return
+	}
+	ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+}
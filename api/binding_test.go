@@ -0,0 +1,74 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+)
+
+func TestBindJSONValidationDetails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	// every field but the from account is invalid, each one is reported
+	data, err := json.Marshal(gin.H{
+		"from_account_number": utils.RandomAccountNumber(),
+		"amount":              -5,
+		"currency":            "XYZ",
+		"description":         strings.Repeat("a", 141),
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.token, _authorizationTypeBearer, utils.RandomOwner(), utils.DepositorRole, time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	rsp := requireErrorCode(t, recorder, codeInvalidRequest)
+	require.Equal(t, []fieldError{
+		{Field: "to_account_number", Rule: "required", Message: "to_account_number is required"},
+		{Field: "amount", Rule: "min", Message: "amount must be at least 1"},
+		{Field: "currency", Rule: "currency", Message: "currency must be a supported currency"},
+		{Field: "description", Rule: "max", Message: "description must be at most 140 characters"},
+	}, rsp.Details)
+}
+
+func TestBindURIValidationDetails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/accounts/0", nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.token, _authorizationTypeBearer, utils.RandomOwner(), utils.DepositorRole, time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// uri fields are named after their path parameter
+	rsp := requireErrorCode(t, recorder, codeInvalidRequest)
+	require.Equal(t, []fieldError{{Field: "number", Rule: "len", Message: "number must be exactly 16 characters"}}, rsp.Details)
+}
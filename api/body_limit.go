@@ -0,0 +1,36 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// bodyLimitMiddleware caps the bytes read from the request body, a larger body is rejected with a 413
+// routeLimits replaces the limit on some routes, keyed by their full path, 0 leaves the body unlimited
+// A body announced larger than the limit is rejected upfront, otherwise reading past it fails with an *http.MaxBytesError
+// that the binding helpers answer with a 413
+func bodyLimitMiddleware(limit int64, routeLimits map[string]int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limit := limit
+		if routeLimit, ok := routeLimits[ctx.FullPath()]; ok {
+			limit = routeLimit
+		}
+		if limit <= 0 {
+			ctx.Next()
+			return
+		}
+
+		if ctx.Request.ContentLength > limit {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, errorResponse(codeRequestTooLarge, errBodyTooLarge(limit)))
+			return
+		}
+
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+		ctx.Next()
+	}
+}
+
+func errBodyTooLarge(limit int64) error {
+	return fmt.Errorf("request body is larger than %v bytes", limit)
+}
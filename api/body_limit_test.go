@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+const (
+	testBodyLimit       = 256
+	testImportBodyLimit = 4 * testBodyLimit
+)
+
+func newBodyLimitTestServer(t *testing.T, store *mockdb.MockStore) *Server {
+	config := utils.Config{
+		TokenSymmetricKey:   utils.RandomString(32),
+		TokenDuration:       time.Minute,
+		MaxRequestBodyBytes: testBodyLimit,
+		MaxImportBodyBytes:  testImportBodyLimit,
+	}
+
+	store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Any()).AnyTimes().Return(time.Time{}, nil)
+
+	server, err := NewServer(config, store, newTestTaskDistributor())
+	require.NoError(t, err)
+
+	return server
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	banker, _ := randomUser()
+	banker.Role = utils.BankerRole
+	user, password := randomUser()
+
+	// userBody is a create user body of about size bytes, padded through the full name
+	userBody := func(size int) string {
+		data, err := json.Marshal(gin.H{
+			"username":  user.Username,
+			"password":  password,
+			"full_name": strings.Repeat("a", size),
+			"email":     user.Email,
+		})
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	testCases := []struct {
+		name          string
+		url           string
+		body          string
+		chunked       bool
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "oversized body",
+			url:  "/users",
+			body: userBody(2 * testBodyLimit),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+				requireErrorCode(t, recorder, codeRequestTooLarge)
+			},
+		},
+		{
+			name:    "oversized body without content length",
+			url:     "/users",
+			body:    userBody(2 * testBodyLimit),
+			chunked: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+				requireErrorCode(t, recorder, codeRequestTooLarge)
+			},
+		},
+		{
+			name:    "import over its own limit without content length",
+			url:     "/admin/users/import",
+			body:    "[" + userBody(2*testImportBodyLimit) + "]",
+			chunked: true,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ImportUsersTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+				requireErrorCode(t, recorder, codeRequestTooLarge)
+			},
+		},
+		{
+			name: "body within the limit",
+			url:  "/users",
+			body: userBody(10),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "import allows a larger body",
+			url:  "/admin/users/import",
+			body: "[" + userBody(2*testBodyLimit) + "]",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// the padded full name is valid, the row reaches the store
+				store.EXPECT().ImportUsersTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ImportUsersTxResult{Users: []db.User{user}, Errs: make([]error, 1)}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "import over its own limit",
+			url:  "/admin/users/import",
+			body: "[" + userBody(2*testImportBodyLimit) + "]",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ImportUsersTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+				requireErrorCode(t, recorder, codeRequestTooLarge)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newBodyLimitTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body io.Reader = strings.NewReader(tc.body)
+			if tc.chunked {
+				// hides the length from the request, like a chunked upload
+				body = io.MultiReader(body)
+			}
+			request, err := http.NewRequest(http.MethodPost, tc.url, body)
+			require.NoError(t, err)
+			if tc.setupAuth != nil {
+				tc.setupAuth(t, request, server.token)
+			}
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
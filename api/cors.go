@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const corsWildcard = "*"
+
+var (
+	corsAllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+	corsAllowedHeaders = []string{"Authorization", "Content-Type", "Accept", idempotencyKeyHeader, requestIDHeader}
+	corsExposedHeaders = []string{"Retry-After", idempotencyReplayedHeader, requestIDHeader}
+	corsMaxAge         = 10 * time.Minute
+
+	errOriginNotAllowed = errors.New("origin not allowed")
+)
+
+// corsPolicy holds the set of origins browsers are allowed to call the api from
+// A wildcard policy accepts any origin but never allows credentials, since browsers reject `*` on credentialed requests
+type corsPolicy struct {
+	allowAll bool
+	origins  map[string]bool
+}
+
+func newCORSPolicy(allowedOrigins []string) *corsPolicy {
+	policy := &corsPolicy{origins: make(map[string]bool)}
+	for _, origin := range allowedOrigins {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+		if origin == corsWildcard {
+			policy.allowAll = true
+			continue
+		}
+		policy.origins[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	return policy
+}
+
+func (p *corsPolicy) allowed(origin string) bool {
+	return p.allowAll || p.origins[origin]
+}
+
+// corsMiddleware sets the Access-Control headers for allowed origins and answers preflight requests
+// Requests without an Origin header don't come from a browser and are let through untouched
+func corsMiddleware(policy *corsPolicy) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		origin := ctx.GetHeader("Origin")
+		if origin == "" {
+			ctx.Next()
+			return
+		}
+
+		if !policy.allowed(origin) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(codeOriginNotAllowed, errOriginNotAllowed))
+			return
+		}
+
+		if policy.allowAll {
+			ctx.Header("Access-Control-Allow-Origin", corsWildcard)
+		} else {
+			// the response depends on the request origin, so caches must key on it
+			ctx.Header("Access-Control-Allow-Origin", origin)
+			ctx.Header("Access-Control-Allow-Credentials", "true")
+			ctx.Writer.Header().Add("Vary", "Origin")
+		}
+
+		isPreflight := ctx.Request.Method == http.MethodOptions && ctx.GetHeader("Access-Control-Request-Method") != ""
+		if !isPreflight {
+			ctx.Header("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("Access-Control-Allow-Methods", strings.Join(corsAllowedMethods, ", "))
+		ctx.Header("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+		ctx.Header("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+		ctx.AbortWithStatus(http.StatusNoContent)
+	}
+}
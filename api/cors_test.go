@@ -0,0 +1,176 @@
+package api
+
+import (
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newCORSTestServer(t *testing.T, allowedOrigins []string) *Server {
+	config := utils.Config{
+		TokenSymmetricKey: utils.RandomString(32),
+		TokenDuration:     time.Minute,
+		AllowedOrigins:    allowedOrigins,
+	}
+
+	server, err := NewServer(config, nil, newTestTaskDistributor())
+	require.NoError(t, err)
+
+	return server
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	allowedOrigin := "http://localhost:3000"
+
+	testCases := []struct {
+		name           string
+		allowedOrigins []string
+		method         string
+		url            string
+		setupHeaders   func(request *http.Request)
+		checkResponse  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "happy path preflight",
+			allowedOrigins: []string{allowedOrigin, " http://example.com"},
+			method:         http.MethodOptions,
+			url:            "/accounts",
+			setupHeaders: func(request *http.Request) {
+				request.Header.Set("Origin", allowedOrigin)
+				request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				request.Header.Set("Access-Control-Request-Headers", "Authorization, Content-Type")
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				require.Equal(t, allowedOrigin, recorder.Header().Get("Access-Control-Allow-Origin"))
+				require.Equal(t, "true", recorder.Header().Get("Access-Control-Allow-Credentials"))
+				require.Contains(t, recorder.Header().Get("Access-Control-Allow-Methods"), http.MethodPost)
+				require.Contains(t, recorder.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+				require.Equal(t, "600", recorder.Header().Get("Access-Control-Max-Age"))
+				require.Equal(t, "Origin", recorder.Header().Get("Vary"))
+			},
+		},
+		{
+			name:           "preflight of a put",
+			allowedOrigins: []string{allowedOrigin},
+			method:         http.MethodOptions,
+			url:            "/users/notification_preferences",
+			setupHeaders: func(request *http.Request) {
+				request.Header.Set("Origin", allowedOrigin)
+				request.Header.Set("Access-Control-Request-Method", http.MethodPut)
+				request.Header.Set("Access-Control-Request-Headers", "Authorization, Content-Type")
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				require.Equal(t, allowedOrigin, recorder.Header().Get("Access-Control-Allow-Origin"))
+				require.Contains(t, recorder.Header().Get("Access-Control-Allow-Methods"), http.MethodPut)
+			},
+		},
+		{
+			name:           "preflight from disallowed origin",
+			allowedOrigins: []string{allowedOrigin},
+			method:         http.MethodOptions,
+			url:            "/accounts",
+			setupHeaders: func(request *http.Request) {
+				request.Header.Set("Origin", "http://evil.com")
+				request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+			},
+		},
+		{
+			name:           "simple request from allowed origin",
+			allowedOrigins: []string{allowedOrigin},
+			method:         http.MethodGet,
+			url:            "/healthz",
+			setupHeaders: func(request *http.Request) {
+				request.Header.Set("Origin", allowedOrigin)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, allowedOrigin, recorder.Header().Get("Access-Control-Allow-Origin"))
+				require.Contains(t, recorder.Header().Get("Access-Control-Expose-Headers"), "Retry-After")
+			},
+		},
+		{
+			name:           "simple request from disallowed origin",
+			allowedOrigins: []string{allowedOrigin},
+			method:         http.MethodGet,
+			url:            "/healthz",
+			setupHeaders: func(request *http.Request) {
+				request.Header.Set("Origin", "http://evil.com")
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:           "request without origin",
+			allowedOrigins: []string{allowedOrigin},
+			method:         http.MethodGet,
+			url:            "/healthz",
+			setupHeaders:   func(request *http.Request) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+			},
+		},
+		{
+			name:           "wildcard origin never allows credentials",
+			allowedOrigins: []string{"*"},
+			method:         http.MethodOptions,
+			url:            "/accounts",
+			setupHeaders: func(request *http.Request) {
+				request.Header.Set("Origin", "http://any.com")
+				request.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				require.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+				require.Empty(t, recorder.Header().Get("Access-Control-Allow-Credentials"))
+			},
+		},
+		{
+			name:           "cors disabled",
+			allowedOrigins: nil,
+			method:         http.MethodGet,
+			url:            "/healthz",
+			setupHeaders: func(request *http.Request) {
+				request.Header.Set("Origin", allowedOrigin)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newCORSTestServer(t, tc.allowedOrigins)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, nil)
+			require.NoError(t, err)
+			tc.setupHeaders(request)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"net/http"
+	"time"
+)
+
+type (
+	listEntriesUriReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	// listEntriesReq range is optional and includes both from and to
+	listEntriesReq struct {
+		From *time.Time `form:"from" binding:"required_with=To" time_format:"2006-01-02T15:04:05Z07:00"`
+		To   *time.Time `form:"to" binding:"required_with=From" time_format:"2006-01-02T15:04:05Z07:00"`
+	}
+)
+
+// listEntries executes a paginated query over the entries of an account, the most recent first
+// When the from and to query params are set only the entries created within that range are listed
+func (s *Server) listEntries(ctx *gin.Context) {
+	var uri listEntriesUriReq
+	if !bindURI(ctx, &uri) {
+		return
+	}
+
+	var req listEntriesReq
+	if !bindQuery(ctx, &req) {
+		return
+	}
+
+	if req.From != nil && req.From.After(*req.To) {
+		err := errors.New("from must not be after to")
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return
+	}
+
+	page, ok := parsePagination(ctx)
+	if !ok {
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, uri.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != account.Owner {
+		err = errors.New("account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	if req.From != nil {
+		s.listEntriesInRange(ctx, account.ID, page, req.From.UTC(), req.To.UTC())
+		return
+	}
+
+	entries, err := s.store.ListEntriesByAccount(ctx, db.ListEntriesByAccountParams{
+		AccountID: account.ID,
+		Limit:     page.PageSize,
+		Offset:    page.offset(),
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	total, err := s.store.CountEntriesByAccount(ctx, account.ID)
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newListResponse(newEntryResponses(entries, requestAmountFormat(ctx)), page.PageID, page.PageSize, total))
+}
+
+// listEntriesInRange writes the page of the account entries created between from and to, both in UTC like the created_at column
+func (s *Server) listEntriesInRange(ctx *gin.Context, accountID int64, page pagination, from, to time.Time) {
+	entries, err := s.store.ListEntriesByAccountInRange(ctx, db.ListEntriesByAccountInRangeParams{
+		AccountID:   accountID,
+		FromTime:    from,
+		ToTime:      to,
+		LimitCount:  page.PageSize,
+		OffsetCount: page.offset(),
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	total, err := s.store.CountEntriesByAccountInRange(ctx, db.CountEntriesByAccountInRangeParams{
+		AccountID: accountID,
+		FromTime:  from,
+		ToTime:    to,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newListResponse(newEntryResponses(entries, requestAmountFormat(ctx)), page.PageID, page.PageSize, total))
+}
@@ -0,0 +1,394 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestListEntriesAPI(t *testing.T) {
+	user, _ := randomUser()
+	otherUser, _ := randomUser()
+	account := randomAccount(user.Username)
+
+	n := 5
+	total := 3 * n
+	entries := make([]db.Entry, n)
+	for i := 0; i < n; i++ {
+		entries[i] = db.Entry{
+			ID:        utils.RandomInt(1, 1000),
+			AccountID: account.ID,
+			Amount:    utils.RandomBalance(),
+		}
+	}
+
+	from := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	type query struct {
+		pageID   int
+		pageSize int
+		from     string
+		to       string
+	}
+
+	testCases := []struct {
+		name          string
+		accountNumber string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		query         query
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:          "happy path list entries",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 2, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListEntriesByAccountParams{
+					AccountID: account.ID,
+					Limit:     int32(n),
+					Offset:    int32(n),
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Eq(arg)).Times(1).Return(entries, nil)
+				store.EXPECT().CountEntriesByAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(int64(total), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp struct {
+					Data     []db.Entry `json:"data"`
+					PageID   int32      `json:"page_id"`
+					PageSize int32      `json:"page_size"`
+					Total    int64      `json:"total"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, publicEntries(entries), rsp.Data)
+				require.Equal(t, int32(2), rsp.PageID)
+				require.Equal(t, int32(n), rsp.PageSize)
+				// total reflects every entry of the account, not just the page
+				require.Equal(t, int64(total), rsp.Total)
+			},
+		},
+		{
+			name:          "happy path list entries in range",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n, from: from.Format(time.RFC3339), to: to.Format(time.RFC3339)},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListEntriesByAccountInRangeParams{
+					AccountID:   account.ID,
+					FromTime:    from,
+					ToTime:      to,
+					LimitCount:  int32(n),
+					OffsetCount: 0,
+				}
+				countArg := db.CountEntriesByAccountInRangeParams{
+					AccountID: account.ID,
+					FromTime:  from,
+					ToTime:    to,
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesByAccountInRange(gomock.Any(), gomock.Eq(arg)).Times(1).Return(entries, nil)
+				store.EXPECT().CountEntriesByAccountInRange(gomock.Any(), gomock.Eq(countArg)).Times(1).Return(int64(n), nil)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp struct {
+					Data  []db.Entry `json:"data"`
+					Total int64      `json:"total"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, publicEntries(entries), rsp.Data)
+				require.Equal(t, int64(n), rsp.Total)
+			},
+		},
+		{
+			name:          "range in another time zone is queried in utc",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{
+				pageID:   1,
+				pageSize: n,
+				from:     from.In(time.FixedZone("ART", -3*60*60)).Format(time.RFC3339),
+				to:       to.In(time.FixedZone("ART", -3*60*60)).Format(time.RFC3339),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListEntriesByAccountInRangeParams{
+					AccountID:   account.ID,
+					FromTime:    from,
+					ToTime:      to,
+					LimitCount:  int32(n),
+					OffsetCount: 0,
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesByAccountInRange(gomock.Any(), gomock.Eq(arg)).Times(1).Return(entries, nil)
+				store.EXPECT().CountEntriesByAccountInRange(gomock.Any(), gomock.Any()).Times(1).Return(int64(n), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:          "inverted range",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n, from: to.Format(time.RFC3339), to: from.Format(time.RFC3339)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListEntriesByAccountInRange(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name:          "range without to",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n, from: from.Format(time.RFC3339)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListEntriesByAccountInRange(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:          "account not found",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:          "unauthorized user",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, otherUser.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:          "page size over the cap",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: 1000},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListEntriesByAccountParams{
+					AccountID: account.ID,
+					Limit:     maxPageSize,
+					Offset:    0,
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Eq(arg)).Times(1).Return(entries, nil)
+				store.EXPECT().CountEntriesByAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(int64(total), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				// the page size is lowered to the cap rather than rejected
+				require.Contains(t, recorder.Body.String(), fmt.Sprintf(`"page_size":%d`, maxPageSize))
+			},
+		},
+		{
+			name:          "page past the last entry",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 4, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListEntriesByAccountParams{
+					AccountID: account.ID,
+					Limit:     int32(n),
+					Offset:    int32(3 * n),
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.Entry{}, nil)
+				store.EXPECT().CountEntriesByAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(int64(total), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				// an empty page is an empty array, not null
+				var rsp struct {
+					Data  json.RawMessage `json:"data"`
+					Total int64           `json:"total"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.JSONEq(t, `[]`, string(rsp.Data))
+				require.Equal(t, int64(total), rsp.Total)
+			},
+		},
+		{
+			name:          "largest page size",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: 100},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListEntriesByAccountParams{
+					AccountID: account.ID,
+					Limit:     100,
+					Offset:    0,
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Eq(arg)).Times(1).Return(entries, nil)
+				store.EXPECT().CountEntriesByAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(int64(n), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:          "zero page size",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: 0},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:          "invalid page id",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 0, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:          "count internal server error",
+			accountNumber: account.AccountNumber,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntriesByAccount(gomock.Any(), gomock.Any()).Times(1).Return(entries, nil)
+				store.EXPECT().CountEntriesByAccount(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/accounts/%s/entries", tc.accountNumber)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			q := request.URL.Query()
+			q.Add("page_id", fmt.Sprintf("%d", tc.query.pageID))
+			q.Add("page_size", fmt.Sprintf("%d", tc.query.pageSize))
+			if tc.query.from != "" {
+				q.Add("from", tc.query.from)
+			}
+			if tc.query.to != "" {
+				q.Add("to", tc.query.to)
+			}
+			request.URL.RawQuery = q.Encode()
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// publicEntries drops the internal account id, which the entries of a response never carry
+func publicEntries(entries []db.Entry) []db.Entry {
+	rsp := make([]db.Entry, len(entries))
+	for i, entry := range entries {
+		entry.AccountID = 0
+		rsp[i] = entry
+	}
+	return rsp
+}
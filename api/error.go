@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/lib/pq"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"net/http"
+)
+
+// error codes are part of the api contract: clients branch on them, so an existing code must never be renamed
+const (
+	codeInvalidRequest        = "invalid_request"
+	codeInvalidPassword       = "invalid_password"
+	codeAmountOutOfRange      = "amount_out_of_range"
+	codeCurrencyMismatch      = "currency_mismatch"
+	codeNoExchangeRate        = "no_exchange_rate"
+	codeUnauthorized          = "unauthorized"
+	codeInvalidToken          = "invalid_token"
+	codeInvalidResetToken     = "invalid_reset_token"
+	codeStepUpRequired        = "step_up_required"
+	codeTOTPRequired          = "totp_required"
+	codeInvalidTOTPCode       = "invalid_totp_code"
+	codeTOTPNotEnrolled       = "totp_not_enrolled"
+	codeTOTPAlreadyEnabled    = "totp_already_enabled"
+	codeInvalidCredentials    = "invalid_credentials"
+	codeInvalidReference      = "invalid_reference"
+	codeForbidden             = "forbidden"
+	codeNotAccountOwner       = "not_account_owner"
+	codeOtherOrganization     = "other_organization"
+	codeOriginNotAllowed      = "origin_not_allowed"
+	codeAccountFrozen         = "account_frozen"
+	codeNotFound              = "not_found"
+	codeAccountNotFound       = "account_not_found"
+	codeAccountDeleted        = "account_deleted"
+	codeUserNotFound          = "user_not_found"
+	codeTransferNotFound      = "transfer_not_found"
+	codeScheduleNotFound      = "scheduled_transfer_not_found"
+	codeHoldNotFound          = "hold_not_found"
+	codeSessionNotFound       = "session_not_found"
+	codeConflict              = "conflict"
+	codeAccountExists         = "account_already_exists"
+	codeUserExists            = "user_already_exists"
+	codeEmailInUse            = "email_in_use"
+	codeUsernameInUse         = "username_in_use"
+	codeAccountNotEmpty       = "account_not_empty"
+	codeInsufficientBalance   = "insufficient_balance"
+	codeIdempotencyMismatch   = "idempotency_key_mismatch"
+	codeTransferNotReversible = "transfer_not_reversible"
+	codeScheduleCancelled     = "scheduled_transfer_cancelled"
+	codeHoldNotAuthorized     = "hold_not_authorized"
+	codeHoldExpired           = "hold_expired"
+	codeCaptureExceedsHold    = "capture_exceeds_hold"
+	codeVersionConflict       = "version_conflict"
+	codeImportRolledBack      = "import_rolled_back"
+	codeAccountLocked         = "account_locked"
+	codeDailyLimitExceeded    = "daily_limit_exceeded"
+	codeSpendingLimitExceeded = "spending_limit_exceeded"
+	codeRateLimited           = "rate_limited"
+	codeRequestTooLarge       = "request_too_large"
+	codeInternal              = "internal_error"
+	codeUnavailable           = "unavailable"
+	codeTimeout               = "timeout"
+)
+
+// apiError is the body of every error response
+type apiError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []fieldError `json:"details,omitempty"`
+}
+
+// errorResponse builds the error body for the given code, the request validation errors are listed one per field in its details
+func errorResponse(code string, err error) apiError {
+	rsp := apiError{
+		Code:    code,
+		Message: err.Error(),
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		rsp.Details = translateValidationErrors(validationErrs)
+	}
+
+	return rsp
+}
+
+// codeForStatus returns the generic code of an http status, for the errors whose status is only known at runtime
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return codeInvalidRequest
+	case http.StatusUnauthorized:
+		return codeUnauthorized
+	case http.StatusForbidden:
+		return codeForbidden
+	case http.StatusNotFound:
+		return codeNotFound
+	case http.StatusConflict:
+		return codeConflict
+	case http.StatusLocked:
+		return codeAccountLocked
+	case http.StatusRequestEntityTooLarge:
+		return codeRequestTooLarge
+	case http.StatusTooManyRequests:
+		return codeRateLimited
+	case http.StatusServiceUnavailable:
+		return codeUnavailable
+	}
+	return codeInternal
+}
+
+// dbErrorToHTTP maps a store error to the status and the generic code of its response
+// Serialization failures and deadlocks are only returned once the store ran out of retries, the client may try again later
+// A lost database connection is reported as unavailable too, the pool reconnects once the database is back
+func dbErrorToHTTP(err error) (int, string) {
+	if errors.Is(err, sql.ErrNoRows) {
+		return http.StatusNotFound, codeNotFound
+	}
+	if db.IsConnectionError(err) {
+		return http.StatusServiceUnavailable, codeUnavailable
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return http.StatusConflict, codeConflict
+		case "foreign_key_violation":
+			return http.StatusUnprocessableEntity, codeInvalidReference
+		case "serialization_failure", "deadlock_detected":
+			return http.StatusServiceUnavailable, codeUnavailable
+		}
+	}
+
+	return http.StatusInternalServerError, codeInternal
+}
+
+// respondDBError writes the error response of a failed store call, notFoundCode names the missing resource
+// A server error after the request deadline expired is answered as a timeout, the store call was cut short by it
+func respondDBError(ctx *gin.Context, err error, notFoundCode string) {
+	status, code := dbErrorToHTTP(err)
+	if status == http.StatusNotFound {
+		code = notFoundCode
+	}
+	if status >= http.StatusInternalServerError && errors.Is(ctx.Request.Context().Err(), context.DeadlineExceeded) {
+		status, code = http.StatusGatewayTimeout, codeTimeout
+	}
+	ctx.JSON(status, errorResponse(code, err))
+}
+
+// transferErrorToHTTP maps the business errors of the transfer-like store calls to the status and code of their response
+// It returns false for any other error, the caller answers those with respondDBError
+func transferErrorToHTTP(err error) (int, string, bool) {
+	switch {
+	case errors.Is(err, db.ErrInsufficientBalance):
+		return http.StatusBadRequest, codeInsufficientBalance, true
+	case errors.Is(err, db.ErrCurrencyMismatch):
+		return http.StatusBadRequest, codeCurrencyMismatch, true
+	case errors.Is(err, db.ErrConversionTooSmall):
+		return http.StatusBadRequest, codeAmountOutOfRange, true
+	case errors.Is(err, db.ErrInvalidSplit):
+		return http.StatusBadRequest, codeInvalidRequest, true
+	case errors.Is(err, db.ErrCaptureExceedsHold):
+		return http.StatusBadRequest, codeCaptureExceedsHold, true
+	case errors.Is(err, db.ErrAccountNotFound):
+		return http.StatusNotFound, codeAccountNotFound, true
+	case errors.Is(err, db.ErrAccountFrozen):
+		return http.StatusForbidden, codeAccountFrozen, true
+	case errors.Is(err, db.ErrOrganizationMismatch):
+		return http.StatusForbidden, codeOtherOrganization, true
+	case errors.Is(err, db.ErrIdempotencyKeyMismatch):
+		return http.StatusConflict, codeIdempotencyMismatch, true
+	case errors.Is(err, db.ErrTransferAlreadyReversed), errors.Is(err, db.ErrTransferIsReversal):
+		return http.StatusConflict, codeTransferNotReversible, true
+	case errors.Is(err, db.ErrAccountDeleted):
+		return http.StatusConflict, codeAccountDeleted, true
+	case errors.Is(err, db.ErrHoldExpired):
+		return http.StatusConflict, codeHoldExpired, true
+	case errors.Is(err, db.ErrHoldNotAuthorized):
+		return http.StatusConflict, codeHoldNotAuthorized, true
+	case errors.Is(err, db.ErrNoExchangeRate):
+		return http.StatusUnprocessableEntity, codeNoExchangeRate, true
+	case errors.Is(err, db.ErrDailyTransferLimit):
+		return http.StatusTooManyRequests, codeDailyLimitExceeded, true
+	case errors.Is(err, db.ErrAccountSpendingLimit):
+		return http.StatusTooManyRequests, codeSpendingLimitExceeded, true
+	}
+	return 0, "", false
+}
+
+// respondTransferError writes the error response of a failed transfer-like store call, notFoundCode names the missing resource
+func respondTransferError(ctx *gin.Context, err error, notFoundCode string) {
+	if status, code, ok := transferErrorToHTTP(err); ok {
+		ctx.JSON(status, errorResponse(code, err))
+		return
+	}
+	respondDBError(ctx, err, notFoundCode)
+}
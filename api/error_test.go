@@ -0,0 +1,145 @@
+package api
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/lib/pq"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+)
+
+// requireErrorCode checks the response is an error body carrying the given code
+func requireErrorCode(t *testing.T, recorder *httptest.ResponseRecorder, code string) apiError {
+	var rsp apiError
+	err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Equal(t, code, rsp.Code)
+	require.NotEmpty(t, rsp.Message)
+
+	return rsp
+}
+
+func TestErrorResponse(t *testing.T) {
+	rsp := errorResponse(codeAccountNotFound, errors.New("account not found"))
+	require.Equal(t, apiError{Code: codeAccountNotFound, Message: "account not found"}, rsp)
+
+	body, err := json.Marshal(rsp)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"code": "account_not_found", "message": "account not found"}`, string(body))
+
+	type split struct {
+		Amount int64 `json:"amount" validate:"min=1"`
+	}
+	type req struct {
+		Amount   int64   `json:"amount" validate:"required,min=1"`
+		Currency string  `form:"currency" validate:"required"`
+		Note     string  `validate:"max=3"`
+		Splits   []split `json:"splits" validate:"max=1,dive"`
+	}
+	v := validator.New()
+	v.RegisterTagNameFunc(fieldName)
+	err = v.Struct(req{Note: "too long", Splits: []split{{Amount: 1}, {Amount: 0}}})
+	require.Error(t, err)
+
+	rsp = errorResponse(codeInvalidRequest, err)
+	require.Equal(t, codeInvalidRequest, rsp.Code)
+	require.Equal(t, []fieldError{
+		{Field: "amount", Rule: "required", Message: "amount is required"},
+		{Field: "currency", Rule: "required", Message: "currency is required"},
+		{Field: "Note", Rule: "max", Message: "Note must be at most 3 characters"},
+		{Field: "splits", Rule: "max", Message: "splits must be at most 1 items"},
+	}, rsp.Details)
+
+	err = v.Struct(req{Amount: 1, Currency: "USD", Splits: []split{{Amount: 0}}})
+	require.Error(t, err)
+	require.Equal(t, []fieldError{
+		{Field: "splits[0].amount", Rule: "min", Message: "splits[0].amount must be at least 1"},
+	}, errorResponse(codeInvalidRequest, err).Details)
+}
+
+func TestCodeForStatus(t *testing.T) {
+	require.Equal(t, codeInvalidRequest, codeForStatus(http.StatusBadRequest))
+	require.Equal(t, codeUnauthorized, codeForStatus(http.StatusUnauthorized))
+	require.Equal(t, codeNotFound, codeForStatus(http.StatusNotFound))
+	require.Equal(t, codeInternal, codeForStatus(http.StatusInternalServerError))
+	require.Equal(t, codeInternal, codeForStatus(http.StatusTeapot))
+}
+
+func TestDBErrorToHTTP(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{name: "no rows", err: sql.ErrNoRows, status: http.StatusNotFound, code: codeNotFound},
+		{name: "wrapped no rows", err: fmt.Errorf("get account: %w", sql.ErrNoRows), status: http.StatusNotFound, code: codeNotFound},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}, status: http.StatusConflict, code: codeConflict},
+		{name: "foreign key violation", err: &pq.Error{Code: "23503"}, status: http.StatusUnprocessableEntity, code: codeInvalidReference},
+		{name: "serialization failure", err: &pq.Error{Code: "40001"}, status: http.StatusServiceUnavailable, code: codeUnavailable},
+		{name: "deadlock", err: &pq.Error{Code: "40P01"}, status: http.StatusServiceUnavailable, code: codeUnavailable},
+		{name: "wrapped serialization failure", err: fmt.Errorf("transfer tx: %w", &pq.Error{Code: "40001"}), status: http.StatusServiceUnavailable, code: codeUnavailable},
+		{name: "other postgres error", err: &pq.Error{Code: "42601"}, status: http.StatusInternalServerError, code: codeInternal},
+		{name: "connection done", err: sql.ErrConnDone, status: http.StatusServiceUnavailable, code: codeUnavailable},
+		{name: "bad connection", err: fmt.Errorf("get account: %w", driver.ErrBadConn), status: http.StatusServiceUnavailable, code: codeUnavailable},
+		{name: "database shutting down", err: &pq.Error{Code: "57P01"}, status: http.StatusServiceUnavailable, code: codeUnavailable},
+		{name: "connection failure", err: &pq.Error{Code: "08006"}, status: http.StatusServiceUnavailable, code: codeUnavailable},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, status: http.StatusServiceUnavailable, code: codeUnavailable},
+		{name: "transaction done", err: sql.ErrTxDone, status: http.StatusInternalServerError, code: codeInternal},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			status, code := dbErrorToHTTP(tc.err)
+			require.Equal(t, tc.status, status)
+			require.Equal(t, tc.code, code)
+		})
+	}
+}
+
+func TestTransferErrorToHTTP(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{name: "insufficient balance", err: db.ErrInsufficientBalance, status: http.StatusBadRequest, code: codeInsufficientBalance},
+		{name: "wrapped insufficient balance", err: fmt.Errorf("transfer tx: %w", db.ErrInsufficientBalance), status: http.StatusBadRequest, code: codeInsufficientBalance},
+		{name: "deleted account", err: db.ErrAccountNotFound, status: http.StatusNotFound, code: codeAccountNotFound},
+		{name: "frozen account", err: db.ErrAccountFrozen, status: http.StatusForbidden, code: codeAccountFrozen},
+		{name: "already reversed", err: db.ErrTransferAlreadyReversed, status: http.StatusConflict, code: codeTransferNotReversible},
+		{name: "reversal of a reversal", err: db.ErrTransferIsReversal, status: http.StatusConflict, code: codeTransferNotReversible},
+		{name: "reversal into a deleted account", err: db.ErrAccountDeleted, status: http.StatusConflict, code: codeAccountDeleted},
+		{name: "expired hold", err: db.ErrHoldExpired, status: http.StatusConflict, code: codeHoldExpired},
+		{name: "no exchange rate", err: db.ErrNoExchangeRate, status: http.StatusUnprocessableEntity, code: codeNoExchangeRate},
+		{name: "daily limit", err: db.ErrDailyTransferLimit, status: http.StatusTooManyRequests, code: codeDailyLimitExceeded},
+		{name: "spending limit", err: db.ErrAccountSpendingLimit, status: http.StatusTooManyRequests, code: codeSpendingLimitExceeded},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			status, code, ok := transferErrorToHTTP(tc.err)
+			require.True(t, ok)
+			require.Equal(t, tc.status, status)
+			require.Equal(t, tc.code, code)
+		})
+	}
+
+	// the store errors are left to dbErrorToHTTP
+	_, _, ok := transferErrorToHTTP(sql.ErrNoRows)
+	require.False(t, ok)
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout bounds the database ping, so a hanging connection marks the server as not ready instead of blocking the probe
+const readinessTimeout = 2 * time.Second
+
+// healthz reports the server process is up, it doesn't check any dependency
+func (s *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyz reports whether the server can handle traffic, which requires a reachable database
+func (s *Server) readyz(ctx *gin.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	if err := s.store.Ping(pingCtx); err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, errorResponse(codeUnavailable, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
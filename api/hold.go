@@ -0,0 +1,184 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"io"
+	"net/http"
+	"time"
+)
+
+type (
+	holdURIReq struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+
+	captureHoldReq struct {
+		// Amount is the part of the hold transferred, the whole hold is captured when it's missing
+		Amount utils.Money `json:"amount" binding:"omitempty,min=1"`
+	}
+
+	// holdResponse sends the uuid of the transfer that captured the hold and the numbers of its accounts in place of their
+	// internal ids. The id fields are never set, they only shadow the ones of db.Hold
+	holdResponse struct {
+		db.Hold
+		FromAccountID     *int64     `json:"from_account_id,omitempty"`
+		ToAccountID       *int64     `json:"to_account_id,omitempty"`
+		FromAccountNumber string     `json:"from_account_number"`
+		ToAccountNumber   string     `json:"to_account_number"`
+		TransferID        *uuid.UUID `json:"transfer_id"`
+	}
+
+	holdTxResponse struct {
+		Hold        holdResponse    `json:"hold"`
+		FromAccount accountResponse `json:"from_account"`
+	}
+
+	captureHoldResponse struct {
+		transferTxResponse
+		Hold holdResponse `json:"hold"`
+	}
+)
+
+// newHoldResponse takes the uuid of the transfer that captured the hold, it's nil for a hold that wasn't captured
+func newHoldResponse(hold db.Hold, numbers accountNumbers, transferID *uuid.UUID) holdResponse {
+	return holdResponse{
+		Hold:              hold,
+		FromAccountNumber: numbers[hold.FromAccountID],
+		ToAccountNumber:   numbers[hold.ToAccountID],
+		TransferID:        transferID,
+	}
+}
+
+// authorizeTransfer places a hold on the from account: the amount stays in its balance but can't be spent until the hold
+// is captured, voided or expires. The accounts are checked like a regular transfer
+func (s *Server) authorizeTransfer(ctx *gin.Context) {
+	var req createTransferReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	if err := s.checkTransferAmount(int64(req.Amount)); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeAmountOutOfRange, err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	fromAccount, toAccount, ok := s.validTransferAccounts(ctx, req.FromAccountNumber, req.ToAccountNumber, req.Currency, authPayload.UserName, false)
+	if !ok {
+		return
+	}
+
+	result, err := s.store.AuthorizeHoldTx(ctx, db.AuthorizeHoldTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        int64(req.Amount),
+		ExpiresAt:     time.Now().Add(s.config.HoldExpiration),
+	})
+	if err != nil {
+		respondTransferError(ctx, err, codeAccountNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, holdTxResponse{
+		Hold:        newHoldResponse(result.Hold, newAccountNumbers(fromAccount, toAccount), nil),
+		FromAccount: newAccountResponse(result.FromAccount, requestAmountFormat(ctx)),
+	})
+}
+
+// captureHold transfers the authorized amount, or a part of it, to the receiver of the hold and releases the rest
+func (s *Server) captureHold(ctx *gin.Context) {
+	var uri holdURIReq
+	if !bindURI(ctx, &uri) {
+		return
+	}
+
+	// the body is optional, an empty one captures the whole hold
+	var req captureHoldReq
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondBindError(ctx, err)
+		return
+	}
+
+	if _, _, ok := s.ownsHold(ctx, uri.ID); !ok {
+		return
+	}
+
+	result, err := s.store.CaptureHoldTx(ctx, db.CaptureHoldTxParams{
+		HoldID:     uri.ID,
+		Amount:     int64(req.Amount),
+		DailyLimit: s.config.DailyTransferLimit,
+	})
+	if err != nil {
+		respondTransferError(ctx, err, codeHoldNotFound)
+		return
+	}
+
+	numbers := newAccountNumbers(result.FromAccountID, result.ToAccountID)
+	ctx.JSON(http.StatusOK, captureHoldResponse{
+		transferTxResponse: newTransferTxResponse(result.TransferTxResult, requestAmountFormat(ctx)),
+		Hold:               newHoldResponse(result.Hold, numbers, &result.Transfer.UUID),
+	})
+}
+
+// voidHold cancels an authorized hold, its amount is available to the from account again
+func (s *Server) voidHold(ctx *gin.Context) {
+	var uri holdURIReq
+	if !bindURI(ctx, &uri) {
+		return
+	}
+
+	hold, fromAccount, ok := s.ownsHold(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	// the receiver number is read before the void, so a voided hold is never answered with an error
+	numbers, ok := s.lookupAccountNumbers(ctx, []int64{hold.ToAccountID}, fromAccount)
+	if !ok {
+		return
+	}
+
+	result, err := s.store.VoidHoldTx(ctx, db.VoidHoldTxParams{HoldID: uri.ID})
+	if err != nil {
+		respondTransferError(ctx, err, codeHoldNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, holdTxResponse{
+		Hold:        newHoldResponse(result.Hold, numbers, nil),
+		FromAccount: newAccountResponse(result.FromAccount, requestAmountFormat(ctx)),
+	})
+}
+
+// ownsHold checks that the hold exists and that its from account belongs to the authenticated user, writing the error response
+// It returns the hold and its from account as they were read, the account is found even if it was closed since
+func (s *Server) ownsHold(ctx *gin.Context, holdID int64) (db.Hold, db.Account, bool) {
+	hold, err := s.store.GetHold(ctx, holdID)
+	if err != nil {
+		respondDBError(ctx, err, codeHoldNotFound)
+		return hold, db.Account{}, false
+	}
+
+	fromAccount, err := s.store.GetAccountIncludingDeleted(ctx, hold.FromAccountID)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return hold, fromAccount, false
+	}
+	if !checkSameOrg(ctx, fromAccount.OrgID) {
+		return hold, fromAccount, false
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.UserName {
+		err = fmt.Errorf("hold wasn't authorized by the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return hold, fromAccount, false
+	}
+	return hold, fromAccount, true
+}
@@ -0,0 +1,499 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func randomHold(fromAccount, toAccount db.Account) db.Hold {
+	return db.Hold{
+		ID:            utils.RandomInt(1, 1000),
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        _amount,
+		Status:        db.HoldStatusAuthorized,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	}
+}
+
+func TestAuthorizeTransferAPI(t *testing.T) {
+	sender, _ := randomUser()
+	receiver, _ := randomUser()
+	fromAccount := randomAccount(sender.Username)
+	toAccount := randomAccount(receiver.Username)
+	toAccount.ID = fromAccount.ID + 1000
+	hold := randomHold(fromAccount, toAccount)
+
+	heldAccount := fromAccount
+	heldAccount.HeldBalance = _amount
+
+	body := gin.H{
+		"from_account_number": fromAccount.AccountNumber,
+		"to_account_number":   toAccount.AccountNumber,
+		"amount":              _amount,
+		"currency":            utils.USD,
+	}
+	lookupNumbers := []string{fromAccount.AccountNumber, toAccount.AccountNumber}
+	accounts := map[string]db.Account{fromAccount.AccountNumber: fromAccount, toAccount.AccountNumber: toAccount}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path authorize transfer",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq(lookupNumbers)).Times(1).Return(accounts, nil)
+				store.EXPECT().AuthorizeHoldTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.AuthorizeHoldTxParams) (db.AuthorizeHoldTxResult, error) {
+						require.Equal(t, fromAccount.ID, arg.FromAccountID)
+						require.Equal(t, toAccount.ID, arg.ToAccountID)
+						require.Equal(t, int64(_amount), arg.Amount)
+						require.False(t, arg.ExpiresAt.IsZero())
+						return db.AuthorizeHoldTxResult{Hold: hold, FromAccount: heldAccount}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp holdTxResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, hold.ID, rsp.Hold.ID)
+				require.Equal(t, fromAccount.AccountNumber, rsp.Hold.FromAccountNumber)
+				require.Equal(t, toAccount.AccountNumber, rsp.Hold.ToAccountNumber)
+				require.Equal(t, heldAccount.Balance, rsp.FromAccount.Balance)
+				require.Equal(t, int64(_amount), rsp.FromAccount.HeldBalance)
+			},
+		},
+		{
+			name: "available balance too low",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq(lookupNumbers)).Times(1).Return(accounts, nil)
+				store.EXPECT().AuthorizeHoldTx(gomock.Any(), gomock.Any()).Times(1).Return(db.AuthorizeHoldTxResult{}, db.ErrInsufficientBalance)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInsufficientBalance)
+			},
+		},
+		{
+			name: "from account of another user",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, receiver.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq(lookupNumbers)).Times(1).Return(accounts, nil)
+				store.EXPECT().AuthorizeHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireErrorCode(t, recorder, codeNotAccountOwner)
+			},
+		},
+		{
+			name: "invalid amount",
+			body: gin.H{
+				"from_account_number": fromAccount.AccountNumber,
+				"to_account_number":   toAccount.AccountNumber,
+				"amount":              -1,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().AuthorizeHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers/authorize", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCaptureHoldAPI(t *testing.T) {
+	sender, _ := randomUser()
+	receiver, _ := randomUser()
+	fromAccount := randomAccount(sender.Username)
+	toAccount := randomAccount(receiver.Username)
+	hold := randomHold(fromAccount, toAccount)
+
+	captured := hold
+	captured.Status = db.HoldStatusCaptured
+	captured.TransferID = sql.NullInt64{Int64: utils.RandomInt(1, 1000), Valid: true}
+	result := db.CaptureHoldTxResult{
+		TransferTxResult: db.TransferTxResult{
+			Transfer: db.Transfer{ID: captured.TransferID.Int64, UUID: uuid.New(), FromAccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: _amount},
+		},
+		Hold: captured,
+	}
+
+	testCases := []struct {
+		name          string
+		holdID        int64
+		body          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "capture the whole hold",
+			holdID: hold.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Eq(db.CaptureHoldTxParams{HoldID: hold.ID})).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp captureHoldResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, db.HoldStatusCaptured, rsp.Hold.Status)
+				require.Equal(t, result.Transfer.UUID, rsp.Transfer.ID)
+				require.NotNil(t, rsp.Hold.TransferID)
+				require.Equal(t, result.Transfer.UUID, *rsp.Hold.TransferID)
+			},
+		},
+		{
+			name:   "capture a part of the hold",
+			holdID: hold.ID,
+			body:   `{"amount": 12}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Eq(db.CaptureHoldTxParams{HoldID: hold.ID, Amount: 12})).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "capture exceeds the hold",
+			holdID: hold.ID,
+			body:   fmt.Sprintf(`{"amount": %v}`, hold.Amount+1),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CaptureHoldTxResult{}, db.ErrCaptureExceedsHold)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeCaptureExceedsHold)
+			},
+		},
+		{
+			name:   "expired hold",
+			holdID: hold.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := hold
+				expired.Status = db.HoldStatusExpired
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CaptureHoldTxResult{Hold: expired}, db.ErrHoldExpired)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeHoldExpired)
+			},
+		},
+		{
+			name:   "hold already captured",
+			holdID: hold.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(captured, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CaptureHoldTxResult{}, db.ErrHoldNotAuthorized)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeHoldNotAuthorized)
+			},
+		},
+		{
+			name:   "hold of another user",
+			holdID: hold.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, receiver.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeNotAccountOwner)
+			},
+		},
+		{
+			name:   "hold not found",
+			holdID: hold.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(db.Hold{}, sql.ErrNoRows)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeHoldNotFound)
+			},
+		},
+		{
+			name:   "invalid amount",
+			holdID: hold.ID,
+			body:   `{"amount": -1}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CaptureHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/transfers/%d/capture", tc.holdID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(tc.body)))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestVoidHoldAPI(t *testing.T) {
+	sender, _ := randomUser()
+	receiver, _ := randomUser()
+	fromAccount := randomAccount(sender.Username)
+	toAccount := randomAccount(receiver.Username)
+	toAccount.ID = fromAccount.ID + 1000
+	hold := randomHold(fromAccount, toAccount)
+
+	voided := hold
+	voided.Status = db.HoldStatusVoided
+	numbers := []db.GetAccountNumbersRow{{ID: toAccount.ID, AccountNumber: toAccount.AccountNumber}}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path void hold",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccountNumbers(gomock.Any(), gomock.Eq([]int64{toAccount.ID})).Times(1).Return(numbers, nil)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Eq(db.VoidHoldTxParams{HoldID: hold.ID})).
+					Times(1).
+					Return(db.VoidHoldTxResult{Hold: voided, FromAccount: fromAccount}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp holdTxResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, db.HoldStatusVoided, rsp.Hold.Status)
+				require.Equal(t, fromAccount.AccountNumber, rsp.Hold.FromAccountNumber)
+				require.Equal(t, toAccount.AccountNumber, rsp.Hold.ToAccountNumber)
+				require.Zero(t, rsp.FromAccount.HeldBalance)
+				require.NotContains(t, recorder.Body.String(), `"from_account_id"`)
+			},
+		},
+		{
+			name: "from account closed since",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				closedAccount := fromAccount
+				closedAccount.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(closedAccount, nil)
+				store.EXPECT().GetAccountNumbers(gomock.Any(), gomock.Eq([]int64{toAccount.ID})).Times(1).Return(numbers, nil)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Eq(db.VoidHoldTxParams{HoldID: hold.ID})).
+					Times(1).
+					Return(db.VoidHoldTxResult{Hold: voided, FromAccount: closedAccount}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "hold already voided",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(voided, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccountNumbers(gomock.Any(), gomock.Eq([]int64{toAccount.ID})).Times(1).Return(numbers, nil)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Any()).Times(1).Return(db.VoidHoldTxResult{}, db.ErrHoldNotAuthorized)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeHoldNotAuthorized)
+			},
+		},
+		{
+			name: "hold of another user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, receiver.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Eq(hold.ID)).Times(1).Return(hold, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeNotAccountOwner)
+			},
+		},
+		{
+			name: "no authorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetHold(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().VoidHoldTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/transfers/%d/void", hold.ID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -8,65 +9,194 @@ import (
 	db "github.com/micaelapucciariello/simplebank/db/sqlc"
 	"github.com/micaelapucciariello/simplebank/token"
 	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/micaelapucciariello/simplebank/worker"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"net/http"
 )
 
 type Server struct {
-	store  db.Store
-	router *gin.Engine
-	token  token.Maker
-	config utils.Config
+	store           db.Store
+	router          *gin.Engine
+	token           *token.DurationMaker
+	config          utils.Config
+	logger          zerolog.Logger
+	taskDistributor worker.TaskDistributor
+	buildInfo       utils.BuildInfo
+	// publicRoutes are the unauthenticated routes, rate limited per client when a limit is configured
+	publicRoutes *gin.RouterGroup
+	// ctx lives as long as the server, the background goroutines it starts stop when Close cancels it
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func NewServer(config utils.Config, store db.Store) (server *Server, err error) {
-	router := gin.Default()
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+// gatewayPrefix is the path the gRPC gateway routes are served under
+const gatewayPrefix = "/v1"
+
+func NewServer(config utils.Config, store db.Store, taskDistributor worker.TaskDistributor) (server *Server, err error) {
+	// the mode is global to gin, it must be set before creating the router
+	if config.GinMode != "" {
+		gin.SetMode(config.GinMode)
+	}
+	router := gin.New()
+	// gin trusts every proxy by default, letting any client pick its ClientIP through X-Forwarded-For
+	// and slip past the rate limits, only the configured proxies are believed
+	if err = router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	// handlers pass the gin context to the store, it must follow the request context: its trace span, deadline and cancellation
+	router.ContextWithFallback = true
+	tokenMaker, err := token.NewMaker(config.TokenType, config.TokenSymmetricKey, config.TokenIssuer, config.TokenAudience)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token validator: %w", err)
 	}
+	logger, err := utils.NewLogger(config, gin.DefaultWriter)
+	if err != nil {
+		return nil, err
+	}
 
 	server = &Server{
-		store:  store,
-		token:  tokenMaker,
-		config: config,
+		store:           store,
+		token:           token.NewDurationMaker(tokenMaker, config.TokenDuration, config.RefreshTokenDuration, config.StepUpTokenDuration),
+		config:          config,
+		logger:          logger,
+		taskDistributor: taskDistributor,
+		buildInfo:       utils.CurrentBuildInfo(),
 	}
+	server.ctx, server.cancel = context.WithCancel(context.Background())
 
-	// set currency validator
+	// set the custom validators, the validation errors name the fields like the client sent them
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
 		err = v.RegisterValidation("currency", validCurrency)
 		if err != nil {
 			return nil, err
 		}
+		err = v.RegisterValidation("username", validUsername)
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	router.Use(gin.Recovery(), requestIDMiddleware(), tracingMiddleware(otel.Tracer(utils.TracerName)), server.loggerMiddleware())
+	if config.RequestTimeout > 0 {
+		router.Use(timeoutMiddleware(config.RequestTimeout))
+	}
+	if config.SecurityHeaders {
+		router.Use(securityHeadersMiddleware(config.SecurityHeadersCSP, config.TLSCertFile != "" && config.TLSKeyFile != ""))
+	}
+	router.Use(bodyLimitMiddleware(config.MaxRequestBodyBytes, routeBodyLimits(config)))
+	// without configured origins browsers are kept to same-origin requests
+	if len(config.AllowedOrigins) > 0 {
+		router.Use(corsMiddleware(newCORSPolicy(config.AllowedOrigins)))
+	}
 	server.initRouter(router)
 	server.router = router
 
 	return
 }
 
-// Start runs the server in the specified address
+// Start runs the server in the specified address, over TLS when both the certificate and key files are configured
 func (s *Server) Start(address string) error {
-	return s.router.Run(address)
+	if s.config.TLSCertFile == "" || s.config.TLSKeyFile == "" {
+		return s.router.Run(address)
+	}
+	return s.startTLS(address)
+}
+
+// Close stops the background goroutines of the server, like the rate limiter cleanup
+func (s *Server) Close() {
+	s.cancel()
+}
+
+// MountGateway serves the gRPC gateway alongside the api routes, so both are reachable on the same address
+// Its user endpoints are public, they are rate limited like the api ones
+func (s *Server) MountGateway(gateway http.Handler) {
+	s.publicRoutes.Any(gatewayPrefix+"/*path", func(ctx *gin.Context) {
+		// the gateway hands X-Forwarded-For to the rpcs as the client ip, it's replaced by the one gin resolved
+		// through the trusted proxies so a client can't spoof it
+		ctx.Request.Header.Set("X-Forwarded-For", ctx.ClientIP())
+		gateway.ServeHTTP(ctx.Writer, ctx.Request)
+	})
+}
+
+// routeBodyLimits are the routes replacing MAX_REQUEST_BODY_BYTES with their own limit, an unset limit keeps the global one
+func routeBodyLimits(config utils.Config) map[string]int64 {
+	limits := make(map[string]int64)
+	if config.MaxImportBodyBytes > 0 {
+		limits["/admin/users/import"] = config.MaxImportBodyBytes
+	}
+	return limits
 }
 
 func (s *Server) initRouter(router *gin.Engine) {
+	// probes used by kubernetes and load balancers, they are neither authenticated nor rate limited
+	router.GET("/healthz", s.healthz)
+	router.GET("/readyz", s.readyz)
+	// the build info is public too, it tells nothing about the users or their accounts
+	router.GET("/version", s.version)
+
+	// the public user endpoints are rate limited per client to slow down brute force attacks
+	limitedRoutes := router.Group("/")
+	if s.config.RateLimitRequests > 0 && s.config.RateLimitWindow > 0 {
+		limiter := newRateLimiter(s.config.RateLimitRequests, s.config.RateLimitWindow)
+		go limiter.runCleanup(s.ctx, s.config.RateLimitWindow)
+		limitedRoutes.Use(rateLimitMiddleware(limiter))
+	}
+	s.publicRoutes = limitedRoutes
+
 	// declares the api routes and its functions
-	router.POST("/users", s.createUser)
-	router.POST("/users/login", s.loginUser)
-	router.POST("/token/new", s.renewAccessToken)
+	limitedRoutes.POST("/users", s.createUser)
+	limitedRoutes.POST("/users/login", s.loginUser)
+	limitedRoutes.POST("/users/forgot_password", s.forgotPassword)
+	limitedRoutes.POST("/users/reset_password", s.resetPassword)
+	router.POST("/tokens/renew_access", s.renewAccessToken)
 
-	authRoutes := router.Group("/", authMiddleware(s.token))
+	authRoutes := router.Group("/", authMiddleware(s.token, s.store), amountFormatMiddleware())
 	authRoutes.GET("/users/:username", s.getUser)
+	authRoutes.PATCH("/users/:username", s.updateUser)
+	authRoutes.POST("/users/step_up", s.stepUp)
+	authRoutes.POST("/users/:username/change_password", s.changePassword)
+	authRoutes.POST("/users/2fa/enroll", s.enrollTOTP)
+	authRoutes.POST("/users/2fa/verify", s.verifyTOTP)
+	authRoutes.GET("/users/notification_preferences", s.getNotificationPreferences)
+	authRoutes.PUT("/users/notification_preferences", s.updateNotificationPreferences)
 
 	authRoutes.POST("/accounts", s.createAccount)
-	authRoutes.GET("/accounts/:id", s.getAccount)
+	authRoutes.GET("/accounts/:number", s.getAccount)
 	authRoutes.GET("/accounts", s.getAccountsList)
-	authRoutes.DELETE("/accounts/:id", s.deleteAccount)
+	authRoutes.DELETE("/accounts/:number", s.deleteAccount)
+	authRoutes.POST("/accounts/:number/close", s.closeAccount)
+	authRoutes.PATCH("/accounts/:number/balance", s.updateAccountBalance)
+	authRoutes.PATCH("/accounts/:number/labels", s.setAccountLabels)
+	authRoutes.PATCH("/accounts/:number/limits", s.setAccountLimits)
+	authRoutes.PATCH("/accounts/:number/low_balance_threshold", s.setLowBalanceThreshold)
+	authRoutes.POST("/accounts/:number/deposit", s.deposit)
+	authRoutes.POST("/accounts/:number/withdraw", s.withdraw)
+	authRoutes.GET("/accounts/:number/statement", s.getAccountStatement)
+	authRoutes.GET("/accounts/:number/entries", s.listEntries)
+	authRoutes.GET("/accounts/:number/balance_history", s.getBalanceHistory)
+	authRoutes.GET("/accounts/:number/stats", s.getAccountStats)
+	authRoutes.POST("/accounts/:number/transfer_ownership", authorizeRoles(utils.BankerRole), s.transferAccountOwnership)
 
 	authRoutes.POST("/transfers", s.createTranfer)
-}
+	authRoutes.POST("/transfers/split", s.createSplitTransfer)
+	authRoutes.GET("/transfers", s.listTransfers)
+	authRoutes.POST("/transfers/:id/reverse", s.reverseTransfer)
+	authRoutes.POST("/transfers/authorize", s.authorizeTransfer)
+	authRoutes.POST("/transfers/:id/capture", s.captureHold)
+	authRoutes.POST("/transfers/:id/void", s.voidHold)
+
+	authRoutes.POST("/scheduled_transfers", s.createScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers", s.listScheduledTransfers)
+	authRoutes.DELETE("/scheduled_transfers/:id", s.cancelScheduledTransfer)
 
-// errResponse returns a gin key-value error
-func errResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+	adminRoutes := authRoutes.Group("/admin", authorizeRoles(utils.BankerRole))
+	adminRoutes.GET("/accounts", s.listAllAccounts)
+	adminRoutes.POST("/accounts/:number/restore", s.restoreAccount)
+	adminRoutes.POST("/accounts/:number/freeze", s.freezeAccount)
+	adminRoutes.POST("/accounts/:number/unfreeze", s.unfreezeAccount)
+	adminRoutes.GET("/users/search", s.searchUsers)
+	adminRoutes.POST("/users/import", s.importUsers)
+	adminRoutes.GET("/audit_logs", s.listAuditLogs)
 }
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	proxyIP := "10.0.0.1"
+	clientIP := "203.0.113.7"
+
+	testCases := []struct {
+		name           string
+		trustedProxies []string
+		remoteIP       string
+		expectedIP     string
+	}{
+		{
+			name:           "forwarded by a trusted proxy",
+			trustedProxies: []string{proxyIP},
+			remoteIP:       proxyIP,
+			expectedIP:     clientIP,
+		},
+		{
+			name:           "forwarded by a trusted proxy range",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteIP:       proxyIP,
+			expectedIP:     clientIP,
+		},
+		{
+			name:           "forwarded by an untrusted client",
+			trustedProxies: []string{proxyIP},
+			remoteIP:       "10.0.0.2",
+			expectedIP:     "10.0.0.2",
+		},
+		{
+			name:           "no trusted proxies",
+			trustedProxies: nil,
+			remoteIP:       proxyIP,
+			expectedIP:     proxyIP,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			config := utils.Config{
+				TokenSymmetricKey: utils.RandomString(32),
+				TokenDuration:     time.Minute,
+				TrustedProxies:    tc.trustedProxies,
+			}
+			server, err := NewServer(config, nil, newTestTaskDistributor())
+			require.NoError(t, err)
+
+			url := "/client_ip"
+			server.router.GET(url, func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, ctx.ClientIP())
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			request.RemoteAddr = tc.remoteIP + ":1234"
+			request.Header.Set("X-Forwarded-For", clientIP)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.Equal(t, tc.expectedIP, recorder.Body.String())
+		})
+	}
+}
+
+func TestNewServerInvalidTrustedProxies(t *testing.T) {
+	config := utils.Config{
+		TokenSymmetricKey: utils.RandomString(32),
+		TokenDuration:     time.Minute,
+		TrustedProxies:    []string{"not-a-proxy"},
+	}
+
+	_, err := NewServer(config, nil, newTestTaskDistributor())
+	require.Error(t, err)
+}
+
+func TestNewServerInvalidLogLevel(t *testing.T) {
+	config := utils.Config{
+		TokenSymmetricKey: utils.RandomString(32),
+		TokenDuration:     time.Minute,
+		LogLevel:          "verbose",
+	}
+
+	_, err := NewServer(config, nil, newTestTaskDistributor())
+	require.ErrorContains(t, err, "LOG_LEVEL")
+}
+
+func TestMountGatewayForwardedFor(t *testing.T) {
+	server := newTestServer(t, nil)
+	// the gateway sees the X-Forwarded-For header the rpcs read the client ip from
+	server.MountGateway(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Forwarded-For")))
+	}))
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, gatewayPrefix+"/login_user", nil)
+	require.NoError(t, err)
+	request.RemoteAddr = "10.0.0.2:1234"
+	request.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	// no proxy is trusted, the spoofed ip is replaced by the peer one
+	require.Equal(t, "10.0.0.2", recorder.Body.String())
+}
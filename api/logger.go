@@ -0,0 +1,41 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"net/http"
+	"time"
+)
+
+// loggerMiddleware writes a structured log line for every request once it has been handled
+// The server logger is read on each request, so it can be swapped after the router is built
+func (s *Server) loggerMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		startTime := time.Now()
+		ctx.Next()
+		latency := time.Since(startTime)
+
+		statusCode := ctx.Writer.Status()
+		logger := s.logger.Info()
+		if statusCode >= http.StatusInternalServerError {
+			logger = s.logger.Error()
+		}
+
+		// the payload is only present once the auth middleware accepted the request
+		if payload, ok := ctx.Get(authorizationHeaderKey); ok {
+			if authPayload, ok := payload.(*token.Payload); ok {
+				logger = logger.Str("username", authPayload.UserName)
+			}
+		}
+
+		logger.
+			Str("request_id", utils.RequestIDFromContext(ctx)).
+			Str("method", ctx.Request.Method).
+			Str("path", ctx.Request.URL.Path).
+			Int("status_code", statusCode).
+			Dur("latency", latency).
+			Str("client_ip", ctx.ClientIP()).
+			Msg("received an HTTP request")
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+)
+
+func TestLoggerMiddleware(t *testing.T) {
+	testCases := []struct {
+		name          string
+		status        int
+		authenticated bool
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		checkLog      func(t *testing.T, logLine map[string]interface{})
+	}{
+		{
+			name:          "authenticated request",
+			status:        http.StatusOK,
+			authenticated: true,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "username", utils.DepositorRole, time.Minute)
+			},
+			checkLog: func(t *testing.T, logLine map[string]interface{}) {
+				require.Equal(t, "info", logLine["level"])
+				require.Equal(t, "username", logLine["username"])
+			},
+		},
+		{
+			name:      "server error",
+			status:    http.StatusInternalServerError,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			checkLog: func(t *testing.T, logLine map[string]interface{}) {
+				require.Equal(t, "error", logLine["level"])
+				require.NotContains(t, logLine, "username")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			var output bytes.Buffer
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+
+			server := newTestServer(t, store)
+			server.logger = zerolog.New(&output)
+
+			url := "/logged"
+			handlers := []gin.HandlerFunc{func(ctx *gin.Context) {
+				ctx.JSON(tc.status, gin.H{})
+			}}
+			if tc.authenticated {
+				handlers = append([]gin.HandlerFunc{authMiddleware(server.token, store)}, handlers...)
+			}
+			server.router.GET(url, handlers...)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.status, recorder.Code)
+
+			var logLine map[string]interface{}
+			err = json.Unmarshal(output.Bytes(), &logLine)
+			require.NoError(t, err)
+
+			require.Equal(t, http.MethodGet, logLine["method"])
+			require.Equal(t, url, logLine["path"])
+			require.Equal(t, float64(tc.status), logLine["status_code"])
+			require.Contains(t, logLine, "latency")
+			require.Contains(t, logLine, "client_ip")
+			require.Equal(t, recorder.Header().Get(requestIDHeader), logLine["request_id"])
+			tc.checkLog(t, logLine)
+		})
+	}
+}
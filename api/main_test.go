@@ -1,27 +1,53 @@
 package api
 
 import (
+	"database/sql"
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
 	db "github.com/micaelapucciariello/simplebank/db/sqlc"
 	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/micaelapucciariello/simplebank/worker"
 	"github.com/stretchr/testify/require"
 	"os"
 	"testing"
 	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
 )
 
 func newTestServer(t *testing.T, store db.Store) *Server {
 	config := utils.Config{
-		TokenSymmetricKey: utils.RandomString(32),
-		TokenDuration:     time.Minute,
+		TokenSymmetricKey:    utils.RandomString(32),
+		TOTPEncryptionKey:    utils.RandomString(utils.TOTPEncryptionKeySize),
+		TOTPIssuer:           "Simple Bank",
+		TokenDuration:        time.Minute,
+		RefreshTokenDuration: time.Hour,
+		StepUpTokenDuration:  time.Minute,
+		ResetTokenDuration:   time.Minute,
+		DefaultCurrency:      utils.USD,
+		DefaultLocale:        utils.DefaultLocale,
+	}
+
+	// the auth middleware looks up the user password change on every request
+	// tests that care about it stub the lookup themselves before building the server, so their stub matches first
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		mockStore.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Any()).AnyTimes().Return(time.Time{}, nil)
+		// and the login looks up the two-factor secret, none is enrolled unless the test says otherwise
+		mockStore.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).AnyTimes().Return(db.TotpSecret{}, sql.ErrNoRows)
 	}
 
-	server, err := NewServer(config, store)
+	server, err := NewServer(config, store, newTestTaskDistributor())
 	require.NoError(t, err)
+	t.Cleanup(server.Close)
 
 	return server
 }
 
+// newTestTaskDistributor queues the tasks in memory, nothing processes them
+func newTestTaskDistributor() worker.TaskDistributor {
+	return worker.NewTaskDistributor(worker.NewInMemoryBroker(10))
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 
@@ -1,9 +1,12 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
 	"github.com/micaelapucciariello/simplebank/token"
 	"net/http"
 	"strings"
@@ -13,37 +16,104 @@ const _authorizationHeaderKey = "authorization"
 const _authorizationTypeBearer = "Bearer"
 const authorizationHeaderKey = "authorization_payload"
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+var errTokenRevoked = errors.New("token was issued before the last password change")
+
+func authMiddleware(tokenMaker token.Maker, store db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(_authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
 			err := errors.New("authorization header not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(codeUnauthorized, err))
 			return
 		}
 
 		fields := strings.Fields(authorizationHeader)
 		if len(fields) < 2 {
 			err := errors.New("invalid authorization header format")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(codeUnauthorized, err))
 			return
 		}
 
 		authorizationType := fields[0]
 		if authorizationType != _authorizationTypeBearer {
 			err := fmt.Errorf("invalid authorization type: %v", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(codeInvalidToken, err))
 			return
 		}
 
 		accessToken := fields[1]
 		payload, err := tokenMaker.VerifyToken(accessToken)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errResponse(err))
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(codeInvalidToken, err))
+			return
+		}
+		// a refresh or step-up token is signed with the same key, only access tokens authenticate a request
+		if err = payload.VerifyKind(token.KindAccess); err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(codeInvalidToken, err))
 			return
 		}
 
+		if status, err := checkPasswordChangedAt(ctx, store, payload); err != nil {
+			ctx.AbortWithStatusJSON(status, errorResponse(codeForStatus(status), err))
+			return
+		}
+		// tokens minted before organizations were added belong to the default one, like every user of that time
+		if payload.OrgID == 0 {
+			payload.OrgID = db.DefaultOrgID
+		}
+
 		ctx.Set(authorizationHeaderKey, payload)
 		ctx.Next()
 	}
 }
+
+// checkPasswordChangedAt rejects tokens minted before the user latest password change, so changing the password logs out every existing session
+// It returns the http status to respond with when the token is not accepted
+func checkPasswordChangedAt(ctx context.Context, store db.Store, payload *token.Payload) (int, error) {
+	passwordChangedAt, err := store.GetUserPasswordChangedAt(ctx, payload.UserName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return http.StatusUnauthorized, fmt.Errorf("token user [%v] doesn't exist", payload.UserName)
+		}
+		status, _ := dbErrorToHTTP(err)
+		return status, err
+	}
+
+	if passwordChangedAt.After(payload.PasswordChangedAt) {
+		return http.StatusUnauthorized, errTokenRevoked
+	}
+
+	return http.StatusOK, nil
+}
+
+// authorizeRoles only lets through the requests whose token carries one of the given roles
+// It must run after authMiddleware, which stores the payload in the context
+func authorizeRoles(roles ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+		if !allowed[authPayload.Role] {
+			err := fmt.Errorf("role %v is not allowed to access this resource", authPayload.Role)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(codeForbidden, err))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// checkSameOrg checks that the resource belongs to the organization of the authenticated user, writing the error response
+// It runs before the owner checks, so not even a banker reaches the accounts of another tenant
+func checkSameOrg(ctx *gin.Context, orgID int64) bool {
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.OrgID != orgID {
+		err := errors.New("resource belongs to another organization")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeOtherOrganization, err))
+		return false
+	}
+	return true
+}
@@ -1,14 +1,20 @@
 package api
 
 import (
+	"database/sql"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
 	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
 )
 
 func addAuthorization(
@@ -17,14 +23,36 @@ func addAuthorization(
 	tokenMaker token.Maker,
 	authorizationType string,
 	username string,
+	role string,
 	duration time.Duration) {
-	tokenAuth, _, err := tokenMaker.CreateToken(username, duration)
+	addOrgAuthorization(t, request, tokenMaker, authorizationType, username, role, db.DefaultOrgID, duration)
+}
+
+// addOrgAuthorization is addAuthorization for a user of another organization than the default one
+func addOrgAuthorization(
+	t *testing.T,
+	request *http.Request,
+	tokenMaker token.Maker,
+	authorizationType string,
+	username string,
+	role string,
+	orgID int64,
+	duration time.Duration) {
+	tokenAuth, _, err := tokenMaker.CreateToken(token.KindAccess, username, role, orgID, time.Time{}, duration)
 	require.NoError(t, err)
 
 	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, tokenAuth)
 	request.Header.Set(_authorizationHeaderKey, authorizationHeader)
 }
 
+// addStepUpToken sets a token of the given kind as the step-up token of the request
+func addStepUpToken(t *testing.T, request *http.Request, tokenMaker token.Maker, kind, username string) {
+	stepUpToken, _, err := tokenMaker.CreateToken(kind, username, utils.DepositorRole, db.DefaultOrgID, time.Time{}, time.Minute)
+	require.NoError(t, err)
+
+	request.Header.Set(stepUpTokenHeader, stepUpToken)
+}
+
 func TestAuthMiddleware(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -34,7 +62,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "happy path",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "username", time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "username", utils.DepositorRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -43,7 +71,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "no authorization",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, "unsupported type", "username", time.Minute)
+				addAuthorization(t, request, tokenMaker, "unsupported type", "username", utils.DepositorRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -52,7 +80,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "invalid auth format: empty auth type",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, "", "username", time.Minute)
+				addAuthorization(t, request, tokenMaker, "", "username", utils.DepositorRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -61,10 +89,22 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "expired token",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "username", -time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "username", utils.DepositorRole, -time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "refresh token",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				refreshToken, _, err := tokenMaker.CreateToken(token.KindRefresh, "username", utils.DepositorRole, db.DefaultOrgID, time.Time{}, time.Minute)
+				require.NoError(t, err)
+				request.Header.Set(_authorizationHeaderKey, fmt.Sprintf("%s %s", _authorizationTypeBearer, refreshToken))
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidToken)
 			},
 		},
 	}
@@ -72,11 +112,15 @@ func TestAuthMiddleware(t *testing.T) {
 		tc := testCases[i]
 
 		t.Run(tc.name, func(t *testing.T) {
-			server := newTestServer(t, nil)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+
+			server := newTestServer(t, store)
 			url := "/auth"
 
 			server.router.GET(url,
-				authMiddleware(server.token),
+				authMiddleware(server.token, store),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				})
@@ -91,3 +135,227 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthMiddlewarePasswordChanged(t *testing.T) {
+	username := utils.RandomOwner()
+	passwordChangedAt := time.Now().UTC().Truncate(time.Second)
+
+	testCases := []struct {
+		name              string
+		buildStubs        func(store *mockdb.MockStore)
+		passwordChangedAt time.Time
+		checkResponse     func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "token minted after the last password change",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), username).Times(1).Return(passwordChangedAt, nil)
+			},
+			passwordChangedAt: passwordChangedAt,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "token minted before the last password change",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), username).Times(1).Return(passwordChangedAt, nil)
+			},
+			passwordChangedAt: passwordChangedAt.Add(-time.Hour),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errTokenRevoked.Error())
+			},
+		},
+		{
+			name: "token user doesn't exist",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), username).Times(1).Return(time.Time{}, sql.ErrNoRows)
+			},
+			passwordChangedAt: passwordChangedAt,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "internal server error",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), username).Times(1).Return(time.Time{}, sql.ErrTxDone)
+			},
+			passwordChangedAt: passwordChangedAt,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "database connection lost",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), username).Times(1).Return(time.Time{}, sql.ErrConnDone)
+			},
+			passwordChangedAt: passwordChangedAt,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			url := "/auth"
+
+			server.router.GET(url,
+				authMiddleware(server.token, store),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				})
+
+			accessToken, _, err := server.token.CreateToken(token.KindAccess, username, utils.DepositorRole, db.DefaultOrgID, tc.passwordChangedAt, time.Minute)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			request.Header.Set(_authorizationHeaderKey, fmt.Sprintf("%s %s", _authorizationTypeBearer, accessToken))
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAuthMiddlewareTokenType(t *testing.T) {
+	username := utils.RandomOwner()
+
+	testCases := []struct {
+		name          string
+		tokenType     string
+		duration      time.Duration
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "paseto token",
+			tokenType: token.TypePaseto,
+			duration:  time.Minute,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "jwt token",
+			tokenType: token.TypeJWT,
+			duration:  time.Minute,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "expired jwt token",
+			tokenType: token.TypeJWT,
+			duration:  -time.Minute,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), token.ErrExpiredToken.Error())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Any()).AnyTimes().Return(time.Time{}, nil)
+
+			config := utils.Config{
+				TokenType:         tc.tokenType,
+				TokenSymmetricKey: utils.RandomString(32),
+				TokenDuration:     time.Minute,
+			}
+			server, err := NewServer(config, store, newTestTaskDistributor())
+			require.NoError(t, err)
+
+			url := "/auth"
+			server.router.GET(url,
+				authMiddleware(server.token, store),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.token, _authorizationTypeBearer, username, utils.DepositorRole, tc.duration)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAuthorizeRoles(t *testing.T) {
+	testCases := []struct {
+		name          string
+		role          string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "banker token is allowed",
+			role: utils.BankerRole,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "depositor token is rejected",
+			role: utils.DepositorRole,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "token without role is rejected",
+			role: "",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+
+			server := newTestServer(t, store)
+			url := "/banker"
+
+			server.router.GET(url,
+				authMiddleware(server.token, store),
+				authorizeRoles(utils.BankerRole),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.token, _authorizationTypeBearer, utils.RandomOwner(), tc.role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"net/http"
+)
+
+type updateNotificationPreferencesReq struct {
+	// every preference is required, so a client can't turn one off by forgetting to send it
+	IncomingTransferEmail   *bool `json:"incoming_transfer_email" binding:"required"`
+	IncomingTransferWebhook *bool `json:"incoming_transfer_webhook" binding:"required"`
+	LowBalanceEmail         *bool `json:"low_balance_email" binding:"required"`
+	LowBalanceWebhook       *bool `json:"low_balance_webhook" binding:"required"`
+}
+
+// getNotificationPreferences returns the notifications the authenticated user gets, all of them until it changes any
+func (s *Server) getNotificationPreferences(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+
+	preferences, err := db.NotificationPreferencesOf(ctx, s.store, authPayload.UserName)
+	if err != nil {
+		respondDBError(ctx, err, codeUserNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preferences)
+}
+
+// updateNotificationPreferences replaces the notification preferences of the authenticated user
+func (s *Server) updateNotificationPreferences(ctx *gin.Context) {
+	var req updateNotificationPreferencesReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+
+	preferences, err := s.store.UpsertNotificationPreferences(ctx, db.UpsertNotificationPreferencesParams{
+		Username:                authPayload.UserName,
+		IncomingTransferEmail:   *req.IncomingTransferEmail,
+		IncomingTransferWebhook: *req.IncomingTransferWebhook,
+		LowBalanceEmail:         *req.LowBalanceEmail,
+		LowBalanceWebhook:       *req.LowBalanceWebhook,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeUserNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preferences)
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func requireResponsePreferences(t *testing.T, recorder *httptest.ResponseRecorder, expected db.NotificationPreference) {
+	var got db.NotificationPreference
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.Equal(t, expected.Username, got.Username)
+	require.Equal(t, expected.IncomingTransferEmail, got.IncomingTransferEmail)
+	require.Equal(t, expected.IncomingTransferWebhook, got.IncomingTransferWebhook)
+	require.Equal(t, expected.LowBalanceEmail, got.LowBalanceEmail)
+	require.Equal(t, expected.LowBalanceWebhook, got.LowBalanceWebhook)
+}
+
+func TestGetNotificationPreferencesAPI(t *testing.T) {
+	user, _ := randomUser()
+	stored := db.DefaultNotificationPreferences(user.Username)
+	stored.IncomingTransferEmail = false
+	stored.UpdatedAt = time.Now()
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path stored preferences",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetNotificationPreferences(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(stored, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireResponsePreferences(t, recorder, stored)
+			},
+		},
+		{
+			name: "no stored preferences",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetNotificationPreferences(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.NotificationPreference{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireResponsePreferences(t, recorder, db.DefaultNotificationPreferences(user.Username))
+			},
+		},
+		{
+			name: "internal error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetNotificationPreferences(gomock.Any(), gomock.Any()).Times(1).Return(db.NotificationPreference{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:      "no authorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetNotificationPreferences(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodGet, "/users/notification_preferences", nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateNotificationPreferencesAPI(t *testing.T) {
+	user, _ := randomUser()
+	arg := db.UpsertNotificationPreferencesParams{
+		Username:                user.Username,
+		IncomingTransferEmail:   false,
+		IncomingTransferWebhook: true,
+		LowBalanceEmail:         true,
+		LowBalanceWebhook:       false,
+	}
+	stored := db.NotificationPreference{
+		Username:                arg.Username,
+		IncomingTransferEmail:   arg.IncomingTransferEmail,
+		IncomingTransferWebhook: arg.IncomingTransferWebhook,
+		LowBalanceEmail:         arg.LowBalanceEmail,
+		LowBalanceWebhook:       arg.LowBalanceWebhook,
+		UpdatedAt:               time.Now(),
+	}
+	body := gin.H{
+		"incoming_transfer_email":   false,
+		"incoming_transfer_webhook": true,
+		"low_balance_email":         true,
+		"low_balance_webhook":       false,
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path update preferences",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertNotificationPreferences(gomock.Any(), gomock.Eq(arg)).Times(1).Return(stored, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireResponsePreferences(t, recorder, stored)
+			},
+		},
+		{
+			name: "missing preference",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: gin.H{
+				"incoming_transfer_email":   false,
+				"incoming_transfer_webhook": true,
+				"low_balance_email":         true,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertNotificationPreferences(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "internal error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			body: body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertNotificationPreferences(gomock.Any(), gomock.Any()).Times(1).Return(db.NotificationPreference{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:      "no authorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			body:      body,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpsertNotificationPreferences(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPut, "/users/notification_preferences", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listResponse is the envelope returned by the list endpoints, total counts every matching row regardless of the page
+type listResponse struct {
+	Data     interface{} `json:"data"`
+	PageID   int32       `json:"page_id"`
+	PageSize int32       `json:"page_size"`
+	Total    int64       `json:"total"`
+}
+
+func newListResponse(data interface{}, pageID, pageSize int32, total int64) listResponse {
+	return listResponse{
+		Data:     data,
+		PageID:   pageID,
+		PageSize: pageSize,
+		Total:    total,
+	}
+}
+
+// cursorListResponse is the envelope returned by the cursor paginated endpoints
+// NextAfterNumber is the cursor of the next page, it is null once the last page was returned
+type cursorListResponse struct {
+	Data            interface{} `json:"data"`
+	Limit           int32       `json:"limit"`
+	NextAfterNumber *string     `json:"next_after_number"`
+}
+
+func newCursorListResponse(data interface{}, limit int32, nextAfterNumber *string) cursorListResponse {
+	return cursorListResponse{
+		Data:            data,
+		Limit:           limit,
+		NextAfterNumber: nextAfterNumber,
+	}
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageReq is the pagination of the offset paginated endpoints, both parameters are optional
+type pageReq struct {
+	PageID   *int32 `form:"page_id" binding:"omitempty,min=1"`
+	PageSize *int32 `form:"page_size" binding:"omitempty,min=1"`
+}
+
+// pagination is the page of rows a list request asks for
+type pagination struct {
+	PageID   int32
+	PageSize int32
+}
+
+// offset is the number of rows skipped to reach the page
+func (p pagination) offset() int32 {
+	return (p.PageID - 1) * p.PageSize
+}
+
+// parsePagination reads the page of a list request, the first page of defaultPageSize rows unless the query asks for another
+// one. Page sizes above maxPageSize are lowered to it, a non-numeric or non-positive parameter, or a page too far for its offset
+// to fit an int32, is answered with a bad request
+// It returns whether the handler can go on
+func parsePagination(ctx *gin.Context) (pagination, bool) {
+	var req pageReq
+	if !bindQuery(ctx, &req) {
+		return pagination{}, false
+	}
+
+	page := pagination{PageID: 1, PageSize: defaultPageSize}
+	if req.PageID != nil {
+		page.PageID = *req.PageID
+	}
+	if req.PageSize != nil {
+		page.PageSize = *req.PageSize
+	}
+	if page.PageSize > maxPageSize {
+		page.PageSize = maxPageSize
+	}
+	if (int64(page.PageID)-1)*int64(page.PageSize) > math.MaxInt32 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, errors.New("page_id is out of range")))
+		return pagination{}, false
+	}
+	return page, true
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePagination(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		ok       bool
+		expected pagination
+	}{
+		{
+			name:     "missing parameters",
+			query:    "",
+			ok:       true,
+			expected: pagination{PageID: 1, PageSize: defaultPageSize},
+		},
+		{
+			name:     "missing page size",
+			query:    "page_id=3",
+			ok:       true,
+			expected: pagination{PageID: 3, PageSize: defaultPageSize},
+		},
+		{
+			name:     "given page",
+			query:    "page_id=2&page_size=5",
+			ok:       true,
+			expected: pagination{PageID: 2, PageSize: 5},
+		},
+		{
+			name:     "page size over the cap",
+			query:    "page_id=1&page_size=1000",
+			ok:       true,
+			expected: pagination{PageID: 1, PageSize: maxPageSize},
+		},
+		{
+			name:     "last page within range",
+			query:    "page_id=21474837&page_size=100",
+			ok:       true,
+			expected: pagination{PageID: 21474837, PageSize: maxPageSize},
+		},
+		{
+			name:  "page offset overflowing",
+			query: "page_id=21474838&page_size=100",
+		},
+		{
+			name:  "page offset overflowing with the default size",
+			query: "page_id=2147483647",
+		},
+		{
+			name:  "zero page size",
+			query: "page_size=0",
+		},
+		{
+			name:  "negative page size",
+			query: "page_size=-5",
+		},
+		{
+			name:  "zero page id",
+			query: "page_id=0",
+		},
+		{
+			name:  "non-numeric page size",
+			query: "page_size=ten",
+		},
+		{
+			name:  "non-numeric page id",
+			query: "page_id=first",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/accounts?"+tc.query, nil)
+
+			page, ok := parsePagination(ctx)
+			require.Equal(t, tc.ok, ok)
+			if !tc.ok {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+				return
+			}
+			require.Equal(t, tc.expected, page)
+			require.Equal(t, (tc.expected.PageID-1)*tc.expected.PageSize, page.offset())
+		})
+	}
+}
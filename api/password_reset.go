@@ -0,0 +1,113 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/micaelapucciariello/simplebank/worker"
+	"net/http"
+	"time"
+)
+
+type (
+	forgotPasswordReq struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	resetPasswordReq struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+
+	forgotPasswordResponse struct {
+		Message string `json:"message"`
+	}
+)
+
+// forgotPasswordMessage answers every forgotten password request, whether its email belongs to a user or not
+const forgotPasswordMessage = "if the email belongs to a user, a password reset token was sent to it"
+
+// forgotPassword emails a single-use password reset token to the user of the email. The response is the same whether
+// the email belongs to a user or not, so the endpoint can't be used to find out which emails are registered
+func (s *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	user, err := s.store.GetUserByEmail(ctx, utils.NormalizeEmail(req.Email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusOK, forgotPasswordResponse{Message: forgotPasswordMessage})
+			return
+		}
+		respondDBError(ctx, err, codeUserNotFound)
+		return
+	}
+
+	resetToken, err := utils.NewResetToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
+	}
+
+	// only the hash is stored, the token itself is only sent in the email
+	_, err = s.store.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: utils.HashResetToken(resetToken),
+		ExpiresAt: time.Now().Add(s.config.ResetTokenDuration),
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeUserNotFound)
+		return
+	}
+
+	// the email is sent in the background, a failure to enqueue it is logged and the user can ask for another token
+	err = s.taskDistributor.DistributeTaskSendPasswordResetEmail(ctx, &worker.PayloadSendPasswordResetEmail{
+		Username: user.Username,
+		Token:    resetToken,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Str("username", user.Username).Msg("cannot distribute password reset email task")
+	}
+
+	ctx.JSON(http.StatusOK, forgotPasswordResponse{Message: forgotPasswordMessage})
+}
+
+// resetPassword redeems a password reset token for a new password, a token is redeemed once and before it expires
+func (s *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	if err := utils.ValidatePassword(req.NewPassword); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidPassword, err))
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword, s.config.BcryptCost)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
+	}
+
+	user, err := s.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash:      utils.HashResetToken(req.Token),
+		HashedPassword: hashedPassword,
+		ClientIP:       ctx.ClientIP(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrResetTokenInvalid), errors.Is(err, db.ErrResetTokenUsed), errors.Is(err, db.ErrResetTokenExpired):
+			ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidResetToken, err))
+		default:
+			respondDBError(ctx, err, codeUserNotFound)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
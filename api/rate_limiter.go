@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter is an in-memory token bucket limiter keyed by client ip
+// Every bucket holds up to `capacity` tokens and refills them evenly along the configured window
+type rateLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	capacity float64
+	rate     float64 // tokens refilled per second
+	window   time.Duration
+	now      func() time.Time
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+func newRateLimiter(requests int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		buckets:  make(map[string]*bucket),
+		capacity: float64(requests),
+		rate:     float64(requests) / window.Seconds(),
+		window:   window,
+		now:      time.Now,
+	}
+}
+
+// allow consumes a token from the key bucket. When the bucket is empty it returns the time to wait for the next token
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.capacity, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	// refill the tokens earned since the last request
+	b.tokens = math.Min(l.capacity, b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate)
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// cleanup removes the buckets that have been idle for a whole window, since they are full again anyway
+func (l *rateLimiter) cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > l.window {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// runCleanup periodically drops stale buckets so the map doesn't grow with every client seen, until ctx is done
+func (l *rateLimiter) runCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.cleanup()
+		}
+	}
+}
+
+func rateLimitMiddleware(limiter *rateLimiter) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		allowed, wait := limiter.allow(ctx.ClientIP())
+		if !allowed {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+			err := fmt.Errorf("too many requests, retry after %v seconds", retryAfter)
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse(codeRateLimited, err))
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limit := 3
+
+	server := newTestServer(t, nil)
+	url := "/limited"
+	server.router.GET(url,
+		rateLimitMiddleware(newRateLimiter(limit, time.Minute)),
+		func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{})
+		})
+
+	sendRequest := func(clientIP string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		request.RemoteAddr = clientIP + ":1234"
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for i := 0; i < limit; i++ {
+		recorder := sendRequest("10.0.0.1")
+		require.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	recorder := sendRequest("10.0.0.1")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	retryAfter, err := strconv.Atoi(recorder.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	require.True(t, retryAfter > 0)
+
+	// other clients keep their own bucket
+	recorder = sendRequest("10.0.0.2")
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	allowed, _ := limiter.allow("client")
+	require.True(t, allowed)
+	allowed, _ = limiter.allow("client")
+	require.True(t, allowed)
+
+	allowed, wait := limiter.allow("client")
+	require.False(t, allowed)
+	require.InDelta(t, 30*time.Second, wait, float64(time.Millisecond))
+
+	// a token is refilled every window / requests
+	now = now.Add(30 * time.Second)
+	allowed, _ = limiter.allow("client")
+	require.True(t, allowed)
+}
+
+func TestRateLimiterCleanup(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	limiter.allow("stale")
+	now = now.Add(2 * time.Minute)
+	limiter.allow("active")
+
+	limiter.cleanup()
+	require.NotContains(t, limiter.buckets, "stale")
+	require.Contains(t, limiter.buckets, "active")
+}
+
+func TestRateLimiterCleanupStops(t *testing.T) {
+	limiter := newRateLimiter(2, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan struct{})
+	go func() {
+		limiter.runCleanup(ctx, time.Millisecond)
+		close(stopped)
+	}()
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("the cleanup goroutine kept running after its context was canceled")
+	}
+}
+
+func TestMountGatewayRateLimited(t *testing.T) {
+	limit := 2
+	config := utils.Config{
+		TokenSymmetricKey: utils.RandomString(32),
+		RateLimitRequests: limit,
+		RateLimitWindow:   time.Minute,
+	}
+	server, err := NewServer(config, nil, newTestTaskDistributor())
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+
+	var gatewayPaths []string
+	server.MountGateway(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gatewayPaths = append(gatewayPaths, r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	sendRequest := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/v1/create_user", nil)
+		require.NoError(t, err)
+		request.RemoteAddr = "10.0.0.1:1234"
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for i := 0; i < limit; i++ {
+		recorder := sendRequest()
+		require.Equal(t, http.StatusOK, recorder.Code)
+	}
+	require.Equal(t, http.StatusTooManyRequests, sendRequest().Code)
+
+	// the gateway gets the full path, its routes are declared with the prefix
+	require.Equal(t, []string{"/v1/create_user", "/v1/create_user"}, gatewayPaths)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/micaelapucciariello/simplebank/utils"
+)
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+// requestIDMiddleware reuses the caller X-Request-ID or generates a new one, stores it in the context and echoes it back
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		ctx.Set(utils.RequestIDKey, requestID)
+		ctx.Request = ctx.Request.WithContext(utils.ContextWithRequestID(ctx.Request.Context(), requestID))
+		ctx.Header(requestIDHeader, requestID)
+		ctx.Next()
+	}
+}
+
+// validRequestID rejects empty, oversized or non printable ids, since they end up verbatim in the logs
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
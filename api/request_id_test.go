@@ -0,0 +1,78 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	testCases := []struct {
+		name          string
+		requestID     string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, handlerRequestID string)
+	}{
+		{
+			name:      "incoming request id is echoed",
+			requestID: "my-request-id",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerRequestID string) {
+				require.Equal(t, "my-request-id", recorder.Header().Get(requestIDHeader))
+				require.Equal(t, "my-request-id", handlerRequestID)
+			},
+		},
+		{
+			name:      "request id is generated when absent",
+			requestID: "",
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerRequestID string) {
+				requestID := recorder.Header().Get(requestIDHeader)
+				_, err := uuid.Parse(requestID)
+				require.NoError(t, err)
+				require.Equal(t, requestID, handlerRequestID)
+			},
+		},
+		{
+			name:      "invalid request id is replaced",
+			requestID: strings.Repeat("a", maxRequestIDLength+1),
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, handlerRequestID string) {
+				requestID := recorder.Header().Get(requestIDHeader)
+				_, err := uuid.Parse(requestID)
+				require.NoError(t, err)
+				require.Equal(t, requestID, handlerRequestID)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			var handlerRequestID, requestContextID string
+			url := "/request_id"
+			server.router.GET(url, func(ctx *gin.Context) {
+				handlerRequestID = utils.RequestIDFromContext(ctx)
+				requestContextID = utils.RequestIDFromContext(ctx.Request.Context())
+				ctx.JSON(http.StatusOK, gin.H{})
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			if tc.requestID != "" {
+				request.Header.Set(requestIDHeader, tc.requestID)
+			}
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			// the id is also reachable from the plain request context handed to the store
+			require.Equal(t, handlerRequestID, requestContextID)
+			tc.checkResponse(t, recorder, handlerRequestID)
+		})
+	}
+}
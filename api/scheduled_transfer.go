@@ -0,0 +1,250 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"net/http"
+	"time"
+)
+
+var errScheduleCancelled = errors.New("scheduled transfer is already cancelled")
+
+type (
+	createScheduledTransferReq struct {
+		FromAccountNumber string      `json:"from_account_number" binding:"required,len=16,numeric"`
+		ToAccountNumber   string      `json:"to_account_number" binding:"required,len=16,numeric,nefield=FromAccountNumber"`
+		Amount            utils.Money `json:"amount" binding:"required,min=1"`
+		Currency          string      `json:"currency" binding:"required,currency"`
+		IntervalSeconds   int64       `json:"interval_seconds" binding:"required,min=60"`
+		// StartAt is the first run of the schedule, it defaults to the next run of the runner
+		StartAt *time.Time `json:"start_at"`
+	}
+
+	listScheduledTransfersReq struct {
+		AccountNumber string `form:"account_number" binding:"required,len=16,numeric"`
+	}
+
+	cancelScheduledTransferReq struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+
+	// scheduledTransferResponse hides the sql null types, the fields never set are null. The accounts are sent by number
+	scheduledTransferResponse struct {
+		ID                int64      `json:"id"`
+		FromAccountNumber string     `json:"from_account_number"`
+		ToAccountNumber   string     `json:"to_account_number"`
+		Amount            int64      `json:"amount"`
+		IntervalSeconds   int64      `json:"interval_seconds"`
+		NextRunAt         time.Time  `json:"next_run_at"`
+		LastRunAt         *time.Time `json:"last_run_at"`
+		LastTransferID    *uuid.UUID `json:"last_transfer_id"`
+		LastError         *string    `json:"last_error"`
+		FailedRuns        int32      `json:"failed_runs"`
+		CancelledAt       *time.Time `json:"cancelled_at"`
+		CreatedAt         time.Time  `json:"created_at"`
+	}
+)
+
+// newScheduledTransferResponse sends the last transfer by its uuid, like every transfer response
+func newScheduledTransferResponse(schedule db.ScheduledTransfer, lastTransferUUID uuid.NullUUID, numbers accountNumbers) scheduledTransferResponse {
+	rsp := scheduledTransferResponse{
+		ID:                schedule.ID,
+		FromAccountNumber: numbers[schedule.FromAccountID],
+		ToAccountNumber:   numbers[schedule.ToAccountID],
+		Amount:            schedule.Amount,
+		IntervalSeconds:   schedule.IntervalSeconds,
+		NextRunAt:         schedule.NextRunAt,
+		FailedRuns:        schedule.FailedRuns,
+		CreatedAt:         schedule.CreatedAt,
+	}
+	if schedule.LastRunAt.Valid {
+		rsp.LastRunAt = &schedule.LastRunAt.Time
+	}
+	if lastTransferUUID.Valid {
+		rsp.LastTransferID = &lastTransferUUID.UUID
+	}
+	if schedule.LastError.Valid {
+		rsp.LastError = &schedule.LastError.String
+	}
+	if schedule.CancelledAt.Valid {
+		rsp.CancelledAt = &schedule.CancelledAt.Time
+	}
+	return rsp
+}
+
+// createScheduledTransfer schedules a transfer from the authenticated user account repeated every interval
+// The accounts are checked as for a single transfer, the balance isn't: it is only checked by every run
+func (s *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	if err := s.checkTransferAmount(int64(req.Amount)); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeAmountOutOfRange, err))
+		return
+	}
+
+	nextRunAt := time.Now()
+	if req.StartAt != nil {
+		if req.StartAt.Before(nextRunAt) {
+			err := fmt.Errorf("start_at %v is in the past", req.StartAt.Format(time.RFC3339))
+			ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+			return
+		}
+		nextRunAt = *req.StartAt
+	}
+
+	fromAccount, isValidFromAccount := s.validAccount(ctx, req.FromAccountNumber, req.Currency)
+	if !isValidFromAccount {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.UserName {
+		err := fmt.Errorf("from account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	toAccount, isValidToAccount := s.validAccount(ctx, req.ToAccountNumber, req.Currency)
+	if !isValidToAccount {
+		return
+	}
+
+	schedule, err := s.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		FromAccountID:   fromAccount.ID,
+		ToAccountID:     toAccount.ID,
+		Amount:          int64(req.Amount),
+		IntervalSeconds: req.IntervalSeconds,
+		NextRunAt:       nextRunAt,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(schedule, uuid.NullUUID{}, newAccountNumbers(fromAccount, toAccount)))
+}
+
+// listScheduledTransfers executes a paginated query over the scheduled transfers sent by an account, the cancelled ones included
+func (s *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersReq
+	if !bindQuery(ctx, &req) {
+		return
+	}
+
+	page, ok := parsePagination(ctx)
+	if !ok {
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, req.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != account.Owner {
+		err = fmt.Errorf("account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	schedules, err := s.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		FromAccountID: account.ID,
+		Limit:         page.PageSize,
+		Offset:        page.offset(),
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	total, err := s.store.CountScheduledTransfers(ctx, account.ID)
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	ids := make([]int64, len(schedules))
+	for i, schedule := range schedules {
+		ids[i] = schedule.ScheduledTransfer.ToAccountID
+	}
+	numbers, ok := s.lookupAccountNumbers(ctx, ids, account)
+	if !ok {
+		return
+	}
+
+	rsp := make([]scheduledTransferResponse, len(schedules))
+	for i, schedule := range schedules {
+		rsp[i] = newScheduledTransferResponse(schedule.ScheduledTransfer, schedule.LastTransferUUID, numbers)
+	}
+
+	ctx.JSON(http.StatusOK, newListResponse(rsp, page.PageID, page.PageSize, total))
+}
+
+// cancelScheduledTransfer stops the future runs of a scheduled transfer, the transfers already executed are kept
+func (s *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	var req cancelScheduledTransferReq
+	if !bindURI(ctx, &req) {
+		return
+	}
+
+	row, err := s.store.GetScheduledTransfer(ctx, req.ID)
+	if err != nil {
+		respondDBError(ctx, err, codeScheduleNotFound)
+		return
+	}
+	schedule := row.ScheduledTransfer
+
+	// the schedules of a deleted account are still the owner's to stop
+	fromAccount, err := s.store.GetAccountIncludingDeleted(ctx, schedule.FromAccountID)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, fromAccount.OrgID) {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != fromAccount.Owner {
+		err = fmt.Errorf("scheduled transfer wasn't created by the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	if schedule.CancelledAt.Valid {
+		ctx.JSON(http.StatusConflict, errorResponse(codeScheduleCancelled, errScheduleCancelled))
+		return
+	}
+
+	numbers, ok := s.lookupAccountNumbers(ctx, []int64{schedule.ToAccountID}, fromAccount)
+	if !ok {
+		return
+	}
+
+	schedule, err = s.store.CancelScheduledTransfer(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// cancelled by a concurrent request since it was read
+			ctx.JSON(http.StatusConflict, errorResponse(codeScheduleCancelled, errScheduleCancelled))
+			return
+		}
+		respondDBError(ctx, err, codeScheduleNotFound)
+		return
+	}
+
+	// cancelling leaves the last run alone, it is still the one read along with the schedule
+	ctx.JSON(http.StatusOK, newScheduledTransferResponse(schedule, row.LastTransferUUID, numbers))
+}
@@ -0,0 +1,591 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func randomScheduledTransfer(fromAccountID, toAccountID int64) db.ScheduledTransfer {
+	return db.ScheduledTransfer{
+		ID:              utils.RandomInt(1, 1000),
+		FromAccountID:   fromAccountID,
+		ToAccountID:     toAccountID,
+		Amount:          _amount,
+		IntervalSeconds: 3600,
+		NextRunAt:       time.Now().UTC().Truncate(time.Second),
+		CreatedAt:       time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestCreateScheduledTransferAPI(t *testing.T) {
+	schedule := randomScheduledTransfer(account1.ID, account2.ID)
+	startAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	body := func(startAt *time.Time) gin.H {
+		b := gin.H{
+			"from_account_number": account1.AccountNumber,
+			"to_account_number":   account2.AccountNumber,
+			"amount":              _amount,
+			"currency":            utils.USD,
+			"interval_seconds":    schedule.IntervalSeconds,
+		}
+		if startAt != nil {
+			b["start_at"] = startAt
+		}
+		return b
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path starting now",
+			body: body(nil),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), account1.AccountNumber).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), account2.AccountNumber).Times(1).Return(account2, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(ctx context.Context, arg db.CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.Equal(t, account1.ID, arg.FromAccountID)
+						require.Equal(t, account2.ID, arg.ToAccountID)
+						require.Equal(t, int64(_amount), arg.Amount)
+						require.Equal(t, schedule.IntervalSeconds, arg.IntervalSeconds)
+						require.WithinDuration(t, time.Now(), arg.NextRunAt, time.Second)
+						return schedule, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp scheduledTransferResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, newScheduledTransferResponse(schedule, uuid.NullUUID{}, newAccountNumbers(account1, account2)), rsp)
+				require.Equal(t, account1.AccountNumber, rsp.FromAccountNumber)
+				require.Equal(t, account2.AccountNumber, rsp.ToAccountNumber)
+				require.Nil(t, rsp.LastError)
+				require.Nil(t, rsp.CancelledAt)
+			},
+		},
+		{
+			name: "happy path starting later",
+			body: body(&startAt),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateScheduledTransferParams{
+					FromAccountID:   account1.ID,
+					ToAccountID:     account2.ID,
+					Amount:          _amount,
+					IntervalSeconds: schedule.IntervalSeconds,
+					NextRunAt:       startAt,
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), account1.AccountNumber).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), account2.AccountNumber).Times(1).Return(account2, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Eq(arg)).Times(1).Return(schedule, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "start in the past",
+			body: func() gin.H {
+				past := time.Now().Add(-time.Hour)
+				return body(&past)
+			}(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "interval too short",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+				"interval_seconds":    59,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "same from and to account",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account1.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+				"interval_seconds":    schedule.IntervalSeconds,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "to account currency mismatch",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   accountARS.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+				"interval_seconds":    schedule.IntervalSeconds,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arsAccount := accountARS
+				arsAccount.Currency = utils.ARS
+				store.EXPECT().GetAccountByNumber(gomock.Any(), account1.AccountNumber).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), accountARS.AccountNumber).Times(1).Return(arsAccount, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeCurrencyMismatch)
+			},
+		},
+		{
+			name: "not the from account owner",
+			body: body(nil),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user2.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), account1.AccountNumber).Times(1).Return(account1, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireErrorCode(t, recorder, codeNotAccountOwner)
+			},
+		},
+		{
+			name: "internal server error",
+			body: body(nil),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), account1.AccountNumber).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountByNumber(gomock.Any(), account2.AccountNumber).Times(1).Return(account2, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(1).Return(db.ScheduledTransfer{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "no authorization",
+			body: body(nil),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/scheduled_transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListScheduledTransfersAPI(t *testing.T) {
+	n := 5
+	schedules := make([]db.ListScheduledTransfersRow, n)
+	for i := range schedules {
+		schedules[i] = db.ListScheduledTransfersRow{ScheduledTransfer: randomScheduledTransfer(account1.ID, account2.ID)}
+	}
+	schedules[0].ScheduledTransfer.LastError = sql.NullString{String: db.ErrInsufficientBalance.Error(), Valid: true}
+	schedules[0].ScheduledTransfer.FailedRuns = 1
+	schedules[1].ScheduledTransfer.LastTransferID = sql.NullInt64{Int64: utils.RandomInt(1, 1000), Valid: true}
+	schedules[1].LastTransferUUID = uuid.NullUUID{UUID: uuid.New(), Valid: true}
+
+	type query struct {
+		accountNumber string
+		pageID        int
+		pageSize      int
+	}
+
+	testCases := []struct {
+		name          string
+		query         query
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "happy path list scheduled transfers",
+			query: query{accountNumber: account1.AccountNumber, pageID: 1, pageSize: n},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListScheduledTransfersParams{
+					FromAccountID: account1.ID,
+					Limit:         int32(n),
+					Offset:        0,
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account1.AccountNumber)).Times(1).Return(account1, nil)
+				store.EXPECT().ListScheduledTransfers(gomock.Any(), gomock.Eq(arg)).Times(1).Return(schedules, nil)
+				store.EXPECT().CountScheduledTransfers(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(int64(n), nil)
+				// every schedule goes to the same account, its number is read once
+				numbers := []db.GetAccountNumbersRow{{ID: account2.ID, AccountNumber: account2.AccountNumber}}
+				store.EXPECT().GetAccountNumbers(gomock.Any(), gomock.Eq([]int64{account2.ID})).Times(1).Return(numbers, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp struct {
+					Data  []scheduledTransferResponse `json:"data"`
+					Total int64                       `json:"total"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.Data, n)
+				require.Equal(t, int64(n), rsp.Total)
+				// the failure of the last run is reported to the owner
+				require.Equal(t, db.ErrInsufficientBalance.Error(), *rsp.Data[0].LastError)
+				require.Equal(t, int32(1), rsp.Data[0].FailedRuns)
+				require.Nil(t, rsp.Data[1].LastError)
+				// the last transfer is sent by its uuid, never by its internal id
+				require.Nil(t, rsp.Data[0].LastTransferID)
+				require.NotNil(t, rsp.Data[1].LastTransferID)
+				require.Equal(t, schedules[1].LastTransferUUID.UUID, *rsp.Data[1].LastTransferID)
+				require.Equal(t, account1.AccountNumber, rsp.Data[0].FromAccountNumber)
+				require.Equal(t, account2.AccountNumber, rsp.Data[0].ToAccountNumber)
+			},
+		},
+		{
+			name:  "not the account owner",
+			query: query{accountNumber: account1.AccountNumber, pageID: 1, pageSize: n},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user2.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account1.AccountNumber)).Times(1).Return(account1, nil)
+				store.EXPECT().ListScheduledTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeNotAccountOwner)
+			},
+		},
+		{
+			name:  "account not found",
+			query: query{accountNumber: account1.AccountNumber, pageID: 1, pageSize: n},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account1.AccountNumber)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().ListScheduledTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountNotFound)
+			},
+		},
+		{
+			name:  "zero page size",
+			query: query{accountNumber: account1.AccountNumber, pageID: 1, pageSize: 0},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListScheduledTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodGet, "/scheduled_transfers", nil)
+			require.NoError(t, err)
+
+			q := request.URL.Query()
+			q.Add("account_number", tc.query.accountNumber)
+			q.Add("page_id", fmt.Sprintf("%d", tc.query.pageID))
+			q.Add("page_size", fmt.Sprintf("%d", tc.query.pageSize))
+			request.URL.RawQuery = q.Encode()
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCancelScheduledTransferAPI(t *testing.T) {
+	schedule := randomScheduledTransfer(account1.ID, account2.ID)
+	schedule.LastTransferID = sql.NullInt64{Int64: utils.RandomInt(1, 1000), Valid: true}
+	cancelled := schedule
+	cancelled.CancelledAt = sql.NullTime{Time: time.Now().UTC().Truncate(time.Second), Valid: true}
+	lastTransferUUID := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	deletedAccount := account1
+	deletedAccount.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	testCases := []struct {
+		name          string
+		scheduleID    int64
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "happy path cancel scheduled transfer",
+			scheduleID: schedule.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(db.GetScheduledTransferRow{ScheduledTransfer: schedule, LastTransferUUID: lastTransferUUID}, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountNumbers(gomock.Any(), gomock.Eq([]int64{account2.ID})).Times(1).
+					Return([]db.GetAccountNumbersRow{{ID: account2.ID, AccountNumber: account2.AccountNumber}}, nil)
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(cancelled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp scheduledTransferResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotNil(t, rsp.CancelledAt)
+				require.True(t, cancelled.CancelledAt.Time.Equal(*rsp.CancelledAt))
+				require.Equal(t, account2.AccountNumber, rsp.ToAccountNumber)
+				require.NotNil(t, rsp.LastTransferID)
+				require.Equal(t, lastTransferUUID.UUID, *rsp.LastTransferID)
+			},
+		},
+		{
+			name:       "from account deleted",
+			scheduleID: schedule.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(db.GetScheduledTransferRow{ScheduledTransfer: schedule, LastTransferUUID: lastTransferUUID}, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(deletedAccount, nil)
+				store.EXPECT().GetAccountNumbers(gomock.Any(), gomock.Eq([]int64{account2.ID})).Times(1).
+					Return([]db.GetAccountNumbersRow{{ID: account2.ID, AccountNumber: account2.AccountNumber}}, nil)
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(cancelled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:       "scheduled transfer not found",
+			scheduleID: schedule.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(db.GetScheduledTransferRow{}, sql.ErrNoRows)
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeScheduleNotFound)
+			},
+		},
+		{
+			name:       "not the from account owner",
+			scheduleID: schedule.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user2.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(db.GetScheduledTransferRow{ScheduledTransfer: schedule, LastTransferUUID: lastTransferUUID}, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeNotAccountOwner)
+			},
+		},
+		{
+			name:       "already cancelled",
+			scheduleID: schedule.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(db.GetScheduledTransferRow{ScheduledTransfer: cancelled}, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeScheduleCancelled)
+			},
+		},
+		{
+			name:       "cancelled concurrently",
+			scheduleID: schedule.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(db.GetScheduledTransferRow{ScheduledTransfer: schedule, LastTransferUUID: lastTransferUUID}, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccountNumbers(gomock.Any(), gomock.Eq([]int64{account2.ID})).Times(1).
+					Return([]db.GetAccountNumbersRow{{ID: account2.ID, AccountNumber: account2.AccountNumber}}, nil)
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeScheduleCancelled)
+			},
+		},
+		{
+			name:       "invalid id",
+			scheduleID: 0,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/scheduled_transfers/%d", tc.scheduleID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// defaultContentSecurityPolicy fits a json api: responses never load anything nor get framed
+const defaultContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+
+// hstsHeader value asks browsers to only reach the api over https for a year
+const hstsHeader = "max-age=31536000; includeSubDomains"
+
+// securityHeadersMiddleware sets the headers telling browsers to not sniff, frame or leak the api responses
+// contentSecurityPolicy replaces the default policy when set, hsts is only sent when the api is served over TLS
+func securityHeadersMiddleware(contentSecurityPolicy string, hsts bool) gin.HandlerFunc {
+	if contentSecurityPolicy == "" {
+		contentSecurityPolicy = defaultContentSecurityPolicy
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Header("X-Content-Type-Options", "nosniff")
+		ctx.Header("X-Frame-Options", "DENY")
+		ctx.Header("Referrer-Policy", "no-referrer")
+		ctx.Header("Content-Security-Policy", contentSecurityPolicy)
+		if hsts {
+			ctx.Header("Strict-Transport-Security", hstsHeader)
+		}
+		ctx.Next()
+	}
+}
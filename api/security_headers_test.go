@@ -0,0 +1,94 @@
+package api
+
+import (
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	testCases := []struct {
+		name          string
+		setupConfig   func(config *utils.Config)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "default headers",
+			setupConfig: func(config *utils.Config) {
+				config.SecurityHeaders = true
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+				require.Equal(t, "DENY", recorder.Header().Get("X-Frame-Options"))
+				require.Equal(t, "no-referrer", recorder.Header().Get("Referrer-Policy"))
+				require.Equal(t, defaultContentSecurityPolicy, recorder.Header().Get("Content-Security-Policy"))
+				// served over plain http
+				require.Empty(t, recorder.Header().Get("Strict-Transport-Security"))
+			},
+		},
+		{
+			name: "configured content security policy",
+			setupConfig: func(config *utils.Config) {
+				config.SecurityHeaders = true
+				config.SecurityHeadersCSP = "default-src 'self'"
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "default-src 'self'", recorder.Header().Get("Content-Security-Policy"))
+				require.Equal(t, "nosniff", recorder.Header().Get("X-Content-Type-Options"))
+			},
+		},
+		{
+			name: "served over tls",
+			setupConfig: func(config *utils.Config) {
+				config.SecurityHeaders = true
+				config.TLSCertFile = "server.crt"
+				config.TLSKeyFile = "server.key"
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, hstsHeader, recorder.Header().Get("Strict-Transport-Security"))
+			},
+		},
+		{
+			name:        "security headers disabled",
+			setupConfig: func(config *utils.Config) {},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				for _, header := range []string{"X-Content-Type-Options", "X-Frame-Options", "Referrer-Policy", "Content-Security-Policy", "Strict-Transport-Security"} {
+					require.Empty(t, recorder.Header().Get(header), header)
+				}
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			config := utils.Config{
+				TokenSymmetricKey: utils.RandomString(32),
+				TokenDuration:     time.Minute,
+			}
+			tc.setupConfig(&config)
+
+			server, err := NewServer(config, nil, newTestTaskDistributor())
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
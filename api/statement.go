@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	statementFormatJSON = "json"
+	statementFormatCSV  = "csv"
+	csvContentType      = "text/csv"
+)
+
+var statementCSVHeader = []string{"id", "account_number", "amount", "running_balance", "created_at"}
+
+type (
+	getAccountStatementUriReq struct {
+		AccountNumber string `uri:"number" binding:"required,len=16,numeric"`
+	}
+
+	// getAccountStatementReq range includes from and excludes to
+	getAccountStatementReq struct {
+		From   time.Time `form:"from" binding:"required" time_format:"2006-01-02T15:04:05Z07:00"`
+		To     time.Time `form:"to" binding:"required" time_format:"2006-01-02T15:04:05Z07:00"`
+		Format string    `form:"format" binding:"omitempty,oneof=json csv"`
+	}
+
+	// statementRowResponse leaves out the internal account id, the statement is requested by account number
+	statementRowResponse struct {
+		db.ListAccountStatementRow
+		AccountID *int64 `json:"account_id,omitempty"`
+	}
+)
+
+// getAccountStatement returns the account entries within the date range along with the balance after each of them
+// The format is taken from the format query param, or from the Accept header when it's missing
+func (s *Server) getAccountStatement(ctx *gin.Context) {
+	var uri getAccountStatementUriReq
+	if !bindURI(ctx, &uri) {
+		return
+	}
+
+	var req getAccountStatementReq
+	if !bindQuery(ctx, &req) {
+		return
+	}
+
+	if !req.From.Before(req.To) {
+		err := errors.New("from must be before to")
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, uri.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != account.Owner {
+		err = errors.New("account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	// the entries created_at column has no time zone and is stored in UTC
+	arg := db.ListAccountStatementParams{
+		AccountID: account.ID,
+		FromTime:  req.From.UTC(),
+		ToTime:    req.To.UTC(),
+	}
+
+	if statementFormat(ctx, req.Format) == statementFormatCSV {
+		s.streamAccountStatementCSV(ctx, account.AccountNumber, arg)
+		return
+	}
+
+	statement, err := s.store.ListAccountStatement(ctx, arg)
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	rsp := make([]statementRowResponse, len(statement))
+	for i, row := range statement {
+		rsp[i] = statementRowResponse{ListAccountStatementRow: row}
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// streamAccountStatementCSV writes every statement row as soon as the store reads it, so large ranges are never buffered whole
+// The amounts are formatted for the configured locale, the csv is meant to be opened in a spreadsheet
+func (s *Server) streamAccountStatementCSV(ctx *gin.Context, accountNumber string, arg db.ListAccountStatementParams) {
+	w := csv.NewWriter(ctx.Writer)
+	err := w.Write(statementCSVHeader)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
+	}
+
+	ctx.Header("Content-Type", csvContentType)
+	ctx.Status(http.StatusOK)
+
+	err = s.store.StreamAccountStatement(ctx, arg, func(row db.ListAccountStatementRow) error {
+		return w.Write([]string{
+			strconv.FormatInt(row.ID, 10),
+			accountNumber,
+			utils.Money(row.Amount).Format(s.config.DefaultLocale),
+			utils.Money(row.RunningBalance).Format(s.config.DefaultLocale),
+			row.CreatedAt.Time.Format(time.RFC3339),
+		})
+	})
+	if err != nil {
+		// nothing reached the client yet, so the csv buffer is dropped and the error can still be reported
+		if !ctx.Writer.Written() {
+			ctx.Writer.Header().Del("Content-Type")
+			respondDBError(ctx, err, codeNotFound)
+			return
+		}
+		_ = ctx.Error(err)
+		return
+	}
+
+	w.Flush()
+	if err = w.Error(); err != nil {
+		_ = ctx.Error(err)
+	}
+}
+
+// statementFormat resolves the requested statement format, the query param wins over the Accept header
+func statementFormat(ctx *gin.Context, format string) string {
+	if format != "" {
+		return format
+	}
+
+	if strings.Contains(ctx.GetHeader("Accept"), csvContentType) {
+		return statementFormatCSV
+	}
+
+	return statementFormatJSON
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestGetAccountStatementAPI(t *testing.T) {
+	user, _ := randomUser()
+	otherUser, _ := randomUser()
+	account := randomAccount(user.Username)
+
+	from := time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Second)
+	to := time.Now().UTC().Truncate(time.Second)
+
+	rows := randomStatementRows(account, from, 3)
+	arg := db.ListAccountStatementParams{
+		AccountID: account.ID,
+		FromTime:  from,
+		ToTime:    to,
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path json statement",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(from, to, ""),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountStatement(gomock.Any(), gomock.Eq(arg)).Times(1).Return(rows, nil)
+				store.EXPECT().StreamAccountStatement(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp []db.ListAccountStatementRow
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp, len(rows))
+				for i := range rows {
+					require.Equal(t, rows[i].ID, rsp[i].ID)
+					require.Equal(t, rows[i].RunningBalance, rsp[i].RunningBalance)
+				}
+			},
+		},
+		{
+			name: "happy path csv statement from query param",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(from, to, statementFormatCSV),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountStatement(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().StreamAccountStatement(gomock.Any(), gomock.Eq(arg), gomock.Any()).
+					Times(1).
+					DoAndReturn(streamRows(rows, nil))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStatementCSV(t, recorder, account.AccountNumber, rows)
+			},
+		},
+		{
+			name: "happy path csv statement from accept header",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+				request.Header.Set("Accept", csvContentType)
+			},
+			query: statementQuery(from, to, ""),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().StreamAccountStatement(gomock.Any(), gomock.Eq(arg), gomock.Any()).
+					Times(1).
+					DoAndReturn(streamRows(rows, nil))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStatementCSV(t, recorder, account.AccountNumber, rows)
+			},
+		},
+		{
+			name: "empty range json statement",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(from, to, statementFormatJSON),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountStatement(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.ListAccountStatementRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, "[]", recorder.Body.String())
+			},
+		},
+		{
+			name: "empty range csv statement",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(from, to, statementFormatCSV),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().StreamAccountStatement(gomock.Any(), gomock.Eq(arg), gomock.Any()).
+					Times(1).
+					DoAndReturn(streamRows(nil, nil))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchStatementCSV(t, recorder, account.AccountNumber, nil)
+			},
+		},
+		{
+			name: "from after to",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(to, from, ""),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "unsupported format",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(from, to, "xml"),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "account doesn't belong to the authenticated user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, otherUser.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(from, to, ""),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListAccountStatement(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "account not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(from, to, ""),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "csv statement internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: statementQuery(from, to, statementFormatCSV),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().StreamAccountStatement(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(streamRows(nil, sql.ErrTxDone))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Contains(t, recorder.Header().Get("Content-Type"), "application/json")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/accounts/%s/statement?%s", account.AccountNumber, tc.query.Encode())
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func statementQuery(from, to time.Time, format string) url.Values {
+	query := url.Values{}
+	query.Add("from", from.Format(time.RFC3339))
+	query.Add("to", to.Format(time.RFC3339))
+	if format != "" {
+		query.Add("format", format)
+	}
+	return query
+}
+
+func randomStatementRows(account db.Account, from time.Time, n int) []db.ListAccountStatementRow {
+	rows := make([]db.ListAccountStatementRow, n)
+	balance := account.Balance
+	for i := 0; i < n; i++ {
+		amount := utils.RandomInt(-100, 100)
+		balance += amount
+		rows[i] = db.ListAccountStatementRow{
+			ID:             utils.RandomInt(1, 1000),
+			Amount:         amount,
+			AccountID:      account.ID,
+			CreatedAt:      sql.NullTime{Time: from.Add(time.Duration(i) * time.Minute), Valid: true},
+			RunningBalance: balance,
+		}
+	}
+	return rows
+}
+
+// streamRows mimics the store streaming the rows one by one into the handler callback
+func streamRows(rows []db.ListAccountStatementRow, err error) func(interface{}, db.ListAccountStatementParams, func(db.ListAccountStatementRow) error) error {
+	return func(_ interface{}, _ db.ListAccountStatementParams, fn func(db.ListAccountStatementRow) error) error {
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func requireBodyMatchStatementCSV(t *testing.T, recorder *httptest.ResponseRecorder, accountNumber string, rows []db.ListAccountStatementRow) {
+	require.Equal(t, csvContentType, recorder.Header().Get("Content-Type"))
+
+	records, err := csv.NewReader(recorder.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, len(rows)+1)
+	require.Equal(t, statementCSVHeader, records[0])
+
+	for i, row := range rows {
+		record := records[i+1]
+		require.Equal(t, strconv.FormatInt(row.ID, 10), record[0])
+		require.Equal(t, accountNumber, record[1])
+		require.Equal(t, utils.Money(row.Amount).Format(utils.DefaultLocale), record[2])
+		require.Equal(t, utils.Money(row.RunningBalance).Format(utils.DefaultLocale), record[3])
+	}
+}
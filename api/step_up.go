@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/micaelapucciariello/simplebank/token"
+	"net/http"
+	"time"
+)
+
+// stepUpTokenHeader carries the step-up token of the sensitive operations, next to the access token of the request
+const stepUpTokenHeader = "X-Step-Up-Token"
+
+var errStepUpRequired = errors.New("this operation requires a step-up token, authenticate again at /users/step_up")
+
+type (
+	stepUpReq struct {
+		Password string `json:"password" binding:"required"`
+		// TOTPCode is the two-factor code, required once the user enabled two-factor authentication
+		TOTPCode string `json:"totp_code"`
+	}
+
+	stepUpResponse struct {
+		StepUpToken          string    `json:"step_up_token"`
+		StepUpTokenExpiresAt time.Time `json:"step_up_token_expires_at"`
+	}
+)
+
+// stepUp checks the credentials of the authenticated user again and returns a short lived step-up token
+// The sensitive operations, like a password change, require it so a stolen access token alone can't perform them
+func (s *Server) stepUp(ctx *gin.Context) {
+	var req stepUpReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	user, err := s.store.GetUser(ctx, authPayload.UserName)
+	if err != nil {
+		respondDBError(ctx, err, codeUserNotFound)
+		return
+	}
+
+	if !s.verifyCredentials(ctx, user, req.Password, req.TOTPCode) {
+		return
+	}
+
+	stepUpToken, stepUpPayload, err := s.token.CreateStepUpToken(user.Username, user.Role, user.OrgID, user.PasswordChangedAt)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, stepUpResponse{
+		StepUpToken:          stepUpToken,
+		StepUpTokenExpiresAt: stepUpPayload.ExpiredAt,
+	})
+}
+
+// requireStepUp checks the request carries a step-up token of the authenticated user, writing the error response
+func (s *Server) requireStepUp(ctx *gin.Context) bool {
+	stepUpToken := ctx.GetHeader(stepUpTokenHeader)
+	if stepUpToken == "" {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeStepUpRequired, errStepUpRequired))
+		return false
+	}
+
+	payload, err := s.token.VerifyToken(stepUpToken)
+	if err == nil {
+		err = payload.VerifyKind(token.KindStepUp)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeInvalidToken, err))
+		return false
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if payload.UserName != authPayload.UserName {
+		err = errors.New("step-up token doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeInvalidToken, err))
+		return false
+	}
+	return true
+}
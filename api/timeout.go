@@ -0,0 +1,19 @@
+package api
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+// timeoutMiddleware bounds every request with the given timeout, the store calls made with the gin context stop once it expires
+// respondDBError answers the store errors caused by the expired deadline with a 504
+func timeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		timeoutCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+		defer cancel()
+
+		ctx.Request = ctx.Request.WithContext(timeoutCtx)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func newTimeoutTestServer(t *testing.T, store *mockdb.MockStore, timeout time.Duration) *Server {
+	config := utils.Config{
+		TokenSymmetricKey: utils.RandomString(32),
+		TokenDuration:     time.Minute,
+		RequestTimeout:    timeout,
+	}
+
+	store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Any()).AnyTimes().Return(time.Time{}, nil)
+
+	server, err := NewServer(config, store, newTestTaskDistributor())
+	require.NoError(t, err)
+
+	return server
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+	timeout := 50 * time.Millisecond
+
+	testCases := []struct {
+		name          string
+		timeout       time.Duration
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "store blocks past the timeout",
+			timeout: timeout,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).DoAndReturn(
+					func(ctx context.Context, accountNumber string) (db.Account, error) {
+						select {
+						case <-ctx.Done():
+							return db.Account{}, ctx.Err()
+						case <-time.After(10 * timeout):
+							return account, nil
+						}
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+				requireErrorCode(t, recorder, codeTimeout)
+				require.Contains(t, recorder.Body.String(), context.DeadlineExceeded.Error())
+			},
+		},
+		{
+			name:    "store answers within the timeout",
+			timeout: timeout,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).DoAndReturn(
+					func(ctx context.Context, accountNumber string) (db.Account, error) {
+						_, ok := ctx.Deadline()
+						require.True(t, ok)
+						return account, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name:    "server error before the timeout",
+			timeout: timeout,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(db.Account{}, context.Canceled)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:    "timeout disabled",
+			timeout: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).DoAndReturn(
+					func(ctx context.Context, accountNumber string) (db.Account, error) {
+						_, ok := ctx.Deadline()
+						require.False(t, ok)
+						return account, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTimeoutTestServer(t, store, tc.timeout)
+
+			url := fmt.Sprintf("/accounts/%s", account.AccountNumber)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.token, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
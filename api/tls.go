@@ -0,0 +1,96 @@
+package api
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/rs/zerolog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// certReloader serves the certificate loaded from its files, it loads them again on reload so a rotated certificate
+// is picked up by the next handshakes without restarting the server. The connections already open keep the old one
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	reloader := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// reload reads the certificate files, a failed reload keeps serving the previous certificate
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load tls certificate %v: %w", r.certFile, err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *certReloader) getCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// watch reloads the certificate on every signal received until signals is closed
+func (r *certReloader) watch(signals <-chan os.Signal, logger zerolog.Logger) {
+	for range signals {
+		if err := r.reload(); err != nil {
+			logger.Error().Err(err).Msg("cannot reload tls certificate, the previous one is still served")
+			continue
+		}
+		logger.Info().Str("cert_file", r.certFile).Msg("tls certificate reloaded")
+	}
+}
+
+// startTLS serves the router over TLS in the specified address, the certificate is reloaded from its files on SIGHUP
+func (s *Server) startTLS(address string) error {
+	reloader, err := newCertReloader(s.config.TLSCertFile, s.config.TLSKeyFile)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer func() {
+		signal.Stop(signals)
+		close(signals)
+	}()
+	go reloader.watch(signals, s.logger)
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.serveTLS(listener, reloader)
+}
+
+// serveTLS serves the router over TLS on the listener with the certificate of the reloader
+func (s *Server) serveTLS(listener net.Listener, reloader *certReloader) error {
+	server := &http.Server{
+		Handler: s.router,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.getCertificate,
+		},
+	}
+	// the certificate comes from the tls config, so no files are given here
+	return server.ServeTLS(listener, "", "")
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert writes a certificate for localhost and its key to the files, the certificate is returned to trust it
+func writeSelfSignedCert(t *testing.T, certFile, keyFile string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(utils.RandomInt(1, 1<<40)),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	require.NoError(t, err)
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	require.NoError(t, err)
+
+	return cert
+}
+
+// freeAddress returns a local address nothing listens on
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestStartTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	cert := writeSelfSignedCert(t, certFile, keyFile)
+
+	server := newTestServer(t, nil)
+	server.config.TLSCertFile = certFile
+	server.config.TLSKeyFile = keyFile
+
+	address := freeAddress(t)
+	go server.Start(address)
+
+	// handshake dials until the server listens, and returns the certificate it served
+	handshake := func(trusted *x509.Certificate) (*x509.Certificate, error) {
+		roots := x509.NewCertPool()
+		roots.AddCert(trusted)
+
+		conn, err := tls.Dial("tcp", address, &tls.Config{RootCAs: roots, ServerName: "localhost"})
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0], nil
+	}
+
+	var served *x509.Certificate
+	require.Eventually(t, func() bool {
+		var err error
+		served, err = handshake(cert)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, cert.SerialNumber, served.SerialNumber)
+
+	// the api answers over the tls connection
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "localhost"}}}
+	rsp, err := client.Get("https://" + address + "/healthz")
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+
+	// plaintext requests are rejected
+	rsp, err = http.Get("http://" + address + "/healthz")
+	require.NoError(t, err)
+	rsp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, rsp.StatusCode)
+
+	// a rotated certificate is served after SIGHUP, without restarting the server
+	rotated := writeSelfSignedCert(t, certFile, keyFile)
+	process, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	if err = process.Signal(syscall.SIGHUP); err != nil {
+		t.Skipf("cannot send SIGHUP: %s", err)
+	}
+
+	require.Eventually(t, func() bool {
+		served, err := handshake(rotated)
+		return err == nil && served.SerialNumber.Cmp(rotated.SerialNumber) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	cert := writeSelfSignedCert(t, certFile, keyFile)
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	served := func() *big.Int {
+		tlsCert, err := reloader.getCertificate(nil)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(tlsCert.Certificate[0])
+		require.NoError(t, err)
+		return leaf.SerialNumber
+	}
+	require.Equal(t, cert.SerialNumber, served())
+
+	signals := make(chan os.Signal)
+	defer close(signals)
+	go reloader.watch(signals, zerolog.Nop())
+
+	rotated := writeSelfSignedCert(t, certFile, keyFile)
+	signals <- syscall.SIGHUP
+	require.Eventually(t, func() bool {
+		return served().Cmp(rotated.SerialNumber) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// a broken rotation keeps the previous certificate
+	err = os.WriteFile(keyFile, []byte("not a key"), 0600)
+	require.NoError(t, err)
+	require.Error(t, reloader.reload())
+	require.Equal(t, rotated.SerialNumber, served())
+
+	_, err = newCertReloader(certFile, keyFile)
+	require.Error(t, err)
+}
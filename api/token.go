@@ -1,73 +1,85 @@
 package api
 
 import (
-	"database/sql"
+	"fmt"
 	"github.com/gin-gonic/gin"
-	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
 	"net/http"
 	"time"
 )
 
 type (
 	renewAccessTokenRequest struct {
-		RefreshToken string `json:"refresh_token"`
+		RefreshToken string `json:"refresh_token" binding:"required"`
 	}
 
 	renewAccessTokenResponse struct {
-		RefreshToken          string    `json:"refresh_token"`
-		RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+		AccessToken          string    `json:"access_token"`
+		AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
 	}
 )
 
+// renewAccessToken issues a new access token for a valid refresh token whose session is still active
 func (s *Server) renewAccessToken(ctx *gin.Context) {
 	var req renewAccessTokenRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+	if !bindJSON(ctx, &req) {
 		return
 	}
 
-	payload, err := s.token.VerifyToken(req.RefreshToken)
+	refreshPayload, err := s.token.VerifyToken(req.RefreshToken)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeInvalidToken, err))
+		return
+	}
+	if err = refreshPayload.VerifyKind(token.KindRefresh); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeInvalidToken, err))
 		return
 	}
 
-	session, err := s.store.GetSession(ctx, payload.ID)
+	session, err := s.store.GetSession(ctx, refreshPayload.ID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		respondDBError(ctx, err, codeSessionNotFound)
 		return
 	}
 
-	if session.Username != payload.UserName {
-		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+	if session.IsBlocked {
+		err = fmt.Errorf("session [%v] is blocked", session.ID)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeInvalidToken, err))
 		return
 	}
 
-	refreshToken, payload, err := s.token.CreateToken(payload.UserName, s.config.RefreshTokenDuration)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+	if session.Username != refreshPayload.UserName {
+		err = fmt.Errorf("session [%v] doesn't belong to the token user", session.ID)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeInvalidToken, err))
+		return
+	}
+
+	if session.RefreshToken != req.RefreshToken {
+		err = fmt.Errorf("session [%v] refresh token mismatched", session.ID)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeInvalidToken, err))
+		return
 	}
 
-	session, err = s.store.CreateSession(ctx, db.CreateSessionParams{
-		ID:           payload.ID,
-		Username:     payload.UserName,
-		RefreshToken: refreshToken,
-		UserAgent:    ctx.Request.UserAgent(),
-		ClientIp:     ctx.ClientIP(),
-		IsBlocked:    false,
-		ExpiresAt:    sql.NullTime{Time: payload.ExpiredAt},
-	})
+	if session.ExpiresAt.Valid && time.Now().After(session.ExpiresAt.Time) {
+		err = fmt.Errorf("session [%v] is expired", session.ID)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeInvalidToken, err))
+		return
+	}
+
+	if status, err := checkPasswordChangedAt(ctx, s.store, refreshPayload); err != nil {
+		ctx.JSON(status, errorResponse(codeForStatus(status), err))
+		return
+	}
+
+	accessToken, accessPayload, err := s.token.CreateAccessToken(refreshPayload.UserName, refreshPayload.Role, refreshPayload.OrgID, refreshPayload.PasswordChangedAt)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
 	}
 
 	rsp := renewAccessTokenResponse{
-		RefreshToken:          refreshToken,
-		RefreshTokenExpiresAt: payload.ExpiredAt,
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
 	}
 
 	ctx.JSON(http.StatusOK, rsp)
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	testCases := []struct {
+		name          string
+		buildSession  func(session db.Session) db.Session
+		buildStubs    func(store *mockdb.MockStore, session db.Session)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path renew access token",
+			buildSession: func(session db.Session) db.Session {
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp renewAccessTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.WithinDuration(t, time.Now().Add(time.Minute), rsp.AccessTokenExpiresAt, time.Second)
+			},
+		},
+		{
+			name: "blocked session",
+			buildSession: func(session db.Session) db.Session {
+				session.IsBlocked = true
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "mismatched session user",
+			buildSession: func(session db.Session) db.Session {
+				session.Username = "another_user"
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "mismatched refresh token",
+			buildSession: func(session db.Session) db.Session {
+				session.RefreshToken = "another_token"
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "expired session",
+			buildSession: func(session db.Session) db.Session {
+				session.ExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "session not found",
+			buildSession: func(session db.Session) db.Session {
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(db.Session{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "internal server error",
+			buildSession: func(session db.Session) db.Session {
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(db.Session{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			refreshToken, refreshPayload, err := server.token.CreateToken(token.KindRefresh, user.Username, user.Role, user.OrgID, user.PasswordChangedAt, time.Hour)
+			require.NoError(t, err)
+
+			session := tc.buildSession(db.Session{
+				ID:           refreshPayload.ID,
+				Username:     user.Username,
+				RefreshToken: refreshToken,
+				ExpiresAt:    sql.NullTime{Time: refreshPayload.ExpiredAt, Valid: true},
+			})
+			tc.buildStubs(store, session)
+
+			data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRenewAccessTokenInvalidTokenAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+
+	recorder := httptest.NewRecorder()
+	server := newTestServer(t, store)
+
+	data, err := json.Marshal(gin.H{"refresh_token": "invalid_token"})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestRenewAccessTokenWithAccessTokenAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+
+	recorder := httptest.NewRecorder()
+	server := newTestServer(t, store)
+
+	// an access token is signed with the same key, it must not renew itself
+	accessToken, _, err := server.token.CreateAccessToken(user.Username, user.Role, user.OrgID, user.PasswordChangedAt)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(gin.H{"refresh_token": accessToken})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	requireErrorCode(t, recorder, codeInvalidToken)
+}
+
+func TestRenewAccessTokenPasswordChangedAPI(t *testing.T) {
+	user, _ := randomUser()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	// the password was changed after the refresh token was minted
+	var session db.Session
+	store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(user.PasswordChangedAt.Add(time.Minute), nil)
+	store.EXPECT().GetSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, _ interface{}) (db.Session, error) {
+			return session, nil
+		})
+
+	recorder := httptest.NewRecorder()
+	server := newTestServer(t, store)
+
+	refreshToken, refreshPayload, err := server.token.CreateToken(token.KindRefresh, user.Username, user.Role, user.OrgID, user.PasswordChangedAt, time.Hour)
+	require.NoError(t, err)
+
+	session = db.Session{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		ExpiresAt:    sql.NullTime{Time: refreshPayload.ExpiredAt, Valid: true},
+	}
+
+	data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
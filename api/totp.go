@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"net/http"
+	"time"
+)
+
+var (
+	errTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	errTOTPNotEnrolled    = errors.New("two-factor authentication isn't enrolled")
+	errTOTPRequired       = errors.New("two-factor code required")
+	errInvalidTOTPCode    = errors.New("invalid two-factor code")
+)
+
+type (
+	enrollTOTPResponse struct {
+		// Secret is shown once, for the users whose authenticator app can't scan the url
+		Secret     string `json:"secret"`
+		OtpauthURL string `json:"otpauth_url"`
+	}
+
+	verifyTOTPReq struct {
+		Code string `json:"code" binding:"required,numeric,len=6"`
+	}
+
+	verifyTOTPResponse struct {
+		Enabled   bool      `json:"enabled"`
+		EnabledAt time.Time `json:"enabled_at"`
+	}
+)
+
+// enrollTOTP generates a new two-factor secret for the authenticated user. It isn't enabled until a code of it is
+// verified, enrolling again before that replaces it
+func (s *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+
+	stored, err := s.store.GetTotpSecret(ctx, authPayload.UserName)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondDBError(ctx, err, codeTOTPNotEnrolled)
+		return
+	}
+	if err == nil && stored.EnabledAt.Valid {
+		ctx.JSON(http.StatusConflict, errorResponse(codeTOTPAlreadyEnabled, errTOTPAlreadyEnabled))
+		return
+	}
+
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
+	}
+	encrypted, err := utils.EncryptTOTPSecret(s.config.TOTPEncryptionKey, secret)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
+	}
+
+	_, err = s.store.UpsertTotpSecret(ctx, db.UpsertTotpSecretParams{
+		Username:        authPayload.UserName,
+		EncryptedSecret: encrypted,
+	})
+	if err != nil {
+		// no row comes back when the secret was enabled since it was read
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, errorResponse(codeTOTPAlreadyEnabled, errTOTPAlreadyEnabled))
+			return
+		}
+		respondDBError(ctx, err, codeUserNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTOTPResponse{
+		Secret:     secret,
+		OtpauthURL: utils.TOTPURL(s.config.TOTPIssuer, authPayload.UserName, secret),
+	})
+}
+
+// verifyTOTP enables two-factor authentication once the user proves their authenticator app generates its codes
+func (s *Server) verifyTOTP(ctx *gin.Context) {
+	var req verifyTOTPReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+
+	stored, err := s.store.GetTotpSecret(ctx, authPayload.UserName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(codeTOTPNotEnrolled, errTOTPNotEnrolled))
+			return
+		}
+		respondDBError(ctx, err, codeTOTPNotEnrolled)
+		return
+	}
+	if stored.EnabledAt.Valid {
+		ctx.JSON(http.StatusConflict, errorResponse(codeTOTPAlreadyEnabled, errTOTPAlreadyEnabled))
+		return
+	}
+
+	secret, err := utils.DecryptTOTPSecret(s.config.TOTPEncryptionKey, stored.EncryptedSecret)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
+	}
+	if !utils.ValidateTOTP(secret, req.Code, time.Now()) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeInvalidTOTPCode, errInvalidTOTPCode))
+		return
+	}
+
+	enabled, err := s.store.EnableTotpSecret(ctx, authPayload.UserName)
+	if err != nil {
+		// no row comes back when a concurrent verification enabled it first
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, errorResponse(codeTOTPAlreadyEnabled, errTOTPAlreadyEnabled))
+			return
+		}
+		respondDBError(ctx, err, codeTOTPNotEnrolled)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, verifyTOTPResponse{Enabled: true, EnabledAt: enabled.EnabledAt.Time})
+}
+
+// enabledTOTPSecret returns the decrypted two-factor secret of the user, ok is false when the user hasn't enabled it
+func (s *Server) enabledTOTPSecret(ctx context.Context, username string) (secret string, ok bool, err error) {
+	stored, err := s.store.GetTotpSecret(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	// a secret enrolled but never verified doesn't protect the login yet
+	if !stored.EnabledAt.Valid {
+		return "", false, nil
+	}
+
+	secret, err = utils.DecryptTOTPSecret(s.config.TOTPEncryptionKey, stored.EncryptedSecret)
+	if err != nil {
+		return "", false, err
+	}
+	return secret, true, nil
+}
@@ -0,0 +1,401 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+// randomTOTPSecret returns a new secret along with its stored row, encrypted with key and enabled when asked
+func randomTOTPSecret(t *testing.T, key, username string, enabled bool) (string, db.TotpSecret) {
+	secret, err := utils.NewTOTPSecret()
+	require.NoError(t, err)
+	encrypted, err := utils.EncryptTOTPSecret(key, secret)
+	require.NoError(t, err)
+
+	stored := db.TotpSecret{Username: username, EncryptedSecret: encrypted, CreatedAt: time.Now()}
+	if enabled {
+		stored.EnabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	return secret, stored
+}
+
+// wrongTOTPCode returns a well formed code that isn't the current one of the secret
+func wrongTOTPCode(t *testing.T, secret string) string {
+	for _, wrong := range []string{"000000", "111111"} {
+		if !utils.ValidateTOTP(secret, wrong, time.Now()) {
+			return wrong
+		}
+	}
+	require.FailNow(t, "no wrong code found")
+	return ""
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser()
+	key := utils.RandomString(utils.TOTPEncryptionKeySize)
+	_, enabled := randomTOTPSecret(t, key, user.Username, true)
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore, stored *[]byte)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, stored []byte)
+	}{
+		{
+			name: "happy path enroll",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, stored *[]byte) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.TotpSecret{}, sql.ErrNoRows)
+				store.EXPECT().UpsertTotpSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpsertTotpSecretParams) (db.TotpSecret, error) {
+						require.Equal(t, user.Username, arg.Username)
+						*stored = arg.EncryptedSecret
+						return db.TotpSecret{Username: arg.Username, EncryptedSecret: arg.EncryptedSecret}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, stored []byte) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp enrollTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.Secret)
+
+				// the secret is stored encrypted, only the key opens it
+				require.NotContains(t, string(stored), rsp.Secret)
+				decrypted, err := utils.DecryptTOTPSecret(key, stored)
+				require.NoError(t, err)
+				require.Equal(t, rsp.Secret, decrypted)
+
+				u, err := url.Parse(rsp.OtpauthURL)
+				require.NoError(t, err)
+				require.Equal(t, "otpauth", u.Scheme)
+				require.Equal(t, rsp.Secret, u.Query().Get("secret"))
+				require.Equal(t, "/Simple Bank:"+user.Username, u.Path)
+			},
+		},
+		{
+			name: "already enabled",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, stored *[]byte) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enabled, nil)
+				store.EXPECT().UpsertTotpSecret(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, stored []byte) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeTOTPAlreadyEnabled)
+			},
+		},
+		{
+			name: "enabled since it was read",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, stored *[]byte) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.TotpSecret{}, sql.ErrNoRows)
+				store.EXPECT().UpsertTotpSecret(gomock.Any(), gomock.Any()).Times(1).Return(db.TotpSecret{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, stored []byte) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeTOTPAlreadyEnabled)
+			},
+		},
+		{
+			name: "no authorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore, stored *[]byte) {
+				store.EXPECT().UpsertTotpSecret(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, stored []byte) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			var stored []byte
+			tc.buildStubs(store, &stored)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+			server.config.TOTPEncryptionKey = key
+
+			request, err := http.NewRequest(http.MethodPost, "/users/2fa/enroll", nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, stored)
+		})
+	}
+}
+
+func TestVerifyTOTPAPI(t *testing.T) {
+	user, _ := randomUser()
+	key := utils.RandomString(utils.TOTPEncryptionKeySize)
+	secret, pending := randomTOTPSecret(t, key, user.Username, false)
+	_, enabled := randomTOTPSecret(t, key, user.Username, true)
+
+	code, err := utils.TOTPCode(secret, time.Now())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path generated code enables it",
+			body: gin.H{"code": code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(pending, nil)
+				store.EXPECT().EnableTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enabled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp verifyTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, rsp.Enabled)
+				require.WithinDuration(t, enabled.EnabledAt.Time, rsp.EnabledAt, time.Second)
+			},
+		},
+		{
+			name: "wrong code is rejected",
+			body: gin.H{"code": wrongTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(pending, nil)
+				store.EXPECT().EnableTotpSecret(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidTOTPCode)
+			},
+		},
+		{
+			name: "malformed code",
+			body: gin.H{"code": "12ab"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "not enrolled",
+			body: gin.H{"code": code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.TotpSecret{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeTOTPNotEnrolled)
+			},
+		},
+		{
+			name: "already enabled",
+			body: gin.H{"code": code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enabled, nil)
+				store.EXPECT().EnableTotpSecret(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeTOTPAlreadyEnabled)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+			server.config.TOTPEncryptionKey = key
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/2fa/verify", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.token, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserTOTPAPI(t *testing.T) {
+	user, password := randomUser()
+	key := utils.RandomString(utils.TOTPEncryptionKeySize)
+	secret, enabled := randomTOTPSecret(t, key, user.Username, true)
+	_, pending := randomTOTPSecret(t, key, user.Username, false)
+
+	code, err := utils.TOTPCode(secret, time.Now())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		maxAttempts   int32
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path valid code",
+			body: gin.H{"username": user.Username, "password": password, "totp_code": code},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enabled, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "missing code is challenged",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enabled, nil)
+				// asking for the code isn't a failed login
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			maxAttempts: 3,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireErrorCode(t, recorder, codeTOTPRequired)
+			},
+		},
+		{
+			name: "wrong code is rejected",
+			body: gin.H{"username": user.Username, "password": password, "totp_code": wrongTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enabled, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidTOTPCode)
+			},
+		},
+		{
+			name: "wrong code counts towards the lockout",
+			body: gin.H{"username": user.Username, "password": password, "totp_code": wrongTOTPCode(t, secret)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enabled, nil)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			maxAttempts: 3,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidTOTPCode)
+			},
+		},
+		{
+			name: "wrong password isn't challenged",
+			body: gin.H{"username": user.Username, "password": "wrong_password"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidCredentials)
+			},
+		},
+		{
+			name: "secret not verified yet",
+			body: gin.H{"username": user.Username, "password": password},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(pending, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+			server.config.TOTPEncryptionKey = key
+			server.config.LoginMaxAttempts = tc.maxAttempts
+			server.config.LoginLockoutDuration = time.Minute
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// tracePropagator reads the W3C traceparent and tracestate headers, so the request span joins the caller trace
+var tracePropagator = propagation.TraceContext{}
+
+// tracingMiddleware starts the root span of every request, continuing the trace found in the incoming headers
+// The span is stored in the request context, so the store spans started by the handlers become its children
+func tracingMiddleware(tracer trace.Tracer) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		parent := tracePropagator.Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+
+		// the route template keeps the span names bounded, unknown routes share a single name
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unknown route"
+		}
+
+		spanCtx, span := tracer.Start(parent, fmt.Sprintf("%s %s", ctx.Request.Method, route),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.method", ctx.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("request_id", utils.RequestIDFromContext(ctx)),
+			),
+		)
+		defer span.End()
+
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+		ctx.Next()
+
+		statusCode := ctx.Writer.Status()
+		span.SetAttributes(attribute.Int("http.status_code", statusCode))
+		if statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(statusCode))
+		}
+	}
+}
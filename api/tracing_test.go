@@ -0,0 +1,173 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+// newTracingTestServer installs a tracer provider recording every span in memory and builds a server over the traced store
+func newTracingTestServer(t *testing.T, store *mockdb.MockStore) (*Server, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	store.EXPECT().GetUserPasswordChangedAt(gomock.Any(), gomock.Any()).AnyTimes().Return(time.Time{}, nil)
+	server := newTestServer(t, db.NewTracedStore(store, provider.Tracer(utils.TracerName)))
+
+	return server, exporter
+}
+
+func TestTracingTransferSpans(t *testing.T) {
+	user, _ := randomUser()
+	otherUser, _ := randomUser()
+	fromAccount := randomAccount(user.Username)
+	toAccount := randomAccount(otherUser.Username)
+	toAccount.Currency = fromAccount.Currency
+
+	body := gin.H{
+		"from_account_number": fromAccount.AccountNumber,
+		"to_account_number":   toAccount.AccountNumber,
+		"amount":              _amount,
+		"currency":            fromAccount.Currency,
+	}
+
+	// the trace id and parent span id the caller sends in the traceparent header
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentSpanID := "00f067aa0ba902b7"
+
+	testCases := []struct {
+		name          string
+		setupHeaders  func(request *http.Request)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, spans tracetest.SpanStubs)
+	}{
+		{
+			name:         "happy path transfer spans",
+			setupHeaders: func(request *http.Request) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq([]string{fromAccount.AccountNumber, toAccount.AccountNumber})).Times(1).
+					Return(map[string]db.Account{fromAccount.AccountNumber: fromAccount, toAccount.AccountNumber: toAccount}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, spans tracetest.SpanStubs) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				root := findSpan(t, spans, "POST /transfers")
+				require.False(t, root.Parent.IsValid())
+
+				children := map[string]int{}
+				for _, span := range spans {
+					if span.Name == root.Name {
+						continue
+					}
+					require.Equal(t, root.SpanContext.TraceID(), span.SpanContext.TraceID())
+					require.Equal(t, root.SpanContext.SpanID(), span.Parent.SpanID())
+					children[span.Name]++
+				}
+				require.Equal(t, 1, children["db.LookupAccountsByNumber"])
+				require.Equal(t, 1, children["db.TransferTx"])
+			},
+		},
+		{
+			name: "incoming trace context",
+			setupHeaders: func(request *http.Request) {
+				request.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq([]string{fromAccount.AccountNumber, toAccount.AccountNumber})).Times(1).
+					Return(map[string]db.Account{fromAccount.AccountNumber: fromAccount, toAccount.AccountNumber: toAccount}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, spans tracetest.SpanStubs) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				root := findSpan(t, spans, "POST /transfers")
+				require.True(t, root.Parent.IsRemote())
+				require.Equal(t, traceID, root.SpanContext.TraceID().String())
+				require.Equal(t, parentSpanID, root.Parent.SpanID().String())
+
+				transferSpan := findSpan(t, spans, "db.TransferTx")
+				require.Equal(t, traceID, transferSpan.SpanContext.TraceID().String())
+			},
+		},
+		{
+			name:         "transfer error",
+			setupHeaders: func(request *http.Request) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq([]string{fromAccount.AccountNumber, toAccount.AccountNumber})).Times(1).
+					Return(map[string]db.Account{fromAccount.AccountNumber: fromAccount, toAccount.AccountNumber: toAccount}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, spans tracetest.SpanStubs) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+
+				transferSpan := findSpan(t, spans, "db.TransferTx")
+				require.Equal(t, codes.Error, transferSpan.Status.Code)
+				require.Equal(t, sql.ErrTxDone.Error(), transferSpan.Status.Description)
+
+				root := findSpan(t, spans, "POST /transfers")
+				require.Equal(t, codes.Error, root.Status.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server, exporter := newTracingTestServer(t, store)
+
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+			tc.setupHeaders(request)
+
+			addAuthorization(t, request, server.token, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, exporter.GetSpans())
+		})
+	}
+}
+
+func findSpan(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+
+	require.FailNow(t, "span not found", name)
+	return tracetest.SpanStub{}
+}
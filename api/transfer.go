@@ -1,78 +1,535 @@
 package api
 
 import (
-	"database/sql"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	db "github.com/micaelapucciariello/simplebank/db/sqlc"
 	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
 	"net/http"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
 )
 
 type (
 	createTransferReq struct {
-		FromAccountID int64  `json:"from_account_id" binding:"required"`
-		ToAccountID   int64  `json:"to_account_id" binding:"required"`
-		Amount        int64  `json:"amount" binding:"required,min=1"`
-		Currency      string `json:"currency" binding:"required,currency"`
+		FromAccountNumber string      `json:"from_account_number" binding:"required,len=16,numeric"`
+		ToAccountNumber   string      `json:"to_account_number" binding:"required,len=16,numeric"`
+		Amount            utils.Money `json:"amount" binding:"required,min=1"`
+		Currency          string      `json:"currency" binding:"required,currency"`
+		Description       string      `json:"description" binding:"omitempty,max=140"`
+	}
+
+	createTransferQueryReq struct {
+		// DryRun checks the transfer and previews the resulting balances, nothing is transferred
+		DryRun bool `form:"dry_run"`
+	}
+
+	transferPreviewResponse struct {
+		DryRun      bool            `json:"dry_run"`
+		Amount      int64           `json:"amount"`
+		ToAmount    int64           `json:"to_amount"`
+		FromAccount accountResponse `json:"from_account"`
+		ToAccount   accountResponse `json:"to_account"`
+		format      amountFormat
+	}
+
+	splitTransferReq struct {
+		FromAccountNumber string                  `json:"from_account_number" binding:"required,len=16,numeric"`
+		Amount            utils.Money             `json:"amount" binding:"required,min=1"`
+		Currency          string                  `json:"currency" binding:"required,currency"`
+		Splits            []splitTransferSplitReq `json:"splits" binding:"required,min=1,max=10,dive"`
+	}
+
+	splitTransferSplitReq struct {
+		ToAccountNumber string      `json:"to_account_number" binding:"required,len=16,numeric"`
+		Amount          utils.Money `json:"amount" binding:"required,min=1"`
+	}
+
+	listTransfersReq struct {
+		AccountNumber string `form:"account_number" binding:"required,len=16,numeric"`
+	}
+
+	reverseTransferReq struct {
+		ID string `uri:"id" binding:"required,uuid"`
+	}
+
+	// transferResponse identifies the transfer by its uuid and the accounts by their number, the sequential internal ids
+	// are never sent to clients. A reversal only tells it is one, the transfer it reverses is returned by the reverse request
+	transferResponse struct {
+		ID                uuid.UUID  `json:"id"`
+		FromAccountNumber string     `json:"from_account_number"`
+		ToAccountNumber   string     `json:"to_account_number"`
+		Amount            int64      `json:"amount"`
+		ToAmount          int64      `json:"to_amount"`
+		Description       *string    `json:"description"`
+		IsReversal        bool       `json:"is_reversal"`
+		ReversedAt        *time.Time `json:"reversed_at"`
+		CreatedAt         time.Time  `json:"created_at"`
+		format            amountFormat
+	}
+
+	transferTxResponse struct {
+		Transfer      transferResponse `json:"transfer"`
+		FromAccountID accountResponse  `json:"from_account_id"`
+		ToAccountID   accountResponse  `json:"to_account_id"`
+		FromEntry     entryResponse    `json:"from_entry"`
+		ToEntry       entryResponse    `json:"to_entry"`
+	}
+
+	splitTransferResponse struct {
+		FromAccount accountResponse      `json:"from_account"`
+		Transfers   []transferTxResponse `json:"transfers"`
+	}
+
+	reverseTransferResponse struct {
+		transferTxResponse
+		OriginalTransfer transferResponse `json:"original_transfer"`
 	}
 )
 
+// accountNumbers maps the internal ids of the accounts to their numbers, the responses only ever carry the numbers
+type accountNumbers map[int64]string
+
+func newAccountNumbers(accounts ...db.Account) accountNumbers {
+	numbers := make(accountNumbers, len(accounts))
+	for _, account := range accounts {
+		numbers[account.ID] = account.AccountNumber
+	}
+	return numbers
+}
+
+// lookupAccountNumbers returns the numbers of the accounts of ids, reading only the ones not among known in a single
+// store call. The deleted accounts are read too since the transfers keep pointing at them. It writes the error response itself
+func (s *Server) lookupAccountNumbers(ctx *gin.Context, ids []int64, known ...db.Account) (accountNumbers, bool) {
+	numbers := newAccountNumbers(known...)
+	var missing []int64
+	for _, id := range ids {
+		if _, ok := numbers[id]; !ok {
+			numbers[id] = ""
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return numbers, true
+	}
+
+	rows, err := s.store.GetAccountNumbers(ctx, missing)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return nil, false
+	}
+	for _, row := range rows {
+		numbers[row.ID] = row.AccountNumber
+	}
+	return numbers, true
+}
+
+func newTransferResponse(transfer db.Transfer, numbers accountNumbers, format amountFormat) transferResponse {
+	rsp := transferResponse{
+		ID:                transfer.UUID,
+		FromAccountNumber: numbers[transfer.FromAccountID],
+		ToAccountNumber:   numbers[transfer.ToAccountID],
+		Amount:            transfer.Amount,
+		ToAmount:          transfer.ToAmount,
+		IsReversal:        transfer.ReversedFrom.Valid,
+		CreatedAt:         transfer.CreatedAt.Time,
+		format:            format,
+	}
+	if transfer.Description.Valid {
+		rsp.Description = &transfer.Description.String
+	}
+	if transfer.ReversedAt.Valid {
+		rsp.ReversedAt = &transfer.ReversedAt.Time
+	}
+	return rsp
+}
+
+func newTransferTxResponse(result db.TransferTxResult, format amountFormat) transferTxResponse {
+	return transferTxResponse{
+		Transfer:      newTransferResponse(result.Transfer, newAccountNumbers(result.FromAccountID, result.ToAccountID), format),
+		FromAccountID: newAccountResponse(result.FromAccountID, format),
+		ToAccountID:   newAccountResponse(result.ToAccountID, format),
+		FromEntry:     entryResponse{Entry: result.FromEntry, format: format},
+		ToEntry:       entryResponse{Entry: result.ToEntry, format: format},
+	}
+}
+
 func (s *Server) createTranfer(ctx *gin.Context) {
 	var req createTransferReq
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+	if !bindJSON(ctx, &req) {
 		return
 	}
 
-	account, isValidFromAccount := s.validAccountCurrency(ctx, req.FromAccountID, req.Currency)
-	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
-	if account.Owner != authPayload.UserName {
-		ctx.JSON(http.StatusUnauthorized, fmt.Errorf("from account doesn't belong to the authenticated user"))
+	var query createTransferQueryReq
+	if !bindQuery(ctx, &query) {
+		return
 	}
 
-	_, isValidToAccount := s.validAccountCurrency(ctx, req.ToAccountID, req.Currency)
+	if err := s.checkTransferAmount(int64(req.Amount)); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeAmountOutOfRange, err))
+		return
+	}
 
-	if !isValidToAccount || !isValidFromAccount {
+	// the currency is the from account one, the store converts the amount credited to a to account of another currency
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	fromAccount, toAccount, ok := s.validTransferAccounts(ctx, req.FromAccountNumber, req.ToAccountNumber, req.Currency, authPayload.UserName, true)
+	if !ok {
 		return
 	}
+
 	arg := db.TransferTxParams{
-		FromAccountID: req.FromAccountID,
-		ToAccountID:   req.ToAccountID,
-		Amount:        req.Amount,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        int64(req.Amount),
+		DailyLimit:    s.config.DailyTransferLimit,
+		Description:   req.Description,
+		DryRun:        query.DryRun,
+	}
+
+	// a dry run is never replayed, its idempotency key stays free for the real transfer
+	idempotencyKey := ctx.GetHeader(idempotencyKeyHeader)
+	if idempotencyKey == "" || query.DryRun {
+		transfer, err := s.store.TransferTx(ctx, arg)
+		if err != nil {
+			respondTransferError(ctx, err, codeAccountNotFound)
+			return
+		}
+		if query.DryRun {
+			format := requestAmountFormat(ctx)
+			ctx.JSON(http.StatusOK, transferPreviewResponse{
+				DryRun:      true,
+				Amount:      transfer.Transfer.Amount,
+				ToAmount:    transfer.Transfer.ToAmount,
+				FromAccount: newAccountResponse(transfer.FromAccountID, format),
+				ToAccount:   newAccountResponse(transfer.ToAccountID, format),
+				format:      format,
+			})
+			return
+		}
+		ctx.JSON(http.StatusOK, newTransferTxResponse(transfer, requestAmountFormat(ctx)))
+		return
 	}
 
-	transfer, err := s.store.TransferTx(ctx, arg)
+	requestHash, err := hashRequest(req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
 		return
-	} else {
-		ctx.JSON(http.StatusOK, transfer)
+	}
+
+	result, err := s.store.IdempotentTransferTx(ctx, db.IdempotentTransferTxParams{
+		TransferTxParams: arg,
+		Username:         authPayload.UserName,
+		IdempotencyKey:   idempotencyKey,
+		RequestHash:      requestHash,
+	})
+	if err != nil {
+		respondTransferError(ctx, err, codeAccountNotFound)
 		return
 	}
+
+	if result.Replayed {
+		ctx.Header(idempotencyReplayedHeader, "true")
+	}
+	ctx.JSON(http.StatusOK, newTransferTxResponse(result.TransferTxResult, requestAmountFormat(ctx)))
 }
 
-func (s *Server) validAccountCurrency(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
-	account, err := s.store.GetAccount(ctx, accountID)
+// createSplitTransfer sends one amount from the authenticated user account to several receivers, all of the splits or none of them are transferred
+func (s *Server) createSplitTransfer(ctx *gin.Context) {
+	var req splitTransferReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	if err := s.checkTransferAmount(int64(req.Amount)); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeAmountOutOfRange, err))
+		return
+	}
+
+	// every account of the split is resolved from its number in a single store call
+	numbers := make([]string, 0, len(req.Splits)+1)
+	numbers = append(numbers, req.FromAccountNumber)
+	for _, split := range req.Splits {
+		numbers = append(numbers, split.ToAccountNumber)
+	}
+	accounts, ok := s.lookupAccountsByNumber(ctx, numbers)
+	if !ok {
+		return
+	}
+
+	fromAccount := accounts[req.FromAccountNumber]
+	if !checkTransferAccount(ctx, fromAccount, req.Currency) {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.UserName {
+		err := fmt.Errorf("from account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	splits := make([]db.TransferSplit, len(req.Splits))
+	for i, split := range req.Splits {
+		splits[i] = db.TransferSplit{ToAccountID: accounts[split.ToAccountNumber].ID, Amount: int64(split.Amount)}
+	}
+
+	// the receivers currency and frozen state are checked by the store once their rows are locked
+	result, err := s.store.SplitTransferTx(ctx, db.SplitTransferTxParams{
+		FromAccountID: fromAccount.ID,
+		Amount:        int64(req.Amount),
+		Splits:        splits,
+		DailyLimit:    s.config.DailyTransferLimit,
+	})
 	if err != nil {
-		if err != nil {
-			if err == sql.ErrNoRows {
-				ctx.JSON(http.StatusNotFound, errResponse(err))
-				return account, false
-			}
-
-			ctx.JSON(http.StatusInternalServerError, errResponse(err))
-			return account, false
-		} else {
-			ctx.JSON(http.StatusOK, account)
+		respondTransferError(ctx, err, codeAccountNotFound)
+		return
+	}
+
+	format := requestAmountFormat(ctx)
+	rsp := splitTransferResponse{
+		FromAccount: newAccountResponse(result.FromAccount, format),
+		Transfers:   make([]transferTxResponse, len(result.Transfers)),
+	}
+	for i, transfer := range result.Transfers {
+		rsp.Transfers[i] = newTransferTxResponse(transfer, format)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// checkTransferAmount rejects the amounts outside of the configured bounds, naming the one that was violated
+func (s *Server) checkTransferAmount(amount int64) error {
+	if amount < s.config.MinTransferAmount {
+		return fmt.Errorf("amount %v is below the minimum transfer amount %v", amount, s.config.MinTransferAmount)
+	}
+	if s.config.MaxTransferAmount > 0 && amount > s.config.MaxTransferAmount {
+		return fmt.Errorf("amount %v is above the maximum transfer amount %v", amount, s.config.MaxTransferAmount)
+	}
+	return nil
+}
+
+// hashRequest returns the hex encoded sha256 of the request body, used to detect an idempotency key reused with a different payload
+func hashRequest(req interface{}) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// validAccount checks that the account of the number exists, isn't frozen and that its currency matches the transfer one
+// It writes the error response itself, so callers only need to return when the account isn't valid
+func (s *Server) validAccount(ctx *gin.Context, accountNumber string, currency string) (db.Account, bool) {
+	account, err := s.store.GetAccountByNumber(ctx, accountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return account, false
+	}
+
+	return account, checkTransferAccount(ctx, account, currency)
+}
+
+// validTransferAccounts reads both accounts of a transfer by their number in a single store call and checks them like validAccount,
+// the from account must also belong to owner. With convertible set the to account may hold any currency. It writes the error response itself
+func (s *Server) validTransferAccounts(ctx *gin.Context, fromAccountNumber, toAccountNumber, currency, owner string, convertible bool) (db.Account, db.Account, bool) {
+	accounts, err := s.store.LookupAccountsByNumber(ctx, []string{fromAccountNumber, toAccountNumber})
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return db.Account{}, db.Account{}, false
+	}
+
+	fromAccount, ok := accounts[fromAccountNumber]
+	if !ok {
+		ctx.JSON(http.StatusNotFound, errorResponse(codeAccountNotFound, fmt.Errorf("account [%v] not found", fromAccountNumber)))
+		return db.Account{}, db.Account{}, false
+	}
+	if !checkTransferAccount(ctx, fromAccount, currency) {
+		return db.Account{}, db.Account{}, false
+	}
+
+	if fromAccount.Owner != owner {
+		err := fmt.Errorf("from account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeNotAccountOwner, err))
+		return db.Account{}, db.Account{}, false
+	}
+
+	toAccount, ok := accounts[toAccountNumber]
+	if !ok {
+		ctx.JSON(http.StatusNotFound, errorResponse(codeAccountNotFound, fmt.Errorf("account [%v] not found", toAccountNumber)))
+		return db.Account{}, db.Account{}, false
+	}
+	if convertible {
+		currency = toAccount.Currency
+	}
+	return fromAccount, toAccount, checkTransferAccount(ctx, toAccount, currency)
+}
+
+// lookupAccountsByNumber reads the accounts of numbers in a single store call, keyed by number. Any number not found
+// is answered with a not found. It writes the error response itself
+func (s *Server) lookupAccountsByNumber(ctx *gin.Context, numbers []string) (map[string]db.Account, bool) {
+	accounts, err := s.store.LookupAccountsByNumber(ctx, numbers)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return nil, false
+	}
+
+	for _, number := range numbers {
+		if _, ok := accounts[number]; !ok {
+			ctx.JSON(http.StatusNotFound, errorResponse(codeAccountNotFound, fmt.Errorf("account [%v] not found", number)))
+			return nil, false
 		}
 	}
+	return accounts, true
+}
+
+// checkTransferAccount checks that the account belongs to the caller organization, isn't frozen and that its currency matches the transfer one,
+// writing the error response
+func checkTransferAccount(ctx *gin.Context, account db.Account, currency string) bool {
+	if !checkSameOrg(ctx, account.OrgID) {
+		return false
+	}
 
 	if account.Currency != currency {
-		err := fmt.Errorf("account [%v] currency mismatched: account currency %v - transfer currency %v", account.ID, account.Currency, currency)
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
-		return account, false
+		err := fmt.Errorf("account [%v] currency mismatched: account currency %v - transfer currency %v", account.AccountNumber, account.Currency, currency)
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeCurrencyMismatch, err))
+		return false
+	}
+
+	if account.IsFrozen {
+		err := fmt.Errorf("%w: account [%v] can't send nor receive transfers", db.ErrAccountFrozen, account.AccountNumber)
+		ctx.JSON(http.StatusForbidden, errorResponse(codeAccountFrozen, err))
+		return false
+	}
+
+	return true
+}
+
+// listTransfers executes a paginated query over the transfers sent or received by an account
+func (s *Server) listTransfers(ctx *gin.Context) {
+	var req listTransfersReq
+	if !bindQuery(ctx, &req) {
+		return
+	}
+
+	page, ok := parsePagination(ctx)
+	if !ok {
+		return
+	}
+
+	account, err := s.store.GetAccountByNumber(ctx, req.AccountNumber)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, account.OrgID) {
+		return
+	}
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != account.Owner {
+		err = fmt.Errorf("account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeNotAccountOwner, err))
+		return
+	}
+
+	params := db.ListTransfersParams{
+		FromAccountID: account.ID,
+		ToAccountID:   account.ID,
+		Limit:         page.PageSize,
+		Offset:        page.offset(),
+	}
+
+	transfers, err := s.store.ListTransfers(ctx, params)
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	total, err := s.store.CountTransfers(ctx, db.CountTransfersParams{
+		FromAccountID: account.ID,
+		ToAccountID:   account.ID,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeNotFound)
+		return
+	}
+
+	// the account is on one side of every transfer, only the other sides are read
+	ids := make([]int64, 0, 2*len(transfers))
+	for _, transfer := range transfers {
+		ids = append(ids, transfer.FromAccountID, transfer.ToAccountID)
+	}
+	numbers, ok := s.lookupAccountNumbers(ctx, ids, account)
+	if !ok {
+		return
+	}
+
+	format := requestAmountFormat(ctx)
+	rsp := make([]transferResponse, len(transfers))
+	for i, transfer := range transfers {
+		rsp[i] = newTransferResponse(transfer, numbers, format)
+	}
+	ctx.JSON(http.StatusOK, newListResponse(rsp, page.PageID, page.PageSize, total))
+}
+
+// reverseTransfer moves the funds of a transfer back to the sender with a compensating transfer
+// Only the sender of the original transfer or a banker can reverse it, and a transfer can be reversed only once
+func (s *Server) reverseTransfer(ctx *gin.Context) {
+	var req reverseTransferReq
+	if !bindURI(ctx, &req) {
+		return
+	}
+
+	// the binding already checked it's a uuid
+	transfer, err := s.store.GetTransferByUUID(ctx, uuid.MustParse(req.ID))
+	if err != nil {
+		respondDBError(ctx, err, codeTransferNotFound)
+		return
+	}
+
+	// a sender that closed its account since still owns the transfer, the store rejects the reversal until the account is restored
+	fromAccount, err := s.store.GetAccountIncludingDeleted(ctx, transfer.FromAccountID)
+	if err != nil {
+		respondDBError(ctx, err, codeAccountNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, fromAccount.OrgID) {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != fromAccount.Owner && authPayload.Role != utils.BankerRole {
+		err = fmt.Errorf("transfer wasn't sent by the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeForbidden, err))
+		return
+	}
+
+	result, err := s.store.ReverseTransferTx(ctx, db.ReverseTransferTxParams{TransferID: transfer.ID})
+	if err != nil {
+		// the receiver already spent the money it got, the transfer can't be undone until it is funded again
+		if errors.Is(err, db.ErrInsufficientBalance) {
+			ctx.JSON(http.StatusConflict, errorResponse(codeInsufficientBalance, err))
+			return
+		}
+		respondTransferError(ctx, err, codeTransferNotFound)
+		return
 	}
 
-	return account, true
+	// the reversal goes between the accounts of the original transfer, the other way around
+	format := requestAmountFormat(ctx)
+	numbers := newAccountNumbers(result.FromAccountID, result.ToAccountID)
+	ctx.JSON(http.StatusOK, reverseTransferResponse{
+		transferTxResponse: newTransferTxResponse(result.TransferTxResult, format),
+		OriginalTransfer:   newTransferResponse(result.OriginalTransfer, numbers, format),
+	})
 }
@@ -7,12 +7,15 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/micaelapucciariello/simplebank/token"
 	"github.com/micaelapucciariello/simplebank/utils"
 	"github.com/stretchr/testify/require"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -37,6 +40,7 @@ func TestCreateTransferAPI(t *testing.T) {
 	transfer := db.TransferTxResult{
 		Transfer: db.Transfer{
 			ID:            utils.RandomInt(1, 1000),
+			UUID:          uuid.New(),
 			FromAccountID: account1.ID,
 			ToAccountID:   account2.ID,
 			Amount:        _amount,
@@ -55,6 +59,29 @@ func TestCreateTransferAPI(t *testing.T) {
 		},
 	}
 
+	// 112 USD at a 0.92 rate credits 103 EUR
+	accountEUR := randomAccount(user2.Username)
+	accountEUR.Currency = utils.EUR
+	convertedTransfer := db.TransferTxResult{
+		Transfer: db.Transfer{
+			ID:            utils.RandomInt(1, 1000),
+			UUID:          uuid.New(),
+			FromAccountID: account1.ID,
+			ToAccountID:   accountEUR.ID,
+			Amount:        _amount,
+			ToAmount:      103,
+		},
+		FromAccountID: account1,
+		ToAccountID:   accountEUR,
+		FromEntry:     db.Entry{Amount: -_amount, AccountID: account1.ID},
+		ToEntry:       db.Entry{Amount: 103, AccountID: accountEUR.ID},
+	}
+
+	frozenAccount1 := account1
+	frozenAccount1.IsFrozen = true
+	frozenAccount2 := account2
+	frozenAccount2.IsFrozen = true
+
 	testCases := []struct {
 		name          string
 		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
@@ -65,22 +92,51 @@ func TestCreateTransferAPI(t *testing.T) {
 		{
 			name: "happy path create transfer",
 			body: gin.H{
-				"from_account_id": account1.ID,
-				"to_account_id":   account2.ID,
-				"amount":          _amount,
-				"currency":        utils.USD,
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        _amount,
+				}
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), arg).Times(1).
+					Return(transfer, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseTransfer(t, recorder.Body, transfer)
+			},
+		},
+		{
+			name: "happy path decimal amount",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              utils.Money(_amount).String(),
+				"currency":            utils.USD,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				// "1.12" is sent as 112 cents
 				arg := db.TransferTxParams{
 					FromAccountID: account1.ID,
 					ToAccountID:   account2.ID,
 					Amount:        _amount,
 				}
-				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
-				store.EXPECT().GetAccount(gomock.Any(), account2.ID).Times(1).Return(account2, nil)
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
 				store.EXPECT().TransferTx(gomock.Any(), arg).Times(1).
 					Return(transfer, nil)
 			},
@@ -90,22 +146,192 @@ func TestCreateTransferAPI(t *testing.T) {
 				validateResponseTransfer(t, recorder.Body, transfer)
 			},
 		},
+		{
+			name: "error: amount with three decimals",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              "1.125",
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+				require.Contains(t, recorder.Body.String(), "decimal places")
+			},
+		},
+		{
+			name: "with description",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+				"description":         "rent",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        _amount,
+					Description:   "rent",
+				}
+				described := transfer
+				described.Transfer.Description = sql.NullString{String: "rent", Valid: true}
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), arg).Times(1).
+					Return(described, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var got transferTxResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.NotNil(t, got.Transfer.Description)
+				require.Equal(t, "rent", *got.Transfer.Description)
+			},
+		},
+		{
+			name: "description too long",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+				"description":         strings.Repeat("a", 141),
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "from account frozen",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: frozenAccount1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountFrozen)
+				require.Contains(t, recorder.Body.String(), db.ErrAccountFrozen.Error())
+			},
+		},
+		{
+			name: "to account frozen",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: frozenAccount2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				require.Contains(t, recorder.Body.String(), db.ErrAccountFrozen.Error())
+			},
+		},
+		{
+			name: "to account of another organization",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				otherOrgAccount2 := account2
+				otherOrgAccount2.OrgID = db.DefaultOrgID + 1
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: otherOrgAccount2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeOtherOrganization)
+			},
+		},
+		{
+			name: "account frozen while transferring",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferTxResult{}, db.ErrAccountFrozen)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
 		{
 			name: "internal server error",
 			body: gin.H{
-				"from_account_id": account1.ID,
-				"to_account_id":   account2.ID,
-				"amount":          _amount,
-				"currency":        utils.USD,
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
-				store.EXPECT().GetAccount(gomock.Any(), account2.ID).Times(1).Return(account2, nil)
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
 				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).
-					Return(db.TransferTxResult{}, sql.ErrConnDone)
+					Return(db.TransferTxResult{}, sql.ErrTxDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				// check response
@@ -113,21 +339,43 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "invalid username",
+			name: "serialization failure",
 			body: gin.H{
-				"from_account_id": account1.ID,
-				"to_account_id":   account2.ID,
-				"amount":          _amount,
-				"currency":        utils.USD,
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "unauthorized token", time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
-				store.EXPECT().GetAccount(gomock.Any(), account2.ID).Times(1).Return(account2, nil)
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
 				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).
-					Return(db.TransferTxResult{}, nil)
+					Return(db.TransferTxResult{}, &pq.Error{Code: "40001"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				requireErrorCode(t, recorder, codeUnavailable)
+			},
+		},
+		{
+			name: "invalid username",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "unauthorized token", utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				// check response
@@ -137,15 +385,14 @@ func TestCreateTransferAPI(t *testing.T) {
 		{
 			name: "no authorization",
 			body: gin.H{
-				"from_account_id": account1.ID,
-				"to_account_id":   account2.ID,
-				"amount":          _amount,
-				"currency":        utils.USD,
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
 			buildStubs: func(store *mockdb.MockStore) {
-				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(0).Return(account1, nil)
-				store.EXPECT().GetAccount(gomock.Any(), account2.ID).Times(0).Return(account2, nil)
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Any()).Times(0)
 				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0).
 					Return(db.TransferTxResult{}, nil)
 			},
@@ -155,15 +402,70 @@ func TestCreateTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "error: mismatched currency",
+			name: "unsupported currency",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            "XYZ",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				rsp := requireErrorCode(t, recorder, codeInvalidRequest)
+				require.Equal(t, "currency", rsp.Details[0].Field)
+			},
+		},
+		{
+			name: "happy path cross currency transfer",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   accountEUR.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   accountEUR.ID,
+					Amount:        _amount,
+				}
+
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, accountEUR.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, accountEUR.AccountNumber: accountEUR}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), arg).Times(1).Return(convertedTransfer, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp transferTxResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, int64(_amount), rsp.Transfer.Amount)
+				require.Equal(t, convertedTransfer.Transfer.ToAmount, rsp.Transfer.ToAmount)
+				require.Equal(t, convertedTransfer.Transfer.ToAmount, rsp.ToEntry.Amount)
+			},
+		},
+		{
+			name: "error: no exchange rate",
 			body: gin.H{
-				"from_account_id": accountARS.ID,
-				"to_account_id":   account1.ID,
-				"amount":          _amount,
-				"currency":        utils.ARS,
+				"from_account_number": accountARS.AccountNumber,
+				"to_account_number":   account1.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.ARS,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, userARS.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, userARS.Username, utils.DepositorRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				arg := db.TransferTxParams{
@@ -172,16 +474,193 @@ func TestCreateTransferAPI(t *testing.T) {
 					Amount:        _amount,
 				}
 
-				store.EXPECT().GetAccount(gomock.Any(), accountARS.ID).Times(1).Return(accountARS, nil)
-				store.EXPECT().GetAccount(gomock.Any(), account1.ID).Times(1).Return(account1, nil)
-				store.EXPECT().TransferTx(gomock.Any(), arg).Times(0).
-					Return(db.TransferTxResult{}, sql.ErrConnDone)
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{accountARS.AccountNumber, account1.AccountNumber}).Times(1).
+					Return(map[string]db.Account{accountARS.AccountNumber: accountARS, account1.AccountNumber: account1}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), arg).Times(1).
+					Return(db.TransferTxResult{}, fmt.Errorf("%w: ARS to USD", db.ErrNoExchangeRate))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireErrorCode(t, recorder, codeNoExchangeRate)
+			},
+		},
+		{
+			name: "error: mismatched from account currency",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.EUR,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				// check response
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
+		{
+			name: "error: from account not found",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "error: to account not found",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "idempotency key first request",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+				request.Header.Set(idempotencyKeyHeader, "key-1")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, params db.IdempotentTransferTxParams) (db.IdempotentTransferTxResult, error) {
+						require.Equal(t, user1.Username, params.Username)
+						require.Equal(t, "key-1", params.IdempotencyKey)
+						require.NotEmpty(t, params.RequestHash)
+						require.Equal(t, account1.ID, params.FromAccountID)
+						require.Equal(t, account2.ID, params.ToAccountID)
+						require.Equal(t, int64(_amount), params.Amount)
+						return db.IdempotentTransferTxResult{TransferTxResult: transfer}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, recorder.Header().Get(idempotencyReplayedHeader))
+				validateResponseTransfer(t, recorder.Body, transfer)
+			},
+		},
+		{
+			name: "idempotency key replayed",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+				request.Header.Set(idempotencyKeyHeader, "key-1")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotentTransferTxResult{TransferTxResult: transfer, Replayed: true}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "true", recorder.Header().Get(idempotencyReplayedHeader))
+				validateResponseTransfer(t, recorder.Body, transfer)
+			},
+		},
+		{
+			name: "idempotency key reused with a different payload",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+				request.Header.Set(idempotencyKeyHeader, "key-1")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotentTransferTxResult{}, db.ErrIdempotencyKeyMismatch)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "idempotency key internal error",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"to_account_number":   account2.AccountNumber,
+				"amount":              _amount,
+				"currency":            utils.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+				request.Header.Set(idempotencyKeyHeader, "key-1")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().
+					IdempotentTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.IdempotentTransferTxResult{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
 	}
 
 	for i := range testCases {
@@ -211,12 +690,979 @@ func TestCreateTransferAPI(t *testing.T) {
 	}
 }
 
-func validateResponseTransfer(t *testing.T, body *bytes.Buffer, trxr db.TransferTxResult) {
-	data, err := ioutil.ReadAll(body)
-	require.NoError(t, err)
+func TestCreateTransferLimitsAPI(t *testing.T) {
+	const (
+		minAmount  = 10
+		maxAmount  = 1000
+		dailyLimit = 5000
+	)
 
-	var rspTransfer db.TransferTxResult
-	err = json.Unmarshal(data, &rspTransfer)
-	require.NoError(t, err)
-	require.Equal(t, trxr, rspTransfer)
+	transferBody := func(amount int64) gin.H {
+		return gin.H{
+			"from_account_number": account1.AccountNumber,
+			"to_account_number":   account2.AccountNumber,
+			"amount":              amount,
+			"currency":            utils.USD,
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		body           gin.H
+		idempotencyKey string
+		buildStubs     func(store *mockdb.MockStore)
+		checkResponse  func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "amounts at the bounds",
+			body: transferBody(maxAmount),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				arg := db.TransferTxParams{
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        maxAmount,
+					DailyLimit:    dailyLimit,
+				}
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TransferTxResult{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "below minimum amount",
+			body: transferBody(minAmount - 1),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), fmt.Sprintf("minimum transfer amount %v", minAmount))
+			},
+		},
+		{
+			name: "above maximum amount",
+			body: transferBody(maxAmount + 1),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), fmt.Sprintf("maximum transfer amount %v", maxAmount))
+			},
+		},
+		{
+			name: "daily limit exceeded",
+			body: transferBody(minAmount),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrDailyTransferLimit)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				requireErrorCode(t, recorder, codeDailyLimitExceeded)
+			},
+		},
+		{
+			name: "account spending limit exceeded",
+			body: transferBody(minAmount),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, db.ErrAccountSpendingLimit)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				requireErrorCode(t, recorder, codeSpendingLimitExceeded)
+			},
+		},
+		{
+			name:           "daily limit exceeded with idempotency key",
+			body:           transferBody(minAmount),
+			idempotencyKey: utils.RandomString(16),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().IdempotentTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.IdempotentTransferTxResult{}, db.ErrDailyTransferLimit)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+			server.config.MinTransferAmount = minAmount
+			server.config.MaxTransferAmount = maxAmount
+			server.config.DailyTransferLimit = dailyLimit
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+			if tc.idempotencyKey != "" {
+				request.Header.Set(idempotencyKeyHeader, tc.idempotencyKey)
+			}
+
+			addAuthorization(t, request, server.token, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateTransferDryRunAPI(t *testing.T) {
+	body := gin.H{
+		"from_account_number": account1.AccountNumber,
+		"to_account_number":   account2.AccountNumber,
+		"amount":              _amount,
+		"currency":            utils.USD,
+	}
+	arg := db.TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        _amount,
+		DryRun:        true,
+	}
+
+	// the balances the transfer would leave, the store rolled it back
+	fromAccount := account1
+	fromAccount.Balance -= _amount
+	toAccount := account2
+	toAccount.Balance += _amount
+	preview := db.TransferTxResult{
+		Transfer:      db.Transfer{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: _amount, ToAmount: _amount},
+		FromAccountID: fromAccount,
+		ToAccountID:   toAccount,
+	}
+
+	testCases := []struct {
+		name           string
+		query          string
+		idempotencyKey string
+		buildStubs     func(store *mockdb.MockStore)
+		checkResponse  func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "happy path dry run",
+			query: "?dry_run=true",
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(preview, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp transferPreviewResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, rsp.DryRun)
+				require.Equal(t, int64(_amount), rsp.Amount)
+				require.Equal(t, int64(_amount), rsp.ToAmount)
+				require.Equal(t, fromAccount.Balance, rsp.FromAccount.Balance)
+				require.Equal(t, toAccount.Balance, rsp.ToAccount.Balance)
+			},
+		},
+		{
+			name:           "dry run ignores the idempotency key",
+			query:          "?dry_run=true",
+			idempotencyKey: utils.RandomString(16),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(preview, nil)
+				store.EXPECT().IdempotentTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "dry run insufficient balance",
+			query: "?dry_run=true",
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), []string{account1.AccountNumber, account2.AccountNumber}).Times(1).
+					Return(map[string]db.Account{account1.AccountNumber: account1, account2.AccountNumber: account2}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientBalance)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInsufficientBalance)
+			},
+		},
+		{
+			name:  "invalid dry run",
+			query: "?dry_run=maybe",
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers"+tc.query, bytes.NewReader(data))
+			require.NoError(t, err)
+			if tc.idempotencyKey != "" {
+				request.Header.Set(idempotencyKeyHeader, tc.idempotencyKey)
+			}
+
+			addAuthorization(t, request, server.token, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListTransfersAPI(t *testing.T) {
+	user, _ := randomUser()
+	account := randomAccount(user.Username)
+
+	n := 5
+	total := 4 * n
+	transfers := make([]db.Transfer, n)
+	counterpartIDs := make([]int64, n)
+	counterparts := make([]db.GetAccountNumbersRow, n)
+	for i := 0; i < n; i++ {
+		counterpartIDs[i] = account.ID + 1000 + int64(i)
+		counterparts[i] = db.GetAccountNumbersRow{ID: counterpartIDs[i], AccountNumber: utils.RandomAccountNumber()}
+		transfers[i] = db.Transfer{
+			ID:            utils.RandomInt(1, 1000),
+			UUID:          uuid.New(),
+			FromAccountID: account.ID,
+			ToAccountID:   counterpartIDs[i],
+			Amount:        utils.RandomBalance(),
+		}
+	}
+	numbers := newAccountNumbers(account)
+	for _, counterpart := range counterparts {
+		numbers[counterpart.ID] = counterpart.AccountNumber
+	}
+
+	type query struct {
+		accountNumber string
+		pageID        int
+		pageSize      int
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		query         query
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path list transfers",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{accountNumber: account.AccountNumber, pageID: 2, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListTransfersParams{
+					FromAccountID: account.ID,
+					ToAccountID:   account.ID,
+					Limit:         int32(n),
+					Offset:        int32(n),
+				}
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Eq(arg)).Times(1).Return(transfers, nil)
+				store.EXPECT().
+					CountTransfers(gomock.Any(), gomock.Eq(db.CountTransfersParams{FromAccountID: account.ID, ToAccountID: account.ID})).
+					Times(1).
+					Return(int64(total), nil)
+				// the numbers of the counterparts are read in one call, the listed account is already known
+				store.EXPECT().GetAccountNumbers(gomock.Any(), gomock.Eq(counterpartIDs)).Times(1).Return(counterparts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp struct {
+					Data     []transferResponse `json:"data"`
+					PageID   int32              `json:"page_id"`
+					PageSize int32              `json:"page_size"`
+					Total    int64              `json:"total"`
+				}
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.Data, n)
+				for i := range transfers {
+					require.Equal(t, newTransferResponse(transfers[i], numbers, amountFormatMinor), rsp.Data[i])
+					require.Equal(t, account.AccountNumber, rsp.Data[i].FromAccountNumber)
+					require.Equal(t, counterparts[i].AccountNumber, rsp.Data[i].ToAccountNumber)
+				}
+				require.Equal(t, int32(2), rsp.PageID)
+				require.Equal(t, int32(n), rsp.PageSize)
+				// total reflects every matching transfer, not just the page
+				require.Equal(t, int64(total), rsp.Total)
+			},
+		},
+		{
+			name: "account not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{accountNumber: account.AccountNumber, pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "unauthorized user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, "unauthorized_user", utils.DepositorRole, time.Minute)
+			},
+			query: query{accountNumber: account.AccountNumber, pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "zero page size",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{accountNumber: account.AccountNumber, pageID: 1, pageSize: 0},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			query: query{accountNumber: account.AccountNumber, pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Any()).Times(1).Return([]db.Transfer{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:      "no authorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			query:     query{accountNumber: account.AccountNumber, pageID: 1, pageSize: n},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodGet, "/transfers", nil)
+			require.NoError(t, err)
+
+			q := request.URL.Query()
+			q.Add("account_number", tc.query.accountNumber)
+			q.Add("page_id", fmt.Sprintf("%d", tc.query.pageID))
+			q.Add("page_size", fmt.Sprintf("%d", tc.query.pageSize))
+			request.URL.RawQuery = q.Encode()
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateSplitTransferAPI(t *testing.T) {
+	receiver1 := randomAccount(user2.Username)
+	receiver1.ID = account1.ID + 1000
+	receiver2 := randomAccount(userARS.Username)
+	receiver2.ID = account1.ID + 2000
+	splitNumbers := []string{account1.AccountNumber, receiver1.AccountNumber, receiver2.AccountNumber}
+	splitAccounts := map[string]db.Account{
+		account1.AccountNumber:  account1,
+		receiver1.AccountNumber: receiver1,
+		receiver2.AccountNumber: receiver2,
+	}
+
+	splitBody := func(amount int64) gin.H {
+		return gin.H{
+			"from_account_number": account1.AccountNumber,
+			"amount":              amount,
+			"currency":            utils.USD,
+			"splits": []gin.H{
+				{"to_account_number": receiver1.AccountNumber, "amount": 40},
+				{"to_account_number": receiver2.AccountNumber, "amount": 60},
+			},
+		}
+	}
+	splitArg := db.SplitTransferTxParams{
+		FromAccountID: account1.ID,
+		Amount:        100,
+		Splits: []db.TransferSplit{
+			{ToAccountID: receiver1.ID, Amount: 40},
+			{ToAccountID: receiver2.ID, Amount: 60},
+		},
+	}
+
+	result := db.SplitTransferTxResult{
+		FromAccount: account1,
+		Transfers: []db.TransferTxResult{
+			{
+				Transfer:      db.Transfer{ID: utils.RandomInt(1, 1000), UUID: uuid.New(), FromAccountID: account1.ID, ToAccountID: receiver1.ID, Amount: 40},
+				FromAccountID: account1,
+				ToAccountID:   receiver1,
+			},
+			{
+				Transfer:      db.Transfer{ID: utils.RandomInt(1, 1000), UUID: uuid.New(), FromAccountID: account1.ID, ToAccountID: receiver2.ID, Amount: 60},
+				FromAccountID: account1,
+				ToAccountID:   receiver2,
+			},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path split transfer",
+			body: splitBody(100),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq(splitNumbers)).Times(1).Return(splitAccounts, nil)
+				store.EXPECT().SplitTransferTx(gomock.Any(), gomock.Eq(splitArg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp splitTransferResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.Transfers, 2)
+				require.Equal(t, newTransferResponse(result.Transfers[0].Transfer, newAccountNumbers(account1, receiver1), amountFormatMinor), rsp.Transfers[0].Transfer)
+				require.Equal(t, newTransferResponse(result.Transfers[1].Transfer, newAccountNumbers(account1, receiver2), amountFormatMinor), rsp.Transfers[1].Transfer)
+				require.Equal(t, receiver2.AccountNumber, rsp.Transfers[1].Transfer.ToAccountNumber)
+			},
+		},
+		{
+			name: "splits don't add up",
+			body: splitBody(90),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := splitArg
+				arg.Amount = 90
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq(splitNumbers)).Times(1).Return(splitAccounts, nil)
+				store.EXPECT().SplitTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).
+					Return(db.SplitTransferTxResult{}, fmt.Errorf("%w: the splits add up to 100, not 90", db.ErrInvalidSplit))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "receiver currency mismatch",
+			body: splitBody(100),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq(splitNumbers)).Times(1).Return(splitAccounts, nil)
+				store.EXPECT().SplitTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.SplitTransferTxResult{}, db.ErrCurrencyMismatch)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeCurrencyMismatch)
+			},
+		},
+		{
+			name: "insufficient balance",
+			body: splitBody(100),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq(splitNumbers)).Times(1).Return(splitAccounts, nil)
+				store.EXPECT().SplitTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.SplitTransferTxResult{}, db.ErrInsufficientBalance)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInsufficientBalance)
+			},
+		},
+		{
+			name: "frozen receiver",
+			body: splitBody(100),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq(splitNumbers)).Times(1).Return(splitAccounts, nil)
+				store.EXPECT().SplitTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.SplitTransferTxResult{}, db.ErrAccountFrozen)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountFrozen)
+			},
+		},
+		{
+			name: "receiver not found",
+			body: splitBody(100),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				found := map[string]db.Account{account1.AccountNumber: account1, receiver1.AccountNumber: receiver1}
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq(splitNumbers)).Times(1).Return(found, nil)
+				store.EXPECT().SplitTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountNotFound)
+			},
+		},
+		{
+			name: "not the from account owner",
+			body: splitBody(100),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user2.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq(splitNumbers)).Times(1).Return(splitAccounts, nil)
+				store.EXPECT().SplitTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireErrorCode(t, recorder, codeNotAccountOwner)
+			},
+		},
+		{
+			name: "no splits",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"amount":              100,
+				"currency":            utils.USD,
+				"splits":              []gin.H{},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().SplitTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "invalid split amount",
+			body: gin.H{
+				"from_account_number": account1.AccountNumber,
+				"amount":              100,
+				"currency":            utils.USD,
+				"splits":              []gin.H{{"to_account_number": receiver1.AccountNumber, "amount": -100}},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user1.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().SplitTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "no authorization",
+			body: splitBody(100),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().SplitTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers/split", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// publicTransferTxResponse drops the internal ids of the accounts and the entries, which a decoded response never has
+func publicTransferTxResponse(rsp transferTxResponse) transferTxResponse {
+	rsp.FromAccountID.Account = publicAccount(rsp.FromAccountID.Account)
+	rsp.ToAccountID.Account = publicAccount(rsp.ToAccountID.Account)
+	rsp.FromEntry.AccountID = 0
+	rsp.ToEntry.AccountID = 0
+	return rsp
+}
+
+func validateResponseTransfer(t *testing.T, body *bytes.Buffer, trxr db.TransferTxResult) {
+	data, err := ioutil.ReadAll(body)
+	require.NoError(t, err)
+
+	var rspTransfer transferTxResponse
+	err = json.Unmarshal(data, &rspTransfer)
+	require.NoError(t, err)
+	require.Equal(t, publicTransferTxResponse(newTransferTxResponse(trxr, amountFormatMinor)), rspTransfer)
+
+	// the internal id is never exposed, the transfer is identified by its uuid
+	var raw struct {
+		Transfer map[string]interface{} `json:"transfer"`
+	}
+	require.NoError(t, json.Unmarshal(data, &raw))
+	require.Equal(t, trxr.Transfer.UUID.String(), raw.Transfer["id"])
+	require.NotContains(t, raw.Transfer, "uuid")
+	require.NotContains(t, raw.Transfer, "reversed_from")
+}
+
+func TestReverseTransferAPI(t *testing.T) {
+	sender, _ := randomUser()
+	receiver, _ := randomUser()
+	banker, _ := randomUser()
+	fromAccount := randomAccount(sender.Username)
+	toAccount := randomAccount(receiver.Username)
+
+	transfer := db.Transfer{
+		ID:            utils.RandomInt(1, 1000),
+		UUID:          uuid.New(),
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        _amount,
+	}
+
+	result := db.ReverseTransferTxResult{
+		TransferTxResult: db.TransferTxResult{
+			Transfer: db.Transfer{
+				ID:            transfer.ID + 1,
+				UUID:          uuid.New(),
+				FromAccountID: toAccount.ID,
+				ToAccountID:   fromAccount.ID,
+				Amount:        _amount,
+				ReversedFrom:  sql.NullInt64{Int64: transfer.ID, Valid: true},
+			},
+			FromAccountID: toAccount,
+			ToAccountID:   fromAccount,
+		},
+		OriginalTransfer: transfer,
+	}
+
+	testCases := []struct {
+		name          string
+		transferID    string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "happy path reverse transfer",
+			transferID: transfer.UUID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferByUUID(gomock.Any(), gomock.Eq(transfer.UUID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Eq(db.ReverseTransferTxParams{TransferID: transfer.ID})).
+					Times(1).
+					Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp reverseTransferResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, publicTransferTxResponse(newTransferTxResponse(result.TransferTxResult, amountFormatMinor)), rsp.transferTxResponse)
+				require.Equal(t, newTransferResponse(transfer, newAccountNumbers(fromAccount, toAccount), amountFormatMinor), rsp.OriginalTransfer)
+			},
+		},
+		{
+			name:       "banker reverses transfer",
+			transferID: transfer.UUID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, utils.BankerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferByUUID(gomock.Any(), gomock.Eq(transfer.UUID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:       "from account closed since",
+			transferID: transfer.UUID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				closedAccount := fromAccount
+				closedAccount.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().GetTransferByUUID(gomock.Any(), gomock.Eq(transfer.UUID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(closedAccount, nil)
+				// the store rejects the reversal with the sender account locked
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(db.ReverseTransferTxParams{TransferID: transfer.ID})).Times(1).
+					Return(db.ReverseTransferTxResult{}, db.ErrAccountDeleted)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireErrorCode(t, recorder, codeAccountDeleted)
+			},
+		},
+		{
+			name:       "receiver can't reverse transfer",
+			transferID: transfer.UUID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, receiver.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferByUUID(gomock.Any(), gomock.Eq(transfer.UUID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:       "transfer not found",
+			transferID: transfer.UUID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferByUUID(gomock.Any(), gomock.Eq(transfer.UUID)).Times(1).Return(db.Transfer{}, sql.ErrNoRows)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "transfer already reversed",
+			transferID: transfer.UUID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferByUUID(gomock.Any(), gomock.Eq(transfer.UUID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ReverseTransferTxResult{}, fmt.Errorf("%w: transfer [%v]", db.ErrTransferAlreadyReversed, transfer.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:       "receiver insufficient balance",
+			transferID: transfer.UUID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferByUUID(gomock.Any(), gomock.Eq(transfer.UUID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().
+					ReverseTransferTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ReverseTransferTxResult{}, fmt.Errorf("%w: account [%v]", db.ErrInsufficientBalance, toAccount.ID))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:       "internal server error",
+			transferID: transfer.UUID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferByUUID(gomock.Any(), gomock.Eq(transfer.UUID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccountIncludingDeleted(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReverseTransferTxResult{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:       "invalid id",
+			transferID: fmt.Sprint(transfer.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, sender.Username, utils.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransferByUUID(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/transfers/%s/reverse", tc.transferID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
 }
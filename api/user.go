@@ -2,159 +2,427 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
 	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/micaelapucciariello/simplebank/worker"
 	"net/http"
 	"time"
 )
 
+// unique constraints of the users table
+const (
+	usersUsernameConstraint = "users_pkey"
+	usersEmailConstraint    = "users_email_key"
+)
+
+var (
+	errUsernameInUse = errors.New("username already in use")
+	errEmailInUse    = errors.New("email already in use")
+)
+
 type (
 	createUserReq struct {
-		UserName string `json:"username" binding:"required,alphanum"`
-		Password string `json:"password" binding:"required,min=6"`
+		UserName string `json:"username" binding:"required,username"`
+		Password string `json:"password" binding:"required"`
 		FullName string `json:"full_name" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
 	}
 
-	createUserRsp struct {
-		UserName string `json:"username"`
-		FullName string `json:"full_name"`
-		Email    string `json:"email"`
+	// userResponse is the public representation of a user, it never carries the hashed password
+	userResponse struct {
+		UserName          string    `json:"username"`
+		FullName          string    `json:"full_name"`
+		Email             string    `json:"email"`
+		Role              string    `json:"role"`
+		PasswordChangedAt time.Time `json:"password_changed_at"`
+		CreatedAt         time.Time `json:"created_at"`
 	}
 
 	getUserReq struct {
-		UserName string `uri:"username" binding:"required,alphanum"`
+		UserName string `uri:"username" binding:"required,username"`
+	}
+
+	updateUserUriReq struct {
+		UserName string `uri:"username" binding:"required,username"`
+	}
+
+	// updateUserReq fields are optional, only the ones sent are updated
+	updateUserReq struct {
+		FullName *string `json:"full_name" binding:"omitempty,min=1"`
+		Email    *string `json:"email" binding:"omitempty,email"`
+	}
+
+	changePasswordUriReq struct {
+		UserName string `uri:"username" binding:"required,username"`
+	}
+
+	changePasswordReq struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
 	}
 
 	loginUserRequest struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
+		// Username identifies the user by either its username or its email
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required,min=6"`
+		// TOTPCode is the two-factor code, required once the user enabled two-factor authentication
+		TOTPCode string `json:"totp_code"`
 	}
 
 	loginUserResponse struct {
-		SessionID             uuid.UUID     `json:"session_id"`
-		RefreshToken          string        `json:"refresh_token"`
-		RefreshTokenExpiresAt time.Time     `json:"refresh_token_expires_at"`
-		AccessToken           string        `json:"access_token"`
-		AccessTokenExpiresAt  time.Time     `json:"access_token_expires_at"`
-		UserMetadata          createUserRsp `json:"user_metadata"`
+		SessionID             uuid.UUID    `json:"session_id"`
+		RefreshToken          string       `json:"refresh_token"`
+		RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+		AccessToken           string       `json:"access_token"`
+		AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+		UserMetadata          userResponse `json:"user_metadata"`
 	}
 )
 
-func parseUserInfo(user db.User) createUserRsp {
-	return createUserRsp{
-		UserName: user.Username,
-		FullName: user.FullName,
-		Email:    user.Email,
+// isLocked reports whether the user is still within the lockout that follows too many failed logins
+func isLocked(user db.User) bool {
+	return user.LockedUntil.Valid && user.LockedUntil.Time.After(time.Now())
+}
+
+func newErrAccountLocked(user db.User) error {
+	return fmt.Errorf("account is locked until %v after too many failed logins", user.LockedUntil.Time.Format(time.RFC3339))
+}
+
+func newUserResponse(user db.User) userResponse {
+	return userResponse{
+		UserName:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		Role:              user.Role,
+		PasswordChangedAt: user.PasswordChangedAt,
+		CreatedAt:         user.CreatedAt.Time,
 	}
 }
 
 func (s *Server) createUser(ctx *gin.Context) {
 	var req createUserReq
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	if err := utils.ValidatePassword(req.Password); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidPassword, err))
+		return
 	}
 
-	hashedPassword, err := utils.HashPassword(req.Password)
+	hashedPassword, err := utils.HashPassword(req.Password, s.config.BcryptCost)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
 	}
 
+	// signing up is public, so there is no caller organization to join: new users land in the default one
 	arg := db.CreateUserParams{
-		Username:       req.UserName,
+		Username:       utils.NormalizeUsername(req.UserName),
 		HashedPassword: hashedPassword,
 		FullName:       req.FullName,
-		Email:          req.Email,
+		Email:          utils.NormalizeEmail(req.Email),
+		OrgID:          db.DefaultOrgID,
 	}
 
 	user, err := s.store.CreateUser(ctx, arg)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				ctx.JSON(http.StatusForbidden, errResponse(err))
-			}
+		if code, conflictErr, ok := userConflict(err); ok {
+			ctx.JSON(http.StatusConflict, errorResponse(code, conflictErr))
 			return
-
 		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		respondDBError(ctx, err, codeUserNotFound)
 	} else {
-		rsp := parseUserInfo(user)
+		// the welcome email is sent in the background, failing to enqueue it doesn't undo the registration
+		err = s.taskDistributor.DistributeTaskSendWelcomeEmail(ctx, &worker.PayloadSendWelcomeEmail{Username: user.Username})
+		if err != nil {
+			s.logger.Error().Err(err).Str("username", user.Username).Msg("cannot distribute welcome email task")
+		}
+
+		rsp := newUserResponse(user)
 		ctx.JSON(http.StatusOK, rsp)
 	}
 }
 
+// userConflict returns the code and the error of a user creation that failed on a unique constraint, ok is false for any other error
+func userConflict(err error) (code string, conflictErr error, ok bool) {
+	pqErr, isPqErr := err.(*pq.Error)
+	if !isPqErr || pqErr.Code.Name() != "unique_violation" {
+		return "", nil, false
+	}
+
+	switch pqErr.Constraint {
+	case usersEmailConstraint:
+		return codeEmailInUse, errEmailInUse, true
+	case usersUsernameConstraint:
+		return codeUsernameInUse, errUsernameInUse, true
+	}
+	return codeUserExists, err, true
+}
+
 func (s *Server) getUser(ctx *gin.Context) {
 	var req getUserReq
-	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+	if !bindURI(ctx, &req) {
 		return
 	}
+	// the binding accepts any case, the usernames are stored lowercase
+	req.UserName = utils.NormalizeUsername(req.UserName)
 
 	user, err := s.store.GetUser(ctx, req.UserName)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
+		respondDBError(ctx, err, codeUserNotFound)
+		return
+	}
+	if !checkSameOrg(ctx, user.OrgID) {
+		return
+	}
+
+	// a profile is read by its user, or by a banker of the same organization
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != user.Username && authPayload.Role != utils.BankerRole {
+		err = errors.New("user doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeForbidden, err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// updateUser applies a partial update over the authenticated user profile
+func (s *Server) updateUser(ctx *gin.Context) {
+	var uri updateUserUriReq
+	if !bindURI(ctx, &uri) {
+		return
+	}
+	uri.UserName = utils.NormalizeUsername(uri.UserName)
+
+	var req updateUserReq
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	if req.FullName == nil && req.Email == nil {
+		err := errors.New("at least one of full_name or email must be provided")
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != uri.UserName {
+		err := errors.New("user doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeForbidden, err))
+		return
+	}
+
+	arg := db.UpdateUserParams{
+		Username: uri.UserName,
+	}
+	if req.FullName != nil {
+		arg.FullName = sql.NullString{String: *req.FullName, Valid: true}
+	}
+	if req.Email != nil {
+		arg.Email = sql.NullString{String: utils.NormalizeEmail(*req.Email), Valid: true}
+	}
+
+	user, err := s.store.UpdateUser(ctx, arg)
+	if err != nil {
+		// email is the only unique column a profile update can change
+		if status, _ := dbErrorToHTTP(err); status == http.StatusConflict {
+			ctx.JSON(status, errorResponse(codeEmailInUse, errEmailInUse))
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
-	} else {
-		rsp := parseUserInfo(user)
-		ctx.JSON(http.StatusOK, rsp)
+		respondDBError(ctx, err, codeUserNotFound)
+		return
 	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
 }
 
-func (s *Server) loginUser(ctx *gin.Context) {
-	var req loginUserRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errResponse(err))
+// changePassword replaces the authenticated user password once the current one is verified
+// The request must carry a step-up token as well, proving the user just authenticated again
+func (s *Server) changePassword(ctx *gin.Context) {
+	var uri changePasswordUriReq
+	if !bindURI(ctx, &uri) {
+		return
+	}
+	uri.UserName = utils.NormalizeUsername(uri.UserName)
+
+	var req changePasswordReq
+	if !bindJSON(ctx, &req) {
 		return
 	}
 
-	user, err := s.store.GetUser(ctx, req.Username)
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	if authPayload.UserName != uri.UserName {
+		err := errors.New("user doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(codeForbidden, err))
+		return
+	}
+
+	if !s.requireStepUp(ctx) {
+		return
+	}
+
+	if err := utils.ValidatePassword(req.NewPassword); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidPassword, err))
+		return
+	}
+
+	user, err := s.store.GetUser(ctx, uri.UserName)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errResponse(err))
-			return
+		respondDBError(ctx, err, codeUserNotFound)
+		return
+	}
+
+	if err = utils.CheckPassword(req.CurrentPassword, user.HashedPassword); err != nil {
+		err = errors.New("current password is incorrect")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(codeInvalidCredentials, err))
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword, s.config.BcryptCost)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
+	}
+
+	user, err = s.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		UpdateUserPasswordParams: db.UpdateUserPasswordParams{
+			HashedPassword: hashedPassword,
+			Username:       user.Username,
+		},
+		Actor: db.Actor{Username: authPayload.UserName, ClientIP: ctx.ClientIP()},
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeUserNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// rejectLogin answers a login whose credentials are wrong, the failure is recorded when the lockout is enabled
+func (s *Server) rejectLogin(ctx *gin.Context, user db.User, code string, loginErr error) {
+	if s.config.LoginMaxAttempts <= 0 {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(code, loginErr))
+		return
+	}
+
+	user, err := s.store.RecordFailedLogin(ctx, db.RecordFailedLoginParams{
+		MaxAttempts: s.config.LoginMaxAttempts,
+		LockedUntil: time.Now().Add(s.config.LoginLockoutDuration),
+		Username:    user.Username,
+	})
+	if err != nil {
+		respondDBError(ctx, err, codeUserNotFound)
+		return
+	}
+	// the failure that reaches the limit locks the account right away
+	if isLocked(user) {
+		ctx.JSON(http.StatusLocked, errorResponse(codeAccountLocked, newErrAccountLocked(user)))
+		return
+	}
+	ctx.JSON(http.StatusUnauthorized, errorResponse(code, loginErr))
+}
+
+// verifyCredentials checks the password of the user and, once two-factor authentication is enabled, its code, writing the error response
+// The failures count towards the lockout, a success clears the failed attempts
+func (s *Server) verifyCredentials(ctx *gin.Context, user db.User, password, totpCode string) bool {
+	if isLocked(user) {
+		ctx.JSON(http.StatusLocked, errorResponse(codeAccountLocked, newErrAccountLocked(user)))
+		return false
+	}
+
+	if passwordErr := utils.CheckPassword(password, user.HashedPassword); passwordErr != nil {
+		s.rejectLogin(ctx, user, codeInvalidCredentials, passwordErr)
+		return false
+	}
+
+	// the two-factor challenge: a user who enabled it authenticates with the code of their authenticator app as well
+	secret, totpEnabled, err := s.enabledTOTPSecret(ctx, user.Username)
+	if err != nil {
+		respondDBError(ctx, err, codeUserNotFound)
+		return false
+	}
+	if totpEnabled {
+		if totpCode == "" {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(codeTOTPRequired, errTOTPRequired))
+			return false
 		}
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
-	} else {
-		rsp := createUserRsp{
-			UserName: user.Username,
-			FullName: user.FullName,
-			Email:    user.Email,
+		// a wrong code counts towards the lockout like a wrong password, so the codes can't be brute forced
+		if !utils.ValidateTOTP(secret, totpCode, time.Now()) {
+			s.rejectLogin(ctx, user, codeInvalidTOTPCode, errInvalidTOTPCode)
+			return false
 		}
-		ctx.JSON(http.StatusOK, rsp)
 	}
 
-	err = utils.CheckPassword(req.Password, user.HashedPassword)
+	if user.FailedLoginAttempts > 0 || user.LockedUntil.Valid {
+		if err = s.store.ResetFailedLogins(ctx, user.Username); err != nil {
+			respondDBError(ctx, err, codeUserNotFound)
+			return false
+		}
+	}
+	return true
+}
+
+// getLoginUser reads the user a login identifier names, an email or a username is matched whatever its case
+func (s *Server) getLoginUser(ctx *gin.Context, identifier string) (db.User, error) {
+	if utils.IsEmailIdentifier(identifier) {
+		return s.store.GetUserByEmail(ctx, utils.NormalizeEmail(identifier))
+	}
+	return s.store.GetUser(ctx, utils.NormalizeUsername(identifier))
+}
+
+// loginUser verifies the user credentials and returns a new access token along with a refresh token bound to a session
+func (s *Server) loginUser(ctx *gin.Context) {
+	var req loginUserRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	user, err := s.getLoginUser(ctx, req.Username)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errResponse(err))
+		respondDBError(ctx, err, codeUserNotFound)
 		return
 	}
 
-	accessToken, accessPayload, err := s.token.CreateToken(req.Username, s.config.TokenDuration)
+	if !s.verifyCredentials(ctx, user, req.Password, req.TOTPCode) {
+		return
+	}
+
+	accessToken, accessPayload, err := s.token.CreateAccessToken(user.Username, user.Role, user.OrgID, user.PasswordChangedAt)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
 	}
 
-	refreshToken, refreshPayload, err := s.token.CreateToken(req.Username, s.config.RefreshTokenDuration)
+	refreshToken, refreshPayload, err := s.token.CreateRefreshToken(user.Username, user.Role, user.OrgID, user.PasswordChangedAt)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errResponse(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
 	}
 
-	session, err := s.store.CreateSession(ctx, db.CreateSessionParams{
-		ID:           refreshPayload.ID,
-		Username:     req.Username,
-		RefreshToken: refreshToken,
-		UserAgent:    ctx.Request.UserAgent(),
-		ClientIp:     ctx.ClientIP(),
-		IsBlocked:    false,
-		ExpiresAt:    sql.NullTime{Time: refreshPayload.ExpiredAt},
+	session, err := s.store.LoginTx(ctx, db.LoginTxParams{
+		CreateSessionParams: db.CreateSessionParams{
+			ID:           refreshPayload.ID,
+			Username:     user.Username,
+			RefreshToken: refreshToken,
+			UserAgent:    ctx.Request.UserAgent(),
+			ClientIp:     ctx.ClientIP(),
+			IsBlocked:    false,
+			ExpiresAt:    sql.NullTime{Time: refreshPayload.ExpiredAt, Valid: true},
+		},
 	})
+	if err != nil {
+		respondDBError(ctx, err, codeUserNotFound)
+		return
+	}
 
 	rsp := loginUserResponse{
 		SessionID:             session.ID,
@@ -162,7 +430,7 @@ func (s *Server) loginUser(ctx *gin.Context) {
 		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
 		AccessToken:           accessToken,
 		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
-		UserMetadata:          parseUserInfo(user),
+		UserMetadata:          newUserResponse(user),
 	}
 
 	ctx.JSON(http.StatusOK, rsp)
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/micaelapucciariello/simplebank/worker"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const maxImportUsers = 100
+
+var (
+	errImportRolledBack = errors.New("not created, the atomic import was rolled back")
+	importCSVColumns    = []string{"username", "password", "full_name", "email"}
+)
+
+type (
+	importUsersReq struct {
+		Atomic bool `form:"atomic"`
+	}
+
+	// importUserRow is a user of the import, the rows are validated one by one so an invalid row doesn't reject the whole import
+	importUserRow struct {
+		UserName string `json:"username" binding:"required,username"`
+		Password string `json:"password" binding:"required"`
+		FullName string `json:"full_name" binding:"required"`
+		Email    string `json:"email" binding:"required,email"`
+	}
+
+	importUserResult struct {
+		// Row is the position of the user in the import starting at 1, the csv header isn't counted
+		Row      int           `json:"row"`
+		UserName string        `json:"username"`
+		Created  bool          `json:"created"`
+		User     *userResponse `json:"user,omitempty"`
+		Error    *apiError     `json:"error,omitempty"`
+	}
+
+	importUsersResponse struct {
+		Atomic     bool               `json:"atomic"`
+		RolledBack bool               `json:"rolled_back"`
+		Created    int                `json:"created"`
+		Failed     int                `json:"failed"`
+		Results    []importUserResult `json:"results"`
+	}
+)
+
+// importUsers creates a batch of users sent as a json array or as csv, it is only reachable by bankers
+// Every row gets its own result: by default the failing rows are skipped and the others created,
+// with atomic set a single failing row leaves every user uncreated
+func (s *Server) importUsers(ctx *gin.Context) {
+	var req importUsersReq
+	if !bindQuery(ctx, &req) {
+		return
+	}
+
+	var rows []importUserRow
+	var err error
+	if ctx.ContentType() == csvContentType {
+		rows, err = parseImportCSV(ctx.Request.Body)
+	} else {
+		err = json.NewDecoder(ctx.Request.Body).Decode(&rows)
+	}
+	if err != nil {
+		respondBindError(ctx, err)
+		return
+	}
+	if len(rows) == 0 || len(rows) > maxImportUsers {
+		err = fmt.Errorf("an import holds from 1 to %v users, got %v", maxImportUsers, len(rows))
+		ctx.JSON(http.StatusBadRequest, errorResponse(codeInvalidRequest, err))
+		return
+	}
+
+	rsp := importUsersResponse{
+		Atomic:  req.Atomic,
+		Results: make([]importUserResult, len(rows)),
+	}
+	for i, row := range rows {
+		row.UserName = utils.NormalizeUsername(row.UserName)
+		rows[i] = row
+		rsp.Results[i] = importUserResult{Row: i + 1, UserName: row.UserName}
+		if err := binding.Validator.ValidateStruct(row); err != nil {
+			rsp.Results[i].Error = importError(codeInvalidRequest, err)
+		} else if err := utils.ValidatePassword(row.Password); err != nil {
+			rsp.Results[i].Error = importError(codeInvalidPassword, err)
+		}
+	}
+
+	hashedPasswords, err := s.hashImportPasswords(rows, rsp.Results)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(codeInternal, err))
+		return
+	}
+
+	// the users join the organization of the banker importing them
+	authPayload := ctx.MustGet(authorizationHeaderKey).(*token.Payload)
+	// the rows sent to the store, indexes maps them back to their result
+	var users []db.CreateUserParams
+	var indexes []int
+	for i, row := range rows {
+		if rsp.Results[i].Error != nil {
+			continue
+		}
+		users = append(users, db.CreateUserParams{
+			Username:       row.UserName,
+			HashedPassword: hashedPasswords[i],
+			FullName:       row.FullName,
+			Email:          utils.NormalizeEmail(row.Email),
+			OrgID:          authPayload.OrgID,
+		})
+		indexes = append(indexes, i)
+	}
+
+	invalidRows := len(users) < len(rows)
+	if len(users) > 0 && !(req.Atomic && invalidRows) {
+		result, err := s.store.ImportUsersTx(ctx, db.ImportUsersTxParams{
+			Users:  users,
+			Atomic: req.Atomic,
+		})
+		if err != nil {
+			respondDBError(ctx, err, codeNotFound)
+			return
+		}
+
+		for j, i := range indexes {
+			if err := result.Errs[j]; err != nil {
+				code, rowErr, ok := userConflict(err)
+				if !ok {
+					_, code = dbErrorToHTTP(err)
+					rowErr = err
+				}
+				rsp.Results[i].Error = importError(code, rowErr)
+				continue
+			}
+			if result.RolledBack {
+				continue
+			}
+
+			user := newUserResponse(result.Users[j])
+			rsp.Results[i].Created = true
+			rsp.Results[i].User = &user
+		}
+		rsp.RolledBack = result.RolledBack
+	} else if req.Atomic && invalidRows {
+		rsp.RolledBack = true
+	}
+
+	for i := range rsp.Results {
+		switch {
+		case rsp.Results[i].Created:
+			rsp.Created++
+		case rsp.Results[i].Error != nil:
+			rsp.Failed++
+		default:
+			rsp.Results[i].Error = importError(codeImportRolledBack, errImportRolledBack)
+		}
+	}
+
+	// like a single registration, the welcome emails are sent in the background and failing to enqueue one doesn't undo the import
+	for _, result := range rsp.Results {
+		if !result.Created {
+			continue
+		}
+		err = s.taskDistributor.DistributeTaskSendWelcomeEmail(ctx, &worker.PayloadSendWelcomeEmail{Username: result.UserName})
+		if err != nil {
+			s.logger.Error().Err(err).Str("username", result.UserName).Msg("cannot distribute welcome email task")
+		}
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// hashImportPasswords hashes the passwords of the valid rows in parallel, bcrypt is the bulk of the cost of an import
+func (s *Server) hashImportPasswords(rows []importUserRow, results []importUserResult) ([]string, error) {
+	hashed := make([]string, len(rows))
+	errs := make([]error, len(rows))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+	for i := range rows {
+		if results[i].Error != nil {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			hashed[i], errs[i] = utils.HashPassword(rows[i].Password, s.config.BcryptCost)
+			<-sem
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hashed, nil
+}
+
+// parseImportCSV reads the users of a csv import, its header names the columns in any order
+func parseImportCSV(r io.Reader) ([]importUserRow, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("invalid csv: the header is missing")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range importCSVColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("invalid csv: the %v column is missing", name)
+		}
+	}
+
+	rows := make([]importUserRow, len(records)-1)
+	for i, record := range records[1:] {
+		rows[i] = importUserRow{
+			UserName: strings.TrimSpace(record[columns["username"]]),
+			Password: record[columns["password"]],
+			FullName: strings.TrimSpace(record[columns["full_name"]]),
+			Email:    strings.TrimSpace(record[columns["email"]]),
+		}
+	}
+	return rows, nil
+}
+
+// importError builds the error of a single row, the same body as an error response
+func importError(code string, err error) *apiError {
+	rsp := errorResponse(code, err)
+	return &rsp
+}
@@ -0,0 +1,369 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestImportUsersAPI(t *testing.T) {
+	banker, _ := randomUser()
+	banker.Role = utils.BankerRole
+	depositor, _ := randomUser()
+
+	user1, password1 := randomUser()
+	user2, password2 := randomUser()
+
+	importRow := func(user db.User, password string) gin.H {
+		return gin.H{
+			"username":  user.Username,
+			"password":  password,
+			"full_name": user.FullName,
+			"email":     user.Email,
+		}
+	}
+	jsonBody := func(rows ...gin.H) string {
+		data, err := json.Marshal(rows)
+		require.NoError(t, err)
+		return string(data)
+	}
+	tooManyRows := make([]gin.H, maxImportUsers+1)
+	for i := range tooManyRows {
+		tooManyRows[i] = importRow(user1, password1)
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		query         string
+		contentType   string
+		body          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			body: jsonBody(importRow(user1, password1), importRow(user2, password2)),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ImportUsersTx(gomock.Any(), EqImportUsersTxParams(false, []db.User{user1, user2}, []string{password1, password2})).
+					Times(1).
+					Return(db.ImportUsersTxResult{Users: []db.User{user1, user2}, Errs: make([]error, 2)}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "hashed_password")
+
+				rsp := decodeImportUsersResponse(t, recorder)
+				require.False(t, rsp.RolledBack)
+				require.Equal(t, 2, rsp.Created)
+				require.Zero(t, rsp.Failed)
+				require.Len(t, rsp.Results, 2)
+				for i, user := range []db.User{user1, user2} {
+					require.Equal(t, i+1, rsp.Results[i].Row)
+					require.True(t, rsp.Results[i].Created)
+					require.Nil(t, rsp.Results[i].Error)
+					require.Equal(t, newUserResponse(user), *rsp.Results[i].User)
+				}
+			},
+		},
+		{
+			name: "duplicated user is skipped",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			body: jsonBody(importRow(user1, password1), importRow(user2, password2)),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ImportUsersTx(gomock.Any(), EqImportUsersTxParams(false, []db.User{user1, user2}, []string{password1, password2})).
+					Times(1).
+					Return(db.ImportUsersTxResult{
+						Users: []db.User{{}, user2},
+						Errs:  []error{&pq.Error{Code: "23505", Constraint: usersEmailConstraint}, nil},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeImportUsersResponse(t, recorder)
+				require.False(t, rsp.RolledBack)
+				require.Equal(t, 1, rsp.Created)
+				require.Equal(t, 1, rsp.Failed)
+				require.False(t, rsp.Results[0].Created)
+				require.Nil(t, rsp.Results[0].User)
+				require.Equal(t, codeEmailInUse, rsp.Results[0].Error.Code)
+				require.True(t, rsp.Results[1].Created)
+			},
+		},
+		{
+			name: "invalid row is skipped",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			body: jsonBody(
+				gin.H{"username": user1.Username, "password": password1, "full_name": user1.FullName, "email": "not-an-email"},
+				importRow(user2, "short"),
+				importRow(user2, password2),
+			),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ImportUsersTx(gomock.Any(), EqImportUsersTxParams(false, []db.User{user2}, []string{password2})).
+					Times(1).
+					Return(db.ImportUsersTxResult{Users: []db.User{user2}, Errs: make([]error, 1)}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeImportUsersResponse(t, recorder)
+				require.Equal(t, 1, rsp.Created)
+				require.Equal(t, 2, rsp.Failed)
+				require.Equal(t, codeInvalidRequest, rsp.Results[0].Error.Code)
+				require.NotEmpty(t, rsp.Results[0].Error.Details)
+				require.Equal(t, codeInvalidPassword, rsp.Results[1].Error.Code)
+				require.True(t, rsp.Results[2].Created)
+			},
+		},
+		{
+			name: "atomic import is rolled back",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: "?atomic=true",
+			body:  jsonBody(importRow(user1, password1), importRow(user2, password2)),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ImportUsersTx(gomock.Any(), EqImportUsersTxParams(true, []db.User{user1, user2}, []string{password1, password2})).
+					Times(1).
+					Return(db.ImportUsersTxResult{
+						Users:      make([]db.User, 2),
+						Errs:       []error{nil, &pq.Error{Code: "23505", Constraint: usersUsernameConstraint}},
+						RolledBack: true,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeImportUsersResponse(t, recorder)
+				require.True(t, rsp.Atomic)
+				require.True(t, rsp.RolledBack)
+				require.Zero(t, rsp.Created)
+				require.Equal(t, 1, rsp.Failed)
+				// the valid user isn't counted as a failure but it wasn't created either
+				require.False(t, rsp.Results[0].Created)
+				require.Equal(t, codeImportRolledBack, rsp.Results[0].Error.Code)
+				require.Equal(t, codeUsernameInUse, rsp.Results[1].Error.Code)
+			},
+		},
+		{
+			name: "atomic import with an invalid row",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			query: "?atomic=true",
+			body:  jsonBody(importRow(user1, password1), importRow(user2, "short")),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ImportUsersTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeImportUsersResponse(t, recorder)
+				require.True(t, rsp.RolledBack)
+				require.Zero(t, rsp.Created)
+				require.Equal(t, 1, rsp.Failed)
+				require.Equal(t, codeImportRolledBack, rsp.Results[0].Error.Code)
+				require.Equal(t, codeInvalidPassword, rsp.Results[1].Error.Code)
+			},
+		},
+		{
+			name: "csv",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			contentType: csvContentType,
+			// the columns may come in any order
+			body: fmt.Sprintf("email,username,full_name,password\n%v,%v,%v,%v\n%v,%v,%v,%v\n",
+				user1.Email, user1.Username, user1.FullName, password1,
+				user2.Email, user2.Username, user2.FullName, password2),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ImportUsersTx(gomock.Any(), EqImportUsersTxParams(false, []db.User{user1, user2}, []string{password1, password2})).
+					Times(1).
+					Return(db.ImportUsersTxResult{Users: []db.User{user1, user2}, Errs: make([]error, 2)}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				rsp := decodeImportUsersResponse(t, recorder)
+				require.Equal(t, 2, rsp.Created)
+				require.Equal(t, user1.Username, rsp.Results[0].UserName)
+				require.Equal(t, user2.Username, rsp.Results[1].UserName)
+			},
+		},
+		{
+			name: "csv missing column",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			contentType: csvContentType,
+			body:        fmt.Sprintf("username,password,full_name\n%v,%v,%v\n", user1.Username, password1, user1.FullName),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ImportUsersTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "empty import",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			body: "[]",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ImportUsersTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "too many users",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			body: jsonBody(tooManyRows...),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ImportUsersTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidRequest)
+			},
+		},
+		{
+			name: "depositor is forbidden",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, depositor.Username, depositor.Role, time.Minute)
+			},
+			body: jsonBody(importRow(user1, password1)),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ImportUsersTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, banker.Username, banker.Role, time.Minute)
+			},
+			body: jsonBody(importRow(user1, password1)),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ImportUsersTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ImportUsersTxResult{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			request, err := http.NewRequest(http.MethodPost, "/admin/users/import"+tc.query, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			if tc.contentType != "" {
+				request.Header.Set("Content-Type", tc.contentType)
+			}
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func decodeImportUsersResponse(t *testing.T, recorder *httptest.ResponseRecorder) importUsersResponse {
+	var rsp importUsersResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	return rsp
+}
+
+type eqImportUsersTxParamsMatcher struct {
+	atomic    bool
+	users     []db.User
+	passwords []string
+}
+
+// Matches compares the imported users field by field, the hashes are salted so they are checked against the passwords
+func (e eqImportUsersTxParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.ImportUsersTxParams)
+	if !ok || arg.Atomic != e.atomic || len(arg.Users) != len(e.users) {
+		return false
+	}
+
+	for i, user := range e.users {
+		got := arg.Users[i]
+		if got.Username != user.Username || got.FullName != user.FullName || got.Email != user.Email {
+			return false
+		}
+		if utils.CheckPassword(e.passwords[i], got.HashedPassword) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (e eqImportUsersTxParamsMatcher) String() string {
+	return fmt.Sprintf("matches atomic %v and users %v", e.atomic, e.users)
+}
+
+func EqImportUsersTxParams(atomic bool, users []db.User, passwords []string) gomock.Matcher {
+	return eqImportUsersTxParamsMatcher{
+		atomic:    atomic,
+		users:     users,
+		passwords: passwords,
+	}
+}
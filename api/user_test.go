@@ -2,17 +2,22 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
 	"github.com/micaelapucciariello/simplebank/token"
+	"github.com/micaelapucciariello/simplebank/worker"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -39,7 +44,7 @@ func TestGetUserAPI(t *testing.T) {
 		{
 			name: "happy path get user",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			username: user.Username,
 			buildStubs: func(store *mockdb.MockStore) {
@@ -54,10 +59,83 @@ func TestGetUserAPI(t *testing.T) {
 				validateResponseUser(t, recorder.Body, user)
 			},
 		},
+		{
+			name: "mixed case username",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			username: strings.ToUpper(user.Username),
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseUser(t, recorder.Body, user)
+			},
+		},
+		{
+			name: "banker of the same organization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, utils.RandomOwner(), utils.BankerRole, time.Minute)
+			},
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseUser(t, recorder.Body, user)
+			},
+		},
+		{
+			name: "another user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, utils.RandomOwner(), utils.DepositorRole, time.Minute)
+			},
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeForbidden)
+			},
+		},
+		{
+			name: "banker of another organization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addOrgAuthorization(t, request, tokenMaker, _authorizationTypeBearer, utils.RandomOwner(), utils.BankerRole, db.DefaultOrgID+1, time.Minute)
+			},
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireErrorCode(t, recorder, codeOtherOrganization)
+				require.NotContains(t, recorder.Body.String(), user.Email)
+			},
+		},
 		{
 			name: "user not found",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			username: user.Username,
 			buildStubs: func(store *mockdb.MockStore) {
@@ -74,14 +152,14 @@ func TestGetUserAPI(t *testing.T) {
 		{
 			name: "internal server error",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			username: user.Username,
 			buildStubs: func(store *mockdb.MockStore) {
 				// build stubs
 				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
 					Times(1).
-					Return(db.User{}, sql.ErrConnDone)
+					Return(db.User{}, sql.ErrTxDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				// check response
@@ -129,9 +207,15 @@ func TestCreateUserAPI(t *testing.T) {
 		{
 			name: "happy path create user",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			user: user,
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": user.FullName,
+				"email":     user.Email,
+				"password":  password,
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				// build stubs
 				arg := db.CreateUserParams{
@@ -139,6 +223,7 @@ func TestCreateUserAPI(t *testing.T) {
 					FullName:       user.FullName,
 					Email:          user.Email,
 					HashedPassword: user.HashedPassword,
+					OrgID:          db.DefaultOrgID,
 				}
 				store.EXPECT().CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
 					Times(1).
@@ -150,12 +235,48 @@ func TestCreateUserAPI(t *testing.T) {
 				validateResponseUser(t, recorder.Body, user)
 			},
 		},
+		{
+			name: "email stored lowercase",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			user: user,
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": user.FullName,
+				"email":     strings.ToUpper(user.Email),
+				"password":  password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				arg := db.CreateUserParams{
+					Username:       user.Username,
+					FullName:       user.FullName,
+					Email:          user.Email,
+					HashedPassword: user.HashedPassword,
+					OrgID:          db.DefaultOrgID,
+				}
+				store.EXPECT().CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
 		{
 			name: "internal server error",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
 			},
 			user: user,
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": user.FullName,
+				"email":     user.Email,
+				"password":  password,
+			},
 			buildStubs: func(store *mockdb.MockStore) {
 				// build stubs
 				arg := db.CreateUserParams{
@@ -163,16 +284,264 @@ func TestCreateUserAPI(t *testing.T) {
 					FullName:       user.FullName,
 					Email:          user.Email,
 					HashedPassword: user.HashedPassword,
+					OrgID:          db.DefaultOrgID,
 				}
 				store.EXPECT().CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
 					Times(1).
-					Return(db.User{}, sql.ErrConnDone)
+					Return(db.User{}, sql.ErrTxDone)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				// check response
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name: "password too short",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			user: user,
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": user.FullName,
+				"email":     user.Email,
+				"password":  "abc123",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), utils.ErrPasswordTooShort.Error())
+			},
+		},
+		{
+			name: "password without digit",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			user: user,
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": user.FullName,
+				"email":     user.Email,
+				"password":  utils.RandomString(utils.MinPasswordLength),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), utils.ErrPasswordNoDigit.Error())
+			},
+		},
+		{
+			name: "password without letter",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			user: user,
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": user.FullName,
+				"email":     user.Email,
+				"password":  "1234567890",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), utils.ErrPasswordNoLetter.Error())
+			},
+		},
+		{
+			name: "duplicated username",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			user: user,
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": user.FullName,
+				"email":     user.Email,
+				"password":  password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pq.Error{Code: "23505", Constraint: usersUsernameConstraint})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errUsernameInUse.Error())
+			},
+		},
+		{
+			name: "duplicated email",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			user: user,
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": user.FullName,
+				"email":     user.Email,
+				"password":  password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pq.Error{Code: "23505", Constraint: usersEmailConstraint})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errEmailInUse.Error())
+			},
+		},
+		{
+			name: "invalid email",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			user: user,
+			body: gin.H{
+				"username":  user.Username,
+				"full_name": user.FullName,
+				"email":     "invalid-email",
+				"password":  password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "username with invalid characters",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			user: user,
+			body: gin.H{
+				"username":  "alice-01",
+				"full_name": user.FullName,
+				"email":     user.Email,
+				"password":  password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				rsp := requireErrorCode(t, recorder, codeInvalidRequest)
+				require.Equal(t, []fieldError{{
+					Field:   "username",
+					Rule:    "username",
+					Message: "username is invalid, " + usernameRule,
+				}}, rsp.Details)
+			},
+		},
+		{
+			name: "username too short",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			user: user,
+			body: gin.H{
+				"username":  strings.Repeat("a", utils.MinUsernameLength-1),
+				"full_name": user.FullName,
+				"email":     user.Email,
+				"password":  password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				rsp := requireErrorCode(t, recorder, codeInvalidRequest)
+				require.Equal(t, []fieldError{{
+					Field:   "username",
+					Rule:    "username",
+					Message: "username is invalid, " + usernameRule,
+				}}, rsp.Details)
+			},
+		},
+		{
+			name: "username too long",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			user: user,
+			body: gin.H{
+				"username":  strings.Repeat("a", utils.MaxUsernameLength+1),
+				"full_name": user.FullName,
+				"email":     user.Email,
+				"password":  password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				rsp := requireErrorCode(t, recorder, codeInvalidRequest)
+				require.Equal(t, []fieldError{{
+					Field:   "username",
+					Rule:    "username",
+					Message: "username is invalid, " + usernameRule,
+				}}, rsp.Details)
+			},
+		},
+		{
+			name: "username stored lowercase",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			user: user,
+			body: gin.H{
+				"username":  strings.ToUpper(user.Username),
+				"full_name": user.FullName,
+				"email":     user.Email,
+				"password":  password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				arg := db.CreateUserParams{
+					Username:       user.Username,
+					FullName:       user.FullName,
+					Email:          user.Email,
+					HashedPassword: user.HashedPassword,
+					OrgID:          db.DefaultOrgID,
+				}
+				store.EXPECT().CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseUser(t, recorder.Body, user)
+			},
+		},
 	}
 
 	for i := range testCases {
@@ -189,11 +558,10 @@ func TestCreateUserAPI(t *testing.T) {
 
 			url := fmt.Sprintf("/users")
 
-			body := fmt.Sprintf(`{"username": "%v", "full_name": "%v", "email": "%v", "password": "%v"}`, user.Username, user.FullName, user.Email, password)
-			jsonBody := []byte(body)
-			bodyReader := bytes.NewReader(jsonBody)
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
 
-			request, err := http.NewRequest(http.MethodPost, url, bodyReader)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
 			// check request
 			require.NoError(t, err)
 
@@ -204,41 +572,64 @@ func TestCreateUserAPI(t *testing.T) {
 	}
 }
 
-func TestLoginUserAPI(t *testing.T) {
+func TestCreateUserWelcomeEmailTask(t *testing.T) {
 	user, password := randomUser()
 
+	body := gin.H{
+		"username":  user.Username,
+		"full_name": user.FullName,
+		"email":     user.Email,
+		"password":  password,
+	}
+
 	testCases := []struct {
 		name          string
-		body          gin.H
+		queueSize     int
 		buildStubs    func(store *mockdb.MockStore)
-		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, broker *worker.InMemoryBroker)
 	}{
 		{
-			name: "happy path login user",
+			name:      "happy path welcome email enqueued",
+			queueSize: 1,
 			buildStubs: func(store *mockdb.MockStore) {
-				// build stubs
-				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
-					Times(1).
-					Return(user, nil)
-				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
-					Times(1) // TODO: use payload
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
 			},
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, broker *worker.InMemoryBroker) {
 				// check response
 				require.Equal(t, http.StatusOK, recorder.Code)
+
+				task, err := dequeueTestTask(broker)
+				require.NoError(t, err)
+				require.Equal(t, worker.TaskSendWelcomeEmail, task.Type)
+
+				var payload worker.PayloadSendWelcomeEmail
+				require.NoError(t, json.Unmarshal(task.Payload, &payload))
+				require.Equal(t, user.Username, payload.Username)
 			},
 		},
 		{
-			name: "internal server error",
+			name:      "queue full doesn't fail the registration",
+			queueSize: 0,
 			buildStubs: func(store *mockdb.MockStore) {
-				// build stubs
-				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
-					Times(1).
-					Return(db.User{}, sql.ErrConnDone)
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
 			},
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, broker *worker.InMemoryBroker) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "no task when the user isn't created",
+			queueSize: 1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, broker *worker.InMemoryBroker) {
 				// check response
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+
+				_, err := dequeueTestTask(broker)
+				require.ErrorIs(t, err, context.DeadlineExceeded)
 			},
 		},
 	}
@@ -254,46 +645,927 @@ func TestLoginUserAPI(t *testing.T) {
 
 			recorder := httptest.NewRecorder()
 			server := newTestServer(t, store)
+			broker := worker.NewInMemoryBroker(tc.queueSize)
+			server.taskDistributor = worker.NewTaskDistributor(broker)
 
-			url := fmt.Sprintf("/users/login")
-
-			body := fmt.Sprintf(`{"username": "%v", "password": "%v"}`, user.Username, password)
-			jsonBody := []byte(body)
-			bodyReader := bytes.NewReader(jsonBody)
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
 
-			request, err := http.NewRequest(http.MethodPost, url, bodyReader)
-			// check request
+			request, err := http.NewRequest(http.MethodPost, "/users", bytes.NewReader(data))
 			require.NoError(t, err)
 
 			server.router.ServeHTTP(recorder, request)
-			tc.checkResponse(t, recorder)
+			tc.checkResponse(t, recorder, broker)
 		})
 	}
 }
 
-func randomUser() (db.User, string) {
-	password := utils.RandomString(10)
-	hashedPassword, _ := utils.HashPassword(password)
-	user := db.User{
-		Username:       utils.RandomOwner(),
-		HashedPassword: hashedPassword,
-		FullName:       utils.RandomOwner(),
-		Email:          utils.RandomEmail(),
-	}
+// dequeueTestTask returns the first queued task, the tasks are enqueued before the response is written so none is expected later
+func dequeueTestTask(broker *worker.InMemoryBroker) (worker.Task, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
 
-	return user, password
+	return broker.Dequeue(ctx)
 }
 
-func validateResponseUser(t *testing.T, body *bytes.Buffer, user db.User) {
-	data, err := ioutil.ReadAll(body)
-	require.NoError(t, err)
+func TestUpdateUserAPI(t *testing.T) {
+	user, _ := randomUser()
+	otherUser, _ := randomUser()
+	newFullName := utils.RandomOwner()
+	newEmail := utils.RandomEmail()
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		username      string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path update full name only",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			username: user.Username,
+			body: gin.H{
+				"full_name": newFullName,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				arg := db.UpdateUserParams{
+					Username: user.Username,
+					FullName: sql.NullString{String: newFullName, Valid: true},
+				}
+				updatedUser := user
+				updatedUser.FullName = newFullName
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(updatedUser, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				updatedUser := user
+				updatedUser.FullName = newFullName
+				validateResponseUser(t, recorder.Body, updatedUser)
+			},
+		},
+		{
+			name: "mixed case username",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			username: strings.ToUpper(user.Username),
+			body: gin.H{
+				"full_name": newFullName,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				arg := db.UpdateUserParams{
+					Username: user.Username,
+					FullName: sql.NullString{String: newFullName, Valid: true},
+				}
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "happy path update email only",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			username: user.Username,
+			body: gin.H{
+				"email": newEmail,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				arg := db.UpdateUserParams{
+					Username: user.Username,
+					Email:    sql.NullString{String: newEmail, Valid: true},
+				}
+				updatedUser := user
+				updatedUser.Email = newEmail
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(updatedUser, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				updatedUser := user
+				updatedUser.Email = newEmail
+				validateResponseUser(t, recorder.Body, updatedUser)
+			},
+		},
+		{
+			name: "no fields to update",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			username: user.Username,
+			body:     gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "invalid email",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			username: user.Username,
+			body: gin.H{
+				"email": "invalid-email",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "user doesn't belong to the authenticated user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, otherUser.Username, utils.DepositorRole, time.Minute)
+			},
+			username: user.Username,
+			body: gin.H{
+				"full_name": newFullName,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "no authorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			username: user.Username,
+			body: gin.H{
+				"full_name": newFullName,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "user not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			username: user.Username,
+			body: gin.H{
+				"full_name": newFullName,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "email already in use",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			username: user.Username,
+			body: gin.H{
+				"email": newEmail,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pq.Error{Code: "23505", Constraint: usersEmailConstraint})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errEmailInUse.Error())
+			},
+		},
+		{
+			name: "internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			username: user.Username,
+			body: gin.H{
+				"full_name": newFullName,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/users/%v", tc.username)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := randomUser()
+	otherUser, _ := randomUser()
+	newPassword := utils.RandomPassword()
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		username      string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path change password",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+				addStepUpToken(t, request, tokenMaker, token.KindStepUp, user.Username)
+			},
+			username: user.Username,
+			body: gin.H{
+				"current_password": password,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ChangePasswordTxParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, utils.CheckPassword(newPassword, arg.HashedPassword))
+						// the change is audited as made by the authenticated user
+						require.Equal(t, user.Username, arg.Actor.Username)
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				validateResponseUser(t, recorder.Body, user)
+			},
+		},
+		{
+			name: "mixed case username",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+				addStepUpToken(t, request, tokenMaker, token.KindStepUp, user.Username)
+			},
+			username: strings.ToUpper(user.Username),
+			body: gin.H{
+				"current_password": password,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "wrong current password",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+				addStepUpToken(t, request, tokenMaker, token.KindStepUp, user.Username)
+			},
+			username: user.Username,
+			body: gin.H{
+				"current_password": utils.RandomPassword(),
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "missing step-up token",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+			},
+			username: user.Username,
+			body: gin.H{
+				"current_password": password,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireErrorCode(t, recorder, codeStepUpRequired)
+			},
+		},
+		{
+			name: "access token as step-up token",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+				addStepUpToken(t, request, tokenMaker, token.KindAccess, user.Username)
+			},
+			username: user.Username,
+			body: gin.H{
+				"current_password": password,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidToken)
+			},
+		},
+		{
+			name: "step-up token of another user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+				addStepUpToken(t, request, tokenMaker, token.KindStepUp, otherUser.Username)
+			},
+			username: user.Username,
+			body: gin.H{
+				"current_password": password,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireErrorCode(t, recorder, codeInvalidToken)
+			},
+		},
+		{
+			name: "weak new password",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+				addStepUpToken(t, request, tokenMaker, token.KindStepUp, user.Username)
+			},
+			username: user.Username,
+			body: gin.H{
+				"current_password": password,
+				"new_password":     "abc",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "user doesn't belong to the authenticated user",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, otherUser.Username, utils.DepositorRole, time.Minute)
+				addStepUpToken(t, request, tokenMaker, token.KindStepUp, otherUser.Username)
+			},
+			username: user.Username,
+			body: gin.H{
+				"current_password": password,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "user not found",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+				addStepUpToken(t, request, tokenMaker, token.KindStepUp, user.Username)
+			},
+			username: user.Username,
+			body: gin.H{
+				"current_password": password,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "internal server error",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, _authorizationTypeBearer, user.Username, utils.DepositorRole, time.Minute)
+				addStepUpToken(t, request, tokenMaker, token.KindStepUp, user.Username)
+			},
+			username: user.Username,
+			body: gin.H{
+				"current_password": password,
+				"new_password":     newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/users/%v/change_password", tc.username)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.token)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserAPI(t *testing.T) {
+	user, password := randomUser()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "happy path login user",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.LoginTxParams) (db.Session, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.True(t, arg.ExpiresAt.Valid)
+						return db.Session{ID: arg.ID, Username: arg.Username, RefreshToken: arg.RefreshToken}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "hashed_password")
+				require.NotContains(t, recorder.Body.String(), user.HashedPassword)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+				require.NotZero(t, rsp.SessionID)
+				require.True(t, rsp.RefreshTokenExpiresAt.After(rsp.AccessTokenExpiresAt))
+				require.Equal(t, user.Username, rsp.UserMetadata.UserName)
+			},
+		},
+		{
+			name: "login by email",
+			body: gin.H{
+				"username": user.Email,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.LoginTxParams) (db.Session, error) {
+						require.Equal(t, user.Username, arg.Username)
+						return db.Session{ID: arg.ID, Username: arg.Username, RefreshToken: arg.RefreshToken}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.UserMetadata.UserName)
+			},
+		},
+		{
+			name: "mixed case email resolves to the same user",
+			body: gin.H{
+				"username": " " + strings.ToUpper(user.Email[:3]) + user.Email[3:],
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.LoginTxParams) (db.Session, error) {
+						return db.Session{ID: arg.ID, Username: arg.Username, RefreshToken: arg.RefreshToken}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "mixed case username resolves to the same user",
+			body: gin.H{
+				"username": strings.ToUpper(user.Username),
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.LoginTxParams) (db.Session, error) {
+						require.Equal(t, user.Username, arg.Username)
+						return db.Session{ID: arg.ID, Username: arg.Username, RefreshToken: arg.RefreshToken}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "email not found",
+			body: gin.H{
+				"username": user.Email,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireErrorCode(t, recorder, codeUserNotFound)
+			},
+		},
+		{
+			name: "user not found",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "wrong password",
+			body: gin.H{
+				"username": user.Username,
+				"password": "wrong_password",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "locked user",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				lockedUser := user
+				lockedUser.LockedUntil = sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true}
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(lockedUser, nil)
+				store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusLocked, recorder.Code)
+			},
+		},
+		{
+			name: "invalid request",
+			body: gin.H{
+				"username": user.Username,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "internal server error",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, sql.ErrTxDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// check response
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			recorder := httptest.NewRecorder()
+			server := newTestServer(t, store)
+
+			url := fmt.Sprintf("/users/login")
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			// check request
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserLockoutAPI(t *testing.T) {
+	user, password := randomUser()
+	maxAttempts := int32(3)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	// the stubs keep the lockout state of the user the same way the queries do
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		AnyTimes().
+		DoAndReturn(func(_ interface{}, _ string) (db.User, error) {
+			return user, nil
+		})
+	store.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).
+		Times(int(maxAttempts)).
+		DoAndReturn(func(_ interface{}, arg db.RecordFailedLoginParams) (db.User, error) {
+			require.Equal(t, maxAttempts, arg.MaxAttempts)
+			user.FailedLoginAttempts++
+			if user.FailedLoginAttempts >= arg.MaxAttempts {
+				user.FailedLoginAttempts = 0
+				user.LockedUntil = sql.NullTime{Time: arg.LockedUntil, Valid: true}
+			}
+			return user, nil
+		})
+	store.EXPECT().ResetFailedLogins(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		DoAndReturn(func(_ interface{}, _ string) error {
+			user.FailedLoginAttempts = 0
+			user.LockedUntil = sql.NullTime{}
+			return nil
+		})
+	store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.LoginTxParams) (db.Session, error) {
+			return db.Session{ID: arg.ID, Username: arg.Username, RefreshToken: arg.RefreshToken}, nil
+		})
+
+	server := newTestServer(t, store)
+	server.config.LoginMaxAttempts = maxAttempts
+	server.config.LoginLockoutDuration = time.Minute
+
+	login := func(password string) *httptest.ResponseRecorder {
+		data, err := json.Marshal(gin.H{
+			"username": user.Username,
+			"password": password,
+		})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+		require.NoError(t, err)
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for i := int32(1); i < maxAttempts; i++ {
+		recorder := login("wrong_password")
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	}
+
+	// the last allowed failure locks the account
+	recorder := login("wrong_password")
+	require.Equal(t, http.StatusLocked, recorder.Code)
+	require.True(t, user.LockedUntil.Valid)
+
+	// while locked even the right password is rejected, and the failures are no longer counted
+	recorder = login(password)
+	require.Equal(t, http.StatusLocked, recorder.Code)
+
+	// once the lockout expires the user can log in again and the counter is reset
+	user.LockedUntil.Time = time.Now().Add(-time.Second)
+	recorder = login(password)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Zero(t, user.FailedLoginAttempts)
+	require.False(t, user.LockedUntil.Valid)
+}
+
+func randomUser() (db.User, string) {
+	password := utils.RandomPassword()
+	hashedPassword, _ := utils.HashPassword(password, bcrypt.DefaultCost)
+	user := db.User{
+		Username:          utils.RandomOwner(),
+		HashedPassword:    hashedPassword,
+		FullName:          utils.RandomOwner(),
+		Email:             utils.RandomEmail(),
+		Role:              utils.DepositorRole,
+		PasswordChangedAt: time.Now().UTC().Truncate(time.Second),
+		CreatedAt:         sql.NullTime{Time: time.Now().UTC().Truncate(time.Second), Valid: true},
+		OrgID:             db.DefaultOrgID,
+	}
+
+	return user, password
+}
+
+func validateResponseUser(t *testing.T, body *bytes.Buffer, user db.User) {
+	data, err := ioutil.ReadAll(body)
+	require.NoError(t, err)
+
+	// the hashed password must never reach the client
+	require.NotContains(t, string(data), "hashed_password")
+	require.NotContains(t, string(data), user.HashedPassword)
 
-	var rspUser db.User
+	var rspUser userResponse
 	err = json.Unmarshal(data, &rspUser)
 	require.NoError(t, err)
-	require.Equal(t, user.Username, rspUser.Username)
-	require.Equal(t, user.Email, rspUser.Email)
-	require.Equal(t, user.FullName, rspUser.FullName)
+	require.Equal(t, newUserResponse(user), rspUser)
 }
 
 func (e eqCreateUserParamsMatcher) Matches(x interface{}) bool {
@@ -1,14 +1,101 @@
 package api
 
 import (
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/micaelapucciariello/simplebank/utils"
+	"reflect"
+	"strings"
 )
 
 // build a validator to test different currencies in a more scalable way
 var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 	if currency, ok := fieldLevel.Field().Interface().(string); ok {
-		return utils.IsSupported(currency)
+		return utils.IsSupportedCurrency(currency)
 	}
 	return false
 }
+
+// usernameRule explains the username validator in the error responses
+var usernameRule = fmt.Sprintf("a username is %d to %d letters, digits or underscores", utils.MinUsernameLength, utils.MaxUsernameLength)
+
+// validUsername accepts the usernames utils.ValidateUsername does, whatever their case: handlers normalize them before using them
+var validUsername validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if username, ok := fieldLevel.Field().Interface().(string); ok {
+		return utils.ValidateUsername(username) == nil
+	}
+	return false
+}
+
+// fieldError tells which field of the request broke which validation rule, clients use it to highlight the bad fields
+type fieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// fieldName names the fields after the key the client sent them with instead of the go field name
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		if name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// translateValidationErrors lists the broken rules in the order of the request fields
+func translateValidationErrors(validationErrs validator.ValidationErrors) []fieldError {
+	fieldErrs := make([]fieldError, len(validationErrs))
+	for i, fieldErr := range validationErrs {
+		// the namespace starts with the request struct, dropping it keeps the path of a nested field like splits[0].amount
+		field := fieldErr.Namespace()
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+
+		fieldErrs[i] = fieldError{
+			Field:   field,
+			Rule:    fieldErr.Tag(),
+			Message: field + " " + validationMessage(fieldErr),
+		}
+	}
+	return fieldErrs
+}
+
+// validationMessage explains the broken rule, the bounds are read as a length for strings and lists and as a value otherwise
+func validationMessage(fieldErr validator.FieldError) string {
+	var unit string
+	switch fieldErr.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %v%v", fieldErr.Param(), unit)
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %v%v", fieldErr.Param(), unit)
+	case "gt":
+		return fmt.Sprintf("must be greater than %v%v", fieldErr.Param(), unit)
+	case "lt":
+		return fmt.Sprintf("must be less than %v%v", fieldErr.Param(), unit)
+	case "len":
+		return fmt.Sprintf("must be exactly %v%v", fieldErr.Param(), unit)
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fieldErr.Param()), ", ")
+	case "email":
+		return "must be a valid email"
+	case "alphanum":
+		return "must only hold letters and digits"
+	case "currency":
+		return "must be a supported currency"
+	case "username":
+		return "is invalid, " + usernameRule
+	}
+	return fmt.Sprintf("failed on the %v rule", fieldErr.Tag())
+}
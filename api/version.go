@@ -0,0 +1,11 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// version reports the build of the running server, so ops can confirm what's deployed
+func (s *Server) version(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, s.buildInfo)
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+)
+
+func TestVersionAPI(t *testing.T) {
+	version, gitCommit, buildTime := utils.Version, utils.GitCommit, utils.BuildTime
+	utils.Version, utils.GitCommit, utils.BuildTime = "v1.2.3", "0123abcd", "2024-03-01T12:00:00Z"
+	t.Cleanup(func() {
+		utils.Version, utils.GitCommit, utils.BuildTime = version, gitCommit, buildTime
+	})
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	recorder := httptest.NewRecorder()
+	server := newTestServer(t, store)
+
+	// no authorization is needed
+	request, err := http.NewRequest(http.MethodGet, "/version", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp map[string]string
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+	require.Equal(t, map[string]string{
+		"version":    "v1.2.3",
+		"git_commit": "0123abcd",
+		"build_time": "2024-03-01T12:00:00Z",
+		"go_version": runtime.Version(),
+	}, rsp)
+}
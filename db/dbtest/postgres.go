@@ -0,0 +1,91 @@
+//go:build integration
+
+// Package dbtest starts the throwaway postgres the integration tests run against, it needs a docker daemon
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/micaelapucciariello/simplebank/db/migration"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"net/url"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+const (
+	// image is the postgres of the make postgres target, so the tests run against the version the server uses
+	image    = "postgres:12-alpine"
+	database = "simple_bank"
+	user     = "root"
+	password = "secret"
+
+	startupTimeout = time.Minute
+)
+
+// Postgres is a postgres container with every migration applied, meant to be shared by the tests of a package
+type Postgres struct {
+	container *postgres.PostgresContainer
+	// URL is the connection string of the database, it goes to sql.Open as is
+	URL string
+}
+
+// StartPostgres starts a new postgres container and migrates its database up to the latest version
+func StartPostgres(ctx context.Context) (*Postgres, error) {
+	container, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage(image),
+		postgres.WithDatabase(database),
+		postgres.WithUsername(user),
+		postgres.WithPassword(password),
+		// postgres logs it twice, the first time before the init restart
+		testcontainers.WithWaitStrategy(wait.ForLog("database system is ready to accept connections").
+			WithOccurrence(2).
+			WithStartupTimeout(startupTimeout)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start postgres container: %w", err)
+	}
+	p := &Postgres{container: container}
+
+	p.URL, err = container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		_ = p.Terminate(ctx)
+		return nil, fmt.Errorf("cannot get postgres connection string: %w", err)
+	}
+
+	if err = migration.Up(p.URL); err != nil {
+		_ = p.Terminate(ctx)
+		return nil, err
+	}
+	return p, nil
+}
+
+// CreateDatabase creates an empty database in the container, no migration ran on it, and returns its connection string
+func (p *Postgres) CreateDatabase(ctx context.Context, name string) (string, error) {
+	conn, err := sql.Open("postgres", p.URL)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// the name can't be a query parameter, it's quoted as an identifier instead
+	if _, err = conn.ExecContext(ctx, fmt.Sprintf(`CREATE DATABASE "%s"`, name)); err != nil {
+		return "", fmt.Errorf("cannot create database %v: %w", name, err)
+	}
+
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return "", err
+	}
+	u.Path = "/" + name
+	return u.String(), nil
+}
+
+// Terminate stops and removes the container along with its data
+func (p *Postgres) Terminate(ctx context.Context) error {
+	return p.container.Terminate(ctx)
+}
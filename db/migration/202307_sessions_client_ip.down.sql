@@ -0,0 +1 @@
+ALTER TABLE "sessions" ADD CONSTRAINT "sessions_client_ip_key" UNIQUE ("client_ip");
@@ -0,0 +1,2 @@
+-- a user can hold several sessions from the same client, so the client ip can't be unique
+ALTER TABLE "sessions" DROP CONSTRAINT IF EXISTS "sessions_client_ip_key";
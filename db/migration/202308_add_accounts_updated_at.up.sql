@@ -0,0 +1 @@
+ALTER TABLE "accounts" ADD COLUMN "updated_at" timestamp DEFAULT (now());
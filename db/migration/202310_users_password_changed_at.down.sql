@@ -0,0 +1,3 @@
+ALTER TABLE "users" ALTER COLUMN "password_changed_at" DROP DEFAULT;
+ALTER TABLE "users" ALTER COLUMN "password_changed_at" TYPE varchar USING "password_changed_at"::varchar;
+ALTER TABLE "users" ALTER COLUMN "password_changed_at" SET DEFAULT (now());
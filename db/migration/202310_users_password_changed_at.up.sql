@@ -0,0 +1,4 @@
+-- password_changed_at is compared against the token payloads, so it has to be a real timestamp
+ALTER TABLE "users" ALTER COLUMN "password_changed_at" DROP DEFAULT;
+ALTER TABLE "users" ALTER COLUMN "password_changed_at" TYPE timestamptz USING "password_changed_at"::timestamptz;
+ALTER TABLE "users" ALTER COLUMN "password_changed_at" SET DEFAULT (now());
@@ -0,0 +1,2 @@
+-- every existing user is a depositor, bankers have to be promoted explicitly
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'depositor';
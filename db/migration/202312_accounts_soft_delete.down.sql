@@ -0,0 +1,4 @@
+DROP INDEX "owner_currency_key";
+ALTER TABLE "accounts" ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
+
+ALTER TABLE "accounts" DROP COLUMN "deleted_at";
@@ -0,0 +1,6 @@
+-- deleted accounts are kept for the audit history, deleted_at marks them as gone
+ALTER TABLE "accounts" ADD COLUMN "deleted_at" timestamp;
+
+-- a deleted account must not keep its owner from opening a new one in the same currency
+ALTER TABLE "accounts" DROP CONSTRAINT "owner_currency_key";
+CREATE UNIQUE INDEX "owner_currency_key" ON "accounts" ("owner", "currency") WHERE "deleted_at" IS NULL;
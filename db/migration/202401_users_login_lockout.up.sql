@@ -0,0 +1,3 @@
+-- consecutive failed logins, an account is locked for a while once they reach the configured limit
+ALTER TABLE "users" ADD COLUMN "failed_login_attempts" integer NOT NULL DEFAULT 0;
+ALTER TABLE "users" ADD COLUMN "locked_until" timestamptz;
@@ -0,0 +1,2 @@
+ALTER TABLE "transfers" DROP COLUMN "reversed_at";
+ALTER TABLE "transfers" DROP COLUMN "reversed_from";
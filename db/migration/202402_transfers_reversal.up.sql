@@ -0,0 +1,4 @@
+-- a reversal is a compensating transfer pointing to the one it reverses, at most one per transfer
+ALTER TABLE "transfers" ADD COLUMN "reversed_from" bigint UNIQUE REFERENCES "transfers" ("id");
+-- set on the original transfer once it has been reversed
+ALTER TABLE "transfers" ADD COLUMN "reversed_at" timestamp;
@@ -0,0 +1,11 @@
+-- events are written in the same transaction as the change they describe and published afterwards by a poller
+CREATE TABLE "outbox"
+(
+    "id"           bigserial PRIMARY KEY,
+    "event_type"   varchar     NOT NULL,
+    "payload"      jsonb       NOT NULL,
+    "created_at"   timestamptz NOT NULL DEFAULT (now()),
+    "published_at" timestamptz
+);
+
+CREATE INDEX ON "outbox" ("id") WHERE "published_at" IS NULL;
@@ -0,0 +1,10 @@
+-- the closing balance of an account at the end of a UTC day, so the balance history doesn't need to replay the entries
+CREATE TABLE "balance_snapshots"
+(
+    "id"         bigserial PRIMARY KEY,
+    "account_id" bigint      NOT NULL REFERENCES "accounts" ("id"),
+    "day"        date        NOT NULL,
+    "balance"    bigint      NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    UNIQUE ("account_id", "day")
+);
@@ -0,0 +1,8 @@
+-- the amount every user sent on a UTC day, it backs the daily transfer limit
+CREATE TABLE "daily_transfer_totals"
+(
+    "username" varchar NOT NULL REFERENCES "users" ("username"),
+    "day"      date    NOT NULL,
+    "total"    bigint  NOT NULL,
+    PRIMARY KEY ("username", "day")
+);
@@ -0,0 +1,2 @@
+-- a frozen account is under a compliance hold, it can neither send nor receive transfers
+ALTER TABLE "accounts" ADD COLUMN "is_frozen" boolean NOT NULL DEFAULT false;
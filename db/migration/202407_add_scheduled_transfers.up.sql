@@ -0,0 +1,20 @@
+-- a transfer repeated every interval_seconds, the runner executes it once next_run_at is due
+CREATE TABLE "scheduled_transfers"
+(
+    "id"               bigserial PRIMARY KEY,
+    "from_account_id"  bigint      NOT NULL REFERENCES "accounts" ("id"),
+    "to_account_id"    bigint      NOT NULL REFERENCES "accounts" ("id"),
+    "amount"           bigint      NOT NULL CHECK ("amount" > 0),
+    "interval_seconds" bigint      NOT NULL CHECK ("interval_seconds" > 0),
+    "next_run_at"      timestamptz NOT NULL,
+    "last_run_at"      timestamptz,
+    "last_transfer_id" bigint REFERENCES "transfers" ("id"),
+    -- the error of the last run and how many runs failed in a row, both are reset by a successful run
+    "last_error"       varchar,
+    "failed_runs"      integer     NOT NULL DEFAULT 0,
+    "cancelled_at"     timestamptz,
+    "created_at"       timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "scheduled_transfers" ("from_account_id");
+CREATE INDEX ON "scheduled_transfers" ("next_run_at") WHERE "cancelled_at" IS NULL;
@@ -0,0 +1,2 @@
+-- bumped by every update of the account, clients send back the version they read so a stale read-modify-write is rejected
+ALTER TABLE "accounts" ADD COLUMN "version" bigint NOT NULL DEFAULT 1;
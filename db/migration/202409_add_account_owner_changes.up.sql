@@ -0,0 +1,12 @@
+-- every change of an account owner, kept so a transferred account can always be traced back to its previous owners
+CREATE TABLE "account_owner_changes"
+(
+    "id"             bigserial PRIMARY KEY,
+    "account_id"     bigint      NOT NULL REFERENCES "accounts" ("id"),
+    "previous_owner" varchar     NOT NULL REFERENCES "users" ("username"),
+    "new_owner"      varchar     NOT NULL REFERENCES "users" ("username"),
+    "changed_by"     varchar     NOT NULL REFERENCES "users" ("username"),
+    "created_at"     timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "account_owner_changes" ("account_id");
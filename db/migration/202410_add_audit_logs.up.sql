@@ -0,0 +1,15 @@
+-- who did what to which entity and from where, for the sensitive operations. Users may be deleted, their audit trail is kept
+CREATE TABLE "audit_logs"
+(
+    "id"          bigserial PRIMARY KEY,
+    "username"    varchar     NOT NULL,
+    "action"      varchar     NOT NULL,
+    "entity_type" varchar     NOT NULL,
+    "entity_id"   varchar     NOT NULL,
+    "client_ip"   varchar     NOT NULL,
+    "created_at"  timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "audit_logs" ("username", "created_at");
+
+CREATE INDEX ON "audit_logs" ("created_at");
@@ -0,0 +1,20 @@
+-- two-phase transfers: a hold reserves funds of the from account until it is captured, voided or expires
+-- held_balance is the sum of the active holds of the account, its available balance is balance - held_balance
+ALTER TABLE "accounts" ADD COLUMN "held_balance" bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE "holds"
+(
+    "id"              bigserial PRIMARY KEY,
+    "from_account_id" bigint      NOT NULL REFERENCES "accounts" ("id"),
+    "to_account_id"   bigint      NOT NULL REFERENCES "accounts" ("id"),
+    "amount"          bigint      NOT NULL CHECK ("amount" > 0),
+    -- authorized until it is captured, voided or expired
+    "status"          varchar     NOT NULL DEFAULT 'authorized',
+    "transfer_id"     bigint REFERENCES "transfers" ("id"),
+    "expires_at"      timestamptz NOT NULL,
+    "created_at"      timestamptz NOT NULL DEFAULT (now()),
+    "updated_at"      timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "holds" ("from_account_id");
+CREATE INDEX ON "holds" ("expires_at") WHERE "status" = 'authorized';
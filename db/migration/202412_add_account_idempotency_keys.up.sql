@@ -0,0 +1,10 @@
+-- idempotent account creation: the account created for a key is returned again when the key is repeated
+CREATE TABLE "account_idempotency_keys"
+(
+    "owner"        varchar     NOT NULL REFERENCES "users" ("username"),
+    "key"          varchar     NOT NULL,
+    "request_hash" varchar     NOT NULL,
+    "account_id"   bigint      NOT NULL REFERENCES "accounts" ("id"),
+    "created_at"   timestamptz NOT NULL DEFAULT (now()),
+    PRIMARY KEY ("owner", "key")
+);
@@ -0,0 +1 @@
+-- the original case of the emails isn't kept, there is nothing to revert
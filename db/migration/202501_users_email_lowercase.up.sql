@@ -0,0 +1,4 @@
+-- emails are stored lowercase so they can be looked up exactly, this fails if two users only differ by the case of their email
+UPDATE "users"
+SET "email" = lower("email")
+WHERE "email" <> lower("email");
@@ -0,0 +1,2 @@
+-- an optional memo the sender labels the transfer with
+ALTER TABLE "transfers" ADD COLUMN "description" varchar(140);
@@ -0,0 +1,3 @@
+ALTER TABLE "accounts" ADD COLUMN "labels" text[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX ON "accounts" USING GIN ("labels");
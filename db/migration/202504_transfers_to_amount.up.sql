@@ -0,0 +1,6 @@
+-- the amount credited to the receiver, in its account currency. It differs from amount on cross-currency transfers
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+
+UPDATE "transfers" SET "to_amount" = "amount";
+
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE "accounts" ADD COLUMN "account_number" varchar(16);
+
+-- the existing accounts get a random number too, a collision fails the unique constraint below and the migration is simply run again
+UPDATE "accounts" SET "account_number" = lpad(floor(random() * 1e16)::bigint::text, 16, '0');
+
+ALTER TABLE "accounts" ALTER COLUMN "account_number" SET NOT NULL;
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_account_number_key" UNIQUE ("account_number");
@@ -0,0 +1,7 @@
+ALTER TABLE "transfers" ADD COLUMN "uuid" uuid;
+
+-- gen_random_uuid needs pgcrypto on postgres 12, a random md5 is as good for the transfers made until now
+UPDATE "transfers" SET "uuid" = md5(random()::text || "id"::text)::uuid;
+
+ALTER TABLE "transfers" ALTER COLUMN "uuid" SET NOT NULL;
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_uuid_key" UNIQUE ("uuid");
@@ -0,0 +1,3 @@
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "org_id";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "org_id";
+DROP TABLE IF EXISTS "organizations";
@@ -0,0 +1,20 @@
+CREATE TABLE "organizations"
+(
+    "id"         bigserial PRIMARY KEY,
+    "name"       varchar UNIQUE NOT NULL,
+    "created_at" timestamptz    NOT NULL DEFAULT (now())
+);
+
+-- the users and accounts created before organizations all belong to the default one, see db.DefaultOrgID
+INSERT INTO "organizations" ("id", "name") VALUES (1, 'default');
+SELECT setval('organizations_id_seq', 1);
+
+ALTER TABLE "users" ADD COLUMN "org_id" bigint NOT NULL DEFAULT 1 REFERENCES "organizations" ("id");
+ALTER TABLE "accounts" ADD COLUMN "org_id" bigint NOT NULL DEFAULT 1 REFERENCES "organizations" ("id");
+
+-- from now on every insert names its organization, a forgotten one must fail instead of landing in the default tenant
+ALTER TABLE "users" ALTER COLUMN "org_id" DROP DEFAULT;
+ALTER TABLE "accounts" ALTER COLUMN "org_id" DROP DEFAULT;
+
+CREATE INDEX ON "users" ("org_id");
+CREATE INDEX ON "accounts" ("org_id");
@@ -0,0 +1,13 @@
+-- a reset is requested with the user email and redeemed with the token emailed to it, once and before it expires
+CREATE TABLE "password_resets"
+(
+    "id"         bigserial PRIMARY KEY,
+    "username"   varchar        NOT NULL REFERENCES "users" ("username"),
+    -- the sha256 of the token, the token itself is only known to the email it was sent to
+    "token_hash" varchar UNIQUE NOT NULL,
+    "expires_at" timestamptz    NOT NULL,
+    "used_at"    timestamptz,
+    "created_at" timestamptz    NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "password_resets" ("username");
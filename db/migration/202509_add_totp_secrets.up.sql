@@ -0,0 +1,9 @@
+-- a user enrolls in two-factor authentication by verifying a code of the secret, only then is it enabled
+CREATE TABLE "totp_secrets"
+(
+    "username"         varchar PRIMARY KEY REFERENCES "users" ("username"),
+    -- the secret is encrypted with TOTP_ENCRYPTION_KEY, a database dump alone doesn't give the codes away
+    "encrypted_secret" bytea       NOT NULL,
+    "enabled_at"       timestamptz,
+    "created_at"       timestamptz NOT NULL DEFAULT (now())
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "transfers_from_account_id_created_at_idx";
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "monthly_limit";
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "daily_limit";
@@ -0,0 +1,6 @@
+-- the outgoing amounts an account can send per UTC day and per UTC month, 0 means no limit
+ALTER TABLE "accounts" ADD COLUMN "daily_limit" bigint NOT NULL DEFAULT 0 CHECK ("daily_limit" >= 0);
+ALTER TABLE "accounts" ADD COLUMN "monthly_limit" bigint NOT NULL DEFAULT 0 CHECK ("monthly_limit" >= 0);
+
+-- the limits are enforced by summing the recent outgoing transfers of the account
+CREATE INDEX ON "transfers" ("from_account_id", "created_at");
@@ -0,0 +1,10 @@
+-- a user without a row gets every notification, the row only exists once the user changed a preference
+CREATE TABLE "notification_preferences"
+(
+    "username"                  varchar PRIMARY KEY REFERENCES "users" ("username"),
+    "incoming_transfer_email"   boolean     NOT NULL DEFAULT true,
+    "incoming_transfer_webhook" boolean     NOT NULL DEFAULT true,
+    "low_balance_email"         boolean     NOT NULL DEFAULT true,
+    "low_balance_webhook"       boolean     NOT NULL DEFAULT true,
+    "updated_at"                timestamptz NOT NULL DEFAULT (now())
+);
@@ -0,0 +1,2 @@
+-- a transfer taking the balance below the threshold alerts the owner, 0 means no alert
+ALTER TABLE "accounts" ADD COLUMN "low_balance_threshold" bigint NOT NULL DEFAULT 0 CHECK ("low_balance_threshold" >= 0);
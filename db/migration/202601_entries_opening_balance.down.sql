@@ -0,0 +1,2 @@
+-- the backfilled opening entries are kept, they match the account balances
+DROP INDEX IF EXISTS "entries_account_id_created_at_idx";
@@ -0,0 +1,12 @@
+-- every balance change records an entry, so the statement running balance is the sum of the entries up to each one
+-- the accounts opened with a balance before the opening entries, or adjusted without an entry, get an entry for the difference
+-- dated at their opening, the closest point the missing history can be placed at
+INSERT INTO "entries" ("account_id", "amount", "created_at")
+SELECT a."id", a."balance" - COALESCE(SUM(e."amount"), 0), a."created_at"
+FROM "accounts" a
+         LEFT JOIN "entries" e ON e."account_id" = a."id"
+GROUP BY a."id"
+HAVING a."balance" <> COALESCE(SUM(e."amount"), 0);
+
+-- the balance at the start of a statement sums the earlier entries of the account
+CREATE INDEX "entries_account_id_created_at_idx" ON "entries" ("account_id", "created_at");
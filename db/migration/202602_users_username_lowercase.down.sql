@@ -0,0 +1 @@
+-- the original case of the usernames isn't kept, there is nothing to revert
@@ -0,0 +1,43 @@
+-- usernames are stored lowercase so a login matches whatever the case it was typed in, this fails if two users only
+-- differ by the case of their username. The foreign keys don't cascade updates, so they are dropped while the
+-- usernames are rewritten everywhere and added back once every row agrees
+ALTER TABLE "accounts" DROP CONSTRAINT "accounts_owner_fkey";
+ALTER TABLE "idempotency_keys" DROP CONSTRAINT "idempotency_keys_username_fkey";
+ALTER TABLE "daily_transfer_totals" DROP CONSTRAINT "daily_transfer_totals_username_fkey";
+ALTER TABLE "account_owner_changes" DROP CONSTRAINT "account_owner_changes_previous_owner_fkey";
+ALTER TABLE "account_owner_changes" DROP CONSTRAINT "account_owner_changes_new_owner_fkey";
+ALTER TABLE "account_owner_changes" DROP CONSTRAINT "account_owner_changes_changed_by_fkey";
+ALTER TABLE "account_idempotency_keys" DROP CONSTRAINT "account_idempotency_keys_owner_fkey";
+ALTER TABLE "password_resets" DROP CONSTRAINT "password_resets_username_fkey";
+ALTER TABLE "totp_secrets" DROP CONSTRAINT "totp_secrets_username_fkey";
+ALTER TABLE "notification_preferences" DROP CONSTRAINT "notification_preferences_username_fkey";
+
+UPDATE "users" SET "username" = lower("username") WHERE "username" <> lower("username");
+UPDATE "accounts" SET "owner" = lower("owner") WHERE "owner" <> lower("owner");
+UPDATE "idempotency_keys" SET "username" = lower("username") WHERE "username" <> lower("username");
+UPDATE "daily_transfer_totals" SET "username" = lower("username") WHERE "username" <> lower("username");
+UPDATE "account_owner_changes"
+SET "previous_owner" = lower("previous_owner"),
+    "new_owner"      = lower("new_owner"),
+    "changed_by"     = lower("changed_by")
+WHERE "previous_owner" <> lower("previous_owner")
+   OR "new_owner" <> lower("new_owner")
+   OR "changed_by" <> lower("changed_by");
+UPDATE "account_idempotency_keys" SET "owner" = lower("owner") WHERE "owner" <> lower("owner");
+UPDATE "password_resets" SET "username" = lower("username") WHERE "username" <> lower("username");
+UPDATE "totp_secrets" SET "username" = lower("username") WHERE "username" <> lower("username");
+UPDATE "notification_preferences" SET "username" = lower("username") WHERE "username" <> lower("username");
+-- the sessions and the audit trail have no foreign key, they are rewritten so they keep matching their user
+UPDATE "sessions" SET "username" = lower("username") WHERE "username" <> lower("username");
+UPDATE "audit_logs" SET "username" = lower("username") WHERE "username" <> lower("username");
+
+ALTER TABLE "accounts" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+ALTER TABLE "idempotency_keys" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+ALTER TABLE "daily_transfer_totals" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+ALTER TABLE "account_owner_changes" ADD FOREIGN KEY ("previous_owner") REFERENCES "users" ("username");
+ALTER TABLE "account_owner_changes" ADD FOREIGN KEY ("new_owner") REFERENCES "users" ("username");
+ALTER TABLE "account_owner_changes" ADD FOREIGN KEY ("changed_by") REFERENCES "users" ("username");
+ALTER TABLE "account_idempotency_keys" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+ALTER TABLE "password_resets" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+ALTER TABLE "totp_secrets" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+ALTER TABLE "notification_preferences" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,95 @@
+// Package migration embeds the sql migrations into the binary and applies them with golang-migrate
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io/fs"
+
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// ErrDirty is returned for a database left dirty by a migration that failed halfway, it has to be fixed by hand
+// and forced to a clean version before any other migration runs
+var ErrDirty = errors.New("database is dirty")
+
+// Up applies the migrations the database at url is missing, an up to date database is left as is
+func Up(url string) error {
+	m, err := newMigrate(url)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("cannot read db version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("%w: version %v", ErrDirty, version)
+	}
+
+	if err = m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("cannot migrate db up: %w", err)
+	}
+	return nil
+}
+
+// Version returns the version the database at url is migrated to, and whether it's dirty. It's 0 for a database
+// no migration ran on
+func Version(url string) (uint, bool, error) {
+	m, err := newMigrate(url)
+	if err != nil {
+		return 0, false, err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// LatestVersion returns the version of the last embedded migration
+func LatestVersion() (uint, error) {
+	src, err := iofs.New(files, ".")
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("cannot read the first migration: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("cannot read the migration after %v: %w", version, err)
+		}
+		version = next
+	}
+}
+
+func newMigrate(url string) (*migrate.Migrate, error) {
+	src, err := iofs.New(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, url)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create migrate instance: %w", err)
+	}
+	return m, nil
+}
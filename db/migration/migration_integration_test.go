@@ -0,0 +1,52 @@
+//go:build integration
+
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"github.com/micaelapucciariello/simplebank/db/dbtest"
+	"github.com/micaelapucciariello/simplebank/db/migration"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestUp(t *testing.T) {
+	ctx := context.Background()
+	postgres, err := dbtest.StartPostgres(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = postgres.Terminate(ctx) })
+
+	url, err := postgres.CreateDatabase(ctx, "fresh")
+	require.NoError(t, err)
+
+	version, dirty, err := migration.Version(url)
+	require.NoError(t, err)
+	require.Zero(t, version)
+	require.False(t, dirty)
+
+	// a fresh database is brought to the latest version
+	require.NoError(t, migration.Up(url))
+	latest, err := migration.LatestVersion()
+	require.NoError(t, err)
+	version, dirty, err = migration.Version(url)
+	require.NoError(t, err)
+	require.Equal(t, latest, version)
+	require.False(t, dirty)
+
+	// running it again on the up to date database changes nothing
+	require.NoError(t, migration.Up(url))
+
+	conn, err := sql.Open("postgres", url)
+	require.NoError(t, err)
+	defer conn.Close()
+	var tables int
+	err = conn.QueryRowContext(ctx, `SELECT count(*) FROM information_schema.tables WHERE table_name IN ('accounts', 'transfers', 'notification_preferences')`).Scan(&tables)
+	require.NoError(t, err)
+	require.Equal(t, 3, tables)
+
+	// a migration that failed halfway leaves the database dirty, nothing runs on it until it's fixed by hand
+	_, err = conn.ExecContext(ctx, `UPDATE schema_migrations SET dirty = true`)
+	require.NoError(t, err)
+	require.ErrorIs(t, migration.Up(url), migration.ErrDirty)
+}
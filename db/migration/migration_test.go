@@ -0,0 +1,32 @@
+package migration
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLatestVersion(t *testing.T) {
+	entries, err := os.ReadDir(".")
+	require.NoError(t, err)
+
+	// every migration on disk is embedded, the newest one included
+	var latest uint64
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".up.sql") {
+			continue
+		}
+		version, err := strconv.ParseUint(strings.SplitN(entry.Name(), "_", 2)[0], 10, 64)
+		require.NoError(t, err)
+		if version > latest {
+			latest = version
+		}
+	}
+	require.NotZero(t, latest)
+
+	version, err := LatestVersion()
+	require.NoError(t, err)
+	require.Equal(t, uint(latest), version)
+}
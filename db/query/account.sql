@@ -1,42 +1,233 @@
 -- name: CreateAccount :one
 INSERT INTO accounts (owner,
                       balance,
-                      currency)
-VALUES ($1, $2, $3)
+                      currency,
+                      account_number,
+                      org_id)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetAccount :one
 SELECT *
 FROM accounts
 WHERE id = $1
+  AND deleted_at IS NULL
+LIMIT 1;
+
+-- name: GetAccountByNumber :one
+SELECT *
+FROM accounts
+WHERE account_number = $1
+  AND deleted_at IS NULL
 LIMIT 1;
 
 -- name: GetAccountForUpdate :one
 SELECT *
 FROM accounts
+WHERE id = $1
+  AND deleted_at IS NULL
+LIMIT 1 FOR NO KEY UPDATE;
+
+-- name: GetAccountForUpdateIncludingDeleted :one
+SELECT *
+FROM accounts
 WHERE id = $1
 LIMIT 1 FOR NO KEY UPDATE;
 
+-- name: GetAccountIncludingDeleted :one
+SELECT *
+FROM accounts
+WHERE id = $1
+LIMIT 1;
+
+-- name: GetAccountNumbers :many
+SELECT id, account_number
+FROM accounts
+WHERE id = ANY(sqlc.arg(ids)::bigint[])
+ORDER BY id;
+
+-- name: GetAccountsByIDs :many
+SELECT *
+FROM accounts
+WHERE id = ANY(sqlc.arg(ids)::bigint[])
+  AND deleted_at IS NULL
+ORDER BY id;
+
+-- name: GetAccountsByNumbers :many
+SELECT *
+FROM accounts
+WHERE account_number = ANY(sqlc.arg(account_numbers)::varchar[])
+  AND deleted_at IS NULL
+ORDER BY id;
+
 -- name: ListAccounts :many
 SELECT *
 FROM accounts
-WHERE owner = $1
+WHERE owner = sqlc.arg(owner)
+  AND (sqlc.narg(label)::text IS NULL OR labels @> ARRAY[sqlc.narg(label)::text])
+  AND deleted_at IS NULL
+ORDER BY CASE WHEN sqlc.arg(sort_by)::text = 'balance' THEN balance END,
+         CASE WHEN sqlc.arg(sort_by)::text = '-balance' THEN balance END DESC,
+         CASE WHEN sqlc.arg(sort_by)::text = 'created_at' THEN created_at END,
+         CASE WHEN sqlc.arg(sort_by)::text = '-created_at' THEN created_at END DESC,
+         CASE WHEN sqlc.arg(sort_by)::text = '-id' THEN id END DESC,
+         id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListAccountsAfterNumber :many
+SELECT *
+FROM accounts
+WHERE owner = sqlc.arg(owner)
+  AND account_number > sqlc.arg(after_number)
+  AND (sqlc.narg(currency)::varchar IS NULL OR currency = sqlc.narg(currency))
+  AND (sqlc.narg(label)::text IS NULL OR labels @> ARRAY[sqlc.narg(label)::text])
+  AND deleted_at IS NULL
+ORDER BY account_number
+LIMIT sqlc.arg('limit');
+
+-- name: ListAccountsByCurrency :many
+SELECT *
+FROM accounts
+WHERE owner = sqlc.arg(owner)
+  AND currency = sqlc.arg(currency)
+  AND (sqlc.narg(label)::text IS NULL OR labels @> ARRAY[sqlc.narg(label)::text])
+  AND deleted_at IS NULL
+ORDER BY CASE WHEN sqlc.arg(sort_by)::text = 'balance' THEN balance END,
+         CASE WHEN sqlc.arg(sort_by)::text = '-balance' THEN balance END DESC,
+         CASE WHEN sqlc.arg(sort_by)::text = 'created_at' THEN created_at END,
+         CASE WHEN sqlc.arg(sort_by)::text = '-created_at' THEN created_at END DESC,
+         CASE WHEN sqlc.arg(sort_by)::text = '-id' THEN id END DESC,
+         id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListAllAccounts :many
+SELECT *
+FROM accounts
+WHERE org_id = sqlc.arg(org_id)
+  AND (sqlc.narg(owner)::varchar IS NULL OR owner = sqlc.narg(owner))
+  AND deleted_at IS NULL
 ORDER BY id
-LIMIT $2 OFFSET $3;
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountAllAccounts :one
+SELECT COUNT(*)
+FROM accounts
+WHERE org_id = sqlc.arg(org_id)
+  AND (sqlc.narg(owner)::varchar IS NULL OR owner = sqlc.narg(owner))
+  AND deleted_at IS NULL;
+
+-- name: CountAccounts :one
+SELECT COUNT(*)
+FROM accounts
+WHERE owner = sqlc.arg(owner)
+  AND (sqlc.narg(label)::text IS NULL OR labels @> ARRAY[sqlc.narg(label)::text])
+  AND deleted_at IS NULL;
+
+-- name: CountAccountsByCurrency :one
+SELECT COUNT(*)
+FROM accounts
+WHERE owner = sqlc.arg(owner)
+  AND currency = sqlc.arg(currency)
+  AND (sqlc.narg(label)::text IS NULL OR labels @> ARRAY[sqlc.narg(label)::text])
+  AND deleted_at IS NULL;
 
 -- name: UpdateAccount :one
 UPDATE accounts
-SET balance = $2
+SET balance    = $2,
+    updated_at = now(),
+    version    = version + 1
 WHERE id = $1
+  AND version = $3
 RETURNING *;
 
 -- name: UpdateAccountBalance :one
 UPDATE accounts
-SET balance = balance + sqlc.arg(amount)
+SET balance    = balance + sqlc.arg(amount),
+    updated_at = now(),
+    version    = version + 1
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
+-- name: AddAccountHeldBalance :one
+UPDATE accounts
+SET held_balance = held_balance + sqlc.arg(amount),
+    updated_at   = now(),
+    version      = version + 1
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: UpdateAccountOwner :one
+UPDATE accounts
+SET owner      = $2,
+    updated_at = now(),
+    version    = version + 1
+WHERE id = $1
+  AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SetAccountLabels :one
+UPDATE accounts
+SET labels     = $2,
+    updated_at = now(),
+    version    = version + 1
+WHERE id = $1
+  AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SetAccountLowBalanceThreshold :one
+UPDATE accounts
+SET low_balance_threshold = $2,
+    updated_at            = now(),
+    version               = version + 1
+WHERE id = $1
+  AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SetAccountSpendingLimits :one
+UPDATE accounts
+SET daily_limit   = $2,
+    monthly_limit = $3,
+    updated_at    = now(),
+    version       = version + 1
+WHERE id = $1
+  AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SoftDeleteAccount :execrows
+-- only an empty account is deleted, checked by the update itself so no money lands on it in between
+UPDATE accounts
+SET deleted_at = now(),
+    version    = version + 1
+WHERE id = $1
+  AND balance = 0
+  AND held_balance = 0
+  AND deleted_at IS NULL;
+
+-- name: RestoreAccount :one
+UPDATE accounts
+SET deleted_at = NULL,
+    version    = version + 1
+WHERE account_number = $1
+  AND org_id = $2
+  AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: SetAccountFrozen :one
+UPDATE accounts
+SET is_frozen  = $2,
+    updated_at = now(),
+    version    = version + 1
+WHERE id = $1
+  AND org_id = $3
+  AND deleted_at IS NULL
+RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE
 FROM accounts
 WHERE id = $1;
+
+-- name: AccountNumberExists :one
+SELECT EXISTS(SELECT 1
+              FROM accounts
+              WHERE account_number = $1);
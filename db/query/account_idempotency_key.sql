@@ -0,0 +1,12 @@
+-- name: CreateAccountIdempotencyKey :one
+INSERT INTO account_idempotency_keys (owner,
+                                      key,
+                                      request_hash,
+                                      account_id)
+VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: GetAccountIdempotencyKey :one
+SELECT *
+FROM account_idempotency_keys
+WHERE owner = $1
+  AND key = $2 LIMIT 1;
@@ -0,0 +1,12 @@
+-- name: CreateAccountOwnerChange :one
+INSERT INTO account_owner_changes (account_id,
+                                   previous_owner,
+                                   new_owner,
+                                   changed_by)
+VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: ListAccountOwnerChanges :many
+SELECT *
+FROM account_owner_changes
+WHERE account_id = $1
+ORDER BY id;
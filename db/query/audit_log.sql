@@ -0,0 +1,25 @@
+-- name: CreateAuditLog :one
+INSERT INTO audit_logs (username,
+                        action,
+                        entity_type,
+                        entity_id,
+                        client_ip)
+VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: ListAuditLogs :many
+SELECT *
+FROM audit_logs
+WHERE username IN (SELECT username FROM users WHERE org_id = sqlc.arg(org_id))
+  AND (sqlc.narg(username)::varchar IS NULL OR username = sqlc.narg(username))
+  AND created_at >= sqlc.arg(from_time)
+  AND created_at < sqlc.arg(to_time)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountAuditLogs :one
+SELECT COUNT(*)
+FROM audit_logs
+WHERE username IN (SELECT username FROM users WHERE org_id = sqlc.arg(org_id))
+  AND (sqlc.narg(username)::varchar IS NULL OR username = sqlc.narg(username))
+  AND created_at >= sqlc.arg(from_time)
+  AND created_at < sqlc.arg(to_time);
@@ -0,0 +1,28 @@
+-- name: CreateBalanceSnapshot :one
+INSERT INTO balance_snapshots (account_id,
+                               day,
+                               balance)
+VALUES ($1, $2, $3) RETURNING *;
+
+-- name: CreateDailyBalanceSnapshots :execrows
+INSERT INTO balance_snapshots (account_id,
+                               day,
+                               balance)
+SELECT a.id,
+       sqlc.arg(day)::date,
+       a.balance - COALESCE((SELECT SUM(e.amount)
+                             FROM entries e
+                             WHERE e.account_id = a.id
+                               AND e.created_at >= sqlc.arg(day)::date + 1), 0)
+FROM accounts a
+WHERE a.deleted_at IS NULL
+  AND a.created_at < sqlc.arg(day)::date + 1
+ON CONFLICT (account_id, day) DO NOTHING;
+
+-- name: ListBalanceSnapshots :many
+SELECT *
+FROM balance_snapshots
+WHERE account_id = sqlc.arg(account_id)
+  AND day >= sqlc.arg(from_day)::date
+  AND day <= sqlc.arg(to_day)::date
+ORDER BY day;
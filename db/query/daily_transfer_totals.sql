@@ -0,0 +1,15 @@
+-- name: AddDailyTransferTotal :one
+INSERT INTO daily_transfer_totals (username,
+                                   day,
+                                   total)
+VALUES (sqlc.arg(username), (now() AT TIME ZONE 'UTC')::date, sqlc.arg(amount))
+ON CONFLICT (username, day) DO UPDATE
+    SET total = daily_transfer_totals.total + EXCLUDED.total
+RETURNING total;
+
+-- name: GetDailyTransferTotal :one
+SELECT total
+FROM daily_transfer_totals
+WHERE username = $1
+  AND day = (now() AT TIME ZONE 'UTC')::date
+LIMIT 1;
@@ -14,6 +14,62 @@ FROM entries
 ORDER BY id LIMIT $1
 OFFSET $2;
 
+-- name: ListAccountStatement :many
+SELECT e.id,
+       e.amount,
+       e.account_id,
+       e.created_at,
+       ((SELECT COALESCE(SUM(o.amount), 0)
+         FROM entries o
+         WHERE o.account_id = sqlc.arg(account_id)
+           AND o.created_at < sqlc.arg(from_time)::timestamp)
+           + SUM(e.amount) OVER (ORDER BY e.created_at, e.id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW))::bigint AS running_balance
+FROM entries e
+WHERE e.account_id = sqlc.arg(account_id)
+  AND e.created_at >= sqlc.arg(from_time)::timestamp
+  AND e.created_at < sqlc.arg(to_time)::timestamp
+ORDER BY e.created_at, e.id;
+
+-- name: GetAccountStats :one
+SELECT COALESCE(SUM(amount) FILTER (WHERE amount > 0), 0)::bigint  AS total_inflow,
+       COALESCE(-SUM(amount) FILTER (WHERE amount < 0), 0)::bigint AS total_outflow,
+       COALESCE(SUM(amount), 0)::bigint                            AS net_change,
+       (SELECT COUNT(*)
+        FROM transfers
+        WHERE (from_account_id = sqlc.arg(account_id) OR to_account_id = sqlc.arg(account_id))
+          AND transfers.created_at >= sqlc.arg(from_time)::timestamp
+          AND transfers.created_at < sqlc.arg(to_time)::timestamp) AS transfer_count
+FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND created_at >= sqlc.arg(from_time)::timestamp
+  AND created_at < sqlc.arg(to_time)::timestamp;
+
+-- name: ListEntriesByAccount :many
+SELECT *
+FROM entries
+WHERE account_id = $1
+ORDER BY created_at DESC, id DESC LIMIT $2
+OFFSET $3;
+
+-- name: CountEntriesByAccount :one
+SELECT COUNT(*)
+FROM entries
+WHERE account_id = $1;
+
+-- name: ListEntriesByAccountInRange :many
+SELECT *
+FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND created_at BETWEEN sqlc.arg(from_time)::timestamp AND sqlc.arg(to_time)::timestamp
+ORDER BY created_at DESC, id DESC LIMIT sqlc.arg(limit_count)
+OFFSET sqlc.arg(offset_count);
+
+-- name: CountEntriesByAccountInRange :one
+SELECT COUNT(*)
+FROM entries
+WHERE account_id = sqlc.arg(account_id)
+  AND created_at BETWEEN sqlc.arg(from_time)::timestamp AND sqlc.arg(to_time)::timestamp;
+
 -- name: DeleteEntry :exec
 DELETE
 FROM entries
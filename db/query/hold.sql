@@ -0,0 +1,31 @@
+-- name: CreateHold :one
+INSERT INTO holds (from_account_id,
+                   to_account_id,
+                   amount,
+                   expires_at)
+VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: GetHold :one
+SELECT *
+FROM holds
+WHERE id = $1 LIMIT 1;
+
+-- name: GetHoldForUpdate :one
+SELECT *
+FROM holds
+WHERE id = $1 LIMIT 1 FOR NO KEY UPDATE;
+
+-- name: ListExpiredHolds :many
+SELECT *
+FROM holds
+WHERE status = 'authorized'
+  AND expires_at <= sqlc.arg(now)
+ORDER BY from_account_id, id
+LIMIT sqlc.arg(limit) FOR NO KEY UPDATE SKIP LOCKED;
+
+-- name: UpdateHoldStatus :one
+UPDATE holds
+SET status      = sqlc.arg(status),
+    transfer_id = sqlc.narg(transfer_id),
+    updated_at  = now()
+WHERE id = sqlc.arg(id) RETURNING *;
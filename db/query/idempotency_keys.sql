@@ -0,0 +1,12 @@
+-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (username,
+                              key,
+                              request_hash,
+                              response)
+VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT *
+FROM idempotency_keys
+WHERE username = $1
+  AND key = $2 LIMIT 1;
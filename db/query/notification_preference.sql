@@ -0,0 +1,18 @@
+-- name: GetNotificationPreferences :one
+SELECT *
+FROM notification_preferences
+WHERE username = $1 LIMIT 1;
+
+-- name: UpsertNotificationPreferences :one
+INSERT INTO notification_preferences (username,
+                                      incoming_transfer_email,
+                                      incoming_transfer_webhook,
+                                      low_balance_email,
+                                      low_balance_webhook)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (username) DO UPDATE
+    SET incoming_transfer_email   = EXCLUDED.incoming_transfer_email,
+        incoming_transfer_webhook = EXCLUDED.incoming_transfer_webhook,
+        low_balance_email         = EXCLUDED.low_balance_email,
+        low_balance_webhook       = EXCLUDED.low_balance_webhook,
+        updated_at                = now() RETURNING *;
@@ -0,0 +1,8 @@
+-- name: CreateOrganization :one
+INSERT INTO organizations (name)
+VALUES ($1) RETURNING *;
+
+-- name: GetOrganization :one
+SELECT *
+FROM organizations
+WHERE id = $1 LIMIT 1;
@@ -0,0 +1,16 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox (event_type,
+                    payload)
+VALUES ($1, $2) RETURNING *;
+
+-- name: ListUnpublishedOutboxEvents :many
+SELECT *
+FROM outbox
+WHERE published_at IS NULL
+ORDER BY id LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventPublished :exec
+UPDATE outbox
+SET published_at = now()
+WHERE id = $1;
@@ -0,0 +1,69 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (from_account_id,
+                                 to_account_id,
+                                 amount,
+                                 interval_seconds,
+                                 next_run_at)
+VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+-- the last transfer is read by its uuid, the internal transfer id is never sent to the clients
+SELECT sqlc.embed(scheduled_transfers), transfers.uuid AS last_transfer_uuid
+FROM scheduled_transfers
+         LEFT JOIN transfers ON transfers.id = scheduled_transfers.last_transfer_id
+WHERE scheduled_transfers.id = $1 LIMIT 1;
+
+-- name: ListScheduledTransfers :many
+SELECT sqlc.embed(scheduled_transfers), transfers.uuid AS last_transfer_uuid
+FROM scheduled_transfers
+         LEFT JOIN transfers ON transfers.id = scheduled_transfers.last_transfer_id
+WHERE scheduled_transfers.from_account_id = $1
+ORDER BY scheduled_transfers.id
+LIMIT $2 OFFSET $3;
+
+-- name: CountScheduledTransfers :one
+SELECT COUNT(*)
+FROM scheduled_transfers
+WHERE from_account_id = $1;
+
+-- name: ListDueScheduledTransfers :many
+SELECT *
+FROM scheduled_transfers
+WHERE next_run_at <= sqlc.arg(now)
+  AND cancelled_at IS NULL
+ORDER BY next_run_at, id
+LIMIT sqlc.arg(limit);
+
+-- name: ClaimScheduledTransfer :one
+UPDATE scheduled_transfers
+SET next_run_at = sqlc.arg(next_run_at)
+WHERE id = sqlc.arg(id)
+  AND next_run_at = sqlc.arg(due_at)
+  AND cancelled_at IS NULL RETURNING *;
+
+-- name: RecordScheduledTransferRun :one
+UPDATE scheduled_transfers
+SET last_run_at      = sqlc.arg(run_at),
+    last_transfer_id = sqlc.arg(transfer_id),
+    last_error       = NULL,
+    failed_runs      = 0
+WHERE id = sqlc.arg(id) RETURNING *;
+
+-- name: RecordScheduledTransferFailure :one
+UPDATE scheduled_transfers
+SET last_run_at = sqlc.arg(run_at),
+    last_error  = sqlc.arg(last_error),
+    failed_runs = failed_runs + 1
+WHERE id = sqlc.arg(id) RETURNING *;
+
+-- name: CancelAccountScheduledTransfers :exec
+UPDATE scheduled_transfers
+SET cancelled_at = now()
+WHERE (from_account_id = $1 OR to_account_id = $1)
+  AND cancelled_at IS NULL;
+
+-- name: CancelScheduledTransfer :one
+UPDATE scheduled_transfers
+SET cancelled_at = now()
+WHERE id = $1
+  AND cancelled_at IS NULL RETURNING *;
@@ -0,0 +1,20 @@
+-- name: UpsertTotpSecret :one
+-- an enabled secret is never replaced, no row is returned for it
+INSERT INTO totp_secrets (username,
+                          encrypted_secret)
+VALUES ($1, $2)
+ON CONFLICT (username) DO UPDATE
+    SET encrypted_secret = EXCLUDED.encrypted_secret,
+        created_at       = now()
+WHERE totp_secrets.enabled_at IS NULL RETURNING *;
+
+-- name: GetTotpSecret :one
+SELECT *
+FROM totp_secrets
+WHERE username = $1 LIMIT 1;
+
+-- name: EnableTotpSecret :one
+UPDATE totp_secrets
+SET enabled_at = now()
+WHERE username = $1
+  AND enabled_at IS NULL RETURNING *;
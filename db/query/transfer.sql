@@ -1,21 +1,58 @@
 -- name: CreateTransfer :one
 INSERT INTO transfers (from_account_id,
                       to_account_id,
-                      amount)
-VALUES ($1, $2, $3) RETURNING *;
+                      amount,
+                      to_amount,
+                      reversed_from,
+                      description,
+                      uuid)
+VALUES ($1, $2, $3, $4, sqlc.narg(reversed_from), sqlc.narg(description), $7) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT *
 FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferByUUID :one
+SELECT *
+FROM transfers
+WHERE uuid = $1 LIMIT 1;
+
+-- name: GetTransferForUpdate :one
+SELECT *
+FROM transfers
+WHERE id = $1 LIMIT 1 FOR NO KEY
+UPDATE;
+
+-- name: MarkTransferReversed :one
+UPDATE transfers
+SET reversed_at = now()
+WHERE id = $1
+  AND reversed_at IS NULL RETURNING *;
+
 -- name: ListTransfers :many
 SELECT *
 FROM transfers
-ORDER BY id LIMIT $1
-OFFSET $2;
+WHERE from_account_id = $1
+   OR to_account_id = $2
+ORDER BY created_at DESC, id DESC
+LIMIT $3 OFFSET $4;
+
+-- name: CountTransfers :one
+SELECT COUNT(*)
+FROM transfers
+WHERE from_account_id = $1
+   OR to_account_id = $2;
 
 -- name: DeleteTransfer :exec
 DELETE
 FROM transfers
 WHERE id = $1;
+
+-- name: SumOutgoingTransfers :one
+-- the reversals are left out, they give money back instead of spending it
+SELECT COALESCE(SUM(amount), 0)::bigint AS total
+FROM transfers
+WHERE from_account_id = sqlc.arg(from_account_id)
+  AND created_at >= sqlc.arg(since)::timestamptz
+  AND reversed_from IS NULL;
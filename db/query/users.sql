@@ -2,30 +2,84 @@
 INSERT INTO users (username,
                    hashed_password,
                    full_name,
-                   email)
-VALUES ($1, $2, $3, $4) RETURNING *;
+                   email,
+                   org_id)
+VALUES ($1, $2, $3, $4, $5) RETURNING *;
 
 -- name: GetUser :one
 SELECT *
 FROM users
 WHERE username = $1 LIMIT 1;
 
+-- name: GetUserByEmail :one
+SELECT *
+FROM users
+WHERE email = $1 LIMIT 1;
+
 -- name: GetUserForUpdate :one
 SELECT *
 FROM users
 WHERE username = $1 LIMIT 1 FOR NO KEY
 UPDATE;
 
+-- name: GetUserPasswordChangedAt :one
+SELECT password_changed_at
+FROM users
+WHERE username = $1 LIMIT 1;
+
 -- name: ListUsers :many
 SELECT *
 FROM users
 ORDER BY username LIMIT $1
 OFFSET $2;
 
+-- name: SearchUsers :many
+-- the query comes with its wildcards escaped, so it is matched as a literal substring
+SELECT *
+FROM users
+WHERE org_id = sqlc.arg(org_id)
+  AND (username ILIKE '%' || sqlc.arg(query)::text || '%' ESCAPE '\'
+    OR full_name ILIKE '%' || sqlc.arg(query)::text || '%' ESCAPE '\'
+    OR email ILIKE '%' || sqlc.arg(query)::text || '%' ESCAPE '\')
+ORDER BY username LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: CountSearchUsers :one
+SELECT COUNT(*)
+FROM users
+WHERE org_id = sqlc.arg(org_id)
+  AND (username ILIKE '%' || sqlc.arg(query)::text || '%' ESCAPE '\'
+    OR full_name ILIKE '%' || sqlc.arg(query)::text || '%' ESCAPE '\'
+    OR email ILIKE '%' || sqlc.arg(query)::text || '%' ESCAPE '\');
+
 -- name: UpdateUser :one
 UPDATE users
-SET hashed_password = $2, password_changed_at = $3, email =$4, full_name = $5
-WHERE username = $1 RETURNING *;
+SET full_name = COALESCE(sqlc.narg(full_name), full_name),
+    email     = COALESCE(sqlc.narg(email), email)
+WHERE username = sqlc.arg(username) RETURNING *;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password     = sqlc.arg(hashed_password),
+    password_changed_at = now()
+WHERE username = sqlc.arg(username) RETURNING *;
+
+-- name: RecordFailedLogin :one
+UPDATE users
+SET failed_login_attempts = CASE
+                                WHEN failed_login_attempts + 1 >= sqlc.arg(max_attempts)::int THEN 0
+                                ELSE failed_login_attempts + 1 END,
+    locked_until          = CASE
+                                WHEN failed_login_attempts + 1 >= sqlc.arg(max_attempts)::int
+                                    THEN sqlc.arg(locked_until)::timestamptz
+                                ELSE locked_until END
+WHERE username = sqlc.arg(username) RETURNING *;
+
+-- name: ResetFailedLogins :exec
+UPDATE users
+SET failed_login_attempts = 0,
+    locked_until          = NULL
+WHERE username = $1;
 
 -- name: DeleteUser :exec
 DELETE
@@ -7,24 +7,150 @@ package db
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
 )
 
+const accountNumberExists = `-- name: AccountNumberExists :one
+SELECT EXISTS(SELECT 1
+              FROM accounts
+              WHERE account_number = $1)
+`
+
+func (q *Queries) AccountNumberExists(ctx context.Context, accountNumber string) (bool, error) {
+	row := q.queryRow(ctx, q.accountNumberExistsStmt, accountNumberExists, accountNumber)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const addAccountHeldBalance = `-- name: AddAccountHeldBalance :one
+UPDATE accounts
+SET held_balance = held_balance + $1,
+    updated_at   = now(),
+    version      = version + 1
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+`
+
+type AddAccountHeldBalanceParams struct {
+	Amount int64 `json:"amount"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error) {
+	row := q.queryRow(ctx, q.addAccountHeldBalanceStmt, addAccountHeldBalance, arg.Amount, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
+	)
+	return i, err
+}
+
+const countAccounts = `-- name: CountAccounts :one
+SELECT COUNT(*)
+FROM accounts
+WHERE owner = $1
+  AND ($2::text IS NULL OR labels @> ARRAY[$2::text])
+  AND deleted_at IS NULL
+`
+
+type CountAccountsParams struct {
+	Owner string         `json:"owner"`
+	Label sql.NullString `json:"label"`
+}
+
+func (q *Queries) CountAccounts(ctx context.Context, arg CountAccountsParams) (int64, error) {
+	row := q.queryRow(ctx, q.countAccountsStmt, countAccounts, arg.Owner, arg.Label)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countAccountsByCurrency = `-- name: CountAccountsByCurrency :one
+SELECT COUNT(*)
+FROM accounts
+WHERE owner = $1
+  AND currency = $2
+  AND ($3::text IS NULL OR labels @> ARRAY[$3::text])
+  AND deleted_at IS NULL
+`
+
+type CountAccountsByCurrencyParams struct {
+	Owner    string         `json:"owner"`
+	Currency string         `json:"currency"`
+	Label    sql.NullString `json:"label"`
+}
+
+func (q *Queries) CountAccountsByCurrency(ctx context.Context, arg CountAccountsByCurrencyParams) (int64, error) {
+	row := q.queryRow(ctx, q.countAccountsByCurrencyStmt, countAccountsByCurrency, arg.Owner, arg.Currency, arg.Label)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countAllAccounts = `-- name: CountAllAccounts :one
+SELECT COUNT(*)
+FROM accounts
+WHERE org_id = $1
+  AND ($2::varchar IS NULL OR owner = $2)
+  AND deleted_at IS NULL
+`
+
+type CountAllAccountsParams struct {
+	OrgID int64          `json:"org_id"`
+	Owner sql.NullString `json:"owner"`
+}
+
+func (q *Queries) CountAllAccounts(ctx context.Context, arg CountAllAccountsParams) (int64, error) {
+	row := q.queryRow(ctx, q.countAllAccountsStmt, countAllAccounts, arg.OrgID, arg.Owner)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createAccount = `-- name: CreateAccount :one
 INSERT INTO accounts (owner,
                       balance,
-                      currency)
-VALUES ($1, $2, $3)
-RETURNING id, owner, balance, currency, created_at
+                      currency,
+                      account_number,
+                      org_id)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
 `
 
 type CreateAccountParams struct {
-	Owner    string `json:"owner"`
-	Balance  int64  `json:"balance"`
-	Currency string `json:"currency"`
+	Owner         string `json:"owner"`
+	Balance       int64  `json:"balance"`
+	Currency      string `json:"currency"`
+	AccountNumber string `json:"account_number"`
+	OrgID         int64  `json:"org_id"`
 }
 
 func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
-	row := q.queryRow(ctx, q.createAccountStmt, createAccount, arg.Owner, arg.Balance, arg.Currency)
+	row := q.queryRow(ctx, q.createAccountStmt, createAccount,
+		arg.Owner,
+		arg.Balance,
+		arg.Currency,
+		arg.AccountNumber,
+		arg.OrgID,
+	)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -32,6 +158,17 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
 	)
 	return i, err
 }
@@ -48,9 +185,10 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at
+SELECT id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
 FROM accounts
 WHERE id = $1
+  AND deleted_at IS NULL
 LIMIT 1
 `
 
@@ -63,14 +201,58 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
+	)
+	return i, err
+}
+
+const getAccountByNumber = `-- name: GetAccountByNumber :one
+SELECT id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+FROM accounts
+WHERE account_number = $1
+  AND deleted_at IS NULL
+LIMIT 1
+`
+
+func (q *Queries) GetAccountByNumber(ctx context.Context, accountNumber string) (Account, error) {
+	row := q.queryRow(ctx, q.getAccountByNumberStmt, getAccountByNumber, accountNumber)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, owner, balance, currency, created_at
+SELECT id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
 FROM accounts
 WHERE id = $1
+  AND deleted_at IS NULL
 LIMIT 1 FOR NO KEY UPDATE
 `
 
@@ -83,26 +265,311 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
 	)
 	return i, err
 }
 
+const getAccountForUpdateIncludingDeleted = `-- name: GetAccountForUpdateIncludingDeleted :one
+SELECT id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+FROM accounts
+WHERE id = $1
+LIMIT 1 FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetAccountForUpdateIncludingDeleted(ctx context.Context, id int64) (Account, error) {
+	row := q.queryRow(ctx, q.getAccountForUpdateIncludingDeletedStmt, getAccountForUpdateIncludingDeleted, id)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
+	)
+	return i, err
+}
+
+const getAccountIncludingDeleted = `-- name: GetAccountIncludingDeleted :one
+SELECT id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+FROM accounts
+WHERE id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetAccountIncludingDeleted(ctx context.Context, id int64) (Account, error) {
+	row := q.queryRow(ctx, q.getAccountIncludingDeletedStmt, getAccountIncludingDeleted, id)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
+	)
+	return i, err
+}
+
+const getAccountNumbers = `-- name: GetAccountNumbers :many
+SELECT id, account_number
+FROM accounts
+WHERE id = ANY($1::bigint[])
+ORDER BY id
+`
+
+type GetAccountNumbersRow struct {
+	ID            int64  `json:"id"`
+	AccountNumber string `json:"account_number"`
+}
+
+func (q *Queries) GetAccountNumbers(ctx context.Context, ids []int64) ([]GetAccountNumbersRow, error) {
+	rows, err := q.query(ctx, q.getAccountNumbersStmt, getAccountNumbers, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetAccountNumbersRow{}
+	for rows.Next() {
+		var i GetAccountNumbersRow
+		if err := rows.Scan(&i.ID, &i.AccountNumber); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAccountsByIDs = `-- name: GetAccountsByIDs :many
+SELECT id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+FROM accounts
+WHERE id = ANY($1::bigint[])
+  AND deleted_at IS NULL
+ORDER BY id
+`
+
+func (q *Queries) GetAccountsByIDs(ctx context.Context, ids []int64) ([]Account, error) {
+	rows, err := q.query(ctx, q.getAccountsByIDsStmt, getAccountsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.IsFrozen,
+			&i.Version,
+			&i.HeldBalance,
+			pq.Array(&i.Labels),
+			&i.AccountNumber,
+			&i.OrgID,
+			&i.DailyLimit,
+			&i.MonthlyLimit,
+			&i.LowBalanceThreshold,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAccountsByNumbers = `-- name: GetAccountsByNumbers :many
+SELECT id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+FROM accounts
+WHERE account_number = ANY($1::varchar[])
+  AND deleted_at IS NULL
+ORDER BY id
+`
+
+func (q *Queries) GetAccountsByNumbers(ctx context.Context, accountNumbers []string) ([]Account, error) {
+	rows, err := q.query(ctx, q.getAccountsByNumbersStmt, getAccountsByNumbers, pq.Array(accountNumbers))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.IsFrozen,
+			&i.Version,
+			&i.HeldBalance,
+			pq.Array(&i.Labels),
+			&i.AccountNumber,
+			&i.OrgID,
+			&i.DailyLimit,
+			&i.MonthlyLimit,
+			&i.LowBalanceThreshold,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at
+SELECT id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
 FROM accounts
 WHERE owner = $1
-ORDER BY id
-LIMIT $2 OFFSET $3
+  AND ($2::text IS NULL OR labels @> ARRAY[$2::text])
+  AND deleted_at IS NULL
+ORDER BY CASE WHEN $3::text = 'balance' THEN balance END,
+         CASE WHEN $3::text = '-balance' THEN balance END DESC,
+         CASE WHEN $3::text = 'created_at' THEN created_at END,
+         CASE WHEN $3::text = '-created_at' THEN created_at END DESC,
+         CASE WHEN $3::text = '-id' THEN id END DESC,
+         id
+LIMIT $4 OFFSET $5
 `
 
 type ListAccountsParams struct {
-	Owner  string `json:"owner"`
-	Limit  int32  `json:"limit"`
-	Offset int32  `json:"offset"`
+	Owner  string         `json:"owner"`
+	Label  sql.NullString `json:"label"`
+	SortBy string         `json:"sort_by"`
+	Limit  int32          `json:"limit"`
+	Offset int32          `json:"offset"`
 }
 
 func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
-	rows, err := q.query(ctx, q.listAccountsStmt, listAccounts, arg.Owner, arg.Limit, arg.Offset)
+	rows, err := q.query(ctx, q.listAccountsStmt, listAccounts,
+		arg.Owner,
+		arg.Label,
+		arg.SortBy,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.IsFrozen,
+			&i.Version,
+			&i.HeldBalance,
+			pq.Array(&i.Labels),
+			&i.AccountNumber,
+			&i.OrgID,
+			&i.DailyLimit,
+			&i.MonthlyLimit,
+			&i.LowBalanceThreshold,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountsAfterNumber = `-- name: ListAccountsAfterNumber :many
+SELECT id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+FROM accounts
+WHERE owner = $1
+  AND account_number > $2
+  AND ($3::varchar IS NULL OR currency = $3)
+  AND ($4::text IS NULL OR labels @> ARRAY[$4::text])
+  AND deleted_at IS NULL
+ORDER BY account_number
+LIMIT $5
+`
+
+type ListAccountsAfterNumberParams struct {
+	Owner       string         `json:"owner"`
+	AfterNumber string         `json:"after_number"`
+	Currency    sql.NullString `json:"currency"`
+	Label       sql.NullString `json:"label"`
+	Limit       int32          `json:"limit"`
+}
+
+func (q *Queries) ListAccountsAfterNumber(ctx context.Context, arg ListAccountsAfterNumberParams) ([]Account, error) {
+	rows, err := q.query(ctx, q.listAccountsAfterNumberStmt, listAccountsAfterNumber,
+		arg.Owner,
+		arg.AfterNumber,
+		arg.Currency,
+		arg.Label,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -116,6 +583,17 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.IsFrozen,
+			&i.Version,
+			&i.HeldBalance,
+			pq.Array(&i.Labels),
+			&i.AccountNumber,
+			&i.OrgID,
+			&i.DailyLimit,
+			&i.MonthlyLimit,
+			&i.LowBalanceThreshold,
 		); err != nil {
 			return nil, err
 		}
@@ -130,20 +608,376 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 	return items, nil
 }
 
+const listAccountsByCurrency = `-- name: ListAccountsByCurrency :many
+SELECT id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+FROM accounts
+WHERE owner = $1
+  AND currency = $2
+  AND ($3::text IS NULL OR labels @> ARRAY[$3::text])
+  AND deleted_at IS NULL
+ORDER BY CASE WHEN $4::text = 'balance' THEN balance END,
+         CASE WHEN $4::text = '-balance' THEN balance END DESC,
+         CASE WHEN $4::text = 'created_at' THEN created_at END,
+         CASE WHEN $4::text = '-created_at' THEN created_at END DESC,
+         CASE WHEN $4::text = '-id' THEN id END DESC,
+         id
+LIMIT $5 OFFSET $6
+`
+
+type ListAccountsByCurrencyParams struct {
+	Owner    string         `json:"owner"`
+	Currency string         `json:"currency"`
+	Label    sql.NullString `json:"label"`
+	SortBy   string         `json:"sort_by"`
+	Limit    int32          `json:"limit"`
+	Offset   int32          `json:"offset"`
+}
+
+func (q *Queries) ListAccountsByCurrency(ctx context.Context, arg ListAccountsByCurrencyParams) ([]Account, error) {
+	rows, err := q.query(ctx, q.listAccountsByCurrencyStmt, listAccountsByCurrency,
+		arg.Owner,
+		arg.Currency,
+		arg.Label,
+		arg.SortBy,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.IsFrozen,
+			&i.Version,
+			&i.HeldBalance,
+			pq.Array(&i.Labels),
+			&i.AccountNumber,
+			&i.OrgID,
+			&i.DailyLimit,
+			&i.MonthlyLimit,
+			&i.LowBalanceThreshold,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAllAccounts = `-- name: ListAllAccounts :many
+SELECT id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+FROM accounts
+WHERE org_id = $1
+  AND ($2::varchar IS NULL OR owner = $2)
+  AND deleted_at IS NULL
+ORDER BY id
+LIMIT $3 OFFSET $4
+`
+
+type ListAllAccountsParams struct {
+	OrgID  int64          `json:"org_id"`
+	Owner  sql.NullString `json:"owner"`
+	Limit  int32          `json:"limit"`
+	Offset int32          `json:"offset"`
+}
+
+func (q *Queries) ListAllAccounts(ctx context.Context, arg ListAllAccountsParams) ([]Account, error) {
+	rows, err := q.query(ctx, q.listAllAccountsStmt, listAllAccounts,
+		arg.OrgID,
+		arg.Owner,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.IsFrozen,
+			&i.Version,
+			&i.HeldBalance,
+			pq.Array(&i.Labels),
+			&i.AccountNumber,
+			&i.OrgID,
+			&i.DailyLimit,
+			&i.MonthlyLimit,
+			&i.LowBalanceThreshold,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreAccount = `-- name: RestoreAccount :one
+UPDATE accounts
+SET deleted_at = NULL,
+    version    = version + 1
+WHERE account_number = $1
+  AND org_id = $2
+  AND deleted_at IS NOT NULL
+RETURNING id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+`
+
+type RestoreAccountParams struct {
+	AccountNumber string `json:"account_number"`
+	OrgID         int64  `json:"org_id"`
+}
+
+func (q *Queries) RestoreAccount(ctx context.Context, arg RestoreAccountParams) (Account, error) {
+	row := q.queryRow(ctx, q.restoreAccountStmt, restoreAccount, arg.AccountNumber, arg.OrgID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
+	)
+	return i, err
+}
+
+const setAccountFrozen = `-- name: SetAccountFrozen :one
+UPDATE accounts
+SET is_frozen  = $2,
+    updated_at = now(),
+    version    = version + 1
+WHERE id = $1
+  AND org_id = $3
+  AND deleted_at IS NULL
+RETURNING id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+`
+
+type SetAccountFrozenParams struct {
+	ID       int64 `json:"id"`
+	IsFrozen bool  `json:"is_frozen"`
+	OrgID    int64 `json:"org_id"`
+}
+
+func (q *Queries) SetAccountFrozen(ctx context.Context, arg SetAccountFrozenParams) (Account, error) {
+	row := q.queryRow(ctx, q.setAccountFrozenStmt, setAccountFrozen, arg.ID, arg.IsFrozen, arg.OrgID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
+	)
+	return i, err
+}
+
+const setAccountLabels = `-- name: SetAccountLabels :one
+UPDATE accounts
+SET labels     = $2,
+    updated_at = now(),
+    version    = version + 1
+WHERE id = $1
+  AND deleted_at IS NULL
+RETURNING id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+`
+
+type SetAccountLabelsParams struct {
+	ID     int64    `json:"id"`
+	Labels []string `json:"labels"`
+}
+
+func (q *Queries) SetAccountLabels(ctx context.Context, arg SetAccountLabelsParams) (Account, error) {
+	row := q.queryRow(ctx, q.setAccountLabelsStmt, setAccountLabels, arg.ID, pq.Array(arg.Labels))
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
+	)
+	return i, err
+}
+
+const setAccountLowBalanceThreshold = `-- name: SetAccountLowBalanceThreshold :one
+UPDATE accounts
+SET low_balance_threshold = $2,
+    updated_at            = now(),
+    version               = version + 1
+WHERE id = $1
+  AND deleted_at IS NULL
+RETURNING id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+`
+
+type SetAccountLowBalanceThresholdParams struct {
+	ID                  int64 `json:"id"`
+	LowBalanceThreshold int64 `json:"low_balance_threshold"`
+}
+
+func (q *Queries) SetAccountLowBalanceThreshold(ctx context.Context, arg SetAccountLowBalanceThresholdParams) (Account, error) {
+	row := q.queryRow(ctx, q.setAccountLowBalanceThresholdStmt, setAccountLowBalanceThreshold, arg.ID, arg.LowBalanceThreshold)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
+	)
+	return i, err
+}
+
+const setAccountSpendingLimits = `-- name: SetAccountSpendingLimits :one
+UPDATE accounts
+SET daily_limit   = $2,
+    monthly_limit = $3,
+    updated_at    = now(),
+    version       = version + 1
+WHERE id = $1
+  AND deleted_at IS NULL
+RETURNING id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+`
+
+type SetAccountSpendingLimitsParams struct {
+	ID           int64 `json:"id"`
+	DailyLimit   int64 `json:"daily_limit"`
+	MonthlyLimit int64 `json:"monthly_limit"`
+}
+
+func (q *Queries) SetAccountSpendingLimits(ctx context.Context, arg SetAccountSpendingLimitsParams) (Account, error) {
+	row := q.queryRow(ctx, q.setAccountSpendingLimitsStmt, setAccountSpendingLimits, arg.ID, arg.DailyLimit, arg.MonthlyLimit)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
+	)
+	return i, err
+}
+
+const softDeleteAccount = `-- name: SoftDeleteAccount :execrows
+UPDATE accounts
+SET deleted_at = now(),
+    version    = version + 1
+WHERE id = $1
+  AND balance = 0
+  AND held_balance = 0
+  AND deleted_at IS NULL
+`
+
+// only an empty account is deleted, checked by the update itself so no money lands on it in between
+func (q *Queries) SoftDeleteAccount(ctx context.Context, id int64) (int64, error) {
+	result, err := q.exec(ctx, q.softDeleteAccountStmt, softDeleteAccount, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateAccount = `-- name: UpdateAccount :one
 UPDATE accounts
-SET balance = $2
+SET balance    = $2,
+    updated_at = now(),
+    version    = version + 1
 WHERE id = $1
-RETURNING id, owner, balance, currency, created_at
+  AND version = $3
+RETURNING id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
 `
 
 type UpdateAccountParams struct {
 	ID      int64 `json:"id"`
 	Balance int64 `json:"balance"`
+	Version int64 `json:"version"`
 }
 
 func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
-	row := q.queryRow(ctx, q.updateAccountStmt, updateAccount, arg.ID, arg.Balance)
+	row := q.queryRow(ctx, q.updateAccountStmt, updateAccount, arg.ID, arg.Balance, arg.Version)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -151,15 +985,28 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
 	)
 	return i, err
 }
 
 const updateAccountBalance = `-- name: UpdateAccountBalance :one
 UPDATE accounts
-SET balance = balance + $1
+SET balance    = balance + $1,
+    updated_at = now(),
+    version    = version + 1
 WHERE id = $2
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
 `
 
 type UpdateAccountBalanceParams struct {
@@ -176,6 +1023,56 @@ func (q *Queries) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBal
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
+	)
+	return i, err
+}
+
+const updateAccountOwner = `-- name: UpdateAccountOwner :one
+UPDATE accounts
+SET owner      = $2,
+    updated_at = now(),
+    version    = version + 1
+WHERE id = $1
+  AND deleted_at IS NULL
+RETURNING id, owner, balance, currency, created_at, updated_at, deleted_at, is_frozen, version, held_balance, labels, account_number, org_id, daily_limit, monthly_limit, low_balance_threshold
+`
+
+type UpdateAccountOwnerParams struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+}
+
+func (q *Queries) UpdateAccountOwner(ctx context.Context, arg UpdateAccountOwnerParams) (Account, error) {
+	row := q.queryRow(ctx, q.updateAccountOwnerStmt, updateAccountOwner, arg.ID, arg.Owner)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.IsFrozen,
+		&i.Version,
+		&i.HeldBalance,
+		pq.Array(&i.Labels),
+		&i.AccountNumber,
+		&i.OrgID,
+		&i.DailyLimit,
+		&i.MonthlyLimit,
+		&i.LowBalanceThreshold,
 	)
 	return i, err
 }
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/micaelapucciariello/simplebank/utils"
+)
+
+// maxAccountNumberAttempts bounds the numbers tried for a new account, with 16 random digits even a second attempt is unlikely
+const maxAccountNumberAttempts = 5
+
+var ErrAccountNumberUnavailable = errors.New("no free account number")
+
+// generateAccountNumber is replaced in the tests to force collisions
+var generateAccountNumber = utils.NewAccountNumber
+
+// NewAccountNumber returns a random account number no account holds yet, deleted accounts included so a number is never reused
+// The accounts unique constraint still rejects a number taken concurrently between the check and the insert
+func NewAccountNumber(ctx context.Context, q Querier) (string, error) {
+	for attempt := 0; attempt < maxAccountNumberAttempts; attempt++ {
+		number, err := generateAccountNumber()
+		if err != nil {
+			return "", err
+		}
+
+		taken, err := q.AccountNumberExists(ctx, number)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return number, nil
+		}
+	}
+	return "", fmt.Errorf("%w after %v attempts", ErrAccountNumberUnavailable, maxAccountNumberAttempts)
+}
+
+// CreateAccount creates the account with a new account number unless the params already carry one
+func (s *SQLStore) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	if arg.AccountNumber == "" {
+		var err error
+		arg.AccountNumber, err = NewAccountNumber(ctx, s.Queries)
+		if err != nil {
+			return Account{}, err
+		}
+	}
+	return s.Queries.CreateAccount(ctx, arg)
+}
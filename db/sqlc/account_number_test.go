@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// stubAccountNumbers makes generateAccountNumber return numbers in order until the test ends
+func stubAccountNumbers(t *testing.T, numbers ...string) {
+	generate := generateAccountNumber
+	t.Cleanup(func() { generateAccountNumber = generate })
+
+	generateAccountNumber = func() (string, error) {
+		require.NotEmpty(t, numbers, "more account numbers generated than expected")
+		number := numbers[0]
+		numbers = numbers[1:]
+		return number, nil
+	}
+}
+
+func TestCreateAccountNumberCollision(t *testing.T) {
+	store := NewStore(testDB)
+	taken := CreateRandomAccount(t)
+	fresh := utils.RandomAccountNumber()
+
+	// the first number is taken, so the store tries another one
+	stubAccountNumbers(t, taken.AccountNumber, fresh)
+
+	account, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    CreateRandomUser(t).Username,
+		Currency: utils.USD,
+		OrgID:    DefaultOrgID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, fresh, account.AccountNumber)
+}
+
+func TestCreateAccountNumberExhausted(t *testing.T) {
+	store := NewStore(testDB)
+	taken := CreateRandomAccount(t)
+
+	numbers := make([]string, maxAccountNumberAttempts)
+	for i := range numbers {
+		numbers[i] = taken.AccountNumber
+	}
+	stubAccountNumbers(t, numbers...)
+
+	_, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    CreateRandomUser(t).Username,
+		Currency: utils.USD,
+		OrgID:    DefaultOrgID,
+	})
+	require.ErrorIs(t, err, ErrAccountNumberUnavailable)
+}
+
+func TestCreateAccountNumberOfDeletedAccount(t *testing.T) {
+	store := NewStore(testDB)
+	deleted := CreateRandomAccount(t)
+	deleteEmptiedAccount(t, deleted.ID)
+	fresh := utils.RandomAccountNumber()
+
+	// a deleted account keeps its number
+	stubAccountNumbers(t, deleted.AccountNumber, fresh)
+
+	account, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    CreateRandomUser(t).Username,
+		Currency: utils.USD,
+		OrgID:    DefaultOrgID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, fresh, account.AccountNumber)
+}
@@ -2,8 +2,11 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"github.com/lib/pq"
 	"github.com/micaelapucciariello/simplebank/utils"
 	"github.com/stretchr/testify/require"
+	"sort"
 	"testing"
 	"time"
 )
@@ -11,9 +14,11 @@ import (
 func CreateRandomAccount(t *testing.T) Account {
 	user := CreateRandomUser(t)
 	args := CreateAccountParams{
-		Owner:    user.Username,
-		Balance:  utils.RandomBalance(),
-		Currency: utils.RandomCurrency(),
+		Owner:         user.Username,
+		Balance:       utils.RandomBalance(),
+		Currency:      utils.RandomCurrency(),
+		AccountNumber: utils.RandomAccountNumber(),
+		OrgID:         DefaultOrgID,
 	}
 
 	account, err := testQueries.CreateAccount(context.Background(), args)
@@ -24,17 +29,49 @@ func CreateRandomAccount(t *testing.T) Account {
 	require.Equal(t, args.Owner, account.Owner)
 	require.Equal(t, args.Balance, account.Balance)
 	require.Equal(t, args.Currency, account.Currency)
+	require.Equal(t, args.AccountNumber, account.AccountNumber)
 
 	require.NotZero(t, account.CreatedAt)
+	require.NotZero(t, account.UpdatedAt)
 	require.NotZero(t, account.ID)
 
 	return account
 }
 
+// deleteEmptiedAccount empties the account and deletes it, only an empty account can be deleted
+func deleteEmptiedAccount(t *testing.T, accountID int64) {
+	account, err := testQueries.GetAccount(context.Background(), accountID)
+	require.NoError(t, err)
+
+	_, err = testQueries.UpdateAccountBalance(context.Background(), UpdateAccountBalanceParams{ID: accountID, Amount: -account.Balance})
+	require.NoError(t, err)
+
+	deleted, err := testQueries.SoftDeleteAccount(context.Background(), accountID)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, deleted)
+}
+
 func TestCreateAccount(t *testing.T) {
 	CreateRandomAccount(t)
 }
 
+func TestCreateAccountDuplicateCurrency(t *testing.T) {
+	a := CreateRandomAccount(t)
+
+	_, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:         a.Owner,
+		Balance:       0,
+		Currency:      a.Currency,
+		AccountNumber: utils.RandomAccountNumber(),
+		OrgID:         DefaultOrgID,
+	})
+	require.Error(t, err)
+
+	pqErr, ok := err.(*pq.Error)
+	require.True(t, ok)
+	require.Equal(t, "unique_violation", pqErr.Code.Name())
+}
+
 func TestGetAccount(t *testing.T) {
 	a := CreateRandomAccount(t)
 
@@ -51,12 +88,88 @@ func TestGetAccount(t *testing.T) {
 	require.WithinDuration(t, a.CreatedAt.Time, account.CreatedAt.Time, time.Second)
 }
 
+func TestGetAccountByNumber(t *testing.T) {
+	a := CreateRandomAccount(t)
+
+	account, err := testQueries.GetAccountByNumber(context.Background(), a.AccountNumber)
+	require.NoError(t, err)
+	require.Equal(t, a.ID, account.ID)
+	require.Equal(t, a.AccountNumber, account.AccountNumber)
+
+	_, err = testQueries.GetAccountByNumber(context.Background(), utils.RandomAccountNumber())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// a deleted account isn't found by its number either
+	deleteEmptiedAccount(t, a.ID)
+	_, err = testQueries.GetAccountByNumber(context.Background(), a.AccountNumber)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestLookupAccounts(t *testing.T) {
+	a1 := CreateRandomAccount(t)
+	a2 := CreateRandomAccount(t)
+	deleted := CreateRandomAccount(t)
+	deleteEmptiedAccount(t, deleted.ID)
+
+	store := NewStore(testDB)
+	missingID := a1.ID + a2.ID + deleted.ID
+	accounts, err := store.LookupAccounts(context.Background(), []int64{a1.ID, missingID, a2.ID, deleted.ID})
+	require.NoError(t, err)
+
+	// the ids that don't exist, or were deleted, are simply absent
+	require.Len(t, accounts, 2)
+	require.Equal(t, a1.ID, accounts[a1.ID].ID)
+	require.Equal(t, a1.Balance, accounts[a1.ID].Balance)
+	require.Equal(t, a2.ID, accounts[a2.ID].ID)
+	require.Equal(t, a2.Owner, accounts[a2.ID].Owner)
+	require.NotContains(t, accounts, missingID)
+	require.NotContains(t, accounts, deleted.ID)
+
+	accounts, err = store.LookupAccounts(context.Background(), nil)
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+}
+
+func TestLookupAccountsByNumber(t *testing.T) {
+	a1 := CreateRandomAccount(t)
+	a2 := CreateRandomAccount(t)
+	deleted := CreateRandomAccount(t)
+	deleteEmptiedAccount(t, deleted.ID)
+
+	store := NewStore(testDB)
+	missingNumber := utils.RandomAccountNumber()
+	accounts, err := store.LookupAccountsByNumber(context.Background(), []string{a1.AccountNumber, missingNumber, a2.AccountNumber, deleted.AccountNumber})
+	require.NoError(t, err)
+
+	// the numbers that don't exist, or whose account was deleted, are simply absent
+	require.Len(t, accounts, 2)
+	require.Equal(t, a1.ID, accounts[a1.AccountNumber].ID)
+	require.Equal(t, a2.ID, accounts[a2.AccountNumber].ID)
+	require.NotContains(t, accounts, missingNumber)
+	require.NotContains(t, accounts, deleted.AccountNumber)
+}
+
+func TestGetAccountNumbers(t *testing.T) {
+	a := CreateRandomAccount(t)
+	deleted := CreateRandomAccount(t)
+	deleteEmptiedAccount(t, deleted.ID)
+
+	// the deleted accounts keep their number, the transfers they took part in still point at them
+	numbers, err := testQueries.GetAccountNumbers(context.Background(), []int64{deleted.ID, a.ID})
+	require.NoError(t, err)
+	require.Equal(t, []GetAccountNumbersRow{
+		{ID: a.ID, AccountNumber: a.AccountNumber},
+		{ID: deleted.ID, AccountNumber: deleted.AccountNumber},
+	}, numbers)
+}
+
 func TestUpdateAccount(t *testing.T) {
 	a := CreateRandomAccount(t)
 
 	args := UpdateAccountParams{
 		ID:      a.ID,
 		Balance: utils.RandomBalance(),
+		Version: a.Version,
 	}
 
 	account, err := testQueries.UpdateAccount(context.Background(), args)
@@ -68,8 +181,48 @@ func TestUpdateAccount(t *testing.T) {
 	require.Equal(t, args.Balance, account.Balance)
 	require.Equal(t, a.Currency, account.Currency)
 	require.Equal(t, a.ID, account.ID)
+	require.Equal(t, a.Version+1, account.Version)
+
+	require.WithinDuration(t, a.CreatedAt.Time, account.CreatedAt.Time, time.Second)
+	require.True(t, account.UpdatedAt.Time.After(a.UpdatedAt.Time))
+}
+
+func TestUpdateAccountStaleVersion(t *testing.T) {
+	a := CreateRandomAccount(t)
+
+	// both updates read the account at the same version, the second one would clobber the first
+	args := UpdateAccountParams{
+		ID:      a.ID,
+		Balance: utils.RandomBalance(),
+		Version: a.Version,
+	}
+	_, err := testQueries.UpdateAccount(context.Background(), args)
+	require.NoError(t, err)
+
+	args.Balance++
+	_, err = testQueries.UpdateAccount(context.Background(), args)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUpdateAccountBalance(t *testing.T) {
+	a := CreateRandomAccount(t)
+
+	args := UpdateAccountBalanceParams{
+		ID:     a.ID,
+		Amount: utils.RandomBalance(),
+	}
+
+	account, err := testQueries.UpdateAccountBalance(context.Background(), args)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, account)
+
+	require.Equal(t, a.Balance+args.Amount, account.Balance)
+	require.Equal(t, a.ID, account.ID)
+	require.Equal(t, a.Version+1, account.Version)
 
 	require.WithinDuration(t, a.CreatedAt.Time, account.CreatedAt.Time, time.Second)
+	require.True(t, account.UpdatedAt.Time.After(a.UpdatedAt.Time))
 }
 
 func TestDeleteAccount(t *testing.T) {
@@ -82,6 +235,81 @@ func TestDeleteAccount(t *testing.T) {
 	require.Empty(t, emptyAccount)
 }
 
+func TestSoftDeleteAccount(t *testing.T) {
+	a := CreateRandomAccount(t)
+	_, err := testQueries.UpdateAccountBalance(context.Background(), UpdateAccountBalanceParams{ID: a.ID, Amount: 1 - a.Balance})
+	require.NoError(t, err)
+
+	// an account holding money isn't deleted
+	deleted, err := testQueries.SoftDeleteAccount(context.Background(), a.ID)
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+	_, err = testQueries.GetAccount(context.Background(), a.ID)
+	require.NoError(t, err)
+
+	deleteEmptiedAccount(t, a.ID)
+
+	// nor is an account already deleted
+	deleted, err = testQueries.SoftDeleteAccount(context.Background(), a.ID)
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+
+	_, err = testQueries.GetAccount(context.Background(), a.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// the deleted account disappears from every listing
+	accounts, err := testQueries.ListAccounts(context.Background(), ListAccountsParams{
+		Owner:  a.Owner,
+		SortBy: "id",
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+
+	total, err := testQueries.CountAccounts(context.Background(), CountAccountsParams{Owner: a.Owner})
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	accounts, err = testQueries.ListAllAccounts(context.Background(), ListAllAccountsParams{
+		Owner:  sql.NullString{String: a.Owner, Valid: true},
+		Limit:  5,
+		Offset: 0,
+		OrgID:  DefaultOrgID,
+	})
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+
+	// the owner can open a new account in the same currency
+	_, err = testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:         a.Owner,
+		Balance:       0,
+		Currency:      a.Currency,
+		AccountNumber: utils.RandomAccountNumber(),
+		OrgID:         DefaultOrgID,
+	})
+	require.NoError(t, err)
+}
+
+func TestRestoreAccount(t *testing.T) {
+	a := CreateRandomAccount(t)
+
+	// an account that isn't deleted can't be restored
+	_, err := testQueries.RestoreAccount(context.Background(), RestoreAccountParams{AccountNumber: a.AccountNumber, OrgID: a.OrgID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	deleteEmptiedAccount(t, a.ID)
+
+	account, err := testQueries.RestoreAccount(context.Background(), RestoreAccountParams{AccountNumber: a.AccountNumber, OrgID: a.OrgID})
+	require.NoError(t, err)
+	require.Equal(t, a.ID, account.ID)
+	require.False(t, account.DeletedAt.Valid)
+
+	account, err = testQueries.GetAccount(context.Background(), a.ID)
+	require.NoError(t, err)
+	require.Equal(t, a, account)
+}
+
 func TestGetAccountList(t *testing.T) {
 	var lastAccount Account
 	for i := 0; i < 10; i++ {
@@ -90,6 +318,7 @@ func TestGetAccountList(t *testing.T) {
 
 	args := ListAccountsParams{
 		Owner:  lastAccount.Owner,
+		SortBy: "id",
 		Limit:  5,
 		Offset: 0,
 	}
@@ -103,3 +332,266 @@ func TestGetAccountList(t *testing.T) {
 		require.Equal(t, lastAccount, account)
 	}
 }
+
+func TestGetAccountListSorted(t *testing.T) {
+	user := CreateRandomUser(t)
+	for _, currency := range []string{utils.USD, utils.EUR, utils.ARS} {
+		_, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:         user.Username,
+			Balance:       utils.RandomBalance(),
+			Currency:      currency,
+			AccountNumber: utils.RandomAccountNumber(),
+			OrgID:         DefaultOrgID,
+		})
+		require.NoError(t, err)
+	}
+
+	args := ListAccountsParams{
+		Owner:  user.Username,
+		SortBy: "-balance",
+		Limit:  5,
+		Offset: 0,
+	}
+
+	accounts, err := testQueries.ListAccounts(context.Background(), args)
+	require.NoError(t, err)
+	require.Len(t, accounts, 3)
+
+	for i := 1; i < len(accounts); i++ {
+		require.GreaterOrEqual(t, accounts[i-1].Balance, accounts[i].Balance)
+	}
+}
+
+func TestGetAccountListByCurrency(t *testing.T) {
+	account := CreateRandomAccount(t)
+
+	args := ListAccountsByCurrencyParams{
+		Owner:    account.Owner,
+		Currency: account.Currency,
+		SortBy:   "-balance",
+		Limit:    5,
+		Offset:   0,
+	}
+
+	accounts, err := testQueries.ListAccountsByCurrency(context.Background(), args)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, account, accounts[0])
+
+	// the owner has no account in any other currency
+	for _, currency := range []string{utils.USD, utils.EUR, utils.ARS} {
+		if currency == account.Currency {
+			continue
+		}
+		args.Currency = currency
+		accounts, err = testQueries.ListAccountsByCurrency(context.Background(), args)
+		require.NoError(t, err)
+		require.NotNil(t, accounts)
+		require.Empty(t, accounts)
+	}
+}
+
+func TestCountAccounts(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	currencies := []string{utils.USD, utils.EUR, utils.ARS}
+	for _, currency := range currencies {
+		_, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:         user.Username,
+			Balance:       utils.RandomBalance(),
+			Currency:      currency,
+			AccountNumber: utils.RandomAccountNumber(),
+			OrgID:         DefaultOrgID,
+		})
+		require.NoError(t, err)
+	}
+
+	total, err := testQueries.CountAccounts(context.Background(), CountAccountsParams{Owner: user.Username})
+	require.NoError(t, err)
+	require.Equal(t, int64(len(currencies)), total)
+
+	total, err = testQueries.CountAccountsByCurrency(context.Background(), CountAccountsByCurrencyParams{
+		Owner:    user.Username,
+		Currency: utils.USD,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+}
+
+func TestListAccountsByLabel(t *testing.T) {
+	user := CreateRandomUser(t)
+	createAccount := func(currency string, labels ...string) Account {
+		account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:         user.Username,
+			Balance:       utils.RandomBalance(),
+			Currency:      currency,
+			AccountNumber: utils.RandomAccountNumber(),
+			OrgID:         DefaultOrgID,
+		})
+		require.NoError(t, err)
+		if len(labels) == 0 {
+			return account
+		}
+
+		account, err = testQueries.SetAccountLabels(context.Background(), SetAccountLabelsParams{
+			ID:     account.ID,
+			Labels: labels,
+		})
+		require.NoError(t, err)
+		require.Equal(t, labels, account.Labels)
+		return account
+	}
+
+	savings := createAccount(utils.USD, "savings", "rent")
+	createAccount(utils.EUR, "travel")
+	unlabeled := createAccount(utils.ARS)
+	require.Empty(t, unlabeled.Labels)
+
+	label := sql.NullString{String: "savings", Valid: true}
+	accounts, err := testQueries.ListAccounts(context.Background(), ListAccountsParams{
+		Owner:  user.Username,
+		Label:  label,
+		SortBy: "id",
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Account{savings}, accounts)
+
+	total, err := testQueries.CountAccounts(context.Background(), CountAccountsParams{Owner: user.Username, Label: label})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+
+	// the label combines with the currency filter
+	accounts, err = testQueries.ListAccountsByCurrency(context.Background(), ListAccountsByCurrencyParams{
+		Owner:    user.Username,
+		Currency: utils.EUR,
+		Label:    label,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+
+	// without a label every account is listed
+	total, err = testQueries.CountAccounts(context.Background(), CountAccountsParams{Owner: user.Username})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+}
+
+func TestListAccountsAfterNumber(t *testing.T) {
+	user := CreateRandomUser(t)
+	createAccount := func(currency string) Account {
+		account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:         user.Username,
+			Balance:       utils.RandomBalance(),
+			Currency:      currency,
+			AccountNumber: utils.RandomAccountNumber(),
+			OrgID:         DefaultOrgID,
+		})
+		require.NoError(t, err)
+		return account
+	}
+	listAfter := func(afterNumber string) []Account {
+		accounts, err := testQueries.ListAccountsAfterNumber(context.Background(), ListAccountsAfterNumberParams{
+			Owner:       user.Username,
+			AfterNumber: afterNumber,
+			Limit:       1,
+		})
+		require.NoError(t, err)
+		return accounts
+	}
+
+	usd := createAccount(utils.USD)
+	ars := createAccount(utils.ARS)
+	eur := createAccount(utils.EUR)
+	// other owners accounts never show up in the pages
+	CreateRandomAccount(t)
+
+	// the numbers are random, the pages follow them in ascending order whatever the order the accounts were opened in
+	expected := []Account{usd, ars, eur}
+	sort.Slice(expected, func(i, j int) bool { return expected[i].AccountNumber < expected[j].AccountNumber })
+
+	afterNumber := ""
+	for _, account := range expected {
+		page := listAfter(afterNumber)
+		require.Equal(t, []Account{account}, page)
+		afterNumber = page[0].AccountNumber
+	}
+	require.Empty(t, listAfter(afterNumber))
+
+	// the filters of the offset list still apply to the cursor pages
+	accounts, err := testQueries.ListAccountsAfterNumber(context.Background(), ListAccountsAfterNumberParams{
+		Owner:    user.Username,
+		Currency: sql.NullString{String: utils.ARS, Valid: true},
+		Limit:    5,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Account{ars}, accounts)
+}
+
+func TestListAllAccounts(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		CreateRandomAccount(t)
+	}
+
+	accounts, err := testQueries.ListAllAccounts(context.Background(), ListAllAccountsParams{
+		Owner:  sql.NullString{},
+		Limit:  5,
+		Offset: 5,
+		OrgID:  DefaultOrgID,
+	})
+	require.NoError(t, err)
+	require.Len(t, accounts, 5)
+
+	// accounts are not scoped to an owner, they come sorted by id
+	for i, account := range accounts {
+		require.NotEmpty(t, account)
+		if i > 0 {
+			require.Greater(t, account.ID, accounts[i-1].ID)
+		}
+	}
+}
+
+func TestListAllAccountsByOwner(t *testing.T) {
+	account := CreateRandomAccount(t)
+	CreateRandomAccount(t)
+
+	owner := sql.NullString{String: account.Owner, Valid: true}
+	accounts, err := testQueries.ListAllAccounts(context.Background(), ListAllAccountsParams{
+		OrgID:  DefaultOrgID,
+		Owner:  owner,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, account, accounts[0])
+
+	total, err := testQueries.CountAllAccounts(context.Background(), CountAllAccountsParams{OrgID: DefaultOrgID, Owner: owner})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+
+	// without an owner filter every account is counted
+	total, err = testQueries.CountAllAccounts(context.Background(), CountAllAccountsParams{OrgID: DefaultOrgID})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, total, int64(2))
+}
+
+func TestSetAccountFrozen(t *testing.T) {
+	account := CreateRandomAccount(t)
+	require.False(t, account.IsFrozen)
+
+	frozen, err := testQueries.SetAccountFrozen(context.Background(), SetAccountFrozenParams{ID: account.ID, IsFrozen: true, OrgID: DefaultOrgID})
+	require.NoError(t, err)
+	require.True(t, frozen.IsFrozen)
+	require.Equal(t, account.Balance, frozen.Balance)
+
+	got, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.True(t, got.IsFrozen)
+
+	// deleted accounts can't be frozen
+	deleteEmptiedAccount(t, account.ID)
+
+	_, err = testQueries.SetAccountFrozen(context.Background(), SetAccountFrozenParams{ID: account.ID, IsFrozen: false, OrgID: DefaultOrgID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// listUserAuditLogs returns the audit rows of the user written since the given time, newest first
+func listUserAuditLogs(t *testing.T, username string, since time.Time) []AuditLog {
+	logs, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Username: sql.NullString{String: username, Valid: true},
+		FromTime: since,
+		ToTime:   time.Now().Add(time.Minute),
+		Limit:    10,
+		Offset:   0,
+		OrgID:    DefaultOrgID,
+	})
+	require.NoError(t, err)
+	return logs
+}
+
+func TestChangePasswordTxAudit(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+	since := time.Now().Add(-time.Minute)
+
+	updated, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		UpdateUserPasswordParams: UpdateUserPasswordParams{
+			HashedPassword: "new-password",
+			Username:       user.Username,
+		},
+		Actor: Actor{Username: user.Username, ClientIP: "10.0.0.1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "new-password", updated.HashedPassword)
+
+	logs := listUserAuditLogs(t, user.Username, since)
+	require.Len(t, logs, 1)
+	require.Equal(t, user.Username, logs[0].Username)
+	require.Equal(t, AuditActionChangePassword, logs[0].Action)
+	require.Equal(t, AuditEntityUser, logs[0].EntityType)
+	require.Equal(t, user.Username, logs[0].EntityID)
+	require.Equal(t, "10.0.0.1", logs[0].ClientIp)
+	require.WithinDuration(t, time.Now(), logs[0].CreatedAt, time.Minute)
+}
+
+func TestChangePasswordTxAuditRollback(t *testing.T) {
+	store := NewStore(testDB)
+	username := utils.RandomOwner()
+	since := time.Now().Add(-time.Minute)
+
+	// the audit row is written in the transaction of the change, a change that fails leaves no trace
+	_, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		UpdateUserPasswordParams: UpdateUserPasswordParams{
+			HashedPassword: "new-password",
+			Username:       username,
+		},
+		Actor: Actor{Username: username, ClientIP: "10.0.0.1"},
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Empty(t, listUserAuditLogs(t, username, since))
+}
+
+func TestLoginTxAudit(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+	since := time.Now().Add(-time.Minute)
+
+	session, err := store.LoginTx(context.Background(), LoginTxParams{
+		CreateSessionParams: CreateSessionParams{
+			ID:           uuid.New(),
+			Username:     user.Username,
+			RefreshToken: "refresh_token",
+			UserAgent:    "user_agent",
+			ClientIp:     "127.0.0.1",
+			ExpiresAt:    sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, session.Username)
+
+	logs := listUserAuditLogs(t, user.Username, since)
+	require.Len(t, logs, 1)
+	require.Equal(t, AuditActionLogin, logs[0].Action)
+	require.Equal(t, "127.0.0.1", logs[0].ClientIp)
+}
+
+func TestSetAccountFrozenTxAudit(t *testing.T) {
+	store := NewStore(testDB)
+	account := CreateRandomAccount(t)
+	banker := CreateRandomUser(t)
+	since := time.Now().Add(-time.Minute)
+
+	for _, frozen := range []bool{true, false} {
+		_, err := store.SetAccountFrozenTx(context.Background(), SetAccountFrozenTxParams{
+			SetAccountFrozenParams: SetAccountFrozenParams{ID: account.ID, IsFrozen: frozen, OrgID: DefaultOrgID},
+			Actor:                  Actor{Username: banker.Username, ClientIP: "10.0.0.2"},
+		})
+		require.NoError(t, err)
+	}
+
+	logs := listUserAuditLogs(t, banker.Username, since)
+	require.Len(t, logs, 2)
+	// newest first
+	require.Equal(t, AuditActionUnfreezeAccount, logs[0].Action)
+	require.Equal(t, AuditActionFreezeAccount, logs[1].Action)
+	for _, log := range logs {
+		require.Equal(t, AuditEntityAccount, log.EntityType)
+		require.Equal(t, fmt.Sprint(account.ID), log.EntityID)
+	}
+}
+
+func TestListAuditLogsRange(t *testing.T) {
+	user := CreateRandomUser(t)
+	log, err := testQueries.CreateAuditLog(context.Background(), CreateAuditLogParams{
+		Username:   user.Username,
+		Action:     AuditActionLogin,
+		EntityType: AuditEntityUser,
+		EntityID:   user.Username,
+		ClientIp:   "10.0.0.3",
+	})
+	require.NoError(t, err)
+
+	arg := CountAuditLogsParams{
+		Username: sql.NullString{String: user.Username, Valid: true},
+		FromTime: log.CreatedAt,
+		ToTime:   log.CreatedAt.Add(time.Second),
+		OrgID:    DefaultOrgID,
+	}
+	count, err := testQueries.CountAuditLogs(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	// the range excludes its end
+	arg.FromTime, arg.ToTime = log.CreatedAt.Add(-time.Second), log.CreatedAt
+	count, err = testQueries.CountAuditLogs(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
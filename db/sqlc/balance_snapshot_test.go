@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"github.com/lib/pq"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func today() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func TestCreateBalanceSnapshot(t *testing.T) {
+	account := CreateRandomAccount(t)
+
+	arg := CreateBalanceSnapshotParams{
+		AccountID: account.ID,
+		Day:       today(),
+		Balance:   account.Balance,
+	}
+	snapshot, err := testQueries.CreateBalanceSnapshot(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, snapshot.ID)
+	require.Equal(t, arg.AccountID, snapshot.AccountID)
+	require.True(t, arg.Day.Equal(snapshot.Day))
+	require.Equal(t, arg.Balance, snapshot.Balance)
+	require.NotZero(t, snapshot.CreatedAt)
+
+	// an account has a single snapshot per day
+	_, err = testQueries.CreateBalanceSnapshot(context.Background(), arg)
+	require.Error(t, err)
+
+	pqErr, ok := err.(*pq.Error)
+	require.True(t, ok)
+	require.Equal(t, "unique_violation", pqErr.Code.Name())
+
+	arg.Day = arg.Day.AddDate(0, 0, -1)
+	_, err = testQueries.CreateBalanceSnapshot(context.Background(), arg)
+	require.NoError(t, err)
+}
+
+func TestCreateDailyBalanceSnapshots(t *testing.T) {
+	store := NewStore(testDB)
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+
+	// the account existed yesterday and has moved money since then
+	_, err := testDB.ExecContext(context.Background(), `UPDATE accounts SET created_at = now() - interval '2 days' WHERE id = $1`, account1.ID)
+	require.NoError(t, err)
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	yesterday := today().AddDate(0, 0, -1)
+	created, err := testQueries.CreateDailyBalanceSnapshots(context.Background(), yesterday)
+	require.NoError(t, err)
+	require.NotZero(t, created)
+
+	snapshots, err := testQueries.ListBalanceSnapshots(context.Background(), ListBalanceSnapshotsParams{
+		AccountID: account1.ID,
+		FromDay:   yesterday,
+		ToDay:     today(),
+	})
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	require.True(t, yesterday.Equal(snapshots[0].Day))
+	// the closing balance doesn't include the transfer made today
+	require.Equal(t, account1.Balance, snapshots[0].Balance)
+
+	// the account didn't exist yesterday
+	snapshots, err = testQueries.ListBalanceSnapshots(context.Background(), ListBalanceSnapshotsParams{
+		AccountID: account2.ID,
+		FromDay:   yesterday,
+		ToDay:     yesterday,
+	})
+	require.NoError(t, err)
+	require.Empty(t, snapshots)
+
+	// snapshotting the same day again keeps the existing snapshots
+	created, err = testQueries.CreateDailyBalanceSnapshots(context.Background(), yesterday)
+	require.NoError(t, err)
+	require.Zero(t, created)
+
+	created, err = testQueries.CreateDailyBalanceSnapshots(context.Background(), today())
+	require.NoError(t, err)
+	require.NotZero(t, created)
+
+	snapshots, err = testQueries.ListBalanceSnapshots(context.Background(), ListBalanceSnapshotsParams{
+		AccountID: account1.ID,
+		FromDay:   yesterday,
+		ToDay:     today(),
+	})
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	require.Equal(t, result.FromAccountID.Balance, snapshots[1].Balance)
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/lib/pq"
+	"net"
+)
+
+// IsConnectionError reports whether err comes from losing the database connection rather than from the query itself
+// database/sql drops the broken connection from the pool and dials a new one on the next call, so the error goes away
+// once the database is reachable again: callers should report it as a temporary outage, not as a failure of the request
+func IsConnectionError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// class 08 covers the connection exceptions, the 57P0x codes a server shutting down or still starting up
+		if pqErr.Code.Class() == "08" {
+			return true
+		}
+		switch pqErr.Code.Name() {
+		case "admin_shutdown", "crash_shutdown", "cannot_connect_now":
+			return true
+		}
+		return false
+	}
+
+	// the driver returns the dial and read errors as is when the database goes away mid query
+	var netErr *net.OpError
+	return errors.As(err, &netErr)
+}
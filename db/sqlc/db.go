@@ -24,12 +24,90 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.accountNumberExistsStmt, err = db.PrepareContext(ctx, accountNumberExists); err != nil {
+		return nil, fmt.Errorf("error preparing query AccountNumberExists: %w", err)
+	}
+	if q.addAccountHeldBalanceStmt, err = db.PrepareContext(ctx, addAccountHeldBalance); err != nil {
+		return nil, fmt.Errorf("error preparing query AddAccountHeldBalance: %w", err)
+	}
+	if q.addDailyTransferTotalStmt, err = db.PrepareContext(ctx, addDailyTransferTotal); err != nil {
+		return nil, fmt.Errorf("error preparing query AddDailyTransferTotal: %w", err)
+	}
+	if q.cancelAccountScheduledTransfersStmt, err = db.PrepareContext(ctx, cancelAccountScheduledTransfers); err != nil {
+		return nil, fmt.Errorf("error preparing query CancelAccountScheduledTransfers: %w", err)
+	}
+	if q.cancelScheduledTransferStmt, err = db.PrepareContext(ctx, cancelScheduledTransfer); err != nil {
+		return nil, fmt.Errorf("error preparing query CancelScheduledTransfer: %w", err)
+	}
+	if q.claimScheduledTransferStmt, err = db.PrepareContext(ctx, claimScheduledTransfer); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimScheduledTransfer: %w", err)
+	}
+	if q.countAccountsStmt, err = db.PrepareContext(ctx, countAccounts); err != nil {
+		return nil, fmt.Errorf("error preparing query CountAccounts: %w", err)
+	}
+	if q.countAccountsByCurrencyStmt, err = db.PrepareContext(ctx, countAccountsByCurrency); err != nil {
+		return nil, fmt.Errorf("error preparing query CountAccountsByCurrency: %w", err)
+	}
+	if q.countAllAccountsStmt, err = db.PrepareContext(ctx, countAllAccounts); err != nil {
+		return nil, fmt.Errorf("error preparing query CountAllAccounts: %w", err)
+	}
+	if q.countAuditLogsStmt, err = db.PrepareContext(ctx, countAuditLogs); err != nil {
+		return nil, fmt.Errorf("error preparing query CountAuditLogs: %w", err)
+	}
+	if q.countEntriesByAccountStmt, err = db.PrepareContext(ctx, countEntriesByAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query CountEntriesByAccount: %w", err)
+	}
+	if q.countEntriesByAccountInRangeStmt, err = db.PrepareContext(ctx, countEntriesByAccountInRange); err != nil {
+		return nil, fmt.Errorf("error preparing query CountEntriesByAccountInRange: %w", err)
+	}
+	if q.countScheduledTransfersStmt, err = db.PrepareContext(ctx, countScheduledTransfers); err != nil {
+		return nil, fmt.Errorf("error preparing query CountScheduledTransfers: %w", err)
+	}
+	if q.countSearchUsersStmt, err = db.PrepareContext(ctx, countSearchUsers); err != nil {
+		return nil, fmt.Errorf("error preparing query CountSearchUsers: %w", err)
+	}
+	if q.countTransfersStmt, err = db.PrepareContext(ctx, countTransfers); err != nil {
+		return nil, fmt.Errorf("error preparing query CountTransfers: %w", err)
+	}
 	if q.createAccountStmt, err = db.PrepareContext(ctx, createAccount); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateAccount: %w", err)
 	}
+	if q.createAccountIdempotencyKeyStmt, err = db.PrepareContext(ctx, createAccountIdempotencyKey); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAccountIdempotencyKey: %w", err)
+	}
+	if q.createAccountOwnerChangeStmt, err = db.PrepareContext(ctx, createAccountOwnerChange); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAccountOwnerChange: %w", err)
+	}
+	if q.createAuditLogStmt, err = db.PrepareContext(ctx, createAuditLog); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAuditLog: %w", err)
+	}
+	if q.createBalanceSnapshotStmt, err = db.PrepareContext(ctx, createBalanceSnapshot); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateBalanceSnapshot: %w", err)
+	}
+	if q.createDailyBalanceSnapshotsStmt, err = db.PrepareContext(ctx, createDailyBalanceSnapshots); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateDailyBalanceSnapshots: %w", err)
+	}
 	if q.createEntryStmt, err = db.PrepareContext(ctx, createEntry); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateEntry: %w", err)
 	}
+	if q.createHoldStmt, err = db.PrepareContext(ctx, createHold); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateHold: %w", err)
+	}
+	if q.createIdempotencyKeyStmt, err = db.PrepareContext(ctx, createIdempotencyKey); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateIdempotencyKey: %w", err)
+	}
+	if q.createOrganizationStmt, err = db.PrepareContext(ctx, createOrganization); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateOrganization: %w", err)
+	}
+	if q.createOutboxEventStmt, err = db.PrepareContext(ctx, createOutboxEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateOutboxEvent: %w", err)
+	}
+	if q.createPasswordResetStmt, err = db.PrepareContext(ctx, createPasswordReset); err != nil {
+		return nil, fmt.Errorf("error preparing query CreatePasswordReset: %w", err)
+	}
+	if q.createScheduledTransferStmt, err = db.PrepareContext(ctx, createScheduledTransfer); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateScheduledTransfer: %w", err)
+	}
 	if q.createSessionStmt, err = db.PrepareContext(ctx, createSession); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateSession: %w", err)
 	}
@@ -51,63 +129,358 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.deleteUserStmt, err = db.PrepareContext(ctx, deleteUser); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteUser: %w", err)
 	}
+	if q.enableTotpSecretStmt, err = db.PrepareContext(ctx, enableTotpSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query EnableTotpSecret: %w", err)
+	}
 	if q.getAccountStmt, err = db.PrepareContext(ctx, getAccount); err != nil {
 		return nil, fmt.Errorf("error preparing query GetAccount: %w", err)
 	}
+	if q.getAccountByNumberStmt, err = db.PrepareContext(ctx, getAccountByNumber); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccountByNumber: %w", err)
+	}
 	if q.getAccountForUpdateStmt, err = db.PrepareContext(ctx, getAccountForUpdate); err != nil {
 		return nil, fmt.Errorf("error preparing query GetAccountForUpdate: %w", err)
 	}
+	if q.getAccountForUpdateIncludingDeletedStmt, err = db.PrepareContext(ctx, getAccountForUpdateIncludingDeleted); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccountForUpdateIncludingDeleted: %w", err)
+	}
+	if q.getAccountIdempotencyKeyStmt, err = db.PrepareContext(ctx, getAccountIdempotencyKey); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccountIdempotencyKey: %w", err)
+	}
+	if q.getAccountIncludingDeletedStmt, err = db.PrepareContext(ctx, getAccountIncludingDeleted); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccountIncludingDeleted: %w", err)
+	}
+	if q.getAccountNumbersStmt, err = db.PrepareContext(ctx, getAccountNumbers); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccountNumbers: %w", err)
+	}
+	if q.getAccountStatsStmt, err = db.PrepareContext(ctx, getAccountStats); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccountStats: %w", err)
+	}
+	if q.getAccountsByIDsStmt, err = db.PrepareContext(ctx, getAccountsByIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccountsByIDs: %w", err)
+	}
+	if q.getAccountsByNumbersStmt, err = db.PrepareContext(ctx, getAccountsByNumbers); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAccountsByNumbers: %w", err)
+	}
+	if q.getDailyTransferTotalStmt, err = db.PrepareContext(ctx, getDailyTransferTotal); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDailyTransferTotal: %w", err)
+	}
 	if q.getEntryStmt, err = db.PrepareContext(ctx, getEntry); err != nil {
 		return nil, fmt.Errorf("error preparing query GetEntry: %w", err)
 	}
+	if q.getHoldStmt, err = db.PrepareContext(ctx, getHold); err != nil {
+		return nil, fmt.Errorf("error preparing query GetHold: %w", err)
+	}
+	if q.getHoldForUpdateStmt, err = db.PrepareContext(ctx, getHoldForUpdate); err != nil {
+		return nil, fmt.Errorf("error preparing query GetHoldForUpdate: %w", err)
+	}
+	if q.getIdempotencyKeyStmt, err = db.PrepareContext(ctx, getIdempotencyKey); err != nil {
+		return nil, fmt.Errorf("error preparing query GetIdempotencyKey: %w", err)
+	}
+	if q.getNotificationPreferencesStmt, err = db.PrepareContext(ctx, getNotificationPreferences); err != nil {
+		return nil, fmt.Errorf("error preparing query GetNotificationPreferences: %w", err)
+	}
+	if q.getOrganizationStmt, err = db.PrepareContext(ctx, getOrganization); err != nil {
+		return nil, fmt.Errorf("error preparing query GetOrganization: %w", err)
+	}
+	if q.getPasswordResetForUpdateStmt, err = db.PrepareContext(ctx, getPasswordResetForUpdate); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPasswordResetForUpdate: %w", err)
+	}
+	if q.getScheduledTransferStmt, err = db.PrepareContext(ctx, getScheduledTransfer); err != nil {
+		return nil, fmt.Errorf("error preparing query GetScheduledTransfer: %w", err)
+	}
 	if q.getSessionStmt, err = db.PrepareContext(ctx, getSession); err != nil {
 		return nil, fmt.Errorf("error preparing query GetSession: %w", err)
 	}
+	if q.getTotpSecretStmt, err = db.PrepareContext(ctx, getTotpSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTotpSecret: %w", err)
+	}
 	if q.getTransferStmt, err = db.PrepareContext(ctx, getTransfer); err != nil {
 		return nil, fmt.Errorf("error preparing query GetTransfer: %w", err)
 	}
+	if q.getTransferByUUIDStmt, err = db.PrepareContext(ctx, getTransferByUUID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTransferByUUID: %w", err)
+	}
+	if q.getTransferForUpdateStmt, err = db.PrepareContext(ctx, getTransferForUpdate); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTransferForUpdate: %w", err)
+	}
 	if q.getUserStmt, err = db.PrepareContext(ctx, getUser); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUser: %w", err)
 	}
+	if q.getUserByEmailStmt, err = db.PrepareContext(ctx, getUserByEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserByEmail: %w", err)
+	}
 	if q.getUserForUpdateStmt, err = db.PrepareContext(ctx, getUserForUpdate); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUserForUpdate: %w", err)
 	}
+	if q.getUserPasswordChangedAtStmt, err = db.PrepareContext(ctx, getUserPasswordChangedAt); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserPasswordChangedAt: %w", err)
+	}
+	if q.listAccountOwnerChangesStmt, err = db.PrepareContext(ctx, listAccountOwnerChanges); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAccountOwnerChanges: %w", err)
+	}
+	if q.listAccountStatementStmt, err = db.PrepareContext(ctx, listAccountStatement); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAccountStatement: %w", err)
+	}
 	if q.listAccountsStmt, err = db.PrepareContext(ctx, listAccounts); err != nil {
 		return nil, fmt.Errorf("error preparing query ListAccounts: %w", err)
 	}
+	if q.listAccountsAfterNumberStmt, err = db.PrepareContext(ctx, listAccountsAfterNumber); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAccountsAfterNumber: %w", err)
+	}
+	if q.listAccountsByCurrencyStmt, err = db.PrepareContext(ctx, listAccountsByCurrency); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAccountsByCurrency: %w", err)
+	}
+	if q.listAllAccountsStmt, err = db.PrepareContext(ctx, listAllAccounts); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAllAccounts: %w", err)
+	}
+	if q.listAuditLogsStmt, err = db.PrepareContext(ctx, listAuditLogs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListAuditLogs: %w", err)
+	}
+	if q.listBalanceSnapshotsStmt, err = db.PrepareContext(ctx, listBalanceSnapshots); err != nil {
+		return nil, fmt.Errorf("error preparing query ListBalanceSnapshots: %w", err)
+	}
+	if q.listDueScheduledTransfersStmt, err = db.PrepareContext(ctx, listDueScheduledTransfers); err != nil {
+		return nil, fmt.Errorf("error preparing query ListDueScheduledTransfers: %w", err)
+	}
 	if q.listEntriesStmt, err = db.PrepareContext(ctx, listEntries); err != nil {
 		return nil, fmt.Errorf("error preparing query ListEntries: %w", err)
 	}
+	if q.listEntriesByAccountStmt, err = db.PrepareContext(ctx, listEntriesByAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query ListEntriesByAccount: %w", err)
+	}
+	if q.listEntriesByAccountInRangeStmt, err = db.PrepareContext(ctx, listEntriesByAccountInRange); err != nil {
+		return nil, fmt.Errorf("error preparing query ListEntriesByAccountInRange: %w", err)
+	}
+	if q.listExpiredHoldsStmt, err = db.PrepareContext(ctx, listExpiredHolds); err != nil {
+		return nil, fmt.Errorf("error preparing query ListExpiredHolds: %w", err)
+	}
+	if q.listScheduledTransfersStmt, err = db.PrepareContext(ctx, listScheduledTransfers); err != nil {
+		return nil, fmt.Errorf("error preparing query ListScheduledTransfers: %w", err)
+	}
 	if q.listTransfersStmt, err = db.PrepareContext(ctx, listTransfers); err != nil {
 		return nil, fmt.Errorf("error preparing query ListTransfers: %w", err)
 	}
+	if q.listUnpublishedOutboxEventsStmt, err = db.PrepareContext(ctx, listUnpublishedOutboxEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUnpublishedOutboxEvents: %w", err)
+	}
 	if q.listUsersStmt, err = db.PrepareContext(ctx, listUsers); err != nil {
 		return nil, fmt.Errorf("error preparing query ListUsers: %w", err)
 	}
+	if q.markOutboxEventPublishedStmt, err = db.PrepareContext(ctx, markOutboxEventPublished); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkOutboxEventPublished: %w", err)
+	}
+	if q.markPasswordResetUsedStmt, err = db.PrepareContext(ctx, markPasswordResetUsed); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkPasswordResetUsed: %w", err)
+	}
+	if q.markTransferReversedStmt, err = db.PrepareContext(ctx, markTransferReversed); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkTransferReversed: %w", err)
+	}
+	if q.recordFailedLoginStmt, err = db.PrepareContext(ctx, recordFailedLogin); err != nil {
+		return nil, fmt.Errorf("error preparing query RecordFailedLogin: %w", err)
+	}
+	if q.recordScheduledTransferFailureStmt, err = db.PrepareContext(ctx, recordScheduledTransferFailure); err != nil {
+		return nil, fmt.Errorf("error preparing query RecordScheduledTransferFailure: %w", err)
+	}
+	if q.recordScheduledTransferRunStmt, err = db.PrepareContext(ctx, recordScheduledTransferRun); err != nil {
+		return nil, fmt.Errorf("error preparing query RecordScheduledTransferRun: %w", err)
+	}
+	if q.resetFailedLoginsStmt, err = db.PrepareContext(ctx, resetFailedLogins); err != nil {
+		return nil, fmt.Errorf("error preparing query ResetFailedLogins: %w", err)
+	}
+	if q.restoreAccountStmt, err = db.PrepareContext(ctx, restoreAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query RestoreAccount: %w", err)
+	}
+	if q.searchUsersStmt, err = db.PrepareContext(ctx, searchUsers); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchUsers: %w", err)
+	}
+	if q.setAccountFrozenStmt, err = db.PrepareContext(ctx, setAccountFrozen); err != nil {
+		return nil, fmt.Errorf("error preparing query SetAccountFrozen: %w", err)
+	}
+	if q.setAccountLabelsStmt, err = db.PrepareContext(ctx, setAccountLabels); err != nil {
+		return nil, fmt.Errorf("error preparing query SetAccountLabels: %w", err)
+	}
+	if q.setAccountLowBalanceThresholdStmt, err = db.PrepareContext(ctx, setAccountLowBalanceThreshold); err != nil {
+		return nil, fmt.Errorf("error preparing query SetAccountLowBalanceThreshold: %w", err)
+	}
+	if q.setAccountSpendingLimitsStmt, err = db.PrepareContext(ctx, setAccountSpendingLimits); err != nil {
+		return nil, fmt.Errorf("error preparing query SetAccountSpendingLimits: %w", err)
+	}
+	if q.softDeleteAccountStmt, err = db.PrepareContext(ctx, softDeleteAccount); err != nil {
+		return nil, fmt.Errorf("error preparing query SoftDeleteAccount: %w", err)
+	}
+	if q.sumOutgoingTransfersStmt, err = db.PrepareContext(ctx, sumOutgoingTransfers); err != nil {
+		return nil, fmt.Errorf("error preparing query SumOutgoingTransfers: %w", err)
+	}
 	if q.updateAccountStmt, err = db.PrepareContext(ctx, updateAccount); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateAccount: %w", err)
 	}
 	if q.updateAccountBalanceStmt, err = db.PrepareContext(ctx, updateAccountBalance); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateAccountBalance: %w", err)
 	}
+	if q.updateAccountOwnerStmt, err = db.PrepareContext(ctx, updateAccountOwner); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateAccountOwner: %w", err)
+	}
+	if q.updateHoldStatusStmt, err = db.PrepareContext(ctx, updateHoldStatus); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateHoldStatus: %w", err)
+	}
 	if q.updateUserStmt, err = db.PrepareContext(ctx, updateUser); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateUser: %w", err)
 	}
+	if q.updateUserPasswordStmt, err = db.PrepareContext(ctx, updateUserPassword); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateUserPassword: %w", err)
+	}
+	if q.upsertNotificationPreferencesStmt, err = db.PrepareContext(ctx, upsertNotificationPreferences); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertNotificationPreferences: %w", err)
+	}
+	if q.upsertTotpSecretStmt, err = db.PrepareContext(ctx, upsertTotpSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertTotpSecret: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.accountNumberExistsStmt != nil {
+		if cerr := q.accountNumberExistsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing accountNumberExistsStmt: %w", cerr)
+		}
+	}
+	if q.addAccountHeldBalanceStmt != nil {
+		if cerr := q.addAccountHeldBalanceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addAccountHeldBalanceStmt: %w", cerr)
+		}
+	}
+	if q.addDailyTransferTotalStmt != nil {
+		if cerr := q.addDailyTransferTotalStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addDailyTransferTotalStmt: %w", cerr)
+		}
+	}
+	if q.cancelAccountScheduledTransfersStmt != nil {
+		if cerr := q.cancelAccountScheduledTransfersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing cancelAccountScheduledTransfersStmt: %w", cerr)
+		}
+	}
+	if q.cancelScheduledTransferStmt != nil {
+		if cerr := q.cancelScheduledTransferStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing cancelScheduledTransferStmt: %w", cerr)
+		}
+	}
+	if q.claimScheduledTransferStmt != nil {
+		if cerr := q.claimScheduledTransferStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimScheduledTransferStmt: %w", cerr)
+		}
+	}
+	if q.countAccountsStmt != nil {
+		if cerr := q.countAccountsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countAccountsStmt: %w", cerr)
+		}
+	}
+	if q.countAccountsByCurrencyStmt != nil {
+		if cerr := q.countAccountsByCurrencyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countAccountsByCurrencyStmt: %w", cerr)
+		}
+	}
+	if q.countAllAccountsStmt != nil {
+		if cerr := q.countAllAccountsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countAllAccountsStmt: %w", cerr)
+		}
+	}
+	if q.countAuditLogsStmt != nil {
+		if cerr := q.countAuditLogsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countAuditLogsStmt: %w", cerr)
+		}
+	}
+	if q.countEntriesByAccountStmt != nil {
+		if cerr := q.countEntriesByAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countEntriesByAccountStmt: %w", cerr)
+		}
+	}
+	if q.countEntriesByAccountInRangeStmt != nil {
+		if cerr := q.countEntriesByAccountInRangeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countEntriesByAccountInRangeStmt: %w", cerr)
+		}
+	}
+	if q.countScheduledTransfersStmt != nil {
+		if cerr := q.countScheduledTransfersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countScheduledTransfersStmt: %w", cerr)
+		}
+	}
+	if q.countSearchUsersStmt != nil {
+		if cerr := q.countSearchUsersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countSearchUsersStmt: %w", cerr)
+		}
+	}
+	if q.countTransfersStmt != nil {
+		if cerr := q.countTransfersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countTransfersStmt: %w", cerr)
+		}
+	}
 	if q.createAccountStmt != nil {
 		if cerr := q.createAccountStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createAccountStmt: %w", cerr)
 		}
 	}
+	if q.createAccountIdempotencyKeyStmt != nil {
+		if cerr := q.createAccountIdempotencyKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAccountIdempotencyKeyStmt: %w", cerr)
+		}
+	}
+	if q.createAccountOwnerChangeStmt != nil {
+		if cerr := q.createAccountOwnerChangeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAccountOwnerChangeStmt: %w", cerr)
+		}
+	}
+	if q.createAuditLogStmt != nil {
+		if cerr := q.createAuditLogStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAuditLogStmt: %w", cerr)
+		}
+	}
+	if q.createBalanceSnapshotStmt != nil {
+		if cerr := q.createBalanceSnapshotStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createBalanceSnapshotStmt: %w", cerr)
+		}
+	}
+	if q.createDailyBalanceSnapshotsStmt != nil {
+		if cerr := q.createDailyBalanceSnapshotsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createDailyBalanceSnapshotsStmt: %w", cerr)
+		}
+	}
 	if q.createEntryStmt != nil {
 		if cerr := q.createEntryStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createEntryStmt: %w", cerr)
 		}
 	}
+	if q.createHoldStmt != nil {
+		if cerr := q.createHoldStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createHoldStmt: %w", cerr)
+		}
+	}
+	if q.createIdempotencyKeyStmt != nil {
+		if cerr := q.createIdempotencyKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createIdempotencyKeyStmt: %w", cerr)
+		}
+	}
+	if q.createOrganizationStmt != nil {
+		if cerr := q.createOrganizationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createOrganizationStmt: %w", cerr)
+		}
+	}
+	if q.createOutboxEventStmt != nil {
+		if cerr := q.createOutboxEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createOutboxEventStmt: %w", cerr)
+		}
+	}
+	if q.createPasswordResetStmt != nil {
+		if cerr := q.createPasswordResetStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createPasswordResetStmt: %w", cerr)
+		}
+	}
+	if q.createScheduledTransferStmt != nil {
+		if cerr := q.createScheduledTransferStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createScheduledTransferStmt: %w", cerr)
+		}
+	}
 	if q.createSessionStmt != nil {
 		if cerr := q.createSessionStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createSessionStmt: %w", cerr)
@@ -143,61 +516,311 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteUserStmt: %w", cerr)
 		}
 	}
+	if q.enableTotpSecretStmt != nil {
+		if cerr := q.enableTotpSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing enableTotpSecretStmt: %w", cerr)
+		}
+	}
 	if q.getAccountStmt != nil {
 		if cerr := q.getAccountStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getAccountStmt: %w", cerr)
 		}
 	}
+	if q.getAccountByNumberStmt != nil {
+		if cerr := q.getAccountByNumberStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccountByNumberStmt: %w", cerr)
+		}
+	}
 	if q.getAccountForUpdateStmt != nil {
 		if cerr := q.getAccountForUpdateStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getAccountForUpdateStmt: %w", cerr)
 		}
 	}
+	if q.getAccountForUpdateIncludingDeletedStmt != nil {
+		if cerr := q.getAccountForUpdateIncludingDeletedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccountForUpdateIncludingDeletedStmt: %w", cerr)
+		}
+	}
+	if q.getAccountIdempotencyKeyStmt != nil {
+		if cerr := q.getAccountIdempotencyKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccountIdempotencyKeyStmt: %w", cerr)
+		}
+	}
+	if q.getAccountIncludingDeletedStmt != nil {
+		if cerr := q.getAccountIncludingDeletedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccountIncludingDeletedStmt: %w", cerr)
+		}
+	}
+	if q.getAccountNumbersStmt != nil {
+		if cerr := q.getAccountNumbersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccountNumbersStmt: %w", cerr)
+		}
+	}
+	if q.getAccountStatsStmt != nil {
+		if cerr := q.getAccountStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccountStatsStmt: %w", cerr)
+		}
+	}
+	if q.getAccountsByIDsStmt != nil {
+		if cerr := q.getAccountsByIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccountsByIDsStmt: %w", cerr)
+		}
+	}
+	if q.getAccountsByNumbersStmt != nil {
+		if cerr := q.getAccountsByNumbersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAccountsByNumbersStmt: %w", cerr)
+		}
+	}
+	if q.getDailyTransferTotalStmt != nil {
+		if cerr := q.getDailyTransferTotalStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDailyTransferTotalStmt: %w", cerr)
+		}
+	}
 	if q.getEntryStmt != nil {
 		if cerr := q.getEntryStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getEntryStmt: %w", cerr)
 		}
 	}
+	if q.getHoldStmt != nil {
+		if cerr := q.getHoldStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getHoldStmt: %w", cerr)
+		}
+	}
+	if q.getHoldForUpdateStmt != nil {
+		if cerr := q.getHoldForUpdateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getHoldForUpdateStmt: %w", cerr)
+		}
+	}
+	if q.getIdempotencyKeyStmt != nil {
+		if cerr := q.getIdempotencyKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getIdempotencyKeyStmt: %w", cerr)
+		}
+	}
+	if q.getNotificationPreferencesStmt != nil {
+		if cerr := q.getNotificationPreferencesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getNotificationPreferencesStmt: %w", cerr)
+		}
+	}
+	if q.getOrganizationStmt != nil {
+		if cerr := q.getOrganizationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getOrganizationStmt: %w", cerr)
+		}
+	}
+	if q.getPasswordResetForUpdateStmt != nil {
+		if cerr := q.getPasswordResetForUpdateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPasswordResetForUpdateStmt: %w", cerr)
+		}
+	}
+	if q.getScheduledTransferStmt != nil {
+		if cerr := q.getScheduledTransferStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getScheduledTransferStmt: %w", cerr)
+		}
+	}
 	if q.getSessionStmt != nil {
 		if cerr := q.getSessionStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getSessionStmt: %w", cerr)
 		}
 	}
+	if q.getTotpSecretStmt != nil {
+		if cerr := q.getTotpSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTotpSecretStmt: %w", cerr)
+		}
+	}
 	if q.getTransferStmt != nil {
 		if cerr := q.getTransferStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getTransferStmt: %w", cerr)
 		}
 	}
+	if q.getTransferByUUIDStmt != nil {
+		if cerr := q.getTransferByUUIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTransferByUUIDStmt: %w", cerr)
+		}
+	}
+	if q.getTransferForUpdateStmt != nil {
+		if cerr := q.getTransferForUpdateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTransferForUpdateStmt: %w", cerr)
+		}
+	}
 	if q.getUserStmt != nil {
 		if cerr := q.getUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getUserStmt: %w", cerr)
 		}
 	}
+	if q.getUserByEmailStmt != nil {
+		if cerr := q.getUserByEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserByEmailStmt: %w", cerr)
+		}
+	}
 	if q.getUserForUpdateStmt != nil {
 		if cerr := q.getUserForUpdateStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getUserForUpdateStmt: %w", cerr)
 		}
 	}
+	if q.getUserPasswordChangedAtStmt != nil {
+		if cerr := q.getUserPasswordChangedAtStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserPasswordChangedAtStmt: %w", cerr)
+		}
+	}
+	if q.listAccountOwnerChangesStmt != nil {
+		if cerr := q.listAccountOwnerChangesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAccountOwnerChangesStmt: %w", cerr)
+		}
+	}
+	if q.listAccountStatementStmt != nil {
+		if cerr := q.listAccountStatementStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAccountStatementStmt: %w", cerr)
+		}
+	}
 	if q.listAccountsStmt != nil {
 		if cerr := q.listAccountsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listAccountsStmt: %w", cerr)
 		}
 	}
+	if q.listAccountsAfterNumberStmt != nil {
+		if cerr := q.listAccountsAfterNumberStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAccountsAfterNumberStmt: %w", cerr)
+		}
+	}
+	if q.listAccountsByCurrencyStmt != nil {
+		if cerr := q.listAccountsByCurrencyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAccountsByCurrencyStmt: %w", cerr)
+		}
+	}
+	if q.listAllAccountsStmt != nil {
+		if cerr := q.listAllAccountsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAllAccountsStmt: %w", cerr)
+		}
+	}
+	if q.listAuditLogsStmt != nil {
+		if cerr := q.listAuditLogsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listAuditLogsStmt: %w", cerr)
+		}
+	}
+	if q.listBalanceSnapshotsStmt != nil {
+		if cerr := q.listBalanceSnapshotsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listBalanceSnapshotsStmt: %w", cerr)
+		}
+	}
+	if q.listDueScheduledTransfersStmt != nil {
+		if cerr := q.listDueScheduledTransfersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listDueScheduledTransfersStmt: %w", cerr)
+		}
+	}
 	if q.listEntriesStmt != nil {
 		if cerr := q.listEntriesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listEntriesStmt: %w", cerr)
 		}
 	}
+	if q.listEntriesByAccountStmt != nil {
+		if cerr := q.listEntriesByAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listEntriesByAccountStmt: %w", cerr)
+		}
+	}
+	if q.listEntriesByAccountInRangeStmt != nil {
+		if cerr := q.listEntriesByAccountInRangeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listEntriesByAccountInRangeStmt: %w", cerr)
+		}
+	}
+	if q.listExpiredHoldsStmt != nil {
+		if cerr := q.listExpiredHoldsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listExpiredHoldsStmt: %w", cerr)
+		}
+	}
+	if q.listScheduledTransfersStmt != nil {
+		if cerr := q.listScheduledTransfersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listScheduledTransfersStmt: %w", cerr)
+		}
+	}
 	if q.listTransfersStmt != nil {
 		if cerr := q.listTransfersStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listTransfersStmt: %w", cerr)
 		}
 	}
+	if q.listUnpublishedOutboxEventsStmt != nil {
+		if cerr := q.listUnpublishedOutboxEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUnpublishedOutboxEventsStmt: %w", cerr)
+		}
+	}
 	if q.listUsersStmt != nil {
 		if cerr := q.listUsersStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listUsersStmt: %w", cerr)
 		}
 	}
+	if q.markOutboxEventPublishedStmt != nil {
+		if cerr := q.markOutboxEventPublishedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markOutboxEventPublishedStmt: %w", cerr)
+		}
+	}
+	if q.markPasswordResetUsedStmt != nil {
+		if cerr := q.markPasswordResetUsedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markPasswordResetUsedStmt: %w", cerr)
+		}
+	}
+	if q.markTransferReversedStmt != nil {
+		if cerr := q.markTransferReversedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markTransferReversedStmt: %w", cerr)
+		}
+	}
+	if q.recordFailedLoginStmt != nil {
+		if cerr := q.recordFailedLoginStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing recordFailedLoginStmt: %w", cerr)
+		}
+	}
+	if q.recordScheduledTransferFailureStmt != nil {
+		if cerr := q.recordScheduledTransferFailureStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing recordScheduledTransferFailureStmt: %w", cerr)
+		}
+	}
+	if q.recordScheduledTransferRunStmt != nil {
+		if cerr := q.recordScheduledTransferRunStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing recordScheduledTransferRunStmt: %w", cerr)
+		}
+	}
+	if q.resetFailedLoginsStmt != nil {
+		if cerr := q.resetFailedLoginsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing resetFailedLoginsStmt: %w", cerr)
+		}
+	}
+	if q.restoreAccountStmt != nil {
+		if cerr := q.restoreAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing restoreAccountStmt: %w", cerr)
+		}
+	}
+	if q.searchUsersStmt != nil {
+		if cerr := q.searchUsersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchUsersStmt: %w", cerr)
+		}
+	}
+	if q.setAccountFrozenStmt != nil {
+		if cerr := q.setAccountFrozenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setAccountFrozenStmt: %w", cerr)
+		}
+	}
+	if q.setAccountLabelsStmt != nil {
+		if cerr := q.setAccountLabelsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setAccountLabelsStmt: %w", cerr)
+		}
+	}
+	if q.setAccountLowBalanceThresholdStmt != nil {
+		if cerr := q.setAccountLowBalanceThresholdStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setAccountLowBalanceThresholdStmt: %w", cerr)
+		}
+	}
+	if q.setAccountSpendingLimitsStmt != nil {
+		if cerr := q.setAccountSpendingLimitsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setAccountSpendingLimitsStmt: %w", cerr)
+		}
+	}
+	if q.softDeleteAccountStmt != nil {
+		if cerr := q.softDeleteAccountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing softDeleteAccountStmt: %w", cerr)
+		}
+	}
+	if q.sumOutgoingTransfersStmt != nil {
+		if cerr := q.sumOutgoingTransfersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing sumOutgoingTransfersStmt: %w", cerr)
+		}
+	}
 	if q.updateAccountStmt != nil {
 		if cerr := q.updateAccountStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateAccountStmt: %w", cerr)
@@ -208,11 +831,36 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing updateAccountBalanceStmt: %w", cerr)
 		}
 	}
+	if q.updateAccountOwnerStmt != nil {
+		if cerr := q.updateAccountOwnerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateAccountOwnerStmt: %w", cerr)
+		}
+	}
+	if q.updateHoldStatusStmt != nil {
+		if cerr := q.updateHoldStatusStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateHoldStatusStmt: %w", cerr)
+		}
+	}
 	if q.updateUserStmt != nil {
 		if cerr := q.updateUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateUserStmt: %w", cerr)
 		}
 	}
+	if q.updateUserPasswordStmt != nil {
+		if cerr := q.updateUserPasswordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateUserPasswordStmt: %w", cerr)
+		}
+	}
+	if q.upsertNotificationPreferencesStmt != nil {
+		if cerr := q.upsertNotificationPreferencesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertNotificationPreferencesStmt: %w", cerr)
+		}
+	}
+	if q.upsertTotpSecretStmt != nil {
+		if cerr := q.upsertTotpSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertTotpSecretStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -250,59 +898,221 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                       DBTX
-	tx                       *sql.Tx
-	createAccountStmt        *sql.Stmt
-	createEntryStmt          *sql.Stmt
-	createSessionStmt        *sql.Stmt
-	createTransferStmt       *sql.Stmt
-	createUserStmt           *sql.Stmt
-	deleteAccountStmt        *sql.Stmt
-	deleteEntryStmt          *sql.Stmt
-	deleteTransferStmt       *sql.Stmt
-	deleteUserStmt           *sql.Stmt
-	getAccountStmt           *sql.Stmt
-	getAccountForUpdateStmt  *sql.Stmt
-	getEntryStmt             *sql.Stmt
-	getSessionStmt           *sql.Stmt
-	getTransferStmt          *sql.Stmt
-	getUserStmt              *sql.Stmt
-	getUserForUpdateStmt     *sql.Stmt
-	listAccountsStmt         *sql.Stmt
-	listEntriesStmt          *sql.Stmt
-	listTransfersStmt        *sql.Stmt
-	listUsersStmt            *sql.Stmt
-	updateAccountStmt        *sql.Stmt
-	updateAccountBalanceStmt *sql.Stmt
-	updateUserStmt           *sql.Stmt
+	db                                      DBTX
+	tx                                      *sql.Tx
+	accountNumberExistsStmt                 *sql.Stmt
+	addAccountHeldBalanceStmt               *sql.Stmt
+	addDailyTransferTotalStmt               *sql.Stmt
+	cancelAccountScheduledTransfersStmt     *sql.Stmt
+	cancelScheduledTransferStmt             *sql.Stmt
+	claimScheduledTransferStmt              *sql.Stmt
+	countAccountsStmt                       *sql.Stmt
+	countAccountsByCurrencyStmt             *sql.Stmt
+	countAllAccountsStmt                    *sql.Stmt
+	countAuditLogsStmt                      *sql.Stmt
+	countEntriesByAccountStmt               *sql.Stmt
+	countEntriesByAccountInRangeStmt        *sql.Stmt
+	countScheduledTransfersStmt             *sql.Stmt
+	countSearchUsersStmt                    *sql.Stmt
+	countTransfersStmt                      *sql.Stmt
+	createAccountStmt                       *sql.Stmt
+	createAccountIdempotencyKeyStmt         *sql.Stmt
+	createAccountOwnerChangeStmt            *sql.Stmt
+	createAuditLogStmt                      *sql.Stmt
+	createBalanceSnapshotStmt               *sql.Stmt
+	createDailyBalanceSnapshotsStmt         *sql.Stmt
+	createEntryStmt                         *sql.Stmt
+	createHoldStmt                          *sql.Stmt
+	createIdempotencyKeyStmt                *sql.Stmt
+	createOrganizationStmt                  *sql.Stmt
+	createOutboxEventStmt                   *sql.Stmt
+	createPasswordResetStmt                 *sql.Stmt
+	createScheduledTransferStmt             *sql.Stmt
+	createSessionStmt                       *sql.Stmt
+	createTransferStmt                      *sql.Stmt
+	createUserStmt                          *sql.Stmt
+	deleteAccountStmt                       *sql.Stmt
+	deleteEntryStmt                         *sql.Stmt
+	deleteTransferStmt                      *sql.Stmt
+	deleteUserStmt                          *sql.Stmt
+	enableTotpSecretStmt                    *sql.Stmt
+	getAccountStmt                          *sql.Stmt
+	getAccountByNumberStmt                  *sql.Stmt
+	getAccountForUpdateStmt                 *sql.Stmt
+	getAccountForUpdateIncludingDeletedStmt *sql.Stmt
+	getAccountIdempotencyKeyStmt            *sql.Stmt
+	getAccountIncludingDeletedStmt          *sql.Stmt
+	getAccountNumbersStmt                   *sql.Stmt
+	getAccountStatsStmt                     *sql.Stmt
+	getAccountsByIDsStmt                    *sql.Stmt
+	getAccountsByNumbersStmt                *sql.Stmt
+	getDailyTransferTotalStmt               *sql.Stmt
+	getEntryStmt                            *sql.Stmt
+	getHoldStmt                             *sql.Stmt
+	getHoldForUpdateStmt                    *sql.Stmt
+	getIdempotencyKeyStmt                   *sql.Stmt
+	getNotificationPreferencesStmt          *sql.Stmt
+	getOrganizationStmt                     *sql.Stmt
+	getPasswordResetForUpdateStmt           *sql.Stmt
+	getScheduledTransferStmt                *sql.Stmt
+	getSessionStmt                          *sql.Stmt
+	getTotpSecretStmt                       *sql.Stmt
+	getTransferStmt                         *sql.Stmt
+	getTransferByUUIDStmt                   *sql.Stmt
+	getTransferForUpdateStmt                *sql.Stmt
+	getUserStmt                             *sql.Stmt
+	getUserByEmailStmt                      *sql.Stmt
+	getUserForUpdateStmt                    *sql.Stmt
+	getUserPasswordChangedAtStmt            *sql.Stmt
+	listAccountOwnerChangesStmt             *sql.Stmt
+	listAccountStatementStmt                *sql.Stmt
+	listAccountsStmt                        *sql.Stmt
+	listAccountsAfterNumberStmt             *sql.Stmt
+	listAccountsByCurrencyStmt              *sql.Stmt
+	listAllAccountsStmt                     *sql.Stmt
+	listAuditLogsStmt                       *sql.Stmt
+	listBalanceSnapshotsStmt                *sql.Stmt
+	listDueScheduledTransfersStmt           *sql.Stmt
+	listEntriesStmt                         *sql.Stmt
+	listEntriesByAccountStmt                *sql.Stmt
+	listEntriesByAccountInRangeStmt         *sql.Stmt
+	listExpiredHoldsStmt                    *sql.Stmt
+	listScheduledTransfersStmt              *sql.Stmt
+	listTransfersStmt                       *sql.Stmt
+	listUnpublishedOutboxEventsStmt         *sql.Stmt
+	listUsersStmt                           *sql.Stmt
+	markOutboxEventPublishedStmt            *sql.Stmt
+	markPasswordResetUsedStmt               *sql.Stmt
+	markTransferReversedStmt                *sql.Stmt
+	recordFailedLoginStmt                   *sql.Stmt
+	recordScheduledTransferFailureStmt      *sql.Stmt
+	recordScheduledTransferRunStmt          *sql.Stmt
+	resetFailedLoginsStmt                   *sql.Stmt
+	restoreAccountStmt                      *sql.Stmt
+	searchUsersStmt                         *sql.Stmt
+	setAccountFrozenStmt                    *sql.Stmt
+	setAccountLabelsStmt                    *sql.Stmt
+	setAccountLowBalanceThresholdStmt       *sql.Stmt
+	setAccountSpendingLimitsStmt            *sql.Stmt
+	softDeleteAccountStmt                   *sql.Stmt
+	sumOutgoingTransfersStmt                *sql.Stmt
+	updateAccountStmt                       *sql.Stmt
+	updateAccountBalanceStmt                *sql.Stmt
+	updateAccountOwnerStmt                  *sql.Stmt
+	updateHoldStatusStmt                    *sql.Stmt
+	updateUserStmt                          *sql.Stmt
+	updateUserPasswordStmt                  *sql.Stmt
+	upsertNotificationPreferencesStmt       *sql.Stmt
+	upsertTotpSecretStmt                    *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                       tx,
-		tx:                       tx,
-		createAccountStmt:        q.createAccountStmt,
-		createEntryStmt:          q.createEntryStmt,
-		createSessionStmt:        q.createSessionStmt,
-		createTransferStmt:       q.createTransferStmt,
-		createUserStmt:           q.createUserStmt,
-		deleteAccountStmt:        q.deleteAccountStmt,
-		deleteEntryStmt:          q.deleteEntryStmt,
-		deleteTransferStmt:       q.deleteTransferStmt,
-		deleteUserStmt:           q.deleteUserStmt,
-		getAccountStmt:           q.getAccountStmt,
-		getAccountForUpdateStmt:  q.getAccountForUpdateStmt,
-		getEntryStmt:             q.getEntryStmt,
-		getSessionStmt:           q.getSessionStmt,
-		getTransferStmt:          q.getTransferStmt,
-		getUserStmt:              q.getUserStmt,
-		getUserForUpdateStmt:     q.getUserForUpdateStmt,
-		listAccountsStmt:         q.listAccountsStmt,
-		listEntriesStmt:          q.listEntriesStmt,
-		listTransfersStmt:        q.listTransfersStmt,
-		listUsersStmt:            q.listUsersStmt,
-		updateAccountStmt:        q.updateAccountStmt,
-		updateAccountBalanceStmt: q.updateAccountBalanceStmt,
-		updateUserStmt:           q.updateUserStmt,
+		db:                                      tx,
+		tx:                                      tx,
+		accountNumberExistsStmt:                 q.accountNumberExistsStmt,
+		addAccountHeldBalanceStmt:               q.addAccountHeldBalanceStmt,
+		addDailyTransferTotalStmt:               q.addDailyTransferTotalStmt,
+		cancelAccountScheduledTransfersStmt:     q.cancelAccountScheduledTransfersStmt,
+		cancelScheduledTransferStmt:             q.cancelScheduledTransferStmt,
+		claimScheduledTransferStmt:              q.claimScheduledTransferStmt,
+		countAccountsStmt:                       q.countAccountsStmt,
+		countAccountsByCurrencyStmt:             q.countAccountsByCurrencyStmt,
+		countAllAccountsStmt:                    q.countAllAccountsStmt,
+		countAuditLogsStmt:                      q.countAuditLogsStmt,
+		countEntriesByAccountStmt:               q.countEntriesByAccountStmt,
+		countEntriesByAccountInRangeStmt:        q.countEntriesByAccountInRangeStmt,
+		countScheduledTransfersStmt:             q.countScheduledTransfersStmt,
+		countSearchUsersStmt:                    q.countSearchUsersStmt,
+		countTransfersStmt:                      q.countTransfersStmt,
+		createAccountStmt:                       q.createAccountStmt,
+		createAccountIdempotencyKeyStmt:         q.createAccountIdempotencyKeyStmt,
+		createAccountOwnerChangeStmt:            q.createAccountOwnerChangeStmt,
+		createAuditLogStmt:                      q.createAuditLogStmt,
+		createBalanceSnapshotStmt:               q.createBalanceSnapshotStmt,
+		createDailyBalanceSnapshotsStmt:         q.createDailyBalanceSnapshotsStmt,
+		createEntryStmt:                         q.createEntryStmt,
+		createHoldStmt:                          q.createHoldStmt,
+		createIdempotencyKeyStmt:                q.createIdempotencyKeyStmt,
+		createOrganizationStmt:                  q.createOrganizationStmt,
+		createOutboxEventStmt:                   q.createOutboxEventStmt,
+		createPasswordResetStmt:                 q.createPasswordResetStmt,
+		createScheduledTransferStmt:             q.createScheduledTransferStmt,
+		createSessionStmt:                       q.createSessionStmt,
+		createTransferStmt:                      q.createTransferStmt,
+		createUserStmt:                          q.createUserStmt,
+		deleteAccountStmt:                       q.deleteAccountStmt,
+		deleteEntryStmt:                         q.deleteEntryStmt,
+		deleteTransferStmt:                      q.deleteTransferStmt,
+		deleteUserStmt:                          q.deleteUserStmt,
+		enableTotpSecretStmt:                    q.enableTotpSecretStmt,
+		getAccountStmt:                          q.getAccountStmt,
+		getAccountByNumberStmt:                  q.getAccountByNumberStmt,
+		getAccountForUpdateStmt:                 q.getAccountForUpdateStmt,
+		getAccountForUpdateIncludingDeletedStmt: q.getAccountForUpdateIncludingDeletedStmt,
+		getAccountIdempotencyKeyStmt:            q.getAccountIdempotencyKeyStmt,
+		getAccountIncludingDeletedStmt:          q.getAccountIncludingDeletedStmt,
+		getAccountNumbersStmt:                   q.getAccountNumbersStmt,
+		getAccountStatsStmt:                     q.getAccountStatsStmt,
+		getAccountsByIDsStmt:                    q.getAccountsByIDsStmt,
+		getAccountsByNumbersStmt:                q.getAccountsByNumbersStmt,
+		getDailyTransferTotalStmt:               q.getDailyTransferTotalStmt,
+		getEntryStmt:                            q.getEntryStmt,
+		getHoldStmt:                             q.getHoldStmt,
+		getHoldForUpdateStmt:                    q.getHoldForUpdateStmt,
+		getIdempotencyKeyStmt:                   q.getIdempotencyKeyStmt,
+		getNotificationPreferencesStmt:          q.getNotificationPreferencesStmt,
+		getOrganizationStmt:                     q.getOrganizationStmt,
+		getPasswordResetForUpdateStmt:           q.getPasswordResetForUpdateStmt,
+		getScheduledTransferStmt:                q.getScheduledTransferStmt,
+		getSessionStmt:                          q.getSessionStmt,
+		getTotpSecretStmt:                       q.getTotpSecretStmt,
+		getTransferStmt:                         q.getTransferStmt,
+		getTransferByUUIDStmt:                   q.getTransferByUUIDStmt,
+		getTransferForUpdateStmt:                q.getTransferForUpdateStmt,
+		getUserStmt:                             q.getUserStmt,
+		getUserByEmailStmt:                      q.getUserByEmailStmt,
+		getUserForUpdateStmt:                    q.getUserForUpdateStmt,
+		getUserPasswordChangedAtStmt:            q.getUserPasswordChangedAtStmt,
+		listAccountOwnerChangesStmt:             q.listAccountOwnerChangesStmt,
+		listAccountStatementStmt:                q.listAccountStatementStmt,
+		listAccountsStmt:                        q.listAccountsStmt,
+		listAccountsAfterNumberStmt:             q.listAccountsAfterNumberStmt,
+		listAccountsByCurrencyStmt:              q.listAccountsByCurrencyStmt,
+		listAllAccountsStmt:                     q.listAllAccountsStmt,
+		listAuditLogsStmt:                       q.listAuditLogsStmt,
+		listBalanceSnapshotsStmt:                q.listBalanceSnapshotsStmt,
+		listDueScheduledTransfersStmt:           q.listDueScheduledTransfersStmt,
+		listEntriesStmt:                         q.listEntriesStmt,
+		listEntriesByAccountStmt:                q.listEntriesByAccountStmt,
+		listEntriesByAccountInRangeStmt:         q.listEntriesByAccountInRangeStmt,
+		listExpiredHoldsStmt:                    q.listExpiredHoldsStmt,
+		listScheduledTransfersStmt:              q.listScheduledTransfersStmt,
+		listTransfersStmt:                       q.listTransfersStmt,
+		listUnpublishedOutboxEventsStmt:         q.listUnpublishedOutboxEventsStmt,
+		listUsersStmt:                           q.listUsersStmt,
+		markOutboxEventPublishedStmt:            q.markOutboxEventPublishedStmt,
+		markPasswordResetUsedStmt:               q.markPasswordResetUsedStmt,
+		markTransferReversedStmt:                q.markTransferReversedStmt,
+		recordFailedLoginStmt:                   q.recordFailedLoginStmt,
+		recordScheduledTransferFailureStmt:      q.recordScheduledTransferFailureStmt,
+		recordScheduledTransferRunStmt:          q.recordScheduledTransferRunStmt,
+		resetFailedLoginsStmt:                   q.resetFailedLoginsStmt,
+		restoreAccountStmt:                      q.restoreAccountStmt,
+		searchUsersStmt:                         q.searchUsersStmt,
+		setAccountFrozenStmt:                    q.setAccountFrozenStmt,
+		setAccountLabelsStmt:                    q.setAccountLabelsStmt,
+		setAccountLowBalanceThresholdStmt:       q.setAccountLowBalanceThresholdStmt,
+		setAccountSpendingLimitsStmt:            q.setAccountSpendingLimitsStmt,
+		softDeleteAccountStmt:                   q.softDeleteAccountStmt,
+		sumOutgoingTransfersStmt:                q.sumOutgoingTransfersStmt,
+		updateAccountStmt:                       q.updateAccountStmt,
+		updateAccountBalanceStmt:                q.updateAccountBalanceStmt,
+		updateAccountOwnerStmt:                  q.updateAccountOwnerStmt,
+		updateHoldStatusStmt:                    q.updateHoldStatusStmt,
+		updateUserStmt:                          q.updateUserStmt,
+		updateUserPasswordStmt:                  q.updateUserPasswordStmt,
+		upsertNotificationPreferencesStmt:       q.upsertNotificationPreferencesStmt,
+		upsertTotpSecretStmt:                    q.upsertTotpSecretStmt,
 	}
 }
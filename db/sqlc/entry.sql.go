@@ -7,8 +7,43 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
+const countEntriesByAccount = `-- name: CountEntriesByAccount :one
+SELECT COUNT(*)
+FROM entries
+WHERE account_id = $1
+`
+
+func (q *Queries) CountEntriesByAccount(ctx context.Context, accountID int64) (int64, error) {
+	row := q.queryRow(ctx, q.countEntriesByAccountStmt, countEntriesByAccount, accountID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countEntriesByAccountInRange = `-- name: CountEntriesByAccountInRange :one
+SELECT COUNT(*)
+FROM entries
+WHERE account_id = $1
+  AND created_at BETWEEN $2::timestamp AND $3::timestamp
+`
+
+type CountEntriesByAccountInRangeParams struct {
+	AccountID int64     `json:"account_id"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
+}
+
+func (q *Queries) CountEntriesByAccountInRange(ctx context.Context, arg CountEntriesByAccountInRangeParams) (int64, error) {
+	row := q.queryRow(ctx, q.countEntriesByAccountInRangeStmt, countEntriesByAccountInRange, arg.AccountID, arg.FromTime, arg.ToTime)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (amount,
                      account_id)
@@ -43,6 +78,46 @@ func (q *Queries) DeleteEntry(ctx context.Context, id int64) error {
 	return err
 }
 
+const getAccountStats = `-- name: GetAccountStats :one
+SELECT COALESCE(SUM(amount) FILTER (WHERE amount > 0), 0)::bigint  AS total_inflow,
+       COALESCE(-SUM(amount) FILTER (WHERE amount < 0), 0)::bigint AS total_outflow,
+       COALESCE(SUM(amount), 0)::bigint                            AS net_change,
+       (SELECT COUNT(*)
+        FROM transfers
+        WHERE (from_account_id = $1 OR to_account_id = $1)
+          AND transfers.created_at >= $2::timestamp
+          AND transfers.created_at < $3::timestamp) AS transfer_count
+FROM entries
+WHERE account_id = $1
+  AND created_at >= $2::timestamp
+  AND created_at < $3::timestamp
+`
+
+type GetAccountStatsParams struct {
+	AccountID int64     `json:"account_id"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
+}
+
+type GetAccountStatsRow struct {
+	TotalInflow   int64 `json:"total_inflow"`
+	TotalOutflow  int64 `json:"total_outflow"`
+	NetChange     int64 `json:"net_change"`
+	TransferCount int64 `json:"transfer_count"`
+}
+
+func (q *Queries) GetAccountStats(ctx context.Context, arg GetAccountStatsParams) (GetAccountStatsRow, error) {
+	row := q.queryRow(ctx, q.getAccountStatsStmt, getAccountStats, arg.AccountID, arg.FromTime, arg.ToTime)
+	var i GetAccountStatsRow
+	err := row.Scan(
+		&i.TotalInflow,
+		&i.TotalOutflow,
+		&i.NetChange,
+		&i.TransferCount,
+	)
+	return i, err
+}
+
 const getEntry = `-- name: GetEntry :one
 SELECT id, amount, account_id, created_at
 FROM entries
@@ -61,6 +136,66 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 	return i, err
 }
 
+const listAccountStatement = `-- name: ListAccountStatement :many
+SELECT e.id,
+       e.amount,
+       e.account_id,
+       e.created_at,
+       ((SELECT COALESCE(SUM(o.amount), 0)
+         FROM entries o
+         WHERE o.account_id = $1
+           AND o.created_at < $2::timestamp)
+           + SUM(e.amount) OVER (ORDER BY e.created_at, e.id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW))::bigint AS running_balance
+FROM entries e
+WHERE e.account_id = $1
+  AND e.created_at >= $2::timestamp
+  AND e.created_at < $3::timestamp
+ORDER BY e.created_at, e.id
+`
+
+type ListAccountStatementParams struct {
+	AccountID int64     `json:"account_id"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
+}
+
+type ListAccountStatementRow struct {
+	ID             int64        `json:"id"`
+	Amount         int64        `json:"amount"`
+	AccountID      int64        `json:"account_id"`
+	CreatedAt      sql.NullTime `json:"created_at"`
+	RunningBalance int64        `json:"running_balance"`
+}
+
+func (q *Queries) ListAccountStatement(ctx context.Context, arg ListAccountStatementParams) ([]ListAccountStatementRow, error) {
+	rows, err := q.query(ctx, q.listAccountStatementStmt, listAccountStatement, arg.AccountID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountStatementRow{}
+	for rows.Next() {
+		var i ListAccountStatementRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreatedAt,
+			&i.RunningBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEntries = `-- name: ListEntries :many
 SELECT id, amount, account_id, created_at
 FROM entries
@@ -100,3 +235,96 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 	}
 	return items, nil
 }
+
+const listEntriesByAccount = `-- name: ListEntriesByAccount :many
+SELECT id, amount, account_id, created_at
+FROM entries
+WHERE account_id = $1
+ORDER BY created_at DESC, id DESC LIMIT $2
+OFFSET $3
+`
+
+type ListEntriesByAccountParams struct {
+	AccountID int64 `json:"account_id"`
+	Limit     int32 `json:"limit"`
+	Offset    int32 `json:"offset"`
+}
+
+func (q *Queries) ListEntriesByAccount(ctx context.Context, arg ListEntriesByAccountParams) ([]Entry, error) {
+	rows, err := q.query(ctx, q.listEntriesByAccountStmt, listEntriesByAccount, arg.AccountID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesByAccountInRange = `-- name: ListEntriesByAccountInRange :many
+SELECT id, amount, account_id, created_at
+FROM entries
+WHERE account_id = $1
+  AND created_at BETWEEN $2::timestamp AND $3::timestamp
+ORDER BY created_at DESC, id DESC LIMIT $4
+OFFSET $5
+`
+
+type ListEntriesByAccountInRangeParams struct {
+	AccountID   int64     `json:"account_id"`
+	FromTime    time.Time `json:"from_time"`
+	ToTime      time.Time `json:"to_time"`
+	LimitCount  int32     `json:"limit_count"`
+	OffsetCount int32     `json:"offset_count"`
+}
+
+func (q *Queries) ListEntriesByAccountInRange(ctx context.Context, arg ListEntriesByAccountInRangeParams) ([]Entry, error) {
+	rows, err := q.query(ctx, q.listEntriesByAccountInRangeStmt, listEntriesByAccountInRange,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.LimitCount,
+		arg.OffsetCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"github.com/google/uuid"
 	"github.com/micaelapucciariello/simplebank/utils"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -75,3 +76,206 @@ func TestGetEntryList(t *testing.T) {
 		require.NotEmpty(t, account)
 	}
 }
+
+func TestListEntriesByAccount(t *testing.T) {
+	account := CreateRandomAccount(t)
+
+	n := 10
+	created := make([]Entry, n)
+	for i := 0; i < n; i++ {
+		entry, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    utils.RandomBalance(),
+		})
+		require.NoError(t, err)
+		created[i] = entry
+	}
+
+	entries, err := testQueries.ListEntriesByAccount(context.Background(), ListEntriesByAccountParams{
+		AccountID: account.ID,
+		Limit:     5,
+		Offset:    5,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+	// the most recent entries come first, so the second page holds the oldest ones
+	for i, entry := range entries {
+		require.Equal(t, account.ID, entry.AccountID)
+		require.Equal(t, created[n-6-i].ID, entry.ID)
+	}
+
+	entries, err = testQueries.ListEntriesByAccount(context.Background(), ListEntriesByAccountParams{
+		AccountID: account.ID,
+		Limit:     5,
+		Offset:    int32(n),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, entries)
+	require.Empty(t, entries)
+
+	total, err := testQueries.CountEntriesByAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(n), total)
+}
+
+func TestListEntriesByAccountInRange(t *testing.T) {
+	account := CreateRandomAccount(t)
+
+	n := 5
+	created := make([]Entry, n)
+	for i := 0; i < n; i++ {
+		entry, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    utils.RandomBalance(),
+		})
+		require.NoError(t, err)
+		created[i] = entry
+	}
+
+	// both ends of the range are included
+	entries, err := testQueries.ListEntriesByAccountInRange(context.Background(), ListEntriesByAccountInRangeParams{
+		AccountID:   account.ID,
+		FromTime:    created[1].CreatedAt.Time,
+		ToTime:      created[3].CreatedAt.Time,
+		LimitCount:  int32(n),
+		OffsetCount: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i, entry := range entries {
+		require.Equal(t, created[3-i].ID, entry.ID)
+	}
+
+	total, err := testQueries.CountEntriesByAccountInRange(context.Background(), CountEntriesByAccountInRangeParams{
+		AccountID: account.ID,
+		FromTime:  created[1].CreatedAt.Time,
+		ToTime:    created[3].CreatedAt.Time,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+
+	entries, err = testQueries.ListEntriesByAccountInRange(context.Background(), ListEntriesByAccountInRangeParams{
+		AccountID:   account.ID,
+		FromTime:    time.Now().UTC().Add(time.Hour),
+		ToTime:      time.Now().UTC().Add(2 * time.Hour),
+		LimitCount:  int32(n),
+		OffsetCount: 0,
+	})
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestListAccountStatement(t *testing.T) {
+	account := CreateRandomAccount(t)
+	store := NewStore(testDB)
+
+	// the account is opened with its balance recorded by an opening entry, like the api does
+	_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+		AccountID: account.ID,
+		Amount:    account.Balance,
+	})
+	require.NoError(t, err)
+
+	from := time.Now().UTC()
+	n := 5
+	for i := 0; i < n; i++ {
+		_, err = store.EntryTx(context.Background(), EntryTxParams{
+			AccountID: account.ID,
+			Amount:    utils.RandomInt(1, 100),
+		})
+		require.NoError(t, err)
+	}
+	// an adjustment of the balance shows up in the statement like a deposit
+	account, err = store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+		AccountID: account.ID,
+		Amount:    -1,
+	})
+	require.NoError(t, err)
+	to := time.Now().UTC().Add(time.Minute)
+
+	statement, err := testQueries.ListAccountStatement(context.Background(), ListAccountStatementParams{
+		AccountID: account.ID,
+		FromTime:  from,
+		ToTime:    to,
+	})
+	require.NoError(t, err)
+	require.Len(t, statement, n+1)
+
+	// the range starts after the opening entry, its balance is carried into the first row
+	require.Equal(t, account.Balance-sumStatementAmounts(statement[1:]), statement[0].RunningBalance)
+	for i := 1; i < len(statement); i++ {
+		require.Equal(t, account.ID, statement[i].AccountID)
+		require.Equal(t, statement[i-1].RunningBalance+statement[i].Amount, statement[i].RunningBalance)
+	}
+	// the balance after the latest entry is the current account balance
+	require.Equal(t, account.Balance, statement[n].RunningBalance)
+}
+
+func sumStatementAmounts(rows []ListAccountStatementRow) int64 {
+	var sum int64
+	for _, row := range rows {
+		sum += row.Amount
+	}
+	return sum
+}
+
+func TestListAccountStatementEmptyRange(t *testing.T) {
+	entry := createRandomEntry(t)
+
+	statement, err := testQueries.ListAccountStatement(context.Background(), ListAccountStatementParams{
+		AccountID: entry.AccountID,
+		FromTime:  time.Now().UTC().Add(time.Hour),
+		ToTime:    time.Now().UTC().Add(2 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Empty(t, statement)
+}
+
+func TestGetAccountStats(t *testing.T) {
+	account := CreateRandomAccount(t)
+	other := CreateRandomAccount(t)
+
+	from := time.Now().UTC().Add(-time.Minute)
+	for _, amount := range []int64{100, 250, -40, -80} {
+		_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    amount,
+		})
+		require.NoError(t, err)
+	}
+	// the transfers are counted whichever side the account is on, the other account ones aren't
+	for _, transfer := range []CreateTransferParams{
+		{FromAccountID: account.ID, ToAccountID: other.ID, Amount: 40, ToAmount: 40, UUID: uuid.New()},
+		{FromAccountID: other.ID, ToAccountID: account.ID, Amount: 100, ToAmount: 100, UUID: uuid.New()},
+		{FromAccountID: other.ID, ToAccountID: CreateRandomAccount(t).ID, Amount: 10, ToAmount: 10, UUID: uuid.New()},
+	} {
+		_, err := testQueries.CreateTransfer(context.Background(), transfer)
+		require.NoError(t, err)
+	}
+	// entries of other accounts aren't aggregated
+	_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{AccountID: other.ID, Amount: 1000})
+	require.NoError(t, err)
+	to := time.Now().UTC().Add(time.Minute)
+
+	stats, err := testQueries.GetAccountStats(context.Background(), GetAccountStatsParams{
+		AccountID: account.ID,
+		FromTime:  from,
+		ToTime:    to,
+	})
+	require.NoError(t, err)
+	require.Equal(t, GetAccountStatsRow{
+		TotalInflow:   350,
+		TotalOutflow:  120,
+		NetChange:     230,
+		TransferCount: 2,
+	}, stats)
+
+	// a range without entries aggregates to zero
+	stats, err = testQueries.GetAccountStats(context.Background(), GetAccountStatsParams{
+		AccountID: account.ID,
+		FromTime:  to,
+		ToTime:    to.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Zero(t, stats)
+}
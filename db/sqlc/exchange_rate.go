@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrNoExchangeRate     = errors.New("no exchange rate between the account currencies")
+	ErrConversionTooSmall = errors.New("amount is too small to convert")
+)
+
+// ExchangeRateProvider gives the rates the cross-currency transfers are converted with
+// Implementations must be safe for concurrent use, a static table is provided
+type ExchangeRateProvider interface {
+	// Rate returns the amount of to currency a unit of from currency is worth, or ErrNoExchangeRate
+	Rate(ctx context.Context, from, to string) (*big.Rat, error)
+}
+
+// CurrencyPair is the direction of a conversion, the rate of USD to EUR says nothing about EUR to USD
+type CurrencyPair struct {
+	From string
+	To   string
+}
+
+// StaticExchangeRates is a fixed rates table held in memory, it's never refreshed
+type StaticExchangeRates map[CurrencyPair]*big.Rat
+
+func (r StaticExchangeRates) Rate(_ context.Context, from, to string) (*big.Rat, error) {
+	rate, ok := r[CurrencyPair{From: from, To: to}]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v to %v", ErrNoExchangeRate, from, to)
+	}
+	return rate, nil
+}
+
+// ParseExchangeRates reads a rates table whose entries look like USD/EUR=0.92, a unit of USD being worth 0.92 EUR
+func ParseExchangeRates(table []string) (StaticExchangeRates, error) {
+	rates := make(StaticExchangeRates, len(table))
+	for _, entry := range table {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pair, value, ok := strings.Cut(entry, "=")
+		from, to, okPair := strings.Cut(pair, "/")
+		if !ok || !okPair || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid exchange rate %q, expected FROM/TO=RATE", entry)
+		}
+
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid exchange rate %q, the rate must be a positive number", entry)
+		}
+		rates[CurrencyPair{From: strings.ToUpper(from), To: strings.ToUpper(to)}] = rate
+	}
+	return rates, nil
+}
+
+// convertAmount converts amount with rate, the fraction of the smallest currency unit left is rounded down
+func convertAmount(amount int64, rate *big.Rat) (int64, error) {
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), rate)
+	credit := new(big.Int).Quo(converted.Num(), converted.Denom())
+	if !credit.IsInt64() {
+		return 0, fmt.Errorf("converted amount of %v overflows", amount)
+	}
+	if credit.Sign() <= 0 {
+		return 0, fmt.Errorf("%w: %v converts to %v", ErrConversionTooSmall, amount, credit)
+	}
+	return credit.Int64(), nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParseExchangeRates(t *testing.T) {
+	rates, err := ParseExchangeRates([]string{"USD/EUR=0.92", " eur/usd=1.087 ", ""})
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+
+	rate, err := rates.Rate(context.Background(), utils.USD, utils.EUR)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(92, 100), rate)
+
+	rate, err = rates.Rate(context.Background(), utils.EUR, utils.USD)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(1087, 1000), rate)
+
+	_, err = rates.Rate(context.Background(), utils.USD, utils.ARS)
+	require.ErrorIs(t, err, ErrNoExchangeRate)
+
+	for _, entry := range []string{"USD=0.92", "USD/=0.92", "USD/EUR", "USD/EUR=abc", "USD/EUR=0", "USD/EUR=-1"} {
+		_, err := ParseExchangeRates([]string{entry})
+		require.Error(t, err, entry)
+	}
+}
+
+func TestConvertAmount(t *testing.T) {
+	testCases := []struct {
+		name     string
+		amount   int64
+		rate     *big.Rat
+		expected int64
+		err      error
+	}{
+		{name: "usd to eur", amount: 250, rate: big.NewRat(92, 100), expected: 230},
+		{name: "fraction rounded down", amount: 99, rate: big.NewRat(92, 100), expected: 91},
+		{name: "rate above one", amount: 100, rate: big.NewRat(1087, 1000), expected: 108},
+		{name: "converts to nothing", amount: 1, rate: big.NewRat(92, 100), err: ErrConversionTooSmall},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			converted, err := convertAmount(tc.amount, tc.rate)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, converted)
+		})
+	}
+
+	_, err := convertAmount(math.MaxInt64, big.NewRat(2, 1))
+	require.Error(t, err)
+}
@@ -0,0 +1,43 @@
+//go:build integration
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/micaelapucciariello/simplebank/db/dbtest"
+	"log"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+const driverName = "postgres"
+
+var testQueries *Queries
+var testDB *sql.DB
+
+// TestMain runs every test of the package against one throwaway postgres, started for the run and removed after it
+func TestMain(m *testing.M) {
+	os.Exit(runWithPostgres(m))
+}
+
+func runWithPostgres(m *testing.M) int {
+	ctx := context.Background()
+	postgres, err := dbtest.StartPostgres(ctx)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("cannot start postgres: %s", err))
+	}
+	defer postgres.Terminate(ctx)
+
+	testDB, err = sql.Open(driverName, postgres.URL)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("cannot connect to db: %s", err))
+	}
+	defer testDB.Close()
+
+	testQueries = New(testDB)
+	return m.Run()
+}
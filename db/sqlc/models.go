@@ -6,16 +6,70 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Account struct {
-	ID        int64        `json:"id"`
-	Owner     string       `json:"owner"`
-	Balance   int64        `json:"balance"`
-	Currency  string       `json:"currency"`
-	CreatedAt sql.NullTime `json:"created_at"`
+	ID                  int64        `json:"id"`
+	Owner               string       `json:"owner"`
+	Balance             int64        `json:"balance"`
+	Currency            string       `json:"currency"`
+	CreatedAt           sql.NullTime `json:"created_at"`
+	UpdatedAt           sql.NullTime `json:"updated_at"`
+	DeletedAt           sql.NullTime `json:"deleted_at"`
+	IsFrozen            bool         `json:"is_frozen"`
+	Version             int64        `json:"version"`
+	HeldBalance         int64        `json:"held_balance"`
+	Labels              []string     `json:"labels"`
+	AccountNumber       string       `json:"account_number"`
+	OrgID               int64        `json:"org_id"`
+	DailyLimit          int64        `json:"daily_limit"`
+	MonthlyLimit        int64        `json:"monthly_limit"`
+	LowBalanceThreshold int64        `json:"low_balance_threshold"`
+}
+
+type AccountIdempotencyKey struct {
+	Owner       string    `json:"owner"`
+	Key         string    `json:"key"`
+	RequestHash string    `json:"request_hash"`
+	AccountID   int64     `json:"account_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type AccountOwnerChange struct {
+	ID            int64     `json:"id"`
+	AccountID     int64     `json:"account_id"`
+	PreviousOwner string    `json:"previous_owner"`
+	NewOwner      string    `json:"new_owner"`
+	ChangedBy     string    `json:"changed_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type AuditLog struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"`
+	Action     string    `json:"action"`
+	EntityType string    `json:"entity_type"`
+	EntityID   string    `json:"entity_id"`
+	ClientIp   string    `json:"client_ip"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type BalanceSnapshot struct {
+	ID        int64     `json:"id"`
+	AccountID int64     `json:"account_id"`
+	Day       time.Time `json:"day"`
+	Balance   int64     `json:"balance"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DailyTransferTotal struct {
+	Username string    `json:"username"`
+	Day      time.Time `json:"day"`
+	Total    int64     `json:"total"`
 }
 
 type Entry struct {
@@ -25,6 +79,73 @@ type Entry struct {
 	CreatedAt sql.NullTime `json:"created_at"`
 }
 
+type Hold struct {
+	ID            int64         `json:"id"`
+	FromAccountID int64         `json:"from_account_id"`
+	ToAccountID   int64         `json:"to_account_id"`
+	Amount        int64         `json:"amount"`
+	Status        string        `json:"status"`
+	TransferID    sql.NullInt64 `json:"transfer_id"`
+	ExpiresAt     time.Time     `json:"expires_at"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+type IdempotencyKey struct {
+	Username    string          `json:"username"`
+	Key         string          `json:"key"`
+	RequestHash string          `json:"request_hash"`
+	Response    json.RawMessage `json:"response"`
+	CreatedAt   sql.NullTime    `json:"created_at"`
+}
+
+type NotificationPreference struct {
+	Username                string    `json:"username"`
+	IncomingTransferEmail   bool      `json:"incoming_transfer_email"`
+	IncomingTransferWebhook bool      `json:"incoming_transfer_webhook"`
+	LowBalanceEmail         bool      `json:"low_balance_email"`
+	LowBalanceWebhook       bool      `json:"low_balance_webhook"`
+	UpdatedAt               time.Time `json:"updated_at"`
+}
+
+type Organization struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Outbox struct {
+	ID          int64           `json:"id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+	PublishedAt sql.NullTime    `json:"published_at"`
+}
+
+type PasswordReset struct {
+	ID        int64        `json:"id"`
+	Username  string       `json:"username"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type ScheduledTransfer struct {
+	ID              int64          `json:"id"`
+	FromAccountID   int64          `json:"from_account_id"`
+	ToAccountID     int64          `json:"to_account_id"`
+	Amount          int64          `json:"amount"`
+	IntervalSeconds int64          `json:"interval_seconds"`
+	NextRunAt       time.Time      `json:"next_run_at"`
+	LastRunAt       sql.NullTime   `json:"last_run_at"`
+	LastTransferID  sql.NullInt64  `json:"last_transfer_id"`
+	LastError       sql.NullString `json:"last_error"`
+	FailedRuns      int32          `json:"failed_runs"`
+	CancelledAt     sql.NullTime   `json:"cancelled_at"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
 type Session struct {
 	ID           uuid.UUID    `json:"id"`
 	Username     string       `json:"username"`
@@ -36,19 +157,35 @@ type Session struct {
 	CreatedAt    sql.NullTime `json:"created_at"`
 }
 
+type TotpSecret struct {
+	Username        string       `json:"username"`
+	EncryptedSecret []byte       `json:"encrypted_secret"`
+	EnabledAt       sql.NullTime `json:"enabled_at"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
 type Transfer struct {
-	ID            int64        `json:"id"`
-	FromAccountID int64        `json:"from_account_id"`
-	ToAccountID   int64        `json:"to_account_id"`
-	Amount        int64        `json:"amount"`
-	CreatedAt     sql.NullTime `json:"created_at"`
+	ID            int64          `json:"id"`
+	FromAccountID int64          `json:"from_account_id"`
+	ToAccountID   int64          `json:"to_account_id"`
+	Amount        int64          `json:"amount"`
+	CreatedAt     sql.NullTime   `json:"created_at"`
+	ReversedFrom  sql.NullInt64  `json:"reversed_from"`
+	ReversedAt    sql.NullTime   `json:"reversed_at"`
+	Description   sql.NullString `json:"description"`
+	ToAmount      int64          `json:"to_amount"`
+	UUID          uuid.UUID      `json:"uuid"`
 }
 
 type User struct {
-	Username          string       `json:"username"`
-	HashedPassword    string       `json:"hashed_password"`
-	FullName          string       `json:"full_name"`
-	Email             string       `json:"email"`
-	PasswordChangedAt string       `json:"password_changed_at"`
-	CreatedAt         sql.NullTime `json:"created_at"`
+	Username            string       `json:"username"`
+	HashedPassword      string       `json:"hashed_password"`
+	FullName            string       `json:"full_name"`
+	Email               string       `json:"email"`
+	PasswordChangedAt   time.Time    `json:"password_changed_at"`
+	CreatedAt           sql.NullTime `json:"created_at"`
+	Role                string       `json:"role"`
+	FailedLoginAttempts int32        `json:"failed_login_attempts"`
+	LockedUntil         sql.NullTime `json:"locked_until"`
+	OrgID               int64        `json:"org_id"`
 }
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// DefaultNotificationPreferences are the preferences of a user who never changed them, every notification is sent
+func DefaultNotificationPreferences(username string) NotificationPreference {
+	return NotificationPreference{
+		Username:                username,
+		IncomingTransferEmail:   true,
+		IncomingTransferWebhook: true,
+		LowBalanceEmail:         true,
+		LowBalanceWebhook:       true,
+	}
+}
+
+// NotificationPreferencesOf returns the preferences of the user, or the default ones when the user has none stored
+func NotificationPreferencesOf(ctx context.Context, q Querier, username string) (NotificationPreference, error) {
+	preferences, err := q.GetNotificationPreferences(ctx, username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultNotificationPreferences(username), nil
+	}
+	return preferences, err
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestUpsertNotificationPreferences(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	// a user who never changed the preferences gets every notification
+	preferences, err := NotificationPreferencesOf(context.Background(), testQueries, user.Username)
+	require.NoError(t, err)
+	require.Equal(t, DefaultNotificationPreferences(user.Username), preferences)
+
+	arg := UpsertNotificationPreferencesParams{
+		Username:                user.Username,
+		IncomingTransferEmail:   false,
+		IncomingTransferWebhook: true,
+		LowBalanceEmail:         true,
+		LowBalanceWebhook:       false,
+	}
+	stored, err := testQueries.UpsertNotificationPreferences(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, stored.IncomingTransferEmail)
+	require.True(t, stored.IncomingTransferWebhook)
+	require.True(t, stored.LowBalanceEmail)
+	require.False(t, stored.LowBalanceWebhook)
+	require.NotZero(t, stored.UpdatedAt)
+
+	// updating replaces every preference
+	arg.IncomingTransferEmail = true
+	updated, err := testQueries.UpsertNotificationPreferences(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, updated.IncomingTransferEmail)
+	require.False(t, updated.LowBalanceWebhook)
+
+	preferences, err = NotificationPreferencesOf(context.Background(), testQueries, user.Username)
+	require.NoError(t, err)
+	require.Equal(t, updated, preferences)
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func createRandomOrganization(t *testing.T) Organization {
+	name := utils.RandomString(12)
+	organization, err := testQueries.CreateOrganization(context.Background(), name)
+	require.NoError(t, err)
+	require.Equal(t, name, organization.Name)
+	require.NotZero(t, organization.ID)
+	require.NotZero(t, organization.CreatedAt)
+	return organization
+}
+
+// createAccountInOrganization opens a USD account for a new user of the organization
+func createAccountInOrganization(t *testing.T, orgID int64, balance int64) Account {
+	params := randomCreateUserParams()
+	params.OrgID = orgID
+	user, err := testQueries.CreateUser(context.Background(), params)
+	require.NoError(t, err)
+
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:         user.Username,
+		Balance:       balance,
+		Currency:      utils.USD,
+		AccountNumber: utils.RandomAccountNumber(),
+		OrgID:         orgID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, orgID, account.OrgID)
+	return account
+}
+
+func TestGetOrganization(t *testing.T) {
+	organization := createRandomOrganization(t)
+
+	got, err := testQueries.GetOrganization(context.Background(), organization.ID)
+	require.NoError(t, err)
+	require.Equal(t, organization, got)
+
+	// the users and accounts created before organizations belong to the default one
+	_, err = testQueries.GetOrganization(context.Background(), DefaultOrgID)
+	require.NoError(t, err)
+}
+
+func TestOrganizationScopedQueries(t *testing.T) {
+	organization := createRandomOrganization(t)
+	account := createAccountInOrganization(t, organization.ID, 0)
+	owner := sql.NullString{String: account.Owner, Valid: true}
+
+	// the account is only listed to its own organization
+	accounts, err := testQueries.ListAllAccounts(context.Background(), ListAllAccountsParams{
+		OrgID:  organization.ID,
+		Owner:  owner,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Account{account}, accounts)
+
+	accounts, err = testQueries.ListAllAccounts(context.Background(), ListAllAccountsParams{
+		OrgID:  DefaultOrgID,
+		Owner:  owner,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Empty(t, accounts)
+
+	users, err := testQueries.SearchUsers(context.Background(), SearchUsersParams{
+		OrgID:  DefaultOrgID,
+		Query:  account.Owner,
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Empty(t, users)
+
+	// another organization can neither freeze nor restore the account
+	_, err = testQueries.SetAccountFrozen(context.Background(), SetAccountFrozenParams{ID: account.ID, IsFrozen: true, OrgID: DefaultOrgID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	deleteEmptiedAccount(t, account.ID)
+	_, err = testQueries.RestoreAccount(context.Background(), RestoreAccountParams{AccountNumber: account.AccountNumber, OrgID: DefaultOrgID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestTransferTxOtherOrganization(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createAccountInCurrency(t, utils.USD, 1000)
+	other := createAccountInOrganization(t, createRandomOrganization(t).ID, 1000)
+
+	// money never moves between tenants, in either direction
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   other.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrOrganizationMismatch)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: other.ID,
+		ToAccountID:   account.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrOrganizationMismatch)
+
+	for _, a := range []Account{account, other} {
+		updated, err := store.GetAccount(context.Background(), a.ID)
+		require.NoError(t, err)
+		require.Equal(t, a.Balance, updated.Balance)
+	}
+}
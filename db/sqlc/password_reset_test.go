@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func createRandomPasswordReset(t *testing.T, user User, expiresAt time.Time) PasswordReset {
+	arg := CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: utils.RandomString(64),
+		ExpiresAt: expiresAt,
+	}
+
+	reset, err := testQueries.CreatePasswordReset(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, reset.ID)
+	require.Equal(t, arg.Username, reset.Username)
+	require.Equal(t, arg.TokenHash, reset.TokenHash)
+	require.WithinDuration(t, arg.ExpiresAt, reset.ExpiresAt, time.Second)
+	require.False(t, reset.UsedAt.Valid)
+	return reset
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+	reset := createRandomPasswordReset(t, user, time.Now().Add(time.Minute))
+	since := time.Now().Add(-time.Minute)
+
+	updated, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      reset.TokenHash,
+		HashedPassword: "new-password",
+		ClientIP:       "10.0.0.1",
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, updated.Username)
+	require.Equal(t, "new-password", updated.HashedPassword)
+	require.True(t, updated.PasswordChangedAt.After(user.PasswordChangedAt))
+
+	logs := listUserAuditLogs(t, user.Username, since)
+	require.Len(t, logs, 1)
+	require.Equal(t, AuditActionResetPassword, logs[0].Action)
+	require.Equal(t, "10.0.0.1", logs[0].ClientIp)
+}
+
+func TestResetPasswordTxReused(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+	reset := createRandomPasswordReset(t, user, time.Now().Add(time.Minute))
+
+	params := ResetPasswordTxParams{TokenHash: reset.TokenHash, HashedPassword: "new-password"}
+	_, err := store.ResetPasswordTx(context.Background(), params)
+	require.NoError(t, err)
+
+	// a token is single use, the second redemption doesn't touch the password
+	params.HashedPassword = "other-password"
+	_, err = store.ResetPasswordTx(context.Background(), params)
+	require.ErrorIs(t, err, ErrResetTokenUsed)
+
+	stored, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, "new-password", stored.HashedPassword)
+}
+
+func TestResetPasswordTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+	reset := createRandomPasswordReset(t, user, time.Now().Add(-time.Second))
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      reset.TokenHash,
+		HashedPassword: "new-password",
+	})
+	require.ErrorIs(t, err, ErrResetTokenExpired)
+
+	// an expired reset is left unused and the password unchanged
+	stored, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.HashedPassword, stored.HashedPassword)
+}
+
+func TestResetPasswordTxUnknownToken(t *testing.T) {
+	store := NewStore(testDB)
+
+	_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      utils.RandomString(64),
+		HashedPassword: "new-password",
+	})
+	require.ErrorIs(t, err, ErrResetTokenInvalid)
+}
@@ -6,13 +6,40 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
+	AccountNumberExists(ctx context.Context, accountNumber string) (bool, error)
+	AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error)
+	AddDailyTransferTotal(ctx context.Context, arg AddDailyTransferTotalParams) (int64, error)
+	CancelAccountScheduledTransfers(ctx context.Context, fromAccountID int64) error
+	CancelScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	ClaimScheduledTransfer(ctx context.Context, arg ClaimScheduledTransferParams) (ScheduledTransfer, error)
+	CountAccounts(ctx context.Context, arg CountAccountsParams) (int64, error)
+	CountAccountsByCurrency(ctx context.Context, arg CountAccountsByCurrencyParams) (int64, error)
+	CountAllAccounts(ctx context.Context, arg CountAllAccountsParams) (int64, error)
+	CountAuditLogs(ctx context.Context, arg CountAuditLogsParams) (int64, error)
+	CountEntriesByAccount(ctx context.Context, accountID int64) (int64, error)
+	CountEntriesByAccountInRange(ctx context.Context, arg CountEntriesByAccountInRangeParams) (int64, error)
+	CountScheduledTransfers(ctx context.Context, fromAccountID int64) (int64, error)
+	CountSearchUsers(ctx context.Context, arg CountSearchUsersParams) (int64, error)
+	CountTransfers(ctx context.Context, arg CountTransfersParams) (int64, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAccountIdempotencyKey(ctx context.Context, arg CreateAccountIdempotencyKeyParams) (AccountIdempotencyKey, error)
+	CreateAccountOwnerChange(ctx context.Context, arg CreateAccountOwnerChangeParams) (AccountOwnerChange, error)
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error)
+	CreateBalanceSnapshot(ctx context.Context, arg CreateBalanceSnapshotParams) (BalanceSnapshot, error)
+	CreateDailyBalanceSnapshots(ctx context.Context, day time.Time) (int64, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error)
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreateOrganization(ctx context.Context, name string) (Organization, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
@@ -20,20 +47,75 @@ type Querier interface {
 	DeleteEntry(ctx context.Context, id int64) error
 	DeleteTransfer(ctx context.Context, id int64) error
 	DeleteUser(ctx context.Context, username string) error
+	EnableTotpSecret(ctx context.Context, username string) (TotpSecret, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountByNumber(ctx context.Context, accountNumber string) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountForUpdateIncludingDeleted(ctx context.Context, id int64) (Account, error)
+	GetAccountIdempotencyKey(ctx context.Context, arg GetAccountIdempotencyKeyParams) (AccountIdempotencyKey, error)
+	GetAccountIncludingDeleted(ctx context.Context, id int64) (Account, error)
+	GetAccountNumbers(ctx context.Context, ids []int64) ([]GetAccountNumbersRow, error)
+	GetAccountStats(ctx context.Context, arg GetAccountStatsParams) (GetAccountStatsRow, error)
+	GetAccountsByIDs(ctx context.Context, ids []int64) ([]Account, error)
+	GetAccountsByNumbers(ctx context.Context, accountNumbers []string) ([]Account, error)
+	GetDailyTransferTotal(ctx context.Context, username string) (int64, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetHold(ctx context.Context, id int64) (Hold, error)
+	GetHoldForUpdate(ctx context.Context, id int64) (Hold, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetNotificationPreferences(ctx context.Context, username string) (NotificationPreference, error)
+	GetOrganization(ctx context.Context, id int64) (Organization, error)
+	GetPasswordResetForUpdate(ctx context.Context, tokenHash string) (PasswordReset, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (GetScheduledTransferRow, error)
 	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetTotpSecret(ctx context.Context, username string) (TotpSecret, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferByUUID(ctx context.Context, uuid uuid.UUID) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserForUpdate(ctx context.Context, username string) (User, error)
+	GetUserPasswordChangedAt(ctx context.Context, username string) (time.Time, error)
+	ListAccountOwnerChanges(ctx context.Context, accountID int64) ([]AccountOwnerChange, error)
+	ListAccountStatement(ctx context.Context, arg ListAccountStatementParams) ([]ListAccountStatementRow, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAccountsAfterNumber(ctx context.Context, arg ListAccountsAfterNumberParams) ([]Account, error)
+	ListAccountsByCurrency(ctx context.Context, arg ListAccountsByCurrencyParams) ([]Account, error)
+	ListAllAccounts(ctx context.Context, arg ListAllAccountsParams) ([]Account, error)
+	ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error)
+	ListBalanceSnapshots(ctx context.Context, arg ListBalanceSnapshotsParams) ([]BalanceSnapshot, error)
+	ListDueScheduledTransfers(ctx context.Context, arg ListDueScheduledTransfersParams) ([]ScheduledTransfer, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListEntriesByAccount(ctx context.Context, arg ListEntriesByAccountParams) ([]Entry, error)
+	ListEntriesByAccountInRange(ctx context.Context, arg ListEntriesByAccountInRangeParams) ([]Entry, error)
+	ListExpiredHolds(ctx context.Context, arg ListExpiredHoldsParams) ([]Hold, error)
+	ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ListScheduledTransfersRow, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListUnpublishedOutboxEvents(ctx context.Context, limit int32) ([]Outbox, error)
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	MarkPasswordResetUsed(ctx context.Context, id int64) (PasswordReset, error)
+	MarkTransferReversed(ctx context.Context, id int64) (Transfer, error)
+	RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (User, error)
+	RecordScheduledTransferFailure(ctx context.Context, arg RecordScheduledTransferFailureParams) (ScheduledTransfer, error)
+	RecordScheduledTransferRun(ctx context.Context, arg RecordScheduledTransferRunParams) (ScheduledTransfer, error)
+	ResetFailedLogins(ctx context.Context, username string) error
+	RestoreAccount(ctx context.Context, arg RestoreAccountParams) (Account, error)
+	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error)
+	SetAccountFrozen(ctx context.Context, arg SetAccountFrozenParams) (Account, error)
+	SetAccountLabels(ctx context.Context, arg SetAccountLabelsParams) (Account, error)
+	SetAccountLowBalanceThreshold(ctx context.Context, arg SetAccountLowBalanceThresholdParams) (Account, error)
+	SetAccountSpendingLimits(ctx context.Context, arg SetAccountSpendingLimitsParams) (Account, error)
+	SoftDeleteAccount(ctx context.Context, id int64) (int64, error)
+	SumOutgoingTransfers(ctx context.Context, arg SumOutgoingTransfersParams) (int64, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
 	UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error)
+	UpdateAccountOwner(ctx context.Context, arg UpdateAccountOwnerParams) (Account, error)
+	UpdateHoldStatus(ctx context.Context, arg UpdateHoldStatusParams) (Hold, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpsertNotificationPreferences(ctx context.Context, arg UpsertNotificationPreferencesParams) (NotificationPreference, error)
+	UpsertTotpSecret(ctx context.Context, arg UpsertTotpSecretParams) (TotpSecret, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func createRandomScheduledTransfer(t *testing.T, fromAccount Account, nextRunAt time.Time) ScheduledTransfer {
+	arg := CreateScheduledTransferParams{
+		FromAccountID:   fromAccount.ID,
+		ToAccountID:     CreateRandomAccount(t).ID,
+		Amount:          utils.RandomInt(1, 100),
+		IntervalSeconds: 3600,
+		NextRunAt:       nextRunAt,
+	}
+
+	schedule, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.NotZero(t, schedule.ID)
+	require.Equal(t, arg.FromAccountID, schedule.FromAccountID)
+	require.Equal(t, arg.ToAccountID, schedule.ToAccountID)
+	require.Equal(t, arg.Amount, schedule.Amount)
+	require.Equal(t, arg.IntervalSeconds, schedule.IntervalSeconds)
+	require.WithinDuration(t, arg.NextRunAt, schedule.NextRunAt, time.Millisecond)
+	require.False(t, schedule.LastRunAt.Valid)
+	require.False(t, schedule.LastError.Valid)
+	require.Zero(t, schedule.FailedRuns)
+	require.False(t, schedule.CancelledAt.Valid)
+
+	return schedule
+}
+
+func TestCreateScheduledTransfer(t *testing.T) {
+	createRandomScheduledTransfer(t, CreateRandomAccount(t), time.Now())
+}
+
+func TestListScheduledTransfers(t *testing.T) {
+	account := CreateRandomAccount(t)
+	n := 5
+	for i := 0; i < n; i++ {
+		createRandomScheduledTransfer(t, account, time.Now().Add(time.Hour))
+	}
+
+	schedules, err := testQueries.ListScheduledTransfers(context.Background(), ListScheduledTransfersParams{
+		FromAccountID: account.ID,
+		Limit:         int32(n),
+		Offset:        0,
+	})
+	require.NoError(t, err)
+	require.Len(t, schedules, n)
+	for _, schedule := range schedules {
+		require.Equal(t, account.ID, schedule.ScheduledTransfer.FromAccountID)
+		require.False(t, schedule.LastTransferUUID.Valid)
+	}
+
+	total, err := testQueries.CountScheduledTransfers(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(n), total)
+}
+
+func TestListDueScheduledTransfers(t *testing.T) {
+	// the other tests schedule their transfers around now, an hour ago leaves them out
+	now := time.Now().Add(-time.Hour).Truncate(time.Second)
+	account := CreateRandomAccount(t)
+
+	due1 := createRandomScheduledTransfer(t, account, now.Add(-2*time.Minute))
+	due2 := createRandomScheduledTransfer(t, account, now)
+	notDue := createRandomScheduledTransfer(t, account, now.Add(time.Minute))
+	cancelled := createRandomScheduledTransfer(t, account, now.Add(-3*time.Minute))
+	_, err := testQueries.CancelScheduledTransfer(context.Background(), cancelled.ID)
+	require.NoError(t, err)
+
+	// the due transfers left by this test would be listed by its next runs
+	t.Cleanup(func() {
+		for _, schedule := range []ScheduledTransfer{due1, due2} {
+			_, err := testQueries.CancelScheduledTransfer(context.Background(), schedule.ID)
+			require.NoError(t, err)
+		}
+	})
+
+	schedules, err := testQueries.ListDueScheduledTransfers(context.Background(), ListDueScheduledTransfersParams{
+		Now:   now,
+		Limit: 1000,
+	})
+	require.NoError(t, err)
+
+	positions := make(map[int64]int)
+	for i, schedule := range schedules {
+		require.False(t, schedule.NextRunAt.After(now))
+		require.False(t, schedule.CancelledAt.Valid)
+		positions[schedule.ID] = i
+	}
+
+	require.Contains(t, positions, due1.ID)
+	require.Contains(t, positions, due2.ID)
+	require.NotContains(t, positions, notDue.ID)
+	require.NotContains(t, positions, cancelled.ID)
+	// the most overdue transfers run first
+	require.Less(t, positions[due1.ID], positions[due2.ID])
+}
+
+func TestClaimScheduledTransfer(t *testing.T) {
+	schedule := createRandomScheduledTransfer(t, CreateRandomAccount(t), time.Now().Add(-time.Minute))
+	arg := ClaimScheduledTransferParams{
+		ID:        schedule.ID,
+		DueAt:     schedule.NextRunAt,
+		NextRunAt: schedule.NextRunAt.Add(time.Hour),
+	}
+
+	claimed, err := testQueries.ClaimScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.WithinDuration(t, arg.NextRunAt, claimed.NextRunAt, time.Millisecond)
+
+	// a second runner listed the same cycle, it must not claim it again
+	_, err = testQueries.ClaimScheduledTransfer(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = testQueries.CancelScheduledTransfer(context.Background(), schedule.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.ClaimScheduledTransfer(context.Background(), ClaimScheduledTransferParams{
+		ID:        schedule.ID,
+		DueAt:     claimed.NextRunAt,
+		NextRunAt: claimed.NextRunAt.Add(time.Hour),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRecordScheduledTransferRun(t *testing.T) {
+	schedule := createRandomScheduledTransfer(t, CreateRandomAccount(t), time.Now().Add(time.Hour))
+	runAt := sql.NullTime{Time: time.Now(), Valid: true}
+
+	for i := 1; i <= 2; i++ {
+		failed, err := testQueries.RecordScheduledTransferFailure(context.Background(), RecordScheduledTransferFailureParams{
+			ID:        schedule.ID,
+			RunAt:     runAt,
+			LastError: sql.NullString{String: ErrInsufficientBalance.Error(), Valid: true},
+		})
+		require.NoError(t, err)
+		require.Equal(t, int32(i), failed.FailedRuns)
+		require.Equal(t, ErrInsufficientBalance.Error(), failed.LastError.String)
+		require.WithinDuration(t, runAt.Time, failed.LastRunAt.Time, time.Millisecond)
+	}
+
+	transfer := createRandomTransfer(t)
+	succeeded, err := testQueries.RecordScheduledTransferRun(context.Background(), RecordScheduledTransferRunParams{
+		ID:         schedule.ID,
+		RunAt:      runAt,
+		TransferID: sql.NullInt64{Int64: transfer.ID, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Zero(t, succeeded.FailedRuns)
+	require.False(t, succeeded.LastError.Valid)
+	require.Equal(t, transfer.ID, succeeded.LastTransferID.Int64)
+
+	// the last transfer is read back by its uuid
+	read, err := testQueries.GetScheduledTransfer(context.Background(), schedule.ID)
+	require.NoError(t, err)
+	require.Equal(t, transfer.ID, read.ScheduledTransfer.LastTransferID.Int64)
+	require.True(t, read.LastTransferUUID.Valid)
+	require.Equal(t, transfer.UUID, read.LastTransferUUID.UUID)
+}
+
+func TestCancelScheduledTransfer(t *testing.T) {
+	schedule := createRandomScheduledTransfer(t, CreateRandomAccount(t), time.Now())
+
+	cancelled, err := testQueries.CancelScheduledTransfer(context.Background(), schedule.ID)
+	require.NoError(t, err)
+	require.True(t, cancelled.CancelledAt.Valid)
+
+	_, err = testQueries.CancelScheduledTransfer(context.Background(), schedule.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCancelAccountScheduledTransfers(t *testing.T) {
+	account := CreateRandomAccount(t)
+	sent := createRandomScheduledTransfer(t, account, time.Now())
+	other := createRandomScheduledTransfer(t, CreateRandomAccount(t), time.Now())
+
+	received, err := testQueries.CreateScheduledTransfer(context.Background(), CreateScheduledTransferParams{
+		FromAccountID:   other.FromAccountID,
+		ToAccountID:     account.ID,
+		Amount:          utils.RandomInt(1, 100),
+		IntervalSeconds: 3600,
+		NextRunAt:       time.Now(),
+	})
+	require.NoError(t, err)
+
+	err = testQueries.CancelAccountScheduledTransfers(context.Background(), account.ID)
+	require.NoError(t, err)
+
+	// the schedules sending to the account are cancelled too, only the unrelated one keeps running
+	for _, schedule := range []ScheduledTransfer{sent, received} {
+		cancelled, err := testQueries.GetScheduledTransfer(context.Background(), schedule.ID)
+		require.NoError(t, err)
+		require.True(t, cancelled.ScheduledTransfer.CancelledAt.Valid)
+	}
+
+	untouched, err := testQueries.GetScheduledTransfer(context.Background(), other.ID)
+	require.NoError(t, err)
+	require.False(t, untouched.ScheduledTransfer.CancelledAt.Valid)
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func createRandomSession(t *testing.T) Session {
+	user := CreateRandomUser(t)
+	args := CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		RefreshToken: "refresh_token",
+		UserAgent:    "user_agent",
+		ClientIp:     "127.0.0.1",
+		IsBlocked:    false,
+		ExpiresAt:    sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), args)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, session)
+
+	require.Equal(t, args.ID, session.ID)
+	require.Equal(t, args.Username, session.Username)
+	require.Equal(t, args.RefreshToken, session.RefreshToken)
+	require.Equal(t, args.ClientIp, session.ClientIP)
+	require.False(t, session.IsBlocked)
+
+	require.NotZero(t, session.CreatedAt)
+
+	return session
+}
+
+func TestCreateSession(t *testing.T) {
+	// sessions from the same client must not collide
+	createRandomSession(t)
+	createRandomSession(t)
+}
+
+func TestGetSession(t *testing.T) {
+	s := createRandomSession(t)
+
+	session, err := testQueries.GetSession(context.Background(), s.ID)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, session)
+
+	require.Equal(t, s.ID, session.ID)
+	require.Equal(t, s.Username, session.Username)
+	require.Equal(t, s.RefreshToken, session.RefreshToken)
+
+	require.WithinDuration(t, s.ExpiresAt.Time, session.ExpiresAt.Time, time.Second)
+}
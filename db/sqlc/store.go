@@ -3,23 +3,131 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OutboxEventTransferCompleted is written to the outbox by every committed transfer, its payload is the transfer
+const OutboxEventTransferCompleted = "transfer.completed"
+
+// OutboxEventAccountLowBalance is written by the transfer taking the from account balance below its low balance threshold,
+// its payload is a LowBalanceAlert
+const OutboxEventAccountLowBalance = "account.low_balance"
+
+// LowBalanceAlert tells the owner the account balance went below the threshold the owner set on it
+type LowBalanceAlert struct {
+	AccountID           int64  `json:"account_id"`
+	Owner               string `json:"owner"`
+	Currency            string `json:"currency"`
+	Balance             int64  `json:"balance"`
+	LowBalanceThreshold int64  `json:"low_balance_threshold"`
+	TransferID          int64  `json:"transfer_id"`
+}
+
+// the actions recorded in the audit log and the entities they target
+const (
+	AuditActionLogin             = "login"
+	AuditActionChangePassword    = "password_change"
+	AuditActionResetPassword     = "password_reset"
+	AuditActionTransferOwnership = "account_ownership_transfer"
+	AuditActionFreezeAccount     = "account_freeze"
+	AuditActionUnfreezeAccount   = "account_unfreeze"
+	AuditEntityUser              = "user"
+	AuditEntityAccount           = "account"
+)
+
+var (
+	ErrInsufficientBalance     = errors.New("insufficient account balance")
+	ErrIdempotencyKeyMismatch  = errors.New("idempotency key already used with a different request")
+	ErrTransferAlreadyReversed = errors.New("transfer already reversed")
+	ErrTransferIsReversal      = errors.New("transfer is itself a reversal")
+	ErrDailyTransferLimit      = errors.New("daily transfer limit exceeded")
+	ErrAccountSpendingLimit    = errors.New("account spending limit exceeded")
+	ErrAccountFrozen           = errors.New("account is frozen")
+	ErrInvalidSplit            = errors.New("invalid split transfer")
+	ErrCurrencyMismatch        = errors.New("account currencies mismatch")
+	ErrVersionConflict         = errors.New("account was updated since it was read")
+	ErrNewOwnerNotFound        = errors.New("new owner doesn't exist")
+	ErrOwnerHasCurrency        = errors.New("new owner already has an account in that currency")
+	ErrHoldNotAuthorized       = errors.New("hold is no longer authorized")
+	ErrHoldExpired             = errors.New("hold expired")
+	ErrCaptureExceedsHold      = errors.New("capture exceeds the authorized amount")
+	ErrOrganizationMismatch    = errors.New("accounts belong to different organizations")
+	ErrCloseIntoItself         = errors.New("account can't be closed into itself")
+	ErrAccountHasHolds         = errors.New("account has authorized holds")
+	ErrResetTokenInvalid       = errors.New("invalid password reset token")
+	ErrResetTokenUsed          = errors.New("password reset token already used")
+	ErrResetTokenExpired       = errors.New("password reset token expired")
+	ErrAccountNotFound         = errors.New("account not found")
+	ErrAccountDeleted          = errors.New("account is deleted")
+)
+
+// DefaultOrgID is the organization every user and account created before organizations were added belongs to
+const DefaultOrgID int64 = 1
+
+// the statuses of a hold, only an authorized hold reserves funds of its from account
+const (
+	HoldStatusAuthorized = "authorized"
+	HoldStatusCaptured   = "captured"
+	HoldStatusVoided     = "voided"
+	HoldStatusExpired    = "expired"
 )
 
 type Store interface {
 	Querier
+	ExecTx(ctx context.Context, fn func(Querier) error) error
 	TransferTx(ctx context.Context, params TransferTxParams) (TransferTxResult, error)
+	IdempotentTransferTx(ctx context.Context, params IdempotentTransferTxParams) (IdempotentTransferTxResult, error)
+	IdempotentCreateAccountTx(ctx context.Context, params IdempotentCreateAccountTxParams) (IdempotentCreateAccountTxResult, error)
+	ReverseTransferTx(ctx context.Context, params ReverseTransferTxParams) (ReverseTransferTxResult, error)
+	SplitTransferTx(ctx context.Context, params SplitTransferTxParams) (SplitTransferTxResult, error)
+	AddAccountBalanceTx(ctx context.Context, params AddAccountBalanceTxParams) (Account, error)
+	EntryTx(ctx context.Context, params EntryTxParams) (EntryTxResult, error)
+	ImportUsersTx(ctx context.Context, params ImportUsersTxParams) (ImportUsersTxResult, error)
+	TransferAccountOwnershipTx(ctx context.Context, params TransferAccountOwnershipTxParams) (TransferAccountOwnershipTxResult, error)
+	CloseAccountTx(ctx context.Context, params CloseAccountTxParams) (CloseAccountTxResult, error)
+	LoginTx(ctx context.Context, params LoginTxParams) (Session, error)
+	ChangePasswordTx(ctx context.Context, params ChangePasswordTxParams) (User, error)
+	ResetPasswordTx(ctx context.Context, params ResetPasswordTxParams) (User, error)
+	SetAccountFrozenTx(ctx context.Context, params SetAccountFrozenTxParams) (Account, error)
+	AuthorizeHoldTx(ctx context.Context, params AuthorizeHoldTxParams) (AuthorizeHoldTxResult, error)
+	CaptureHoldTx(ctx context.Context, params CaptureHoldTxParams) (CaptureHoldTxResult, error)
+	VoidHoldTx(ctx context.Context, params VoidHoldTxParams) (VoidHoldTxResult, error)
+	ExpireHoldsTx(ctx context.Context, params ExpireHoldsTxParams) ([]Hold, error)
+	LookupAccounts(ctx context.Context, ids []int64) (map[int64]Account, error)
+	LookupAccountsByNumber(ctx context.Context, accountNumbers []string) (map[string]Account, error)
+	Ping(ctx context.Context) error
+	StreamAccountStatement(ctx context.Context, arg ListAccountStatementParams, fn func(ListAccountStatementRow) error) error
+	PublishOutbox(ctx context.Context, limit int32, publish func(context.Context, Outbox) error) (int, error)
 }
 
 type (
 	SQLStore struct {
 		db *sql.DB
 		*Queries
+		rates ExchangeRateProvider
 	}
 	TransferTxParams struct {
 		FromAccountID int64 `json:"from_account_id"`
 		ToAccountID   int64 `json:"to_account_id"`
 		Amount        int64 `json:"amount"`
+		// DailyLimit caps the amount the from account owner sends per UTC day, 0 disables it
+		DailyLimit int64 `json:"daily_limit"`
+		// Description labels the transfer for its owners, empty stores none
+		Description string `json:"description"`
+		// DryRun runs every check of the transfer and rolls it back, the result previews the balances without changing them
+		DryRun bool `json:"dry_run"`
+		// SkipAccountLimits leaves out the daily and monthly limits of the from account, for the money an owner moves to itself
+		SkipAccountLimits bool `json:"skip_account_limits"`
 	}
 	TransferTxResult struct {
 		Transfer      Transfer `json:"transfer"`
@@ -28,21 +136,241 @@ type (
 		FromEntry     Entry    `json:"from_entry"`
 		ToEntry       Entry    `json:"to_entry"`
 	}
+	IdempotentTransferTxParams struct {
+		TransferTxParams
+		Username       string `json:"username"`
+		IdempotencyKey string `json:"idempotency_key"`
+		RequestHash    string `json:"request_hash"`
+	}
+	IdempotentTransferTxResult struct {
+		TransferTxResult
+		Replayed bool `json:"replayed"`
+	}
+	IdempotentCreateAccountTxParams struct {
+		CreateAccountParams
+		IdempotencyKey string `json:"idempotency_key"`
+		RequestHash    string `json:"request_hash"`
+	}
+	IdempotentCreateAccountTxResult struct {
+		Account  Account `json:"account"`
+		Replayed bool    `json:"replayed"`
+	}
+	ReverseTransferTxParams struct {
+		TransferID int64 `json:"transfer_id"`
+	}
+	ReverseTransferTxResult struct {
+		TransferTxResult
+		OriginalTransfer Transfer `json:"original_transfer"`
+	}
+	TransferSplit struct {
+		ToAccountID int64 `json:"to_account_id"`
+		Amount      int64 `json:"amount"`
+	}
+	SplitTransferTxParams struct {
+		FromAccountID int64           `json:"from_account_id"`
+		Amount        int64           `json:"amount"`
+		Splits        []TransferSplit `json:"splits"`
+		// DailyLimit caps the amount the from account owner sends per UTC day, 0 disables it
+		DailyLimit int64 `json:"daily_limit"`
+	}
+	SplitTransferTxResult struct {
+		FromAccount Account            `json:"from_account"`
+		Transfers   []TransferTxResult `json:"transfers"`
+	}
+	AddAccountBalanceTxParams struct {
+		AccountID int64 `json:"account_id"`
+		Amount    int64 `json:"amount"`
+		// ExpectedVersion is the account version the caller read, the update is rejected if the account changed since. 0 skips the check
+		ExpectedVersion int64 `json:"expected_version"`
+	}
+	EntryTxParams struct {
+		AccountID int64 `json:"account_id"`
+		Amount    int64 `json:"amount"`
+	}
+	EntryTxResult struct {
+		Account Account `json:"account"`
+		Entry   Entry   `json:"entry"`
+	}
 	BalanceTx struct {
 		AccountID1 int64
 		AccountID2 int64
 		Amount1    int64
 		Amount2    int64
 	}
+	ImportUsersTxParams struct {
+		Users []CreateUserParams `json:"users"`
+		// Atomic creates either every user or none of them, otherwise the users that fail are skipped and the others are created
+		Atomic bool `json:"atomic"`
+	}
+	ImportUsersTxResult struct {
+		// Users and Errs follow the order of the params users, a user was created when its error is nil
+		Users []User  `json:"users"`
+		Errs  []error `json:"-"`
+		// RolledBack is set when an atomic import had a failing user, none of them was created then
+		RolledBack bool `json:"rolled_back"`
+	}
+	// Actor is who makes an audited change, it is recorded in the audit log within the transaction of the change
+	Actor struct {
+		Username string `json:"username"`
+		ClientIP string `json:"client_ip"`
+	}
+	TransferAccountOwnershipTxParams struct {
+		AccountID int64  `json:"account_id"`
+		NewOwner  string `json:"new_owner"`
+		// Actor is the banker making the change, it is recorded in the account owner history too
+		Actor Actor `json:"actor"`
+	}
+	TransferAccountOwnershipTxResult struct {
+		Account Account            `json:"account"`
+		Change  AccountOwnerChange `json:"change"`
+	}
+	CloseAccountTxParams struct {
+		AccountID int64 `json:"account_id"`
+		// DestinationID receives the remaining balance, it must be another live account in the same currency and organization.
+		// The owner_currency_key index allows a single live account per owner and currency, so the destination always belongs
+		// to someone else: requiring the same owner as well as the same currency would leave no account to close into
+		DestinationID int64 `json:"destination_id"`
+	}
+	CloseAccountTxResult struct {
+		// SweptAmount is the balance moved to the destination, Transfer is nil when the account was already empty
+		SweptAmount int64     `json:"swept_amount"`
+		Transfer    *Transfer `json:"transfer"`
+		Destination Account   `json:"destination"`
+	}
+	// LoginTxParams is the session of the login, its user and client ip are the ones audited
+	LoginTxParams struct {
+		CreateSessionParams
+	}
+	ChangePasswordTxParams struct {
+		UpdateUserPasswordParams
+		Actor Actor `json:"actor"`
+	}
+	// ResetPasswordTxParams redeems the reset whose token hashes to TokenHash, its user and the client ip are the ones audited
+	ResetPasswordTxParams struct {
+		TokenHash      string `json:"token_hash"`
+		HashedPassword string `json:"hashed_password"`
+		ClientIP       string `json:"client_ip"`
+	}
+	SetAccountFrozenTxParams struct {
+		SetAccountFrozenParams
+		Actor Actor `json:"actor"`
+	}
+	AuthorizeHoldTxParams struct {
+		FromAccountID int64     `json:"from_account_id"`
+		ToAccountID   int64     `json:"to_account_id"`
+		Amount        int64     `json:"amount"`
+		ExpiresAt     time.Time `json:"expires_at"`
+	}
+	AuthorizeHoldTxResult struct {
+		Hold        Hold    `json:"hold"`
+		FromAccount Account `json:"from_account"`
+	}
+	CaptureHoldTxParams struct {
+		HoldID int64 `json:"hold_id"`
+		// Amount is the part of the hold transferred, 0 captures all of it. What isn't captured is released
+		Amount int64 `json:"amount"`
+		// DailyLimit caps the amount the from account owner sends per UTC day, 0 disables it
+		DailyLimit int64 `json:"daily_limit"`
+	}
+	CaptureHoldTxResult struct {
+		TransferTxResult
+		Hold Hold `json:"hold"`
+	}
+	VoidHoldTxParams struct {
+		HoldID int64 `json:"hold_id"`
+	}
+	VoidHoldTxResult struct {
+		Hold        Hold    `json:"hold"`
+		FromAccount Account `json:"from_account"`
+	}
+	ExpireHoldsTxParams struct {
+		Now   time.Time `json:"now"`
+		Limit int32     `json:"limit"`
+	}
 )
 
+// errImportRolledBack makes execTx roll back an atomic import once every user was tried
+var errImportRolledBack = errors.New("import rolled back")
+
+// errDryRunRolledBack makes execTx roll back a dry run transfer once it went through
+var errDryRunRolledBack = errors.New("dry run rolled back")
+
 var txKey = struct{}{}
 
 func NewStore(db *sql.DB) Store {
+	return NewStoreWithExchangeRates(db, nil)
+}
+
+// NewStoreWithExchangeRates returns a Store converting the transfers between accounts of different currencies with rates,
+// without rates only the accounts of the same currency can transfer to each other
+func NewStoreWithExchangeRates(db *sql.DB, rates ExchangeRateProvider) Store {
 	return &SQLStore{
 		db:      db,
 		Queries: New(db),
+		rates:   rates,
+	}
+}
+
+// Ping verifies the database connection is still alive
+func (s *SQLStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// LookupAccounts reads the accounts of ids in a single query, keyed by id. The ids not found are absent from the map
+func (s *SQLStore) LookupAccounts(ctx context.Context, ids []int64) (map[int64]Account, error) {
+	accounts, err := s.GetAccountsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]Account, len(accounts))
+	for _, account := range accounts {
+		byID[account.ID] = account
+	}
+	return byID, nil
+}
+
+// LookupAccountsByNumber reads the accounts of accountNumbers in a single query, keyed by account number.
+// The numbers not found are absent from the map
+func (s *SQLStore) LookupAccountsByNumber(ctx context.Context, accountNumbers []string) (map[string]Account, error) {
+	accounts, err := s.GetAccountsByNumbers(ctx, accountNumbers)
+	if err != nil {
+		return nil, err
+	}
+
+	byNumber := make(map[string]Account, len(accounts))
+	for _, account := range accounts {
+		byNumber[account.AccountNumber] = account
+	}
+	return byNumber, nil
+}
+
+// StreamAccountStatement runs the ListAccountStatement query calling fn for every row as soon as it's read,
+// so large statements are never held in memory. It stops at the first error returned by fn
+func (s *SQLStore) StreamAccountStatement(ctx context.Context, arg ListAccountStatementParams, fn func(ListAccountStatementRow) error) error {
+	rows, err := s.db.QueryContext(ctx, listAccountStatement, arg.AccountID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i ListAccountStatementRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Amount,
+			&i.AccountID,
+			&i.CreatedAt,
+			&i.RunningBalance,
+		); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
 	}
+
+	return rows.Err()
 }
 
 // execTx receives a function as a parameter and executes it within the database transaction
@@ -66,91 +394,1191 @@ func (s *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
 	return tx.Commit()
 }
 
+// ExecTx runs fn within a database transaction, so application code can compose several queries atomically
+// The transaction is committed once fn returns nil, any error rolls back every write fn made through the given queries.
+// A retrying store may run fn again after a serialization failure, so fn must not have effects outside the database
+func (s *SQLStore) ExecTx(ctx context.Context, fn func(Querier) error) error {
+	return s.execTx(ctx, func(q *Queries) error {
+		return fn(q)
+	})
+}
+
 // TransferTx executes a query performing all the necessary db transactions involved in a transfer
-// It creates the transfer register, creates the account entries and updates the balance in both accounts within a single database transaction
+// It creates the transfer register, creates the account entries and updates the balance in both accounts within a single database transaction.
+// When the accounts currencies differ the to account is credited the amount converted with the store exchange rates
+// A dry run goes through the same statements and rolls them back, so it fails exactly like the real transfer would
+// Locking order: the account rows are always locked in ascending ID order, whatever the transfer direction,
+// so concurrent transfers between the same accounts wait for each other instead of deadlocking
 func (s *SQLStore) TransferTx(ctx context.Context, params TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
-	txName := ctx.Value(txKey)
+	// a no-op unless the store is traced, it helps tell slow transfers apart
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int64("transfer.from_account_id", params.FromAccountID),
+		attribute.Int64("transfer.to_account_id", params.ToAccountID),
+		attribute.Int64("transfer.amount", params.Amount),
+	)
 
 	err := s.execTx(ctx, func(q *Queries) error {
-		var err error
-		fmt.Println(txName, "create transfer")
-		result.Transfer, err = s.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: params.FromAccountID,
-			ToAccountID:   params.ToAccountID,
-			Amount:        params.Amount,
-		})
+		toAmount, err := s.creditAmount(ctx, q, params)
+		if err != nil {
+			return err
+		}
+
+		result, err = transfer(ctx, q, params, toAmount, sql.NullInt64{})
+		if err == nil && params.DryRun {
+			return errDryRunRolledBack
+		}
+		return err
+	})
+	if errors.Is(err, errDryRunRolledBack) {
+		return result, nil
+	}
+
+	return result, err
+}
+
+// IdempotentTransferTx executes the transfer only once per username and idempotency key
+// The transfer result is stored along with the key in the same database transaction, so a repeated key replays the stored result instead of moving the money again
+func (s *SQLStore) IdempotentTransferTx(ctx context.Context, params IdempotentTransferTxParams) (IdempotentTransferTxResult, error) {
+	var result IdempotentTransferTxResult
+
+	keyParams := GetIdempotencyKeyParams{
+		Username: params.Username,
+		Key:      params.IdempotencyKey,
+	}
+
+	key, err := s.GetIdempotencyKey(ctx, keyParams)
+	if err == nil {
+		return replayTransfer(key, params.RequestHash)
+	}
+	if err != sql.ErrNoRows {
+		return result, err
+	}
 
+	err = s.execTx(ctx, func(q *Queries) error {
+		toAmount, err := s.creditAmount(ctx, q, params.TransferTxParams)
 		if err != nil {
 			return err
 		}
 
-		fmt.Println(txName, "create first entry")
-		result.FromEntry, err = s.CreateEntry(ctx, CreateEntryParams{
-			Amount:    -params.Amount,
-			AccountID: params.FromAccountID,
-		})
+		result.TransferTxResult, err = transfer(ctx, q, params.TransferTxParams, toAmount, sql.NullInt64{})
+		if err != nil {
+			return err
+		}
 
+		response, err := json.Marshal(result.TransferTxResult)
 		if err != nil {
 			return err
 		}
 
-		fmt.Println(txName, "create second entry")
-		result.ToEntry, err = s.CreateEntry(ctx, CreateEntryParams{
-			Amount:    params.Amount,
-			AccountID: params.ToAccountID,
+		_, err = q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+			Username:    params.Username,
+			Key:         params.IdempotencyKey,
+			RequestHash: params.RequestHash,
+			Response:    response,
 		})
+		return err
+	})
 
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+		// a concurrent request with the same key committed first, so this transfer was rolled back
+		key, err = s.GetIdempotencyKey(ctx, keyParams)
 		if err != nil {
-			return err
+			return result, err
 		}
+		return replayTransfer(key, params.RequestHash)
+	}
 
-		if params.FromAccountID < params.ToAccountID {
-			result.FromAccountID, result.ToAccountID, err = modifyBalance(ctx, q, BalanceTx{
-				AccountID1: params.FromAccountID,
-				AccountID2: params.ToAccountID,
-				Amount1:    -params.Amount,
-				Amount2:    params.Amount,
-			})
+	return result, err
+}
+
+// replayTransfer returns the transfer result stored with the idempotency key
+// It fails if the key was first used with a different request
+func replayTransfer(key IdempotencyKey, requestHash string) (IdempotentTransferTxResult, error) {
+	var result IdempotentTransferTxResult
+
+	if key.RequestHash != requestHash {
+		return result, fmt.Errorf("%w: key [%v]", ErrIdempotencyKeyMismatch, key.Key)
+	}
+
+	if err := json.Unmarshal(key.Response, &result.TransferTxResult); err != nil {
+		return result, err
+	}
+	result.Replayed = true
+
+	return result, nil
+}
+
+// IdempotentCreateAccountTx creates the account only once per owner and idempotency key
+// The account id is stored along with the key in the same database transaction, so a repeated key returns the account created first
+func (s *SQLStore) IdempotentCreateAccountTx(ctx context.Context, params IdempotentCreateAccountTxParams) (IdempotentCreateAccountTxResult, error) {
+	var result IdempotentCreateAccountTxResult
+
+	keyParams := GetAccountIdempotencyKeyParams{
+		Owner: params.Owner,
+		Key:   params.IdempotencyKey,
+	}
+
+	key, err := s.GetAccountIdempotencyKey(ctx, keyParams)
+	if err == nil {
+		return s.replayCreateAccount(ctx, key, params.RequestHash)
+	}
+	if err != sql.ErrNoRows {
+		return result, err
+	}
+
+	err = s.execTx(ctx, func(q *Queries) error {
+		arg := params.CreateAccountParams
+		if arg.AccountNumber == "" {
+			var err error
+			arg.AccountNumber, err = NewAccountNumber(ctx, q)
 			if err != nil {
 				return err
 			}
-		} else {
-			result.ToAccountID, result.FromAccountID, err = modifyBalance(ctx, q, BalanceTx{
-				AccountID1: params.ToAccountID,
-				AccountID2: params.FromAccountID,
-				Amount1:    params.Amount,
-				Amount2:    -params.Amount,
+		}
+
+		var err error
+		result.Account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		// the initial balance is recorded by an opening entry, like a deposit
+		if params.Balance != 0 {
+			_, err = q.CreateEntry(ctx, CreateEntryParams{
+				AccountID: result.Account.ID,
+				Amount:    params.Balance,
 			})
 			if err != nil {
 				return err
 			}
 		}
 
-		return nil
-
+		_, err = q.CreateAccountIdempotencyKey(ctx, CreateAccountIdempotencyKeyParams{
+			Owner:       params.Owner,
+			Key:         params.IdempotencyKey,
+			RequestHash: params.RequestHash,
+			AccountID:   result.Account.ID,
+		})
+		return err
 	})
 
+	// a concurrent request with the same key committed first, so this account was rolled back:
+	// its insert may have failed on the currency constraint before the key one did
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+		key, keyErr := s.GetAccountIdempotencyKey(ctx, keyParams)
+		if keyErr == sql.ErrNoRows {
+			return result, err
+		}
+		if keyErr != nil {
+			return result, keyErr
+		}
+		return s.replayCreateAccount(ctx, key, params.RequestHash)
+	}
+
 	return result, err
 }
 
-func modifyBalance(ctx context.Context, q *Queries, balance BalanceTx) (account1 Account, account2 Account, err error) {
-	account1, err = q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
-		Amount: balance.Amount1,
-		ID:     balance.AccountID1,
+// replayCreateAccount returns the account created with the idempotency key
+// It fails if the key was first used with a different request
+func (s *SQLStore) replayCreateAccount(ctx context.Context, key AccountIdempotencyKey, requestHash string) (IdempotentCreateAccountTxResult, error) {
+	var result IdempotentCreateAccountTxResult
+
+	if key.RequestHash != requestHash {
+		return result, fmt.Errorf("%w: key [%v]", ErrIdempotencyKeyMismatch, key.Key)
+	}
+
+	account, err := s.GetAccount(ctx, key.AccountID)
+	if err != nil {
+		return result, err
+	}
+	result.Account = account
+	result.Replayed = true
+
+	return result, nil
+}
+
+// ReverseTransferTx moves the funds of a transfer back with a compensating transfer linked to it through reversed_from,
+// and marks the original as reversed so it can't be reversed twice
+// The original transfer row is locked first, so concurrent reversals of the same transfer wait for each other and only one succeeds.
+// The account rows are then locked in ascending ID order, as in TransferTx
+func (s *SQLStore) ReverseTransferTx(ctx context.Context, params ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		original, err := q.GetTransferForUpdate(ctx, params.TransferID)
+		if err != nil {
+			return err
+		}
+
+		if original.ReversedAt.Valid {
+			return fmt.Errorf("%w: transfer [%v]", ErrTransferAlreadyReversed, original.UUID)
+		}
+		if original.ReversedFrom.Valid {
+			return fmt.Errorf("%w: transfer [%v]", ErrTransferIsReversal, original.UUID)
+		}
+
+		// the deleted accounts are locked too, so a sender that closed its account since is told so instead of not being found.
+		// Its owner couldn't see nor withdraw a refund, the account has to be restored before the transfer is reversed
+		sender, receiver, err := lockAccountsWith(ctx, q.GetAccountForUpdateIncludingDeleted, original.FromAccountID, original.ToAccountID)
+		if err != nil {
+			return err
+		}
+		for _, account := range []Account{sender, receiver} {
+			if account.DeletedAt.Valid {
+				return fmt.Errorf("%w: account [%v] must be restored before the transfer is reversed", ErrAccountDeleted, account.AccountNumber)
+			}
+		}
+
+		// a converted transfer is reversed at its original rate, the receiver gives back what it was credited
+		if receiver.availableBalance() < original.ToAmount {
+			return fmt.Errorf("%w: account [%v] available balance %v can't cover %v", ErrInsufficientBalance, receiver.AccountNumber, receiver.availableBalance(), original.ToAmount)
+		}
+
+		result.TransferTxResult, err = transfer(ctx, q, TransferTxParams{
+			FromAccountID: original.ToAccountID,
+			ToAccountID:   original.FromAccountID,
+			Amount:        original.ToAmount,
+		}, original.Amount, sql.NullInt64{Int64: original.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+
+		result.OriginalTransfer, err = q.MarkTransferReversed(ctx, original.ID)
+		return err
 	})
+
+	return result, err
+}
+
+// lockAccounts locks both account rows in ascending ID order and returns them as the from and to accounts
+func lockAccounts(ctx context.Context, q *Queries, fromAccountID, toAccountID int64) (from Account, to Account, err error) {
+	return lockAccountsWith(ctx, q.GetAccountForUpdate, fromAccountID, toAccountID)
+}
+
+// lockAccountsWith is lockAccounts locking each row with lock, so the callers can choose whether the deleted accounts are found
+func lockAccountsWith(ctx context.Context, lock func(context.Context, int64) (Account, error), fromAccountID, toAccountID int64) (from Account, to Account, err error) {
+	firstID, secondID := fromAccountID, toAccountID
+	if firstID > secondID {
+		firstID, secondID = secondID, firstID
+	}
+
+	first, err := lock(ctx, firstID)
 	if err != nil {
 		return
 	}
 
-	account2, err = q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
-		Amount: balance.Amount2,
-		ID:     balance.AccountID2,
-	})
+	second, err := lock(ctx, secondID)
 	if err != nil {
 		return
 	}
 
-	return account1, account2, nil
+	if first.ID == toAccountID {
+		return second, first, nil
+	}
+	return first, second, nil
+}
+
+// creditAmount returns the amount the to account of the transfer receives in its own currency
+// The currencies are read without locking the accounts, an account currency never changes
+func (s *SQLStore) creditAmount(ctx context.Context, q *Queries, params TransferTxParams) (int64, error) {
+	accounts, err := q.GetAccountsByIDs(ctx, []int64{params.FromAccountID, params.ToAccountID})
+	if err != nil {
+		return 0, err
+	}
+
+	currencies := make(map[int64]string, len(accounts))
+	for _, account := range accounts {
+		currencies[account.ID] = account.Currency
+	}
+	from, to := currencies[params.FromAccountID], currencies[params.ToAccountID]
+	// a missing account is reported by the transfer itself
+	if from == "" || to == "" || from == to {
+		return params.Amount, nil
+	}
+
+	if s.rates == nil {
+		return 0, fmt.Errorf("%w: %v to %v", ErrNoExchangeRate, from, to)
+	}
+	rate, err := s.rates.Rate(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return convertAmount(params.Amount, rate)
+}
+
+// transfer creates the transfer register, the account entries and updates both balances using the given queries
+// toAmount is credited to the to account, it only differs from the amount on cross-currency transfers.
+// reversedFrom links the transfer to the one it reverses, it's null for regular transfers
+func transfer(ctx context.Context, q *Queries, params TransferTxParams, toAmount int64, reversedFrom sql.NullInt64) (TransferTxResult, error) {
+	var result TransferTxResult
+	var err error
+
+	// the balance update doesn't look at deleted_at, so a deleted account is caught here with its row locked
+	if _, _, err = lockAccounts(ctx, q, params.FromAccountID, params.ToAccountID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, fmt.Errorf("%w: the from or to account doesn't exist or was deleted", ErrAccountNotFound)
+		}
+		return result, err
+	}
+
+	txName := ctx.Value(txKey)
+
+	fmt.Println(txName, "create transfer")
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: params.FromAccountID,
+		ToAccountID:   params.ToAccountID,
+		Amount:        params.Amount,
+		ToAmount:      toAmount,
+		ReversedFrom:  reversedFrom,
+		Description:   sql.NullString{String: params.Description, Valid: params.Description != ""},
+		UUID:          uuid.New(),
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	fmt.Println(txName, "create first entry")
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		Amount:    -params.Amount,
+		AccountID: params.FromAccountID,
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	fmt.Println(txName, "create second entry")
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		Amount:    toAmount,
+		AccountID: params.ToAccountID,
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	// the lower account id is always updated first, see the TransferTx locking order
+	if params.FromAccountID < params.ToAccountID {
+		result.FromAccountID, result.ToAccountID, err = modifyBalance(ctx, q, BalanceTx{
+			AccountID1: params.FromAccountID,
+			AccountID2: params.ToAccountID,
+			Amount1:    -params.Amount,
+			Amount2:    toAmount,
+		})
+	} else {
+		result.ToAccountID, result.FromAccountID, err = modifyBalance(ctx, q, BalanceTx{
+			AccountID1: params.ToAccountID,
+			AccountID2: params.FromAccountID,
+			Amount1:    toAmount,
+			Amount2:    -params.Amount,
+		})
+	}
+	if err != nil {
+		return result, err
+	}
+
+	// the balance updates locked both rows, so an account can't be frozen between this check and the commit
+	for _, account := range []Account{result.FromAccountID, result.ToAccountID} {
+		if account.IsFrozen {
+			return result, fmt.Errorf("%w: account [%v] can't send nor receive transfers", ErrAccountFrozen, account.AccountNumber)
+		}
+	}
+	// money never crosses tenants, whatever the handler calling the store checked
+	if result.FromAccountID.OrgID != result.ToAccountID.OrgID {
+		return result, fmt.Errorf("%w: account [%v] can't send to account [%v]", ErrOrganizationMismatch, result.FromAccountID.AccountNumber, result.ToAccountID.AccountNumber)
+	}
+	if result.FromAccountID.availableBalance() < 0 {
+		return result, fmt.Errorf("%w: account [%v] available balance %v can't cover %v", ErrInsufficientBalance, result.FromAccountID.AccountNumber, result.FromAccountID.availableBalance()+params.Amount, params.Amount)
+	}
+
+	// a reversal gives the money back, it doesn't spend from the limits of the account returning it
+	if !params.SkipAccountLimits && !reversedFrom.Valid {
+		if err = checkAccountLimits(ctx, q, result.FromAccountID); err != nil {
+			return result, err
+		}
+	}
+
+	// the total row is locked until the transaction ends, so concurrent transfers of the same user can't all slip under the limit
+	if params.DailyLimit > 0 {
+		total, err := q.AddDailyTransferTotal(ctx, AddDailyTransferTotalParams{
+			Username: result.FromAccountID.Owner,
+			Amount:   params.Amount,
+		})
+		if err != nil {
+			return result, err
+		}
+		if total > params.DailyLimit {
+			return result, fmt.Errorf("%w: user [%v] would send %v today, the limit is %v", ErrDailyTransferLimit, result.FromAccountID.Owner, total, params.DailyLimit)
+		}
+	}
+
+	// the event is committed along with the transfer, so it can't be lost nor published for a rolled back transfer
+	payload, err := json.Marshal(result.Transfer)
+	if err != nil {
+		return result, err
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventType: OutboxEventTransferCompleted,
+		Payload:   payload,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if crossedLowBalance(result.FromAccountID, params.Amount) {
+		err = createLowBalanceAlert(ctx, q, result)
+	}
+
+	return result, err
+}
+
+// crossedLowBalance reports whether debiting amount took the account balance from its low balance threshold or above
+// to below it. The transfers debiting an account already below don't cross it, so the owner is alerted once until
+// the balance is back above the threshold
+func crossedLowBalance(account Account, amount int64) bool {
+	if account.LowBalanceThreshold <= 0 || amount <= 0 {
+		return false
+	}
+	return account.Balance < account.LowBalanceThreshold && account.Balance+amount >= account.LowBalanceThreshold
+}
+
+// createLowBalanceAlert writes the low balance alert of the from account to the outbox, it's committed or rolled back
+// along with the transfer that crossed the threshold
+func createLowBalanceAlert(ctx context.Context, q *Queries, result TransferTxResult) error {
+	account := result.FromAccountID
+	payload, err := json.Marshal(LowBalanceAlert{
+		AccountID:           account.ID,
+		Owner:               account.Owner,
+		Currency:            account.Currency,
+		Balance:             account.Balance,
+		LowBalanceThreshold: account.LowBalanceThreshold,
+		TransferID:          result.Transfer.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventType: OutboxEventAccountLowBalance,
+		Payload:   payload,
+	})
+	return err
+}
+
+// checkAccountLimits fails when the outgoing transfers of the account this UTC day or month, the new one included, exceed
+// its daily or monthly limit. The balance update locked the account row, so concurrent transfers of the account are
+// summed one after the other and can't all slip under a limit
+func checkAccountLimits(ctx context.Context, q *Queries, account Account) error {
+	now := time.Now().UTC()
+	limits := []struct {
+		period string
+		limit  int64
+		since  time.Time
+	}{
+		{"today", account.DailyLimit, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)},
+		{"this month", account.MonthlyLimit, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, l := range limits {
+		if l.limit <= 0 {
+			continue
+		}
+		total, err := q.SumOutgoingTransfers(ctx, SumOutgoingTransfersParams{
+			FromAccountID: account.ID,
+			Since:         l.since,
+		})
+		if err != nil {
+			return err
+		}
+		if total > l.limit {
+			return fmt.Errorf("%w: account [%v] would send %v %v, the limit is %v", ErrAccountSpendingLimit, account.AccountNumber, total, l.period, l.limit)
+		}
+	}
+	return nil
+}
+
+// SplitTransferTx sends amount from one account to several receivers, one transfer per split, within a single database transaction
+// Every account is locked in ascending ID order before moving any money, the same order TransferTx follows, so split and regular transfers don't deadlock.
+// The splits must add up to amount, go to distinct accounts other than the sender and share its currency, otherwise nothing is transferred
+func (s *SQLStore) SplitTransferTx(ctx context.Context, params SplitTransferTxParams) (SplitTransferTxResult, error) {
+	var result SplitTransferTxResult
+
+	if err := validSplits(params); err != nil {
+		return result, err
+	}
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		accountIDs := []int64{params.FromAccountID}
+		for _, split := range params.Splits {
+			accountIDs = append(accountIDs, split.ToAccountID)
+		}
+		sort.Slice(accountIDs, func(i, j int) bool { return accountIDs[i] < accountIDs[j] })
+
+		accounts := make(map[int64]Account, len(accountIDs))
+		for _, id := range accountIDs {
+			account, err := q.GetAccountForUpdate(ctx, id)
+			if err != nil {
+				return err
+			}
+			accounts[id] = account
+		}
+
+		from := accounts[params.FromAccountID]
+		for _, account := range accounts {
+			if account.Currency != from.Currency {
+				return fmt.Errorf("%w: account [%v] currency %v - from account currency %v", ErrCurrencyMismatch, account.AccountNumber, account.Currency, from.Currency)
+			}
+			if account.OrgID != from.OrgID {
+				return fmt.Errorf("%w: account [%v] can't send to account [%v]", ErrOrganizationMismatch, from.AccountNumber, account.AccountNumber)
+			}
+		}
+		if from.availableBalance() < params.Amount {
+			return fmt.Errorf("%w: account [%v] available balance %v can't cover %v", ErrInsufficientBalance, from.AccountNumber, from.availableBalance(), params.Amount)
+		}
+
+		result.Transfers = make([]TransferTxResult, 0, len(params.Splits))
+		for _, split := range params.Splits {
+			transferResult, err := transfer(ctx, q, TransferTxParams{
+				FromAccountID: params.FromAccountID,
+				ToAccountID:   split.ToAccountID,
+				Amount:        split.Amount,
+				DailyLimit:    params.DailyLimit,
+			}, split.Amount, sql.NullInt64{})
+			if err != nil {
+				return err
+			}
+			result.Transfers = append(result.Transfers, transferResult)
+			result.FromAccount = transferResult.FromAccountID
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+// validSplits rejects the split transfers that can't be executed whatever the accounts state
+func validSplits(params SplitTransferTxParams) error {
+	if len(params.Splits) == 0 {
+		return fmt.Errorf("%w: at least one split is required", ErrInvalidSplit)
+	}
+
+	var sum int64
+	receivers := make(map[int64]bool, len(params.Splits))
+	for _, split := range params.Splits {
+		if split.Amount <= 0 {
+			return fmt.Errorf("%w: split amount %v must be positive", ErrInvalidSplit, split.Amount)
+		}
+		if split.ToAccountID == params.FromAccountID {
+			return fmt.Errorf("%w: the from account can't send to itself", ErrInvalidSplit)
+		}
+		if receivers[split.ToAccountID] {
+			return fmt.Errorf("%w: an account receives more than one split", ErrInvalidSplit)
+		}
+		receivers[split.ToAccountID] = true
+		sum += split.Amount
+	}
+
+	if sum != params.Amount {
+		return fmt.Errorf("%w: splits add up to %v, the amount is %v", ErrInvalidSplit, sum, params.Amount)
+	}
+	return nil
+}
+
+// modifyBalance updates AccountID1 and then AccountID2, callers must pass the lower account id as AccountID1
+func modifyBalance(ctx context.Context, q *Queries, balance BalanceTx) (account1 Account, account2 Account, err error) {
+	account1, err = q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
+		Amount: balance.Amount1,
+		ID:     balance.AccountID1,
+	})
+	if err != nil {
+		return
+	}
+
+	account2, err = q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
+		Amount: balance.Amount2,
+		ID:     balance.AccountID2,
+	})
+	if err != nil {
+		return
+	}
+
+	return account1, account2, nil
+}
+
+// PublishOutbox hands the oldest unpublished outbox events to publish, in order, and marks the published ones
+// It stops at the first event publish fails on, so the events are never published out of order, and returns how many were published.
+// The events are locked until the transaction ends, so concurrent pollers skip them instead of publishing them twice.
+// An event published right before a crash stays unpublished and is handed again, receivers must expect duplicates
+func (s *SQLStore) PublishOutbox(ctx context.Context, limit int32, publish func(context.Context, Outbox) error) (int, error) {
+	published := 0
+	var publishErr error
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		events, err := q.ListUnpublishedOutboxEvents(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if publishErr = publish(ctx, event); publishErr != nil {
+				// the events published so far are still committed
+				return nil
+			}
+
+			if err = q.MarkOutboxEventPublished(ctx, event.ID); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return published, publishErr
+}
+
+// AddAccountBalanceTx adds the given amount (positive or negative) to the account balance within a single database transaction
+// The account row is locked before reading it, so concurrent updates are applied one after the other and none of them is lost.
+// With an expected version the update fails with ErrVersionConflict if another update was applied first.
+// The change is recorded by an entry like any other, the statement running balance sums them
+func (s *SQLStore) AddAccountBalanceTx(ctx context.Context, params AddAccountBalanceTxParams) (Account, error) {
+	var account Account
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		account, err = addAccountBalance(ctx, q, params.AccountID, params.Amount, params.ExpectedVersion)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: params.AccountID,
+			Amount:    params.Amount,
+		})
+		return err
+	})
+
+	return account, err
+}
+
+// EntryTx deposits (positive amount) or withdraws (negative amount) external funds, recording the entry along with the new balance
+// It locks the account the same way AddAccountBalanceTx does, so concurrent deposits and withdrawals never overdraw it
+func (s *SQLStore) EntryTx(ctx context.Context, params EntryTxParams) (EntryTxResult, error) {
+	var result EntryTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.Account, err = addAccountBalance(ctx, q, params.AccountID, params.Amount, 0)
+		if err != nil {
+			return err
+		}
+
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID: params.AccountID,
+			Amount:    params.Amount,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// addAccountBalance locks the account row and adds amount to its balance, rejecting the withdrawals its available balance can't cover
+// The version and the freeze are checked once the row is locked, so no update can slip in between the check and the write
+func addAccountBalance(ctx context.Context, q *Queries, accountID, amount, expectedVersion int64) (Account, error) {
+	account, err := q.GetAccountForUpdate(ctx, accountID)
+	if err != nil {
+		return Account{}, err
+	}
+
+	if account.IsFrozen {
+		return Account{}, fmt.Errorf("%w: account [%v] balance can't change", ErrAccountFrozen, account.AccountNumber)
+	}
+
+	if expectedVersion != 0 && account.Version != expectedVersion {
+		return Account{}, fmt.Errorf("%w: account [%v] is at version %v, expected %v", ErrVersionConflict, account.AccountNumber, account.Version, expectedVersion)
+	}
+
+	// the held funds can't be withdrawn, but a deposit is always accepted
+	if amount < 0 && account.availableBalance()+amount < 0 {
+		return Account{}, fmt.Errorf("%w: account [%v] available balance %v can't cover %v", ErrInsufficientBalance, account.AccountNumber, account.availableBalance(), amount)
+	}
+
+	return q.UpdateAccountBalance(ctx, UpdateAccountBalanceParams{
+		Amount: amount,
+		ID:     accountID,
+	})
+}
+
+// ImportUsersTx creates the users within a single database transaction and reports the error of every user it couldn't create
+// Every user is created in its own savepoint, so a duplicated username or email only undoes that user and the next ones are still tried.
+// An atomic import tries every user the same way, so all the failures are reported, and then rolls the whole transaction back if any failed
+func (s *SQLStore) ImportUsersTx(ctx context.Context, params ImportUsersTxParams) (ImportUsersTxResult, error) {
+	result := ImportUsersTxResult{
+		Users: make([]User, len(params.Users)),
+		Errs:  make([]error, len(params.Users)),
+	}
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		failed := false
+		for i, user := range params.Users {
+			if _, err := q.db.ExecContext(ctx, "SAVEPOINT import_user"); err != nil {
+				return err
+			}
+
+			result.Users[i], result.Errs[i] = q.CreateUser(ctx, user)
+			if result.Errs[i] != nil {
+				failed = true
+				if _, err := q.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_user"); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if _, err := q.db.ExecContext(ctx, "RELEASE SAVEPOINT import_user"); err != nil {
+				return err
+			}
+		}
+
+		if params.Atomic && failed {
+			return errImportRolledBack
+		}
+		return nil
+	})
+	if errors.Is(err, errImportRolledBack) {
+		result.RolledBack = true
+		for i := range result.Users {
+			result.Users[i] = User{}
+		}
+		return result, nil
+	}
+	if err != nil {
+		return ImportUsersTxResult{}, err
+	}
+
+	return result, nil
+}
+
+// TransferAccountOwnershipTx hands the account over to another user and records the change within a single database transaction
+// The account row is locked first, so the owner recorded as previous is the one actually replaced. The owner currency check is
+// backed by the owner_currency_key index: an account opened concurrently by the new owner fails the update with ErrOwnerHasCurrency too
+func (s *SQLStore) TransferAccountOwnershipTx(ctx context.Context, params TransferAccountOwnershipTxParams) (TransferAccountOwnershipTxResult, error) {
+	var result TransferAccountOwnershipTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, params.AccountID)
+		if err != nil {
+			return err
+		}
+
+		newOwner, err := q.GetUser(ctx, params.NewOwner)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %v", ErrNewOwnerNotFound, params.NewOwner)
+		}
+		if err != nil {
+			return err
+		}
+		if newOwner.OrgID != account.OrgID {
+			return fmt.Errorf("%w: %v can't own account [%v]", ErrOrganizationMismatch, params.NewOwner, account.AccountNumber)
+		}
+
+		// the current owner counts too, an account can't be transferred to the user already holding it
+		count, err := q.CountAccountsByCurrency(ctx, CountAccountsByCurrencyParams{
+			Owner:    params.NewOwner,
+			Currency: account.Currency,
+		})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: %v already has a %v account", ErrOwnerHasCurrency, params.NewOwner, account.Currency)
+		}
+
+		result.Account, err = q.UpdateAccountOwner(ctx, UpdateAccountOwnerParams{
+			ID:    params.AccountID,
+			Owner: params.NewOwner,
+		})
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return fmt.Errorf("%w: %v already has a %v account", ErrOwnerHasCurrency, params.NewOwner, account.Currency)
+		}
+		if err != nil {
+			return err
+		}
+
+		result.Change, err = q.CreateAccountOwnerChange(ctx, CreateAccountOwnerChangeParams{
+			AccountID:     params.AccountID,
+			PreviousOwner: account.Owner,
+			NewOwner:      params.NewOwner,
+			ChangedBy:     params.Actor.Username,
+		})
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, q, params.Actor, AuditActionTransferOwnership, AuditEntityAccount, strconv.FormatInt(params.AccountID, 10))
+	})
+
+	return result, err
+}
+
+// CloseAccountTx moves the remaining balance of the account to the destination and marks the account as deleted within a single database transaction
+// Both accounts are locked in ascending ID order, like every transfer, so no money can reach the account between the sweep and the close
+func (s *SQLStore) CloseAccountTx(ctx context.Context, params CloseAccountTxParams) (CloseAccountTxResult, error) {
+	var result CloseAccountTxResult
+
+	if params.AccountID == params.DestinationID {
+		return result, ErrCloseIntoItself
+	}
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		account, destination, err := lockAccounts(ctx, q, params.AccountID, params.DestinationID)
+		if err != nil {
+			return err
+		}
+
+		if destination.OrgID != account.OrgID {
+			return fmt.Errorf("%w: account [%v] can't be closed into account [%v]", ErrOrganizationMismatch, account.AccountNumber, destination.AccountNumber)
+		}
+		if destination.Currency != account.Currency {
+			return fmt.Errorf("%w: account [%v] currency %v - destination account currency %v", ErrCurrencyMismatch, account.AccountNumber, account.Currency, destination.Currency)
+		}
+		if account.IsFrozen {
+			return fmt.Errorf("%w: account [%v] can't be closed", ErrAccountFrozen, account.AccountNumber)
+		}
+		// the held funds are promised to a capture, sweeping them would leave the hold uncovered
+		if account.HeldBalance > 0 {
+			return fmt.Errorf("%w: account [%v] holds %v", ErrAccountHasHolds, account.AccountNumber, account.HeldBalance)
+		}
+		if account.Balance < 0 {
+			return fmt.Errorf("%w: account [%v] balance %v is negative", ErrInsufficientBalance, account.AccountNumber, account.Balance)
+		}
+
+		result.Destination = destination
+		if account.Balance > 0 {
+			swept, err := transfer(ctx, q, TransferTxParams{
+				FromAccountID: account.ID,
+				ToAccountID:   destination.ID,
+				Amount:        account.Balance,
+				Description:   fmt.Sprintf("closing balance of account %v", account.AccountNumber),
+				// the balance stays with the owner, the limits of an account being closed don't keep it in
+				SkipAccountLimits: true,
+			}, account.Balance, sql.NullInt64{})
+			if err != nil {
+				return err
+			}
+			result.SweptAmount = account.Balance
+			result.Transfer = &swept.Transfer
+			result.Destination = swept.ToAccountID
+		}
+
+		// the schedules from or to a closed account would only fail from now on
+		if err = q.CancelAccountScheduledTransfers(ctx, account.ID); err != nil {
+			return err
+		}
+		// the account row is locked since the sweep emptied it, so the delete can't miss it
+		_, err = q.SoftDeleteAccount(ctx, account.ID)
+		return err
+	})
+
+	return result, err
+}
+
+// LoginTx creates the session of a login and records the login in the audit log within a single database transaction
+func (s *SQLStore) LoginTx(ctx context.Context, params LoginTxParams) (Session, error) {
+	var session Session
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		session, err = q.CreateSession(ctx, params.CreateSessionParams)
+		if err != nil {
+			return err
+		}
+
+		actor := Actor{Username: params.Username, ClientIP: params.ClientIp}
+		return audit(ctx, q, actor, AuditActionLogin, AuditEntityUser, params.Username)
+	})
+
+	return session, err
+}
+
+// ChangePasswordTx updates the user password and records the change in the audit log within a single database transaction
+func (s *SQLStore) ChangePasswordTx(ctx context.Context, params ChangePasswordTxParams) (User, error) {
+	var user User
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		user, err = q.UpdateUserPassword(ctx, params.UpdateUserPasswordParams)
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, q, params.Actor, AuditActionChangePassword, AuditEntityUser, params.Username)
+	})
+
+	return user, err
+}
+
+// ResetPasswordTx redeems a password reset: the reset is marked used, the user password is replaced and the reset is
+// recorded in the audit log within a single database transaction. A reset is redeemed once and before it expires,
+// ErrResetTokenInvalid, ErrResetTokenUsed and ErrResetTokenExpired are returned otherwise.
+func (s *SQLStore) ResetPasswordTx(ctx context.Context, params ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		// the lock keeps two concurrent redemptions of the same token from both succeeding
+		reset, err := q.GetPasswordResetForUpdate(ctx, params.TokenHash)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrResetTokenInvalid
+			}
+			return err
+		}
+		if reset.UsedAt.Valid {
+			return fmt.Errorf("%w: used at %v", ErrResetTokenUsed, reset.UsedAt.Time)
+		}
+		if !time.Now().Before(reset.ExpiresAt) {
+			return fmt.Errorf("%w: expired at %v", ErrResetTokenExpired, reset.ExpiresAt)
+		}
+
+		if _, err = q.MarkPasswordResetUsed(ctx, reset.ID); err != nil {
+			return err
+		}
+
+		// updating the password revokes the tokens issued before it, like a password change
+		user, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			Username:       reset.Username,
+			HashedPassword: params.HashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+
+		actor := Actor{Username: reset.Username, ClientIP: params.ClientIP}
+		return audit(ctx, q, actor, AuditActionResetPassword, AuditEntityUser, reset.Username)
+	})
+
+	return user, err
+}
+
+// SetAccountFrozenTx freezes or unfreezes the account and records it in the audit log within a single database transaction
+func (s *SQLStore) SetAccountFrozenTx(ctx context.Context, params SetAccountFrozenTxParams) (Account, error) {
+	var account Account
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		account, err = q.SetAccountFrozen(ctx, params.SetAccountFrozenParams)
+		if err != nil {
+			return err
+		}
+
+		action := AuditActionUnfreezeAccount
+		if params.IsFrozen {
+			action = AuditActionFreezeAccount
+		}
+		return audit(ctx, q, params.Actor, action, AuditEntityAccount, strconv.FormatInt(params.ID, 10))
+	})
+
+	return account, err
+}
+
+// availableBalance is the part of the balance not reserved by a hold
+func (a Account) availableBalance() int64 {
+	return a.Balance - a.HeldBalance
+}
+
+// AuthorizeHoldTx reserves amount of the from account for a later transfer to the to account, until the hold expires
+// The funds stay in the balance but are no longer available: withdrawals, split transfers and other holds can't use them.
+// Both account rows are locked in ascending ID order, as in TransferTx
+func (s *SQLStore) AuthorizeHoldTx(ctx context.Context, params AuthorizeHoldTxParams) (AuthorizeHoldTxResult, error) {
+	var result AuthorizeHoldTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		from, to, err := lockAccounts(ctx, q, params.FromAccountID, params.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		for _, account := range []Account{from, to} {
+			if account.IsFrozen {
+				return fmt.Errorf("%w: account [%v] can't send nor receive transfers", ErrAccountFrozen, account.AccountNumber)
+			}
+		}
+		if to.Currency != from.Currency {
+			return fmt.Errorf("%w: account [%v] currency %v - from account currency %v", ErrCurrencyMismatch, to.AccountNumber, to.Currency, from.Currency)
+		}
+		if from.availableBalance() < params.Amount {
+			return fmt.Errorf("%w: account [%v] available balance %v can't cover %v", ErrInsufficientBalance, from.AccountNumber, from.availableBalance(), params.Amount)
+		}
+
+		result.FromAccount, err = q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+			Amount: params.Amount,
+			ID:     params.FromAccountID,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.CreateHold(ctx, CreateHoldParams{
+			FromAccountID: params.FromAccountID,
+			ToAccountID:   params.ToAccountID,
+			Amount:        params.Amount,
+			ExpiresAt:     params.ExpiresAt,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// CaptureHoldTx transfers the captured amount of an authorized hold and releases the whole hold, a hold is captured only once
+// An expired hold isn't captured: it is marked expired and released, that is committed, and ErrHoldExpired is returned along with it.
+// The hold row is locked first, so a capture and a void of the same hold wait for each other, then the accounts as in TransferTx
+func (s *SQLStore) CaptureHoldTx(ctx context.Context, params CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	var result CaptureHoldTxResult
+	expired := false
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		hold, err := lockAuthorizedHold(ctx, q, params.HoldID)
+		if err != nil {
+			return err
+		}
+
+		if !time.Now().Before(hold.ExpiresAt) {
+			expired = true
+			_, result.Hold, err = releaseHold(ctx, q, hold, HoldStatusExpired)
+			return err
+		}
+
+		amount := params.Amount
+		if amount == 0 {
+			amount = hold.Amount
+		}
+		if amount > hold.Amount {
+			return fmt.Errorf("%w: hold [%v] authorized %v, can't capture %v", ErrCaptureExceedsHold, hold.ID, hold.Amount, amount)
+		}
+
+		if _, _, err = lockAccounts(ctx, q, hold.FromAccountID, hold.ToAccountID); err != nil {
+			return err
+		}
+		if _, _, err = releaseHold(ctx, q, hold, HoldStatusCaptured); err != nil {
+			return err
+		}
+
+		result.TransferTxResult, err = transfer(ctx, q, TransferTxParams{
+			FromAccountID: hold.FromAccountID,
+			ToAccountID:   hold.ToAccountID,
+			Amount:        amount,
+			DailyLimit:    params.DailyLimit,
+		}, amount, sql.NullInt64{})
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = q.UpdateHoldStatus(ctx, UpdateHoldStatusParams{
+			Status:     HoldStatusCaptured,
+			TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+			ID:         hold.ID,
+		})
+		return err
+	})
+	if err == nil && expired {
+		err = fmt.Errorf("%w: hold [%v] expired at %v", ErrHoldExpired, result.Hold.ID, result.Hold.ExpiresAt)
+	}
+
+	return result, err
+}
+
+// VoidHoldTx cancels an authorized hold, its funds are available again. A hold past its expiration can still be voided
+func (s *SQLStore) VoidHoldTx(ctx context.Context, params VoidHoldTxParams) (VoidHoldTxResult, error) {
+	var result VoidHoldTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		hold, err := lockAuthorizedHold(ctx, q, params.HoldID)
+		if err != nil {
+			return err
+		}
+
+		result.FromAccount, result.Hold, err = releaseHold(ctx, q, hold, HoldStatusVoided)
+		return err
+	})
+
+	return result, err
+}
+
+// ExpireHoldsTx releases the authorized holds expired at now, up to limit of them, and returns them
+// The holds are listed by from account, so their accounts are locked in ascending ID order. The holds being captured or voided are skipped
+func (s *SQLStore) ExpireHoldsTx(ctx context.Context, params ExpireHoldsTxParams) ([]Hold, error) {
+	var holds []Hold
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		expired, err := q.ListExpiredHolds(ctx, ListExpiredHoldsParams{
+			Now:   params.Now,
+			Limit: params.Limit,
+		})
+		if err != nil {
+			return err
+		}
+
+		holds = make([]Hold, 0, len(expired))
+		for _, hold := range expired {
+			_, hold, err = releaseHold(ctx, q, hold, HoldStatusExpired)
+			if err != nil {
+				return err
+			}
+			holds = append(holds, hold)
+		}
+		return nil
+	})
+
+	return holds, err
+}
+
+// lockAuthorizedHold locks the hold row, failing if it was already captured, voided or expired
+func lockAuthorizedHold(ctx context.Context, q *Queries, holdID int64) (Hold, error) {
+	hold, err := q.GetHoldForUpdate(ctx, holdID)
+	if err != nil {
+		return hold, err
+	}
+
+	if hold.Status != HoldStatusAuthorized {
+		return hold, fmt.Errorf("%w: hold [%v] is %v", ErrHoldNotAuthorized, hold.ID, hold.Status)
+	}
+	return hold, nil
+}
+
+// releaseHold gives the funds of the hold back to the available balance of its from account and sets its final status
+// It returns the updated from account and hold
+func releaseHold(ctx context.Context, q *Queries, hold Hold, status string) (Account, Hold, error) {
+	account, err := q.AddAccountHeldBalance(ctx, AddAccountHeldBalanceParams{
+		Amount: -hold.Amount,
+		ID:     hold.FromAccountID,
+	})
+	if err != nil {
+		return account, hold, err
+	}
+
+	hold, err = q.UpdateHoldStatus(ctx, UpdateHoldStatusParams{
+		Status:     status,
+		TransferID: hold.TransferID,
+		ID:         hold.ID,
+	})
+	return account, hold, err
+}
+
+// audit records an action of the actor on an entity, within the transaction of q so the entry is kept only if the action is
+func audit(ctx context.Context, q *Queries, actor Actor, action, entityType, entityID string) error {
+	_, err := q.CreateAuditLog(ctx, CreateAuditLogParams{
+		Username:   actor.Username,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		ClientIp:   actor.ClientIP,
+	})
+	return err
 }
@@ -0,0 +1,432 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// AccountCache holds the accounts read by id or by number, the caching store reads through it
+// Implementations must be safe for concurrent use and expire the accounts themselves, an in-memory LRU is provided
+type AccountCache interface {
+	// Get returns the cached account, ok is false when it isn't cached or has expired
+	Get(ctx context.Context, id int64) (account Account, ok bool)
+	// GetByNumber is Get for the account with the number, deleting the account by id drops it from both
+	GetByNumber(ctx context.Context, accountNumber string) (account Account, ok bool)
+	Set(ctx context.Context, account Account)
+	Delete(ctx context.Context, id int64)
+}
+
+// cachingStore wraps a Store serving the account reads by id and by number from the cache, every other call goes to store
+// The calls changing an account drop it from the cache once they return, whether they failed or not.
+// Only the changes made through this store are seen: another instance updating the account leaves it stale up to the cache ttl
+type cachingStore struct {
+	Store
+	cache AccountCache
+}
+
+// NewCachingStore returns a Store caching the accounts read from store in cache
+func NewCachingStore(store Store, cache AccountCache) Store {
+	return &cachingStore{
+		Store: store,
+		cache: cache,
+	}
+}
+
+func (s *cachingStore) GetAccount(ctx context.Context, id int64) (Account, error) {
+	if account, ok := s.cache.Get(ctx, id); ok {
+		return account, nil
+	}
+
+	account, err := s.Store.GetAccount(ctx, id)
+	if err != nil {
+		return account, err
+	}
+	s.cache.Set(ctx, account)
+	return account, nil
+}
+
+// LookupAccounts serves the cached accounts and reads the missing ones in a single call
+func (s *cachingStore) LookupAccounts(ctx context.Context, ids []int64) (map[int64]Account, error) {
+	accounts := make(map[int64]Account, len(ids))
+	var missing []int64
+	for _, id := range ids {
+		if account, ok := s.cache.Get(ctx, id); ok {
+			accounts[id] = account
+			continue
+		}
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return accounts, nil
+	}
+
+	found, err := s.Store.LookupAccounts(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for id, account := range found {
+		s.cache.Set(ctx, account)
+		accounts[id] = account
+	}
+	return accounts, nil
+}
+
+func (s *cachingStore) GetAccountByNumber(ctx context.Context, accountNumber string) (Account, error) {
+	if account, ok := s.cache.GetByNumber(ctx, accountNumber); ok {
+		return account, nil
+	}
+
+	account, err := s.Store.GetAccountByNumber(ctx, accountNumber)
+	if err != nil {
+		return account, err
+	}
+	s.cache.Set(ctx, account)
+	return account, nil
+}
+
+// LookupAccountsByNumber serves the cached accounts and reads the missing ones in a single call
+func (s *cachingStore) LookupAccountsByNumber(ctx context.Context, accountNumbers []string) (map[string]Account, error) {
+	accounts := make(map[string]Account, len(accountNumbers))
+	var missing []string
+	for _, number := range accountNumbers {
+		if account, ok := s.cache.GetByNumber(ctx, number); ok {
+			accounts[number] = account
+			continue
+		}
+		missing = append(missing, number)
+	}
+	if len(missing) == 0 {
+		return accounts, nil
+	}
+
+	found, err := s.Store.LookupAccountsByNumber(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for number, account := range found {
+		s.cache.Set(ctx, account)
+		accounts[number] = account
+	}
+	return accounts, nil
+}
+
+func (s *cachingStore) invalidate(ctx context.Context, ids ...int64) {
+	for _, id := range ids {
+		s.cache.Delete(ctx, id)
+	}
+}
+
+func (s *cachingStore) AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error) {
+	defer s.invalidate(ctx, arg.ID)
+	return s.Store.AddAccountHeldBalance(ctx, arg)
+}
+
+func (s *cachingStore) DeleteAccount(ctx context.Context, id int64) error {
+	defer s.invalidate(ctx, id)
+	return s.Store.DeleteAccount(ctx, id)
+}
+
+// RestoreAccount only knows the account id from the account it returns, nothing changed when it failed
+func (s *cachingStore) RestoreAccount(ctx context.Context, arg RestoreAccountParams) (Account, error) {
+	account, err := s.Store.RestoreAccount(ctx, arg)
+	if err == nil {
+		s.invalidate(ctx, account.ID)
+	}
+	return account, err
+}
+
+func (s *cachingStore) SetAccountFrozen(ctx context.Context, arg SetAccountFrozenParams) (Account, error) {
+	defer s.invalidate(ctx, arg.ID)
+	return s.Store.SetAccountFrozen(ctx, arg)
+}
+
+func (s *cachingStore) SetAccountLabels(ctx context.Context, arg SetAccountLabelsParams) (Account, error) {
+	defer s.invalidate(ctx, arg.ID)
+	return s.Store.SetAccountLabels(ctx, arg)
+}
+
+func (s *cachingStore) SetAccountLowBalanceThreshold(ctx context.Context, arg SetAccountLowBalanceThresholdParams) (Account, error) {
+	defer s.invalidate(ctx, arg.ID)
+	return s.Store.SetAccountLowBalanceThreshold(ctx, arg)
+}
+
+func (s *cachingStore) SetAccountSpendingLimits(ctx context.Context, arg SetAccountSpendingLimitsParams) (Account, error) {
+	defer s.invalidate(ctx, arg.ID)
+	return s.Store.SetAccountSpendingLimits(ctx, arg)
+}
+
+func (s *cachingStore) SoftDeleteAccount(ctx context.Context, id int64) (int64, error) {
+	defer s.invalidate(ctx, id)
+	return s.Store.SoftDeleteAccount(ctx, id)
+}
+
+func (s *cachingStore) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	defer s.invalidate(ctx, arg.ID)
+	return s.Store.UpdateAccount(ctx, arg)
+}
+
+func (s *cachingStore) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error) {
+	defer s.invalidate(ctx, arg.ID)
+	return s.Store.UpdateAccountBalance(ctx, arg)
+}
+
+func (s *cachingStore) UpdateAccountOwner(ctx context.Context, arg UpdateAccountOwnerParams) (Account, error) {
+	defer s.invalidate(ctx, arg.ID)
+	return s.Store.UpdateAccountOwner(ctx, arg)
+}
+
+// ExecTx drops every account fn changed once the transaction is over, the queries of fn never read from the cache
+func (s *cachingStore) ExecTx(ctx context.Context, fn func(Querier) error) error {
+	q := &txAccountRecorder{}
+	defer func() {
+		s.invalidate(ctx, q.ids...)
+	}()
+
+	return s.Store.ExecTx(ctx, func(querier Querier) error {
+		q.Querier = querier
+		return fn(q)
+	})
+}
+
+func (s *cachingStore) TransferTx(ctx context.Context, params TransferTxParams) (TransferTxResult, error) {
+	defer s.invalidate(ctx, params.FromAccountID, params.ToAccountID)
+	return s.Store.TransferTx(ctx, params)
+}
+
+func (s *cachingStore) IdempotentTransferTx(ctx context.Context, params IdempotentTransferTxParams) (IdempotentTransferTxResult, error) {
+	defer s.invalidate(ctx, params.FromAccountID, params.ToAccountID)
+	return s.Store.IdempotentTransferTx(ctx, params)
+}
+
+// ReverseTransferTx only knows the accounts from its result, a failed reversal rolled back and changed none of them
+func (s *cachingStore) ReverseTransferTx(ctx context.Context, params ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	result, err := s.Store.ReverseTransferTx(ctx, params)
+	if err == nil {
+		s.invalidate(ctx, result.FromAccountID.ID, result.ToAccountID.ID)
+	}
+	return result, err
+}
+
+func (s *cachingStore) SplitTransferTx(ctx context.Context, params SplitTransferTxParams) (SplitTransferTxResult, error) {
+	ids := []int64{params.FromAccountID}
+	for _, split := range params.Splits {
+		ids = append(ids, split.ToAccountID)
+	}
+	defer s.invalidate(ctx, ids...)
+	return s.Store.SplitTransferTx(ctx, params)
+}
+
+func (s *cachingStore) AddAccountBalanceTx(ctx context.Context, params AddAccountBalanceTxParams) (Account, error) {
+	defer s.invalidate(ctx, params.AccountID)
+	return s.Store.AddAccountBalanceTx(ctx, params)
+}
+
+func (s *cachingStore) EntryTx(ctx context.Context, params EntryTxParams) (EntryTxResult, error) {
+	defer s.invalidate(ctx, params.AccountID)
+	return s.Store.EntryTx(ctx, params)
+}
+
+func (s *cachingStore) TransferAccountOwnershipTx(ctx context.Context, params TransferAccountOwnershipTxParams) (TransferAccountOwnershipTxResult, error) {
+	defer s.invalidate(ctx, params.AccountID)
+	return s.Store.TransferAccountOwnershipTx(ctx, params)
+}
+
+func (s *cachingStore) CloseAccountTx(ctx context.Context, params CloseAccountTxParams) (CloseAccountTxResult, error) {
+	defer s.invalidate(ctx, params.AccountID, params.DestinationID)
+	return s.Store.CloseAccountTx(ctx, params)
+}
+
+func (s *cachingStore) SetAccountFrozenTx(ctx context.Context, params SetAccountFrozenTxParams) (Account, error) {
+	defer s.invalidate(ctx, params.ID)
+	return s.Store.SetAccountFrozenTx(ctx, params)
+}
+
+func (s *cachingStore) AuthorizeHoldTx(ctx context.Context, params AuthorizeHoldTxParams) (AuthorizeHoldTxResult, error) {
+	defer s.invalidate(ctx, params.FromAccountID)
+	return s.Store.AuthorizeHoldTx(ctx, params)
+}
+
+// CaptureHoldTx only knows the accounts from the hold it returns, an expired hold was released though the capture failed
+func (s *cachingStore) CaptureHoldTx(ctx context.Context, params CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	result, err := s.Store.CaptureHoldTx(ctx, params)
+	if err == nil || errors.Is(err, ErrHoldExpired) {
+		s.invalidate(ctx, result.Hold.FromAccountID, result.Hold.ToAccountID)
+	}
+	return result, err
+}
+
+func (s *cachingStore) VoidHoldTx(ctx context.Context, params VoidHoldTxParams) (VoidHoldTxResult, error) {
+	result, err := s.Store.VoidHoldTx(ctx, params)
+	if err == nil {
+		s.invalidate(ctx, result.Hold.FromAccountID)
+	}
+	return result, err
+}
+
+func (s *cachingStore) ExpireHoldsTx(ctx context.Context, params ExpireHoldsTxParams) ([]Hold, error) {
+	holds, err := s.Store.ExpireHoldsTx(ctx, params)
+	for _, hold := range holds {
+		s.invalidate(ctx, hold.FromAccountID)
+	}
+	return holds, err
+}
+
+// txAccountRecorder records the accounts changed through the queries of a transaction, the caching store can't tell them
+// from its parameters
+type txAccountRecorder struct {
+	Querier
+	ids []int64
+}
+
+func (q *txAccountRecorder) AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error) {
+	q.ids = append(q.ids, arg.ID)
+	return q.Querier.AddAccountHeldBalance(ctx, arg)
+}
+
+func (q *txAccountRecorder) DeleteAccount(ctx context.Context, id int64) error {
+	q.ids = append(q.ids, id)
+	return q.Querier.DeleteAccount(ctx, id)
+}
+
+func (q *txAccountRecorder) RestoreAccount(ctx context.Context, arg RestoreAccountParams) (Account, error) {
+	account, err := q.Querier.RestoreAccount(ctx, arg)
+	if err == nil {
+		q.ids = append(q.ids, account.ID)
+	}
+	return account, err
+}
+
+func (q *txAccountRecorder) SetAccountFrozen(ctx context.Context, arg SetAccountFrozenParams) (Account, error) {
+	q.ids = append(q.ids, arg.ID)
+	return q.Querier.SetAccountFrozen(ctx, arg)
+}
+
+func (q *txAccountRecorder) SetAccountLabels(ctx context.Context, arg SetAccountLabelsParams) (Account, error) {
+	q.ids = append(q.ids, arg.ID)
+	return q.Querier.SetAccountLabels(ctx, arg)
+}
+
+func (q *txAccountRecorder) SetAccountLowBalanceThreshold(ctx context.Context, arg SetAccountLowBalanceThresholdParams) (Account, error) {
+	q.ids = append(q.ids, arg.ID)
+	return q.Querier.SetAccountLowBalanceThreshold(ctx, arg)
+}
+
+func (q *txAccountRecorder) SetAccountSpendingLimits(ctx context.Context, arg SetAccountSpendingLimitsParams) (Account, error) {
+	q.ids = append(q.ids, arg.ID)
+	return q.Querier.SetAccountSpendingLimits(ctx, arg)
+}
+
+func (q *txAccountRecorder) SoftDeleteAccount(ctx context.Context, id int64) (int64, error) {
+	q.ids = append(q.ids, id)
+	return q.Querier.SoftDeleteAccount(ctx, id)
+}
+
+func (q *txAccountRecorder) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	q.ids = append(q.ids, arg.ID)
+	return q.Querier.UpdateAccount(ctx, arg)
+}
+
+func (q *txAccountRecorder) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error) {
+	q.ids = append(q.ids, arg.ID)
+	return q.Querier.UpdateAccountBalance(ctx, arg)
+}
+
+func (q *txAccountRecorder) UpdateAccountOwner(ctx context.Context, arg UpdateAccountOwnerParams) (Account, error) {
+	q.ids = append(q.ids, arg.ID)
+	return q.Querier.UpdateAccountOwner(ctx, arg)
+}
+
+// lruAccountCache keeps the most recently used accounts in memory, for up to ttl after they were cached
+type lruAccountCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List // front is the most recently used, its values are *lruEntry
+	entries map[int64]*list.Element
+	numbers map[string]*list.Element // the same elements as entries, by account number
+}
+
+type lruEntry struct {
+	account   Account
+	expiresAt time.Time
+}
+
+// NewLRUAccountCache returns an in-memory AccountCache holding up to size accounts, the least recently used is evicted first
+func NewLRUAccountCache(size int, ttl time.Duration) AccountCache {
+	return &lruAccountCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[int64]*list.Element),
+		numbers: make(map[string]*list.Element),
+	}
+}
+
+func (c *lruAccountCache) Get(_ context.Context, id int64) (Account, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.get(c.entries[id])
+}
+
+func (c *lruAccountCache) GetByNumber(_ context.Context, accountNumber string) (Account, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.get(c.numbers[accountNumber])
+}
+
+func (c *lruAccountCache) get(elem *list.Element) (Account, bool) {
+	if elem == nil {
+		return Account{}, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return Account{}, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.account, true
+}
+
+func (c *lruAccountCache) Set(_ context.Context, account Account) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{account: account, expiresAt: time.Now().Add(c.ttl)}
+	if elem, ok := c.entries[account.ID]; ok {
+		delete(c.numbers, elem.Value.(*lruEntry).account.AccountNumber)
+		elem.Value = entry
+		c.numbers[account.AccountNumber] = elem
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	elem := c.order.PushFront(entry)
+	c.entries[account.ID] = elem
+	c.numbers[account.AccountNumber] = elem
+	if c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *lruAccountCache) Delete(_ context.Context, id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[id]; ok {
+		c.remove(elem)
+	}
+}
+
+func (c *lruAccountCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	account := elem.Value.(*lruEntry).account
+	delete(c.entries, account.ID)
+	delete(c.numbers, account.AccountNumber)
+}
@@ -0,0 +1,398 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func randomCachedAccount() db.Account {
+	return db.Account{
+		ID:            utils.RandomInt(1, 1000),
+		AccountNumber: utils.RandomAccountNumber(),
+		Owner:         utils.RandomOwner(),
+		Balance:       utils.RandomBalance(),
+		Currency:      utils.RandomCurrency(),
+		Version:       1,
+	}
+}
+
+func TestCachingStoreGetAccount(t *testing.T) {
+	account := randomCachedAccount()
+	updated := account
+	updated.Balance += 10
+	updated.Version++
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		run        func(t *testing.T, store db.Store)
+	}{
+		{
+			name: "cache hit",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			run: func(t *testing.T, store db.Store) {
+				for i := 0; i < 3; i++ {
+					got, err := store.GetAccount(context.Background(), account.ID)
+					require.NoError(t, err)
+					require.Equal(t, account, got)
+				}
+			},
+		},
+		{
+			name: "balance update invalidates",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil),
+					store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).Times(1).Return(updated, nil),
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(updated, nil),
+				)
+			},
+			run: func(t *testing.T, store db.Store) {
+				_, err := store.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+
+				_, err = store.AddAccountBalanceTx(context.Background(), db.AddAccountBalanceTxParams{AccountID: account.ID, Amount: 10})
+				require.NoError(t, err)
+
+				got, err := store.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+				require.Equal(t, updated, got)
+			},
+		},
+		{
+			name: "transfer invalidates both accounts",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil),
+					store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, nil),
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(updated, nil),
+				)
+			},
+			run: func(t *testing.T, store db.Store) {
+				_, err := store.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+
+				_, err = store.TransferTx(context.Background(), db.TransferTxParams{FromAccountID: account.ID + 1, ToAccountID: account.ID, Amount: 10})
+				require.NoError(t, err)
+
+				got, err := store.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+				require.Equal(t, updated, got)
+			},
+		},
+		{
+			name: "transaction invalidates the accounts it changed",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil),
+					store.EXPECT().ExecTx(gomock.Any(), gomock.Any()).Times(1).
+						DoAndReturn(func(ctx context.Context, fn func(db.Querier) error) error {
+							return fn(store)
+						}),
+					// the read within the transaction skips the cache
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil),
+					store.EXPECT().UpdateAccountBalance(gomock.Any(), gomock.Any()).Times(1).Return(updated, nil),
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(updated, nil),
+				)
+			},
+			run: func(t *testing.T, store db.Store) {
+				_, err := store.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+
+				err = store.ExecTx(context.Background(), func(q db.Querier) error {
+					if _, err := q.GetAccount(context.Background(), account.ID); err != nil {
+						return err
+					}
+					_, err := q.UpdateAccountBalance(context.Background(), db.UpdateAccountBalanceParams{ID: account.ID, Amount: 10})
+					return err
+				})
+				require.NoError(t, err)
+
+				got, err := store.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+				require.Equal(t, updated, got)
+			},
+		},
+		{
+			name: "failed update still invalidates",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil),
+					store.EXPECT().UpdateAccountBalance(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrConnDone),
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil),
+				)
+			},
+			run: func(t *testing.T, store db.Store) {
+				_, err := store.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+
+				_, err = store.UpdateAccountBalance(context.Background(), db.UpdateAccountBalanceParams{ID: account.ID, Amount: 10})
+				require.ErrorIs(t, err, sql.ErrConnDone)
+
+				_, err = store.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "errors aren't cached",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(db.Account{}, sql.ErrNoRows)
+			},
+			run: func(t *testing.T, store db.Store) {
+				for i := 0; i < 2; i++ {
+					_, err := store.GetAccount(context.Background(), account.ID)
+					require.ErrorIs(t, err, sql.ErrNoRows)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStore := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(mockStore)
+
+			tc.run(t, db.NewCachingStore(mockStore, db.NewLRUAccountCache(10, time.Minute)))
+		})
+	}
+}
+
+func TestCachingStoreLookupAccounts(t *testing.T) {
+	cached := randomCachedAccount()
+	missing := randomCachedAccount()
+	missing.ID = cached.ID + 1
+	unknownID := cached.ID + 2
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mockdb.NewMockStore(ctrl)
+	mockStore.EXPECT().GetAccount(gomock.Any(), gomock.Eq(cached.ID)).Times(1).Return(cached, nil)
+	// only the accounts that aren't cached are read
+	mockStore.EXPECT().LookupAccounts(gomock.Any(), gomock.Eq([]int64{missing.ID, unknownID})).Times(1).
+		Return(map[int64]db.Account{missing.ID: missing}, nil)
+
+	store := db.NewCachingStore(mockStore, db.NewLRUAccountCache(10, time.Minute))
+	_, err := store.GetAccount(context.Background(), cached.ID)
+	require.NoError(t, err)
+
+	accounts, err := store.LookupAccounts(context.Background(), []int64{cached.ID, missing.ID, unknownID})
+	require.NoError(t, err)
+	require.Equal(t, map[int64]db.Account{cached.ID: cached, missing.ID: missing}, accounts)
+
+	// the accounts read are cached as well
+	got, err := store.GetAccount(context.Background(), missing.ID)
+	require.NoError(t, err)
+	require.Equal(t, missing, got)
+}
+
+func TestCachingStoreGetAccountByNumber(t *testing.T) {
+	account := randomCachedAccount()
+	updated := account
+	updated.Balance += 10
+	updated.Version++
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		run        func(t *testing.T, store db.Store)
+	}{
+		{
+			name: "cache hit",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil)
+			},
+			run: func(t *testing.T, store db.Store) {
+				for i := 0; i < 3; i++ {
+					got, err := store.GetAccountByNumber(context.Background(), account.AccountNumber)
+					require.NoError(t, err)
+					require.Equal(t, account, got)
+				}
+				// the account read by number is served by id as well
+				got, err := store.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+				require.Equal(t, account, got)
+			},
+		},
+		{
+			name: "balance update invalidates",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil),
+					store.EXPECT().AddAccountBalanceTx(gomock.Any(), gomock.Any()).Times(1).Return(updated, nil),
+					store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(updated, nil),
+				)
+			},
+			run: func(t *testing.T, store db.Store) {
+				_, err := store.GetAccountByNumber(context.Background(), account.AccountNumber)
+				require.NoError(t, err)
+
+				_, err = store.AddAccountBalanceTx(context.Background(), db.AddAccountBalanceTxParams{AccountID: account.ID, Amount: 10})
+				require.NoError(t, err)
+
+				got, err := store.GetAccountByNumber(context.Background(), account.AccountNumber)
+				require.NoError(t, err)
+				require.Equal(t, updated, got)
+			},
+		},
+		{
+			name: "freeze invalidates",
+			buildStubs: func(store *mockdb.MockStore) {
+				frozen := account
+				frozen.IsFrozen = true
+				gomock.InOrder(
+					store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(account, nil),
+					store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Any()).Times(1).Return(frozen, nil),
+					store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(1).Return(frozen, nil),
+				)
+			},
+			run: func(t *testing.T, store db.Store) {
+				_, err := store.GetAccountByNumber(context.Background(), account.AccountNumber)
+				require.NoError(t, err)
+
+				_, err = store.SetAccountFrozenTx(context.Background(), db.SetAccountFrozenTxParams{
+					SetAccountFrozenParams: db.SetAccountFrozenParams{ID: account.ID, IsFrozen: true},
+				})
+				require.NoError(t, err)
+
+				got, err := store.GetAccountByNumber(context.Background(), account.AccountNumber)
+				require.NoError(t, err)
+				require.True(t, got.IsFrozen)
+			},
+		},
+		{
+			name: "errors aren't cached",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccountByNumber(gomock.Any(), gomock.Eq(account.AccountNumber)).Times(2).Return(db.Account{}, sql.ErrNoRows)
+			},
+			run: func(t *testing.T, store db.Store) {
+				for i := 0; i < 2; i++ {
+					_, err := store.GetAccountByNumber(context.Background(), account.AccountNumber)
+					require.ErrorIs(t, err, sql.ErrNoRows)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockStore := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(mockStore)
+
+			tc.run(t, db.NewCachingStore(mockStore, db.NewLRUAccountCache(10, time.Minute)))
+		})
+	}
+}
+
+func TestCachingStoreLookupAccountsByNumber(t *testing.T) {
+	cached := randomCachedAccount()
+	missing := randomCachedAccount()
+	missing.ID = cached.ID + 1
+	unknownNumber := utils.RandomAccountNumber()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mockdb.NewMockStore(ctrl)
+	mockStore.EXPECT().GetAccount(gomock.Any(), gomock.Eq(cached.ID)).Times(1).Return(cached, nil)
+	// only the accounts that aren't cached are read
+	mockStore.EXPECT().LookupAccountsByNumber(gomock.Any(), gomock.Eq([]string{missing.AccountNumber, unknownNumber})).Times(1).
+		Return(map[string]db.Account{missing.AccountNumber: missing}, nil)
+
+	store := db.NewCachingStore(mockStore, db.NewLRUAccountCache(10, time.Minute))
+	_, err := store.GetAccount(context.Background(), cached.ID)
+	require.NoError(t, err)
+
+	accounts, err := store.LookupAccountsByNumber(context.Background(), []string{cached.AccountNumber, missing.AccountNumber, unknownNumber})
+	require.NoError(t, err)
+	require.Equal(t, map[string]db.Account{cached.AccountNumber: cached, missing.AccountNumber: missing}, accounts)
+
+	// the accounts read are cached by id as well
+	got, err := store.GetAccount(context.Background(), missing.ID)
+	require.NoError(t, err)
+	require.Equal(t, missing, got)
+}
+
+func TestLRUAccountCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("evicts the least recently used", func(t *testing.T) {
+		cache := db.NewLRUAccountCache(2, time.Minute)
+		account1, account2, account3 := db.Account{ID: 1}, db.Account{ID: 2}, db.Account{ID: 3}
+
+		cache.Set(ctx, account1)
+		cache.Set(ctx, account2)
+		// reading the first account makes the second one the least recently used
+		_, ok := cache.Get(ctx, account1.ID)
+		require.True(t, ok)
+		cache.Set(ctx, account3)
+
+		_, ok = cache.Get(ctx, account2.ID)
+		require.False(t, ok)
+		_, ok = cache.Get(ctx, account1.ID)
+		require.True(t, ok)
+		_, ok = cache.Get(ctx, account3.ID)
+		require.True(t, ok)
+	})
+
+	t.Run("expires after the ttl", func(t *testing.T) {
+		ttl := 20 * time.Millisecond
+		cache := db.NewLRUAccountCache(2, ttl)
+		account := randomCachedAccount()
+
+		cache.Set(ctx, account)
+		got, ok := cache.Get(ctx, account.ID)
+		require.True(t, ok)
+		require.Equal(t, account, got)
+
+		time.Sleep(2 * ttl)
+		_, ok = cache.Get(ctx, account.ID)
+		require.False(t, ok)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		cache := db.NewLRUAccountCache(2, time.Minute)
+		account := randomCachedAccount()
+
+		cache.Set(ctx, account)
+		cache.Delete(ctx, account.ID)
+		_, ok := cache.Get(ctx, account.ID)
+		require.False(t, ok)
+	})
+
+	t.Run("by number", func(t *testing.T) {
+		cache := db.NewLRUAccountCache(1, time.Minute)
+		account1, account2 := randomCachedAccount(), randomCachedAccount()
+		account2.ID = account1.ID + 1
+
+		cache.Set(ctx, account1)
+		got, ok := cache.GetByNumber(ctx, account1.AccountNumber)
+		require.True(t, ok)
+		require.Equal(t, account1, got)
+
+		// the evicted account is gone by number too
+		cache.Set(ctx, account2)
+		_, ok = cache.GetByNumber(ctx, account1.AccountNumber)
+		require.False(t, ok)
+
+		cache.Delete(ctx, account2.ID)
+		_, ok = cache.GetByNumber(ctx, account2.AccountNumber)
+		require.False(t, ok)
+	})
+}
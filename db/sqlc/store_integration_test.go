@@ -0,0 +1,117 @@
+//go:build integration
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestIntegrationAccountColumns writes every account column through the queries and reads it back, a column missing
+// from a RETURNING list or scanned in the wrong order fails here while the mocks keep passing
+func TestIntegrationAccountColumns(t *testing.T) {
+	store := NewStore(testDB)
+	account := createAccountInCurrency(t, utils.EUR, 1000)
+
+	limited, err := store.SetAccountSpendingLimits(context.Background(), SetAccountSpendingLimitsParams{
+		ID:           account.ID,
+		DailyLimit:   100,
+		MonthlyLimit: 1000,
+	})
+	require.NoError(t, err)
+	thresholded, err := store.SetAccountLowBalanceThreshold(context.Background(), SetAccountLowBalanceThresholdParams{
+		ID:                  account.ID,
+		LowBalanceThreshold: 50,
+	})
+	require.NoError(t, err)
+	require.Equal(t, limited.Version+1, thresholded.Version)
+
+	byID, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	byNumber, err := store.GetAccountByNumber(context.Background(), account.AccountNumber)
+	require.NoError(t, err)
+	forUpdate, err := store.GetAccountForUpdate(context.Background(), account.ID)
+	require.NoError(t, err)
+
+	for _, got := range []Account{byID, byNumber, forUpdate} {
+		require.Equal(t, account.Owner, got.Owner)
+		require.Equal(t, account.Currency, got.Currency)
+		require.Equal(t, account.AccountNumber, got.AccountNumber)
+		require.Equal(t, DefaultOrgID, got.OrgID)
+		require.Equal(t, int64(1000), got.Balance)
+		require.Equal(t, int64(100), got.DailyLimit)
+		require.Equal(t, int64(1000), got.MonthlyLimit)
+		require.Equal(t, int64(50), got.LowBalanceThreshold)
+		require.Equal(t, thresholded.Version, got.Version)
+		require.WithinDuration(t, thresholded.UpdatedAt.Time, got.UpdatedAt.Time, time.Second)
+	}
+
+	deleteEmptiedAccount(t, account.ID)
+	_, err = store.GetAccount(context.Background(), account.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	// a deleted account can't be changed either
+	_, err = store.SetAccountSpendingLimits(context.Background(), SetAccountSpendingLimitsParams{ID: account.ID})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// TestIntegrationTransferTxConcurrent moves money back and forth between accounts from many goroutines through the
+// store stack the server runs, the locking order has to keep it free of deadlocks and of lost updates
+func TestIntegrationTransferTxConcurrent(t *testing.T) {
+	store := NewRetryStore(NewStore(testDB), RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: 100 * time.Millisecond})
+
+	accounts := []Account{
+		createAccountInCurrency(t, utils.USD, 1000),
+		createAccountInCurrency(t, utils.USD, 1000),
+		createAccountInCurrency(t, utils.USD, 1000),
+	}
+	amount := int64(10)
+	rounds := 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, rounds*len(accounts)*2)
+	for i := 0; i < rounds; i++ {
+		for j := range accounts {
+			// every account sends to the next one and to the previous one, so each pair is locked from both sides
+			next, previous := accounts[(j+1)%len(accounts)], accounts[(j+len(accounts)-1)%len(accounts)]
+			for _, to := range []Account{next, previous} {
+				wg.Add(1)
+				go func(from, to Account) {
+					defer wg.Done()
+					_, err := store.TransferTx(context.Background(), TransferTxParams{
+						FromAccountID: from.ID,
+						ToAccountID:   to.ID,
+						Amount:        amount,
+					})
+					errs <- err
+				}(accounts[j], to)
+			}
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// every account sent as much as it received in its 4 transfers a round, and its entries add up to the balance change
+	for _, account := range accounts {
+		updated, err := store.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updated.Balance)
+
+		entries, err := store.ListEntriesByAccount(context.Background(), ListEntriesByAccountParams{AccountID: account.ID, Limit: int32(rounds*4) + 1})
+		require.NoError(t, err)
+		require.Len(t, entries, rounds*4)
+		var total int64
+		for _, entry := range entries {
+			total += entry.Amount
+		}
+		require.Zero(t, total)
+	}
+}
@@ -0,0 +1,857 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/lib/pq"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RetryPolicy sets how many times and how fast a store call failing with a transient error is attempted again
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first one, 1 or less disables retries
+	BaseDelay   time.Duration // wait before the first retry, doubled on every next one
+	MaxDelay    time.Duration // upper bound of the wait between attempts
+}
+
+// retryStore wraps a Store attempting again the calls that fail with a serialization failure or a deadlock
+// Postgres rolls back the whole transaction on those errors, so repeating the call is safe and usually succeeds once the contention is gone
+type retryStore struct {
+	store  Store
+	policy RetryPolicy
+}
+
+// NewRetryStore returns a Store that retries the calls to store failing with a transient error, following the given policy
+func NewRetryStore(store Store, policy RetryPolicy) Store {
+	return &retryStore{
+		store:  store,
+		policy: policy,
+	}
+}
+
+// isTransientError reports whether err is a Postgres error that goes away when the transaction is run again
+func isTransientError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	switch pqErr.Code.Name() {
+	case "serialization_failure", "deadlock_detected":
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait before the given retry, exponential from the base delay and capped to the max one
+// Half of it is random, so the transactions that collided don't all retry at the same time and collide again
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retry calls fn until it succeeds, fails with a non transient error or runs out of attempts
+// It gives up early, returning the last error, if ctx is done while waiting
+func retry[T any](ctx context.Context, policy RetryPolicy, fn func() (T, error)) (T, error) {
+	result, err := fn()
+	for attempt := 1; attempt < policy.MaxAttempts && isTransientError(err); attempt++ {
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+
+		result, err = fn()
+	}
+
+	return result, err
+}
+
+func (s *retryStore) retryExec(ctx context.Context, fn func() error) error {
+	_, err := retry(ctx, s.policy, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	return err
+}
+
+func (s *retryStore) AccountNumberExists(ctx context.Context, accountNumber string) (bool, error) {
+	return retry(ctx, s.policy, func() (bool, error) {
+		return s.store.AccountNumberExists(ctx, accountNumber)
+	})
+}
+
+func (s *retryStore) AddAccountBalanceTx(ctx context.Context, params AddAccountBalanceTxParams) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.AddAccountBalanceTx(ctx, params)
+	})
+}
+
+func (s *retryStore) AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.AddAccountHeldBalance(ctx, arg)
+	})
+}
+
+func (s *retryStore) AddDailyTransferTotal(ctx context.Context, arg AddDailyTransferTotalParams) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.AddDailyTransferTotal(ctx, arg)
+	})
+}
+
+func (s *retryStore) AuthorizeHoldTx(ctx context.Context, params AuthorizeHoldTxParams) (AuthorizeHoldTxResult, error) {
+	return retry(ctx, s.policy, func() (AuthorizeHoldTxResult, error) {
+		return s.store.AuthorizeHoldTx(ctx, params)
+	})
+}
+
+func (s *retryStore) CancelAccountScheduledTransfers(ctx context.Context, fromAccountID int64) error {
+	return s.retryExec(ctx, func() error {
+		return s.store.CancelAccountScheduledTransfers(ctx, fromAccountID)
+	})
+}
+
+func (s *retryStore) CancelScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	return retry(ctx, s.policy, func() (ScheduledTransfer, error) {
+		return s.store.CancelScheduledTransfer(ctx, id)
+	})
+}
+
+func (s *retryStore) CaptureHoldTx(ctx context.Context, params CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	return retry(ctx, s.policy, func() (CaptureHoldTxResult, error) {
+		return s.store.CaptureHoldTx(ctx, params)
+	})
+}
+
+func (s *retryStore) ChangePasswordTx(ctx context.Context, params ChangePasswordTxParams) (User, error) {
+	return retry(ctx, s.policy, func() (User, error) {
+		return s.store.ChangePasswordTx(ctx, params)
+	})
+}
+
+func (s *retryStore) ResetPasswordTx(ctx context.Context, params ResetPasswordTxParams) (User, error) {
+	return retry(ctx, s.policy, func() (User, error) {
+		return s.store.ResetPasswordTx(ctx, params)
+	})
+}
+
+func (s *retryStore) ClaimScheduledTransfer(ctx context.Context, arg ClaimScheduledTransferParams) (ScheduledTransfer, error) {
+	return retry(ctx, s.policy, func() (ScheduledTransfer, error) {
+		return s.store.ClaimScheduledTransfer(ctx, arg)
+	})
+}
+
+func (s *retryStore) CloseAccountTx(ctx context.Context, params CloseAccountTxParams) (CloseAccountTxResult, error) {
+	return retry(ctx, s.policy, func() (CloseAccountTxResult, error) {
+		return s.store.CloseAccountTx(ctx, params)
+	})
+}
+
+func (s *retryStore) CountAccounts(ctx context.Context, arg CountAccountsParams) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.CountAccounts(ctx, arg)
+	})
+}
+
+func (s *retryStore) CountAccountsByCurrency(ctx context.Context, arg CountAccountsByCurrencyParams) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.CountAccountsByCurrency(ctx, arg)
+	})
+}
+
+func (s *retryStore) CountAllAccounts(ctx context.Context, arg CountAllAccountsParams) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.CountAllAccounts(ctx, arg)
+	})
+}
+
+func (s *retryStore) CountAuditLogs(ctx context.Context, arg CountAuditLogsParams) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.CountAuditLogs(ctx, arg)
+	})
+}
+
+func (s *retryStore) CountEntriesByAccount(ctx context.Context, accountID int64) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.CountEntriesByAccount(ctx, accountID)
+	})
+}
+
+func (s *retryStore) CountEntriesByAccountInRange(ctx context.Context, arg CountEntriesByAccountInRangeParams) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.CountEntriesByAccountInRange(ctx, arg)
+	})
+}
+
+func (s *retryStore) CountScheduledTransfers(ctx context.Context, fromAccountID int64) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.CountScheduledTransfers(ctx, fromAccountID)
+	})
+}
+
+func (s *retryStore) CountSearchUsers(ctx context.Context, arg CountSearchUsersParams) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.CountSearchUsers(ctx, arg)
+	})
+}
+
+func (s *retryStore) CountTransfers(ctx context.Context, arg CountTransfersParams) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.CountTransfers(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.CreateAccount(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateAccountIdempotencyKey(ctx context.Context, arg CreateAccountIdempotencyKeyParams) (AccountIdempotencyKey, error) {
+	return retry(ctx, s.policy, func() (AccountIdempotencyKey, error) {
+		return s.store.CreateAccountIdempotencyKey(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateAccountOwnerChange(ctx context.Context, arg CreateAccountOwnerChangeParams) (AccountOwnerChange, error) {
+	return retry(ctx, s.policy, func() (AccountOwnerChange, error) {
+		return s.store.CreateAccountOwnerChange(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error) {
+	return retry(ctx, s.policy, func() (AuditLog, error) {
+		return s.store.CreateAuditLog(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateBalanceSnapshot(ctx context.Context, arg CreateBalanceSnapshotParams) (BalanceSnapshot, error) {
+	return retry(ctx, s.policy, func() (BalanceSnapshot, error) {
+		return s.store.CreateBalanceSnapshot(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateDailyBalanceSnapshots(ctx context.Context, day time.Time) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.CreateDailyBalanceSnapshots(ctx, day)
+	})
+}
+
+func (s *retryStore) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
+	return retry(ctx, s.policy, func() (Entry, error) {
+		return s.store.CreateEntry(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error) {
+	return retry(ctx, s.policy, func() (Hold, error) {
+		return s.store.CreateHold(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error) {
+	return retry(ctx, s.policy, func() (IdempotencyKey, error) {
+		return s.store.CreateIdempotencyKey(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateOrganization(ctx context.Context, name string) (Organization, error) {
+	return retry(ctx, s.policy, func() (Organization, error) {
+		return s.store.CreateOrganization(ctx, name)
+	})
+}
+
+func (s *retryStore) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error) {
+	return retry(ctx, s.policy, func() (Outbox, error) {
+		return s.store.CreateOutboxEvent(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error) {
+	return retry(ctx, s.policy, func() (PasswordReset, error) {
+		return s.store.CreatePasswordReset(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	return retry(ctx, s.policy, func() (ScheduledTransfer, error) {
+		return s.store.CreateScheduledTransfer(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	return retry(ctx, s.policy, func() (Session, error) {
+		return s.store.CreateSession(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
+	return retry(ctx, s.policy, func() (Transfer, error) {
+		return s.store.CreateTransfer(ctx, arg)
+	})
+}
+
+func (s *retryStore) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	return retry(ctx, s.policy, func() (User, error) {
+		return s.store.CreateUser(ctx, arg)
+	})
+}
+
+func (s *retryStore) DeleteAccount(ctx context.Context, id int64) error {
+	return s.retryExec(ctx, func() error {
+		return s.store.DeleteAccount(ctx, id)
+	})
+}
+
+func (s *retryStore) DeleteEntry(ctx context.Context, id int64) error {
+	return s.retryExec(ctx, func() error {
+		return s.store.DeleteEntry(ctx, id)
+	})
+}
+
+func (s *retryStore) DeleteTransfer(ctx context.Context, id int64) error {
+	return s.retryExec(ctx, func() error {
+		return s.store.DeleteTransfer(ctx, id)
+	})
+}
+
+func (s *retryStore) DeleteUser(ctx context.Context, username string) error {
+	return s.retryExec(ctx, func() error {
+		return s.store.DeleteUser(ctx, username)
+	})
+}
+
+func (s *retryStore) EnableTotpSecret(ctx context.Context, username string) (TotpSecret, error) {
+	return retry(ctx, s.policy, func() (TotpSecret, error) {
+		return s.store.EnableTotpSecret(ctx, username)
+	})
+}
+
+func (s *retryStore) EntryTx(ctx context.Context, params EntryTxParams) (EntryTxResult, error) {
+	return retry(ctx, s.policy, func() (EntryTxResult, error) {
+		return s.store.EntryTx(ctx, params)
+	})
+}
+
+func (s *retryStore) ExecTx(ctx context.Context, fn func(Querier) error) error {
+	return s.retryExec(ctx, func() error {
+		return s.store.ExecTx(ctx, fn)
+	})
+}
+
+func (s *retryStore) ExpireHoldsTx(ctx context.Context, params ExpireHoldsTxParams) ([]Hold, error) {
+	return retry(ctx, s.policy, func() ([]Hold, error) {
+		return s.store.ExpireHoldsTx(ctx, params)
+	})
+}
+
+func (s *retryStore) GetAccount(ctx context.Context, id int64) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.GetAccount(ctx, id)
+	})
+}
+
+func (s *retryStore) GetAccountByNumber(ctx context.Context, accountNumber string) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.GetAccountByNumber(ctx, accountNumber)
+	})
+}
+
+func (s *retryStore) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.GetAccountForUpdate(ctx, id)
+	})
+}
+
+func (s *retryStore) GetAccountForUpdateIncludingDeleted(ctx context.Context, id int64) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.GetAccountForUpdateIncludingDeleted(ctx, id)
+	})
+}
+
+func (s *retryStore) GetAccountIdempotencyKey(ctx context.Context, arg GetAccountIdempotencyKeyParams) (AccountIdempotencyKey, error) {
+	return retry(ctx, s.policy, func() (AccountIdempotencyKey, error) {
+		return s.store.GetAccountIdempotencyKey(ctx, arg)
+	})
+}
+
+func (s *retryStore) GetAccountIncludingDeleted(ctx context.Context, id int64) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.GetAccountIncludingDeleted(ctx, id)
+	})
+}
+
+func (s *retryStore) GetAccountNumbers(ctx context.Context, ids []int64) ([]GetAccountNumbersRow, error) {
+	return retry(ctx, s.policy, func() ([]GetAccountNumbersRow, error) {
+		return s.store.GetAccountNumbers(ctx, ids)
+	})
+}
+
+func (s *retryStore) GetAccountStats(ctx context.Context, arg GetAccountStatsParams) (GetAccountStatsRow, error) {
+	return retry(ctx, s.policy, func() (GetAccountStatsRow, error) {
+		return s.store.GetAccountStats(ctx, arg)
+	})
+}
+
+func (s *retryStore) GetAccountsByIDs(ctx context.Context, ids []int64) ([]Account, error) {
+	return retry(ctx, s.policy, func() ([]Account, error) {
+		return s.store.GetAccountsByIDs(ctx, ids)
+	})
+}
+
+func (s *retryStore) GetAccountsByNumbers(ctx context.Context, accountNumbers []string) ([]Account, error) {
+	return retry(ctx, s.policy, func() ([]Account, error) {
+		return s.store.GetAccountsByNumbers(ctx, accountNumbers)
+	})
+}
+
+func (s *retryStore) GetDailyTransferTotal(ctx context.Context, username string) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.GetDailyTransferTotal(ctx, username)
+	})
+}
+
+func (s *retryStore) GetEntry(ctx context.Context, id int64) (Entry, error) {
+	return retry(ctx, s.policy, func() (Entry, error) {
+		return s.store.GetEntry(ctx, id)
+	})
+}
+
+func (s *retryStore) GetHold(ctx context.Context, id int64) (Hold, error) {
+	return retry(ctx, s.policy, func() (Hold, error) {
+		return s.store.GetHold(ctx, id)
+	})
+}
+
+func (s *retryStore) GetHoldForUpdate(ctx context.Context, id int64) (Hold, error) {
+	return retry(ctx, s.policy, func() (Hold, error) {
+		return s.store.GetHoldForUpdate(ctx, id)
+	})
+}
+
+func (s *retryStore) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	return retry(ctx, s.policy, func() (IdempotencyKey, error) {
+		return s.store.GetIdempotencyKey(ctx, arg)
+	})
+}
+
+func (s *retryStore) GetNotificationPreferences(ctx context.Context, username string) (NotificationPreference, error) {
+	return retry(ctx, s.policy, func() (NotificationPreference, error) {
+		return s.store.GetNotificationPreferences(ctx, username)
+	})
+}
+
+func (s *retryStore) GetOrganization(ctx context.Context, id int64) (Organization, error) {
+	return retry(ctx, s.policy, func() (Organization, error) {
+		return s.store.GetOrganization(ctx, id)
+	})
+}
+
+func (s *retryStore) GetPasswordResetForUpdate(ctx context.Context, tokenHash string) (PasswordReset, error) {
+	return retry(ctx, s.policy, func() (PasswordReset, error) {
+		return s.store.GetPasswordResetForUpdate(ctx, tokenHash)
+	})
+}
+
+func (s *retryStore) GetScheduledTransfer(ctx context.Context, id int64) (GetScheduledTransferRow, error) {
+	return retry(ctx, s.policy, func() (GetScheduledTransferRow, error) {
+		return s.store.GetScheduledTransfer(ctx, id)
+	})
+}
+
+func (s *retryStore) GetSession(ctx context.Context, id uuid.UUID) (Session, error) {
+	return retry(ctx, s.policy, func() (Session, error) {
+		return s.store.GetSession(ctx, id)
+	})
+}
+
+func (s *retryStore) GetTotpSecret(ctx context.Context, username string) (TotpSecret, error) {
+	return retry(ctx, s.policy, func() (TotpSecret, error) {
+		return s.store.GetTotpSecret(ctx, username)
+	})
+}
+
+func (s *retryStore) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	return retry(ctx, s.policy, func() (Transfer, error) {
+		return s.store.GetTransfer(ctx, id)
+	})
+}
+
+func (s *retryStore) GetTransferByUUID(ctx context.Context, uuid uuid.UUID) (Transfer, error) {
+	return retry(ctx, s.policy, func() (Transfer, error) {
+		return s.store.GetTransferByUUID(ctx, uuid)
+	})
+}
+
+func (s *retryStore) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	return retry(ctx, s.policy, func() (Transfer, error) {
+		return s.store.GetTransferForUpdate(ctx, id)
+	})
+}
+
+func (s *retryStore) GetUser(ctx context.Context, username string) (User, error) {
+	return retry(ctx, s.policy, func() (User, error) {
+		return s.store.GetUser(ctx, username)
+	})
+}
+
+func (s *retryStore) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	return retry(ctx, s.policy, func() (User, error) {
+		return s.store.GetUserByEmail(ctx, email)
+	})
+}
+
+func (s *retryStore) GetUserForUpdate(ctx context.Context, username string) (User, error) {
+	return retry(ctx, s.policy, func() (User, error) {
+		return s.store.GetUserForUpdate(ctx, username)
+	})
+}
+
+func (s *retryStore) GetUserPasswordChangedAt(ctx context.Context, username string) (time.Time, error) {
+	return retry(ctx, s.policy, func() (time.Time, error) {
+		return s.store.GetUserPasswordChangedAt(ctx, username)
+	})
+}
+
+func (s *retryStore) IdempotentCreateAccountTx(ctx context.Context, params IdempotentCreateAccountTxParams) (IdempotentCreateAccountTxResult, error) {
+	return retry(ctx, s.policy, func() (IdempotentCreateAccountTxResult, error) {
+		return s.store.IdempotentCreateAccountTx(ctx, params)
+	})
+}
+
+func (s *retryStore) IdempotentTransferTx(ctx context.Context, params IdempotentTransferTxParams) (IdempotentTransferTxResult, error) {
+	return retry(ctx, s.policy, func() (IdempotentTransferTxResult, error) {
+		return s.store.IdempotentTransferTx(ctx, params)
+	})
+}
+
+func (s *retryStore) ImportUsersTx(ctx context.Context, params ImportUsersTxParams) (ImportUsersTxResult, error) {
+	return retry(ctx, s.policy, func() (ImportUsersTxResult, error) {
+		return s.store.ImportUsersTx(ctx, params)
+	})
+}
+
+func (s *retryStore) ListAccountOwnerChanges(ctx context.Context, accountID int64) ([]AccountOwnerChange, error) {
+	return retry(ctx, s.policy, func() ([]AccountOwnerChange, error) {
+		return s.store.ListAccountOwnerChanges(ctx, accountID)
+	})
+}
+
+func (s *retryStore) ListAccountStatement(ctx context.Context, arg ListAccountStatementParams) ([]ListAccountStatementRow, error) {
+	return retry(ctx, s.policy, func() ([]ListAccountStatementRow, error) {
+		return s.store.ListAccountStatement(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	return retry(ctx, s.policy, func() ([]Account, error) {
+		return s.store.ListAccounts(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListAccountsAfterNumber(ctx context.Context, arg ListAccountsAfterNumberParams) ([]Account, error) {
+	return retry(ctx, s.policy, func() ([]Account, error) {
+		return s.store.ListAccountsAfterNumber(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListAccountsByCurrency(ctx context.Context, arg ListAccountsByCurrencyParams) ([]Account, error) {
+	return retry(ctx, s.policy, func() ([]Account, error) {
+		return s.store.ListAccountsByCurrency(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListAllAccounts(ctx context.Context, arg ListAllAccountsParams) ([]Account, error) {
+	return retry(ctx, s.policy, func() ([]Account, error) {
+		return s.store.ListAllAccounts(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error) {
+	return retry(ctx, s.policy, func() ([]AuditLog, error) {
+		return s.store.ListAuditLogs(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListBalanceSnapshots(ctx context.Context, arg ListBalanceSnapshotsParams) ([]BalanceSnapshot, error) {
+	return retry(ctx, s.policy, func() ([]BalanceSnapshot, error) {
+		return s.store.ListBalanceSnapshots(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListDueScheduledTransfers(ctx context.Context, arg ListDueScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	return retry(ctx, s.policy, func() ([]ScheduledTransfer, error) {
+		return s.store.ListDueScheduledTransfers(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	return retry(ctx, s.policy, func() ([]Entry, error) {
+		return s.store.ListEntries(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListEntriesByAccount(ctx context.Context, arg ListEntriesByAccountParams) ([]Entry, error) {
+	return retry(ctx, s.policy, func() ([]Entry, error) {
+		return s.store.ListEntriesByAccount(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListEntriesByAccountInRange(ctx context.Context, arg ListEntriesByAccountInRangeParams) ([]Entry, error) {
+	return retry(ctx, s.policy, func() ([]Entry, error) {
+		return s.store.ListEntriesByAccountInRange(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListExpiredHolds(ctx context.Context, arg ListExpiredHoldsParams) ([]Hold, error) {
+	return retry(ctx, s.policy, func() ([]Hold, error) {
+		return s.store.ListExpiredHolds(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ListScheduledTransfersRow, error) {
+	return retry(ctx, s.policy, func() ([]ListScheduledTransfersRow, error) {
+		return s.store.ListScheduledTransfers(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	return retry(ctx, s.policy, func() ([]Transfer, error) {
+		return s.store.ListTransfers(ctx, arg)
+	})
+}
+
+func (s *retryStore) ListUnpublishedOutboxEvents(ctx context.Context, limit int32) ([]Outbox, error) {
+	return retry(ctx, s.policy, func() ([]Outbox, error) {
+		return s.store.ListUnpublishedOutboxEvents(ctx, limit)
+	})
+}
+
+func (s *retryStore) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	return retry(ctx, s.policy, func() ([]User, error) {
+		return s.store.ListUsers(ctx, arg)
+	})
+}
+
+func (s *retryStore) LoginTx(ctx context.Context, params LoginTxParams) (Session, error) {
+	return retry(ctx, s.policy, func() (Session, error) {
+		return s.store.LoginTx(ctx, params)
+	})
+}
+
+func (s *retryStore) LookupAccounts(ctx context.Context, ids []int64) (map[int64]Account, error) {
+	return retry(ctx, s.policy, func() (map[int64]Account, error) {
+		return s.store.LookupAccounts(ctx, ids)
+	})
+}
+
+func (s *retryStore) LookupAccountsByNumber(ctx context.Context, accountNumbers []string) (map[string]Account, error) {
+	return retry(ctx, s.policy, func() (map[string]Account, error) {
+		return s.store.LookupAccountsByNumber(ctx, accountNumbers)
+	})
+}
+
+func (s *retryStore) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	return s.retryExec(ctx, func() error {
+		return s.store.MarkOutboxEventPublished(ctx, id)
+	})
+}
+
+func (s *retryStore) MarkPasswordResetUsed(ctx context.Context, id int64) (PasswordReset, error) {
+	return retry(ctx, s.policy, func() (PasswordReset, error) {
+		return s.store.MarkPasswordResetUsed(ctx, id)
+	})
+}
+
+func (s *retryStore) MarkTransferReversed(ctx context.Context, id int64) (Transfer, error) {
+	return retry(ctx, s.policy, func() (Transfer, error) {
+		return s.store.MarkTransferReversed(ctx, id)
+	})
+}
+
+func (s *retryStore) Ping(ctx context.Context) error {
+	return s.retryExec(ctx, func() error {
+		return s.store.Ping(ctx)
+	})
+}
+
+// PublishOutbox is never retried, the rows already handed to publish can't be taken back
+func (s *retryStore) PublishOutbox(ctx context.Context, limit int32, publish func(context.Context, Outbox) error) (int, error) {
+	return s.store.PublishOutbox(ctx, limit, publish)
+}
+
+func (s *retryStore) RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (User, error) {
+	return retry(ctx, s.policy, func() (User, error) {
+		return s.store.RecordFailedLogin(ctx, arg)
+	})
+}
+
+func (s *retryStore) RecordScheduledTransferFailure(ctx context.Context, arg RecordScheduledTransferFailureParams) (ScheduledTransfer, error) {
+	return retry(ctx, s.policy, func() (ScheduledTransfer, error) {
+		return s.store.RecordScheduledTransferFailure(ctx, arg)
+	})
+}
+
+func (s *retryStore) RecordScheduledTransferRun(ctx context.Context, arg RecordScheduledTransferRunParams) (ScheduledTransfer, error) {
+	return retry(ctx, s.policy, func() (ScheduledTransfer, error) {
+		return s.store.RecordScheduledTransferRun(ctx, arg)
+	})
+}
+
+func (s *retryStore) ResetFailedLogins(ctx context.Context, username string) error {
+	return s.retryExec(ctx, func() error {
+		return s.store.ResetFailedLogins(ctx, username)
+	})
+}
+
+func (s *retryStore) RestoreAccount(ctx context.Context, arg RestoreAccountParams) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.RestoreAccount(ctx, arg)
+	})
+}
+
+func (s *retryStore) ReverseTransferTx(ctx context.Context, params ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	return retry(ctx, s.policy, func() (ReverseTransferTxResult, error) {
+		return s.store.ReverseTransferTx(ctx, params)
+	})
+}
+
+func (s *retryStore) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error) {
+	return retry(ctx, s.policy, func() ([]User, error) {
+		return s.store.SearchUsers(ctx, arg)
+	})
+}
+
+func (s *retryStore) SetAccountFrozen(ctx context.Context, arg SetAccountFrozenParams) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.SetAccountFrozen(ctx, arg)
+	})
+}
+
+func (s *retryStore) SetAccountFrozenTx(ctx context.Context, params SetAccountFrozenTxParams) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.SetAccountFrozenTx(ctx, params)
+	})
+}
+
+func (s *retryStore) SetAccountLabels(ctx context.Context, arg SetAccountLabelsParams) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.SetAccountLabels(ctx, arg)
+	})
+}
+
+func (s *retryStore) SetAccountLowBalanceThreshold(ctx context.Context, arg SetAccountLowBalanceThresholdParams) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.SetAccountLowBalanceThreshold(ctx, arg)
+	})
+}
+
+func (s *retryStore) SetAccountSpendingLimits(ctx context.Context, arg SetAccountSpendingLimitsParams) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.SetAccountSpendingLimits(ctx, arg)
+	})
+}
+
+func (s *retryStore) SoftDeleteAccount(ctx context.Context, id int64) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.SoftDeleteAccount(ctx, id)
+	})
+}
+
+func (s *retryStore) SumOutgoingTransfers(ctx context.Context, arg SumOutgoingTransfersParams) (int64, error) {
+	return retry(ctx, s.policy, func() (int64, error) {
+		return s.store.SumOutgoingTransfers(ctx, arg)
+	})
+}
+
+func (s *retryStore) SplitTransferTx(ctx context.Context, params SplitTransferTxParams) (SplitTransferTxResult, error) {
+	return retry(ctx, s.policy, func() (SplitTransferTxResult, error) {
+		return s.store.SplitTransferTx(ctx, params)
+	})
+}
+
+// StreamAccountStatement is never retried, the rows already handed to fn can't be taken back
+func (s *retryStore) StreamAccountStatement(ctx context.Context, arg ListAccountStatementParams, fn func(ListAccountStatementRow) error) error {
+	return s.store.StreamAccountStatement(ctx, arg, fn)
+}
+
+func (s *retryStore) TransferAccountOwnershipTx(ctx context.Context, params TransferAccountOwnershipTxParams) (TransferAccountOwnershipTxResult, error) {
+	return retry(ctx, s.policy, func() (TransferAccountOwnershipTxResult, error) {
+		return s.store.TransferAccountOwnershipTx(ctx, params)
+	})
+}
+
+func (s *retryStore) TransferTx(ctx context.Context, params TransferTxParams) (TransferTxResult, error) {
+	return retry(ctx, s.policy, func() (TransferTxResult, error) {
+		return s.store.TransferTx(ctx, params)
+	})
+}
+
+func (s *retryStore) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.UpdateAccount(ctx, arg)
+	})
+}
+
+func (s *retryStore) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.UpdateAccountBalance(ctx, arg)
+	})
+}
+
+func (s *retryStore) UpdateAccountOwner(ctx context.Context, arg UpdateAccountOwnerParams) (Account, error) {
+	return retry(ctx, s.policy, func() (Account, error) {
+		return s.store.UpdateAccountOwner(ctx, arg)
+	})
+}
+
+func (s *retryStore) UpdateHoldStatus(ctx context.Context, arg UpdateHoldStatusParams) (Hold, error) {
+	return retry(ctx, s.policy, func() (Hold, error) {
+		return s.store.UpdateHoldStatus(ctx, arg)
+	})
+}
+
+func (s *retryStore) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	return retry(ctx, s.policy, func() (User, error) {
+		return s.store.UpdateUser(ctx, arg)
+	})
+}
+
+func (s *retryStore) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	return retry(ctx, s.policy, func() (User, error) {
+		return s.store.UpdateUserPassword(ctx, arg)
+	})
+}
+
+func (s *retryStore) UpsertNotificationPreferences(ctx context.Context, arg UpsertNotificationPreferencesParams) (NotificationPreference, error) {
+	return retry(ctx, s.policy, func() (NotificationPreference, error) {
+		return s.store.UpsertNotificationPreferences(ctx, arg)
+	})
+}
+
+func (s *retryStore) UpsertTotpSecret(ctx context.Context, arg UpsertTotpSecretParams) (TotpSecret, error) {
+	return retry(ctx, s.policy, func() (TotpSecret, error) {
+		return s.store.UpsertTotpSecret(ctx, arg)
+	})
+}
+
+func (s *retryStore) VoidHoldTx(ctx context.Context, params VoidHoldTxParams) (VoidHoldTxResult, error) {
+	return retry(ctx, s.policy, func() (VoidHoldTxResult, error) {
+		return s.store.VoidHoldTx(ctx, params)
+	})
+}
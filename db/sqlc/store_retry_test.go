@@ -0,0 +1,152 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+var (
+	serializationFailure = &pq.Error{Code: "40001"}
+	deadlockDetected     = &pq.Error{Code: "40P01"}
+)
+
+func TestRetryStoreTransferTx(t *testing.T) {
+	params := db.TransferTxParams{
+		FromAccountID: utils.RandomInt(1, 1000),
+		ToAccountID:   utils.RandomInt(1, 1000),
+		Amount:        utils.RandomBalance(),
+	}
+	result := db.TransferTxResult{
+		Transfer: db.Transfer{
+			ID:            utils.RandomInt(1, 1000),
+			FromAccountID: params.FromAccountID,
+			ToAccountID:   params.ToAccountID,
+			Amount:        params.Amount,
+		},
+	}
+
+	policy := db.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp db.TransferTxResult, err error)
+	}{
+		{
+			name: "fails twice then succeeds",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(params)).Times(1).Return(db.TransferTxResult{}, serializationFailure),
+					store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(params)).Times(1).Return(db.TransferTxResult{}, deadlockDetected),
+					store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(params)).Times(1).Return(result, nil),
+				)
+			},
+			checkResponse: func(t *testing.T, rsp db.TransferTxResult, err error) {
+				require.NoError(t, err)
+				require.Equal(t, result, rsp)
+			},
+		},
+		{
+			name: "runs out of attempts",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(params)).Times(policy.MaxAttempts).Return(db.TransferTxResult{}, serializationFailure)
+			},
+			checkResponse: func(t *testing.T, rsp db.TransferTxResult, err error) {
+				require.ErrorIs(t, err, serializationFailure)
+			},
+		},
+		{
+			name: "non transient error",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(params)).Times(1).Return(db.TransferTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rsp db.TransferTxResult, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+		{
+			name: "unique violation isn't retried",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(params)).Times(1).Return(db.TransferTxResult{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(t *testing.T, rsp db.TransferTxResult, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			retryStore := db.NewRetryStore(store, policy)
+			rsp, err := retryStore.TransferTx(context.Background(), params)
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
+
+func TestRetryStoreExec(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	id := utils.RandomInt(1, 1000)
+	gomock.InOrder(
+		store.EXPECT().CancelAccountScheduledTransfers(gomock.Any(), gomock.Eq(id)).Times(2).Return(deadlockDetected),
+		store.EXPECT().CancelAccountScheduledTransfers(gomock.Any(), gomock.Eq(id)).Times(1).Return(nil),
+	)
+
+	retryStore := db.NewRetryStore(store, db.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	err := retryStore.CancelAccountScheduledTransfers(context.Background(), id)
+	require.NoError(t, err)
+}
+
+func TestRetryStoreDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.TransferTxResult{}, serializationFailure)
+
+	retryStore := db.NewRetryStore(store, db.RetryPolicy{MaxAttempts: 1, BaseDelay: time.Millisecond})
+	_, err := retryStore.TransferTx(context.Background(), db.TransferTxParams{})
+	require.ErrorIs(t, err, serializationFailure)
+}
+
+func TestRetryStoreContextDone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the request is cancelled while the store waits for the first retry
+	store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(
+		func(context.Context, db.TransferTxParams) (db.TransferTxResult, error) {
+			cancel()
+			return db.TransferTxResult{}, serializationFailure
+		})
+
+	retryStore := db.NewRetryStore(store, db.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Minute})
+	_, err := retryStore.TransferTx(ctx, db.TransferTxParams{})
+	require.ErrorIs(t, err, serializationFailure)
+}
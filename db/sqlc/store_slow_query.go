@@ -0,0 +1,687 @@
+package db
+
+import (
+	"context"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/rs/zerolog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// slowQueryStore wraps a Store timing every call, the ones taking longer than threshold are logged at warn level
+type slowQueryStore struct {
+	store     Store
+	threshold time.Duration
+	logger    zerolog.Logger
+}
+
+// NewSlowQueryStore returns a Store that logs the calls to store taking longer than threshold, with the method name and duration
+// A threshold of 0 or less leaves store untouched
+func NewSlowQueryStore(store Store, threshold time.Duration, logger zerolog.Logger) Store {
+	if threshold <= 0 {
+		return store
+	}
+
+	return &slowQueryStore{
+		store:     store,
+		threshold: threshold,
+		logger:    logger,
+	}
+}
+
+// observe logs the call to method started at start when it's slow, it's deferred so it also times the failing calls
+// The log carries the id of the request making the call, to find which one was slowed down
+func (s *slowQueryStore) observe(ctx context.Context, method string, start time.Time) {
+	duration := time.Since(start)
+	if duration <= s.threshold {
+		return
+	}
+
+	s.logger.Warn().
+		Str("method", method).
+		Str(utils.RequestIDKey, utils.RequestIDFromContext(ctx)).
+		Dur("duration", duration).
+		Dur("threshold", s.threshold).
+		Msg("slow db query")
+}
+
+func (s *slowQueryStore) AccountNumberExists(ctx context.Context, accountNumber string) (bool, error) {
+	defer s.observe(ctx, "AccountNumberExists", time.Now())
+	return s.store.AccountNumberExists(ctx, accountNumber)
+}
+
+func (s *slowQueryStore) AddAccountBalanceTx(ctx context.Context, params AddAccountBalanceTxParams) (Account, error) {
+	defer s.observe(ctx, "AddAccountBalanceTx", time.Now())
+	return s.store.AddAccountBalanceTx(ctx, params)
+}
+
+func (s *slowQueryStore) AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error) {
+	defer s.observe(ctx, "AddAccountHeldBalance", time.Now())
+	return s.store.AddAccountHeldBalance(ctx, arg)
+}
+
+func (s *slowQueryStore) AddDailyTransferTotal(ctx context.Context, arg AddDailyTransferTotalParams) (int64, error) {
+	defer s.observe(ctx, "AddDailyTransferTotal", time.Now())
+	return s.store.AddDailyTransferTotal(ctx, arg)
+}
+
+func (s *slowQueryStore) AuthorizeHoldTx(ctx context.Context, params AuthorizeHoldTxParams) (AuthorizeHoldTxResult, error) {
+	defer s.observe(ctx, "AuthorizeHoldTx", time.Now())
+	return s.store.AuthorizeHoldTx(ctx, params)
+}
+
+func (s *slowQueryStore) CancelAccountScheduledTransfers(ctx context.Context, fromAccountID int64) error {
+	defer s.observe(ctx, "CancelAccountScheduledTransfers", time.Now())
+	return s.store.CancelAccountScheduledTransfers(ctx, fromAccountID)
+}
+
+func (s *slowQueryStore) CancelScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	defer s.observe(ctx, "CancelScheduledTransfer", time.Now())
+	return s.store.CancelScheduledTransfer(ctx, id)
+}
+
+func (s *slowQueryStore) CaptureHoldTx(ctx context.Context, params CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	defer s.observe(ctx, "CaptureHoldTx", time.Now())
+	return s.store.CaptureHoldTx(ctx, params)
+}
+
+func (s *slowQueryStore) ChangePasswordTx(ctx context.Context, params ChangePasswordTxParams) (User, error) {
+	defer s.observe(ctx, "ChangePasswordTx", time.Now())
+	return s.store.ChangePasswordTx(ctx, params)
+}
+
+func (s *slowQueryStore) ResetPasswordTx(ctx context.Context, params ResetPasswordTxParams) (User, error) {
+	defer s.observe(ctx, "ResetPasswordTx", time.Now())
+	return s.store.ResetPasswordTx(ctx, params)
+}
+
+func (s *slowQueryStore) ClaimScheduledTransfer(ctx context.Context, arg ClaimScheduledTransferParams) (ScheduledTransfer, error) {
+	defer s.observe(ctx, "ClaimScheduledTransfer", time.Now())
+	return s.store.ClaimScheduledTransfer(ctx, arg)
+}
+
+func (s *slowQueryStore) CloseAccountTx(ctx context.Context, params CloseAccountTxParams) (CloseAccountTxResult, error) {
+	defer s.observe(ctx, "CloseAccountTx", time.Now())
+	return s.store.CloseAccountTx(ctx, params)
+}
+
+func (s *slowQueryStore) CountAccounts(ctx context.Context, arg CountAccountsParams) (int64, error) {
+	defer s.observe(ctx, "CountAccounts", time.Now())
+	return s.store.CountAccounts(ctx, arg)
+}
+
+func (s *slowQueryStore) CountAccountsByCurrency(ctx context.Context, arg CountAccountsByCurrencyParams) (int64, error) {
+	defer s.observe(ctx, "CountAccountsByCurrency", time.Now())
+	return s.store.CountAccountsByCurrency(ctx, arg)
+}
+
+func (s *slowQueryStore) CountAllAccounts(ctx context.Context, arg CountAllAccountsParams) (int64, error) {
+	defer s.observe(ctx, "CountAllAccounts", time.Now())
+	return s.store.CountAllAccounts(ctx, arg)
+}
+
+func (s *slowQueryStore) CountAuditLogs(ctx context.Context, arg CountAuditLogsParams) (int64, error) {
+	defer s.observe(ctx, "CountAuditLogs", time.Now())
+	return s.store.CountAuditLogs(ctx, arg)
+}
+
+func (s *slowQueryStore) CountEntriesByAccount(ctx context.Context, accountID int64) (int64, error) {
+	defer s.observe(ctx, "CountEntriesByAccount", time.Now())
+	return s.store.CountEntriesByAccount(ctx, accountID)
+}
+
+func (s *slowQueryStore) CountEntriesByAccountInRange(ctx context.Context, arg CountEntriesByAccountInRangeParams) (int64, error) {
+	defer s.observe(ctx, "CountEntriesByAccountInRange", time.Now())
+	return s.store.CountEntriesByAccountInRange(ctx, arg)
+}
+
+func (s *slowQueryStore) CountScheduledTransfers(ctx context.Context, fromAccountID int64) (int64, error) {
+	defer s.observe(ctx, "CountScheduledTransfers", time.Now())
+	return s.store.CountScheduledTransfers(ctx, fromAccountID)
+}
+
+func (s *slowQueryStore) CountSearchUsers(ctx context.Context, arg CountSearchUsersParams) (int64, error) {
+	defer s.observe(ctx, "CountSearchUsers", time.Now())
+	return s.store.CountSearchUsers(ctx, arg)
+}
+
+func (s *slowQueryStore) CountTransfers(ctx context.Context, arg CountTransfersParams) (int64, error) {
+	defer s.observe(ctx, "CountTransfers", time.Now())
+	return s.store.CountTransfers(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	defer s.observe(ctx, "CreateAccount", time.Now())
+	return s.store.CreateAccount(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateAccountIdempotencyKey(ctx context.Context, arg CreateAccountIdempotencyKeyParams) (AccountIdempotencyKey, error) {
+	defer s.observe(ctx, "CreateAccountIdempotencyKey", time.Now())
+	return s.store.CreateAccountIdempotencyKey(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateAccountOwnerChange(ctx context.Context, arg CreateAccountOwnerChangeParams) (AccountOwnerChange, error) {
+	defer s.observe(ctx, "CreateAccountOwnerChange", time.Now())
+	return s.store.CreateAccountOwnerChange(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error) {
+	defer s.observe(ctx, "CreateAuditLog", time.Now())
+	return s.store.CreateAuditLog(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateBalanceSnapshot(ctx context.Context, arg CreateBalanceSnapshotParams) (BalanceSnapshot, error) {
+	defer s.observe(ctx, "CreateBalanceSnapshot", time.Now())
+	return s.store.CreateBalanceSnapshot(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateDailyBalanceSnapshots(ctx context.Context, day time.Time) (int64, error) {
+	defer s.observe(ctx, "CreateDailyBalanceSnapshots", time.Now())
+	return s.store.CreateDailyBalanceSnapshots(ctx, day)
+}
+
+func (s *slowQueryStore) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
+	defer s.observe(ctx, "CreateEntry", time.Now())
+	return s.store.CreateEntry(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error) {
+	defer s.observe(ctx, "CreateHold", time.Now())
+	return s.store.CreateHold(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error) {
+	defer s.observe(ctx, "CreateIdempotencyKey", time.Now())
+	return s.store.CreateIdempotencyKey(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateOrganization(ctx context.Context, name string) (Organization, error) {
+	defer s.observe(ctx, "CreateOrganization", time.Now())
+	return s.store.CreateOrganization(ctx, name)
+}
+
+func (s *slowQueryStore) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error) {
+	defer s.observe(ctx, "CreateOutboxEvent", time.Now())
+	return s.store.CreateOutboxEvent(ctx, arg)
+}
+
+func (s *slowQueryStore) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error) {
+	defer s.observe(ctx, "CreatePasswordReset", time.Now())
+	return s.store.CreatePasswordReset(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	defer s.observe(ctx, "CreateScheduledTransfer", time.Now())
+	return s.store.CreateScheduledTransfer(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	defer s.observe(ctx, "CreateSession", time.Now())
+	return s.store.CreateSession(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
+	defer s.observe(ctx, "CreateTransfer", time.Now())
+	return s.store.CreateTransfer(ctx, arg)
+}
+
+func (s *slowQueryStore) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	defer s.observe(ctx, "CreateUser", time.Now())
+	return s.store.CreateUser(ctx, arg)
+}
+
+func (s *slowQueryStore) DeleteAccount(ctx context.Context, id int64) error {
+	defer s.observe(ctx, "DeleteAccount", time.Now())
+	return s.store.DeleteAccount(ctx, id)
+}
+
+func (s *slowQueryStore) DeleteEntry(ctx context.Context, id int64) error {
+	defer s.observe(ctx, "DeleteEntry", time.Now())
+	return s.store.DeleteEntry(ctx, id)
+}
+
+func (s *slowQueryStore) DeleteTransfer(ctx context.Context, id int64) error {
+	defer s.observe(ctx, "DeleteTransfer", time.Now())
+	return s.store.DeleteTransfer(ctx, id)
+}
+
+func (s *slowQueryStore) DeleteUser(ctx context.Context, username string) error {
+	defer s.observe(ctx, "DeleteUser", time.Now())
+	return s.store.DeleteUser(ctx, username)
+}
+
+func (s *slowQueryStore) EnableTotpSecret(ctx context.Context, username string) (TotpSecret, error) {
+	defer s.observe(ctx, "EnableTotpSecret", time.Now())
+	return s.store.EnableTotpSecret(ctx, username)
+}
+
+func (s *slowQueryStore) EntryTx(ctx context.Context, params EntryTxParams) (EntryTxResult, error) {
+	defer s.observe(ctx, "EntryTx", time.Now())
+	return s.store.EntryTx(ctx, params)
+}
+
+func (s *slowQueryStore) ExecTx(ctx context.Context, fn func(Querier) error) error {
+	defer s.observe(ctx, "ExecTx", time.Now())
+	return s.store.ExecTx(ctx, fn)
+}
+
+func (s *slowQueryStore) ExpireHoldsTx(ctx context.Context, params ExpireHoldsTxParams) ([]Hold, error) {
+	defer s.observe(ctx, "ExpireHoldsTx", time.Now())
+	return s.store.ExpireHoldsTx(ctx, params)
+}
+
+func (s *slowQueryStore) GetAccount(ctx context.Context, id int64) (Account, error) {
+	defer s.observe(ctx, "GetAccount", time.Now())
+	return s.store.GetAccount(ctx, id)
+}
+
+func (s *slowQueryStore) GetAccountByNumber(ctx context.Context, accountNumber string) (Account, error) {
+	defer s.observe(ctx, "GetAccountByNumber", time.Now())
+	return s.store.GetAccountByNumber(ctx, accountNumber)
+}
+
+func (s *slowQueryStore) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
+	defer s.observe(ctx, "GetAccountForUpdate", time.Now())
+	return s.store.GetAccountForUpdate(ctx, id)
+}
+
+func (s *slowQueryStore) GetAccountForUpdateIncludingDeleted(ctx context.Context, id int64) (Account, error) {
+	defer s.observe(ctx, "GetAccountForUpdateIncludingDeleted", time.Now())
+	return s.store.GetAccountForUpdateIncludingDeleted(ctx, id)
+}
+
+func (s *slowQueryStore) GetAccountIdempotencyKey(ctx context.Context, arg GetAccountIdempotencyKeyParams) (AccountIdempotencyKey, error) {
+	defer s.observe(ctx, "GetAccountIdempotencyKey", time.Now())
+	return s.store.GetAccountIdempotencyKey(ctx, arg)
+}
+
+func (s *slowQueryStore) GetAccountIncludingDeleted(ctx context.Context, id int64) (Account, error) {
+	defer s.observe(ctx, "GetAccountIncludingDeleted", time.Now())
+	return s.store.GetAccountIncludingDeleted(ctx, id)
+}
+
+func (s *slowQueryStore) GetAccountNumbers(ctx context.Context, ids []int64) ([]GetAccountNumbersRow, error) {
+	defer s.observe(ctx, "GetAccountNumbers", time.Now())
+	return s.store.GetAccountNumbers(ctx, ids)
+}
+
+func (s *slowQueryStore) GetAccountStats(ctx context.Context, arg GetAccountStatsParams) (GetAccountStatsRow, error) {
+	defer s.observe(ctx, "GetAccountStats", time.Now())
+	return s.store.GetAccountStats(ctx, arg)
+}
+
+func (s *slowQueryStore) GetAccountsByIDs(ctx context.Context, ids []int64) ([]Account, error) {
+	defer s.observe(ctx, "GetAccountsByIDs", time.Now())
+	return s.store.GetAccountsByIDs(ctx, ids)
+}
+
+func (s *slowQueryStore) GetAccountsByNumbers(ctx context.Context, accountNumbers []string) ([]Account, error) {
+	defer s.observe(ctx, "GetAccountsByNumbers", time.Now())
+	return s.store.GetAccountsByNumbers(ctx, accountNumbers)
+}
+
+func (s *slowQueryStore) GetDailyTransferTotal(ctx context.Context, username string) (int64, error) {
+	defer s.observe(ctx, "GetDailyTransferTotal", time.Now())
+	return s.store.GetDailyTransferTotal(ctx, username)
+}
+
+func (s *slowQueryStore) GetEntry(ctx context.Context, id int64) (Entry, error) {
+	defer s.observe(ctx, "GetEntry", time.Now())
+	return s.store.GetEntry(ctx, id)
+}
+
+func (s *slowQueryStore) GetHold(ctx context.Context, id int64) (Hold, error) {
+	defer s.observe(ctx, "GetHold", time.Now())
+	return s.store.GetHold(ctx, id)
+}
+
+func (s *slowQueryStore) GetHoldForUpdate(ctx context.Context, id int64) (Hold, error) {
+	defer s.observe(ctx, "GetHoldForUpdate", time.Now())
+	return s.store.GetHoldForUpdate(ctx, id)
+}
+
+func (s *slowQueryStore) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	defer s.observe(ctx, "GetIdempotencyKey", time.Now())
+	return s.store.GetIdempotencyKey(ctx, arg)
+}
+
+func (s *slowQueryStore) GetNotificationPreferences(ctx context.Context, username string) (NotificationPreference, error) {
+	defer s.observe(ctx, "GetNotificationPreferences", time.Now())
+	return s.store.GetNotificationPreferences(ctx, username)
+}
+
+func (s *slowQueryStore) GetOrganization(ctx context.Context, id int64) (Organization, error) {
+	defer s.observe(ctx, "GetOrganization", time.Now())
+	return s.store.GetOrganization(ctx, id)
+}
+
+func (s *slowQueryStore) GetPasswordResetForUpdate(ctx context.Context, tokenHash string) (PasswordReset, error) {
+	defer s.observe(ctx, "GetPasswordResetForUpdate", time.Now())
+	return s.store.GetPasswordResetForUpdate(ctx, tokenHash)
+}
+
+func (s *slowQueryStore) GetScheduledTransfer(ctx context.Context, id int64) (GetScheduledTransferRow, error) {
+	defer s.observe(ctx, "GetScheduledTransfer", time.Now())
+	return s.store.GetScheduledTransfer(ctx, id)
+}
+
+func (s *slowQueryStore) GetSession(ctx context.Context, id uuid.UUID) (Session, error) {
+	defer s.observe(ctx, "GetSession", time.Now())
+	return s.store.GetSession(ctx, id)
+}
+
+func (s *slowQueryStore) GetTotpSecret(ctx context.Context, username string) (TotpSecret, error) {
+	defer s.observe(ctx, "GetTotpSecret", time.Now())
+	return s.store.GetTotpSecret(ctx, username)
+}
+
+func (s *slowQueryStore) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	defer s.observe(ctx, "GetTransfer", time.Now())
+	return s.store.GetTransfer(ctx, id)
+}
+
+func (s *slowQueryStore) GetTransferByUUID(ctx context.Context, uuid uuid.UUID) (Transfer, error) {
+	defer s.observe(ctx, "GetTransferByUUID", time.Now())
+	return s.store.GetTransferByUUID(ctx, uuid)
+}
+
+func (s *slowQueryStore) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	defer s.observe(ctx, "GetTransferForUpdate", time.Now())
+	return s.store.GetTransferForUpdate(ctx, id)
+}
+
+func (s *slowQueryStore) GetUser(ctx context.Context, username string) (User, error) {
+	defer s.observe(ctx, "GetUser", time.Now())
+	return s.store.GetUser(ctx, username)
+}
+
+func (s *slowQueryStore) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	defer s.observe(ctx, "GetUserByEmail", time.Now())
+	return s.store.GetUserByEmail(ctx, email)
+}
+
+func (s *slowQueryStore) GetUserForUpdate(ctx context.Context, username string) (User, error) {
+	defer s.observe(ctx, "GetUserForUpdate", time.Now())
+	return s.store.GetUserForUpdate(ctx, username)
+}
+
+func (s *slowQueryStore) GetUserPasswordChangedAt(ctx context.Context, username string) (time.Time, error) {
+	defer s.observe(ctx, "GetUserPasswordChangedAt", time.Now())
+	return s.store.GetUserPasswordChangedAt(ctx, username)
+}
+
+func (s *slowQueryStore) IdempotentCreateAccountTx(ctx context.Context, params IdempotentCreateAccountTxParams) (IdempotentCreateAccountTxResult, error) {
+	defer s.observe(ctx, "IdempotentCreateAccountTx", time.Now())
+	return s.store.IdempotentCreateAccountTx(ctx, params)
+}
+
+func (s *slowQueryStore) IdempotentTransferTx(ctx context.Context, params IdempotentTransferTxParams) (IdempotentTransferTxResult, error) {
+	defer s.observe(ctx, "IdempotentTransferTx", time.Now())
+	return s.store.IdempotentTransferTx(ctx, params)
+}
+
+func (s *slowQueryStore) ImportUsersTx(ctx context.Context, params ImportUsersTxParams) (ImportUsersTxResult, error) {
+	defer s.observe(ctx, "ImportUsersTx", time.Now())
+	return s.store.ImportUsersTx(ctx, params)
+}
+
+func (s *slowQueryStore) ListAccountOwnerChanges(ctx context.Context, accountID int64) ([]AccountOwnerChange, error) {
+	defer s.observe(ctx, "ListAccountOwnerChanges", time.Now())
+	return s.store.ListAccountOwnerChanges(ctx, accountID)
+}
+
+func (s *slowQueryStore) ListAccountStatement(ctx context.Context, arg ListAccountStatementParams) ([]ListAccountStatementRow, error) {
+	defer s.observe(ctx, "ListAccountStatement", time.Now())
+	return s.store.ListAccountStatement(ctx, arg)
+}
+
+func (s *slowQueryStore) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	defer s.observe(ctx, "ListAccounts", time.Now())
+	return s.store.ListAccounts(ctx, arg)
+}
+
+func (s *slowQueryStore) ListAccountsAfterNumber(ctx context.Context, arg ListAccountsAfterNumberParams) ([]Account, error) {
+	defer s.observe(ctx, "ListAccountsAfterNumber", time.Now())
+	return s.store.ListAccountsAfterNumber(ctx, arg)
+}
+
+func (s *slowQueryStore) ListAccountsByCurrency(ctx context.Context, arg ListAccountsByCurrencyParams) ([]Account, error) {
+	defer s.observe(ctx, "ListAccountsByCurrency", time.Now())
+	return s.store.ListAccountsByCurrency(ctx, arg)
+}
+
+func (s *slowQueryStore) ListAllAccounts(ctx context.Context, arg ListAllAccountsParams) ([]Account, error) {
+	defer s.observe(ctx, "ListAllAccounts", time.Now())
+	return s.store.ListAllAccounts(ctx, arg)
+}
+
+func (s *slowQueryStore) ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error) {
+	defer s.observe(ctx, "ListAuditLogs", time.Now())
+	return s.store.ListAuditLogs(ctx, arg)
+}
+
+func (s *slowQueryStore) ListBalanceSnapshots(ctx context.Context, arg ListBalanceSnapshotsParams) ([]BalanceSnapshot, error) {
+	defer s.observe(ctx, "ListBalanceSnapshots", time.Now())
+	return s.store.ListBalanceSnapshots(ctx, arg)
+}
+
+func (s *slowQueryStore) ListDueScheduledTransfers(ctx context.Context, arg ListDueScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	defer s.observe(ctx, "ListDueScheduledTransfers", time.Now())
+	return s.store.ListDueScheduledTransfers(ctx, arg)
+}
+
+func (s *slowQueryStore) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	defer s.observe(ctx, "ListEntries", time.Now())
+	return s.store.ListEntries(ctx, arg)
+}
+
+func (s *slowQueryStore) ListEntriesByAccount(ctx context.Context, arg ListEntriesByAccountParams) ([]Entry, error) {
+	defer s.observe(ctx, "ListEntriesByAccount", time.Now())
+	return s.store.ListEntriesByAccount(ctx, arg)
+}
+
+func (s *slowQueryStore) ListEntriesByAccountInRange(ctx context.Context, arg ListEntriesByAccountInRangeParams) ([]Entry, error) {
+	defer s.observe(ctx, "ListEntriesByAccountInRange", time.Now())
+	return s.store.ListEntriesByAccountInRange(ctx, arg)
+}
+
+func (s *slowQueryStore) ListExpiredHolds(ctx context.Context, arg ListExpiredHoldsParams) ([]Hold, error) {
+	defer s.observe(ctx, "ListExpiredHolds", time.Now())
+	return s.store.ListExpiredHolds(ctx, arg)
+}
+
+func (s *slowQueryStore) ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ListScheduledTransfersRow, error) {
+	defer s.observe(ctx, "ListScheduledTransfers", time.Now())
+	return s.store.ListScheduledTransfers(ctx, arg)
+}
+
+func (s *slowQueryStore) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	defer s.observe(ctx, "ListTransfers", time.Now())
+	return s.store.ListTransfers(ctx, arg)
+}
+
+func (s *slowQueryStore) ListUnpublishedOutboxEvents(ctx context.Context, limit int32) ([]Outbox, error) {
+	defer s.observe(ctx, "ListUnpublishedOutboxEvents", time.Now())
+	return s.store.ListUnpublishedOutboxEvents(ctx, limit)
+}
+
+func (s *slowQueryStore) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	defer s.observe(ctx, "ListUsers", time.Now())
+	return s.store.ListUsers(ctx, arg)
+}
+
+func (s *slowQueryStore) LoginTx(ctx context.Context, params LoginTxParams) (Session, error) {
+	defer s.observe(ctx, "LoginTx", time.Now())
+	return s.store.LoginTx(ctx, params)
+}
+
+func (s *slowQueryStore) LookupAccounts(ctx context.Context, ids []int64) (map[int64]Account, error) {
+	defer s.observe(ctx, "LookupAccounts", time.Now())
+	return s.store.LookupAccounts(ctx, ids)
+}
+
+func (s *slowQueryStore) LookupAccountsByNumber(ctx context.Context, accountNumbers []string) (map[string]Account, error) {
+	defer s.observe(ctx, "LookupAccountsByNumber", time.Now())
+	return s.store.LookupAccountsByNumber(ctx, accountNumbers)
+}
+
+func (s *slowQueryStore) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	defer s.observe(ctx, "MarkOutboxEventPublished", time.Now())
+	return s.store.MarkOutboxEventPublished(ctx, id)
+}
+
+func (s *slowQueryStore) MarkPasswordResetUsed(ctx context.Context, id int64) (PasswordReset, error) {
+	defer s.observe(ctx, "MarkPasswordResetUsed", time.Now())
+	return s.store.MarkPasswordResetUsed(ctx, id)
+}
+
+func (s *slowQueryStore) MarkTransferReversed(ctx context.Context, id int64) (Transfer, error) {
+	defer s.observe(ctx, "MarkTransferReversed", time.Now())
+	return s.store.MarkTransferReversed(ctx, id)
+}
+
+func (s *slowQueryStore) Ping(ctx context.Context) error {
+	defer s.observe(ctx, "Ping", time.Now())
+	return s.store.Ping(ctx)
+}
+
+func (s *slowQueryStore) PublishOutbox(ctx context.Context, limit int32, publish func(context.Context, Outbox) error) (int, error) {
+	defer s.observe(ctx, "PublishOutbox", time.Now())
+	return s.store.PublishOutbox(ctx, limit, publish)
+}
+
+func (s *slowQueryStore) RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (User, error) {
+	defer s.observe(ctx, "RecordFailedLogin", time.Now())
+	return s.store.RecordFailedLogin(ctx, arg)
+}
+
+func (s *slowQueryStore) RecordScheduledTransferFailure(ctx context.Context, arg RecordScheduledTransferFailureParams) (ScheduledTransfer, error) {
+	defer s.observe(ctx, "RecordScheduledTransferFailure", time.Now())
+	return s.store.RecordScheduledTransferFailure(ctx, arg)
+}
+
+func (s *slowQueryStore) RecordScheduledTransferRun(ctx context.Context, arg RecordScheduledTransferRunParams) (ScheduledTransfer, error) {
+	defer s.observe(ctx, "RecordScheduledTransferRun", time.Now())
+	return s.store.RecordScheduledTransferRun(ctx, arg)
+}
+
+func (s *slowQueryStore) ResetFailedLogins(ctx context.Context, username string) error {
+	defer s.observe(ctx, "ResetFailedLogins", time.Now())
+	return s.store.ResetFailedLogins(ctx, username)
+}
+
+func (s *slowQueryStore) RestoreAccount(ctx context.Context, arg RestoreAccountParams) (Account, error) {
+	defer s.observe(ctx, "RestoreAccount", time.Now())
+	return s.store.RestoreAccount(ctx, arg)
+}
+
+func (s *slowQueryStore) ReverseTransferTx(ctx context.Context, params ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	defer s.observe(ctx, "ReverseTransferTx", time.Now())
+	return s.store.ReverseTransferTx(ctx, params)
+}
+
+func (s *slowQueryStore) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error) {
+	defer s.observe(ctx, "SearchUsers", time.Now())
+	return s.store.SearchUsers(ctx, arg)
+}
+
+func (s *slowQueryStore) SetAccountFrozen(ctx context.Context, arg SetAccountFrozenParams) (Account, error) {
+	defer s.observe(ctx, "SetAccountFrozen", time.Now())
+	return s.store.SetAccountFrozen(ctx, arg)
+}
+
+func (s *slowQueryStore) SetAccountFrozenTx(ctx context.Context, params SetAccountFrozenTxParams) (Account, error) {
+	defer s.observe(ctx, "SetAccountFrozenTx", time.Now())
+	return s.store.SetAccountFrozenTx(ctx, params)
+}
+
+func (s *slowQueryStore) SetAccountLabels(ctx context.Context, arg SetAccountLabelsParams) (Account, error) {
+	defer s.observe(ctx, "SetAccountLabels", time.Now())
+	return s.store.SetAccountLabels(ctx, arg)
+}
+
+func (s *slowQueryStore) SetAccountLowBalanceThreshold(ctx context.Context, arg SetAccountLowBalanceThresholdParams) (Account, error) {
+	defer s.observe(ctx, "SetAccountLowBalanceThreshold", time.Now())
+	return s.store.SetAccountLowBalanceThreshold(ctx, arg)
+}
+
+func (s *slowQueryStore) SetAccountSpendingLimits(ctx context.Context, arg SetAccountSpendingLimitsParams) (Account, error) {
+	defer s.observe(ctx, "SetAccountSpendingLimits", time.Now())
+	return s.store.SetAccountSpendingLimits(ctx, arg)
+}
+
+func (s *slowQueryStore) SoftDeleteAccount(ctx context.Context, id int64) (int64, error) {
+	defer s.observe(ctx, "SoftDeleteAccount", time.Now())
+	return s.store.SoftDeleteAccount(ctx, id)
+}
+
+func (s *slowQueryStore) SumOutgoingTransfers(ctx context.Context, arg SumOutgoingTransfersParams) (int64, error) {
+	defer s.observe(ctx, "SumOutgoingTransfers", time.Now())
+	return s.store.SumOutgoingTransfers(ctx, arg)
+}
+
+func (s *slowQueryStore) SplitTransferTx(ctx context.Context, params SplitTransferTxParams) (SplitTransferTxResult, error) {
+	defer s.observe(ctx, "SplitTransferTx", time.Now())
+	return s.store.SplitTransferTx(ctx, params)
+}
+
+func (s *slowQueryStore) StreamAccountStatement(ctx context.Context, arg ListAccountStatementParams, fn func(ListAccountStatementRow) error) error {
+	defer s.observe(ctx, "StreamAccountStatement", time.Now())
+	return s.store.StreamAccountStatement(ctx, arg, fn)
+}
+
+func (s *slowQueryStore) TransferAccountOwnershipTx(ctx context.Context, params TransferAccountOwnershipTxParams) (TransferAccountOwnershipTxResult, error) {
+	defer s.observe(ctx, "TransferAccountOwnershipTx", time.Now())
+	return s.store.TransferAccountOwnershipTx(ctx, params)
+}
+
+func (s *slowQueryStore) TransferTx(ctx context.Context, params TransferTxParams) (TransferTxResult, error) {
+	defer s.observe(ctx, "TransferTx", time.Now())
+	return s.store.TransferTx(ctx, params)
+}
+
+func (s *slowQueryStore) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	defer s.observe(ctx, "UpdateAccount", time.Now())
+	return s.store.UpdateAccount(ctx, arg)
+}
+
+func (s *slowQueryStore) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error) {
+	defer s.observe(ctx, "UpdateAccountBalance", time.Now())
+	return s.store.UpdateAccountBalance(ctx, arg)
+}
+
+func (s *slowQueryStore) UpdateAccountOwner(ctx context.Context, arg UpdateAccountOwnerParams) (Account, error) {
+	defer s.observe(ctx, "UpdateAccountOwner", time.Now())
+	return s.store.UpdateAccountOwner(ctx, arg)
+}
+
+func (s *slowQueryStore) UpdateHoldStatus(ctx context.Context, arg UpdateHoldStatusParams) (Hold, error) {
+	defer s.observe(ctx, "UpdateHoldStatus", time.Now())
+	return s.store.UpdateHoldStatus(ctx, arg)
+}
+
+func (s *slowQueryStore) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	defer s.observe(ctx, "UpdateUser", time.Now())
+	return s.store.UpdateUser(ctx, arg)
+}
+
+func (s *slowQueryStore) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	defer s.observe(ctx, "UpdateUserPassword", time.Now())
+	return s.store.UpdateUserPassword(ctx, arg)
+}
+
+func (s *slowQueryStore) UpsertNotificationPreferences(ctx context.Context, arg UpsertNotificationPreferencesParams) (NotificationPreference, error) {
+	defer s.observe(ctx, "UpsertNotificationPreferences", time.Now())
+	return s.store.UpsertNotificationPreferences(ctx, arg)
+}
+
+func (s *slowQueryStore) UpsertTotpSecret(ctx context.Context, arg UpsertTotpSecretParams) (TotpSecret, error) {
+	defer s.observe(ctx, "UpsertTotpSecret", time.Now())
+	return s.store.UpsertTotpSecret(ctx, arg)
+}
+
+func (s *slowQueryStore) VoidHoldTx(ctx context.Context, params VoidHoldTxParams) (VoidHoldTxResult, error) {
+	defer s.observe(ctx, "VoidHoldTx", time.Now())
+	return s.store.VoidHoldTx(ctx, params)
+}
@@ -0,0 +1,123 @@
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+const (
+	testSlowQueryThreshold = 20 * time.Millisecond
+	testRequestID          = "slow-request"
+)
+
+func TestSlowQueryStore(t *testing.T) {
+	account := db.Account{
+		ID:       utils.RandomInt(1, 1000),
+		Owner:    utils.RandomOwner(),
+		Balance:  utils.RandomBalance(),
+		Currency: utils.USD,
+	}
+
+	// sleepingGetAccount answers GetAccount after the given delay
+	sleepingGetAccount := func(delay time.Duration, err error) func(ctx context.Context, id int64) (db.Account, error) {
+		return func(ctx context.Context, id int64) (db.Account, error) {
+			time.Sleep(delay)
+			return account, err
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkLogs  func(t *testing.T, logs *bytes.Buffer, err error)
+	}{
+		{
+			name: "slow query is logged",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).
+					DoAndReturn(sleepingGetAccount(2*testSlowQueryThreshold, nil))
+			},
+			checkLogs: func(t *testing.T, logs *bytes.Buffer, err error) {
+				require.NoError(t, err)
+
+				var entry struct {
+					Level     string  `json:"level"`
+					Method    string  `json:"method"`
+					RequestID string  `json:"request_id"`
+					Duration  float64 `json:"duration"`
+					Message   string  `json:"message"`
+				}
+				require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+				require.Equal(t, zerolog.LevelWarnValue, entry.Level)
+				require.Equal(t, "GetAccount", entry.Method)
+				require.Equal(t, testRequestID, entry.RequestID)
+				require.Equal(t, "slow db query", entry.Message)
+				// zerolog writes durations in milliseconds
+				require.GreaterOrEqual(t, entry.Duration, float64(2*testSlowQueryThreshold/time.Millisecond))
+			},
+		},
+		{
+			name: "failing slow query is logged",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).
+					DoAndReturn(sleepingGetAccount(2*testSlowQueryThreshold, sql.ErrConnDone))
+			},
+			checkLogs: func(t *testing.T, logs *bytes.Buffer, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+				require.Contains(t, logs.String(), `"method":"GetAccount"`)
+			},
+		},
+		{
+			name: "fast query isn't logged",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkLogs: func(t *testing.T, logs *bytes.Buffer, err error) {
+				require.NoError(t, err)
+				require.Empty(t, logs.String())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockStore := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(mockStore)
+
+			var logs bytes.Buffer
+			store := db.NewSlowQueryStore(mockStore, testSlowQueryThreshold, zerolog.New(&logs))
+
+			ctx := utils.ContextWithRequestID(context.Background(), testRequestID)
+			rsp, err := store.GetAccount(ctx, account.ID)
+			if err == nil {
+				require.Equal(t, account, rsp)
+			}
+			tc.checkLogs(t, &logs, err)
+		})
+	}
+}
+
+func TestSlowQueryStoreDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockStore := mockdb.NewMockStore(ctrl)
+
+	// without a threshold there is nothing to time, the store is returned as is
+	store := db.NewSlowQueryStore(mockStore, 0, zerolog.Nop())
+	require.Equal(t, db.Store(mockStore), store)
+}
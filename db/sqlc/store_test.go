@@ -2,16 +2,22 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/micaelapucciariello/simplebank/utils"
 	"github.com/stretchr/testify/require"
+	"math/big"
 	"testing"
+	"time"
 )
 
 func TestTxStore(t *testing.T) {
 	store := NewStore(testDB)
 
-	account1 := CreateRandomAccount(t)
-	account2 := CreateRandomAccount(t)
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
 
 	fmt.Println(">> before transfer:", account1.Balance, account2.Balance)
 	amount := int64(10)
@@ -53,8 +59,10 @@ func TestTxStore(t *testing.T) {
 		require.Equal(t, account1.ID, transfer.FromAccountID)
 		require.Equal(t, account2.ID, transfer.ToAccountID)
 		require.Equal(t, amount, transfer.Amount)
+		require.Equal(t, amount, transfer.ToAmount)
 
 		require.NotZero(t, transfer.ID)
+		require.NotZero(t, transfer.UUID)
 		require.NotZero(t, transfer.CreatedAt)
 
 		// validate get transfer
@@ -133,8 +141,8 @@ func TestTxStore(t *testing.T) {
 func TestTxStoreDeadlock(t *testing.T) {
 	store := NewStore(testDB)
 
-	account1 := CreateRandomAccount(t)
-	account2 := CreateRandomAccount(t)
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
 
 	fmt.Println(">> before transfer:", account1.Balance, account2.Balance)
 	amount := int64(10)
@@ -188,3 +196,1790 @@ func TestTxStoreDeadlock(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+func TestTxStoreDeadlockRing(t *testing.T) {
+	store := NewStore(testDB)
+
+	accounts := []Account{
+		createAccountInCurrency(t, utils.USD, 1000),
+		createAccountInCurrency(t, utils.USD, 1000),
+		createAccountInCurrency(t, utils.USD, 1000),
+	}
+	amount := int64(10)
+
+	// every account sends to the next one and receives from the previous one, in both directions
+	// without a global locking order the ring can deadlock
+	rounds := 5
+	n := rounds * len(accounts) * 2
+
+	errs := make(chan error)
+
+	for r := 0; r < rounds; r++ {
+		for i := range accounts {
+			from := accounts[i]
+			to := accounts[(i+1)%len(accounts)]
+
+			for _, params := range []TransferTxParams{
+				{FromAccountID: from.ID, ToAccountID: to.ID, Amount: amount},
+				{FromAccountID: to.ID, ToAccountID: from.ID, Amount: amount},
+			} {
+				params := params
+				go func() {
+					_, err := store.TransferTx(context.Background(), params)
+					errs <- err
+				}()
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+	}
+
+	// every account sent and received the same amount, so the balances are unchanged
+	for _, account := range accounts {
+		updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updatedAccount.Balance)
+	}
+}
+
+func TestExecTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := CreateRandomAccount(t)
+	amount := int64(10)
+
+	// an account opened with an initial deposit, both writes are committed together
+	err := store.ExecTx(context.Background(), func(q Querier) error {
+		_, err := q.UpdateAccountBalance(context.Background(), UpdateAccountBalanceParams{ID: account.ID, Amount: amount})
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateEntry(context.Background(), CreateEntryParams{AccountID: account.ID, Amount: amount})
+		return err
+	})
+	require.NoError(t, err)
+
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+amount, updatedAccount.Balance)
+
+	entries, err := store.CountEntriesByAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), entries)
+}
+
+func TestExecTxRollback(t *testing.T) {
+	store := NewStore(testDB)
+	account := CreateRandomAccount(t)
+	errFailed := errors.New("failed")
+
+	err := store.ExecTx(context.Background(), func(q Querier) error {
+		_, err := q.UpdateAccountBalance(context.Background(), UpdateAccountBalanceParams{ID: account.ID, Amount: 10})
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateEntry(context.Background(), CreateEntryParams{AccountID: account.ID, Amount: 10})
+		if err != nil {
+			return err
+		}
+		return errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+
+	// neither the balance update nor the entry made it
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, updatedAccount.Balance)
+	require.Equal(t, account.Version, updatedAccount.Version)
+
+	entries, err := store.CountEntriesByAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Zero(t, entries)
+}
+
+func TestAddAccountBalanceTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := CreateRandomAccount(t)
+	amount := int64(10)
+
+	n := 10
+
+	errs := make(chan error)
+
+	// every increment locks the account row, so none of them can be lost
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+				AccountID: account.ID,
+				Amount:    amount,
+			})
+
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+	}
+
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+int64(n)*amount, updatedAccount.Balance)
+
+	// every increment is recorded by its entry
+	total, err := store.CountEntriesByAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(n), total)
+}
+
+func TestAddAccountBalanceTxInsufficientBalance(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := CreateRandomAccount(t)
+
+	_, err := store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+		AccountID: account.ID,
+		Amount:    -(account.Balance + 1),
+	})
+	require.ErrorIs(t, err, ErrInsufficientBalance)
+
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, updatedAccount.Balance)
+}
+
+func TestAddAccountBalanceTxFrozenAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := CreateRandomAccount(t)
+	_, err := testQueries.SetAccountFrozen(context.Background(), SetAccountFrozenParams{ID: account.ID, IsFrozen: true, OrgID: DefaultOrgID})
+	require.NoError(t, err)
+
+	// neither the balance updates nor the withdrawals get past the freeze
+	_, err = store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+		AccountID: account.ID,
+		Amount:    10,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.EntryTx(context.Background(), EntryTxParams{
+		AccountID: account.ID,
+		Amount:    -1,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, updatedAccount.Balance)
+}
+
+func TestAddAccountBalanceTxVersionConflict(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := CreateRandomAccount(t)
+	amount := int64(10)
+
+	// both updates read the account at the same version, only the first one to lock it is applied
+	n := 2
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+				AccountID:       account.ID,
+				Amount:          amount,
+				ExpectedVersion: account.Version,
+			})
+			errs <- err
+		}()
+	}
+
+	conflicts := 0
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			require.ErrorIs(t, err, ErrVersionConflict)
+			conflicts++
+		}
+	}
+	require.Equal(t, 1, conflicts)
+
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+amount, updatedAccount.Balance)
+	require.Equal(t, account.Version+1, updatedAccount.Version)
+
+	// a write based on the new version goes through
+	_, err = store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+		AccountID:       account.ID,
+		Amount:          amount,
+		ExpectedVersion: updatedAccount.Version,
+	})
+	require.NoError(t, err)
+}
+
+func TestTransferTxDailyLimit(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+	limit := int64(100)
+
+	params := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        60,
+		DailyLimit:    limit,
+	}
+	result, err := store.TransferTx(context.Background(), params)
+	require.NoError(t, err)
+
+	// the second transfer would take the day total to 120
+	_, err = store.TransferTx(context.Background(), params)
+	require.ErrorIs(t, err, ErrDailyTransferLimit)
+
+	// the rejected transfer is rolled back along with its share of the total
+	total, err := store.GetDailyTransferTotal(context.Background(), account1.Owner)
+	require.NoError(t, err)
+	require.Equal(t, params.Amount, total)
+
+	updatedAccount, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.FromAccountID.Balance, updatedAccount.Balance)
+
+	params.Amount = limit - params.Amount
+	_, err = store.TransferTx(context.Background(), params)
+	require.NoError(t, err)
+
+	// the receiver is limited on what it sends, not on what it receives
+	_, err = store.GetDailyTransferTotal(context.Background(), account2.Owner)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestTransferTxDailyLimitConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+	amount := int64(10)
+
+	n := 10
+	errs := make(chan error)
+
+	// the limit leaves room for half of the transfers, whatever order they commit in
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+				DailyLimit:    int64(n/2) * amount,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrDailyTransferLimit)
+	}
+	require.Equal(t, n/2, succeeded)
+
+	total, err := store.GetDailyTransferTotal(context.Background(), account1.Owner)
+	require.NoError(t, err)
+	require.Equal(t, int64(n/2)*amount, total)
+}
+
+func TestTransferTxAccountSpendingLimit(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+
+	_, err := testQueries.SetAccountSpendingLimits(context.Background(), SetAccountSpendingLimitsParams{
+		ID:         account1.ID,
+		DailyLimit: 100,
+	})
+	require.NoError(t, err)
+	// the receiver limit doesn't apply to what it receives, nor to the reversal below
+	_, err = testQueries.SetAccountSpendingLimits(context.Background(), SetAccountSpendingLimitsParams{
+		ID:         account2.ID,
+		DailyLimit: 1,
+	})
+	require.NoError(t, err)
+
+	params := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        60,
+	}
+	result, err := store.TransferTx(context.Background(), params)
+	require.NoError(t, err)
+
+	// the second transfer would take the day total to 120
+	_, err = store.TransferTx(context.Background(), params)
+	require.ErrorIs(t, err, ErrAccountSpendingLimit)
+
+	updatedAccount, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.FromAccountID.Balance, updatedAccount.Balance)
+
+	params.Amount = 40
+	_, err = store.TransferTx(context.Background(), params)
+	require.NoError(t, err)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: result.Transfer.ID})
+	require.NoError(t, err)
+}
+
+func TestTransferTxAccountMonthlySpendingLimit(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+
+	_, err := testQueries.SetAccountSpendingLimits(context.Background(), SetAccountSpendingLimitsParams{
+		ID:           account1.ID,
+		MonthlyLimit: 100,
+	})
+	require.NoError(t, err)
+
+	params := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        60,
+	}
+	_, err = store.TransferTx(context.Background(), params)
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), params)
+	require.ErrorIs(t, err, ErrAccountSpendingLimit)
+
+	// the closing sweep isn't limited, the account has to be emptied
+	params.SkipAccountLimits = true
+	_, err = store.TransferTx(context.Background(), params)
+	require.NoError(t, err)
+
+	total, err := testQueries.SumOutgoingTransfers(context.Background(), SumOutgoingTransfersParams{
+		FromAccountID: account1.ID,
+		Since:         time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2*params.Amount, total)
+}
+
+func TestTransferTxFrozenAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+	frozen := createAccountInCurrency(t, utils.USD, 1000)
+	amount := int64(10)
+
+	_, err := store.SetAccountFrozen(context.Background(), SetAccountFrozenParams{ID: frozen.ID, IsFrozen: true, OrgID: DefaultOrgID})
+	require.NoError(t, err)
+
+	// a frozen account can neither send nor receive
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: frozen.ID,
+		ToAccountID:   account1.ID,
+		Amount:        amount,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   frozen.ID,
+		Amount:        amount,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	// the rejected transfers left no trace
+	for _, account := range []Account{account1, frozen} {
+		updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updatedAccount.Balance)
+
+		total, err := store.CountEntriesByAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Zero(t, total)
+	}
+
+	// the other accounts keep transferring
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-amount, result.FromAccountID.Balance)
+
+	unfrozen, err := store.SetAccountFrozen(context.Background(), SetAccountFrozenParams{ID: frozen.ID, IsFrozen: false, OrgID: DefaultOrgID})
+	require.NoError(t, err)
+	require.False(t, unfrozen.IsFrozen)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   frozen.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+}
+
+func TestTransferTxExchangeRate(t *testing.T) {
+	store := NewStoreWithExchangeRates(testDB, StaticExchangeRates{
+		{From: utils.USD, To: utils.EUR}: big.NewRat(92, 100),
+	})
+
+	usd := createAccountInCurrency(t, utils.USD, 1000)
+	eur := createAccountInCurrency(t, utils.EUR, 1000)
+
+	// 250 USD at 0.92 credits 230 EUR
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: usd.ID,
+		ToAccountID:   eur.ID,
+		Amount:        250,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(250), result.Transfer.Amount)
+	require.Equal(t, int64(230), result.Transfer.ToAmount)
+	require.Equal(t, int64(-250), result.FromEntry.Amount)
+	require.Equal(t, int64(230), result.ToEntry.Amount)
+	require.Equal(t, int64(750), result.FromAccountID.Balance)
+	require.Equal(t, int64(1230), result.ToAccountID.Balance)
+
+	got, err := store.GetTransfer(context.Background(), result.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(230), got.ToAmount)
+
+	// the rates table only goes from USD to EUR
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: eur.ID,
+		ToAccountID:   usd.ID,
+		Amount:        100,
+	})
+	require.ErrorIs(t, err, ErrNoExchangeRate)
+
+	// the reversal gives back the credited euros and returns the original dollars
+	reversal, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: result.Transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, int64(230), reversal.Transfer.Amount)
+	require.Equal(t, int64(250), reversal.Transfer.ToAmount)
+	require.Equal(t, usd.Balance, reversal.ToAccountID.Balance)
+	require.Equal(t, eur.Balance, reversal.FromAccountID.Balance)
+
+	// without rates the currencies must match
+	_, err = NewStore(testDB).TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: usd.ID,
+		ToAccountID:   eur.ID,
+		Amount:        100,
+	})
+	require.ErrorIs(t, err, ErrNoExchangeRate)
+}
+
+func TestTransferTxDryRun(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 100)
+	account2 := createAccountInCurrency(t, utils.USD, 100)
+
+	// the preview has the balances the transfer would leave
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        60,
+		DryRun:        true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(40), result.FromAccountID.Balance)
+	require.Equal(t, int64(160), result.ToAccountID.Balance)
+
+	// a second dry run can't overspend, the first one never happened
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        101,
+		DryRun:        true,
+	})
+	require.ErrorIs(t, err, ErrInsufficientBalance)
+
+	for _, account := range []Account{account1, account2} {
+		updated, err := store.GetAccount(context.Background(), account.ID)
+		require.NoError(t, err)
+		require.Equal(t, account.Balance, updated.Balance)
+
+		entries, err := store.ListEntriesByAccount(context.Background(), ListEntriesByAccountParams{
+			AccountID: account.ID,
+			Limit:     5,
+		})
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	}
+
+	_, err = store.GetTransfer(context.Background(), result.Transfer.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestTransferTxInsufficientBalance(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 50)
+	account2 := createAccountInCurrency(t, utils.USD, 50)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        51,
+	})
+	require.ErrorIs(t, err, ErrInsufficientBalance)
+
+	// the whole balance can be sent
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        50,
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.FromAccountID.Balance)
+}
+
+func TestEntryTxConcurrentDeposits(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := CreateRandomAccount(t)
+	amount := int64(10)
+
+	n := 10
+
+	errs := make(chan error)
+	results := make(chan EntryTxResult)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := store.EntryTx(context.Background(), EntryTxParams{
+				AccountID: account.ID,
+				Amount:    amount,
+			})
+
+			errs <- err
+			results <- result
+		}()
+	}
+
+	// every deposit sees the balance left by the previous one, so the balances after them are all distinct
+	balances := make(map[int64]bool)
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+
+		result := <-results
+		require.Equal(t, account.ID, result.Entry.AccountID)
+		require.Equal(t, amount, result.Entry.Amount)
+		require.NotZero(t, result.Entry.ID)
+
+		k := (result.Account.Balance - account.Balance) / amount
+		require.True(t, k >= 1 && k <= int64(n))
+		require.NotContains(t, balances, result.Account.Balance)
+		balances[result.Account.Balance] = true
+	}
+
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+int64(n)*amount, updatedAccount.Balance)
+
+	total, err := store.CountEntriesByAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(n), total)
+}
+
+func TestEntryTxOverdraft(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := CreateRandomAccount(t)
+
+	_, err := store.EntryTx(context.Background(), EntryTxParams{
+		AccountID: account.ID,
+		Amount:    -(account.Balance + 1),
+	})
+	require.ErrorIs(t, err, ErrInsufficientBalance)
+
+	// neither the balance nor the entries are touched
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, updatedAccount.Balance)
+
+	total, err := store.CountEntriesByAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	result, err := store.EntryTx(context.Background(), EntryTxParams{
+		AccountID: account.ID,
+		Amount:    -account.Balance,
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.Account.Balance)
+}
+
+func TestIdempotentTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+	amount := int64(10)
+
+	params := IdempotentTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+		},
+		Username:       account1.Owner,
+		IdempotencyKey: utils.RandomString(16),
+		RequestHash:    utils.RandomString(32),
+	}
+
+	n := 2
+
+	errs := make(chan error)
+	results := make(chan IdempotentTransferTxResult)
+
+	// both requests share the key, so only one of them can move the money
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := store.IdempotentTransferTx(context.Background(), params)
+
+			errs <- err
+			results <- result
+		}()
+	}
+
+	var transferIDs []int64
+	replayed := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		require.NoError(t, err)
+
+		result := <-results
+		require.NotZero(t, result.Transfer.ID)
+		transferIDs = append(transferIDs, result.Transfer.ID)
+		if result.Replayed {
+			replayed++
+		}
+	}
+
+	require.Equal(t, transferIDs[0], transferIDs[1])
+	require.Equal(t, n-1, replayed)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-amount, updatedAccount1.Balance)
+
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance+amount, updatedAccount2.Balance)
+}
+
+func TestIdempotentTransferTxMismatch(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+
+	params := IdempotentTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+		},
+		Username:       account1.Owner,
+		IdempotencyKey: utils.RandomString(16),
+		RequestHash:    utils.RandomString(32),
+	}
+
+	result, err := store.IdempotentTransferTx(context.Background(), params)
+	require.NoError(t, err)
+	require.False(t, result.Replayed)
+
+	params.Amount = 20
+	params.RequestHash = utils.RandomString(32)
+
+	_, err = store.IdempotentTransferTx(context.Background(), params)
+	require.ErrorIs(t, err, ErrIdempotencyKeyMismatch)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-10, updatedAccount1.Balance)
+}
+
+func TestIdempotentCreateAccountTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := CreateRandomUser(t)
+	params := IdempotentCreateAccountTxParams{
+		CreateAccountParams: CreateAccountParams{
+			Owner:    user.Username,
+			Currency: utils.USD,
+			OrgID:    DefaultOrgID,
+		},
+		IdempotencyKey: utils.RandomString(16),
+		RequestHash:    utils.RandomString(32),
+	}
+
+	first, err := store.IdempotentCreateAccountTx(context.Background(), params)
+	require.NoError(t, err)
+	require.False(t, first.Replayed)
+	require.Equal(t, user.Username, first.Account.Owner)
+
+	// the retry gets the same account back instead of failing on the currency constraint
+	retry, err := store.IdempotentCreateAccountTx(context.Background(), params)
+	require.NoError(t, err)
+	require.True(t, retry.Replayed)
+	require.Equal(t, first.Account.ID, retry.Account.ID)
+
+	params.Currency = utils.EUR
+	params.RequestHash = utils.RandomString(32)
+	_, err = store.IdempotentCreateAccountTx(context.Background(), params)
+	require.ErrorIs(t, err, ErrIdempotencyKeyMismatch)
+
+	// the key is scoped to its owner
+	other := CreateRandomUser(t)
+	params.Owner = other.Username
+	result, err := store.IdempotentCreateAccountTx(context.Background(), params)
+	require.NoError(t, err)
+	require.False(t, result.Replayed)
+	require.NotEqual(t, first.Account.ID, result.Account.ID)
+}
+
+func TestIdempotentCreateAccountTxInitialBalance(t *testing.T) {
+	store := NewStore(testDB)
+	user := CreateRandomUser(t)
+	balance := utils.RandomBalance()
+
+	result, err := store.IdempotentCreateAccountTx(context.Background(), IdempotentCreateAccountTxParams{
+		CreateAccountParams: CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  balance,
+			Currency: utils.USD,
+			OrgID:    DefaultOrgID,
+		},
+		IdempotencyKey: utils.RandomString(16),
+		RequestHash:    utils.RandomString(32),
+	})
+	require.NoError(t, err)
+	require.Equal(t, balance, result.Account.Balance)
+
+	// the balance is recorded by the opening entry
+	entries, err := store.ListEntriesByAccount(context.Background(), ListEntriesByAccountParams{
+		AccountID: result.Account.ID,
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, balance, entries[0].Amount)
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+	amount := int64(10)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.NoError(t, err)
+
+	require.Equal(t, account2.ID, result.Transfer.FromAccountID)
+	require.Equal(t, account1.ID, result.Transfer.ToAccountID)
+	require.Equal(t, amount, result.Transfer.Amount)
+	require.True(t, result.Transfer.ReversedFrom.Valid)
+	require.Equal(t, original.Transfer.ID, result.Transfer.ReversedFrom.Int64)
+	require.Equal(t, original.Transfer.ID, result.OriginalTransfer.ID)
+	require.True(t, result.OriginalTransfer.ReversedAt.Valid)
+
+	// the funds are back where they started
+	require.Equal(t, account1.Balance, result.ToAccountID.Balance)
+	require.Equal(t, account2.Balance, result.FromAccountID.Balance)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+
+	// a reversal can't be reversed either, otherwise the original transfer would be replayed
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: result.Transfer.ID})
+	require.ErrorIs(t, err, ErrTransferIsReversal)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
+
+func TestReverseTransferTxClosedSender(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+	amount := int64(10)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+
+	deleteEmptiedAccount(t, account1.ID)
+
+	// the owner couldn't see nor withdraw a refund credited to the closed account, nothing moves until it is restored
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.ErrorIs(t, err, ErrAccountDeleted)
+
+	closed, err := store.GetAccountIncludingDeleted(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, closed.Balance)
+
+	notReversed, err := store.GetTransfer(context.Background(), original.Transfer.ID)
+	require.NoError(t, err)
+	require.False(t, notReversed.ReversedAt.Valid)
+
+	_, err = store.RestoreAccount(context.Background(), RestoreAccountParams{AccountNumber: account1.AccountNumber, OrgID: DefaultOrgID})
+	require.NoError(t, err)
+
+	result, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.NoError(t, err)
+	require.Equal(t, account1.ID, result.ToAccountID.ID)
+	require.Equal(t, amount, result.ToAccountID.Balance)
+}
+
+func TestTransferTxDeletedAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createAccountInCurrency(t, utils.USD, 0)
+	other := createAccountInCurrency(t, utils.USD, 1000)
+	deleteEmptiedAccount(t, account.ID)
+
+	// a deleted account neither sends nor receives, even from a caller that still holds its id
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: other.ID,
+		ToAccountID:   account.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountNotFound)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   other.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountNotFound)
+
+	unchanged, err := store.GetAccount(context.Background(), other.ID)
+	require.NoError(t, err)
+	require.Equal(t, other.Balance, unchanged.Balance)
+}
+
+func TestReverseTransferTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+	amount := int64(10)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+
+	n := 5
+
+	errs := make(chan error)
+
+	// the original transfer row is locked, so only one reversal can go through
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID})
+
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+	}
+	require.Equal(t, 1, succeeded)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestReverseTransferTxInsufficientBalance(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+	amount := int64(10)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+
+	// the receiver spends the money before the transfer is reversed
+	_, err = store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{
+		AccountID: account2.ID,
+		Amount:    -original.ToAccountID.Balance,
+	})
+	require.NoError(t, err)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{TransferID: original.Transfer.ID})
+	require.ErrorIs(t, err, ErrInsufficientBalance)
+
+	transfer, err := store.GetTransfer(context.Background(), original.Transfer.ID)
+	require.NoError(t, err)
+	require.False(t, transfer.ReversedAt.Valid)
+}
+
+// outboxEventOf returns the outbox event written for the transfer
+func outboxEventOf(t *testing.T, transferID int64) Outbox {
+	var event Outbox
+	err := testDB.QueryRowContext(context.Background(),
+		`SELECT id, event_type, payload, created_at, published_at FROM outbox WHERE (payload->>'id')::bigint = $1`, transferID,
+	).Scan(&event.ID, &event.EventType, &event.Payload, &event.CreatedAt, &event.PublishedAt)
+	require.NoError(t, err)
+	return event
+}
+
+// drainOutbox publishes the events left behind by the other tests
+func drainOutbox(t *testing.T, store Store) {
+	for {
+		published, err := store.PublishOutbox(context.Background(), 100, func(ctx context.Context, event Outbox) error { return nil })
+		require.NoError(t, err)
+		if published == 0 {
+			return
+		}
+	}
+}
+
+func TestTransferTxOutbox(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	event := outboxEventOf(t, result.Transfer.ID)
+	require.Equal(t, OutboxEventTransferCompleted, event.EventType)
+	require.False(t, event.PublishedAt.Valid)
+
+	var transfer Transfer
+	require.NoError(t, json.Unmarshal(event.Payload, &transfer))
+	require.Equal(t, result.Transfer.ID, transfer.ID)
+	require.Equal(t, account1.ID, transfer.FromAccountID)
+	require.Equal(t, account2.ID, transfer.ToAccountID)
+}
+
+// lowBalanceAlertsOf returns the low balance alerts written to the outbox for the account
+func lowBalanceAlertsOf(t *testing.T, accountID int64) []LowBalanceAlert {
+	rows, err := testDB.QueryContext(context.Background(),
+		`SELECT payload FROM outbox WHERE event_type = $1 AND (payload->>'account_id')::bigint = $2 ORDER BY id`,
+		OutboxEventAccountLowBalance, accountID,
+	)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var alerts []LowBalanceAlert
+	for rows.Next() {
+		var payload []byte
+		require.NoError(t, rows.Scan(&payload))
+		var alert LowBalanceAlert
+		require.NoError(t, json.Unmarshal(payload, &alert))
+		alerts = append(alerts, alert)
+	}
+	require.NoError(t, rows.Err())
+	return alerts
+}
+
+func TestTransferTxLowBalanceAlert(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+	_, err := testQueries.SetAccountLowBalanceThreshold(context.Background(), SetAccountLowBalanceThresholdParams{
+		ID:                  account1.ID,
+		LowBalanceThreshold: 500,
+	})
+	require.NoError(t, err)
+
+	transfer := func(from, to Account, amount int64) TransferTxResult {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        amount,
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	// 1000 to 700 stays above the threshold
+	transfer(account1, account2, 300)
+	require.Empty(t, lowBalanceAlertsOf(t, account1.ID))
+
+	// 700 to 400 crosses it
+	crossing := transfer(account1, account2, 300)
+	// and 400 to 300 is already below it
+	transfer(account1, account2, 100)
+
+	alerts := lowBalanceAlertsOf(t, account1.ID)
+	require.Len(t, alerts, 1)
+	require.Equal(t, account1.Owner, alerts[0].Owner)
+	require.Equal(t, int64(400), alerts[0].Balance)
+	require.Equal(t, int64(500), alerts[0].LowBalanceThreshold)
+	require.Equal(t, crossing.Transfer.ID, alerts[0].TransferID)
+
+	// once back above the threshold, the next crossing alerts again
+	transfer(account2, account1, 300)
+	transfer(account1, account2, 200)
+	require.Len(t, lowBalanceAlertsOf(t, account1.ID), 2)
+
+	// the receiver has no threshold
+	require.Empty(t, lowBalanceAlertsOf(t, account2.ID))
+}
+
+func TestCrossedLowBalance(t *testing.T) {
+	testCases := []struct {
+		name      string
+		balance   int64
+		threshold int64
+		amount    int64
+		crossed   bool
+	}{
+		{name: "crosses the threshold", balance: 499, threshold: 500, amount: 1, crossed: true},
+		{name: "from exactly the threshold", balance: 400, threshold: 500, amount: 100, crossed: true},
+		{name: "down to exactly the threshold", balance: 500, threshold: 500, amount: 100},
+		{name: "already below", balance: 300, threshold: 500, amount: 100},
+		{name: "still above", balance: 700, threshold: 500, amount: 300},
+		{name: "no threshold", balance: -100, threshold: 0, amount: 200},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			account := Account{Balance: tc.balance, LowBalanceThreshold: tc.threshold}
+			require.Equal(t, tc.crossed, crossedLowBalance(account, tc.amount))
+		})
+	}
+}
+
+func TestTransferTxOutboxRollback(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+
+	// the idempotency key is stored after the transfer and its event, and fails since the user doesn't exist
+	_, err := store.IdempotentTransferTx(context.Background(), IdempotentTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+		},
+		Username:       utils.RandomOwner(),
+		IdempotencyKey: utils.RandomString(16),
+		RequestHash:    utils.RandomString(64),
+	})
+	require.Error(t, err)
+
+	// the event is written in the transfer transaction, so it is rolled back with it
+	var count int
+	err = testDB.QueryRowContext(context.Background(),
+		`SELECT count(*) FROM outbox WHERE (payload->>'from_account_id')::bigint = $1`, account1.ID,
+	).Scan(&count)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestPublishOutbox(t *testing.T) {
+	store := NewStore(testDB)
+	drainOutbox(t, store)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+
+	n := 3
+	transfers := make([]Transfer, n)
+	for i := 0; i < n; i++ {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+		})
+		require.NoError(t, err)
+		transfers[i] = result.Transfer
+	}
+
+	var handed []Outbox
+	published, err := store.PublishOutbox(context.Background(), 10, func(ctx context.Context, event Outbox) error {
+		handed = append(handed, event)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, n, published)
+
+	for i, transfer := range transfers {
+		event := outboxEventOf(t, transfer.ID)
+		require.Equal(t, event.ID, handed[i].ID)
+		require.True(t, event.PublishedAt.Valid)
+	}
+
+	// published events aren't handed again
+	published, err = store.PublishOutbox(context.Background(), 10, func(ctx context.Context, event Outbox) error {
+		require.FailNow(t, "published event handed again")
+		return nil
+	})
+	require.NoError(t, err)
+	require.Zero(t, published)
+}
+
+func TestPublishOutboxError(t *testing.T) {
+	store := NewStore(testDB)
+	drainOutbox(t, store)
+
+	account1 := createAccountInCurrency(t, utils.USD, 1000)
+	account2 := createAccountInCurrency(t, utils.USD, 1000)
+
+	n := 3
+	transfers := make([]Transfer, n)
+	for i := 0; i < n; i++ {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        10,
+		})
+		require.NoError(t, err)
+		transfers[i] = result.Transfer
+	}
+
+	errPublish := errors.New("webhook unreachable")
+	failingEvent := outboxEventOf(t, transfers[1].ID)
+	published, err := store.PublishOutbox(context.Background(), 10, func(ctx context.Context, event Outbox) error {
+		if event.ID == failingEvent.ID {
+			return errPublish
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, errPublish)
+	require.Equal(t, 1, published)
+
+	// the event before the failing one stays published, the ones after it wait for the next poll
+	require.True(t, outboxEventOf(t, transfers[0].ID).PublishedAt.Valid)
+	require.False(t, outboxEventOf(t, transfers[1].ID).PublishedAt.Valid)
+	require.False(t, outboxEventOf(t, transfers[2].ID).PublishedAt.Valid)
+}
+
+// createAccountInCurrency creates an account of a new user, the transfers between accounts of different currencies need an exchange rate
+func createAccountInCurrency(t *testing.T, currency string, balance int64) Account {
+	user := CreateRandomUser(t)
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:         user.Username,
+		Balance:       balance,
+		Currency:      currency,
+		AccountNumber: utils.RandomAccountNumber(),
+		OrgID:         DefaultOrgID,
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func TestSplitTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	from := createAccountInCurrency(t, utils.USD, 1000)
+	receiver1 := createAccountInCurrency(t, utils.USD, 100)
+	receiver2 := createAccountInCurrency(t, utils.USD, 100)
+
+	result, err := store.SplitTransferTx(context.Background(), SplitTransferTxParams{
+		FromAccountID: from.ID,
+		Amount:        100,
+		Splits: []TransferSplit{
+			{ToAccountID: receiver1.ID, Amount: 30},
+			{ToAccountID: receiver2.ID, Amount: 70},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, from.Balance-100, result.FromAccount.Balance)
+	require.Len(t, result.Transfers, 2)
+
+	require.Equal(t, receiver1.ID, result.Transfers[0].Transfer.ToAccountID)
+	require.Equal(t, int64(30), result.Transfers[0].Transfer.Amount)
+	require.Equal(t, receiver1.Balance+30, result.Transfers[0].ToAccountID.Balance)
+	require.Equal(t, receiver2.ID, result.Transfers[1].Transfer.ToAccountID)
+	require.Equal(t, int64(70), result.Transfers[1].Transfer.Amount)
+	require.Equal(t, receiver2.Balance+70, result.Transfers[1].ToAccountID.Balance)
+
+	for _, transfer := range result.Transfers {
+		require.Equal(t, from.ID, transfer.Transfer.FromAccountID)
+		require.Equal(t, -transfer.Transfer.Amount, transfer.FromEntry.Amount)
+		require.Equal(t, transfer.Transfer.Amount, transfer.ToEntry.Amount)
+	}
+}
+
+func TestSplitTransferTxInvalid(t *testing.T) {
+	store := NewStore(testDB)
+
+	from := createAccountInCurrency(t, utils.USD, 1000)
+	receiver := createAccountInCurrency(t, utils.USD, 100)
+
+	testCases := []struct {
+		name   string
+		amount int64
+		splits []TransferSplit
+	}{
+		{name: "no splits", amount: 10},
+		{name: "splits don't add up", amount: 100, splits: []TransferSplit{{ToAccountID: receiver.ID, Amount: 90}}},
+		{name: "zero split", amount: 10, splits: []TransferSplit{{ToAccountID: receiver.ID, Amount: 10}, {ToAccountID: receiver.ID + 1, Amount: 0}}},
+		{name: "split to the sender", amount: 20, splits: []TransferSplit{{ToAccountID: receiver.ID, Amount: 10}, {ToAccountID: from.ID, Amount: 10}}},
+		{name: "duplicated receiver", amount: 20, splits: []TransferSplit{{ToAccountID: receiver.ID, Amount: 10}, {ToAccountID: receiver.ID, Amount: 10}}},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := store.SplitTransferTx(context.Background(), SplitTransferTxParams{
+				FromAccountID: from.ID,
+				Amount:        tc.amount,
+				Splits:        tc.splits,
+			})
+			require.ErrorIs(t, err, ErrInvalidSplit)
+		})
+	}
+
+	updatedFrom, err := store.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Equal(t, from.Balance, updatedFrom.Balance)
+}
+
+func TestSplitTransferTxRollback(t *testing.T) {
+	store := NewStore(testDB)
+
+	from := createAccountInCurrency(t, utils.USD, 1000)
+	receiver := createAccountInCurrency(t, utils.USD, 100)
+	eurReceiver := createAccountInCurrency(t, utils.EUR, 100)
+	frozenReceiver := createAccountInCurrency(t, utils.USD, 100)
+	_, err := testQueries.SetAccountFrozen(context.Background(), SetAccountFrozenParams{ID: frozenReceiver.ID, IsFrozen: true, OrgID: DefaultOrgID})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		lastSplitTo int64
+		checkErr    func(t *testing.T, err error)
+	}{
+		{
+			name:        "currency mismatch",
+			lastSplitTo: eurReceiver.ID,
+			checkErr: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrCurrencyMismatch)
+			},
+		},
+		{
+			// the first split is already transferred when the frozen receiver is found
+			name:        "frozen receiver",
+			lastSplitTo: frozenReceiver.ID,
+			checkErr: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrAccountFrozen)
+			},
+		},
+		{
+			name:        "missing receiver",
+			lastSplitTo: frozenReceiver.ID + 1000000,
+			checkErr: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, sql.ErrNoRows)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := store.SplitTransferTx(context.Background(), SplitTransferTxParams{
+				FromAccountID: from.ID,
+				Amount:        50,
+				Splits: []TransferSplit{
+					{ToAccountID: receiver.ID, Amount: 20},
+					{ToAccountID: tc.lastSplitTo, Amount: 30},
+				},
+			})
+			tc.checkErr(t, err)
+
+			// nothing of the split is kept, including the splits that went through
+			for _, account := range []Account{from, receiver} {
+				updated, err := store.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+				require.Equal(t, account.Balance, updated.Balance)
+			}
+
+			total, err := store.CountTransfers(context.Background(), CountTransfersParams{FromAccountID: from.ID, ToAccountID: from.ID})
+			require.NoError(t, err)
+			require.Zero(t, total)
+		})
+	}
+}
+
+func TestSplitTransferTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	from := createAccountInCurrency(t, utils.USD, 1000)
+	receivers := []Account{
+		createAccountInCurrency(t, utils.USD, 100),
+		createAccountInCurrency(t, utils.USD, 100),
+		createAccountInCurrency(t, utils.USD, 100),
+	}
+	amount := int64(10)
+
+	n := 10
+	errs := make(chan error)
+
+	// the regular transfers going back to the sender lock the same rows in the opposite direction, they must not deadlock with the splits
+	for i := 0; i < n; i++ {
+		go func() {
+			splits := make([]TransferSplit, len(receivers))
+			for j, receiver := range receivers {
+				splits[j] = TransferSplit{ToAccountID: receiver.ID, Amount: amount}
+			}
+			_, err := store.SplitTransferTx(context.Background(), SplitTransferTxParams{
+				FromAccountID: from.ID,
+				Amount:        amount * int64(len(receivers)),
+				Splits:        splits,
+			})
+			errs <- err
+		}()
+
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: receivers[2].ID,
+				ToAccountID:   from.ID,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < 2*n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	updatedFrom, err := store.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Equal(t, from.Balance-int64(n)*amount*int64(len(receivers))+int64(n)*amount, updatedFrom.Balance)
+
+	for i, receiver := range receivers {
+		updated, err := store.GetAccount(context.Background(), receiver.ID)
+		require.NoError(t, err)
+
+		expected := receiver.Balance + int64(n)*amount
+		if i == 2 {
+			expected -= int64(n) * amount
+		}
+		require.Equal(t, expected, updated.Balance)
+	}
+}
+
+func TestSplitTransferTxConcurrentOverdraft(t *testing.T) {
+	store := NewStore(testDB)
+
+	from := createAccountInCurrency(t, utils.USD, 50)
+	receiver1 := createAccountInCurrency(t, utils.USD, 0)
+	receiver2 := createAccountInCurrency(t, utils.USD, 0)
+
+	// each split transfer alone is covered by the balance, both together aren't
+	n := 2
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.SplitTransferTx(context.Background(), SplitTransferTxParams{
+				FromAccountID: from.ID,
+				Amount:        40,
+				Splits: []TransferSplit{
+					{ToAccountID: receiver1.ID, Amount: 20},
+					{ToAccountID: receiver2.ID, Amount: 20},
+				},
+			})
+			errs <- err
+		}()
+	}
+
+	failed := 0
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			require.ErrorIs(t, err, ErrInsufficientBalance)
+			failed++
+		}
+	}
+	require.Equal(t, 1, failed)
+
+	updatedFrom, err := store.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), updatedFrom.Balance)
+}
+
+func randomCreateUserParams() CreateUserParams {
+	return CreateUserParams{
+		Username:       utils.RandomOwner(),
+		HashedPassword: "password",
+		FullName:       utils.RandomOwner(),
+		Email:          utils.RandomEmail(),
+		OrgID:          DefaultOrgID,
+	}
+}
+
+func TestImportUsersTx(t *testing.T) {
+	store := NewStore(testDB)
+	existing := CreateRandomUser(t)
+
+	duplicated := randomCreateUserParams()
+	duplicated.Email = existing.Email
+	users := []CreateUserParams{randomCreateUserParams(), duplicated, randomCreateUserParams()}
+
+	result, err := store.ImportUsersTx(context.Background(), ImportUsersTxParams{Users: users})
+	require.NoError(t, err)
+	require.False(t, result.RolledBack)
+	require.Len(t, result.Users, 3)
+	require.Len(t, result.Errs, 3)
+
+	// the duplicated email only undoes its own user, the one after it is still created
+	require.Error(t, result.Errs[1])
+	require.Empty(t, result.Users[1])
+	for _, i := range []int{0, 2} {
+		require.NoError(t, result.Errs[i])
+		require.Equal(t, users[i].Username, result.Users[i].Username)
+
+		user, err := store.GetUser(context.Background(), users[i].Username)
+		require.NoError(t, err)
+		require.Equal(t, users[i].Email, user.Email)
+	}
+}
+
+func TestImportUsersTxAtomic(t *testing.T) {
+	store := NewStore(testDB)
+	existing := CreateRandomUser(t)
+
+	duplicated := randomCreateUserParams()
+	duplicated.Username = existing.Username
+	users := []CreateUserParams{randomCreateUserParams(), duplicated}
+
+	result, err := store.ImportUsersTx(context.Background(), ImportUsersTxParams{Users: users, Atomic: true})
+	require.NoError(t, err)
+	require.True(t, result.RolledBack)
+	require.NoError(t, result.Errs[0])
+	require.Error(t, result.Errs[1])
+
+	// the user created before the failure is rolled back with the rest of the import
+	require.Empty(t, result.Users[0])
+	_, err = store.GetUser(context.Background(), users[0].Username)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestTransferAccountOwnershipTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createAccountInCurrency(t, utils.USD, 100)
+	newOwner := CreateRandomUser(t)
+	banker := CreateRandomUser(t)
+
+	result, err := store.TransferAccountOwnershipTx(context.Background(), TransferAccountOwnershipTxParams{
+		AccountID: account.ID,
+		NewOwner:  newOwner.Username,
+		Actor:     Actor{Username: banker.Username, ClientIP: "127.0.0.1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newOwner.Username, result.Account.Owner)
+	require.Equal(t, account.Balance, result.Account.Balance)
+	require.Equal(t, account.Version+1, result.Account.Version)
+
+	require.Equal(t, account.ID, result.Change.AccountID)
+	require.Equal(t, account.Owner, result.Change.PreviousOwner)
+	require.Equal(t, newOwner.Username, result.Change.NewOwner)
+	require.Equal(t, banker.Username, result.Change.ChangedBy)
+
+	changes, err := testQueries.ListAccountOwnerChanges(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, []AccountOwnerChange{result.Change}, changes)
+
+	logs := listUserAuditLogs(t, banker.Username, time.Now().Add(-time.Minute))
+	require.Len(t, logs, 1)
+	require.Equal(t, AuditActionTransferOwnership, logs[0].Action)
+	require.Equal(t, fmt.Sprint(account.ID), logs[0].EntityID)
+}
+
+func TestTransferAccountOwnershipTxConflict(t *testing.T) {
+	store := NewStore(testDB)
+	account := createAccountInCurrency(t, utils.USD, 100)
+	banker := CreateRandomUser(t)
+
+	// the new owner already holds an account in the same currency
+	existing := createAccountInCurrency(t, utils.USD, 0)
+	_, err := store.TransferAccountOwnershipTx(context.Background(), TransferAccountOwnershipTxParams{
+		AccountID: account.ID,
+		NewOwner:  existing.Owner,
+		Actor:     Actor{Username: banker.Username, ClientIP: "127.0.0.1"},
+	})
+	require.ErrorIs(t, err, ErrOwnerHasCurrency)
+
+	_, err = store.TransferAccountOwnershipTx(context.Background(), TransferAccountOwnershipTxParams{
+		AccountID: account.ID,
+		NewOwner:  utils.RandomOwner(),
+		Actor:     Actor{Username: banker.Username, ClientIP: "127.0.0.1"},
+	})
+	require.ErrorIs(t, err, ErrNewOwnerNotFound)
+
+	// nothing changed and nothing was recorded
+	unchanged, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Owner, unchanged.Owner)
+
+	changes, err := testQueries.ListAccountOwnerChanges(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func TestCaptureHoldTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	from := createAccountInCurrency(t, utils.USD, 100)
+	to := createAccountInCurrency(t, utils.USD, 100)
+
+	authorized, err := store.AuthorizeHoldTx(context.Background(), AuthorizeHoldTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        60,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusAuthorized, authorized.Hold.Status)
+	// the held funds stay in the balance but can't be spent
+	require.Equal(t, from.Balance, authorized.FromAccount.Balance)
+	require.Equal(t, int64(60), authorized.FromAccount.HeldBalance)
+
+	_, err = store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{AccountID: from.ID, Amount: -50})
+	require.ErrorIs(t, err, ErrInsufficientBalance)
+	_, err = store.AuthorizeHoldTx(context.Background(), AuthorizeHoldTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        50,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrInsufficientBalance)
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: authorized.Hold.ID, Amount: 61})
+	require.ErrorIs(t, err, ErrCaptureExceedsHold)
+
+	// the part of the hold that isn't captured is released
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: authorized.Hold.ID, Amount: 40})
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusCaptured, result.Hold.Status)
+	require.True(t, result.Hold.TransferID.Valid)
+	require.Equal(t, result.Transfer.ID, result.Hold.TransferID.Int64)
+	require.Equal(t, int64(40), result.Transfer.Amount)
+	require.Equal(t, from.Balance-40, result.FromAccountID.Balance)
+	require.Zero(t, result.FromAccountID.HeldBalance)
+	require.Equal(t, to.Balance+40, result.ToAccountID.Balance)
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: authorized.Hold.ID})
+	require.ErrorIs(t, err, ErrHoldNotAuthorized)
+}
+
+func TestVoidHoldTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	from := createAccountInCurrency(t, utils.USD, 100)
+	to := createAccountInCurrency(t, utils.USD, 100)
+
+	authorized, err := store.AuthorizeHoldTx(context.Background(), AuthorizeHoldTxParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        100,
+		ExpiresAt:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	result, err := store.VoidHoldTx(context.Background(), VoidHoldTxParams{HoldID: authorized.Hold.ID})
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusVoided, result.Hold.Status)
+	require.Equal(t, from.Balance, result.FromAccount.Balance)
+	require.Zero(t, result.FromAccount.HeldBalance)
+
+	_, err = store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: authorized.Hold.ID})
+	require.ErrorIs(t, err, ErrHoldNotAuthorized)
+	_, err = store.VoidHoldTx(context.Background(), VoidHoldTxParams{HoldID: authorized.Hold.ID})
+	require.ErrorIs(t, err, ErrHoldNotAuthorized)
+
+	// the released funds can be spent again
+	_, err = store.AddAccountBalanceTx(context.Background(), AddAccountBalanceTxParams{AccountID: from.ID, Amount: -100})
+	require.NoError(t, err)
+}
+
+func TestHoldExpiry(t *testing.T) {
+	store := NewStore(testDB)
+
+	from := createAccountInCurrency(t, utils.USD, 100)
+	to := createAccountInCurrency(t, utils.USD, 100)
+
+	authorize := func(expiresAt time.Time) Hold {
+		result, err := store.AuthorizeHoldTx(context.Background(), AuthorizeHoldTxParams{
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        30,
+			ExpiresAt:     expiresAt,
+		})
+		require.NoError(t, err)
+		return result.Hold
+	}
+	expired := authorize(time.Now().Add(-time.Minute))
+	listed := authorize(time.Now().Add(-time.Minute))
+	pending := authorize(time.Now().Add(time.Hour))
+
+	// capturing an expired hold releases it instead
+	result, err := store.CaptureHoldTx(context.Background(), CaptureHoldTxParams{HoldID: expired.ID})
+	require.ErrorIs(t, err, ErrHoldExpired)
+	require.Equal(t, HoldStatusExpired, result.Hold.Status)
+
+	holds, err := store.ExpireHoldsTx(context.Background(), ExpireHoldsTxParams{Now: time.Now(), Limit: 100})
+	require.NoError(t, err)
+	var expiredIDs []int64
+	for _, hold := range holds {
+		require.Equal(t, HoldStatusExpired, hold.Status)
+		expiredIDs = append(expiredIDs, hold.ID)
+	}
+	require.Contains(t, expiredIDs, listed.ID)
+	require.NotContains(t, expiredIDs, expired.ID)
+	require.NotContains(t, expiredIDs, pending.ID)
+
+	account, err := store.GetAccount(context.Background(), from.ID)
+	require.NoError(t, err)
+	require.Equal(t, from.Balance, account.Balance)
+	require.Equal(t, pending.Amount, account.HeldBalance)
+}
+
+func TestCloseAccountTxCurrencyMismatch(t *testing.T) {
+	store := NewStore(testDB)
+	account := createAccountInCurrency(t, utils.USD, 100)
+
+	// an owner holds a single live account per currency, so its other account is in another currency
+	destination, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:         account.Owner,
+		Balance:       50,
+		Currency:      utils.EUR,
+		AccountNumber: utils.RandomAccountNumber(),
+		OrgID:         DefaultOrgID,
+	})
+	require.NoError(t, err)
+
+	_, err = store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID:     account.ID,
+		DestinationID: destination.ID,
+	})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	// nothing was swept and the account is still open
+	unchanged, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, unchanged.Balance)
+
+	unchanged, err = testQueries.GetAccount(context.Background(), destination.ID)
+	require.NoError(t, err)
+	require.Equal(t, destination.Balance, unchanged.Balance)
+}
+
+func TestCloseAccountTx(t *testing.T) {
+	store := NewStore(testDB)
+	account := createAccountInCurrency(t, utils.USD, 100)
+	// an owner holds a single live account per currency, so the balance is swept into another owner's account
+	destination := createAccountInCurrency(t, utils.USD, 50)
+	schedule := createRandomScheduledTransfer(t, account, time.Now().Add(time.Hour))
+
+	result, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID:     account.ID,
+		DestinationID: destination.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, result.SweptAmount)
+	require.NotNil(t, result.Transfer)
+	require.Equal(t, account.ID, result.Transfer.FromAccountID)
+	require.Equal(t, destination.ID, result.Transfer.ToAccountID)
+	require.Equal(t, account.Balance, result.Transfer.Amount)
+	require.Equal(t, destination.Balance+account.Balance, result.Destination.Balance)
+
+	swept, err := testQueries.GetAccount(context.Background(), destination.ID)
+	require.NoError(t, err)
+	require.Equal(t, destination.Balance+account.Balance, swept.Balance)
+
+	// the sweep is booked like any transfer, with an entry on each account
+	fromEntries, err := testQueries.ListEntriesByAccount(context.Background(), ListEntriesByAccountParams{AccountID: account.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, fromEntries, 1)
+	require.Equal(t, -account.Balance, fromEntries[0].Amount)
+
+	toEntries, err := testQueries.ListEntriesByAccount(context.Background(), ListEntriesByAccountParams{AccountID: destination.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, toEntries, 1)
+	require.Equal(t, account.Balance, toEntries[0].Amount)
+
+	_, err = testQueries.GetAccount(context.Background(), account.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	closed, err := testQueries.GetAccountIncludingDeleted(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Zero(t, closed.Balance)
+	require.True(t, closed.DeletedAt.Valid)
+
+	cancelled, err := testQueries.GetScheduledTransfer(context.Background(), schedule.ID)
+	require.NoError(t, err)
+	require.True(t, cancelled.ScheduledTransfer.CancelledAt.Valid)
+}
+
+func TestCloseAccountTxIntoItself(t *testing.T) {
+	store := NewStore(testDB)
+	account := createAccountInCurrency(t, utils.USD, 100)
+
+	_, err := store.CloseAccountTx(context.Background(), CloseAccountTxParams{
+		AccountID:     account.ID,
+		DestinationID: account.ID,
+	})
+	require.ErrorIs(t, err, ErrCloseIntoItself)
+
+	unchanged, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, unchanged.Balance)
+}
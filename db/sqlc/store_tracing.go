@@ -0,0 +1,936 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// tracedStore wraps a Store creating a child span, named after the method, around every call
+// The spans hang from the one found in the context, which for the api is the span of the http request
+type tracedStore struct {
+	store  Store
+	tracer trace.Tracer
+}
+
+// NewTracedStore returns a Store that traces every call to store with the given tracer
+func NewTracedStore(store Store, tracer trace.Tracer) Store {
+	return &tracedStore{
+		store:  store,
+		tracer: tracer,
+	}
+}
+
+func (s *tracedStore) startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "db."+method, trace.WithSpanKind(trace.SpanKindClient))
+}
+
+// endSpan records err in the span and ends it. Missing rows are an expected result, not a failure
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *tracedStore) AccountNumberExists(ctx context.Context, accountNumber string) (bool, error) {
+	ctx, span := s.startSpan(ctx, "AccountNumberExists")
+	result, err := s.store.AccountNumberExists(ctx, accountNumber)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) AddAccountBalanceTx(ctx context.Context, params AddAccountBalanceTxParams) (Account, error) {
+	ctx, span := s.startSpan(ctx, "AddAccountBalanceTx")
+	result, err := s.store.AddAccountBalanceTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) AddAccountHeldBalance(ctx context.Context, arg AddAccountHeldBalanceParams) (Account, error) {
+	ctx, span := s.startSpan(ctx, "AddAccountHeldBalance")
+	result, err := s.store.AddAccountHeldBalance(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) AddDailyTransferTotal(ctx context.Context, arg AddDailyTransferTotalParams) (int64, error) {
+	ctx, span := s.startSpan(ctx, "AddDailyTransferTotal")
+	result, err := s.store.AddDailyTransferTotal(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) AuthorizeHoldTx(ctx context.Context, params AuthorizeHoldTxParams) (AuthorizeHoldTxResult, error) {
+	ctx, span := s.startSpan(ctx, "AuthorizeHoldTx")
+	result, err := s.store.AuthorizeHoldTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CancelAccountScheduledTransfers(ctx context.Context, fromAccountID int64) error {
+	ctx, span := s.startSpan(ctx, "CancelAccountScheduledTransfers")
+	err := s.store.CancelAccountScheduledTransfers(ctx, fromAccountID)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) CancelScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error) {
+	ctx, span := s.startSpan(ctx, "CancelScheduledTransfer")
+	result, err := s.store.CancelScheduledTransfer(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CaptureHoldTx(ctx context.Context, params CaptureHoldTxParams) (CaptureHoldTxResult, error) {
+	ctx, span := s.startSpan(ctx, "CaptureHoldTx")
+	result, err := s.store.CaptureHoldTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ChangePasswordTx(ctx context.Context, params ChangePasswordTxParams) (User, error) {
+	ctx, span := s.startSpan(ctx, "ChangePasswordTx")
+	result, err := s.store.ChangePasswordTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ResetPasswordTx(ctx context.Context, params ResetPasswordTxParams) (User, error) {
+	ctx, span := s.startSpan(ctx, "ResetPasswordTx")
+	result, err := s.store.ResetPasswordTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ClaimScheduledTransfer(ctx context.Context, arg ClaimScheduledTransferParams) (ScheduledTransfer, error) {
+	ctx, span := s.startSpan(ctx, "ClaimScheduledTransfer")
+	result, err := s.store.ClaimScheduledTransfer(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CloseAccountTx(ctx context.Context, params CloseAccountTxParams) (CloseAccountTxResult, error) {
+	ctx, span := s.startSpan(ctx, "CloseAccountTx")
+	result, err := s.store.CloseAccountTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CountAccounts(ctx context.Context, arg CountAccountsParams) (int64, error) {
+	ctx, span := s.startSpan(ctx, "CountAccounts")
+	result, err := s.store.CountAccounts(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CountAccountsByCurrency(ctx context.Context, arg CountAccountsByCurrencyParams) (int64, error) {
+	ctx, span := s.startSpan(ctx, "CountAccountsByCurrency")
+	result, err := s.store.CountAccountsByCurrency(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CountAllAccounts(ctx context.Context, arg CountAllAccountsParams) (int64, error) {
+	ctx, span := s.startSpan(ctx, "CountAllAccounts")
+	result, err := s.store.CountAllAccounts(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CountAuditLogs(ctx context.Context, arg CountAuditLogsParams) (int64, error) {
+	ctx, span := s.startSpan(ctx, "CountAuditLogs")
+	result, err := s.store.CountAuditLogs(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CountEntriesByAccount(ctx context.Context, accountID int64) (int64, error) {
+	ctx, span := s.startSpan(ctx, "CountEntriesByAccount")
+	result, err := s.store.CountEntriesByAccount(ctx, accountID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CountEntriesByAccountInRange(ctx context.Context, arg CountEntriesByAccountInRangeParams) (int64, error) {
+	ctx, span := s.startSpan(ctx, "CountEntriesByAccountInRange")
+	result, err := s.store.CountEntriesByAccountInRange(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CountScheduledTransfers(ctx context.Context, fromAccountID int64) (int64, error) {
+	ctx, span := s.startSpan(ctx, "CountScheduledTransfers")
+	result, err := s.store.CountScheduledTransfers(ctx, fromAccountID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CountSearchUsers(ctx context.Context, arg CountSearchUsersParams) (int64, error) {
+	ctx, span := s.startSpan(ctx, "CountSearchUsers")
+	result, err := s.store.CountSearchUsers(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CountTransfers(ctx context.Context, arg CountTransfersParams) (int64, error) {
+	ctx, span := s.startSpan(ctx, "CountTransfers")
+	result, err := s.store.CountTransfers(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	ctx, span := s.startSpan(ctx, "CreateAccount")
+	result, err := s.store.CreateAccount(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateAccountIdempotencyKey(ctx context.Context, arg CreateAccountIdempotencyKeyParams) (AccountIdempotencyKey, error) {
+	ctx, span := s.startSpan(ctx, "CreateAccountIdempotencyKey")
+	result, err := s.store.CreateAccountIdempotencyKey(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateAccountOwnerChange(ctx context.Context, arg CreateAccountOwnerChangeParams) (AccountOwnerChange, error) {
+	ctx, span := s.startSpan(ctx, "CreateAccountOwnerChange")
+	result, err := s.store.CreateAccountOwnerChange(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error) {
+	ctx, span := s.startSpan(ctx, "CreateAuditLog")
+	result, err := s.store.CreateAuditLog(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateBalanceSnapshot(ctx context.Context, arg CreateBalanceSnapshotParams) (BalanceSnapshot, error) {
+	ctx, span := s.startSpan(ctx, "CreateBalanceSnapshot")
+	result, err := s.store.CreateBalanceSnapshot(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateDailyBalanceSnapshots(ctx context.Context, day time.Time) (int64, error) {
+	ctx, span := s.startSpan(ctx, "CreateDailyBalanceSnapshots")
+	result, err := s.store.CreateDailyBalanceSnapshots(ctx, day)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
+	ctx, span := s.startSpan(ctx, "CreateEntry")
+	result, err := s.store.CreateEntry(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error) {
+	ctx, span := s.startSpan(ctx, "CreateHold")
+	result, err := s.store.CreateHold(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error) {
+	ctx, span := s.startSpan(ctx, "CreateIdempotencyKey")
+	result, err := s.store.CreateIdempotencyKey(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateOrganization(ctx context.Context, name string) (Organization, error) {
+	ctx, span := s.startSpan(ctx, "CreateOrganization")
+	result, err := s.store.CreateOrganization(ctx, name)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error) {
+	ctx, span := s.startSpan(ctx, "CreateOutboxEvent")
+	result, err := s.store.CreateOutboxEvent(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error) {
+	ctx, span := s.startSpan(ctx, "CreatePasswordReset")
+	result, err := s.store.CreatePasswordReset(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error) {
+	ctx, span := s.startSpan(ctx, "CreateScheduledTransfer")
+	result, err := s.store.CreateScheduledTransfer(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	ctx, span := s.startSpan(ctx, "CreateSession")
+	result, err := s.store.CreateSession(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
+	ctx, span := s.startSpan(ctx, "CreateTransfer")
+	result, err := s.store.CreateTransfer(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	ctx, span := s.startSpan(ctx, "CreateUser")
+	result, err := s.store.CreateUser(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) DeleteAccount(ctx context.Context, id int64) error {
+	ctx, span := s.startSpan(ctx, "DeleteAccount")
+	err := s.store.DeleteAccount(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) DeleteEntry(ctx context.Context, id int64) error {
+	ctx, span := s.startSpan(ctx, "DeleteEntry")
+	err := s.store.DeleteEntry(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) DeleteTransfer(ctx context.Context, id int64) error {
+	ctx, span := s.startSpan(ctx, "DeleteTransfer")
+	err := s.store.DeleteTransfer(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) DeleteUser(ctx context.Context, username string) error {
+	ctx, span := s.startSpan(ctx, "DeleteUser")
+	err := s.store.DeleteUser(ctx, username)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) EnableTotpSecret(ctx context.Context, username string) (TotpSecret, error) {
+	ctx, span := s.startSpan(ctx, "EnableTotpSecret")
+	result, err := s.store.EnableTotpSecret(ctx, username)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) EntryTx(ctx context.Context, params EntryTxParams) (EntryTxResult, error) {
+	ctx, span := s.startSpan(ctx, "EntryTx")
+	result, err := s.store.EntryTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ExecTx(ctx context.Context, fn func(Querier) error) error {
+	ctx, span := s.startSpan(ctx, "ExecTx")
+	err := s.store.ExecTx(ctx, fn)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) ExpireHoldsTx(ctx context.Context, params ExpireHoldsTxParams) ([]Hold, error) {
+	ctx, span := s.startSpan(ctx, "ExpireHoldsTx")
+	result, err := s.store.ExpireHoldsTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetAccount(ctx context.Context, id int64) (Account, error) {
+	ctx, span := s.startSpan(ctx, "GetAccount")
+	result, err := s.store.GetAccount(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetAccountByNumber(ctx context.Context, accountNumber string) (Account, error) {
+	ctx, span := s.startSpan(ctx, "GetAccountByNumber")
+	result, err := s.store.GetAccountByNumber(ctx, accountNumber)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetAccountForUpdate(ctx context.Context, id int64) (Account, error) {
+	ctx, span := s.startSpan(ctx, "GetAccountForUpdate")
+	result, err := s.store.GetAccountForUpdate(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetAccountForUpdateIncludingDeleted(ctx context.Context, id int64) (Account, error) {
+	ctx, span := s.startSpan(ctx, "GetAccountForUpdateIncludingDeleted")
+	result, err := s.store.GetAccountForUpdateIncludingDeleted(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetAccountIdempotencyKey(ctx context.Context, arg GetAccountIdempotencyKeyParams) (AccountIdempotencyKey, error) {
+	ctx, span := s.startSpan(ctx, "GetAccountIdempotencyKey")
+	result, err := s.store.GetAccountIdempotencyKey(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetAccountIncludingDeleted(ctx context.Context, id int64) (Account, error) {
+	ctx, span := s.startSpan(ctx, "GetAccountIncludingDeleted")
+	result, err := s.store.GetAccountIncludingDeleted(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetAccountNumbers(ctx context.Context, ids []int64) ([]GetAccountNumbersRow, error) {
+	ctx, span := s.startSpan(ctx, "GetAccountNumbers")
+	result, err := s.store.GetAccountNumbers(ctx, ids)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetAccountStats(ctx context.Context, arg GetAccountStatsParams) (GetAccountStatsRow, error) {
+	ctx, span := s.startSpan(ctx, "GetAccountStats")
+	result, err := s.store.GetAccountStats(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetAccountsByIDs(ctx context.Context, ids []int64) ([]Account, error) {
+	ctx, span := s.startSpan(ctx, "GetAccountsByIDs")
+	result, err := s.store.GetAccountsByIDs(ctx, ids)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetAccountsByNumbers(ctx context.Context, accountNumbers []string) ([]Account, error) {
+	ctx, span := s.startSpan(ctx, "GetAccountsByNumbers")
+	result, err := s.store.GetAccountsByNumbers(ctx, accountNumbers)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetDailyTransferTotal(ctx context.Context, username string) (int64, error) {
+	ctx, span := s.startSpan(ctx, "GetDailyTransferTotal")
+	result, err := s.store.GetDailyTransferTotal(ctx, username)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetEntry(ctx context.Context, id int64) (Entry, error) {
+	ctx, span := s.startSpan(ctx, "GetEntry")
+	result, err := s.store.GetEntry(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetHold(ctx context.Context, id int64) (Hold, error) {
+	ctx, span := s.startSpan(ctx, "GetHold")
+	result, err := s.store.GetHold(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetHoldForUpdate(ctx context.Context, id int64) (Hold, error) {
+	ctx, span := s.startSpan(ctx, "GetHoldForUpdate")
+	result, err := s.store.GetHoldForUpdate(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error) {
+	ctx, span := s.startSpan(ctx, "GetIdempotencyKey")
+	result, err := s.store.GetIdempotencyKey(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetNotificationPreferences(ctx context.Context, username string) (NotificationPreference, error) {
+	ctx, span := s.startSpan(ctx, "GetNotificationPreferences")
+	result, err := s.store.GetNotificationPreferences(ctx, username)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetOrganization(ctx context.Context, id int64) (Organization, error) {
+	ctx, span := s.startSpan(ctx, "GetOrganization")
+	result, err := s.store.GetOrganization(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetPasswordResetForUpdate(ctx context.Context, tokenHash string) (PasswordReset, error) {
+	ctx, span := s.startSpan(ctx, "GetPasswordResetForUpdate")
+	result, err := s.store.GetPasswordResetForUpdate(ctx, tokenHash)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetScheduledTransfer(ctx context.Context, id int64) (GetScheduledTransferRow, error) {
+	ctx, span := s.startSpan(ctx, "GetScheduledTransfer")
+	result, err := s.store.GetScheduledTransfer(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetSession(ctx context.Context, id uuid.UUID) (Session, error) {
+	ctx, span := s.startSpan(ctx, "GetSession")
+	result, err := s.store.GetSession(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetTotpSecret(ctx context.Context, username string) (TotpSecret, error) {
+	ctx, span := s.startSpan(ctx, "GetTotpSecret")
+	result, err := s.store.GetTotpSecret(ctx, username)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	ctx, span := s.startSpan(ctx, "GetTransfer")
+	result, err := s.store.GetTransfer(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetTransferByUUID(ctx context.Context, uuid uuid.UUID) (Transfer, error) {
+	ctx, span := s.startSpan(ctx, "GetTransferByUUID")
+	result, err := s.store.GetTransferByUUID(ctx, uuid)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	ctx, span := s.startSpan(ctx, "GetTransferForUpdate")
+	result, err := s.store.GetTransferForUpdate(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetUser(ctx context.Context, username string) (User, error) {
+	ctx, span := s.startSpan(ctx, "GetUser")
+	result, err := s.store.GetUser(ctx, username)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	ctx, span := s.startSpan(ctx, "GetUserByEmail")
+	result, err := s.store.GetUserByEmail(ctx, email)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetUserForUpdate(ctx context.Context, username string) (User, error) {
+	ctx, span := s.startSpan(ctx, "GetUserForUpdate")
+	result, err := s.store.GetUserForUpdate(ctx, username)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) GetUserPasswordChangedAt(ctx context.Context, username string) (time.Time, error) {
+	ctx, span := s.startSpan(ctx, "GetUserPasswordChangedAt")
+	result, err := s.store.GetUserPasswordChangedAt(ctx, username)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) IdempotentCreateAccountTx(ctx context.Context, params IdempotentCreateAccountTxParams) (IdempotentCreateAccountTxResult, error) {
+	ctx, span := s.startSpan(ctx, "IdempotentCreateAccountTx")
+	result, err := s.store.IdempotentCreateAccountTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) IdempotentTransferTx(ctx context.Context, params IdempotentTransferTxParams) (IdempotentTransferTxResult, error) {
+	ctx, span := s.startSpan(ctx, "IdempotentTransferTx")
+	result, err := s.store.IdempotentTransferTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ImportUsersTx(ctx context.Context, params ImportUsersTxParams) (ImportUsersTxResult, error) {
+	ctx, span := s.startSpan(ctx, "ImportUsersTx")
+	result, err := s.store.ImportUsersTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListAccountOwnerChanges(ctx context.Context, accountID int64) ([]AccountOwnerChange, error) {
+	ctx, span := s.startSpan(ctx, "ListAccountOwnerChanges")
+	result, err := s.store.ListAccountOwnerChanges(ctx, accountID)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListAccountStatement(ctx context.Context, arg ListAccountStatementParams) ([]ListAccountStatementRow, error) {
+	ctx, span := s.startSpan(ctx, "ListAccountStatement")
+	result, err := s.store.ListAccountStatement(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error) {
+	ctx, span := s.startSpan(ctx, "ListAccounts")
+	result, err := s.store.ListAccounts(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListAccountsAfterNumber(ctx context.Context, arg ListAccountsAfterNumberParams) ([]Account, error) {
+	ctx, span := s.startSpan(ctx, "ListAccountsAfterNumber")
+	result, err := s.store.ListAccountsAfterNumber(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListAccountsByCurrency(ctx context.Context, arg ListAccountsByCurrencyParams) ([]Account, error) {
+	ctx, span := s.startSpan(ctx, "ListAccountsByCurrency")
+	result, err := s.store.ListAccountsByCurrency(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListAllAccounts(ctx context.Context, arg ListAllAccountsParams) ([]Account, error) {
+	ctx, span := s.startSpan(ctx, "ListAllAccounts")
+	result, err := s.store.ListAllAccounts(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error) {
+	ctx, span := s.startSpan(ctx, "ListAuditLogs")
+	result, err := s.store.ListAuditLogs(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListBalanceSnapshots(ctx context.Context, arg ListBalanceSnapshotsParams) ([]BalanceSnapshot, error) {
+	ctx, span := s.startSpan(ctx, "ListBalanceSnapshots")
+	result, err := s.store.ListBalanceSnapshots(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListDueScheduledTransfers(ctx context.Context, arg ListDueScheduledTransfersParams) ([]ScheduledTransfer, error) {
+	ctx, span := s.startSpan(ctx, "ListDueScheduledTransfers")
+	result, err := s.store.ListDueScheduledTransfers(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error) {
+	ctx, span := s.startSpan(ctx, "ListEntries")
+	result, err := s.store.ListEntries(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListEntriesByAccount(ctx context.Context, arg ListEntriesByAccountParams) ([]Entry, error) {
+	ctx, span := s.startSpan(ctx, "ListEntriesByAccount")
+	result, err := s.store.ListEntriesByAccount(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListEntriesByAccountInRange(ctx context.Context, arg ListEntriesByAccountInRangeParams) ([]Entry, error) {
+	ctx, span := s.startSpan(ctx, "ListEntriesByAccountInRange")
+	result, err := s.store.ListEntriesByAccountInRange(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListExpiredHolds(ctx context.Context, arg ListExpiredHoldsParams) ([]Hold, error) {
+	ctx, span := s.startSpan(ctx, "ListExpiredHolds")
+	result, err := s.store.ListExpiredHolds(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ListScheduledTransfersRow, error) {
+	ctx, span := s.startSpan(ctx, "ListScheduledTransfers")
+	result, err := s.store.ListScheduledTransfers(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
+	ctx, span := s.startSpan(ctx, "ListTransfers")
+	result, err := s.store.ListTransfers(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListUnpublishedOutboxEvents(ctx context.Context, limit int32) ([]Outbox, error) {
+	ctx, span := s.startSpan(ctx, "ListUnpublishedOutboxEvents")
+	result, err := s.store.ListUnpublishedOutboxEvents(ctx, limit)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	ctx, span := s.startSpan(ctx, "ListUsers")
+	result, err := s.store.ListUsers(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) LoginTx(ctx context.Context, params LoginTxParams) (Session, error) {
+	ctx, span := s.startSpan(ctx, "LoginTx")
+	result, err := s.store.LoginTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) LookupAccounts(ctx context.Context, ids []int64) (map[int64]Account, error) {
+	ctx, span := s.startSpan(ctx, "LookupAccounts")
+	result, err := s.store.LookupAccounts(ctx, ids)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) LookupAccountsByNumber(ctx context.Context, accountNumbers []string) (map[string]Account, error) {
+	ctx, span := s.startSpan(ctx, "LookupAccountsByNumber")
+	result, err := s.store.LookupAccountsByNumber(ctx, accountNumbers)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	ctx, span := s.startSpan(ctx, "MarkOutboxEventPublished")
+	err := s.store.MarkOutboxEventPublished(ctx, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) MarkPasswordResetUsed(ctx context.Context, id int64) (PasswordReset, error) {
+	ctx, span := s.startSpan(ctx, "MarkPasswordResetUsed")
+	result, err := s.store.MarkPasswordResetUsed(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) MarkTransferReversed(ctx context.Context, id int64) (Transfer, error) {
+	ctx, span := s.startSpan(ctx, "MarkTransferReversed")
+	result, err := s.store.MarkTransferReversed(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) Ping(ctx context.Context) error {
+	ctx, span := s.startSpan(ctx, "Ping")
+	err := s.store.Ping(ctx)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) PublishOutbox(ctx context.Context, limit int32, publish func(context.Context, Outbox) error) (int, error) {
+	ctx, span := s.startSpan(ctx, "PublishOutbox")
+	result, err := s.store.PublishOutbox(ctx, limit, publish)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (User, error) {
+	ctx, span := s.startSpan(ctx, "RecordFailedLogin")
+	result, err := s.store.RecordFailedLogin(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) RecordScheduledTransferFailure(ctx context.Context, arg RecordScheduledTransferFailureParams) (ScheduledTransfer, error) {
+	ctx, span := s.startSpan(ctx, "RecordScheduledTransferFailure")
+	result, err := s.store.RecordScheduledTransferFailure(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) RecordScheduledTransferRun(ctx context.Context, arg RecordScheduledTransferRunParams) (ScheduledTransfer, error) {
+	ctx, span := s.startSpan(ctx, "RecordScheduledTransferRun")
+	result, err := s.store.RecordScheduledTransferRun(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ResetFailedLogins(ctx context.Context, username string) error {
+	ctx, span := s.startSpan(ctx, "ResetFailedLogins")
+	err := s.store.ResetFailedLogins(ctx, username)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) RestoreAccount(ctx context.Context, arg RestoreAccountParams) (Account, error) {
+	ctx, span := s.startSpan(ctx, "RestoreAccount")
+	result, err := s.store.RestoreAccount(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) ReverseTransferTx(ctx context.Context, params ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	ctx, span := s.startSpan(ctx, "ReverseTransferTx")
+	result, err := s.store.ReverseTransferTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error) {
+	ctx, span := s.startSpan(ctx, "SearchUsers")
+	result, err := s.store.SearchUsers(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) SetAccountFrozen(ctx context.Context, arg SetAccountFrozenParams) (Account, error) {
+	ctx, span := s.startSpan(ctx, "SetAccountFrozen")
+	result, err := s.store.SetAccountFrozen(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) SetAccountFrozenTx(ctx context.Context, params SetAccountFrozenTxParams) (Account, error) {
+	ctx, span := s.startSpan(ctx, "SetAccountFrozenTx")
+	result, err := s.store.SetAccountFrozenTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) SetAccountLabels(ctx context.Context, arg SetAccountLabelsParams) (Account, error) {
+	ctx, span := s.startSpan(ctx, "SetAccountLabels")
+	result, err := s.store.SetAccountLabels(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) SetAccountLowBalanceThreshold(ctx context.Context, arg SetAccountLowBalanceThresholdParams) (Account, error) {
+	ctx, span := s.startSpan(ctx, "SetAccountLowBalanceThreshold")
+	result, err := s.store.SetAccountLowBalanceThreshold(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) SetAccountSpendingLimits(ctx context.Context, arg SetAccountSpendingLimitsParams) (Account, error) {
+	ctx, span := s.startSpan(ctx, "SetAccountSpendingLimits")
+	result, err := s.store.SetAccountSpendingLimits(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) SoftDeleteAccount(ctx context.Context, id int64) (int64, error) {
+	ctx, span := s.startSpan(ctx, "SoftDeleteAccount")
+	result, err := s.store.SoftDeleteAccount(ctx, id)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) SumOutgoingTransfers(ctx context.Context, arg SumOutgoingTransfersParams) (int64, error) {
+	ctx, span := s.startSpan(ctx, "SumOutgoingTransfers")
+	result, err := s.store.SumOutgoingTransfers(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) SplitTransferTx(ctx context.Context, params SplitTransferTxParams) (SplitTransferTxResult, error) {
+	ctx, span := s.startSpan(ctx, "SplitTransferTx")
+	result, err := s.store.SplitTransferTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) StreamAccountStatement(ctx context.Context, arg ListAccountStatementParams, fn func(ListAccountStatementRow) error) error {
+	ctx, span := s.startSpan(ctx, "StreamAccountStatement")
+	err := s.store.StreamAccountStatement(ctx, arg, fn)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedStore) TransferAccountOwnershipTx(ctx context.Context, params TransferAccountOwnershipTxParams) (TransferAccountOwnershipTxResult, error) {
+	ctx, span := s.startSpan(ctx, "TransferAccountOwnershipTx")
+	result, err := s.store.TransferAccountOwnershipTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) TransferTx(ctx context.Context, params TransferTxParams) (TransferTxResult, error) {
+	ctx, span := s.startSpan(ctx, "TransferTx")
+	result, err := s.store.TransferTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
+	ctx, span := s.startSpan(ctx, "UpdateAccount")
+	result, err := s.store.UpdateAccount(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error) {
+	ctx, span := s.startSpan(ctx, "UpdateAccountBalance")
+	result, err := s.store.UpdateAccountBalance(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) UpdateAccountOwner(ctx context.Context, arg UpdateAccountOwnerParams) (Account, error) {
+	ctx, span := s.startSpan(ctx, "UpdateAccountOwner")
+	result, err := s.store.UpdateAccountOwner(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) UpdateHoldStatus(ctx context.Context, arg UpdateHoldStatusParams) (Hold, error) {
+	ctx, span := s.startSpan(ctx, "UpdateHoldStatus")
+	result, err := s.store.UpdateHoldStatus(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	ctx, span := s.startSpan(ctx, "UpdateUser")
+	result, err := s.store.UpdateUser(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	ctx, span := s.startSpan(ctx, "UpdateUserPassword")
+	result, err := s.store.UpdateUserPassword(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) UpsertNotificationPreferences(ctx context.Context, arg UpsertNotificationPreferencesParams) (NotificationPreference, error) {
+	ctx, span := s.startSpan(ctx, "UpsertNotificationPreferences")
+	result, err := s.store.UpsertNotificationPreferences(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) UpsertTotpSecret(ctx context.Context, arg UpsertTotpSecretParams) (TotpSecret, error) {
+	ctx, span := s.startSpan(ctx, "UpsertTotpSecret")
+	result, err := s.store.UpsertTotpSecret(ctx, arg)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *tracedStore) VoidHoldTx(ctx context.Context, params VoidHoldTxParams) (VoidHoldTxResult, error) {
+	ctx, span := s.startSpan(ctx, "VoidHoldTx")
+	result, err := s.store.VoidHoldTx(ctx, params)
+	endSpan(span, err)
+	return result, err
+}
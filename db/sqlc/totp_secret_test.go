@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestUpsertTotpSecret(t *testing.T) {
+	user := CreateRandomUser(t)
+
+	arg := UpsertTotpSecretParams{Username: user.Username, EncryptedSecret: []byte(utils.RandomString(40))}
+	secret, err := testQueries.UpsertTotpSecret(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.EncryptedSecret, secret.EncryptedSecret)
+	require.False(t, secret.EnabledAt.Valid)
+
+	// enrolling again before verifying replaces the secret
+	arg.EncryptedSecret = []byte(utils.RandomString(40))
+	secret, err = testQueries.UpsertTotpSecret(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.EncryptedSecret, secret.EncryptedSecret)
+
+	enabled, err := testQueries.EnableTotpSecret(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.True(t, enabled.EnabledAt.Valid)
+
+	// an enabled secret is neither replaced nor enabled again
+	_, err = testQueries.UpsertTotpSecret(context.Background(), UpsertTotpSecretParams{Username: user.Username, EncryptedSecret: []byte("other")})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = testQueries.EnableTotpSecret(context.Background(), user.Username)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	stored, err := testQueries.GetTotpSecret(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, arg.EncryptedSecret, stored.EncryptedSecret)
+	require.Equal(t, enabled.EnabledAt.Time, stored.EnabledAt.Time)
+}
@@ -7,23 +7,62 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
 )
 
+const countTransfers = `-- name: CountTransfers :one
+SELECT COUNT(*)
+FROM transfers
+WHERE from_account_id = $1
+   OR to_account_id = $2
+`
+
+type CountTransfersParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+}
+
+func (q *Queries) CountTransfers(ctx context.Context, arg CountTransfersParams) (int64, error) {
+	row := q.queryRow(ctx, q.countTransfersStmt, countTransfers, arg.FromAccountID, arg.ToAccountID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (from_account_id,
                       to_account_id,
-                      amount)
-VALUES ($1, $2, $3) RETURNING id, from_account_id, to_account_id, amount, created_at
+                      amount,
+                      to_amount,
+                      reversed_from,
+                      description,
+                      uuid)
+VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, from_account_id, to_account_id, amount, created_at, reversed_from, reversed_at, description, to_amount, uuid
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64          `json:"from_account_id"`
+	ToAccountID   int64          `json:"to_account_id"`
+	Amount        int64          `json:"amount"`
+	ToAmount      int64          `json:"to_amount"`
+	ReversedFrom  sql.NullInt64  `json:"reversed_from"`
+	Description   sql.NullString `json:"description"`
+	UUID          uuid.UUID      `json:"uuid"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.queryRow(ctx, q.createTransferStmt, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.queryRow(ctx, q.createTransferStmt, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.ReversedFrom,
+		arg.Description,
+		arg.UUID,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -31,6 +70,11 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ReversedFrom,
+		&i.ReversedAt,
+		&i.Description,
+		&i.ToAmount,
+		&i.UUID,
 	)
 	return i, err
 }
@@ -47,7 +91,7 @@ func (q *Queries) DeleteTransfer(ctx context.Context, id int64) error {
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at
+SELECT id, from_account_id, to_account_id, amount, created_at, reversed_from, reversed_at, description, to_amount, uuid
 FROM transfers
 WHERE id = $1 LIMIT 1
 `
@@ -61,24 +105,87 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ReversedFrom,
+		&i.ReversedAt,
+		&i.Description,
+		&i.ToAmount,
+		&i.UUID,
+	)
+	return i, err
+}
+
+const getTransferByUUID = `-- name: GetTransferByUUID :one
+SELECT id, from_account_id, to_account_id, amount, created_at, reversed_from, reversed_at, description, to_amount, uuid
+FROM transfers
+WHERE uuid = $1 LIMIT 1
+`
+
+func (q *Queries) GetTransferByUUID(ctx context.Context, uuid uuid.UUID) (Transfer, error) {
+	row := q.queryRow(ctx, q.getTransferByUUIDStmt, getTransferByUUID, uuid)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ReversedFrom,
+		&i.ReversedAt,
+		&i.Description,
+		&i.ToAmount,
+		&i.UUID,
+	)
+	return i, err
+}
+
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, reversed_from, reversed_at, description, to_amount, uuid
+FROM transfers
+WHERE id = $1 LIMIT 1 FOR NO KEY
+UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.queryRow(ctx, q.getTransferForUpdateStmt, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ReversedFrom,
+		&i.ReversedAt,
+		&i.Description,
+		&i.ToAmount,
+		&i.UUID,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at
+SELECT id, from_account_id, to_account_id, amount, created_at, reversed_from, reversed_at, description, to_amount, uuid
 FROM transfers
-ORDER BY id LIMIT $1
-OFFSET $2
+WHERE from_account_id = $1
+   OR to_account_id = $2
+ORDER BY created_at DESC, id DESC
+LIMIT $3 OFFSET $4
 `
 
 type ListTransfersParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Limit         int32 `json:"limit"`
+	Offset        int32 `json:"offset"`
 }
 
 func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
-	rows, err := q.query(ctx, q.listTransfersStmt, listTransfers, arg.Limit, arg.Offset)
+	rows, err := q.query(ctx, q.listTransfersStmt, listTransfers,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +199,12 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ReversedFrom,
+			&i.ReversedAt,
+			&i.Description,
+			&i.ToAmount,
+			&i.UUID,
+			&i.UUID,
 		); err != nil {
 			return nil, err
 		}
@@ -105,3 +218,49 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 	}
 	return items, nil
 }
+
+const markTransferReversed = `-- name: MarkTransferReversed :one
+UPDATE transfers
+SET reversed_at = now()
+WHERE id = $1
+  AND reversed_at IS NULL RETURNING id, from_account_id, to_account_id, amount, created_at, reversed_from, reversed_at, description, to_amount, uuid
+`
+
+func (q *Queries) MarkTransferReversed(ctx context.Context, id int64) (Transfer, error) {
+	row := q.queryRow(ctx, q.markTransferReversedStmt, markTransferReversed, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ReversedFrom,
+		&i.ReversedAt,
+		&i.Description,
+		&i.ToAmount,
+		&i.UUID,
+	)
+	return i, err
+}
+
+const sumOutgoingTransfers = `-- name: SumOutgoingTransfers :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total
+FROM transfers
+WHERE from_account_id = $1
+  AND created_at >= $2::timestamptz
+  AND reversed_from IS NULL
+`
+
+type SumOutgoingTransfersParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	Since         time.Time `json:"since"`
+}
+
+// the reversals are left out, they give money back instead of spending it
+func (q *Queries) SumOutgoingTransfers(ctx context.Context, arg SumOutgoingTransfersParams) (int64, error) {
+	row := q.queryRow(ctx, q.sumOutgoingTransfersStmt, sumOutgoingTransfers, arg.FromAccountID, arg.Since)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"github.com/google/uuid"
 	"github.com/micaelapucciariello/simplebank/utils"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -13,6 +15,7 @@ func createRandomTransfer(t *testing.T) Transfer {
 		FromAccountID: CreateRandomAccount(t).ID,
 		ToAccountID:   CreateRandomAccount(t).ID,
 		Amount:        utils.RandomBalance(),
+		UUID:          uuid.New(),
 	}
 
 	transfer, err := testQueries.CreateTransfer(context.Background(), args)
@@ -23,6 +26,7 @@ func createRandomTransfer(t *testing.T) Transfer {
 	require.Equal(t, args.ToAccountID, transfer.ToAccountID)
 	require.Equal(t, args.FromAccountID, transfer.FromAccountID)
 	require.Equal(t, args.Amount, transfer.Amount)
+	require.Equal(t, args.UUID, transfer.UUID)
 
 	require.NotZero(t, transfer.CreatedAt)
 	require.NotZero(t, transfer.ID)
@@ -34,6 +38,38 @@ func TestCreateTransfer(t *testing.T) {
 	createRandomTransfer(t)
 }
 
+func TestGetTransferByUUID(t *testing.T) {
+	transfer := createRandomTransfer(t)
+
+	got, err := testQueries.GetTransferByUUID(context.Background(), transfer.UUID)
+	require.NoError(t, err)
+	require.Equal(t, transfer.ID, got.ID)
+	require.Equal(t, transfer.UUID, got.UUID)
+
+	_, err = testQueries.GetTransferByUUID(context.Background(), uuid.New())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCreateTransferWithDescription(t *testing.T) {
+	description := sql.NullString{String: utils.RandomString(20), Valid: true}
+	transfer, err := testQueries.CreateTransfer(context.Background(), CreateTransferParams{
+		FromAccountID: CreateRandomAccount(t).ID,
+		ToAccountID:   CreateRandomAccount(t).ID,
+		Amount:        utils.RandomBalance(),
+		Description:   description,
+		UUID:          uuid.New(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, description, transfer.Description)
+
+	got, err := testQueries.GetTransfer(context.Background(), transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, description, got.Description)
+
+	// a transfer without description stores none
+	require.False(t, createRandomTransfer(t).Description.Valid)
+}
+
 func TestGetTransfer(t *testing.T) {
 	tr := createRandomTransfer(t)
 
@@ -49,6 +85,27 @@ func TestGetTransfer(t *testing.T) {
 	require.WithinDuration(t, tr.CreatedAt.Time, transfer.CreatedAt.Time, time.Second)
 }
 
+func TestGetTransferOfSoftDeletedAccount(t *testing.T) {
+	tr := createRandomTransfer(t)
+
+	deleteEmptiedAccount(t, tr.FromAccountID)
+
+	// the history of a deleted account is kept
+	transfer, err := testQueries.GetTransfer(context.Background(), tr.ID)
+	require.NoError(t, err)
+	require.Equal(t, tr, transfer)
+
+	transfers, err := testQueries.ListTransfers(context.Background(), ListTransfersParams{
+		FromAccountID: tr.FromAccountID,
+		ToAccountID:   tr.FromAccountID,
+		Limit:         5,
+		Offset:        0,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, tr, transfers[0])
+}
+
 func TestDeleteTransfer(t *testing.T) {
 	tr := createRandomTransfer(t)
 	err := testQueries.DeleteTransfer(context.Background(), tr.ID)
@@ -60,20 +117,63 @@ func TestDeleteTransfer(t *testing.T) {
 }
 
 func TestGetTransferList(t *testing.T) {
-	for i := 0; i < 10; i++ {
-		createRandomTransfer(t)
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	for i := 0; i < 5; i++ {
+		createTransferBetween(t, account1.ID, account2.ID)
+		createTransferBetween(t, account2.ID, account1.ID)
 	}
 
 	args := ListTransfersParams{
-		Limit:  5,
-		Offset: 5,
+		FromAccountID: account1.ID,
+		ToAccountID:   account1.ID,
+		Limit:         5,
+		Offset:        5,
 	}
 
 	transfers, err := testQueries.ListTransfers(context.Background(), args)
 	require.NoError(t, err)
 	require.Len(t, transfers, 5)
 
-	for _, transfer := range transfers {
+	for i, transfer := range transfers {
 		require.NotEmpty(t, transfer)
+		require.True(t, transfer.FromAccountID == account1.ID || transfer.ToAccountID == account1.ID)
+
+		// newest transfers come first
+		if i > 0 {
+			require.False(t, transfer.CreatedAt.Time.After(transfers[i-1].CreatedAt.Time))
+		}
+	}
+}
+
+func TestCountTransfers(t *testing.T) {
+	account1 := CreateRandomAccount(t)
+	account2 := CreateRandomAccount(t)
+
+	for i := 0; i < 5; i++ {
+		createTransferBetween(t, account1.ID, account2.ID)
+		createTransferBetween(t, account2.ID, account1.ID)
 	}
+
+	// the count covers transfers in both directions, regardless of pagination
+	total, err := testQueries.CountTransfers(context.Background(), CountTransfersParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account1.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(10), total)
+}
+
+func createTransferBetween(t *testing.T, fromAccountID, toAccountID int64) Transfer {
+	transfer, err := testQueries.CreateTransfer(context.Background(), CreateTransferParams{
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        utils.RandomBalance(),
+		UUID:          uuid.New(),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, transfer)
+
+	return transfer
 }
@@ -7,14 +7,38 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
+const countSearchUsers = `-- name: CountSearchUsers :one
+SELECT COUNT(*)
+FROM users
+WHERE org_id = $1
+  AND (username ILIKE '%' || $2::text || '%' ESCAPE '\'
+    OR full_name ILIKE '%' || $2::text || '%' ESCAPE '\'
+    OR email ILIKE '%' || $2::text || '%' ESCAPE '\')
+`
+
+type CountSearchUsersParams struct {
+	OrgID int64  `json:"org_id"`
+	Query string `json:"query"`
+}
+
+func (q *Queries) CountSearchUsers(ctx context.Context, arg CountSearchUsersParams) (int64, error) {
+	row := q.queryRow(ctx, q.countSearchUsersStmt, countSearchUsers, arg.OrgID, arg.Query)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username,
                    hashed_password,
                    full_name,
-                   email)
-VALUES ($1, $2, $3, $4) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at
+                   email,
+                   org_id)
+VALUES ($1, $2, $3, $4, $5) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, org_id
 `
 
 type CreateUserParams struct {
@@ -22,6 +46,7 @@ type CreateUserParams struct {
 	HashedPassword string `json:"hashed_password"`
 	FullName       string `json:"full_name"`
 	Email          string `json:"email"`
+	OrgID          int64  `json:"org_id"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -30,6 +55,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.HashedPassword,
 		arg.FullName,
 		arg.Email,
+		arg.OrgID,
 	)
 	var i User
 	err := row.Scan(
@@ -39,6 +65,10 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.OrgID,
 	)
 	return i, err
 }
@@ -55,7 +85,7 @@ func (q *Queries) DeleteUser(ctx context.Context, username string) error {
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, org_id
 FROM users
 WHERE username = $1 LIMIT 1
 `
@@ -70,12 +100,40 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.OrgID,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, org_id
+FROM users
+WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.queryRow(ctx, q.getUserByEmailStmt, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.OrgID,
 	)
 	return i, err
 }
 
 const getUserForUpdate = `-- name: GetUserForUpdate :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, org_id
 FROM users
 WHERE username = $1 LIMIT 1 FOR NO KEY
 UPDATE
@@ -91,12 +149,29 @@ func (q *Queries) GetUserForUpdate(ctx context.Context, username string) (User,
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.OrgID,
 	)
 	return i, err
 }
 
+const getUserPasswordChangedAt = `-- name: GetUserPasswordChangedAt :one
+SELECT password_changed_at
+FROM users
+WHERE username = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserPasswordChangedAt(ctx context.Context, username string) (time.Time, error) {
+	row := q.queryRow(ctx, q.getUserPasswordChangedAtStmt, getUserPasswordChangedAt, username)
+	var password_changed_at time.Time
+	err := row.Scan(&password_changed_at)
+	return password_changed_at, err
+}
+
 const listUsers = `-- name: ListUsers :many
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, org_id
 FROM users
 ORDER BY username LIMIT $1
 OFFSET $2
@@ -123,6 +198,116 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 			&i.Email,
 			&i.PasswordChangedAt,
 			&i.CreatedAt,
+			&i.Role,
+			&i.FailedLoginAttempts,
+			&i.LockedUntil,
+			&i.OrgID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordFailedLogin = `-- name: RecordFailedLogin :one
+UPDATE users
+SET failed_login_attempts = CASE
+                                WHEN failed_login_attempts + 1 >= $1::int THEN 0
+                                ELSE failed_login_attempts + 1 END,
+    locked_until          = CASE
+                                WHEN failed_login_attempts + 1 >= $1::int
+                                    THEN $2::timestamptz
+                                ELSE locked_until END
+WHERE username = $3 RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, org_id
+`
+
+type RecordFailedLoginParams struct {
+	MaxAttempts int32     `json:"max_attempts"`
+	LockedUntil time.Time `json:"locked_until"`
+	Username    string    `json:"username"`
+}
+
+func (q *Queries) RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (User, error) {
+	row := q.queryRow(ctx, q.recordFailedLoginStmt, recordFailedLogin, arg.MaxAttempts, arg.LockedUntil, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.OrgID,
+	)
+	return i, err
+}
+
+const resetFailedLogins = `-- name: ResetFailedLogins :exec
+UPDATE users
+SET failed_login_attempts = 0,
+    locked_until          = NULL
+WHERE username = $1
+`
+
+func (q *Queries) ResetFailedLogins(ctx context.Context, username string) error {
+	_, err := q.exec(ctx, q.resetFailedLoginsStmt, resetFailedLogins, username)
+	return err
+}
+
+const searchUsers = `-- name: SearchUsers :many
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, org_id
+FROM users
+WHERE org_id = $1
+  AND (username ILIKE '%' || $2::text || '%' ESCAPE '\'
+    OR full_name ILIKE '%' || $2::text || '%' ESCAPE '\'
+    OR email ILIKE '%' || $2::text || '%' ESCAPE '\')
+ORDER BY username LIMIT $3
+OFFSET $4
+`
+
+type SearchUsersParams struct {
+	OrgID  int64  `json:"org_id"`
+	Query  string `json:"query"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+// the query comes with its wildcards escaped, so it is matched as a literal substring
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error) {
+	rows, err := q.query(ctx, q.searchUsersStmt, searchUsers,
+		arg.OrgID,
+		arg.Query,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.Username,
+			&i.HashedPassword,
+			&i.FullName,
+			&i.Email,
+			&i.PasswordChangedAt,
+			&i.CreatedAt,
+			&i.Role,
+			&i.FailedLoginAttempts,
+			&i.LockedUntil,
+			&i.OrgID,
 		); err != nil {
 			return nil, err
 		}
@@ -139,26 +324,49 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
-SET hashed_password = $2, password_changed_at = $3, email =$4, full_name = $5
-WHERE username = $1 RETURNING username, hashed_password, full_name, email, password_changed_at, created_at
+SET full_name = COALESCE($1, full_name),
+    email     = COALESCE($2, email)
+WHERE username = $3 RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, org_id
 `
 
 type UpdateUserParams struct {
-	Username          string `json:"username"`
-	HashedPassword    string `json:"hashed_password"`
-	PasswordChangedAt string `json:"password_changed_at"`
-	Email             string `json:"email"`
-	FullName          string `json:"full_name"`
+	FullName sql.NullString `json:"full_name"`
+	Email    sql.NullString `json:"email"`
+	Username string         `json:"username"`
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.queryRow(ctx, q.updateUserStmt, updateUser,
-		arg.Username,
-		arg.HashedPassword,
-		arg.PasswordChangedAt,
-		arg.Email,
-		arg.FullName,
+	row := q.queryRow(ctx, q.updateUserStmt, updateUser, arg.FullName, arg.Email, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.OrgID,
 	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password     = $1,
+    password_changed_at = now()
+WHERE username = $2 RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, org_id
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword string `json:"hashed_password"`
+	Username       string `json:"username"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.queryRow(ctx, q.updateUserPasswordStmt, updateUserPassword, arg.HashedPassword, arg.Username)
 	var i User
 	err := row.Scan(
 		&i.Username,
@@ -167,6 +375,10 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.OrgID,
 	)
 	return i, err
 }
@@ -2,8 +2,11 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"github.com/micaelapucciariello/simplebank/utils"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -14,6 +17,7 @@ func CreateRandomUser(t *testing.T) User {
 		HashedPassword: "password",
 		FullName:       utils.RandomOwner(),
 		Email:          utils.RandomEmail(),
+		OrgID:          DefaultOrgID,
 	}
 
 	user, err := testQueries.CreateUser(context.Background(), args)
@@ -24,6 +28,7 @@ func CreateRandomUser(t *testing.T) User {
 	require.Equal(t, args.Username, user.Username)
 	require.Equal(t, args.FullName, user.FullName)
 	require.Equal(t, args.Email, user.Email)
+	require.Equal(t, utils.DepositorRole, user.Role)
 
 	require.NotZero(t, user.CreatedAt)
 	require.NotZero(t, user.PasswordChangedAt)
@@ -50,25 +55,70 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, u.CreatedAt.Time, user.CreatedAt.Time, time.Second)
 }
 
-func TestUpdateUser(t *testing.T) {
+func TestGetUserByEmail(t *testing.T) {
 	u := CreateRandomUser(t)
 
-	args := UpdateUserParams{
+	user, err := testQueries.GetUserByEmail(context.Background(), u.Email)
+	require.NoError(t, err)
+	require.Equal(t, u.Username, user.Username)
+	require.Equal(t, u.Email, user.Email)
+
+	_, err = testQueries.GetUserByEmail(context.Background(), utils.RandomEmail())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUpdateUserFullName(t *testing.T) {
+	u := CreateRandomUser(t)
+	newFullName := utils.RandomOwner()
+
+	user, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
 		Username: u.Username,
-		Email:    utils.RandomEmail(),
-		FullName: utils.RandomOwner(),
-	}
+		FullName: sql.NullString{String: newFullName, Valid: true},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, user)
+
+	require.Equal(t, u.Username, user.Username)
+	require.Equal(t, newFullName, user.FullName)
+	require.Equal(t, u.Email, user.Email)
+	require.Equal(t, u.HashedPassword, user.HashedPassword)
 
-	user, err := testQueries.UpdateUser(context.Background(), args)
+	require.WithinDuration(t, u.CreatedAt.Time, user.CreatedAt.Time, time.Second)
+}
+
+func TestUpdateUserEmail(t *testing.T) {
+	u := CreateRandomUser(t)
+	newEmail := utils.RandomEmail()
+
+	user, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username: u.Username,
+		Email:    sql.NullString{String: newEmail, Valid: true},
+	})
 	require.NoError(t, err)
+	require.NotEmpty(t, user)
 
-	require.Equal(t, args.Username, user.Username)
-	require.Equal(t, args.FullName, user.FullName)
-	require.Equal(t, args.Email, user.Email)
+	require.Equal(t, u.Username, user.Username)
+	require.Equal(t, u.FullName, user.FullName)
+	require.Equal(t, newEmail, user.Email)
+	require.Equal(t, u.HashedPassword, user.HashedPassword)
+}
+
+func TestUpdateUserPassword(t *testing.T) {
+	u := CreateRandomUser(t)
+
+	hashedPassword, err := utils.HashPassword(utils.RandomPassword(), bcrypt.DefaultCost)
+	require.NoError(t, err)
 
+	user, err := testQueries.UpdateUserPassword(context.Background(), UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		Username:       u.Username,
+	})
+	require.NoError(t, err)
 	require.NotEmpty(t, user)
 
-	require.WithinDuration(t, u.CreatedAt.Time, user.CreatedAt.Time, time.Second)
+	require.Equal(t, u.Username, user.Username)
+	require.Equal(t, hashedPassword, user.HashedPassword)
+	require.True(t, user.PasswordChangedAt.After(u.PasswordChangedAt))
 }
 
 func TestDeleteUser(t *testing.T) {
@@ -80,3 +130,108 @@ func TestDeleteUser(t *testing.T) {
 	require.Error(t, err)
 	require.Empty(t, emptyAccount)
 }
+
+func TestRecordFailedLogin(t *testing.T) {
+	u := CreateRandomUser(t)
+	require.Zero(t, u.FailedLoginAttempts)
+	require.False(t, u.LockedUntil.Valid)
+
+	maxAttempts := int32(3)
+	lockedUntil := time.Now().Add(time.Minute).UTC()
+	arg := RecordFailedLoginParams{
+		MaxAttempts: maxAttempts,
+		LockedUntil: lockedUntil,
+		Username:    u.Username,
+	}
+
+	for i := int32(1); i < maxAttempts; i++ {
+		user, err := testQueries.RecordFailedLogin(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, i, user.FailedLoginAttempts)
+		require.False(t, user.LockedUntil.Valid)
+	}
+
+	// reaching the limit locks the user and starts counting again
+	user, err := testQueries.RecordFailedLogin(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, user.FailedLoginAttempts)
+	require.True(t, user.LockedUntil.Valid)
+	require.WithinDuration(t, lockedUntil, user.LockedUntil.Time, time.Second)
+
+	err = testQueries.ResetFailedLogins(context.Background(), u.Username)
+	require.NoError(t, err)
+
+	user, err = testQueries.GetUser(context.Background(), u.Username)
+	require.NoError(t, err)
+	require.Zero(t, user.FailedLoginAttempts)
+	require.False(t, user.LockedUntil.Valid)
+}
+
+func TestSearchUsers(t *testing.T) {
+	user := CreateRandomUser(t)
+	CreateRandomUser(t)
+
+	// usernames are random enough for a part of one to match a single user, whatever its case
+	query := strings.ToUpper(user.Username[1:5])
+	users, err := testQueries.SearchUsers(context.Background(), SearchUsersParams{
+		Query:  query,
+		Limit:  5,
+		Offset: 0,
+		OrgID:  DefaultOrgID,
+	})
+	require.NoError(t, err)
+	require.Contains(t, users, user)
+
+	total, err := testQueries.CountSearchUsers(context.Background(), CountSearchUsersParams{OrgID: DefaultOrgID, Query: query})
+	require.NoError(t, err)
+	require.Equal(t, int64(len(users)), total)
+
+	// emails are searched too
+	users, err = testQueries.SearchUsers(context.Background(), SearchUsersParams{
+		Query:  user.Email,
+		Limit:  5,
+		Offset: 0,
+		OrgID:  DefaultOrgID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []User{user}, users)
+
+	users, err = testQueries.SearchUsers(context.Background(), SearchUsersParams{
+		Query:  utils.RandomString(32),
+		Limit:  5,
+		Offset: 0,
+		OrgID:  DefaultOrgID,
+	})
+	require.NoError(t, err)
+	require.Empty(t, users)
+}
+
+func TestSearchUsersLiteralWildcards(t *testing.T) {
+	prefix := utils.RandomString(12)
+	underscored := CreateRandomUser(t)
+	lookalike := CreateRandomUser(t)
+
+	for username, fullName := range map[string]string{underscored.Username: prefix + "a_b", lookalike.Username: prefix + "axb"} {
+		_, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+			Username: username,
+			FullName: sql.NullString{String: fullName, Valid: true},
+		})
+		require.NoError(t, err)
+	}
+
+	// the handler escapes the query, the escaped underscore only matches itself
+	query := prefix + `a\_b`
+	users, err := testQueries.SearchUsers(context.Background(), SearchUsersParams{
+		Query:  query,
+		Limit:  5,
+		Offset: 0,
+		OrgID:  DefaultOrgID,
+	})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, underscored.Username, users[0].Username)
+
+	total, err := testQueries.CountSearchUsers(context.Background(), CountSearchUsersParams{OrgID: DefaultOrgID, Query: query})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+}
@@ -0,0 +1,18 @@
+package gapi
+
+import (
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// convertUser maps a db user into its public protobuf representation, leaving the hashed password out
+func convertUser(user db.User) *pb.User {
+	return &pb.User{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		PasswordChangedAt: timestamppb.New(user.PasswordChangedAt),
+		CreatedAt:         timestamppb.New(user.CreatedAt.Time),
+	}
+}
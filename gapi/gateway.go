@@ -0,0 +1,29 @@
+package gapi
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/micaelapucciariello/simplebank/pb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"net/http"
+)
+
+// NewGateway returns the HTTP/JSON gateway of the server
+// Its handlers call the server methods in process, so a request runs the same logic whether it comes over gRPC or through the gateway.
+// The JSON fields keep the proto names, the same snake case as the rest of the http api
+func NewGateway(ctx context.Context, server *Server) (http.Handler, error) {
+	mux := runtime.NewServeMux(runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+		MarshalOptions: protojson.MarshalOptions{
+			UseProtoNames:   true,
+			EmitUnpopulated: true,
+		},
+		UnmarshalOptions: protojson.UnmarshalOptions{
+			DiscardUnknown: true,
+		},
+	}))
+
+	if err := pb.RegisterSimpleBankHandlerServer(ctx, mux, server); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
@@ -0,0 +1,125 @@
+package gapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/pb"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+// TestGatewayCreateUser sends the same user over native gRPC and through the gateway, both must reach the store the same way
+func TestGatewayCreateUser(t *testing.T) {
+	user, password := randomUser()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, arg db.CreateUserParams) (db.User, error) {
+			require.Equal(t, user.Username, arg.Username)
+			require.Equal(t, user.FullName, arg.FullName)
+			require.Equal(t, user.Email, arg.Email)
+			require.NoError(t, utils.CheckPassword(password, arg.HashedPassword))
+			return user, nil
+		})
+
+	server := newTestServer(t, store)
+	req := &pb.CreateUserRequest{
+		Username: user.Username,
+		FullName: user.FullName,
+		Email:    user.Email,
+		Password: password,
+	}
+
+	// native gRPC, over an in memory listener
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	pb.RegisterSimpleBankServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	grpcRsp, err := pb.NewSimpleBankClient(conn).CreateUser(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, grpcRsp.GetUser().GetUsername())
+
+	// HTTP/JSON gateway
+	gateway, err := NewGateway(context.Background(), server)
+	require.NoError(t, err)
+
+	body, err := json.Marshal(map[string]string{
+		"username":  user.Username,
+		"full_name": user.FullName,
+		"email":     user.Email,
+		"password":  password,
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/v1/create_user", bytes.NewReader(body))
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	gateway.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var gatewayRsp struct {
+		User struct {
+			Username string `json:"username"`
+			FullName string `json:"full_name"`
+			Email    string `json:"email"`
+		} `json:"user"`
+	}
+	err = json.Unmarshal(recorder.Body.Bytes(), &gatewayRsp)
+	require.NoError(t, err)
+	require.Equal(t, grpcRsp.GetUser().GetUsername(), gatewayRsp.User.Username)
+	require.Equal(t, grpcRsp.GetUser().GetFullName(), gatewayRsp.User.FullName)
+	require.Equal(t, grpcRsp.GetUser().GetEmail(), gatewayRsp.User.Email)
+}
+
+func TestGatewayCreateUserInvalidPassword(t *testing.T) {
+	user, _ := randomUser()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+
+	gateway, err := NewGateway(context.Background(), newTestServer(t, store))
+	require.NoError(t, err)
+
+	body, err := json.Marshal(map[string]string{
+		"username":  user.Username,
+		"full_name": user.FullName,
+		"email":     user.Email,
+		"password":  "short",
+	})
+	require.NoError(t, err)
+
+	// the gRPC status is mapped to its http status
+	request, err := http.NewRequest(http.MethodPost, "/v1/create_user", bytes.NewReader(body))
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	gateway.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
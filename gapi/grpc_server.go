@@ -6,26 +6,37 @@ import (
 	"github.com/micaelapucciariello/simplebank/pb"
 	"github.com/micaelapucciariello/simplebank/token"
 	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/micaelapucciariello/simplebank/worker"
+	"github.com/rs/zerolog"
+	"os"
 )
 
 // Server serves gRPC requests
 type Server struct {
 	pb.UnimplementedSimpleBankServer
-	store  db.Store
-	token  token.Maker
-	config utils.Config
+	store           db.Store
+	token           *token.DurationMaker
+	config          utils.Config
+	logger          zerolog.Logger
+	taskDistributor worker.TaskDistributor
 }
 
-func NewServer(config utils.Config, store db.Store) (server *Server, err error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+func NewServer(config utils.Config, store db.Store, taskDistributor worker.TaskDistributor) (server *Server, err error) {
+	tokenMaker, err := token.NewMaker(config.TokenType, config.TokenSymmetricKey, config.TokenIssuer, config.TokenAudience)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token validator: %w", err)
 	}
+	logger, err := utils.NewLogger(config, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
 
 	server = &Server{
-		store:  store,
-		token:  tokenMaker,
-		config: config,
+		store:           store,
+		token:           token.NewDurationMaker(tokenMaker, config.TokenDuration, config.RefreshTokenDuration, config.StepUpTokenDuration),
+		config:          config,
+		logger:          logger,
+		taskDistributor: taskDistributor,
 	}
 	return
 }
@@ -0,0 +1,49 @@
+package gapi
+
+import (
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/micaelapucciariello/simplebank/worker"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+)
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	config := utils.Config{
+		TokenSymmetricKey:    utils.RandomString(32),
+		TokenDuration:        time.Minute,
+		RefreshTokenDuration: time.Hour,
+	}
+
+	// the login looks up the two-factor secret, none is enrolled unless the test stubbed it first
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		mockStore.EXPECT().GetTotpSecret(gomock.Any(), gomock.Any()).AnyTimes().Return(db.TotpSecret{}, sql.ErrNoRows)
+	}
+
+	server, err := NewServer(config, store, worker.NewTaskDistributor(worker.NewInMemoryBroker(10)))
+	require.NoError(t, err)
+
+	return server
+}
+
+func randomUser() (db.User, string) {
+	password := utils.RandomPassword()
+	hashedPassword, _ := utils.HashPassword(password, bcrypt.DefaultCost)
+	user := db.User{
+		Username:          utils.RandomOwner(),
+		HashedPassword:    hashedPassword,
+		FullName:          utils.RandomOwner(),
+		Email:             utils.RandomEmail(),
+		Role:              utils.DepositorRole,
+		PasswordChangedAt: time.Now().UTC().Truncate(time.Second),
+		CreatedAt:         sql.NullTime{Time: time.Now().UTC().Truncate(time.Second), Valid: true},
+	}
+
+	return user, password
+}
@@ -0,0 +1,48 @@
+package gapi
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	grpcGatewayUserAgentHeader = "grpcgateway-user-agent"
+	userAgentHeader            = "user-agent"
+	xForwardedForHeader        = "x-forwarded-for"
+)
+
+type Metadata struct {
+	UserAgent string
+	ClientIP  string
+}
+
+// extractMetadata reads the client user agent and ip from the incoming request
+// Requests going through the HTTP gateway carry them as forwarded headers, native gRPC ones as the peer address. The gateway
+// calls the handlers in-process, so its calls have no peer: x-forwarded-for is only trusted then, since a native client can
+// send it, along with any gateway prefixed header, set to whatever it likes
+func (s *Server) extractMetadata(ctx context.Context) *Metadata {
+	mtdt := &Metadata{}
+
+	p, hasPeer := peer.FromContext(ctx)
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if userAgents := md.Get(grpcGatewayUserAgentHeader); len(userAgents) > 0 {
+			mtdt.UserAgent = userAgents[0]
+		}
+
+		if userAgents := md.Get(userAgentHeader); len(userAgents) > 0 && mtdt.UserAgent == "" {
+			mtdt.UserAgent = userAgents[0]
+		}
+
+		if clientIPs := md.Get(xForwardedForHeader); len(clientIPs) > 0 && !hasPeer {
+			mtdt.ClientIP = clientIPs[0]
+		}
+	}
+
+	if hasPeer {
+		mtdt.ClientIP = p.Addr.String()
+	}
+
+	return mtdt
+}
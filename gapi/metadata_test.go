@@ -0,0 +1,65 @@
+package gapi
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"testing"
+)
+
+func TestExtractMetadata(t *testing.T) {
+	peerAddr := &net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 51234}
+
+	testCases := []struct {
+		name      string
+		md        metadata.MD
+		peer      bool
+		userAgent string
+		clientIP  string
+	}{
+		{
+			name:      "gateway call",
+			md:        metadata.Pairs(grpcGatewayUserAgentHeader, "curl/8.0", xForwardedForHeader, "203.0.113.9"),
+			userAgent: "curl/8.0",
+			clientIP:  "203.0.113.9",
+		},
+		{
+			name:      "native call forging a gateway header",
+			md:        metadata.Pairs(grpcGatewayUserAgentHeader, "curl/8.0", xForwardedForHeader, "203.0.113.9"),
+			peer:      true,
+			userAgent: "curl/8.0",
+			clientIP:  peerAddr.String(),
+		},
+		{
+			name:      "native call",
+			md:        metadata.Pairs(userAgentHeader, "grpc-go/1.56"),
+			peer:      true,
+			userAgent: "grpc-go/1.56",
+			clientIP:  peerAddr.String(),
+		},
+		{
+			name:      "native call forging a forwarded ip",
+			md:        metadata.Pairs(userAgentHeader, "grpc-go/1.56", xForwardedForHeader, "203.0.113.9"),
+			peer:      true,
+			userAgent: "grpc-go/1.56",
+			clientIP:  peerAddr.String(),
+		},
+	}
+
+	server := &Server{}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tc.md)
+			// the gateway calls the handlers in-process, without a peer
+			if tc.peer {
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: peerAddr})
+			}
+
+			mtdt := server.extractMetadata(ctx)
+			require.Equal(t, tc.userAgent, mtdt.UserAgent)
+			require.Equal(t, tc.clientIP, mtdt.ClientIP)
+		})
+	}
+}
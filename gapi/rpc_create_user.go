@@ -0,0 +1,69 @@
+package gapi
+
+import (
+	"context"
+	"github.com/lib/pq"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/pb"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/micaelapucciariello/simplebank/worker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// unique constraints of the users table
+const (
+	usersUsernameConstraint = "users_pkey"
+	usersEmailConstraint    = "users_email_key"
+)
+
+// CreateUser hashes the user password and stores the new user
+func (s *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	if err := utils.ValidateUsername(req.GetUsername()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid username: %s", err)
+	}
+	if err := utils.ValidateEmail(req.GetEmail()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid email: %s", err)
+	}
+	if err := utils.ValidatePassword(req.GetPassword()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid password: %s", err)
+	}
+
+	hashedPassword, err := utils.HashPassword(req.GetPassword(), s.config.BcryptCost)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to hash password: %s", err)
+	}
+
+	arg := db.CreateUserParams{
+		Username:       utils.NormalizeUsername(req.GetUsername()),
+		HashedPassword: hashedPassword,
+		FullName:       req.GetFullName(),
+		Email:          utils.NormalizeEmail(req.GetEmail()),
+		OrgID:          db.DefaultOrgID,
+	}
+
+	user, err := s.store.CreateUser(ctx, arg)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			switch pqErr.Constraint {
+			case usersEmailConstraint:
+				return nil, status.Errorf(codes.AlreadyExists, "email already in use")
+			case usersUsernameConstraint:
+				return nil, status.Errorf(codes.AlreadyExists, "username already in use")
+			}
+			return nil, status.Errorf(codes.AlreadyExists, "user already exists: %s", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create user: %s", err)
+	}
+
+	// the welcome email is sent in the background, failing to enqueue it doesn't undo the registration
+	err = s.taskDistributor.DistributeTaskSendWelcomeEmail(ctx, &worker.PayloadSendWelcomeEmail{Username: user.Username})
+	if err != nil {
+		s.logger.Error().Err(err).Str("username", user.Username).Msg("cannot distribute welcome email task")
+	}
+
+	rsp := &pb.CreateUserResponse{
+		User: convertUser(user),
+	}
+	return rsp, nil
+}
@@ -0,0 +1,200 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/micaelapucciariello/simplebank/pb"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/micaelapucciariello/simplebank/worker"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestCreateUserRPC(t *testing.T) {
+	user, password := randomUser()
+
+	req := &pb.CreateUserRequest{
+		Username: user.Username,
+		FullName: user.FullName,
+		Email:    user.Email,
+		Password: password,
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.CreateUserResponse, err error)
+	}{
+		{
+			name: "happy path create user",
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateUserParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, user.FullName, arg.FullName)
+						require.Equal(t, user.Email, arg.Email)
+						require.NoError(t, utils.CheckPassword(password, arg.HashedPassword))
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				// check response
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+				require.Equal(t, user.FullName, rsp.GetUser().GetFullName())
+				require.Equal(t, user.Email, rsp.GetUser().GetEmail())
+				require.NotNil(t, rsp.GetUser().GetPasswordChangedAt())
+				require.Equal(t, user.CreatedAt.Time, rsp.GetUser().GetCreatedAt().AsTime())
+			},
+		},
+		{
+			name: "duplicated username",
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pq.Error{Code: "23505", Constraint: usersUsernameConstraint})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				// check response
+				require.Equal(t, codes.AlreadyExists, status.Code(err))
+				require.Equal(t, "username already in use", status.Convert(err).Message())
+			},
+		},
+		{
+			name: "duplicated email",
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pq.Error{Code: "23505", Constraint: usersEmailConstraint})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				// check response
+				require.Equal(t, codes.AlreadyExists, status.Code(err))
+				require.Equal(t, "email already in use", status.Convert(err).Message())
+			},
+		},
+		{
+			name: "internal server error",
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.CreateUserResponse, err error) {
+				// check response
+				require.Error(t, err)
+				require.Equal(t, codes.Internal, status.Code(err))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			rsp, err := server.CreateUser(context.Background(), req)
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
+
+func TestCreateUserRPCUsername(t *testing.T) {
+	user, password := randomUser()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateUserParams) (db.User, error) {
+			require.Equal(t, user.Username, arg.Username)
+			return user, nil
+		})
+
+	server := newTestServer(t, store)
+	req := &pb.CreateUserRequest{
+		Username: "bad-name",
+		FullName: user.FullName,
+		Email:    user.Email,
+		Password: password,
+	}
+	_, err := server.CreateUser(context.Background(), req)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// the username is stored lowercase
+	req.Username = strings.ToUpper(user.Username)
+	_, err = server.CreateUser(context.Background(), req)
+	require.NoError(t, err)
+}
+
+func TestCreateUserRPCInvalidEmail(t *testing.T) {
+	user, password := randomUser()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+
+	server := newTestServer(t, store)
+	for _, email := range []string{"", "not-an-email", "alice@"} {
+		_, err := server.CreateUser(context.Background(), &pb.CreateUserRequest{
+			Username: user.Username,
+			FullName: user.FullName,
+			Email:    email,
+			Password: password,
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err), email)
+	}
+}
+
+func TestCreateUserRPCWelcomeEmailTask(t *testing.T) {
+	user, password := randomUser()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+
+	server := newTestServer(t, store)
+	broker := worker.NewInMemoryBroker(1)
+	server.taskDistributor = worker.NewTaskDistributor(broker)
+
+	_, err := server.CreateUser(context.Background(), &pb.CreateUserRequest{
+		Username: user.Username,
+		FullName: user.FullName,
+		Email:    user.Email,
+		Password: password,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	task, err := broker.Dequeue(ctx)
+	require.NoError(t, err)
+	require.Equal(t, worker.TaskSendWelcomeEmail, task.Type)
+
+	var payload worker.PayloadSendWelcomeEmail
+	require.NoError(t, json.Unmarshal(task.Payload, &payload))
+	require.Equal(t, user.Username, payload.Username)
+}
@@ -0,0 +1,113 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/pb"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+// LoginUser verifies the user credentials and returns a new access token along with a refresh token bound to a session
+func (s *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	user, err := s.getLoginUser(ctx, req.GetUsername())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Errorf(codes.NotFound, "user not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get user: %s", err)
+	}
+
+	if isLocked(user) {
+		return nil, status.Errorf(codes.PermissionDenied, "account is locked until %v", user.LockedUntil.Time.Format(time.RFC3339))
+	}
+
+	err = utils.CheckPassword(req.GetPassword(), user.HashedPassword)
+	if err != nil {
+		if s.config.LoginMaxAttempts <= 0 {
+			return nil, status.Errorf(codes.Unauthenticated, "incorrect password")
+		}
+
+		user, err = s.store.RecordFailedLogin(ctx, db.RecordFailedLoginParams{
+			MaxAttempts: s.config.LoginMaxAttempts,
+			LockedUntil: time.Now().Add(s.config.LoginLockoutDuration),
+			Username:    user.Username,
+		})
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to record failed login: %s", err)
+		}
+		if isLocked(user) {
+			return nil, status.Errorf(codes.PermissionDenied, "account is locked until %v", user.LockedUntil.Time.Format(time.RFC3339))
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "incorrect password")
+	}
+
+	// the two-factor code is only part of the http login, a user who enabled it can't log in through grpc
+	totp, err := s.store.GetTotpSecret(ctx, user.Username)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, status.Errorf(codes.Internal, "failed to get two-factor secret: %s", err)
+	}
+	if err == nil && totp.EnabledAt.Valid {
+		return nil, status.Errorf(codes.FailedPrecondition, "two-factor authentication is enabled, log in through the http api")
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil.Valid {
+		err = s.store.ResetFailedLogins(ctx, user.Username)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to reset failed logins: %s", err)
+		}
+	}
+
+	accessToken, accessPayload, err := s.token.CreateAccessToken(user.Username, user.Role, user.OrgID, user.PasswordChangedAt)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create access token: %s", err)
+	}
+
+	refreshToken, refreshPayload, err := s.token.CreateRefreshToken(user.Username, user.Role, user.OrgID, user.PasswordChangedAt)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create refresh token: %s", err)
+	}
+
+	mtdt := s.extractMetadata(ctx)
+	session, err := s.store.LoginTx(ctx, db.LoginTxParams{
+		CreateSessionParams: db.CreateSessionParams{
+			ID:           refreshPayload.ID,
+			Username:     user.Username,
+			RefreshToken: refreshToken,
+			UserAgent:    mtdt.UserAgent,
+			ClientIp:     mtdt.ClientIP,
+			IsBlocked:    false,
+			ExpiresAt:    sql.NullTime{Time: refreshPayload.ExpiredAt, Valid: true},
+		},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create session: %s", err)
+	}
+
+	rsp := &pb.LoginUserResponse{
+		User:                  convertUser(user),
+		SessionId:             session.ID.String(),
+		AccessToken:           accessToken,
+		RefreshToken:          refreshToken,
+		AccessTokenExpiresAt:  timestamppb.New(accessPayload.ExpiredAt),
+		RefreshTokenExpiresAt: timestamppb.New(refreshPayload.ExpiredAt),
+	}
+	return rsp, nil
+}
+
+// isLocked reports whether the user is still within the lockout that follows too many failed logins
+func isLocked(user db.User) bool {
+	return user.LockedUntil.Valid && user.LockedUntil.Time.After(time.Now())
+}
+
+// getLoginUser reads the user a login identifier names, an email or a username is matched whatever its case
+func (s *Server) getLoginUser(ctx context.Context, identifier string) (db.User, error) {
+	if utils.IsEmailIdentifier(identifier) {
+		return s.store.GetUserByEmail(ctx, utils.NormalizeEmail(identifier))
+	}
+	return s.store.GetUser(ctx, utils.NormalizeUsername(identifier))
+}
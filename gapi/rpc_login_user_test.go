@@ -0,0 +1,242 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestLoginUserRPC(t *testing.T) {
+	user, password := randomUser()
+
+	testCases := []struct {
+		name          string
+		req           *pb.LoginUserRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.LoginUserResponse, err error)
+	}{
+		{
+			name: "happy path login user",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), user.Username).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.LoginTxParams) (db.Session, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, "test-agent", arg.UserAgent)
+						require.Equal(t, "10.0.0.1", arg.ClientIp)
+						return db.Session{ID: arg.ID, Username: arg.Username}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.LoginUserResponse, err error) {
+				// check response
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+				require.NotEmpty(t, rsp.GetAccessToken())
+				require.NotEmpty(t, rsp.GetRefreshToken())
+				require.NotEmpty(t, rsp.GetSessionId())
+				require.True(t, rsp.GetRefreshTokenExpiresAt().AsTime().After(rsp.GetAccessTokenExpiresAt().AsTime()))
+			},
+		},
+		{
+			name: "login by mixed case email",
+			req: &pb.LoginUserRequest{
+				Username: strings.ToUpper(user.Email),
+				Password: password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetUserByEmail(gomock.Any(), user.Email).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.LoginTxParams) (db.Session, error) {
+						return db.Session{ID: arg.ID, Username: arg.Username}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.LoginUserResponse, err error) {
+				// check response
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+			},
+		},
+		{
+			name: "login by mixed case username",
+			req: &pb.LoginUserRequest{
+				Username: strings.ToUpper(user.Username),
+				Password: password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), user.Username).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.LoginTxParams) (db.Session, error) {
+						return db.Session{ID: arg.ID, Username: arg.Username}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, rsp *pb.LoginUserResponse, err error) {
+				// check response
+				require.NoError(t, err)
+				require.Equal(t, user.Username, rsp.GetUser().GetUsername())
+			},
+		},
+		{
+			name: "user not found",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), user.Username).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.LoginUserResponse, err error) {
+				// check response
+				require.Equal(t, codes.NotFound, status.Code(err))
+			},
+		},
+		{
+			name: "locked user",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				lockedUser := user
+				lockedUser.LockedUntil = sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true}
+				store.EXPECT().GetUser(gomock.Any(), user.Username).
+					Times(1).
+					Return(lockedUser, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.LoginUserResponse, err error) {
+				// check response
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+		{
+			name: "incorrect password",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: "wrong-password",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), user.Username).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.LoginUserResponse, err error) {
+				// check response
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name: "internal server error",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), user.Username).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.LoginUserResponse, err error) {
+				// check response
+				require.Equal(t, codes.Internal, status.Code(err))
+			},
+		},
+		{
+			name: "two-factor enabled",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), user.Username).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().GetTotpSecret(gomock.Any(), user.Username).
+					Times(1).
+					Return(db.TotpSecret{Username: user.Username, EnabledAt: sql.NullTime{Time: time.Now(), Valid: true}}, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.LoginUserResponse, err error) {
+				// check response
+				require.Equal(t, codes.FailedPrecondition, status.Code(err))
+			},
+		},
+		{
+			name: "create session error",
+			req: &pb.LoginUserRequest{
+				Username: user.Username,
+				Password: password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				// build stubs
+				store.EXPECT().GetUser(gomock.Any(), user.Username).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().LoginTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, rsp *pb.LoginUserResponse, err error) {
+				// check response
+				require.Equal(t, codes.Internal, status.Code(err))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			// the call is annotated the way the gateway does, so the forwarded ip is trusted
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+				grpcGatewayUserAgentHeader, "test-agent",
+				xForwardedForHeader, "10.0.0.1",
+			))
+			rsp, err := server.LoginUser(ctx, tc.req)
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
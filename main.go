@@ -2,101 +2,178 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
-	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/micaelapucciariello/simplebank/api"
+	"github.com/micaelapucciariello/simplebank/db/migration"
 	db "github.com/micaelapucciariello/simplebank/db/sqlc"
 	"github.com/micaelapucciariello/simplebank/gapi"
 	"github.com/micaelapucciariello/simplebank/pb"
 	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/micaelapucciariello/simplebank/webhook"
+	"github.com/micaelapucciariello/simplebank/worker"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"log"
 	"net"
-	"net/http"
+	"os"
 
 	_ "github.com/lib/pq"
 )
 
+const taskQueueSize = 1024
+
 func main() {
 	cfg, err := utils.LoadConfig("")
 	if err != nil {
 		log.Fatal("cannot get config: ", err)
 	}
-	conn, err := sql.Open(cfg.DriverName, cfg.SourceName)
+	if err = cfg.Validate(); err != nil {
+		log.Fatal("invalid config: ", err)
+	}
+	logger, err := utils.NewLogger(cfg, os.Stdout)
+	if err != nil {
+		log.Fatal("cannot create logger: ", err)
+	}
+	conn, err := utils.OpenDB(cfg)
 	if err != nil {
 		log.Fatal(fmt.Sprintf("cannot connect to db: %s", err))
 	}
+	if cfg.RunMigrationsOnStart {
+		runMigrations(cfg)
+	}
 
-	store := db.NewStore(conn)
-	go runGatewayServer(cfg, store)
-	rungRPCServer(cfg, store)
-}
-
-func runHTTPServer(cfg utils.Config, store db.Store) {
-	server, err := api.NewServer(cfg, store)
+	tracerProvider, shutdownTracing, err := utils.NewTracerProvider(context.Background(), cfg)
 	if err != nil {
-		log.Fatal(fmt.Sprintf("cannot initiate http server: %s", err))
+		log.Fatal("cannot create tracer provider: ", err)
 	}
+	defer shutdownTracing(context.Background())
+	otel.SetTracerProvider(tracerProvider)
 
-	err = server.Start(cfg.HTTPServerAddress)
+	exchangeRates, err := db.ParseExchangeRates(cfg.ExchangeRates)
 	if err != nil {
-		log.Fatal(fmt.Sprintf("cannot start http server: %s", err))
+		log.Fatal("invalid EXCHANGE_RATES: ", err)
 	}
-}
 
-func rungRPCServer(cfg utils.Config, store db.Store) {
-	server, err := gapi.NewServer(cfg, store)
+	// the retries wrap the traced store, so every attempt shows up as its own span and is timed on its own
+	store := db.NewSlowQueryStore(db.NewStoreWithExchangeRates(conn, exchangeRates), cfg.SlowQueryThreshold, logger)
+	store = db.NewRetryStore(db.NewTracedStore(store, tracerProvider.Tracer(utils.TracerName)), db.RetryPolicy{
+		MaxAttempts: cfg.DBRetryMaxAttempts,
+		BaseDelay:   cfg.DBRetryBaseDelay,
+		MaxDelay:    cfg.DBRetryMaxDelay,
+	})
+	// the cache wraps the retries, a cached account is served without any db call
+	if cfg.AccountCacheSize > 0 {
+		store = db.NewCachingStore(store, db.NewLRUAccountCache(cfg.AccountCacheSize, cfg.AccountCacheTTL))
+	}
+	// the tasks are queued in memory, so the ones still queued are lost when the process stops
+	broker := worker.NewInMemoryBroker(taskQueueSize)
+	taskDistributor := worker.NewTaskDistributor(broker)
+	go runTaskProcessor(broker, store, logger)
+	go runOutboxPoller(cfg, store, taskDistributor, logger)
+	go runBalanceSnapshotScheduler(store, logger)
+	go runScheduledTransferRunner(cfg, store, logger)
+	go runHoldExpirer(cfg, store, logger)
+
+	// the gRPC server and the gateway share the same server, so both transports run the same handlers
+	grpcServer, err := gapi.NewServer(cfg, store, taskDistributor)
 	if err != nil {
 		log.Fatal(fmt.Sprintf("cannot initiate gRPC server: %s", err))
 	}
-	grpcServer := grpc.NewServer()
-	pb.RegisterSimpleBankServer(grpcServer, server)
-	reflection.Register(grpcServer)
 
-	listener, err := net.Listen("tcp", cfg.GRPCServerAddress)
-	if err != nil {
-		log.Fatal(fmt.Sprintf("cannot create listener: %s", err))
+	go runHTTPServer(cfg, store, taskDistributor, grpcServer)
+	rungRPCServer(cfg, grpcServer)
+}
+
+// runMigrations applies the embedded migrations the database is missing, the server doesn't start on a database it
+// can't bring to the latest version
+func runMigrations(cfg utils.Config) {
+	if err := migration.Up(cfg.SourceName); err != nil {
+		log.Fatal(fmt.Sprintf("cannot run migrations: %s", err))
 	}
+	log.Printf("db migrated up")
+}
 
-	err = grpcServer.Serve(listener)
-	if err != nil {
-		log.Fatal(fmt.Sprintf("cannot start gRPC server: %s", err))
+func runTaskProcessor(broker worker.Broker, store db.Store, logger zerolog.Logger) {
+	processor := worker.NewTaskProcessor(broker, store, worker.NewLogEmailSender(logger), logger)
+	log.Printf("task processor started")
+	if err := processor.Start(context.Background()); err != nil {
+		log.Fatal(fmt.Sprintf("cannot start task processor: %s", err))
+	}
+}
+
+func runOutboxPoller(cfg utils.Config, store db.Store, taskDistributor worker.TaskDistributor, logger zerolog.Logger) {
+	publish := worker.LogOutboxPublisher(logger)
+	if cfg.WebhookURL != "" {
+		publish = worker.WebhookOutboxPublisher(webhook.NewDispatcher(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookMaxAttempts))
 	}
+	publish = worker.NotificationOutboxPublisher(store, taskDistributor, publish)
 
-	log.Printf("gRPC server started at address %v", listener.Addr().String())
+	poller := worker.NewOutboxPoller(store, publish, cfg.OutboxPollInterval, logger)
+	log.Printf("outbox poller started")
+	if err := poller.Start(context.Background()); err != nil {
+		log.Fatal(fmt.Sprintf("cannot start outbox poller: %s", err))
+	}
 }
 
-func runGatewayServer(cfg utils.Config, store db.Store) {
-	server, err := gapi.NewServer(cfg, store)
-	if err != nil {
-		log.Fatal(fmt.Sprintf("cannot initiate gateway server: %s", err))
+func runBalanceSnapshotScheduler(store db.Store, logger zerolog.Logger) {
+	scheduler := worker.NewBalanceSnapshotScheduler(store, logger)
+	log.Printf("balance snapshot scheduler started")
+	if err := scheduler.Start(context.Background()); err != nil {
+		log.Fatal(fmt.Sprintf("cannot start balance snapshot scheduler: %s", err))
 	}
+}
 
-	grpcMux := runtime.NewServeMux()
+func runScheduledTransferRunner(cfg utils.Config, store db.Store, logger zerolog.Logger) {
+	runner := worker.NewScheduledTransferRunner(store, cfg.SchedulePollInterval, cfg.DailyTransferLimit, logger)
+	log.Printf("scheduled transfer runner started")
+	if err := runner.Start(context.Background()); err != nil {
+		log.Fatal(fmt.Sprintf("cannot start scheduled transfer runner: %s", err))
+	}
+}
 
-	// invokes the cancel context function when the execution is completed
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+func runHoldExpirer(cfg utils.Config, store db.Store, logger zerolog.Logger) {
+	expirer := worker.NewHoldExpirer(store, cfg.HoldPollInterval, logger)
+	log.Printf("hold expirer started")
+	if err := expirer.Start(context.Background()); err != nil {
+		log.Fatal(fmt.Sprintf("cannot start hold expirer: %s", err))
+	}
+}
 
-	err = pb.RegisterSimpleBankHandlerServer(ctx, grpcMux, server)
+func runHTTPServer(cfg utils.Config, store db.Store, taskDistributor worker.TaskDistributor, grpcServer *gapi.Server) {
+	server, err := api.NewServer(cfg, store, taskDistributor)
 	if err != nil {
-		log.Fatal(fmt.Sprintf("cannot create server handler: %s", err))
+		log.Fatal(fmt.Sprintf("cannot initiate http server: %s", err))
 	}
+	defer server.Close()
 
-	mux := http.NewServeMux()
-	mux.Handle("/", grpcMux)
+	gateway, err := gapi.NewGateway(context.Background(), grpcServer)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("cannot create gateway: %s", err))
+	}
+	server.MountGateway(gateway)
 
-	listener, err := net.Listen("tcp", cfg.HTTPServerAddress)
+	log.Printf("HTTP server and gateway listening at address %v", cfg.HTTPServerAddress)
+	err = server.Start(cfg.HTTPServerAddress)
 	if err != nil {
-		log.Fatal(fmt.Sprintf("cannot create listener: %s", err))
+		log.Fatal(fmt.Sprintf("cannot start http server: %s", err))
 	}
-	log.Printf("HTTP Gateway server listening at address %v", listener.Addr().String())
-	err = http.Serve(listener, mux)
+}
+
+func rungRPCServer(cfg utils.Config, server *gapi.Server) {
+	grpcServer := grpc.NewServer()
+	pb.RegisterSimpleBankServer(grpcServer, server)
+	reflection.Register(grpcServer)
+
+	listener, err := net.Listen("tcp", cfg.GRPCServerAddress)
 	if err != nil {
-		log.Fatal(fmt.Sprintf("cannot start HTTP Gateway server: %s", err))
+		log.Fatal(fmt.Sprintf("cannot create listener: %s", err))
 	}
 
-	log.Printf("HTTP Gateway server started at address %v", listener.Addr().String())
+	log.Printf("gRPC server listening at address %v", listener.Addr().String())
+	err = grpcServer.Serve(listener)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("cannot start gRPC server: %s", err))
+	}
 }
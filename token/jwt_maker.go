@@ -11,24 +11,31 @@ const minKeySize = 32
 
 type JWTMaker struct {
 	secretKey string
+	issuer    string
+	audience  string
 }
 
-func NewJWTMaker(secreykey string) (Maker, error) {
+func NewJWTMaker(secreykey, issuer, audience string) (Maker, error) {
 	if len(secreykey) < minKeySize {
 		return nil, errors.New(fmt.Sprintf("error: invalid key size. must be aqt least%v characters", minKeySize))
 	}
 
-	return &JWTMaker{secreykey}, nil
+	return &JWTMaker{secretKey: secreykey, issuer: issuer, audience: audience}, nil
 }
 
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(username, duration)
+func (maker *JWTMaker) CreateToken(kind, username, role string, orgID int64, passwordChangedAt time.Time, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(kind, username, role, orgID, passwordChangedAt, duration)
 	if err != nil {
 		return "", nil, err
 	}
+	payload.Issuer = maker.issuer
+	payload.Audience = maker.audience
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	signedToken, _ := jwtToken.SignedString([]byte(maker.secretKey))
+	signedToken, err := jwtToken.SignedString([]byte(maker.secretKey))
+	if err != nil {
+		return "", nil, err
+	}
 	return signedToken, payload, nil
 }
 
@@ -43,9 +50,9 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 
 	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
 	if err != nil {
-		// json validator error
+		// json validator error, the inner error is the one returned by Payload.Valid
 		verr, ok := err.(*jwt.ValidationError)
-		if ok && errors.Is(verr.Inner, jwt.ErrTokenExpired) {
+		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
 			return nil, ErrExpiredToken
 		}
 		return nil, ErrInvalidToken
@@ -55,5 +62,10 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 	if !ok {
 		return nil, ErrInvalidToken
 	}
+
+	err = payload.verifyAudience(maker.audience)
+	if err != nil {
+		return nil, err
+	}
 	return payload, nil
 }
@@ -9,15 +9,17 @@ import (
 )
 
 func TestJWTMaker(t *testing.T) {
-	maker, err := NewJWTMaker(utils.RandomString(32))
+	maker, err := NewJWTMaker(utils.RandomString(32), "", "")
 	require.NoError(t, err)
 
 	username := utils.RandomOwner()
+	orgID := utils.RandomInt(1, 1000)
+	passwordChangedAt := time.Now().Add(-time.Hour).UTC()
 	duration := time.Minute
 	issuedAt := time.Now()
 	expiredAt := time.Now().Add(duration)
 
-	token, payload, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(KindAccess, username, utils.DepositorRole, orgID, passwordChangedAt, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 	require.NotEmpty(t, payload)
@@ -28,33 +30,37 @@ func TestJWTMaker(t *testing.T) {
 
 	require.NotZero(t, payload.ID)
 	require.Equal(t, username, payload.UserName)
+	require.Equal(t, KindAccess, payload.Kind)
+	require.Equal(t, orgID, payload.OrgID)
+	require.True(t, passwordChangedAt.Equal(payload.PasswordChangedAt))
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 }
 
 func TestJWTExpiredToken(t *testing.T) {
-	maker, err := NewJWTMaker(utils.RandomString(32))
+	maker, err := NewJWTMaker(utils.RandomString(32), "", "")
 	require.NoError(t, err)
 
-	token, payload, err := maker.CreateToken(utils.RandomString(32), -time.Minute)
+	token, payload, err := maker.CreateToken(KindAccess, utils.RandomString(32), utils.DepositorRole, 1, time.Now(), -time.Minute)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
 	require.NotEmpty(t, payload)
 
 	payload, err = maker.VerifyToken(token)
 	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
 	require.Nil(t, payload)
 }
 
 func TestJWTInvalidToken(t *testing.T) {
-	payload, err := NewPayload(utils.RandomString(32), time.Minute)
+	payload, err := NewPayload(KindAccess, utils.RandomString(32), utils.DepositorRole, 1, time.Now(), time.Minute)
 	require.NoError(t, err)
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
 	token, err := jwtToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
 	require.NoError(t, err)
 
-	maker, err := NewJWTMaker(utils.RandomString(32))
+	maker, err := NewJWTMaker(utils.RandomString(32), "", "")
 	require.NoError(t, err)
 
 	payload, err = maker.VerifyToken(token)
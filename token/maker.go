@@ -1,8 +1,58 @@
 package token
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	TypePaseto = "paseto"
+	TypeJWT    = "jwt"
+)
 
 type Maker interface {
-	CreateToken(username string, duration time.Duration) (string, *Payload, error)
+	CreateToken(kind, username, role string, orgID int64, passwordChangedAt time.Time, duration time.Duration) (string, *Payload, error)
 	VerifyToken(token string) (*Payload, error)
 }
+
+// NewMaker builds the maker for the configured token type, defaulting to paseto when none is set
+// Tokens are minted for the given issuer and audience, and only tokens for that audience are accepted back
+func NewMaker(tokenType, key, issuer, audience string) (Maker, error) {
+	switch tokenType {
+	case "", TypePaseto:
+		return NewPasetoMaker(key, issuer, audience)
+	case TypeJWT:
+		return NewJWTMaker(key, issuer, audience)
+	}
+	return nil, fmt.Errorf("unsupported token type: %v", tokenType)
+}
+
+// DurationMaker mints the access, refresh and step-up tokens, each kind with its own duration
+// Step-up tokens are short lived, they prove the user just authenticated again before a sensitive operation such as a password change
+type DurationMaker struct {
+	Maker
+	AccessDuration  time.Duration
+	RefreshDuration time.Duration
+	StepUpDuration  time.Duration
+}
+
+func NewDurationMaker(maker Maker, accessDuration, refreshDuration, stepUpDuration time.Duration) *DurationMaker {
+	return &DurationMaker{
+		Maker:           maker,
+		AccessDuration:  accessDuration,
+		RefreshDuration: refreshDuration,
+		StepUpDuration:  stepUpDuration,
+	}
+}
+
+func (m *DurationMaker) CreateAccessToken(username, role string, orgID int64, passwordChangedAt time.Time) (string, *Payload, error) {
+	return m.CreateToken(KindAccess, username, role, orgID, passwordChangedAt, m.AccessDuration)
+}
+
+func (m *DurationMaker) CreateRefreshToken(username, role string, orgID int64, passwordChangedAt time.Time) (string, *Payload, error) {
+	return m.CreateToken(KindRefresh, username, role, orgID, passwordChangedAt, m.RefreshDuration)
+}
+
+func (m *DurationMaker) CreateStepUpToken(username, role string, orgID int64, passwordChangedAt time.Time) (string, *Payload, error) {
+	return m.CreateToken(KindStepUp, username, role, orgID, passwordChangedAt, m.StepUpDuration)
+}
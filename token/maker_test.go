@@ -0,0 +1,191 @@
+package token
+
+import (
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNewMaker(t *testing.T) {
+	testCases := []struct {
+		name      string
+		tokenType string
+		checkType func(t *testing.T, maker Maker, err error)
+	}{
+		{
+			name:      "default to paseto",
+			tokenType: "",
+			checkType: func(t *testing.T, maker Maker, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &PasetoMaker{}, maker)
+			},
+		},
+		{
+			name:      "paseto",
+			tokenType: TypePaseto,
+			checkType: func(t *testing.T, maker Maker, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &PasetoMaker{}, maker)
+			},
+		},
+		{
+			name:      "jwt",
+			tokenType: TypeJWT,
+			checkType: func(t *testing.T, maker Maker, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &JWTMaker{}, maker)
+			},
+		},
+		{
+			name:      "unsupported type",
+			tokenType: "macaroon",
+			checkType: func(t *testing.T, maker Maker, err error) {
+				require.Error(t, err)
+				require.Nil(t, maker)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := NewMaker(tc.tokenType, utils.RandomString(32), "", "")
+			tc.checkType(t, maker, err)
+		})
+	}
+}
+
+func TestMakersSharePayload(t *testing.T) {
+	username := utils.RandomOwner()
+	passwordChangedAt := time.Now().Add(-time.Hour).UTC()
+
+	var payloads []*Payload
+	for _, tokenType := range []string{TypePaseto, TypeJWT} {
+		maker, err := NewMaker(tokenType, utils.RandomString(32), "", "")
+		require.NoError(t, err)
+
+		token, _, err := maker.CreateToken(KindAccess, username, utils.BankerRole, 1, passwordChangedAt, time.Minute)
+		require.NoError(t, err)
+
+		payload, err := maker.VerifyToken(token)
+		require.NoError(t, err)
+		payloads = append(payloads, payload)
+	}
+
+	// both makers round trip the same claims
+	require.Equal(t, payloads[0].UserName, payloads[1].UserName)
+	require.Equal(t, utils.BankerRole, payloads[0].Role)
+	require.Equal(t, payloads[0].Role, payloads[1].Role)
+	require.Equal(t, KindAccess, payloads[0].Kind)
+	require.Equal(t, payloads[0].Kind, payloads[1].Kind)
+	require.True(t, payloads[0].PasswordChangedAt.Equal(payloads[1].PasswordChangedAt))
+	require.WithinDuration(t, payloads[0].IssuedAt, payloads[1].IssuedAt, time.Second)
+	require.WithinDuration(t, payloads[0].ExpiredAt, payloads[1].ExpiredAt, time.Second)
+}
+
+func TestMakerAudience(t *testing.T) {
+	for _, tokenType := range []string{TypePaseto, TypeJWT} {
+		tokenType := tokenType
+
+		t.Run(tokenType, func(t *testing.T) {
+			// both services share the signing key but serve different audiences
+			key := utils.RandomString(32)
+			apiMaker, err := NewMaker(tokenType, key, "simplebank", "simplebank-api")
+			require.NoError(t, err)
+			otherMaker, err := NewMaker(tokenType, key, "simplebank", "simplebank-reports")
+			require.NoError(t, err)
+
+			token, payload, err := apiMaker.CreateToken(KindAccess, utils.RandomOwner(), utils.DepositorRole, 1, time.Now(), time.Minute)
+			require.NoError(t, err)
+			require.Equal(t, "simplebank", payload.Issuer)
+			require.Equal(t, "simplebank-api", payload.Audience)
+
+			payload, err = apiMaker.VerifyToken(token)
+			require.NoError(t, err)
+			require.Equal(t, "simplebank", payload.Issuer)
+			require.Equal(t, "simplebank-api", payload.Audience)
+
+			payload, err = otherMaker.VerifyToken(token)
+			require.Error(t, err)
+			require.EqualError(t, err, ErrInvalidAudience.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestDurationMaker(t *testing.T) {
+	maker, err := NewMaker(TypePaseto, utils.RandomString(32), "", "")
+	require.NoError(t, err)
+	durationMaker := NewDurationMaker(maker, 15*time.Minute, 24*time.Hour, 2*time.Minute)
+
+	username := utils.RandomOwner()
+	passwordChangedAt := time.Now().Add(-time.Hour).UTC()
+
+	testCases := []struct {
+		name        string
+		createToken func(username, role string, orgID int64, passwordChangedAt time.Time) (string, *Payload, error)
+		duration    time.Duration
+	}{
+		{
+			name:        "access token",
+			createToken: durationMaker.CreateAccessToken,
+			duration:    15 * time.Minute,
+		},
+		{
+			name:        "refresh token",
+			createToken: durationMaker.CreateRefreshToken,
+			duration:    24 * time.Hour,
+		},
+		{
+			name:        "step-up token",
+			createToken: durationMaker.CreateStepUpToken,
+			duration:    2 * time.Minute,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			token, payload, err := tc.createToken(username, utils.DepositorRole, 1, passwordChangedAt)
+			require.NoError(t, err)
+			require.WithinDuration(t, time.Now().Add(tc.duration), payload.ExpiredAt, time.Second)
+
+			// the expiry survives the round trip through the token
+			payload, err = durationMaker.VerifyToken(token)
+			require.NoError(t, err)
+			require.Equal(t, username, payload.UserName)
+			require.WithinDuration(t, payload.IssuedAt.Add(tc.duration), payload.ExpiredAt, time.Second)
+		})
+	}
+}
+
+func TestDurationMakerKinds(t *testing.T) {
+	maker, err := NewMaker(TypePaseto, utils.RandomString(32), "", "")
+	require.NoError(t, err)
+	durationMaker := NewDurationMaker(maker, time.Minute, time.Hour, 5*time.Minute)
+
+	username := utils.RandomOwner()
+	create := map[string]func(username, role string, orgID int64, passwordChangedAt time.Time) (string, *Payload, error){
+		KindAccess:  durationMaker.CreateAccessToken,
+		KindRefresh: durationMaker.CreateRefreshToken,
+		KindStepUp:  durationMaker.CreateStepUpToken,
+	}
+	for kind, createToken := range create {
+		token, _, err := createToken(username, utils.DepositorRole, 1, time.Now())
+		require.NoError(t, err)
+
+		payload, err := durationMaker.VerifyToken(token)
+		require.NoError(t, err)
+		require.NoError(t, payload.VerifyKind(kind))
+
+		// every other kind is rejected
+		for otherKind := range create {
+			if otherKind != kind {
+				require.ErrorIs(t, payload.VerifyKind(otherKind), ErrWrongKind)
+			}
+		}
+	}
+}
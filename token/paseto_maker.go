@@ -12,9 +12,11 @@ var ErrInvalidKeySize = errors.New("invalid key size")
 type PasetoMaker struct {
 	paseto       *paseto.V2
 	symmetricKey []byte
+	issuer       string
+	audience     string
 }
 
-func NewPasetoMaker(symmetricKey string) (Maker, error) {
+func NewPasetoMaker(symmetricKey, issuer, audience string) (Maker, error) {
 	if len(symmetricKey) != aead.KeySize {
 		return nil, ErrInvalidKeySize
 	}
@@ -22,16 +24,20 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 	maker := PasetoMaker{
 		paseto:       paseto.NewV2(),
 		symmetricKey: []byte(symmetricKey),
+		issuer:       issuer,
+		audience:     audience,
 	}
 
 	return &maker, nil
 }
 
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(username, duration)
+func (maker *PasetoMaker) CreateToken(kind, username, role string, orgID int64, passwordChangedAt time.Time, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(kind, username, role, orgID, passwordChangedAt, duration)
 	if err != nil {
 		return "", nil, err
 	}
+	payload.Issuer = maker.issuer
+	payload.Audience = maker.audience
 
 	encrypt, err := maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
 	if err != nil {
@@ -54,5 +60,10 @@ func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, err
 	}
 
+	err = payload.verifyAudience(maker.audience)
+	if err != nil {
+		return nil, err
+	}
+
 	return payload, nil
 }
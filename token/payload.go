@@ -8,25 +8,49 @@ import (
 
 var ErrExpiredToken = errors.New("token is expired")
 var ErrInvalidToken = errors.New("token is invalid")
+var ErrInvalidAudience = errors.New("token was minted for a different audience")
+var ErrWrongKind = errors.New("token kind doesn't match its use")
+
+// the kinds of token, a token is only accepted where its kind is expected: a refresh token can't authenticate a request
+const (
+	KindAccess  = "access"
+	KindRefresh = "refresh"
+	KindStepUp  = "step_up"
+)
 
 type Payload struct {
-	ID        uuid.UUID `json:"id"`
-	UserName  string    `json:"user_name"`
+	ID       uuid.UUID `json:"id"`
+	UserName string    `json:"user_name"`
+	Role     string    `json:"role"`
+	// Kind tells what the token was minted for, tokens minted before kinds were added have none and are accepted nowhere
+	Kind string `json:"kind"`
+	// OrgID is the organization of the user, tokens minted before organizations were added have none
+	OrgID int64 `json:"org_id"`
+	// PasswordChangedAt is the user password change time when the token was minted
+	// tokens minted before a later password change are no longer accepted
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+	// Issuer and Audience let services sharing a signing key tell apart the tokens minted for each other
+	Issuer    string    `json:"issuer"`
+	Audience  string    `json:"audience"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
 
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+func NewPayload(kind, username, role string, orgID int64, passwordChangedAt time.Time, duration time.Duration) (*Payload, error) {
 	id, err := uuid.NewUUID()
 	if err != nil {
 		return nil, errors.New("error generating token id")
 	}
 
 	payload := Payload{
-		ID:        id,
-		UserName:  username,
-		IssuedAt:  time.Now(),
-		ExpiredAt: time.Now().Add(duration),
+		ID:                id,
+		Kind:              kind,
+		UserName:          username,
+		Role:              role,
+		OrgID:             orgID,
+		PasswordChangedAt: passwordChangedAt,
+		IssuedAt:          time.Now(),
+		ExpiredAt:         time.Now().Add(duration),
 	}
 
 	return &payload, nil
@@ -38,3 +62,19 @@ func (p Payload) Valid() error {
 	}
 	return nil
 }
+
+// verifyAudience rejects tokens that weren't minted for the given audience
+func (p Payload) verifyAudience(audience string) error {
+	if p.Audience != audience {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+// VerifyKind rejects tokens that weren't minted for the given use
+func (p Payload) VerifyKind(kind string) error {
+	if p.Kind != kind {
+		return ErrWrongKind
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// AccountNumberLength is the number of digits of an account number
+const AccountNumberLength = 16
+
+var maxAccountNumber = new(big.Int).Exp(big.NewInt(10), big.NewInt(AccountNumberLength), nil)
+
+// NewAccountNumber returns AccountNumberLength random digits, it's read from crypto/rand so an account number can't be guessed from another one
+// It may have leading zeros, account numbers are strings
+func NewAccountNumber() (string, error) {
+	n, err := rand.Int(rand.Reader, maxAccountNumber)
+	if err != nil {
+		return "", fmt.Errorf("generate account number: %w", err)
+	}
+	return fmt.Sprintf("%0*d", AccountNumberLength, n), nil
+}
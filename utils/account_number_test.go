@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestNewAccountNumber(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		number, err := NewAccountNumber()
+		require.NoError(t, err)
+		require.Len(t, number, AccountNumberLength)
+		require.Empty(t, strings.Trim(number, "0123456789"))
+
+		require.False(t, seen[number])
+		seen[number] = true
+	}
+}
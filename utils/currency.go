@@ -6,8 +6,8 @@ const (
 	EUR = "EUR"
 )
 
-// IsSupported returns true if the currency is supported
-func IsSupported(currency string) bool {
+// IsSupportedCurrency returns true if the currency is supported
+func IsSupportedCurrency(currency string) bool {
 	switch currency {
 	case USD, ARS, EUR:
 		return true
@@ -0,0 +1,18 @@
+package utils
+
+import "database/sql"
+
+// OpenDB opens the database handle and sizes its connection pool from the config
+// Without limits database/sql opens a new connection for every concurrent query, which under load exhausts the Postgres connections
+func OpenDB(config Config) (*sql.DB, error) {
+	conn, err := sql.Open(config.DriverName, config.SourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetMaxOpenConns(config.MaxOpenConns)
+	conn.SetMaxIdleConns(config.MaxIdleConns)
+	conn.SetConnMaxLifetime(config.ConnMaxLifetime)
+
+	return conn, nil
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	// emailValidator is the validator gin binds the requests with, so an email accepted by one API is accepted by the other
+	emailValidator = validator.New()
+
+	ErrEmailFormat = errors.New("email must be a valid email address")
+)
+
+// NormalizeEmail returns the form emails are stored and looked up in, so an email matches whatever case it was typed in
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail checks the email with the same rules as the "required,email" binding of the HTTP requests
+func ValidateEmail(email string) error {
+	if err := emailValidator.Var(email, "required,email"); err != nil {
+		return ErrEmailFormat
+	}
+	return nil
+}
+
+// IsEmailIdentifier tells a login identifier holding an email from a username, usernames are alphanumeric
+func IsEmailIdentifier(identifier string) bool {
+	return strings.Contains(identifier, "@")
+}
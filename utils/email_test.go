@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateEmail(t *testing.T) {
+	testCases := []struct {
+		name  string
+		email string
+		err   error
+	}{
+		{name: "valid email", email: "alice@example.com", err: nil},
+		{name: "random email", email: RandomEmail(), err: nil},
+		{name: "empty", email: "", err: ErrEmailFormat},
+		{name: "missing domain", email: "alice@", err: ErrEmailFormat},
+		{name: "missing at sign", email: "alice.example.com", err: ErrEmailFormat},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.err, ValidateEmail(tc.email))
+		})
+	}
+}
@@ -1,32 +1,369 @@
 package utils
 
 import (
+	"fmt"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
-// Config these values are read by viper from the config.env configuration file
+// Config these values are read by viper from the config.env configuration file, env variables and the override file take precedence over it
 type Config struct {
 	DriverName           string        `mapstructure:"DB_DRIVER"`
 	SourceName           string        `mapstructure:"DB_SOURCE"`
+	MaxOpenConns         int           `mapstructure:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns         int           `mapstructure:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime      time.Duration `mapstructure:"DB_CONN_MAX_LIFETIME"`  // connections are recycled after it, so they follow database failovers
+	DBRetryMaxAttempts   int           `mapstructure:"DB_RETRY_MAX_ATTEMPTS"` // attempts of a store call failing with a serialization failure or deadlock, 1 disables retries
+	DBRetryBaseDelay     time.Duration `mapstructure:"DB_RETRY_BASE_DELAY"`
+	DBRetryMaxDelay      time.Duration `mapstructure:"DB_RETRY_MAX_DELAY"`
+	SlowQueryThreshold   time.Duration `mapstructure:"DB_SLOW_QUERY_THRESHOLD"` // store calls taking longer are logged at warn level, 0 disables it
+	RunMigrationsOnStart bool          `mapstructure:"RUN_MIGRATIONS_ON_START"` // the pending migrations are applied before serving, a dirty database stops the server
+	AccountCacheSize     int           `mapstructure:"ACCOUNT_CACHE_SIZE"`      // accounts kept in memory by id, 0 disables the cache
+	AccountCacheTTL      time.Duration `mapstructure:"ACCOUNT_CACHE_TTL"`       // bounds how stale an account updated by another instance can be
 	HTTPServerAddress    string        `mapstructure:"HTTP_SERVER_ADDRESS"`
+	GinMode              string        `mapstructure:"GIN_MODE"`        // debug, release or test, defaults to release
+	TrustedProxies       []string      `mapstructure:"TRUSTED_PROXIES"` // comma-separated ips or cidrs whose X-Forwarded-For is believed, none when empty
 	GRPCServerAddress    string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	TLSCertFile          string        `mapstructure:"TLS_CERT_FILE"` // the http server serves TLS when both files are set, SIGHUP reloads them
+	TLSKeyFile           string        `mapstructure:"TLS_KEY_FILE"`
+	TokenType            string        `mapstructure:"TOKEN_TYPE"` // paseto or jwt, defaults to paseto
 	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenIssuer          string        `mapstructure:"TOKEN_ISSUER"`
+	TokenAudience        string        `mapstructure:"TOKEN_AUDIENCE"`      // tokens minted for any other audience are rejected
+	TOTPEncryptionKey    string        `mapstructure:"TOTP_ENCRYPTION_KEY"` // encrypts the two-factor secrets at rest, changing it makes the enrolled ones unreadable
+	TOTPIssuer           string        `mapstructure:"TOTP_ISSUER"`         // the name authenticator apps show next to the code
+	BcryptCost           int           `mapstructure:"BCRYPT_COST"`
+	LoginMaxAttempts     int32         `mapstructure:"LOGIN_MAX_ATTEMPTS"` // consecutive failures before locking the account, 0 disables the lockout
+	LoginLockoutDuration time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
 	TokenDuration        time.Duration `mapstructure:"TOKEN_DURATION"`
 	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	StepUpTokenDuration  time.Duration `mapstructure:"STEP_UP_TOKEN_DURATION"`        // lifetime of the tokens guarding sensitive operations, keep it short
+	ResetTokenDuration   time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_DURATION"` // how long an emailed password reset token can be redeemed
+	RequestTimeout       time.Duration `mapstructure:"REQUEST_TIMEOUT"`               // deadline of every http request, 0 disables it
+	MaxRequestBodyBytes  int64         `mapstructure:"MAX_REQUEST_BODY_BYTES"`        // larger http request bodies are rejected with a 413, 0 disables the limit
+	MaxImportBodyBytes   int64         `mapstructure:"MAX_IMPORT_BODY_BYTES"`         // replaces MAX_REQUEST_BODY_BYTES on the user import
+	RateLimitRequests    int           `mapstructure:"RATE_LIMIT_REQUESTS"`
+	RateLimitWindow      time.Duration `mapstructure:"RATE_LIMIT_WINDOW"`
+	AllowedOrigins       []string      `mapstructure:"ALLOWED_ORIGINS"`      // comma-separated, `*` allows any origin
+	SecurityHeaders      bool          `mapstructure:"SECURITY_HEADERS"`     // sets the nosniff, frame, referrer and content security headers on every response
+	SecurityHeadersCSP   string        `mapstructure:"SECURITY_HEADERS_CSP"` // replaces the default policy, which blocks everything
+	MinTransferAmount    int64         `mapstructure:"MIN_TRANSFER_AMOUNT"`
+	MaxTransferAmount    int64         `mapstructure:"MAX_TRANSFER_AMOUNT"`  // 0 disables the maximum
+	DailyTransferLimit   int64         `mapstructure:"DAILY_TRANSFER_LIMIT"` // amount a user can send per UTC day, 0 disables it
+	ExchangeRates        []string      `mapstructure:"EXCHANGE_RATES"`       // comma-separated FROM/TO=RATE entries, cross-currency transfers without a rate are rejected
+	DefaultCurrency      string        `mapstructure:"DEFAULT_CURRENCY"`     // of the accounts opened without a currency
+	DefaultLocale        string        `mapstructure:"DEFAULT_LOCALE"`       // formats the amounts people read, like the csv statements
+	WebhookURL           string        `mapstructure:"WEBHOOK_URL"`          // completed transfers are posted to it, webhooks are disabled when empty
+	WebhookSecret        string        `mapstructure:"WEBHOOK_SECRET"`
+	WebhookMaxAttempts   int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	OutboxPollInterval   time.Duration `mapstructure:"OUTBOX_POLL_INTERVAL"`             // how often the unpublished outbox events are looked for
+	SchedulePollInterval time.Duration `mapstructure:"SCHEDULED_TRANSFER_POLL_INTERVAL"` // how often the due scheduled transfers are looked for
+	HoldExpiration       time.Duration `mapstructure:"HOLD_EXPIRATION"`                  // how long an authorized transfer can be captured
+	HoldPollInterval     time.Duration `mapstructure:"HOLD_EXPIRY_POLL_INTERVAL"`        // how often the expired holds are released
+	TracingOTLPEndpoint  string        `mapstructure:"TRACING_OTLP_ENDPOINT"`            // collector host:port, tracing is disabled when empty
+	LogLevel             string        `mapstructure:"LOG_LEVEL"`                        // debug, info, warn or error, defaults to info
+	LogFormat            string        `mapstructure:"LOG_FORMAT"`                       // json or console, console is meant for people reading it locally
 }
 
+const (
+	defaultMaxOpenConns    = 25
+	defaultMaxIdleConns    = 25
+	defaultConnMaxLifetime = 5 * time.Minute
+
+	defaultOutboxPollInterval   = time.Second
+	defaultSchedulePollInterval = time.Minute
+	defaultHoldExpiration       = 7 * 24 * time.Hour
+	defaultHoldPollInterval     = time.Minute
+
+	defaultStepUpTokenDuration = 5 * time.Minute
+	defaultResetTokenDuration  = 30 * time.Minute
+
+	defaultTOTPIssuer = "Simple Bank"
+)
+
+// the gin modes, utils doesn't depend on gin so they are repeated here
+const (
+	ginDebugMode   = "debug"
+	ginReleaseMode = "release"
+	ginTestMode    = "test"
+)
+
+// ConfigOverrideFileEnv names the env variable holding the path of an optional file overriding config.env
+const ConfigOverrideFileEnv = "CONFIG_OVERRIDE_FILE"
+
+// LoadConfig reads the configuration, it doesn't check it: callers must Validate it before using it
+// Every field is read from the first source setting its key, in order:
+//   - the env variable named after its mapstructure tag, e.g. TOKEN_SYMMETRIC_KEY
+//   - the override file at CONFIG_OVERRIDE_FILE, when set
+//   - config.env in path
+//   - the defaults below
+//
+// The secrets can also be read from a file, see loadSecretFiles
 func LoadConfig(path string) (config Config, err error) {
 	viper.AddConfigPath(path)
 	viper.SetConfigFile("config.env")
 	viper.SetConfigType("env")
 
+	viper.SetDefault("BCRYPT_COST", bcrypt.DefaultCost)
+	viper.SetDefault("DB_MAX_OPEN_CONNS", defaultMaxOpenConns)
+	viper.SetDefault("DB_MAX_IDLE_CONNS", defaultMaxIdleConns)
+	viper.SetDefault("DB_CONN_MAX_LIFETIME", defaultConnMaxLifetime)
+	viper.SetDefault("OUTBOX_POLL_INTERVAL", defaultOutboxPollInterval)
+	viper.SetDefault("SCHEDULED_TRANSFER_POLL_INTERVAL", defaultSchedulePollInterval)
+	viper.SetDefault("HOLD_EXPIRATION", defaultHoldExpiration)
+	viper.SetDefault("HOLD_EXPIRY_POLL_INTERVAL", defaultHoldPollInterval)
+	viper.SetDefault("STEP_UP_TOKEN_DURATION", defaultStepUpTokenDuration)
+	viper.SetDefault("PASSWORD_RESET_TOKEN_DURATION", defaultResetTokenDuration)
+	viper.SetDefault("DEFAULT_CURRENCY", USD)
+	viper.SetDefault("DEFAULT_LOCALE", DefaultLocale)
+	viper.SetDefault("SECURITY_HEADERS", true)
+	viper.SetDefault("GIN_MODE", ginReleaseMode)
+	viper.SetDefault("LOG_LEVEL", defaultLogLevel)
+	viper.SetDefault("LOG_FORMAT", LogFormatJSON)
+	viper.SetDefault("TOTP_ISSUER", defaultTOTPIssuer)
+
 	// checks if variables exists and loads them into viper
 	viper.AutomaticEnv()
+	if err = bindConfigEnv(); err != nil {
+		return
+	}
 	if err = viper.ReadInConfig(); err != nil {
 		return
 	}
 
-	err = viper.Unmarshal(&config)
+	if overrideFile := os.Getenv(ConfigOverrideFileEnv); overrideFile != "" {
+		viper.SetConfigFile(overrideFile)
+		if err = viper.MergeInConfig(); err != nil {
+			err = fmt.Errorf("cannot read %v %v: %w", ConfigOverrideFileEnv, overrideFile, err)
+			return
+		}
+	}
+
+	if err = viper.Unmarshal(&config); err != nil {
+		return
+	}
+
+	err = config.loadSecretFiles()
 	return
 }
+
+// secretFileSuffix follows the docker secrets convention: KEY_FILE holds the path of a file containing the value of KEY
+const secretFileSuffix = "_FILE"
+
+// loadSecretFiles replaces the sensitive fields by the content of the file their KEY_FILE key points to, if any
+// The file wins over a value set directly, so a dev value left in config.env doesn't shadow the mounted secret
+func (config *Config) loadSecretFiles() error {
+	secrets := []struct {
+		key   string
+		value *string
+	}{
+		{"DB_SOURCE", &config.SourceName},
+		{"TOKEN_SYMMETRIC_KEY", &config.TokenSymmetricKey},
+		{"TOTP_ENCRYPTION_KEY", &config.TOTPEncryptionKey},
+		{"WEBHOOK_SECRET", &config.WebhookSecret},
+	}
+	for _, secret := range secrets {
+		fileKey := secret.key + secretFileSuffix
+		if err := viper.BindEnv(fileKey); err != nil {
+			return err
+		}
+
+		path := viper.GetString(fileKey)
+		if path == "" {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("cannot read %v: %w", fileKey, err)
+		}
+		// editors and `echo` leave a trailing newline the key would otherwise include
+		*secret.value = strings.TrimSpace(string(content))
+	}
+	return nil
+}
+
+// bindConfigEnv binds every Config key to its env variable
+// AutomaticEnv only overrides the keys viper already knows about, so without it a key missing from the files
+// would be ignored even when its env variable is set
+func bindConfigEnv() error {
+	fields := reflect.TypeOf(Config{})
+	for i := 0; i < fields.NumField(); i++ {
+		if err := viper.BindEnv(fields.Field(i).Tag.Get("mapstructure")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tokenSymmetricKeySize is the key size of paseto v2 local tokens, jwt tokens are signed with the same key
+const tokenSymmetricKeySize = 32
+
+// Validate checks the configuration is usable, so a bad value is reported at startup instead of deep inside the first request needing it
+// It returns the first problem found, naming the env key to fix
+func (config Config) Validate() error {
+	required := []struct {
+		key   string
+		value string
+	}{
+		{"DB_DRIVER", config.DriverName},
+		{"DB_SOURCE", config.SourceName},
+		{"HTTP_SERVER_ADDRESS", config.HTTPServerAddress},
+		{"GRPC_SERVER_ADDRESS", config.GRPCServerAddress},
+		{"TOKEN_SYMMETRIC_KEY", config.TokenSymmetricKey},
+	}
+	for _, field := range required {
+		if field.value == "" {
+			return fmt.Errorf("missing %v", field.key)
+		}
+	}
+
+	for key, address := range map[string]string{
+		"HTTP_SERVER_ADDRESS": config.HTTPServerAddress,
+		"GRPC_SERVER_ADDRESS": config.GRPCServerAddress,
+	} {
+		if err := validAddress(address); err != nil {
+			return fmt.Errorf("invalid %v %q: %w", key, address, err)
+		}
+	}
+
+	switch config.GinMode {
+	case "", ginDebugMode, ginReleaseMode, ginTestMode:
+	default:
+		return fmt.Errorf("invalid GIN_MODE %q: must be %v, %v or %v", config.GinMode, ginDebugMode, ginReleaseMode, ginTestMode)
+	}
+	if _, ok := logLevels[config.LogLevel]; !ok && config.LogLevel != "" {
+		return fmt.Errorf("invalid LOG_LEVEL %q: must be debug, info, warn or error", config.LogLevel)
+	}
+	switch config.LogFormat {
+	case "", LogFormatJSON, LogFormatConsole:
+	default:
+		return fmt.Errorf("invalid LOG_FORMAT %q: must be %v or %v", config.LogFormat, LogFormatJSON, LogFormatConsole)
+	}
+	for _, proxy := range config.TrustedProxies {
+		if err := validProxy(proxy); err != nil {
+			return fmt.Errorf("invalid TRUSTED_PROXIES %q: %w", proxy, err)
+		}
+	}
+
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		return fmt.Errorf("invalid tls settings: TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	if config.TokenType != "" && config.TokenType != "paseto" && config.TokenType != "jwt" {
+		return fmt.Errorf("invalid TOKEN_TYPE %q: must be paseto or jwt", config.TokenType)
+	}
+	if len(config.TokenSymmetricKey) != tokenSymmetricKeySize {
+		return fmt.Errorf("invalid TOKEN_SYMMETRIC_KEY: must be exactly %v bytes, got %v", tokenSymmetricKeySize, len(config.TokenSymmetricKey))
+	}
+	if len(config.TOTPEncryptionKey) != TOTPEncryptionKeySize {
+		return fmt.Errorf("invalid TOTP_ENCRYPTION_KEY: must be exactly %v bytes, got %v", TOTPEncryptionKeySize, len(config.TOTPEncryptionKey))
+	}
+
+	durations := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"TOKEN_DURATION", config.TokenDuration},
+		{"REFRESH_TOKEN_DURATION", config.RefreshTokenDuration},
+		{"STEP_UP_TOKEN_DURATION", config.StepUpTokenDuration},
+		{"PASSWORD_RESET_TOKEN_DURATION", config.ResetTokenDuration},
+		{"OUTBOX_POLL_INTERVAL", config.OutboxPollInterval},
+		{"SCHEDULED_TRANSFER_POLL_INTERVAL", config.SchedulePollInterval},
+		{"HOLD_EXPIRATION", config.HoldExpiration},
+		{"HOLD_EXPIRY_POLL_INTERVAL", config.HoldPollInterval},
+	}
+	for _, field := range durations {
+		if field.value <= 0 {
+			return fmt.Errorf("invalid %v %v: must be positive", field.key, field.value)
+		}
+	}
+	if config.RequestTimeout < 0 {
+		return fmt.Errorf("invalid REQUEST_TIMEOUT %v: can't be negative", config.RequestTimeout)
+	}
+	if config.SlowQueryThreshold < 0 {
+		return fmt.Errorf("invalid DB_SLOW_QUERY_THRESHOLD %v: can't be negative", config.SlowQueryThreshold)
+	}
+
+	if config.AccountCacheSize < 0 {
+		return fmt.Errorf("invalid ACCOUNT_CACHE_SIZE %v: can't be negative", config.AccountCacheSize)
+	}
+	if config.AccountCacheSize > 0 && config.AccountCacheTTL <= 0 {
+		return fmt.Errorf("invalid ACCOUNT_CACHE_TTL %v: must be positive when ACCOUNT_CACHE_SIZE is set", config.AccountCacheTTL)
+	}
+
+	if config.MaxRequestBodyBytes < 0 || config.MaxImportBodyBytes < 0 {
+		return fmt.Errorf("invalid body limits: MAX_REQUEST_BODY_BYTES %v and MAX_IMPORT_BODY_BYTES %v can't be negative",
+			config.MaxRequestBodyBytes, config.MaxImportBodyBytes)
+	}
+
+	if config.LoginMaxAttempts < 0 {
+		return fmt.Errorf("invalid LOGIN_MAX_ATTEMPTS %v: can't be negative", config.LoginMaxAttempts)
+	}
+	if config.LoginMaxAttempts > 0 && config.LoginLockoutDuration <= 0 {
+		return fmt.Errorf("invalid LOGIN_LOCKOUT_DURATION %v: must be positive when LOGIN_MAX_ATTEMPTS is set", config.LoginLockoutDuration)
+	}
+
+	// fail fast instead of returning an error on the first password hashed
+	if config.BcryptCost < bcrypt.MinCost || config.BcryptCost > bcrypt.MaxCost {
+		return fmt.Errorf("invalid BCRYPT_COST %v: must be between %v and %v", config.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	// database/sql reads zero or negative values as no limit, which is what the pool settings are meant to prevent
+	if config.MaxOpenConns <= 0 || config.MaxIdleConns <= 0 || config.ConnMaxLifetime <= 0 {
+		return fmt.Errorf("invalid db pool settings: DB_MAX_OPEN_CONNS %v, DB_MAX_IDLE_CONNS %v and DB_CONN_MAX_LIFETIME %v must be positive",
+			config.MaxOpenConns, config.MaxIdleConns, config.ConnMaxLifetime)
+	}
+
+	if config.MinTransferAmount < 0 || config.MaxTransferAmount < 0 || config.DailyTransferLimit < 0 {
+		return fmt.Errorf("invalid transfer limits: MIN_TRANSFER_AMOUNT %v, MAX_TRANSFER_AMOUNT %v and DAILY_TRANSFER_LIMIT %v can't be negative",
+			config.MinTransferAmount, config.MaxTransferAmount, config.DailyTransferLimit)
+	}
+	if config.MaxTransferAmount > 0 && config.MinTransferAmount > config.MaxTransferAmount {
+		return fmt.Errorf("invalid transfer limits: MIN_TRANSFER_AMOUNT %v is above MAX_TRANSFER_AMOUNT %v", config.MinTransferAmount, config.MaxTransferAmount)
+	}
+
+	if !IsSupportedCurrency(config.DefaultCurrency) {
+		return fmt.Errorf("invalid DEFAULT_CURRENCY %q: must be one of %v, %v or %v", config.DefaultCurrency, USD, ARS, EUR)
+	}
+	if !IsSupportedLocale(config.DefaultLocale) {
+		return fmt.Errorf("invalid DEFAULT_LOCALE %q: unsupported locale", config.DefaultLocale)
+	}
+
+	if config.WebhookURL != "" && config.WebhookSecret == "" {
+		return fmt.Errorf("invalid webhook settings: WEBHOOK_SECRET is required to sign the webhooks posted to WEBHOOK_URL")
+	}
+
+	return nil
+}
+
+// validAddress checks the address is a host:port pair the servers can listen on, the host may be empty
+func validAddress(address string) error {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+// validProxy checks the proxy is an ip or a cidr, the formats gin accepts as trusted proxies
+func validProxy(proxy string) error {
+	if strings.Contains(proxy, "/") {
+		_, _, err := net.ParseCIDR(proxy)
+		return err
+	}
+	if net.ParseIP(proxy) == nil {
+		return fmt.Errorf("not an ip address")
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package utils
+
+// DefaultLocale formats the amounts when no locale is configured
+const DefaultLocale = "en-US"
+
+// locales holds the separators the amounts are written with for people reading them in each locale
+var locales = map[string]struct {
+	decimal string
+	group   string
+}{
+	"en-US": {decimal: ".", group: ","},
+	"en-GB": {decimal: ".", group: ","},
+	"es-AR": {decimal: ",", group: "."},
+	"de-DE": {decimal: ",", group: "."},
+}
+
+// IsSupportedLocale returns true if amounts can be formatted for the locale
+func IsSupportedLocale(locale string) bool {
+	_, ok := locales[locale]
+	return ok
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/rs/zerolog"
+	"io"
+)
+
+// the log formats, json lines for the log collectors and colored text for people reading it locally
+const (
+	LogFormatJSON    = "json"
+	LogFormatConsole = "console"
+)
+
+const defaultLogLevel = "info"
+
+var logLevels = map[string]zerolog.Level{
+	"debug": zerolog.DebugLevel,
+	"info":  zerolog.InfoLevel,
+	"warn":  zerolog.WarnLevel,
+	"error": zerolog.ErrorLevel,
+}
+
+// NewLogger returns the logger writing to w in the LOG_FORMAT of the config, the events below its LOG_LEVEL are dropped
+// An empty level or format falls back to info and json, an unknown one is an error
+func NewLogger(config Config, w io.Writer) (zerolog.Logger, error) {
+	levelName := config.LogLevel
+	if levelName == "" {
+		levelName = defaultLogLevel
+	}
+	level, ok := logLevels[levelName]
+	if !ok {
+		return zerolog.Nop(), fmt.Errorf("invalid LOG_LEVEL %q: must be debug, info, warn or error", config.LogLevel)
+	}
+
+	switch config.LogFormat {
+	case "", LogFormatJSON:
+	case LogFormatConsole:
+		w = zerolog.ConsoleWriter{Out: w}
+	default:
+		return zerolog.Nop(), fmt.Errorf("invalid LOG_FORMAT %q: must be %v or %v", config.LogFormat, LogFormatJSON, LogFormatConsole)
+	}
+
+	// the level is set on the logger instead of globally, so loggers built from another config keep their own
+	return zerolog.New(w).Level(level).With().Timestamp().Logger(), nil
+}
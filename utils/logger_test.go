@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestNewLoggerLevel(t *testing.T) {
+	testCases := []struct {
+		level   string
+		written []string
+	}{
+		{level: "debug", written: []string{"debug", "info", "warn", "error"}},
+		{level: "info", written: []string{"info", "warn", "error"}},
+		{level: "warn", written: []string{"warn", "error"}},
+		{level: "error", written: []string{"error"}},
+		// an unset level is info
+		{level: "", written: []string{"info", "warn", "error"}},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.level, func(t *testing.T) {
+			var output bytes.Buffer
+			logger, err := NewLogger(Config{LogLevel: tc.level}, &output)
+			require.NoError(t, err)
+
+			logger.Debug().Msg("debug")
+			logger.Info().Msg("info")
+			logger.Warn().Msg("warn")
+			logger.Error().Msg("error")
+
+			var written []string
+			for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+				var logLine map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(line), &logLine))
+				require.Equal(t, logLine["level"], logLine["message"])
+				require.Contains(t, logLine, "time")
+				written = append(written, logLine["message"].(string))
+			}
+			require.Equal(t, tc.written, written)
+		})
+	}
+}
+
+func TestNewLoggerFormat(t *testing.T) {
+	var output bytes.Buffer
+	logger, err := NewLogger(Config{LogFormat: LogFormatConsole}, &output)
+	require.NoError(t, err)
+
+	logger.Info().Str("username", "alice").Msg("logged in")
+	// the console format is text for people, not a json line
+	require.False(t, json.Valid(output.Bytes()))
+	require.Contains(t, output.String(), "logged in")
+	require.Contains(t, output.String(), "username=")
+}
+
+func TestNewLoggerInvalid(t *testing.T) {
+	_, err := NewLogger(Config{LogLevel: "verbose"}, &bytes.Buffer{})
+	require.ErrorContains(t, err, "LOG_LEVEL")
+
+	_, err = NewLogger(Config{LogFormat: "xml"}, &bytes.Buffer{})
+	require.ErrorContains(t, err, "LOG_FORMAT")
+}
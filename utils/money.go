@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MinorUnits is the number of decimals of every supported currency, an amount of 1 is a cent
+const MinorUnits = 2
+
+const minorUnitsPerUnit = 100
+
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// Money is an amount in minor units, so 1234 is 12.34 of its currency. The balances and amounts stored are all in minor units
+// In a request it is either a json integer of minor units or a decimal string like "12.34", see ParseMoney
+type Money int64
+
+// ParseMoney reads a decimal amount like "12.34", "-5" or "0.5" into minor units
+// More decimals than MinorUnits are rejected instead of rounded, so no client ever loses a fraction of a cent silently
+func ParseMoney(s string) (Money, error) {
+	units, cents, hasCents := strings.Cut(s, ".")
+	negative := strings.HasPrefix(units, "-")
+	units = strings.TrimPrefix(units, "-")
+
+	if units == "" || !isDigits(units) || (hasCents && (cents == "" || !isDigits(cents))) {
+		return 0, fmt.Errorf("%w %q: expected a decimal number like 12.34", ErrInvalidMoney, s)
+	}
+	if len(cents) > MinorUnits {
+		return 0, fmt.Errorf("%w %q: at most %v decimal places are allowed", ErrInvalidMoney, s, MinorUnits)
+	}
+
+	whole, err := strconv.ParseInt(units, 10, 64)
+	if err != nil || whole > (math.MaxInt64-minorUnitsPerUnit)/minorUnitsPerUnit {
+		return 0, fmt.Errorf("%w %q: out of range", ErrInvalidMoney, s)
+	}
+
+	// "0.5" is 50 cents, not 5
+	cents += strings.Repeat("0", MinorUnits-len(cents))
+	fraction, _ := strconv.ParseInt(cents, 10, 64)
+
+	amount := whole*minorUnitsPerUnit + fraction
+	if negative {
+		amount = -amount
+	}
+	return Money(amount), nil
+}
+
+// String formats the amount as a decimal with MinorUnits decimals, like "12.34" or "-0.50"
+func (m Money) String() string {
+	sign := ""
+	amount := uint64(m)
+	if m < 0 {
+		sign = "-"
+		amount = uint64(-m)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/minorUnitsPerUnit, amount%minorUnitsPerUnit)
+}
+
+// Format writes the amount for people reading it in locale, like "1,234.56" in en-US or "1.234,56" in es-AR
+// An unsupported locale is formatted like DefaultLocale
+func (m Money) Format(locale string) string {
+	separators, ok := locales[locale]
+	if !ok {
+		separators = locales[DefaultLocale]
+	}
+
+	sign := ""
+	amount := uint64(m)
+	if m < 0 {
+		sign = "-"
+		amount = uint64(-m)
+	}
+
+	units := strconv.FormatUint(amount/minorUnitsPerUnit, 10)
+	var grouped strings.Builder
+	for i, digit := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			grouped.WriteString(separators.group)
+		}
+		grouped.WriteRune(digit)
+	}
+	return fmt.Sprintf("%s%s%s%02d", sign, grouped.String(), separators.decimal, amount%minorUnitsPerUnit)
+}
+
+// UnmarshalJSON accepts an integer of minor units, like every amount before Money, or a decimal string parsed by ParseMoney
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		amount, err := ParseMoney(s)
+		if err != nil {
+			return err
+		}
+		*m = amount
+		return nil
+	}
+
+	var amount int64
+	if err := json.Unmarshal(data, &amount); err != nil {
+		return fmt.Errorf("%w %s: a number is an integer of minor units, send decimals as a string like \"12.34\"", ErrInvalidMoney, data)
+	}
+	*m = Money(amount)
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
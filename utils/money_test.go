@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	testCases := []struct {
+		name     string
+		amount   string
+		expected Money
+		err      error
+	}{
+		{name: "two decimals", amount: "12.34", expected: 1234},
+		{name: "one decimal", amount: "0.5", expected: 50},
+		{name: "no decimals", amount: "12", expected: 1200},
+		{name: "negative", amount: "-5.01", expected: -501},
+		{name: "zero", amount: "0.00", expected: 0},
+		{name: "three decimals", amount: "12.345", err: ErrInvalidMoney},
+		{name: "trailing dot", amount: "12.", err: ErrInvalidMoney},
+		{name: "leading dot", amount: ".5", err: ErrInvalidMoney},
+		{name: "empty", amount: "", err: ErrInvalidMoney},
+		{name: "letters", amount: "12.ab", err: ErrInvalidMoney},
+		{name: "plus sign", amount: "+12", err: ErrInvalidMoney},
+		{name: "exponent", amount: "1e3", err: ErrInvalidMoney},
+		{name: "out of range", amount: "92233720368547758", err: ErrInvalidMoney},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			amount, err := ParseMoney(tc.amount)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, amount)
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	require.Equal(t, "12.34", Money(1234).String())
+	require.Equal(t, "0.05", Money(5).String())
+	require.Equal(t, "-0.50", Money(-50).String())
+	require.Equal(t, "100.00", Money(10000).String())
+
+	// formatting and parsing back gives the same amount
+	for _, amount := range []Money{0, 1, 99, 1234, -501, 123456789} {
+		parsed, err := ParseMoney(amount.String())
+		require.NoError(t, err)
+		require.Equal(t, amount, parsed)
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	var req struct {
+		Amount Money `json:"amount"`
+	}
+
+	// an integer is read as minor units
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": 1234}`), &req))
+	require.Equal(t, Money(1234), req.Amount)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"amount": "12.34"}`), &req))
+	require.Equal(t, Money(1234), req.Amount)
+
+	require.ErrorIs(t, json.Unmarshal([]byte(`{"amount": "12.345"}`), &req), ErrInvalidMoney)
+	// a fractional number would be ambiguous: 12.34 units or 12.34 minor units
+	require.ErrorIs(t, json.Unmarshal([]byte(`{"amount": 12.34}`), &req), ErrInvalidMoney)
+}
+
+func TestMoneyFormat(t *testing.T) {
+	testCases := []struct {
+		locale   string
+		amount   Money
+		expected string
+	}{
+		{locale: "en-US", amount: 123456789, expected: "1,234,567.89"},
+		{locale: "en-US", amount: 12345, expected: "123.45"},
+		{locale: "es-AR", amount: 123456789, expected: "1.234.567,89"},
+		{locale: "de-DE", amount: -100000, expected: "-1.000,00"},
+		{locale: "en-GB", amount: 5, expected: "0.05"},
+		{locale: "xx-XX", amount: 100000, expected: "1,000.00"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, tc.amount.Format(tc.locale), tc.locale)
+	}
+}
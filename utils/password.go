@@ -1,20 +1,63 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
+	"unicode"
 )
 
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+// password strength rules, tune them here
+const (
+	MinPasswordLength      = 8
+	PasswordRequiresDigit  = true
+	PasswordRequiresLetter = true
+)
+
+var (
+	ErrPasswordTooShort = fmt.Errorf("password must be at least %d characters long", MinPasswordLength)
+	ErrPasswordNoDigit  = errors.New("password must contain at least one digit")
+	ErrPasswordNoLetter = errors.New("password must contain at least one letter")
+)
+
+// HashPassword hashes the password with the given bcrypt cost, a cost below bcrypt.MinCost falls back to bcrypt.DefaultCost
+// The cost is stored in the hash itself, so hashes made with a previous cost keep verifying after it changes
+func HashPassword(password string, cost int) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	if err != nil {
 		return "", fmt.Errorf("error hashing password: %s", err)
 	}
 
 	return string(hashedPassword), nil
-
 }
 
 func CheckPassword(password, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+// ValidatePassword checks the password against the strength rules and returns the first one it violates
+func ValidatePassword(password string) error {
+	if len([]rune(password)) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	var hasDigit, hasLetter bool
+	for _, r := range password {
+		switch {
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsLetter(r):
+			hasLetter = true
+		}
+	}
+
+	if PasswordRequiresDigit && !hasDigit {
+		return ErrPasswordNoDigit
+	}
+
+	if PasswordRequiresLetter && !hasLetter {
+		return ErrPasswordNoLetter
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+func TestValidatePassword(t *testing.T) {
+	testCases := []struct {
+		name     string
+		password string
+		err      error
+	}{
+		{
+			name:     "valid password",
+			password: RandomPassword(),
+			err:      nil,
+		},
+		{
+			name:     "too short",
+			password: "abc123",
+			err:      ErrPasswordTooShort,
+		},
+		{
+			name:     "no digit",
+			password: RandomString(MinPasswordLength),
+			err:      ErrPasswordNoDigit,
+		},
+		{
+			name:     "no letter",
+			password: "1234567890",
+			err:      ErrPasswordNoLetter,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidatePassword(tc.password)
+			require.Equal(t, tc.err, err)
+		})
+	}
+}
+
+func TestPassword(t *testing.T) {
+	password := RandomPassword()
+
+	hashedPassword, err := HashPassword(password, bcrypt.DefaultCost)
+	require.NoError(t, err)
+	require.NotEmpty(t, hashedPassword)
+
+	require.NoError(t, CheckPassword(password, hashedPassword))
+	require.Error(t, CheckPassword(RandomPassword(), hashedPassword))
+}
+
+func TestPasswordCost(t *testing.T) {
+	password := RandomPassword()
+
+	for _, cost := range []int{bcrypt.MinCost, bcrypt.DefaultCost} {
+		hashedPassword, err := HashPassword(password, cost)
+		require.NoError(t, err)
+
+		hashCost, err := bcrypt.Cost([]byte(hashedPassword))
+		require.NoError(t, err)
+		require.Equal(t, cost, hashCost)
+
+		// the cost travels with the hash, so it verifies whatever cost is configured now
+		require.NoError(t, CheckPassword(password, hashedPassword))
+	}
+
+	// out of range costs are rejected by bcrypt
+	_, err := HashPassword(password, bcrypt.MaxCost+1)
+	require.Error(t, err)
+}
+
+func BenchmarkHashPassword(b *testing.B) {
+	password := RandomPassword()
+
+	for _, cost := range []int{bcrypt.MinCost, bcrypt.DefaultCost, 12} {
+		b.Run(fmt.Sprintf("cost %d", cost), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := HashPassword(password, cost)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -26,12 +26,14 @@ func RandomString(n int) string {
 	return strings.Title(sb.String())
 }
 
+// RandomOwner returns a random username in the lowercase form usernames are stored in
 func RandomOwner() string {
-	return RandomString(6)
+	return NormalizeUsername(RandomString(6))
 }
 
+// RandomEmail returns a random email in the lowercase form emails are stored in
 func RandomEmail() string {
-	return fmt.Sprintf("%v@mail.com", RandomString(8))
+	return NormalizeEmail(fmt.Sprintf("%v@mail.com", RandomString(8)))
 }
 
 func RandomBalance() int64 {
@@ -42,3 +44,17 @@ func RandomCurrency() string {
 	currencies := []string{ARS, EUR, USD}
 	return currencies[rand.Intn(len(currencies))]
 }
+
+// RandomPassword returns a password that satisfies the strength rules
+func RandomPassword() string {
+	return fmt.Sprintf("%v%d", RandomString(MinPasswordLength), RandomInt(0, 9))
+}
+
+// RandomAccountNumber returns AccountNumberLength random digits
+func RandomAccountNumber() string {
+	var sb strings.Builder
+	for i := 0; i < AccountNumberLength; i++ {
+		sb.WriteByte(byte('0' + rand.Intn(10)))
+	}
+	return sb.String()
+}
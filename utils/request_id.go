@@ -0,0 +1,23 @@
+package utils
+
+import "context"
+
+// RequestIDKey is the key the request id is stored under in the gin context
+const RequestIDKey = "request_id"
+
+type requestIDContextKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the request id, for callers outside of a gin handler
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext returns the id of the request ctx belongs to, or an empty string if it has none
+// It accepts both a *gin.Context and any context derived from it, so the store layer can read it too
+func RequestIDFromContext(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDContextKey{}).(string); ok {
+		return requestID
+	}
+	requestID, _ := ctx.Value(RequestIDKey).(string)
+	return requestID
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// resetTokenBytes is the entropy of a password reset token, it's emailed once and can't be asked for again
+const resetTokenBytes = 32
+
+// NewResetToken returns a random url safe password reset token, it's read from crypto/rand so it can't be guessed
+func NewResetToken() (string, error) {
+	b := make([]byte, resetTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate reset token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashResetToken returns the hex encoded sha256 of the token, only the hash is stored so a leaked table can't reset passwords
+func HashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
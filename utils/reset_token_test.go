@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewResetToken(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		token, err := NewResetToken()
+		require.NoError(t, err)
+		require.NotEmpty(t, token)
+
+		require.False(t, seen[token])
+		seen[token] = true
+	}
+}
+
+func TestHashResetToken(t *testing.T) {
+	token, err := NewResetToken()
+	require.NoError(t, err)
+
+	hash := HashResetToken(token)
+	require.Len(t, hash, 64)
+	require.Equal(t, hash, HashResetToken(token))
+	require.NotEqual(t, hash, HashResetToken(token+"x"))
+}
@@ -0,0 +1,6 @@
+package utils
+
+const (
+	DepositorRole = "depositor"
+	BankerRole    = "banker"
+)
@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// the parameters of the time-based one-time passwords (RFC 6238), they are the defaults every authenticator app supports
+const (
+	totpSecretBytes = 20
+	totpDigits      = 6
+	totpPeriod      = 30 * time.Second
+	// totpSkew is the number of periods before and after the current one whose code is still accepted, for clock drift
+	totpSkew = 1
+)
+
+// TOTPEncryptionKeySize is the key size of AES-256, which encrypts the stored secrets
+const TOTPEncryptionKeySize = 32
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret returns a random base32 secret, the form authenticator apps expect to be typed or scanned
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate totp secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPURL returns the otpauth url of the secret, authenticator apps enroll it from a qr code of the url
+func TOTPURL(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// TOTPCode returns the code of the secret for the period t falls in
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		return "", fmt.Errorf("decode totp secret: %w", err)
+	}
+	return hotp(key, uint64(t.Unix()/int64(totpPeriod.Seconds()))), nil
+}
+
+// ValidateTOTP reports whether the code is the one of the secret at t, or of the periods right around it
+func ValidateTOTP(secret, code string, t time.Time) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		return false
+	}
+
+	counter := t.Unix() / int64(totpPeriod.Seconds())
+	valid := false
+	for i := -totpSkew; i <= totpSkew; i++ {
+		// every period is compared, in constant time, so the timing doesn't tell which one matched
+		if subtle.ConstantTimeCompare([]byte(hotp(key, uint64(counter+int64(i)))), []byte(code)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// hotp is the HMAC-based one-time password of RFC 4226 for the counter
+func hotp(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// EncryptTOTPSecret seals the secret with AES-GCM, the random nonce is prepended to the ciphertext
+func EncryptTOTPSecret(key, secret string) ([]byte, error) {
+	gcm, err := newTOTPCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, []byte(secret), nil), nil
+}
+
+// DecryptTOTPSecret opens a secret sealed by EncryptTOTPSecret with the same key
+func DecryptTOTPSecret(key string, encrypted []byte) (string, error) {
+	gcm, err := newTOTPCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(encrypted) < gcm.NonceSize() {
+		return "", errors.New("decrypt totp secret: ciphertext too short")
+	}
+	nonce, ciphertext := encrypted[:gcm.NonceSize()], encrypted[gcm.NonceSize():]
+	secret, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt totp secret: %w", err)
+	}
+	return string(secret), nil
+}
+
+func newTOTPCipher(key string) (cipher.AEAD, error) {
+	if len(key) != TOTPEncryptionKeySize {
+		return nil, fmt.Errorf("invalid totp encryption key: must be exactly %v bytes, got %v", TOTPEncryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcTOTPSecret is the base32 of the ascii "12345678901234567890", the sha1 secret of the RFC 6238 test vectors
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// the last 6 digits of the 8 digit RFC 6238 test vectors
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, v := range vectors {
+		code, err := TOTPCode(rfcTOTPSecret, time.Unix(v.unix, 0))
+		require.NoError(t, err)
+		require.Equal(t, v.code, code)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	require.NoError(t, err)
+	now := time.Now()
+
+	code, err := TOTPCode(secret, now)
+	require.NoError(t, err)
+	require.True(t, ValidateTOTP(secret, code, now))
+	// the code of the previous period is still accepted, for clock drift, but not older ones
+	require.True(t, ValidateTOTP(secret, code, now.Add(totpPeriod)))
+	require.False(t, ValidateTOTP(secret, code, now.Add(3*totpPeriod)))
+
+	wrong := "000000"
+	if wrong == code {
+		wrong = "111111"
+	}
+	require.False(t, ValidateTOTP(secret, wrong, now))
+	require.False(t, ValidateTOTP(secret, code[:5], now))
+	require.False(t, ValidateTOTP("not base32!", code, now))
+}
+
+func TestTOTPURL(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	require.NoError(t, err)
+
+	u, err := url.Parse(TOTPURL("Simple Bank", "alice", secret))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", u.Scheme)
+	require.Equal(t, "totp", u.Host)
+	require.Equal(t, "/Simple Bank:alice", u.Path)
+	require.Equal(t, secret, u.Query().Get("secret"))
+	require.Equal(t, "Simple Bank", u.Query().Get("issuer"))
+}
+
+func TestEncryptTOTPSecret(t *testing.T) {
+	key := RandomString(TOTPEncryptionKeySize)
+	secret, err := NewTOTPSecret()
+	require.NoError(t, err)
+
+	encrypted, err := EncryptTOTPSecret(key, secret)
+	require.NoError(t, err)
+	require.NotContains(t, string(encrypted), secret)
+
+	decrypted, err := DecryptTOTPSecret(key, encrypted)
+	require.NoError(t, err)
+	require.Equal(t, secret, decrypted)
+
+	// another key, or a tampered ciphertext, can't open it
+	_, err = DecryptTOTPSecret(RandomString(TOTPEncryptionKeySize), encrypted)
+	require.Error(t, err)
+	encrypted[len(encrypted)-1] ^= 1
+	_, err = DecryptTOTPSecret(key, encrypted)
+	require.Error(t, err)
+
+	_, err = EncryptTOTPSecret("short", secret)
+	require.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TracerName is the instrumentation name of every span created by the service
+	TracerName  = "github.com/micaelapucciariello/simplebank"
+	serviceName = "simplebank"
+)
+
+// NewTracerProvider returns the tracer provider selected by the config along with the function flushing it on shutdown
+// Without TRACING_OTLP_ENDPOINT it returns a no-op provider, so nothing is recorded and no collector is needed
+func NewTracerProvider(ctx context.Context, config Config) (trace.TracerProvider, func(context.Context) error, error) {
+	if config.TracingOTLPEndpoint == "" {
+		return trace.NewNoopTracerProvider(), func(context.Context) error { return nil }, nil
+	}
+
+	// the collector is expected to run next to the service, so the connection isn't encrypted
+	exporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(config.TracingOTLPEndpoint),
+		otlptracegrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	)
+	return provider, provider.Shutdown, nil
+}
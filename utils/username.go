@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// username rules, the usernames are stored lowercase so "Alice" and "alice" can't both register
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 30
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[a-z0-9_]*$`)
+
+	ErrUsernameLength = fmt.Errorf("username must be %d to %d characters long", MinUsernameLength, MaxUsernameLength)
+	ErrUsernameChars  = fmt.Errorf("username can only contain letters, digits and underscores")
+)
+
+// NormalizeUsername returns the form usernames are stored and looked up in
+func NormalizeUsername(username string) string {
+	return strings.ToLower(username)
+}
+
+// ValidateUsername checks the username against the rules once normalized, so an uppercase letter is accepted
+func ValidateUsername(username string) error {
+	username = NormalizeUsername(username)
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return ErrUsernameLength
+	}
+	if !usernamePattern.MatchString(username) {
+		return ErrUsernameChars
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestValidateUsername(t *testing.T) {
+	testCases := []struct {
+		name     string
+		username string
+		err      error
+	}{
+		{name: "valid username", username: "alice_01", err: nil},
+		{name: "uppercase is accepted", username: "Alice", err: nil},
+		{name: "shortest", username: strings.Repeat("a", MinUsernameLength), err: nil},
+		{name: "longest", username: strings.Repeat("a", MaxUsernameLength), err: nil},
+		{name: "too short", username: strings.Repeat("a", MinUsernameLength-1), err: ErrUsernameLength},
+		{name: "too long", username: strings.Repeat("a", MaxUsernameLength+1), err: ErrUsernameLength},
+		{name: "hyphen", username: "alice-01", err: ErrUsernameChars},
+		{name: "space", username: "alice 01", err: ErrUsernameChars},
+		{name: "non ascii letter", username: "alicé", err: ErrUsernameChars},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateUsername(tc.username)
+			require.Equal(t, tc.err, err)
+		})
+	}
+}
+
+func TestNormalizeUsername(t *testing.T) {
+	require.Equal(t, "alice_01", NormalizeUsername("Alice_01"))
+	require.Equal(t, NormalizeUsername("ALICE"), NormalizeUsername("alice"))
+}
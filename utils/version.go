@@ -0,0 +1,30 @@
+package utils
+
+import "runtime"
+
+// The build of the binary, set at link time like
+// go build -ldflags "-X github.com/micaelapucciariello/simplebank/utils.Version=v1.2.0 -X ..."
+// A binary built without them, like go run, reports the defaults
+var (
+	Version   = "dev"
+	GitCommit = "unknown"
+	BuildTime = "unknown"
+)
+
+// BuildInfo tells which build of the service is running
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// CurrentBuildInfo returns the build info injected at link time along with the go version the binary was built with
+func CurrentBuildInfo() BuildInfo {
+	return BuildInfo{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
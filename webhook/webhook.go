@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the body, computed with the shared secret
+	SignatureHeader = "X-Webhook-Signature"
+	// EventIDHeader carries the event id, receivers use it to drop the deliveries they already processed
+	EventIDHeader = "X-Webhook-Event-ID"
+
+	requestTimeout = 10 * time.Second
+)
+
+// Event is the payload posted to the webhook url, the same event may be delivered more than once
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Dispatcher posts the events to the webhook url signed with the shared secret
+// A delivery answered with a non 2xx status is attempted again with exponential backoff, up to MaxAttempts times
+type Dispatcher struct {
+	URL         string
+	Secret      string
+	MaxAttempts int
+	BaseDelay   time.Duration
+	Client      *http.Client
+}
+
+func NewDispatcher(url, secret string, maxAttempts int) *Dispatcher {
+	return &Dispatcher{
+		URL:         url,
+		Secret:      secret,
+		MaxAttempts: maxAttempts,
+		BaseDelay:   time.Second,
+		Client:      &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Deliver posts the event until the receiver accepts it, the attempts run out or ctx is done
+func (d *Dispatcher) Deliver(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	signature := Sign(d.Secret, body)
+
+	delay := d.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err = d.post(ctx, event.ID, body, signature)
+		if err == nil || attempt >= d.MaxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, eventID string, body []byte, signature string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventIDHeader, eventID)
+	request.Header.Set(SignatureHeader, signature)
+
+	response, err := d.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook answered with status %v", response.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body with the given secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the one of body with the given secret, comparing them in constant time
+func Verify(secret string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
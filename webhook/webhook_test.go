@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type delivery struct {
+	body      []byte
+	signature string
+	eventID   string
+}
+
+// newTestReceiver starts a webhook receiver answering with the given statuses in turn, the last one is then repeated
+func newTestReceiver(t *testing.T, statuses ...int) (*httptest.Server, <-chan delivery, *int32) {
+	deliveries := make(chan delivery, 10)
+	var attempts int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		attempt := int(atomic.AddInt32(&attempts, 1))
+		status := statuses[len(statuses)-1]
+		if attempt <= len(statuses) {
+			status = statuses[attempt-1]
+		}
+
+		deliveries <- delivery{
+			body:      body,
+			signature: r.Header.Get(SignatureHeader),
+			eventID:   r.Header.Get(EventIDHeader),
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, deliveries, &attempts
+}
+
+func newTestDispatcher(url, secret string, maxAttempts int) *Dispatcher {
+	dispatcher := NewDispatcher(url, secret, maxAttempts)
+	dispatcher.BaseDelay = time.Millisecond
+	return dispatcher
+}
+
+func randomEvent() Event {
+	return Event{
+		ID:        fmt.Sprint(utils.RandomInt(1, 1000)),
+		Type:      "transfer.completed",
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Data:      json.RawMessage(fmt.Sprintf(`{"id":%d}`, utils.RandomInt(1, 1000))),
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	secret := utils.RandomString(32)
+	receiver, deliveries, attempts := newTestReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+	dispatcher := newTestDispatcher(receiver.URL, secret, 5)
+
+	event := randomEvent()
+	require.NoError(t, dispatcher.Deliver(context.Background(), event))
+	require.Equal(t, int32(3), atomic.LoadInt32(attempts))
+
+	// every attempt carries the same signed body
+	for i := 0; i < 3; i++ {
+		d := <-deliveries
+		require.True(t, Verify(secret, d.body, d.signature))
+		require.Equal(t, event.ID, d.eventID)
+
+		var received Event
+		require.NoError(t, json.Unmarshal(d.body, &received))
+		require.Equal(t, event.ID, received.ID)
+		require.Equal(t, event.Type, received.Type)
+		require.JSONEq(t, string(event.Data), string(received.Data))
+	}
+}
+
+func TestDeliverGivesUp(t *testing.T) {
+	receiver, _, attempts := newTestReceiver(t, http.StatusInternalServerError)
+	dispatcher := newTestDispatcher(receiver.URL, utils.RandomString(32), 3)
+
+	err := dispatcher.Deliver(context.Background(), randomEvent())
+	require.Error(t, err)
+	require.Equal(t, int32(3), atomic.LoadInt32(attempts))
+}
+
+func TestDeliverCanceled(t *testing.T) {
+	receiver, _, attempts := newTestReceiver(t, http.StatusInternalServerError)
+	dispatcher := newTestDispatcher(receiver.URL, utils.RandomString(32), 5)
+	dispatcher.BaseDelay = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := dispatcher.Deliver(ctx, randomEvent())
+	require.Error(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(attempts))
+}
+
+func TestVerify(t *testing.T) {
+	secret := utils.RandomString(32)
+	body := []byte(utils.RandomString(64))
+	signature := Sign(secret, body)
+
+	require.True(t, Verify(secret, body, signature))
+	require.False(t, Verify(utils.RandomString(32), body, signature))
+	require.False(t, Verify(secret, []byte(utils.RandomString(64)), signature))
+	require.False(t, Verify(secret, body, "not hex"))
+}
@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+// snapshotGracePeriod leaves the transactions still running at midnight the time to commit before the day is closed
+const snapshotGracePeriod = time.Minute
+
+// BalanceSnapshotScheduler records the closing balance of every account once a UTC day is over
+type BalanceSnapshotScheduler struct {
+	store  db.Store
+	logger zerolog.Logger
+}
+
+func NewBalanceSnapshotScheduler(store db.Store, logger zerolog.Logger) *BalanceSnapshotScheduler {
+	return &BalanceSnapshotScheduler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Start snapshots the previous day right away, in case the process was down at midnight, and then every day until ctx is done
+// Snapshotting a day twice is harmless, the accounts that already have a snapshot for it are skipped
+func (s *BalanceSnapshotScheduler) Start(ctx context.Context) error {
+	for {
+		now := time.Now().UTC()
+		day := startOfDay(now).AddDate(0, 0, -1)
+		if _, err := s.Snapshot(ctx, day); err != nil && ctx.Err() == nil {
+			s.logger.Error().Err(err).Time("day", day).Msg("cannot snapshot balances")
+		}
+
+		timer := time.NewTimer(startOfDay(now).AddDate(0, 0, 1).Add(snapshotGracePeriod).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// Snapshot records the closing balance of day for the accounts that don't have one yet and returns how many were recorded
+func (s *BalanceSnapshotScheduler) Snapshot(ctx context.Context, day time.Time) (int64, error) {
+	created, err := s.store.CreateDailyBalanceSnapshots(ctx, day)
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Info().Time("day", day).Int64("accounts", created).Msg("balances snapshotted")
+	return created, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+)
+
+func TestBalanceSnapshotSchedulerStart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	// the previous day is closed as soon as the scheduler starts
+	yesterday := startOfDay(time.Now().UTC()).AddDate(0, 0, -1)
+	snapshotted := make(chan time.Time, 1)
+	store.EXPECT().CreateDailyBalanceSnapshots(gomock.Any(), gomock.Eq(yesterday)).Times(1).
+		DoAndReturn(func(ctx context.Context, day time.Time) (int64, error) {
+			snapshotted <- day
+			return 3, nil
+		})
+
+	scheduler := NewBalanceSnapshotScheduler(store, zerolog.Nop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- scheduler.Start(ctx)
+	}()
+
+	select {
+	case day := <-snapshotted:
+		require.Equal(t, time.UTC, day.Location())
+	case <-time.After(time.Second):
+		require.FailNow(t, "balances not snapshotted")
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestBalanceSnapshotSchedulerSnapshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	day := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	gomock.InOrder(
+		store.EXPECT().CreateDailyBalanceSnapshots(gomock.Any(), gomock.Eq(day)).Times(1).Return(int64(5), nil),
+		store.EXPECT().CreateDailyBalanceSnapshots(gomock.Any(), gomock.Eq(day)).Times(1).Return(int64(0), sql.ErrConnDone),
+	)
+
+	scheduler := NewBalanceSnapshotScheduler(store, zerolog.Nop())
+
+	created, err := scheduler.Snapshot(context.Background(), day)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), created)
+
+	_, err = scheduler.Snapshot(context.Background(), day)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrQueueFull = errors.New("task queue full")
+
+// Task is the unit of work moved through a Broker, its payload is the json encoding of the task type payload
+type Task struct {
+	Type    string `json:"type"`
+	Payload []byte `json:"payload"`
+}
+
+// Broker is the transport between the TaskDistributor and the TaskProcessor
+// Implementations backed by an external queue survive restarts, the in-memory one doesn't
+type Broker interface {
+	// Enqueue stores the task for the processor without waiting for it to be handled
+	Enqueue(ctx context.Context, task Task) error
+	// Dequeue blocks until a task is available or ctx is done
+	Dequeue(ctx context.Context) (Task, error)
+}
+
+// InMemoryBroker keeps the tasks in a buffered channel, the queued tasks are lost when the process stops
+type InMemoryBroker struct {
+	tasks chan Task
+}
+
+func NewInMemoryBroker(size int) *InMemoryBroker {
+	return &InMemoryBroker{tasks: make(chan Task, size)}
+}
+
+// Enqueue never blocks the request path, it fails when the queue is full
+func (b *InMemoryBroker) Enqueue(ctx context.Context, task Task) error {
+	select {
+	case b.tasks <- task:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (b *InMemoryBroker) Dequeue(ctx context.Context) (Task, error) {
+	select {
+	case task := <-b.tasks:
+		return task, nil
+	case <-ctx.Done():
+		return Task{}, ctx.Err()
+	}
+}
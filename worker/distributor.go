@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// TaskDistributor enqueues the background tasks, so the request handling doesn't wait for them
+type TaskDistributor interface {
+	DistributeTaskSendWelcomeEmail(ctx context.Context, payload *PayloadSendWelcomeEmail) error
+	DistributeTaskSendPasswordResetEmail(ctx context.Context, payload *PayloadSendPasswordResetEmail) error
+	DistributeTaskSendTransferReceivedEmail(ctx context.Context, payload *PayloadSendTransferReceivedEmail) error
+	DistributeTaskSendLowBalanceEmail(ctx context.Context, payload *PayloadSendLowBalanceEmail) error
+}
+
+type BrokerTaskDistributor struct {
+	broker Broker
+}
+
+func NewTaskDistributor(broker Broker) TaskDistributor {
+	return &BrokerTaskDistributor{broker: broker}
+}
+
+func (d *BrokerTaskDistributor) distribute(ctx context.Context, taskType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot marshal %s task payload: %w", taskType, err)
+	}
+
+	if err = d.broker.Enqueue(ctx, Task{Type: taskType, Payload: data}); err != nil {
+		return fmt.Errorf("cannot enqueue %s task: %w", taskType, err)
+	}
+	return nil
+}
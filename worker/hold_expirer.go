@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"context"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+const holdExpiryBatchSize = 100
+
+// HoldExpirer releases the authorized holds once they expire, so their funds are available again without waiting for a capture attempt
+type HoldExpirer struct {
+	store    db.Store
+	interval time.Duration
+	logger   zerolog.Logger
+}
+
+func NewHoldExpirer(store db.Store, interval time.Duration, logger zerolog.Logger) *HoldExpirer {
+	return &HoldExpirer{
+		store:    store,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start releases the expired holds every interval until ctx is done. A failed run is logged and retried on the next tick
+func (e *HoldExpirer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := e.Run(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				e.logger.Error().Err(err).Msg("cannot expire holds")
+			}
+		}
+	}
+}
+
+// Run releases the holds expired at now batch after batch, one transaction per batch, and returns how many were released
+func (e *HoldExpirer) Run(ctx context.Context, now time.Time) (int, error) {
+	expired := 0
+	for {
+		holds, err := e.store.ExpireHoldsTx(ctx, db.ExpireHoldsTxParams{
+			Now:   now,
+			Limit: holdExpiryBatchSize,
+		})
+		if err != nil {
+			return expired, err
+		}
+		expired += len(holds)
+
+		if len(holds) < holdExpiryBatchSize {
+			return expired, nil
+		}
+	}
+}
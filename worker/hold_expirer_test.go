@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func TestHoldExpirerRun(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	arg := db.ExpireHoldsTxParams{Now: now, Limit: holdExpiryBatchSize}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, expired int, err error)
+	}{
+		{
+			name: "happy path run",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ExpireHoldsTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(make([]db.Hold, 2), nil)
+			},
+			checkResponse: func(t *testing.T, expired int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 2, expired)
+			},
+		},
+		{
+			name: "drains full batches",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().ExpireHoldsTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(make([]db.Hold, holdExpiryBatchSize), nil),
+					store.EXPECT().ExpireHoldsTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.Hold{}, nil),
+				)
+			},
+			checkResponse: func(t *testing.T, expired int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, holdExpiryBatchSize, expired)
+			},
+		},
+		{
+			name: "store error",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ExpireHoldsTx(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, expired int, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+				require.Zero(t, expired)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			expirer := NewHoldExpirer(store, time.Second, zerolog.Nop())
+			expired, err := expirer.Run(context.Background(), now)
+			tc.checkResponse(t, expired, err)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package worker
+
+import (
+	"context"
+	"github.com/rs/zerolog"
+)
+
+// EmailSender delivers the emails written by the tasks
+type EmailSender interface {
+	SendEmail(ctx context.Context, to, subject, content string) error
+}
+
+// LogEmailSender only logs the emails, it stands in until a mail provider is configured
+type LogEmailSender struct {
+	logger zerolog.Logger
+}
+
+func NewLogEmailSender(logger zerolog.Logger) EmailSender {
+	return &LogEmailSender{logger: logger}
+}
+
+func (s *LogEmailSender) SendEmail(ctx context.Context, to, subject, content string) error {
+	s.logger.Info().Str("to", to).Str("subject", subject).Msg("email not sent: no mail provider configured")
+	return nil
+}
@@ -0,0 +1,155 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/micaelapucciariello/simplebank/webhook"
+	"github.com/rs/zerolog"
+	"strconv"
+	"time"
+)
+
+const outboxBatchSize = 100
+
+// OutboxPublisher delivers an outbox event, the event is marked published only if it returns nil
+type OutboxPublisher func(ctx context.Context, event db.Outbox) error
+
+// OutboxPoller publishes the events written to the outbox by the committed transactions
+type OutboxPoller struct {
+	store    db.Store
+	publish  OutboxPublisher
+	interval time.Duration
+	logger   zerolog.Logger
+}
+
+func NewOutboxPoller(store db.Store, publish OutboxPublisher, interval time.Duration, logger zerolog.Logger) *OutboxPoller {
+	return &OutboxPoller{
+		store:    store,
+		publish:  publish,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start polls the outbox every interval until ctx is done. A failed poll is logged and retried on the next tick
+func (p *OutboxPoller) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := p.Poll(ctx); err != nil && ctx.Err() == nil {
+				p.logger.Error().Err(err).Msg("cannot publish outbox events")
+			}
+		}
+	}
+}
+
+// Poll publishes the pending events batch after batch, until the outbox is empty or an event can't be published
+func (p *OutboxPoller) Poll(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		published, err := p.store.PublishOutbox(ctx, outboxBatchSize, p.publish)
+		total += published
+		if err != nil || published < outboxBatchSize {
+			return total, err
+		}
+	}
+}
+
+// WebhookOutboxPublisher posts the outbox events to the webhook, the outbox id is the event id receivers deduplicate on
+func WebhookOutboxPublisher(dispatcher *webhook.Dispatcher) OutboxPublisher {
+	return func(ctx context.Context, event db.Outbox) error {
+		return dispatcher.Deliver(ctx, webhook.Event{
+			ID:        strconv.FormatInt(event.ID, 10),
+			Type:      event.EventType,
+			CreatedAt: event.CreatedAt,
+			Data:      event.Payload,
+		})
+	}
+}
+
+// NotificationOutboxPublisher sends the completed transfers and the low balance alerts the way the account owner
+// prefers, to publish and by email. The other events and the transfers to an account closed since are handed to publish alone
+func NotificationOutboxPublisher(store db.Store, distributor TaskDistributor, publish OutboxPublisher) OutboxPublisher {
+	return func(ctx context.Context, event db.Outbox) error {
+		switch event.EventType {
+		case db.OutboxEventTransferCompleted:
+			return notifyTransferReceived(ctx, store, distributor, publish, event)
+		case db.OutboxEventAccountLowBalance:
+			return notifyLowBalance(ctx, store, distributor, publish, event)
+		default:
+			return publish(ctx, event)
+		}
+	}
+}
+
+func notifyTransferReceived(ctx context.Context, store db.Store, distributor TaskDistributor, publish OutboxPublisher, event db.Outbox) error {
+	var transfer db.Transfer
+	if err := json.Unmarshal(event.Payload, &transfer); err != nil {
+		return fmt.Errorf("cannot unmarshal outbox event [%v]: %w", event.ID, err)
+	}
+	account, err := store.GetAccount(ctx, transfer.ToAccountID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return publish(ctx, event)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot get account [%v]: %w", transfer.ToAccountID, err)
+	}
+	preferences, err := db.NotificationPreferencesOf(ctx, store, account.Owner)
+	if err != nil {
+		return fmt.Errorf("cannot get notification preferences of user [%v]: %w", account.Owner, err)
+	}
+
+	return notify(ctx, publish, event, preferences.IncomingTransferWebhook, preferences.IncomingTransferEmail, func() error {
+		return distributor.DistributeTaskSendTransferReceivedEmail(ctx, &PayloadSendTransferReceivedEmail{TransferID: transfer.ID})
+	})
+}
+
+func notifyLowBalance(ctx context.Context, store db.Store, distributor TaskDistributor, publish OutboxPublisher, event db.Outbox) error {
+	var alert db.LowBalanceAlert
+	if err := json.Unmarshal(event.Payload, &alert); err != nil {
+		return fmt.Errorf("cannot unmarshal outbox event [%v]: %w", event.ID, err)
+	}
+	preferences, err := db.NotificationPreferencesOf(ctx, store, alert.Owner)
+	if err != nil {
+		return fmt.Errorf("cannot get notification preferences of user [%v]: %w", alert.Owner, err)
+	}
+
+	return notify(ctx, publish, event, preferences.LowBalanceWebhook, preferences.LowBalanceEmail, func() error {
+		return distributor.DistributeTaskSendLowBalanceEmail(ctx, &PayloadSendLowBalanceEmail{
+			AccountID:           alert.AccountID,
+			Balance:             alert.Balance,
+			LowBalanceThreshold: alert.LowBalanceThreshold,
+		})
+	})
+}
+
+// notify publishes the event and queues its email, each only when the preference allows it. The email is only queued
+// once the webhook is delivered, so a failed delivery is retried without emailing twice
+func notify(ctx context.Context, publish OutboxPublisher, event db.Outbox, webhook, email bool, sendEmail func() error) error {
+	if webhook {
+		if err := publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	if email {
+		return sendEmail()
+	}
+	return nil
+}
+
+// LogOutboxPublisher only logs the outbox events, it stands in when no webhook is configured so the outbox doesn't grow forever
+func LogOutboxPublisher(logger zerolog.Logger) OutboxPublisher {
+	return func(ctx context.Context, event db.Outbox) error {
+		logger.Info().Int64("event_id", event.ID).Str("event_type", event.EventType).Msg("outbox event published")
+		return nil
+	}
+}
@@ -0,0 +1,366 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/micaelapucciariello/simplebank/webhook"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+func randomOutboxEvent() db.Outbox {
+	return db.Outbox{
+		ID:        utils.RandomInt(1, 1000),
+		EventType: db.OutboxEventTransferCompleted,
+		Payload:   json.RawMessage(`{"id":1}`),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// publishEvents stubs PublishOutbox by handing the events to publish the way the store does, stopping at the first error
+func publishEvents(events ...db.Outbox) func(ctx context.Context, limit int32, publish func(context.Context, db.Outbox) error) (int, error) {
+	return func(ctx context.Context, limit int32, publish func(context.Context, db.Outbox) error) (int, error) {
+		for i, event := range events {
+			if err := publish(ctx, event); err != nil {
+				return i, err
+			}
+		}
+		return len(events), nil
+	}
+}
+
+func TestOutboxPollerPoll(t *testing.T) {
+	fullBatch := make([]db.Outbox, outboxBatchSize)
+	for i := range fullBatch {
+		fullBatch[i] = randomOutboxEvent()
+	}
+	event := randomOutboxEvent()
+	errPublish := errors.New("webhook unreachable")
+
+	testCases := []struct {
+		name          string
+		publish       OutboxPublisher
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, published int, err error)
+	}{
+		{
+			name:    "drains full batches",
+			publish: func(ctx context.Context, event db.Outbox) error { return nil },
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().PublishOutbox(gomock.Any(), gomock.Eq(int32(outboxBatchSize)), gomock.Any()).Times(1).DoAndReturn(publishEvents(fullBatch...)),
+					store.EXPECT().PublishOutbox(gomock.Any(), gomock.Eq(int32(outboxBatchSize)), gomock.Any()).Times(1).DoAndReturn(publishEvents(event)),
+				)
+			},
+			checkResponse: func(t *testing.T, published int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, outboxBatchSize+1, published)
+			},
+		},
+		{
+			name:    "empty outbox",
+			publish: func(ctx context.Context, event db.Outbox) error { return nil },
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().PublishOutbox(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).DoAndReturn(publishEvents())
+			},
+			checkResponse: func(t *testing.T, published int, err error) {
+				require.NoError(t, err)
+				require.Zero(t, published)
+			},
+		},
+		{
+			name:    "publish error",
+			publish: func(ctx context.Context, event db.Outbox) error { return errPublish },
+			buildStubs: func(store *mockdb.MockStore) {
+				// the poller waits for the next tick instead of retrying right away
+				store.EXPECT().PublishOutbox(gomock.Any(), gomock.Any(), gomock.Any()).Times(1).DoAndReturn(publishEvents(fullBatch...))
+			},
+			checkResponse: func(t *testing.T, published int, err error) {
+				require.ErrorIs(t, err, errPublish)
+				require.Zero(t, published)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			poller := NewOutboxPoller(store, tc.publish, time.Second, zerolog.Nop())
+			published, err := poller.Poll(context.Background())
+			tc.checkResponse(t, published, err)
+		})
+	}
+}
+
+func TestOutboxPollerStart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	event := randomOutboxEvent()
+	store.EXPECT().PublishOutbox(gomock.Any(), gomock.Any(), gomock.Any()).MinTimes(1).DoAndReturn(publishEvents(event))
+
+	delivered := make(chan db.Outbox, 10)
+	publish := func(ctx context.Context, event db.Outbox) error {
+		delivered <- event
+		return nil
+	}
+	poller := NewOutboxPoller(store, publish, time.Millisecond, zerolog.Nop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- poller.Start(ctx)
+	}()
+
+	select {
+	case got := <-delivered:
+		require.Equal(t, event, got)
+	case <-time.After(time.Second):
+		require.FailNow(t, "outbox event not published")
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestWebhookOutboxPublisher(t *testing.T) {
+	secret := utils.RandomString(32)
+	event := randomOutboxEvent()
+
+	received := make(chan webhook.Event, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.True(t, webhook.Verify(secret, body, r.Header.Get(webhook.SignatureHeader)))
+
+		var got webhook.Event
+		require.NoError(t, json.Unmarshal(body, &got))
+		received <- got
+	}))
+	defer receiver.Close()
+
+	publish := WebhookOutboxPublisher(webhook.NewDispatcher(receiver.URL, secret, 1))
+	require.NoError(t, publish(context.Background(), event))
+
+	got := <-received
+	// the outbox id is the event id, so a redelivered event can be told apart from a new one
+	require.Equal(t, strconv.FormatInt(event.ID, 10), got.ID)
+	require.Equal(t, event.EventType, got.Type)
+	require.True(t, event.CreatedAt.Equal(got.CreatedAt))
+	require.JSONEq(t, string(event.Payload), string(got.Data))
+}
+
+func TestNotificationOutboxPublisher(t *testing.T) {
+	user := randomUser()
+	transfer := db.Transfer{ID: utils.RandomInt(1, 1000), FromAccountID: 1, ToAccountID: 2, Amount: 10, ToAmount: 10}
+	payload, err := json.Marshal(transfer)
+	require.NoError(t, err)
+	event := randomOutboxEvent()
+	event.Payload = payload
+	account := db.Account{ID: transfer.ToAccountID, Owner: user.Username}
+
+	preferences := func(incomingTransferEmail, incomingTransferWebhook bool) db.NotificationPreference {
+		p := db.DefaultNotificationPreferences(user.Username)
+		p.IncomingTransferEmail = incomingTransferEmail
+		p.IncomingTransferWebhook = incomingTransferWebhook
+		return p
+	}
+
+	testCases := []struct {
+		name       string
+		event      db.Outbox
+		buildStubs func(store *mockdb.MockStore)
+		published  bool
+		emailed    bool
+	}{
+		{
+			name:  "default preferences",
+			event: event,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(transfer.ToAccountID)).Times(1).Return(account, nil)
+				store.EXPECT().GetNotificationPreferences(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.NotificationPreference{}, sql.ErrNoRows)
+			},
+			published: true,
+			emailed:   true,
+		},
+		{
+			name:  "webhook disabled",
+			event: event,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(transfer.ToAccountID)).Times(1).Return(account, nil)
+				store.EXPECT().GetNotificationPreferences(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(preferences(true, false), nil)
+			},
+			emailed: true,
+		},
+		{
+			name:  "email disabled",
+			event: event,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(transfer.ToAccountID)).Times(1).Return(account, nil)
+				store.EXPECT().GetNotificationPreferences(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(preferences(false, true), nil)
+			},
+			published: true,
+		},
+		{
+			name:  "every notification disabled",
+			event: event,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(transfer.ToAccountID)).Times(1).Return(account, nil)
+				store.EXPECT().GetNotificationPreferences(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(preferences(false, false), nil)
+			},
+		},
+		{
+			name:  "to account closed",
+			event: event,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(transfer.ToAccountID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().GetNotificationPreferences(gomock.Any(), gomock.Any()).Times(0)
+			},
+			published: true,
+		},
+		{
+			name:  "other event",
+			event: db.Outbox{ID: event.ID, EventType: "account.closed", Payload: json.RawMessage(`{}`)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			published: true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			published := false
+			publish := func(ctx context.Context, event db.Outbox) error {
+				published = true
+				return nil
+			}
+			broker := NewInMemoryBroker(1)
+			notify := NotificationOutboxPublisher(store, NewTaskDistributor(broker), publish)
+
+			require.NoError(t, notify(context.Background(), tc.event))
+			require.Equal(t, tc.published, published)
+			if !tc.emailed {
+				require.Empty(t, broker.tasks)
+				return
+			}
+
+			task, err := broker.Dequeue(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, TaskSendTransferReceivedEmail, task.Type)
+			require.JSONEq(t, fmt.Sprintf(`{"transfer_id":%d}`, transfer.ID), string(task.Payload))
+		})
+	}
+}
+
+func TestNotificationOutboxPublisherError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	user := randomUser()
+	event := randomOutboxEvent()
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{Owner: user.Username}, nil)
+	store.EXPECT().GetNotificationPreferences(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.NotificationPreference{}, sql.ErrNoRows)
+
+	errPublish := errors.New("webhook unreachable")
+	publish := func(ctx context.Context, event db.Outbox) error { return errPublish }
+	broker := NewInMemoryBroker(1)
+	notify := NotificationOutboxPublisher(store, NewTaskDistributor(broker), publish)
+
+	// the event stays unpublished and no email is queued, the next poll tries both again
+	require.ErrorIs(t, notify(context.Background(), event), errPublish)
+	require.Empty(t, broker.tasks)
+}
+
+func TestNotificationOutboxPublisherLowBalance(t *testing.T) {
+	user := randomUser()
+	alert := db.LowBalanceAlert{AccountID: utils.RandomInt(1, 1000), Owner: user.Username, Currency: utils.USD, Balance: 400, LowBalanceThreshold: 500}
+	payload, err := json.Marshal(alert)
+	require.NoError(t, err)
+	event := randomOutboxEvent()
+	event.EventType = db.OutboxEventAccountLowBalance
+	event.Payload = payload
+
+	preferences := func(lowBalanceEmail, lowBalanceWebhook bool) db.NotificationPreference {
+		p := db.DefaultNotificationPreferences(user.Username)
+		p.LowBalanceEmail = lowBalanceEmail
+		p.LowBalanceWebhook = lowBalanceWebhook
+		return p
+	}
+
+	testCases := []struct {
+		name        string
+		preferences db.NotificationPreference
+		published   bool
+		emailed     bool
+	}{
+		{name: "email and webhook", preferences: preferences(true, true), published: true, emailed: true},
+		{name: "webhook disabled", preferences: preferences(true, false), emailed: true},
+		{name: "email disabled", preferences: preferences(false, true), published: true},
+		// the incoming transfer preferences don't suppress the low balance alert
+		{name: "incoming transfers disabled", preferences: db.NotificationPreference{Username: user.Username, LowBalanceEmail: true, LowBalanceWebhook: true}, published: true, emailed: true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetNotificationPreferences(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(tc.preferences, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+			published := false
+			publish := func(ctx context.Context, event db.Outbox) error {
+				published = true
+				return nil
+			}
+			broker := NewInMemoryBroker(1)
+			notify := NotificationOutboxPublisher(store, NewTaskDistributor(broker), publish)
+
+			require.NoError(t, notify(context.Background(), event))
+			require.Equal(t, tc.published, published)
+			if !tc.emailed {
+				require.Empty(t, broker.tasks)
+				return
+			}
+
+			task, err := broker.Dequeue(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, TaskSendLowBalanceEmail, task.Type)
+			var got PayloadSendLowBalanceEmail
+			require.NoError(t, json.Unmarshal(task.Payload, &got))
+			require.Equal(t, PayloadSendLowBalanceEmail{AccountID: alert.AccountID, Balance: alert.Balance, LowBalanceThreshold: alert.LowBalanceThreshold}, got)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/rs/zerolog"
+)
+
+// TaskProcessor runs the tasks received from the broker
+type TaskProcessor interface {
+	Start(ctx context.Context) error
+	ProcessTaskSendWelcomeEmail(ctx context.Context, task Task) error
+	ProcessTaskSendPasswordResetEmail(ctx context.Context, task Task) error
+	ProcessTaskSendTransferReceivedEmail(ctx context.Context, task Task) error
+	ProcessTaskSendLowBalanceEmail(ctx context.Context, task Task) error
+}
+
+type BrokerTaskProcessor struct {
+	broker Broker
+	store  db.Store
+	mailer EmailSender
+	logger zerolog.Logger
+}
+
+func NewTaskProcessor(broker Broker, store db.Store, mailer EmailSender, logger zerolog.Logger) TaskProcessor {
+	return &BrokerTaskProcessor{
+		broker: broker,
+		store:  store,
+		mailer: mailer,
+		logger: logger,
+	}
+}
+
+// Start processes the tasks one at a time until ctx is done. A failed task is logged and dropped
+func (p *BrokerTaskProcessor) Start(ctx context.Context) error {
+	for {
+		task, err := p.broker.Dequeue(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil
+			}
+			return err
+		}
+
+		if err = p.process(ctx, task); err != nil {
+			p.logger.Error().Err(err).Str("task_type", task.Type).Msg("cannot process task")
+		}
+	}
+}
+
+func (p *BrokerTaskProcessor) process(ctx context.Context, task Task) error {
+	switch task.Type {
+	case TaskSendWelcomeEmail:
+		return p.ProcessTaskSendWelcomeEmail(ctx, task)
+	case TaskSendPasswordResetEmail:
+		return p.ProcessTaskSendPasswordResetEmail(ctx, task)
+	case TaskSendTransferReceivedEmail:
+		return p.ProcessTaskSendTransferReceivedEmail(ctx, task)
+	case TaskSendLowBalanceEmail:
+		return p.ProcessTaskSendLowBalanceEmail(ctx, task)
+	default:
+		return fmt.Errorf("unknown task type %s", task.Type)
+	}
+}
@@ -0,0 +1,138 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+const scheduledTransferBatchSize = 100
+
+// ScheduledTransferRunner executes the scheduled transfers once they are due
+type ScheduledTransferRunner struct {
+	store      db.Store
+	interval   time.Duration
+	dailyLimit int64
+	logger     zerolog.Logger
+}
+
+func NewScheduledTransferRunner(store db.Store, interval time.Duration, dailyLimit int64, logger zerolog.Logger) *ScheduledTransferRunner {
+	return &ScheduledTransferRunner{
+		store:      store,
+		interval:   interval,
+		dailyLimit: dailyLimit,
+		logger:     logger,
+	}
+}
+
+// Start runs the due transfers every interval until ctx is done. A failed run is logged and retried on the next tick
+func (r *ScheduledTransferRunner) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, _, err := r.Run(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				r.logger.Error().Err(err).Msg("cannot run scheduled transfers")
+			}
+		}
+	}
+}
+
+// Run executes the transfers due at now batch after batch and returns how many went through and how many failed
+// A schedule is claimed by moving its next run forward before its transfer is executed, so concurrent runners never execute the same cycle twice:
+// a crash between the claim and the transfer skips that cycle instead of paying it twice
+func (r *ScheduledTransferRunner) Run(ctx context.Context, now time.Time) (executed int, failed int, err error) {
+	for {
+		due, err := r.store.ListDueScheduledTransfers(ctx, db.ListDueScheduledTransfersParams{
+			Now:   now,
+			Limit: scheduledTransferBatchSize,
+		})
+		if err != nil {
+			return executed, failed, err
+		}
+
+		for _, schedule := range due {
+			_, err = r.store.ClaimScheduledTransfer(ctx, db.ClaimScheduledTransferParams{
+				ID:        schedule.ID,
+				DueAt:     schedule.NextRunAt,
+				NextRunAt: nextRunAt(schedule, now),
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				// another runner claimed it, or it was cancelled since it was listed
+				continue
+			}
+			if err != nil {
+				return executed, failed, err
+			}
+
+			if r.execute(ctx, schedule, now) {
+				executed++
+			} else {
+				failed++
+			}
+		}
+
+		if len(due) < scheduledTransferBatchSize {
+			return executed, failed, nil
+		}
+	}
+}
+
+// execute transfers the amount of a claimed schedule and records the outcome on it
+// A failed transfer, usually an insufficient balance, is recorded and retried on the next cycle of the schedule.
+// A schedule whose account was deleted is cancelled instead
+func (r *ScheduledTransferRunner) execute(ctx context.Context, schedule db.ScheduledTransfer, now time.Time) bool {
+	runAt := sql.NullTime{Time: now, Valid: true}
+
+	result, err := r.store.TransferTx(ctx, db.TransferTxParams{
+		FromAccountID: schedule.FromAccountID,
+		ToAccountID:   schedule.ToAccountID,
+		Amount:        schedule.Amount,
+		DailyLimit:    r.dailyLimit,
+	})
+	if err != nil {
+		r.logger.Warn().Err(err).Int64("scheduled_transfer_id", schedule.ID).Msg("scheduled transfer failed")
+
+		_, recordErr := r.store.RecordScheduledTransferFailure(ctx, db.RecordScheduledTransferFailureParams{
+			ID:        schedule.ID,
+			RunAt:     runAt,
+			LastError: sql.NullString{String: err.Error(), Valid: true},
+		})
+		if recordErr != nil {
+			r.logger.Error().Err(recordErr).Int64("scheduled_transfer_id", schedule.ID).Msg("cannot record scheduled transfer failure")
+		}
+
+		// a deleted account never comes back to life by itself, retrying the schedule would only fail again
+		if errors.Is(err, db.ErrAccountNotFound) {
+			if _, cancelErr := r.store.CancelScheduledTransfer(ctx, schedule.ID); cancelErr != nil && !errors.Is(cancelErr, sql.ErrNoRows) {
+				r.logger.Error().Err(cancelErr).Int64("scheduled_transfer_id", schedule.ID).Msg("cannot cancel scheduled transfer of a deleted account")
+			}
+		}
+		return false
+	}
+
+	_, err = r.store.RecordScheduledTransferRun(ctx, db.RecordScheduledTransferRunParams{
+		ID:         schedule.ID,
+		RunAt:      runAt,
+		TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+	})
+	if err != nil {
+		// the money already moved, only the bookkeeping of the schedule is missing
+		r.logger.Error().Err(err).Int64("scheduled_transfer_id", schedule.ID).Int64("transfer_id", result.Transfer.ID).Msg("cannot record scheduled transfer run")
+	}
+	return true
+}
+
+// nextRunAt returns the first cycle of the schedule after now, the cycles missed while no runner was up are skipped instead of run back to back
+func nextRunAt(schedule db.ScheduledTransfer, now time.Time) time.Time {
+	interval := time.Duration(schedule.IntervalSeconds) * time.Second
+	missed := now.Sub(schedule.NextRunAt) / interval
+	return schedule.NextRunAt.Add((missed + 1) * interval)
+}
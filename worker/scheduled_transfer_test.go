@@ -0,0 +1,198 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+const testDailyLimit = 5000
+
+func randomScheduledTransfer(nextRunAt time.Time) db.ScheduledTransfer {
+	return db.ScheduledTransfer{
+		ID:              utils.RandomInt(1, 1000),
+		FromAccountID:   utils.RandomInt(1, 1000),
+		ToAccountID:     utils.RandomInt(1001, 2000),
+		Amount:          utils.RandomInt(1, 100),
+		IntervalSeconds: 3600,
+		NextRunAt:       nextRunAt,
+	}
+}
+
+func TestScheduledTransferRunnerRun(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	schedule := randomScheduledTransfer(now.Add(-time.Minute))
+	fullBatch := make([]db.ScheduledTransfer, scheduledTransferBatchSize)
+	for i := range fullBatch {
+		fullBatch[i] = randomScheduledTransfer(now)
+	}
+
+	transferArg := db.TransferTxParams{
+		FromAccountID: schedule.FromAccountID,
+		ToAccountID:   schedule.ToAccountID,
+		Amount:        schedule.Amount,
+		DailyLimit:    testDailyLimit,
+	}
+	claimArg := db.ClaimScheduledTransferParams{
+		ID:        schedule.ID,
+		DueAt:     schedule.NextRunAt,
+		NextRunAt: schedule.NextRunAt.Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, executed, failed int, err error)
+	}{
+		{
+			name: "happy path run",
+			buildStubs: func(store *mockdb.MockStore) {
+				transfer := db.TransferTxResult{Transfer: db.Transfer{ID: utils.RandomInt(1, 1000)}}
+				gomock.InOrder(
+					store.EXPECT().ListDueScheduledTransfers(gomock.Any(), gomock.Eq(db.ListDueScheduledTransfersParams{Now: now, Limit: scheduledTransferBatchSize})).
+						Times(1).Return([]db.ScheduledTransfer{schedule}, nil),
+					store.EXPECT().ClaimScheduledTransfer(gomock.Any(), gomock.Eq(claimArg)).Times(1).Return(schedule, nil),
+					store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(transferArg)).Times(1).Return(transfer, nil),
+					store.EXPECT().RecordScheduledTransferRun(gomock.Any(), gomock.Eq(db.RecordScheduledTransferRunParams{
+						ID:         schedule.ID,
+						RunAt:      sql.NullTime{Time: now, Valid: true},
+						TransferID: sql.NullInt64{Int64: transfer.Transfer.ID, Valid: true},
+					})).Times(1).Return(schedule, nil),
+				)
+				store.EXPECT().RecordScheduledTransferFailure(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, executed, failed int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 1, executed)
+				require.Zero(t, failed)
+			},
+		},
+		{
+			name: "insufficient balance is recorded",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().ListDueScheduledTransfers(gomock.Any(), gomock.Any()).Times(1).Return([]db.ScheduledTransfer{schedule}, nil),
+					// the schedule is claimed before the transfer, so the failed cycle is only retried on the next one
+					store.EXPECT().ClaimScheduledTransfer(gomock.Any(), gomock.Eq(claimArg)).Times(1).Return(schedule, nil),
+					store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(transferArg)).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientBalance),
+					store.EXPECT().RecordScheduledTransferFailure(gomock.Any(), gomock.Eq(db.RecordScheduledTransferFailureParams{
+						ID:        schedule.ID,
+						RunAt:     sql.NullTime{Time: now, Valid: true},
+						LastError: sql.NullString{String: db.ErrInsufficientBalance.Error(), Valid: true},
+					})).Times(1).Return(schedule, nil),
+				)
+				store.EXPECT().RecordScheduledTransferRun(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, executed, failed int, err error) {
+				require.NoError(t, err)
+				require.Zero(t, executed)
+				require.Equal(t, 1, failed)
+			},
+		},
+		{
+			name: "deleted account cancels the schedule",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().ListDueScheduledTransfers(gomock.Any(), gomock.Any()).Times(1).Return([]db.ScheduledTransfer{schedule}, nil),
+					store.EXPECT().ClaimScheduledTransfer(gomock.Any(), gomock.Eq(claimArg)).Times(1).Return(schedule, nil),
+					store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(transferArg)).Times(1).Return(db.TransferTxResult{}, db.ErrAccountNotFound),
+					store.EXPECT().RecordScheduledTransferFailure(gomock.Any(), gomock.Any()).Times(1).Return(schedule, nil),
+					store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Eq(schedule.ID)).Times(1).Return(schedule, nil),
+				)
+				store.EXPECT().RecordScheduledTransferRun(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, executed, failed int, err error) {
+				require.NoError(t, err)
+				require.Zero(t, executed)
+				require.Equal(t, 1, failed)
+			},
+		},
+		{
+			name: "claimed by another runner",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListDueScheduledTransfers(gomock.Any(), gomock.Any()).Times(1).Return([]db.ScheduledTransfer{schedule}, nil)
+				store.EXPECT().ClaimScheduledTransfer(gomock.Any(), gomock.Any()).Times(1).Return(db.ScheduledTransfer{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, executed, failed int, err error) {
+				require.NoError(t, err)
+				require.Zero(t, executed)
+				require.Zero(t, failed)
+			},
+		},
+		{
+			name: "drains full batches",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().ListDueScheduledTransfers(gomock.Any(), gomock.Any()).Times(1).Return(fullBatch, nil),
+					store.EXPECT().ListDueScheduledTransfers(gomock.Any(), gomock.Any()).Times(1).Return([]db.ScheduledTransfer{}, nil),
+				)
+				store.EXPECT().ClaimScheduledTransfer(gomock.Any(), gomock.Any()).Times(scheduledTransferBatchSize).Return(db.ScheduledTransfer{}, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(scheduledTransferBatchSize).Return(db.TransferTxResult{}, nil)
+				store.EXPECT().RecordScheduledTransferRun(gomock.Any(), gomock.Any()).Times(scheduledTransferBatchSize).Return(db.ScheduledTransfer{}, nil)
+			},
+			checkResponse: func(t *testing.T, executed, failed int, err error) {
+				require.NoError(t, err)
+				require.Equal(t, scheduledTransferBatchSize, executed)
+				require.Zero(t, failed)
+			},
+		},
+		{
+			name: "list error",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListDueScheduledTransfers(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+				store.EXPECT().ClaimScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, executed, failed int, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			runner := NewScheduledTransferRunner(store, time.Second, testDailyLimit, zerolog.Nop())
+			executed, failed, err := runner.Run(context.Background(), now)
+			tc.checkResponse(t, executed, failed, err)
+		})
+	}
+}
+
+func TestNextRunAt(t *testing.T) {
+	due := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	schedule := randomScheduledTransfer(due)
+
+	testCases := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{name: "run on time", now: due, expected: due.Add(time.Hour)},
+		{name: "run late", now: due.Add(10 * time.Minute), expected: due.Add(time.Hour)},
+		{name: "missed cycles are skipped", now: due.Add(150 * time.Minute), expected: due.Add(3 * time.Hour)},
+		{name: "missed exactly one cycle", now: due.Add(time.Hour), expected: due.Add(2 * time.Hour)},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, nextRunAt(schedule, tc.now))
+		})
+	}
+}
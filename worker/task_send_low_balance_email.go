@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/micaelapucciariello/simplebank/utils"
+)
+
+const TaskSendLowBalanceEmail = "task:send_low_balance_email"
+
+// PayloadSendLowBalanceEmail carries the balance the transfer left, the account may have received money since
+type PayloadSendLowBalanceEmail struct {
+	AccountID           int64 `json:"account_id"`
+	Balance             int64 `json:"balance"`
+	LowBalanceThreshold int64 `json:"low_balance_threshold"`
+}
+
+func (d *BrokerTaskDistributor) DistributeTaskSendLowBalanceEmail(ctx context.Context, payload *PayloadSendLowBalanceEmail) error {
+	return d.distribute(ctx, TaskSendLowBalanceEmail, payload)
+}
+
+// ProcessTaskSendLowBalanceEmail warns the account owner the balance went below the threshold it set
+func (p *BrokerTaskProcessor) ProcessTaskSendLowBalanceEmail(ctx context.Context, task Task) error {
+	var payload PayloadSendLowBalanceEmail
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("cannot unmarshal task payload: %w", err)
+	}
+
+	account, err := p.store.GetAccount(ctx, payload.AccountID)
+	if err != nil {
+		return fmt.Errorf("cannot get account [%v]: %w", payload.AccountID, err)
+	}
+	user, err := p.store.GetUser(ctx, account.Owner)
+	if err != nil {
+		return fmt.Errorf("cannot get user [%v]: %w", account.Owner, err)
+	}
+
+	subject := "Your balance is low"
+	content := fmt.Sprintf("Hello %s,\nThe balance of your account %s went down to %s %s, below the %s %s you asked to be warned about.",
+		user.FullName, account.AccountNumber, utils.Money(payload.Balance).String(), account.Currency,
+		utils.Money(payload.LowBalanceThreshold).String(), account.Currency)
+	if err = p.mailer.SendEmail(ctx, user.Email, subject, content); err != nil {
+		return fmt.Errorf("cannot send low balance email to user [%v]: %w", user.Username, err)
+	}
+
+	p.logger.Info().Str("username", user.Username).Int64("account_id", account.ID).Msg("low balance email sent")
+	return nil
+}
@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const TaskSendPasswordResetEmail = "task:send_password_reset_email"
+
+// PayloadSendPasswordResetEmail carries the reset token, the store only keeps its hash so it can't be read back
+type PayloadSendPasswordResetEmail struct {
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
+func (d *BrokerTaskDistributor) DistributeTaskSendPasswordResetEmail(ctx context.Context, payload *PayloadSendPasswordResetEmail) error {
+	return d.distribute(ctx, TaskSendPasswordResetEmail, payload)
+}
+
+// ProcessTaskSendPasswordResetEmail emails the reset token to the address the user has when the task runs
+func (p *BrokerTaskProcessor) ProcessTaskSendPasswordResetEmail(ctx context.Context, task Task) error {
+	var payload PayloadSendPasswordResetEmail
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("cannot unmarshal task payload: %w", err)
+	}
+
+	user, err := p.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		return fmt.Errorf("cannot get user [%v]: %w", payload.Username, err)
+	}
+
+	subject := "Reset your Simple Bank password"
+	content := fmt.Sprintf("Hello %s,\nUse this token to reset your password: %s\n"+
+		"If you didn't ask for a password reset, you can ignore this email.", user.FullName, payload.Token)
+	if err = p.mailer.SendEmail(ctx, user.Email, subject, content); err != nil {
+		return fmt.Errorf("cannot send password reset email to user [%v]: %w", user.Username, err)
+	}
+
+	p.logger.Info().Str("username", user.Username).Msg("password reset email sent")
+	return nil
+}
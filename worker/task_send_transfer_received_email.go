@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/micaelapucciariello/simplebank/utils"
+)
+
+const TaskSendTransferReceivedEmail = "task:send_transfer_received_email"
+
+type PayloadSendTransferReceivedEmail struct {
+	TransferID int64 `json:"transfer_id"`
+}
+
+func (d *BrokerTaskDistributor) DistributeTaskSendTransferReceivedEmail(ctx context.Context, payload *PayloadSendTransferReceivedEmail) error {
+	return d.distribute(ctx, TaskSendTransferReceivedEmail, payload)
+}
+
+// ProcessTaskSendTransferReceivedEmail tells the owner of the to account about the money it received
+func (p *BrokerTaskProcessor) ProcessTaskSendTransferReceivedEmail(ctx context.Context, task Task) error {
+	var payload PayloadSendTransferReceivedEmail
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("cannot unmarshal task payload: %w", err)
+	}
+
+	transfer, err := p.store.GetTransfer(ctx, payload.TransferID)
+	if err != nil {
+		return fmt.Errorf("cannot get transfer [%v]: %w", payload.TransferID, err)
+	}
+	account, err := p.store.GetAccount(ctx, transfer.ToAccountID)
+	if err != nil {
+		return fmt.Errorf("cannot get account [%v]: %w", transfer.ToAccountID, err)
+	}
+	user, err := p.store.GetUser(ctx, account.Owner)
+	if err != nil {
+		return fmt.Errorf("cannot get user [%v]: %w", account.Owner, err)
+	}
+
+	subject := "You received a transfer"
+	content := fmt.Sprintf("Hello %s,\nYour account %s received %s %s.", user.FullName, account.AccountNumber,
+		utils.Money(transfer.ToAmount).String(), account.Currency)
+	if err = p.mailer.SendEmail(ctx, user.Email, subject, content); err != nil {
+		return fmt.Errorf("cannot send transfer received email to user [%v]: %w", user.Username, err)
+	}
+
+	p.logger.Info().Str("username", user.Username).Int64("transfer_id", transfer.ID).Msg("transfer received email sent")
+	return nil
+}
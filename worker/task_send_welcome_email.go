@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const TaskSendWelcomeEmail = "task:send_welcome_email"
+
+type PayloadSendWelcomeEmail struct {
+	Username string `json:"username"`
+}
+
+func (d *BrokerTaskDistributor) DistributeTaskSendWelcomeEmail(ctx context.Context, payload *PayloadSendWelcomeEmail) error {
+	return d.distribute(ctx, TaskSendWelcomeEmail, payload)
+}
+
+// ProcessTaskSendWelcomeEmail reads the user back from the store, so the task payload doesn't carry any personal data
+func (p *BrokerTaskProcessor) ProcessTaskSendWelcomeEmail(ctx context.Context, task Task) error {
+	var payload PayloadSendWelcomeEmail
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("cannot unmarshal task payload: %w", err)
+	}
+
+	user, err := p.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		return fmt.Errorf("cannot get user [%v]: %w", payload.Username, err)
+	}
+
+	subject := "Welcome to Simple Bank"
+	content := fmt.Sprintf("Hello %s,\nThank you for registering with us!", user.FullName)
+	if err = p.mailer.SendEmail(ctx, user.Email, subject, content); err != nil {
+		return fmt.Errorf("cannot send welcome email to user [%v]: %w", user.Username, err)
+	}
+
+	p.logger.Info().Str("username", user.Username).Msg("welcome email sent")
+	return nil
+}
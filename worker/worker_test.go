@@ -0,0 +1,261 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/micaelapucciariello/simplebank/utils"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+
+	mockdb "github.com/micaelapucciariello/simplebank/db/mock"
+	db "github.com/micaelapucciariello/simplebank/db/sqlc"
+)
+
+type sentEmail struct {
+	to      string
+	subject string
+	content string
+}
+
+// testEmailSender records the emails instead of sending them
+type testEmailSender struct {
+	emails chan sentEmail
+}
+
+func (s *testEmailSender) SendEmail(ctx context.Context, to, subject, content string) error {
+	s.emails <- sentEmail{to: to, subject: subject, content: content}
+	return nil
+}
+
+func randomUser() db.User {
+	return db.User{
+		Username: utils.RandomOwner(),
+		FullName: utils.RandomOwner(),
+		Email:    utils.RandomEmail(),
+	}
+}
+
+func TestDistributeTaskSendWelcomeEmail(t *testing.T) {
+	broker := NewInMemoryBroker(1)
+	distributor := NewTaskDistributor(broker)
+	username := utils.RandomOwner()
+
+	err := distributor.DistributeTaskSendWelcomeEmail(context.Background(), &PayloadSendWelcomeEmail{Username: username})
+	require.NoError(t, err)
+
+	task, err := broker.Dequeue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, TaskSendWelcomeEmail, task.Type)
+
+	var payload PayloadSendWelcomeEmail
+	require.NoError(t, json.Unmarshal(task.Payload, &payload))
+	require.Equal(t, username, payload.Username)
+
+	// the queue was emptied, nothing else was enqueued
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = broker.Dequeue(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestDistributeTaskQueueFull(t *testing.T) {
+	distributor := NewTaskDistributor(NewInMemoryBroker(0))
+
+	err := distributor.DistributeTaskSendWelcomeEmail(context.Background(), &PayloadSendWelcomeEmail{Username: utils.RandomOwner()})
+	require.ErrorIs(t, err, ErrQueueFull)
+}
+
+func TestProcessTaskSendWelcomeEmail(t *testing.T) {
+	user := randomUser()
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		checkEmail func(t *testing.T, err error, mailer *testEmailSender)
+	}{
+		{
+			name: "happy path send welcome email",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			checkEmail: func(t *testing.T, err error, mailer *testEmailSender) {
+				require.NoError(t, err)
+				require.Len(t, mailer.emails, 1)
+
+				email := <-mailer.emails
+				require.Equal(t, user.Email, email.to)
+				require.Contains(t, email.content, user.FullName)
+			},
+		},
+		{
+			name: "user not found",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			checkEmail: func(t *testing.T, err error, mailer *testEmailSender) {
+				require.ErrorIs(t, err, sql.ErrNoRows)
+				require.Len(t, mailer.emails, 0)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			mailer := &testEmailSender{emails: make(chan sentEmail, 1)}
+			processor := NewTaskProcessor(NewInMemoryBroker(1), store, mailer, zerolog.Nop())
+
+			payload, err := json.Marshal(PayloadSendWelcomeEmail{Username: user.Username})
+			require.NoError(t, err)
+
+			err = processor.ProcessTaskSendWelcomeEmail(context.Background(), Task{Type: TaskSendWelcomeEmail, Payload: payload})
+			tc.checkEmail(t, err, mailer)
+		})
+	}
+}
+
+func TestProcessTaskSendPasswordResetEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	user := randomUser()
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+
+	broker := NewInMemoryBroker(1)
+	mailer := &testEmailSender{emails: make(chan sentEmail, 1)}
+	processor := NewTaskProcessor(broker, store, mailer, zerolog.Nop())
+
+	token := utils.RandomString(32)
+	err := NewTaskDistributor(broker).DistributeTaskSendPasswordResetEmail(context.Background(), &PayloadSendPasswordResetEmail{
+		Username: user.Username,
+		Token:    token,
+	})
+	require.NoError(t, err)
+
+	task, err := broker.Dequeue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, TaskSendPasswordResetEmail, task.Type)
+
+	err = processor.ProcessTaskSendPasswordResetEmail(context.Background(), task)
+	require.NoError(t, err)
+	require.Len(t, mailer.emails, 1)
+
+	email := <-mailer.emails
+	require.Equal(t, user.Email, email.to)
+	require.Contains(t, email.content, token)
+}
+
+func TestProcessTaskSendTransferReceivedEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	user := randomUser()
+	account := db.Account{ID: 2, Owner: user.Username, AccountNumber: utils.RandomAccountNumber(), Currency: utils.USD}
+	transfer := db.Transfer{ID: utils.RandomInt(1, 1000), FromAccountID: 1, ToAccountID: account.ID, Amount: 1234, ToAmount: 1234}
+	store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+
+	broker := NewInMemoryBroker(1)
+	mailer := &testEmailSender{emails: make(chan sentEmail, 1)}
+	processor := NewTaskProcessor(broker, store, mailer, zerolog.Nop())
+
+	err := NewTaskDistributor(broker).DistributeTaskSendTransferReceivedEmail(context.Background(), &PayloadSendTransferReceivedEmail{TransferID: transfer.ID})
+	require.NoError(t, err)
+
+	task, err := broker.Dequeue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, TaskSendTransferReceivedEmail, task.Type)
+
+	err = processor.ProcessTaskSendTransferReceivedEmail(context.Background(), task)
+	require.NoError(t, err)
+	require.Len(t, mailer.emails, 1)
+
+	email := <-mailer.emails
+	require.Equal(t, user.Email, email.to)
+	require.Contains(t, email.content, "12.34 USD")
+	require.Contains(t, email.content, account.AccountNumber)
+}
+
+func TestProcessTaskSendLowBalanceEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	user := randomUser()
+	account := db.Account{ID: utils.RandomInt(1, 1000), Owner: user.Username, AccountNumber: utils.RandomAccountNumber(), Currency: utils.USD, Balance: 9000}
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+
+	broker := NewInMemoryBroker(1)
+	mailer := &testEmailSender{emails: make(chan sentEmail, 1)}
+	processor := NewTaskProcessor(broker, store, mailer, zerolog.Nop())
+
+	err := NewTaskDistributor(broker).DistributeTaskSendLowBalanceEmail(context.Background(), &PayloadSendLowBalanceEmail{
+		AccountID:           account.ID,
+		Balance:             4000,
+		LowBalanceThreshold: 5000,
+	})
+	require.NoError(t, err)
+
+	task, err := broker.Dequeue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, TaskSendLowBalanceEmail, task.Type)
+
+	err = processor.ProcessTaskSendLowBalanceEmail(context.Background(), task)
+	require.NoError(t, err)
+	require.Len(t, mailer.emails, 1)
+
+	// the email tells the balance the transfer left, not the current one
+	email := <-mailer.emails
+	require.Equal(t, user.Email, email.to)
+	require.Contains(t, email.content, "40.00 USD")
+	require.Contains(t, email.content, "50.00 USD")
+	require.NotContains(t, email.content, "90.00")
+}
+
+func TestTaskProcessorStart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mockdb.NewMockStore(ctrl)
+
+	user := randomUser()
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+
+	broker := NewInMemoryBroker(2)
+	mailer := &testEmailSender{emails: make(chan sentEmail, 1)}
+	processor := NewTaskProcessor(broker, store, mailer, zerolog.Nop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- processor.Start(ctx)
+	}()
+
+	// an unknown task is dropped and the processor moves on to the next one
+	require.NoError(t, broker.Enqueue(ctx, Task{Type: "task:unknown"}))
+	err := NewTaskDistributor(broker).DistributeTaskSendWelcomeEmail(ctx, &PayloadSendWelcomeEmail{Username: user.Username})
+	require.NoError(t, err)
+
+	select {
+	case email := <-mailer.emails:
+		require.Equal(t, user.Email, email.to)
+	case <-time.After(time.Second):
+		require.FailNow(t, "welcome email not sent")
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}